@@ -0,0 +1,208 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// List of circuit states, the values are reported by the goa.client.circuit.state gauge.
+const (
+	// CircuitClosed is the state of circuits that let requests through.
+	CircuitClosed CircuitState = iota
+	// CircuitHalfOpen is the state of circuits that let a limited number of probe requests
+	// through to test whether the service recovered.
+	CircuitHalfOpen
+	// CircuitOpen is the state of circuits that reject requests.
+	CircuitOpen
+)
+
+// ErrCircuitOpen is the error returned by the doers created with BreakerDoer when the circuit is
+// open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+type (
+	// CircuitState is the state of a circuit breaker.
+	CircuitState int
+
+	// BreakerOption allows to override the default circuit breaker parameters.
+	BreakerOption func(*breakerOptions) error
+
+	// breakerOptions contains the final circuit breaker parameters.
+	breakerOptions struct {
+		threshold int
+		timeout   time.Duration
+		probes    int
+		now       func() time.Time
+	}
+
+	// breakerDoer is a Doer that implements a circuit breaker per host and action.
+	breakerDoer struct {
+		Doer
+		opts     *breakerOptions
+		lock     sync.Mutex
+		circuits map[string]*circuit
+	}
+
+	// circuit is the state of the circuit of a host and action.
+	circuit struct {
+		labels   []string
+		state    CircuitState
+		failures int
+		openedAt time.Time
+		probes   int
+	}
+)
+
+// FailureThreshold sets the number of consecutive failures that open the circuit, the default
+// is 5.
+func FailureThreshold(n int) BreakerOption {
+	return func(o *breakerOptions) error {
+		if n < 1 {
+			return fmt.Errorf("client: invalid failure threshold %d", n)
+		}
+		o.threshold = n
+		return nil
+	}
+}
+
+// OpenTimeout sets the duration a circuit stays open before probe requests are let through, the
+// default is 30s.
+func OpenTimeout(d time.Duration) BreakerOption {
+	return func(o *breakerOptions) error {
+		if d <= 0 {
+			return fmt.Errorf("client: invalid open timeout %s", d)
+		}
+		o.timeout = d
+		return nil
+	}
+}
+
+// HalfOpenProbes sets the maximum number of concurrent probe requests let through by a half-open
+// circuit, the default is 1.
+func HalfOpenProbes(n int) BreakerOption {
+	return func(o *breakerOptions) error {
+		if n < 1 {
+			return fmt.Errorf("client: invalid half-open probes %d", n)
+		}
+		o.probes = n
+		return nil
+	}
+}
+
+// String returns the name of the state.
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitHalfOpen:
+		return "half-open"
+	case CircuitOpen:
+		return "open"
+	}
+	return fmt.Sprintf("CircuitState(%d)", int(s))
+}
+
+// BreakerDoer wraps doer with a circuit breaker. Each host and action (see WithAction) gets its
+// own circuit. A circuit opens after a number of consecutive failures, failures being transport
+// errors and 5xx responses. Requests made while the circuit is open fail with ErrCircuitOpen.
+// Once the open timeout elapses the circuit becomes half-open and lets probe requests through:
+// the circuit closes if a probe succeeds and opens again if it fails.
+//
+// The state of the circuits is reported with the goa.client.circuit.state gauge labeled with the
+// host, resource and action, see CircuitState for the values. Rejected requests are counted by
+// the goa.client.circuit.rejected counter.
+func BreakerDoer(doer Doer, o ...BreakerOption) Doer {
+	opts := &breakerOptions{
+		threshold: 5,
+		timeout:   30 * time.Second,
+		probes:    1,
+		now:       time.Now,
+	}
+	for _, opt := range o {
+		if err := opt(opts); err != nil {
+			panic(err)
+		}
+	}
+	return &breakerDoer{Doer: doer, opts: opts, circuits: make(map[string]*circuit)}
+}
+
+// Do implements Doer.
+func (d *breakerDoer) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	c, probe, ok := d.acquire(ctx, req)
+	if !ok {
+		incrCircuitRejected(c.labels)
+		return nil, ErrCircuitOpen
+	}
+	resp, err := d.Doer.Do(ctx, req)
+	d.release(c, probe, err == nil && resp.StatusCode < 500)
+	return resp, err
+}
+
+// acquire returns the circuit of the request, whether the request is a probe and whether it may
+// be made.
+func (d *breakerDoer) acquire(ctx context.Context, req *http.Request) (*circuit, bool, bool) {
+	resource, action := ContextAction(ctx)
+	key := req.URL.Host + "#" + resource + "#" + action
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	c, ok := d.circuits[key]
+	if !ok {
+		c = &circuit{labels: []string{req.URL.Host, resource, action}}
+		d.circuits[key] = c
+	}
+	switch c.state {
+	case CircuitOpen:
+		if d.opts.now().Sub(c.openedAt) < d.opts.timeout {
+			return c, false, false
+		}
+		d.setState(c, CircuitHalfOpen)
+		fallthrough
+	case CircuitHalfOpen:
+		if c.probes >= d.opts.probes {
+			return c, false, false
+		}
+		c.probes++
+		return c, true, true
+	}
+	return c, false, true
+}
+
+// release records the outcome of a request made through c. Only the outcome of probes is taken
+// into account when the circuit is not closed.
+func (d *breakerDoer) release(c *circuit, probe, success bool) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if c.state != CircuitClosed {
+		if !probe || c.state != CircuitHalfOpen {
+			return
+		}
+		if c.probes > 0 {
+			c.probes--
+		}
+	}
+	if success {
+		c.failures = 0
+		if c.state != CircuitClosed {
+			d.setState(c, CircuitClosed)
+		}
+		return
+	}
+	c.failures++
+	if c.state == CircuitHalfOpen || c.state == CircuitClosed && c.failures >= d.opts.threshold {
+		c.openedAt = d.opts.now()
+		d.setState(c, CircuitOpen)
+	}
+}
+
+// setState changes the state of c and reports it.
+func (d *breakerDoer) setState(c *circuit, s CircuitState) {
+	if s != CircuitHalfOpen {
+		c.probes = 0
+	}
+	c.state = s
+	setCircuitState(c.labels, s)
+}
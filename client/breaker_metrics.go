@@ -0,0 +1,27 @@
+// +build !js,!appengine
+
+package client
+
+import (
+	"github.com/armon/go-metrics"
+	"github.com/goadesign/goa"
+)
+
+// setCircuitState reports the state of a circuit, labels contains the host, resource and action.
+func setCircuitState(labels []string, s CircuitState) {
+	goa.SetGaugeWithLabels([]string{"goa", "client", "circuit", "state"}, float32(s), circuitLabels(labels))
+}
+
+// incrCircuitRejected counts a request rejected by an open circuit.
+func incrCircuitRejected(labels []string) {
+	goa.IncrCounterWithLabels([]string{"goa", "client", "circuit", "rejected"}, 1.0, circuitLabels(labels))
+}
+
+// circuitLabels returns the metric labels of a circuit.
+func circuitLabels(labels []string) []metrics.Label {
+	return []metrics.Label{
+		{Name: "host", Value: labels[0]},
+		{Name: "resource", Value: labels[1]},
+		{Name: "action", Value: labels[2]},
+	}
+}
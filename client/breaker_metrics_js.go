@@ -0,0 +1,9 @@
+// +build js appengine
+
+package client
+
+// setCircuitState is a no-op, labeled metrics are not supported on this platform.
+func setCircuitState(labels []string, s CircuitState) {}
+
+// incrCircuitRejected is a no-op, labeled metrics are not supported on this platform.
+func incrCircuitRejected(labels []string) {}
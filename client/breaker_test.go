@@ -0,0 +1,151 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// clock is a fake clock used to control the time seen by the circuit breakers.
+type clock struct {
+	t time.Time
+}
+
+func newClock() *clock                   { return &clock{t: time.Unix(1000, 0)} }
+func (c *clock) now() time.Time          { return c.t }
+func (c *clock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+// statuses is a Doer that returns responses with the configured status codes in order, an
+// error if the status code is 0 and 200 once all the status codes have been returned.
+type statuses struct {
+	codes []int
+	calls int
+}
+
+func (s *statuses) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	code := 200
+	if s.calls < len(s.codes) {
+		code = s.codes[s.calls]
+	}
+	s.calls++
+	if code == 0 {
+		return nil, errors.New("connection refused")
+	}
+	return &http.Response{StatusCode: code}, nil
+}
+
+// newBreaker returns a circuit breaker that uses c as clock.
+func newBreaker(doer Doer, c *clock, o ...BreakerOption) *breakerDoer {
+	d := BreakerDoer(doer, o...).(*breakerDoer)
+	d.opts.now = c.now
+	return d
+}
+
+// do makes a request for the given action and returns the response status code or 0 if the
+// request failed.
+func do(d Doer, action string) (int, error) {
+	req, _ := http.NewRequest("GET", "http://example.com/payments", nil)
+	resp, err := d.Do(WithAction(context.Background(), "payment", action), req)
+	if err != nil {
+		return 0, err
+	}
+	return resp.StatusCode, nil
+}
+
+func TestBreakerDoer(t *testing.T) {
+	c := newClock()
+	doer := &statuses{codes: []int{500, 0, 200, 500, 200}}
+	d := newBreaker(doer, c, FailureThreshold(2), OpenTimeout(time.Minute))
+
+	do(d, "list")
+	do(d, "list")
+	if _, err := do(d, "list"); err != ErrCircuitOpen {
+		t.Errorf("open: got error %v, expected ErrCircuitOpen", err)
+	}
+	if doer.calls != 2 {
+		t.Errorf("open: got %d calls, expected 2", doer.calls)
+	}
+	if code, err := do(d, "show"); err != nil || code != 200 {
+		t.Errorf("other action: got status %d and error %v, expected 200 and no error", code, err)
+	}
+
+	c.advance(time.Minute - time.Second)
+	if _, err := do(d, "list"); err != ErrCircuitOpen {
+		t.Errorf("before timeout: got error %v, expected ErrCircuitOpen", err)
+	}
+
+	c.advance(time.Second)
+	if code, err := do(d, "list"); err != nil || code != 500 {
+		t.Errorf("failed probe: got status %d and error %v, expected 500 and no error", code, err)
+	}
+	if _, err := do(d, "list"); err != ErrCircuitOpen {
+		t.Errorf("reopened: got error %v, expected ErrCircuitOpen", err)
+	}
+
+	c.advance(time.Minute)
+	if code, err := do(d, "list"); err != nil || code != 200 {
+		t.Errorf("successful probe: got status %d and error %v, expected 200 and no error", code, err)
+	}
+	if code, err := do(d, "list"); err != nil || code != 200 {
+		t.Errorf("closed: got status %d and error %v, expected 200 and no error", code, err)
+	}
+}
+
+func TestBreakerDoerProbeLimit(t *testing.T) {
+	c := newClock()
+	d := newBreaker(&statuses{codes: []int{500}}, c, FailureThreshold(1), OpenTimeout(time.Minute), HalfOpenProbes(2))
+	do(d, "list")
+
+	c.advance(time.Minute)
+	req, _ := http.NewRequest("GET", "http://example.com/payments", nil)
+	ctx := WithAction(context.Background(), "payment", "list")
+	var probes []*circuit
+	for i := 0; i < 2; i++ {
+		cir, probe, ok := d.acquire(ctx, req)
+		if !ok || !probe {
+			t.Fatalf("probe %d: got probe %v and ok %v, expected true and true", i, probe, ok)
+		}
+		probes = append(probes, cir)
+	}
+	if _, _, ok := d.acquire(ctx, req); ok {
+		t.Errorf("probe limit: the request was let through")
+	}
+	if probes[0].state != CircuitHalfOpen {
+		t.Errorf("probe limit: got state %s, expected half-open", probes[0].state)
+	}
+
+	d.release(probes[0], true, true)
+	if probes[0].state != CircuitClosed {
+		t.Errorf("successful probe: got state %s, expected closed", probes[0].state)
+	}
+	if _, _, ok := d.acquire(ctx, req); !ok {
+		t.Errorf("closed: the request was rejected")
+	}
+}
+
+func TestBreakerDoerReopen(t *testing.T) {
+	c := newClock()
+	doer := &statuses{codes: []int{500, 0}}
+	d := newBreaker(doer, c, FailureThreshold(1), OpenTimeout(time.Minute))
+	do(d, "list")
+
+	c.advance(2 * time.Minute)
+	if _, err := do(d, "list"); err == nil || err == ErrCircuitOpen {
+		t.Fatalf("failed probe: got error %v, expected the transport error", err)
+	}
+
+	// The open timeout starts over when the probe fails.
+	c.advance(time.Minute - time.Second)
+	if _, err := do(d, "list"); err != ErrCircuitOpen {
+		t.Errorf("reopened: got error %v, expected ErrCircuitOpen", err)
+	}
+	c.advance(time.Second)
+	if code, err := do(d, "list"); err != nil || code != 200 {
+		t.Errorf("probe: got status %d and error %v, expected 200 and no error", code, err)
+	}
+	if doer.calls != 3 {
+		t.Errorf("got %d calls, expected 3", doer.calls)
+	}
+}
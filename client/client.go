@@ -33,6 +33,9 @@ type (
 		UserAgent string
 		// Dump indicates whether to dump request response.
 		Dump bool
+
+		// actionDoers contains the doers set with SetActionDoer indexed by resource and action.
+		actionDoers map[string]Doer
 	}
 )
 
@@ -60,6 +63,20 @@ func (f doFunc) Do(ctx context.Context, req *http.Request) (*http.Response, erro
 	return f(ctx, req)
 }
 
+// SetActionDoer sets the doer used to make the requests to the given resource action, the
+// requests must be made with a context initialized with WithAction. This makes it possible to
+// apply different retry or circuit breaker policies to different actions, for example:
+//
+//	c.SetActionDoer("payment", "create", client.RetryDoer(c.Doer, client.RetryNonIdempotent()))
+//
+// SetActionDoer must be called before the client is used to make requests.
+func (c *Client) SetActionDoer(resource, action string, doer Doer) {
+	if c.actionDoers == nil {
+		c.actionDoers = make(map[string]Doer)
+	}
+	c.actionDoers[resource+"#"+action] = doer
+}
+
 // Do wraps the underlying http client Do method and adds logging.
 // The logger should be in the context.
 func (c *Client) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
@@ -77,7 +94,13 @@ func (c *Client) Do(ctx context.Context, req *http.Request) (*http.Response, err
 	if c.Dump {
 		c.dumpRequest(ctx, req)
 	}
-	resp, err := c.Doer.Do(ctx, req)
+	doer := c.Doer
+	if resource, action := ContextAction(ctx); action != "" {
+		if d, ok := c.actionDoers[resource+"#"+action]; ok {
+			doer = d
+		}
+	}
+	resp, err := doer.Do(ctx, req)
 	if err != nil {
 		goa.LogError(ctx, "failed", "err", err)
 		return nil, err
@@ -221,8 +244,26 @@ func shortID() string {
 // It is private to avoid possible collisions with keys used by other packages.
 type clientKey int
 
-// ReqIDKey is the context key used to store the request ID value.
-const reqIDKey clientKey = 1
+const (
+	// ReqIDKey is the context key used to store the request ID value.
+	reqIDKey clientKey = iota + 1
+	// actionKey is the context key used to store the resource and action names.
+	actionKey
+)
+
+// WithAction returns a context that records the names of the resource and action the request
+// made with it targets. The generated clients initialize the context of each request with it.
+func WithAction(ctx context.Context, resource, action string) context.Context {
+	return context.WithValue(ctx, actionKey, [2]string{resource, action})
+}
+
+// ContextAction returns the names of the resource and action recorded in ctx with WithAction.
+func ContextAction(ctx context.Context) (resource, action string) {
+	if a, ok := ctx.Value(actionKey).([2]string); ok {
+		return a[0], a[1]
+	}
+	return "", ""
+}
 
 // ContextRequestID extracts the Request ID from the context.
 func ContextRequestID(ctx context.Context) string {
@@ -0,0 +1,214 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type (
+	// RetryOption allows to override the default retry parameters.
+	RetryOption func(*retryOptions) error
+
+	// retryOptions contains the final retry parameters.
+	retryOptions struct {
+		attempts      int
+		base, max     time.Duration
+		statuses      map[int]bool
+		nonIdempotent bool
+		sleep         func(context.Context, time.Duration) error
+		rand          func(int64) int64
+	}
+
+	// retryDoer is a Doer that retries failed requests.
+	retryDoer struct {
+		Doer
+		opts *retryOptions
+	}
+)
+
+// idempotentMethods lists the HTTP methods whose requests may be retried safely, see RFC 9110
+// section 9.2.2.
+var idempotentMethods = map[string]bool{
+	"GET":     true,
+	"HEAD":    true,
+	"OPTIONS": true,
+	"TRACE":   true,
+	"PUT":     true,
+	"DELETE":  true,
+}
+
+// MaxAttempts sets the maximum number of times a request is sent including the first attempt,
+// the default is 3.
+func MaxAttempts(n int) RetryOption {
+	return func(o *retryOptions) error {
+		if n < 1 {
+			return fmt.Errorf("client: invalid max attempts %d", n)
+		}
+		o.attempts = n
+		return nil
+	}
+}
+
+// Backoff sets the base and maximum delays between attempts, the defaults are 100ms and 10s.
+// The delay before the nth retry is chosen randomly between 0 and min(max, base*2^(n-1)).
+func Backoff(base, max time.Duration) RetryOption {
+	return func(o *retryOptions) error {
+		if base <= 0 || max < base {
+			return fmt.Errorf("client: invalid backoff %s-%s", base, max)
+		}
+		o.base, o.max = base, max
+		return nil
+	}
+}
+
+// RetryStatuses sets the response status codes that cause a request to be retried, the defaults
+// are 429, 502, 503 and 504.
+func RetryStatuses(statuses ...int) RetryOption {
+	return func(o *retryOptions) error {
+		o.statuses = make(map[int]bool, len(statuses))
+		for _, s := range statuses {
+			o.statuses[s] = true
+		}
+		return nil
+	}
+}
+
+// RetryNonIdempotent causes requests made with methods that are not idempotent such as POST to
+// be retried as well. Requests that include an Idempotency-Key header are always retried.
+func RetryNonIdempotent() RetryOption {
+	return func(o *retryOptions) error {
+		o.nonIdempotent = true
+		return nil
+	}
+}
+
+// RetryDoer wraps doer and retries the requests that fail with a transport error or with one of
+// the retry statuses using exponential backoff with full jitter. A Retry-After response header
+// overrides the backoff delay, the response is returned as is if the header requests a delay
+// longer than the maximum backoff delay. Only the requests made with idempotent methods are
+// retried unless RetryNonIdempotent is used. Requests with a body are retried only if their
+// GetBody field is set, which is the case of requests created with http.NewRequest.
+//
+// RetryDoer may be combined with BreakerDoer so that each attempt is accounted for by the
+// circuit breaker, requests rejected by an open circuit are not retried:
+//
+//	doer := client.RetryDoer(client.BreakerDoer(client.HTTPClientDoer(http.DefaultClient)))
+func RetryDoer(doer Doer, o ...RetryOption) Doer {
+	opts := &retryOptions{
+		attempts: 3,
+		base:     100 * time.Millisecond,
+		max:      10 * time.Second,
+		statuses: map[int]bool{429: true, 502: true, 503: true, 504: true},
+		sleep:    sleep,
+		rand:     rand.Int63n,
+	}
+	for _, opt := range o {
+		if err := opt(opts); err != nil {
+			panic(err)
+		}
+	}
+	return &retryDoer{Doer: doer, opts: opts}
+}
+
+// Do implements Doer.
+func (d *retryDoer) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	if !d.retryable(req) {
+		return d.Doer.Do(ctx, req)
+	}
+	for attempt := 1; ; attempt++ {
+		resp, err := d.Doer.Do(ctx, req)
+		if attempt == d.opts.attempts || ctx.Err() != nil {
+			return resp, err
+		}
+		var delay time.Duration
+		switch {
+		case err == ErrCircuitOpen:
+			return resp, err
+		case err != nil:
+			delay = d.backoff(attempt)
+		case d.opts.statuses[resp.StatusCode]:
+			delay = d.backoff(attempt)
+			if after, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+				if after > d.opts.max {
+					return resp, nil
+				}
+				delay = after
+			}
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		default:
+			return resp, nil
+		}
+		if err := d.opts.sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+		if req.Body != nil && req.Body != http.NoBody {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+	}
+}
+
+// retryable returns true if req may be retried.
+func (d *retryDoer) retryable(req *http.Request) bool {
+	if d.opts.attempts < 2 {
+		return false
+	}
+	if !idempotentMethods[req.Method] && !d.opts.nonIdempotent && req.Header.Get("Idempotency-Key") == "" {
+		return false
+	}
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// backoff returns the delay before the given retry.
+func (d *retryDoer) backoff(attempt int) time.Duration {
+	ceil := d.opts.max
+	if attempt < 32 {
+		if b := d.opts.base << uint(attempt-1); b > 0 && b < ceil {
+			ceil = b
+		}
+	}
+	return time.Duration(d.opts.rand(int64(ceil) + 1))
+}
+
+// retryAfter parses the value of a Retry-After header, it may be a number of seconds or a date.
+func retryAfter(val string) (time.Duration, bool) {
+	if val == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(val); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	t, err := http.ParseTime(val)
+	if err != nil {
+		return 0, false
+	}
+	if d := time.Until(t); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
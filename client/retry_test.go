@@ -0,0 +1,197 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/goadesign/goa/client"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// responses is a Doer that returns the configured responses in order and records the bodies of
+// the requests it receives.
+type responses struct {
+	statuses []int
+	errs     []error
+	header   http.Header
+	bodies   []string
+}
+
+func (r *responses) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	i := len(r.bodies)
+	var body []byte
+	if req.Body != nil {
+		body, _ = ioutil.ReadAll(req.Body)
+	}
+	r.bodies = append(r.bodies, string(body))
+	if i < len(r.errs) && r.errs[i] != nil {
+		return nil, r.errs[i]
+	}
+	status := 200
+	if i < len(r.statuses) {
+		status = r.statuses[i]
+	}
+	return &http.Response{StatusCode: status, Header: r.header, Body: ioutil.NopCloser(&bytes.Buffer{})}, nil
+}
+
+var _ = Describe("RetryDoer", func() {
+	var doer *responses
+	var method string
+	var header http.Header
+	var options []client.RetryOption
+
+	var resp *http.Response
+	var err error
+
+	BeforeEach(func() {
+		doer = &responses{header: http.Header{}}
+		method = "GET"
+		header = nil
+		options = []client.RetryOption{client.Backoff(time.Millisecond, 5*time.Millisecond)}
+	})
+
+	JustBeforeEach(func() {
+		req, _ := http.NewRequest(method, "http://example.com/payments", bytes.NewBufferString("payload"))
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		resp, err = client.RetryDoer(doer, options...).Do(context.Background(), req)
+	})
+
+	Context("with a successful request", func() {
+		It("sends the request once", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(resp.StatusCode).Should(Equal(200))
+			Ω(doer.bodies).Should(HaveLen(1))
+		})
+	})
+
+	Context("with transient failures", func() {
+		BeforeEach(func() {
+			doer.errs = []error{errors.New("connection reset")}
+			doer.statuses = []int{0, 503, 201}
+		})
+
+		It("retries the request with its body", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(resp.StatusCode).Should(Equal(201))
+			Ω(doer.bodies).Should(Equal([]string{"payload", "payload", "payload"}))
+		})
+	})
+
+	Context("with too many failures", func() {
+		BeforeEach(func() {
+			doer.statuses = []int{503, 503, 503, 200}
+		})
+
+		It("returns the last response", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(resp.StatusCode).Should(Equal(503))
+			Ω(doer.bodies).Should(HaveLen(3))
+		})
+	})
+
+	Context("with a status that is not retried", func() {
+		BeforeEach(func() {
+			doer.statuses = []int{500}
+		})
+
+		It("returns the response", func() {
+			Ω(resp.StatusCode).Should(Equal(500))
+			Ω(doer.bodies).Should(HaveLen(1))
+		})
+	})
+
+	Context("with a non idempotent request", func() {
+		BeforeEach(func() {
+			method = "POST"
+			doer.statuses = []int{503, 200}
+		})
+
+		It("does not retry", func() {
+			Ω(resp.StatusCode).Should(Equal(503))
+			Ω(doer.bodies).Should(HaveLen(1))
+		})
+
+		Context("with an idempotency key", func() {
+			BeforeEach(func() {
+				header = http.Header{"Idempotency-Key": {"k"}}
+			})
+
+			It("retries", func() {
+				Ω(resp.StatusCode).Should(Equal(200))
+				Ω(doer.bodies).Should(HaveLen(2))
+			})
+		})
+
+		Context("configured to retry", func() {
+			BeforeEach(func() {
+				options = append(options, client.RetryNonIdempotent())
+			})
+
+			It("retries", func() {
+				Ω(resp.StatusCode).Should(Equal(200))
+				Ω(doer.bodies).Should(HaveLen(2))
+			})
+		})
+	})
+
+	Context("with a Retry-After header", func() {
+		BeforeEach(func() {
+			doer.statuses = []int{429, 200}
+		})
+
+		Context("within the maximum delay", func() {
+			BeforeEach(func() {
+				doer.header.Set("Retry-After", "0")
+			})
+
+			It("retries", func() {
+				Ω(resp.StatusCode).Should(Equal(200))
+			})
+		})
+
+		Context("exceeding the maximum delay", func() {
+			BeforeEach(func() {
+				doer.header.Set("Retry-After", "120")
+			})
+
+			It("returns the response", func() {
+				Ω(resp.StatusCode).Should(Equal(429))
+				Ω(doer.bodies).Should(HaveLen(1))
+			})
+		})
+	})
+
+	Context("with an open circuit", func() {
+		BeforeEach(func() {
+			doer.errs = []error{client.ErrCircuitOpen}
+		})
+
+		It("does not retry", func() {
+			Ω(err).Should(Equal(client.ErrCircuitOpen))
+			Ω(doer.bodies).Should(HaveLen(1))
+		})
+	})
+})
+
+var _ = Describe("Client", func() {
+	It("uses the doer set for the action", func() {
+		def, create := &responses{}, &responses{}
+		c := client.New(def)
+		c.SetActionDoer("payment", "create", create)
+		req, _ := http.NewRequest("POST", "http://example.com/payments", nil)
+		_, err := c.Do(client.WithAction(context.Background(), "payment", "create"), req)
+		Ω(err).ShouldNot(HaveOccurred())
+		_, err = c.Do(client.WithAction(context.Background(), "payment", "list"), req)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(create.bodies).Should(HaveLen(1))
+		Ω(def.bodies).Should(HaveLen(1))
+	})
+})
@@ -1,8 +1,16 @@
 package client
 
 import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"strings"
+	"time"
 )
 
 type (
@@ -50,6 +58,21 @@ type (
 		TokenSource TokenSource
 	}
 
+	// HTTPSignatureSigner signs the requests with HMAC-SHA256 as described in
+	// draft-cavage-http-signatures. The signature is set in the "Signature" header, the signer
+	// also sets the "Date" header if missing and the "Digest" header containing the SHA-256 hash
+	// of the body when "digest" is one of the signed headers.
+	HTTPSignatureSigner struct {
+		// KeyID identifies the secret used to sign the requests.
+		KeyID string
+		// Secret is the secret shared with the service.
+		Secret []byte
+		// Headers lists the headers covered by the signature, "(request-target)" stands
+		// for the request method and path. The default is "(request-target)", "host",
+		// "date" and "digest".
+		Headers []string
+	}
+
 	// Token is the interface to an OAuth2 token implementation.
 	// It can be implemented with https://godoc.org/golang.org/x/oauth2#Token.
 	Token interface {
@@ -120,6 +143,81 @@ func (s *OAuth2Signer) Sign(req *http.Request) error {
 	return signFromSource(s.TokenSource, req)
 }
 
+// Sign adds the Date, Digest and Signature headers to the request.
+func (s *HTTPSignatureSigner) Sign(req *http.Request) error {
+	if s.KeyID == "" || strings.ContainsAny(s.KeyID, "\"\\") {
+		return fmt.Errorf("invalid HTTP signature key ID %q", s.KeyID)
+	}
+	headers := s.Headers
+	if len(headers) == 0 {
+		headers = []string{"(request-target)", "host", "date", "digest"}
+	}
+	if req.Header.Get("Date") == "" {
+		req.Header.Set("Date", time.Now().UTC().Format(http.TimeFormat))
+	}
+	names := make([]string, len(headers))
+	lines := make([]string, len(headers))
+	for i, h := range headers {
+		name := strings.ToLower(h)
+		var val string
+		switch name {
+		case "(request-target)":
+			val = strings.ToLower(req.Method) + " " + req.URL.RequestURI()
+		case "host":
+			val = req.Host
+			if val == "" {
+				val = req.URL.Host
+			}
+		case "digest":
+			digest, err := bodyDigest(req)
+			if err != nil {
+				return err
+			}
+			val = "SHA-256=" + digest
+			req.Header.Set("Digest", val)
+		default:
+			vals := req.Header[http.CanonicalHeaderKey(name)]
+			if len(vals) == 0 {
+				return fmt.Errorf("cannot sign missing header %q", name)
+			}
+			val = strings.Join(vals, ", ")
+		}
+		names[i] = name
+		lines[i] = name + ": " + strings.TrimSpace(val)
+	}
+	mac := hmac.New(sha256.New, s.Secret)
+	mac.Write([]byte(strings.Join(lines, "\n")))
+	req.Header.Set("Signature", fmt.Sprintf(`keyId="%s",algorithm="hmac-sha256",headers="%s",signature="%s"`,
+		s.KeyID, strings.Join(names, " "), base64.StdEncoding.EncodeToString(mac.Sum(nil))))
+	return nil
+}
+
+// bodyDigest returns the base64 encoded SHA-256 hash of the request body, the body is left
+// untouched.
+func bodyDigest(req *http.Request) (string, error) {
+	var body []byte
+	if req.Body != nil && req.Body != http.NoBody {
+		var err error
+		if req.GetBody != nil {
+			var rc io.ReadCloser
+			if rc, err = req.GetBody(); err != nil {
+				return "", err
+			}
+			body, err = ioutil.ReadAll(rc)
+			rc.Close()
+		} else {
+			body, err = ioutil.ReadAll(req.Body)
+			req.Body.Close()
+			req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+	sum := sha256.Sum256(body)
+	return base64.StdEncoding.EncodeToString(sum[:]), nil
+}
+
 // signFromSource generates a token using the given source and uses it to sign the request.
 func signFromSource(source TokenSource, req *http.Request) error {
 	token, err := source.Token()
@@ -0,0 +1,115 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goadesign/goa"
+)
+
+type (
+	// EventReader reads server-sent events from a text/event-stream response body as described
+	// in https://html.spec.whatwg.org/multipage/server-sent-events.html.
+	EventReader struct {
+		scanner     *bufio.Scanner
+		lastEventID string
+	}
+
+	// EventReaderOption allows to override the default event reader parameters.
+	EventReaderOption func(*eventReaderOptions) error
+
+	// eventReaderOptions contains the final event reader parameters.
+	eventReaderOptions struct {
+		maxLineSize int
+	}
+)
+
+// MaxEventLineSize sets the maximum size in bytes of a line of the event stream, the default is
+// 1MB. Next returns bufio.ErrTooLong when reading longer lines.
+func MaxEventLineSize(n int) EventReaderOption {
+	return func(o *eventReaderOptions) error {
+		if n < 1 {
+			return fmt.Errorf("client: invalid max event line size %d", n)
+		}
+		o.maxLineSize = n
+		return nil
+	}
+}
+
+// NewEventReader returns a reader that reads events from r.
+func NewEventReader(r io.Reader, o ...EventReaderOption) *EventReader {
+	opts := eventReaderOptions{maxLineSize: 1 << 20}
+	for _, opt := range o {
+		if err := opt(&opts); err != nil {
+			panic(err)
+		}
+	}
+	scanner := bufio.NewScanner(r)
+	initial := bufio.MaxScanTokenSize
+	if opts.maxLineSize < initial {
+		initial = opts.maxLineSize
+	}
+	scanner.Buffer(make([]byte, initial), opts.maxLineSize)
+	return &EventReader{scanner: scanner}
+}
+
+// Next blocks until the next event is received and returns it. Comments such as keep-alive
+// messages are skipped. Next returns io.EOF once the stream ends.
+func (r *EventReader) Next() (*goa.Event, error) {
+	var (
+		ev      goa.Event
+		data    bytes.Buffer
+		hasData bool
+	)
+	for r.scanner.Scan() {
+		line := r.scanner.Text()
+		if line == "" {
+			if !hasData {
+				// Dispatch only events with data, reset otherwise.
+				ev = goa.Event{}
+				continue
+			}
+			ev.ID = r.lastEventID
+			ev.Data = bytes.TrimSuffix(data.Bytes(), []byte("\n"))
+			return &ev, nil
+		}
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value := line, ""
+		if i := strings.Index(line, ":"); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+		switch field {
+		case "event":
+			ev.Event = value
+		case "data":
+			data.WriteString(value)
+			data.WriteByte('\n')
+			hasData = true
+		case "id":
+			if !strings.Contains(value, "\x00") {
+				r.lastEventID = value
+			}
+		case "retry":
+			if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+				ev.Retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+// LastEventID returns the ID of the last event read, clients should send it in the Last-Event-ID
+// header when reconnecting.
+func (r *EventReader) LastEventID() string {
+	return r.lastEventID
+}
@@ -0,0 +1,78 @@
+package client_test
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/goadesign/goa/client"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EventReader", func() {
+	var body string
+	var options []client.EventReaderOption
+	var reader *client.EventReader
+
+	BeforeEach(func() {
+		options = nil
+	})
+
+	JustBeforeEach(func() {
+		reader = client.NewEventReader(strings.NewReader(body), options...)
+	})
+
+	Context("with a stream of events", func() {
+		BeforeEach(func() {
+			body = ": keep-alive\n\n" +
+				"id: 1\nevent: update\nretry: 1000\ndata: foo\ndata: bar\n\n" +
+				"event: ignored\n\n" +
+				"data:baz\r\n\r\n"
+		})
+
+		It("reads the events", func() {
+			ev, err := reader.Next()
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(ev.ID).Should(Equal("1"))
+			Ω(ev.Event).Should(Equal("update"))
+			Ω(ev.Retry).Should(Equal(time.Second))
+			Ω(string(ev.Data)).Should(Equal("foo\nbar"))
+
+			ev, err = reader.Next()
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(ev.ID).Should(Equal("1"))
+			Ω(ev.Event).Should(BeEmpty())
+			Ω(string(ev.Data)).Should(Equal("baz"))
+			Ω(reader.LastEventID()).Should(Equal("1"))
+
+			_, err = reader.Next()
+			Ω(err).Should(Equal(io.EOF))
+		})
+	})
+
+	Context("with lines longer than 64KB", func() {
+		BeforeEach(func() {
+			body = "data: " + strings.Repeat("a", 100*1024) + "\n\n"
+		})
+
+		It("reads the events", func() {
+			ev, err := reader.Next()
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(ev.Data).Should(HaveLen(100 * 1024))
+		})
+
+		Context("exceeding the max line size", func() {
+			BeforeEach(func() {
+				options = []client.EventReaderOption{client.MaxEventLineSize(1024)}
+			})
+
+			It("fails", func() {
+				_, err := reader.Next()
+				Ω(err).Should(Equal(bufio.ErrTooLong))
+			})
+		})
+	})
+})
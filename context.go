@@ -1,6 +1,7 @@
 package goa
 
 import (
+	"bytes"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -19,6 +20,10 @@ const (
 	logContextKey
 	errKey
 	securityScopesKey
+	routeKey
+	principalKey
+	muxHandlesKey
+	encodedBodyKey
 )
 
 type (
@@ -71,6 +76,12 @@ func WithAction(ctx context.Context, action string) context.Context {
 	return context.WithValue(ctx, actionKey, action)
 }
 
+// WithRoute creates a context with the given route path. The route path is the path given to
+// ServeMux.Handle, for example "/bottles/:id".
+func WithRoute(ctx context.Context, route string) context.Context {
+	return context.WithValue(ctx, routeKey, route)
+}
+
 // WithLogger sets the request context logger and returns the resulting new context.
 func WithLogger(ctx context.Context, logger LogAdapter) context.Context {
 	return context.WithValue(ctx, logKey, logger)
@@ -108,6 +119,15 @@ func ContextAction(ctx context.Context) string {
 	return "<unknown>"
 }
 
+// ContextRoute extracts the path of the route that matched the request from the given context,
+// the empty string if there is none.
+func ContextRoute(ctx context.Context) string {
+	if r := ctx.Value(routeKey); r != nil {
+		return r.(string)
+	}
+	return ""
+}
+
 // ContextRequest extracts the request data from the given context.
 func ContextRequest(ctx context.Context) *RequestData {
 	if r := ctx.Value(reqKey); r != nil {
@@ -140,6 +160,17 @@ func ContextError(ctx context.Context) error {
 	return nil
 }
 
+// ContextEncodedBody returns the bytes of the request body as sent by the client when the body
+// content coding was decoded using the service ContentDecoders, nil otherwise. The bytes are
+// those read by the content decoders so far, the body is thus complete once the decoded body has
+// been read.
+func ContextEncodedBody(ctx context.Context) []byte {
+	if b := ctx.Value(encodedBodyKey); b != nil {
+		return b.(*bytes.Buffer).Bytes()
+	}
+	return nil
+}
+
 // SwitchWriter overrides the underlying response writer. It returns the response
 // writer that was previously set.
 func (r *ResponseData) SwitchWriter(rw http.ResponseWriter) http.ResponseWriter {
@@ -160,6 +191,13 @@ func (r *ResponseData) WriteHeader(status int) {
 	r.ResponseWriter.WriteHeader(status)
 }
 
+// Flush sends any buffered data to the client if the underlying writer supports it.
+func (r *ResponseData) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
 // Write records the amount of data written and calls the underlying writer.
 func (r *ResponseData) Write(b []byte) (int, error) {
 	if !r.Written() {
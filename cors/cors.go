@@ -42,6 +42,9 @@ func MatchOrigin(origin, spec string) bool {
 		return origin == spec
 	}
 	parts := strings.SplitN(spec, "*", 2)
+	if len(origin) < len(parts[0])+len(parts[1]) {
+		return false
+	}
 	if !strings.HasPrefix(origin, parts[0]) {
 		return false
 	}
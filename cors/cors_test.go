@@ -19,6 +19,9 @@ func TestMatchOrigin(t *testing.T) {
 		{"http://test.example.com", "*.example.com", true},
 		{"http://test.example.com:80", "*.example.com", false},
 		{"http://test.example.com:80", "http://test.example.com*", true},
+		{"http://example.com", "http://example*example.com", false},
+		{"https://a.b.example.com", "https://*.example.com", true},
+		{"https://example.com", "https://*.example.com", false},
 	}
 
 	for _, test := range data {
@@ -0,0 +1,166 @@
+package cors
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/goadesign/goa"
+)
+
+// Policy describes how the middleware handles requests whose origin matches the policy Origin.
+// The fields mirror the CORS definitions of the design so that generated code may initialize
+// policies from the design while services may still override them at startup.
+type Policy struct {
+	// Origin is the origin specification, see MatchOrigin.
+	Origin string
+	// Regexp indicates whether Origin is a regular expression.
+	Regexp bool
+	// Headers lists the request headers allowed in preflight requests, "*" allows all.
+	Headers []string
+	// Methods lists the HTTP methods allowed in preflight requests, "*" allows all.
+	Methods []string
+	// Exposed lists the response headers exposed to clients.
+	Exposed []string
+	// MaxAge is the number of seconds preflight responses may be cached.
+	MaxAge uint
+	// Credentials sets the Access-Control-Allow-Credentials header.
+	Credentials bool
+	// PrivateNetwork allows access from public networks in response to private network
+	// access preflight requests.
+	PrivateNetwork bool
+}
+
+// CORS request and response headers.
+const (
+	headerOrigin                     = "Origin"
+	headerVary                       = "Vary"
+	headerAllowOrigin                = "Access-Control-Allow-Origin"
+	headerAllowCredentials           = "Access-Control-Allow-Credentials"
+	headerAllowMethods               = "Access-Control-Allow-Methods"
+	headerAllowHeaders               = "Access-Control-Allow-Headers"
+	headerAllowPrivateNetwork        = "Access-Control-Allow-Private-Network"
+	headerExposeHeaders              = "Access-Control-Expose-Headers"
+	headerMaxAge                     = "Access-Control-Max-Age"
+	headerRequestMethod              = "Access-Control-Request-Method"
+	headerRequestHeaders             = "Access-Control-Request-Headers"
+	headerRequestPrivateNetwork      = "Access-Control-Request-Private-Network"
+	headerRequestPrivateNetworkValue = "true"
+)
+
+// matcher is a policy with its origin specification compiled.
+type matcher struct {
+	*Policy
+	re *regexp.Regexp
+}
+
+// Middleware returns a middleware that applies the first policy whose origin matches the request
+// Origin header. The middleware sets the Access-Control-* response headers and adds Origin to the
+// Vary header unless all the policies accept any origin without credentials. Preflight requests
+// are identified by the presence of the Access-Control-Request-Method header, they are passed to
+// the next handler (typically HandlePreflight) after the headers have been set. Requests whose
+// origin does not match any policy are passed through without CORS headers. Middleware panics if
+// a policy origin is an invalid regular expression.
+func Middleware(policies ...*Policy) goa.Middleware {
+	matchers := make([]*matcher, len(policies))
+	vary := false
+	for i, p := range policies {
+		m := &matcher{Policy: p}
+		if p.Regexp {
+			m.re = regexp.MustCompile(p.Origin)
+		} else if strings.HasPrefix(p.Origin, "/") && strings.HasSuffix(p.Origin, "/") && len(p.Origin) > 1 {
+			m.re = regexp.MustCompile(strings.Trim(p.Origin, "/"))
+		}
+		if p.Origin != "*" || p.Credentials {
+			vary = true
+		}
+		matchers[i] = m
+	}
+	return func(h goa.Handler) goa.Handler {
+		return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			if vary {
+				addVary(rw.Header(), headerOrigin)
+			}
+			origin := req.Header.Get(headerOrigin)
+			if origin == "" {
+				// Not a CORS request
+				return h(ctx, rw, req)
+			}
+			for _, m := range matchers {
+				if !m.match(origin) {
+					continue
+				}
+				ctx = goa.WithLogContext(ctx, "origin", origin)
+				m.apply(rw.Header(), req, origin)
+				return h(ctx, rw, req)
+			}
+			return h(ctx, rw, req)
+		}
+	}
+}
+
+// match returns true if the origin matches the policy.
+func (m *matcher) match(origin string) bool {
+	if m.re != nil {
+		return MatchOriginRegexp(origin, m.re)
+	}
+	return MatchOrigin(origin, m.Origin)
+}
+
+// apply sets the CORS response headers for the given request.
+func (m *matcher) apply(header http.Header, req *http.Request, origin string) {
+	if m.Origin == "*" && !m.Credentials {
+		header.Set(headerAllowOrigin, "*")
+	} else {
+		header.Set(headerAllowOrigin, origin)
+	}
+	if m.Credentials {
+		header.Set(headerAllowCredentials, "true")
+	}
+	if len(m.Exposed) > 0 {
+		header.Set(headerExposeHeaders, strings.Join(m.Exposed, ", "))
+	}
+	acrm := req.Header.Get(headerRequestMethod)
+	if acrm == "" {
+		return
+	}
+
+	// We are handling a preflight request
+	if len(m.Methods) > 0 {
+		methods := strings.Join(m.Methods, ", ")
+		if methods == "*" && m.Credentials {
+			// The wildcard is not honored by user agents for credentialed requests.
+			methods = acrm
+		}
+		header.Set(headerAllowMethods, methods)
+	}
+	if len(m.Headers) > 0 {
+		headers := strings.Join(m.Headers, ", ")
+		if headers == "*" && m.Credentials {
+			headers = req.Header.Get(headerRequestHeaders)
+		}
+		if headers != "" {
+			header.Set(headerAllowHeaders, headers)
+		}
+	}
+	if m.MaxAge > 0 {
+		header.Set(headerMaxAge, strconv.FormatUint(uint64(m.MaxAge), 10))
+	}
+	if m.PrivateNetwork && req.Header.Get(headerRequestPrivateNetwork) == headerRequestPrivateNetworkValue {
+		header.Set(headerAllowPrivateNetwork, "true")
+	}
+}
+
+// addVary adds the given header name to the Vary response header unless already present.
+func addVary(header http.Header, name string) {
+	for _, v := range header[headerVary] {
+		for _, n := range strings.Split(v, ",") {
+			if n = strings.TrimSpace(n); n == "*" || strings.EqualFold(n, name) {
+				return
+			}
+		}
+	}
+	header.Add(headerVary, name)
+}
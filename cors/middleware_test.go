@@ -0,0 +1,157 @@
+package cors_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/goadesign/goa/cors"
+)
+
+func TestMiddleware(t *testing.T) {
+	var (
+		credentialed = &cors.Policy{
+			Origin:      "https://*.example.com",
+			Headers:     []string{"*"},
+			Methods:     []string{"*"},
+			Exposed:     []string{"X-One", "X-Two"},
+			MaxAge:      600,
+			Credentials: true,
+		}
+		pattern = &cors.Policy{
+			Origin:         "^https://(here|there)\\.goa\\.design$",
+			Regexp:         true,
+			Headers:        []string{"X-Custom"},
+			Methods:        []string{"GET", "POST"},
+			PrivateNetwork: true,
+		}
+		wildcard = &cors.Policy{
+			Origin:  "*",
+			Methods: []string{"GET"},
+		}
+	)
+
+	cases := map[string]struct {
+		Policies []*cors.Policy
+		Method   string
+		Header   map[string]string
+		// output
+		Expected map[string]string
+	}{
+		"not-cors": {[]*cors.Policy{credentialed}, "GET", nil, map[string]string{
+			"Access-Control-Allow-Origin": "",
+			"Vary":                        "Origin",
+		}},
+		"no-match": {[]*cors.Policy{credentialed}, "GET", map[string]string{"Origin": "https://example.com"}, map[string]string{
+			"Access-Control-Allow-Origin": "",
+			"Vary":                        "Origin",
+		}},
+		"credentials": {[]*cors.Policy{credentialed}, "GET", map[string]string{"Origin": "https://api.example.com"}, map[string]string{
+			"Access-Control-Allow-Origin":      "https://api.example.com",
+			"Access-Control-Allow-Credentials": "true",
+			"Access-Control-Expose-Headers":    "X-One, X-Two",
+			"Access-Control-Allow-Methods":     "",
+			"Access-Control-Max-Age":           "",
+			"Vary":                             "Origin",
+		}},
+		"credentials-preflight": {[]*cors.Policy{credentialed}, "OPTIONS", map[string]string{
+			"Origin":                         "https://api.example.com",
+			"Access-Control-Request-Method":  "PUT",
+			"Access-Control-Request-Headers": "X-Custom, Authorization",
+		}, map[string]string{
+			"Access-Control-Allow-Origin":      "https://api.example.com",
+			"Access-Control-Allow-Credentials": "true",
+			"Access-Control-Allow-Methods":     "PUT",
+			"Access-Control-Allow-Headers":     "X-Custom, Authorization",
+			"Access-Control-Max-Age":           "600",
+		}},
+		"regexp": {[]*cors.Policy{credentialed, pattern}, "GET", map[string]string{"Origin": "https://there.goa.design"}, map[string]string{
+			"Access-Control-Allow-Origin":      "https://there.goa.design",
+			"Access-Control-Allow-Credentials": "",
+			"Vary":                             "Origin",
+		}},
+		"regexp-no-match": {[]*cors.Policy{pattern}, "GET", map[string]string{"Origin": "https://elsewhere.goa.design"}, map[string]string{
+			"Access-Control-Allow-Origin": "",
+		}},
+		"regexp-preflight": {[]*cors.Policy{pattern}, "OPTIONS", map[string]string{
+			"Origin":                        "https://here.goa.design",
+			"Access-Control-Request-Method": "POST",
+		}, map[string]string{
+			"Access-Control-Allow-Origin":          "https://here.goa.design",
+			"Access-Control-Allow-Methods":         "GET, POST",
+			"Access-Control-Allow-Headers":         "X-Custom",
+			"Access-Control-Allow-Private-Network": "",
+		}},
+		"private-network": {[]*cors.Policy{pattern}, "OPTIONS", map[string]string{
+			"Origin":                                 "https://here.goa.design",
+			"Access-Control-Request-Method":          "GET",
+			"Access-Control-Request-Private-Network": "true",
+		}, map[string]string{
+			"Access-Control-Allow-Private-Network": "true",
+		}},
+		"private-network-denied": {[]*cors.Policy{credentialed}, "OPTIONS", map[string]string{
+			"Origin":                                 "https://api.example.com",
+			"Access-Control-Request-Method":          "GET",
+			"Access-Control-Request-Private-Network": "true",
+		}, map[string]string{
+			"Access-Control-Allow-Private-Network": "",
+		}},
+		"any": {[]*cors.Policy{wildcard}, "GET", map[string]string{"Origin": "https://example.com"}, map[string]string{
+			"Access-Control-Allow-Origin":      "*",
+			"Access-Control-Allow-Credentials": "",
+			"Vary":                             "",
+		}},
+	}
+
+	for k, c := range cases {
+		called := false
+		h := func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			called = true
+			return nil
+		}
+		req := httptest.NewRequest(c.Method, "/bottles", nil)
+		for n, v := range c.Header {
+			req.Header.Set(n, v)
+		}
+		rw := httptest.NewRecorder()
+
+		if err := cors.Middleware(c.Policies...)(h)(context.Background(), rw, req); err != nil {
+			t.Errorf("%s: unexpected error %s", k, err)
+		}
+
+		if !called {
+			t.Errorf("%s: handler not called", k)
+		}
+		for n, v := range c.Expected {
+			if actual := rw.Header().Get(n); actual != v {
+				t.Errorf("%s: invalid %s header, expected %q - got %q", k, n, v, actual)
+			}
+		}
+	}
+}
+
+func TestMiddlewareVary(t *testing.T) {
+	h := func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		return nil
+	}
+	req := httptest.NewRequest("GET", "/bottles", nil)
+	req.Header.Set("Origin", "https://goa.design")
+	rw := httptest.NewRecorder()
+	rw.Header().Set("Vary", "Accept-Encoding, origin")
+
+	cors.Middleware(&cors.Policy{Origin: "https://goa.design"})(h)(context.Background(), rw, req)
+
+	if vary := rw.Header()["Vary"]; len(vary) != 1 || vary[0] != "Accept-Encoding, origin" {
+		t.Errorf("invalid Vary header %v", vary)
+	}
+}
+
+func TestMiddlewareInvalidRegexp(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	cors.Middleware(&cors.Policy{Origin: "(", Regexp: true})
+}
@@ -127,6 +127,9 @@ var (
 	// Gob by default.
 	GobContentTypes = []string{"application/gob", "application/x-gob"}
 
+	// EventStreamMediaType is the media type of the responses that stream server-sent events.
+	EventStreamMediaType = "text/event-stream"
+
 	// ErrorMediaIdentifier is the media type identifier used for error responses.
 	ErrorMediaIdentifier = "application/vnd.goa.error"
 
@@ -185,6 +188,77 @@ var (
 		AttributeDefinition: &AttributeDefinition{Type: errorMediaType},
 		Name:                "default",
 	}
+
+	// HealthLivenessPath is the request path of the liveness endpoint mounted by APIs that use
+	// HealthCheck.
+	HealthLivenessPath = "/healthz"
+
+	// HealthReadinessPath is the request path of the readiness endpoint mounted by APIs that use
+	// HealthCheck.
+	HealthReadinessPath = "/readyz"
+
+	// HealthReportMediaIdentifier is the media type identifier used for health check responses.
+	HealthReportMediaIdentifier = "application/vnd.goa.health-report"
+
+	// HealthReportMedia is the built-in media type for health check responses.
+	HealthReportMedia = &MediaTypeDefinition{
+		UserTypeDefinition: &UserTypeDefinition{
+			AttributeDefinition: &AttributeDefinition{
+				Type:        healthReportMediaType,
+				Description: "Health check response media type",
+				Validation:  &dslengine.ValidationDefinition{Required: []string{"status"}},
+				Example: map[string]interface{}{
+					"status": "fail",
+					"checks": map[string]interface{}{
+						"db": map[string]interface{}{"status": "fail", "error": "connection refused", "duration": "1.2ms"},
+					},
+				},
+			},
+			TypeName: "health-report",
+		},
+		Identifier: HealthReportMediaIdentifier,
+		Views:      map[string]*ViewDefinition{"default": healthReportMediaView},
+	}
+
+	healthReportMediaType = Object{
+		"status": &AttributeDefinition{
+			Type:        String,
+			Description: "the aggregated status: ok if all the checks succeeded, fail otherwise.",
+			Validation:  &dslengine.ValidationDefinition{Values: []interface{}{"ok", "fail"}},
+			Example:     "ok",
+		},
+		"checks": &AttributeDefinition{
+			Type: &Hash{
+				KeyType:  &AttributeDefinition{Type: String},
+				ElemType: &AttributeDefinition{Type: healthCheckType},
+			},
+			Description: "the results of the individual checks indexed by checker name.",
+		},
+	}
+
+	healthCheckType = Object{
+		"status": &AttributeDefinition{
+			Type:        String,
+			Description: "ok if the check succeeded, fail otherwise.",
+			Validation:  &dslengine.ValidationDefinition{Values: []interface{}{"ok", "fail"}},
+			Example:     "ok",
+		},
+		"error": &AttributeDefinition{
+			Type:        String,
+			Description: "the reason why the check failed.",
+			Example:     "connection refused",
+		},
+		"duration": &AttributeDefinition{
+			Type:        String,
+			Description: "the time it took to run the check.",
+			Example:     "1.2ms",
+		},
+	}
+
+	healthReportMediaView = &ViewDefinition{
+		AttributeDefinition: &AttributeDefinition{Type: healthReportMediaType},
+		Name:                "default",
+	}
 )
 
 func init() {
@@ -200,6 +274,7 @@ func init() {
 		{MIMETypes: GobContentTypes, PackagePath: goa, Function: "NewGobDecoder"},
 	}
 	errorMediaView.Parent = ErrorMedia
+	healthReportMediaView.Parent = HealthReportMedia
 }
 
 // CanonicalIdentifier returns the media type identifier sans suffix
@@ -2,6 +2,7 @@ package apidsl
 
 import (
 	"fmt"
+	"time"
 	"unicode"
 
 	"github.com/goadesign/goa/design"
@@ -489,6 +490,229 @@ func MultipartForm() {
 	}
 }
 
+// InboundMessage can be used in: Action
+//
+// InboundMessage defines the type of the messages sent by clients to a websocket action. The
+// messages are encoded using JSON. The generated action context exposes a Conn method that wraps
+// the websocket connection to receive validated inbound messages and to send outbound messages,
+// the generated client exposes a similar wrapper. The arguments are the same as the Payload DSL
+// arguments, the message type must be an object. Examples:
+//
+//	Action("chat", func() {
+//		Routing(GET("/chat"))
+//		Scheme("ws")
+//		InboundMessage(ChatMessage)	// Clients send ChatMessage messages
+//		OutboundMessage(func() {	// The server sends inline defined messages
+//			Attribute("author")
+//			Attribute("text")
+//			Required("author", "text")
+//		})
+//		Response(SwitchingProtocols)
+//	})
+//
+func InboundMessage(p interface{}, dsls ...func()) {
+	if a, ok := actionDefinition(); ok {
+		if ut := messageType(a, "InboundMessage", p, dsls...); ut != nil {
+			a.InboundMessage = ut
+		}
+	}
+}
+
+// OutboundMessage can be used in: Action
+//
+// OutboundMessage defines the type of the messages sent by a websocket action to its clients. See
+// InboundMessage for details.
+func OutboundMessage(p interface{}, dsls ...func()) {
+	if a, ok := actionDefinition(); ok {
+		if ut := messageType(a, "OutboundMessage", p, dsls...); ut != nil {
+			a.OutboundMessage = ut
+		}
+	}
+}
+
+// messageType builds the type of websocket messages given the arguments of the InboundMessage or
+// OutboundMessage DSL. kind is the name of the DSL function and the suffix of inline type names.
+func messageType(a *design.ActionDefinition, kind string, p interface{}, dsls ...func()) *design.UserTypeDefinition {
+	if len(dsls) > 1 {
+		dslengine.ReportError("too many arguments given to %s", kind)
+		return nil
+	}
+	var att *design.AttributeDefinition
+	var dsl func()
+	switch actual := p.(type) {
+	case func():
+		dsl = actual
+		att = newAttribute(a.Parent.MediaType)
+		att.Type = design.Object{}
+	case *design.UserTypeDefinition:
+		if len(dsls) == 0 {
+			return actual
+		}
+		att = design.DupAtt(actual.Definition())
+	case *design.MediaTypeDefinition:
+		att = design.DupAtt(actual.AttributeDefinition)
+	case string:
+		ut, ok := design.Design.Types[actual]
+		if !ok {
+			dslengine.ReportError("unknown %s type %s", kind, actual)
+			return nil
+		}
+		if len(dsls) == 0 {
+			return ut
+		}
+		att = design.DupAtt(ut.AttributeDefinition)
+	default:
+		dslengine.ReportError("invalid %s argument, must be a type, a media type or a DSL building a type", kind)
+		return nil
+	}
+	if len(dsls) == 1 {
+		if dsl != nil {
+			dslengine.ReportError("invalid arguments in %s call, must be (type), (dsl) or (type, dsl)", kind)
+		}
+		dsl = dsls[0]
+	}
+	if dsl != nil {
+		dslengine.Execute(dsl, att)
+	}
+	return &design.UserTypeDefinition{
+		AttributeDefinition: att,
+		TypeName:            fmt.Sprintf("%s%s%s", camelize(a.Name), camelize(a.Parent.Name), kind),
+	}
+}
+
+// Stream can be used in: Action
+//
+// Stream indicates that the action responds with a stream of server-sent events as described in
+// https://html.spec.whatwg.org/multipage/server-sent-events.html. The data of each event is the
+// given media type rendered with the given view ("default" if omitted). The value can be a media
+// type definition or a media type identifier.
+//
+// The generated action context exposes a Stream method that writes the text/event-stream
+// response headers and returns a stream used to send typed events as well as a LastEventID
+// method that returns the value of the Last-Event-ID header sent by reconnecting clients. The
+// generated client package exposes an event reader that decodes the events data. An "OK"
+// response is added to the action if it does not define one with status code 200. Examples:
+//
+//	Action("updates", func() {
+//		Routing(GET("/updates"))
+//		Stream(BottleMedia)
+//	})
+//
+//	Action("feed", func() {
+//		Routing(GET("/feed"))
+//		Stream("application/vnd.goa.bottle", "tiny")
+//	})
+//
+func Stream(val interface{}, viewName ...string) {
+	if a, ok := actionDefinition(); ok {
+		s := &design.StreamDefinition{Parent: a}
+		if m, ok := val.(*design.MediaTypeDefinition); ok {
+			if m != nil {
+				s.MediaType = m.Identifier
+			}
+		} else if identifier, ok := val.(string); ok {
+			s.MediaType = identifier
+		} else {
+			dslengine.ReportError("media type must be a string or a pointer to MediaTypeDefinition, got %#v", val)
+			return
+		}
+		if len(viewName) == 1 {
+			s.ViewName = viewName[0]
+		} else if len(viewName) > 1 {
+			dslengine.ReportError("too many arguments given to Stream")
+			return
+		}
+		a.Stream = s
+	}
+}
+
+// Idempotent can be used in: Action
+//
+// Idempotent makes the generated code replay the responses of the requests made with the
+// Idempotency-Key header, see package github.com/goadesign/goa/middleware/idempotency. Responses
+// are stored for the duration ttl. required causes requests that do not include the header to be
+// rejected. The action must have a route with a method other than GET, HEAD or OPTIONS. Example:
+//
+//	Action("create", func() {
+//		Routing(POST("/payments"))
+//		Idempotent(24*time.Hour, true)
+//	})
+//
+func Idempotent(ttl time.Duration, required bool) {
+	if a, ok := actionDefinition(); ok {
+		if ttl <= 0 {
+			dslengine.ReportError("invalid idempotency TTL %s, must be positive", ttl)
+			return
+		}
+		if a.Metadata == nil {
+			a.Metadata = make(dslengine.MetadataDefinition)
+		}
+		a.Metadata[design.IdempotencyMetadataKey] = []string{ttl.String()}
+		if required {
+			a.Metadata[design.IdempotencyRequiredMetadataKey] = nil
+		} else {
+			delete(a.Metadata, design.IdempotencyRequiredMetadataKey)
+		}
+	}
+}
+
+// Cache can be used in: Action
+//
+// Cache makes the responses of the action cacheable by HTTP clients and intermediaries, see
+// package github.com/goadesign/goa/middleware/httpcache. directives is the value of the
+// Cache-Control header set on successful responses, it may be empty. The optional DSL may use
+// ETag to change how the ETag header of the responses is computed. Example:
+//
+//	Action("show", func() {
+//		Routing(GET("/bottles/:id"))
+//		Cache("private, max-age=60", func() {
+//			ETag(WeakETag)
+//		})
+//	})
+//
+func Cache(directives string, dsl ...func()) {
+	if len(dsl) > 1 {
+		dslengine.ReportError("too many arguments given to Cache")
+		return
+	}
+	if a, ok := actionDefinition(); ok {
+		d, err := design.ParseCacheControl(directives)
+		if err != nil {
+			dslengine.ReportError("%s", err)
+			return
+		}
+		if a.Metadata == nil {
+			a.Metadata = make(dslengine.MetadataDefinition)
+		}
+		a.Metadata[design.CacheMetadataKey] = []string{d}
+		delete(a.Metadata, design.CacheETagMetadataKey)
+		if len(dsl) == 1 {
+			dslengine.Execute(dsl[0], a)
+		}
+	}
+}
+
+// ETag can be used in: Cache
+//
+// ETag sets how the ETag header of the responses of a cacheable action is computed, one of
+// StrongETag (the default), WeakETag or NoETag (only the validators set explicitly by the action
+// are used).
+func ETag(strategy string) {
+	if a, ok := actionDefinition(); ok {
+		if _, ok := a.Metadata[design.CacheMetadataKey]; !ok {
+			dslengine.IncompatibleDSL()
+			return
+		}
+		switch strategy {
+		case design.StrongETag, design.WeakETag, design.NoETag:
+			a.Metadata[design.CacheETagMetadataKey] = []string{strategy}
+		default:
+			dslengine.ReportError("invalid ETag strategy %q, must be %q, %q or %q",
+				strategy, design.StrongETag, design.WeakETag, design.NoETag)
+		}
+	}
+}
+
 // newAttribute creates a new attribute definition using the media type with the given identifier
 // as base type.
 func newAttribute(baseMT string) *design.AttributeDefinition {
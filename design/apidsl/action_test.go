@@ -2,6 +2,7 @@ package apidsl_test
 
 import (
 	"strconv"
+	"time"
 
 	. "github.com/goadesign/goa/design"
 	. "github.com/goadesign/goa/design/apidsl"
@@ -363,4 +364,254 @@ var _ = Describe("Payload", func() {
 		})
 	})
 
+	Context("with a stream", func() {
+		var viewName string
+
+		BeforeEach(func() {
+			dslengine.Reset()
+			viewName = "default"
+		})
+
+		JustBeforeEach(func() {
+			MediaType("application/vnd.event", func() {
+				Attributes(func() {
+					Attribute("id", Integer)
+				})
+				View("default", func() {
+					Attribute("id")
+				})
+			})
+			Resource("foo", func() {
+				Action("bar", func() {
+					Routing(GET(""))
+					Stream("application/vnd.event", viewName)
+				})
+			})
+			dslengine.Run()
+		})
+
+		It("sets the stream and adds the event stream response", func() {
+			Ω(dslengine.Errors).ShouldNot(HaveOccurred())
+			action := Design.Resources["foo"].Actions["bar"]
+			Ω(action.Stream).ShouldNot(BeNil())
+			Ω(action.Stream.MediaType).Should(Equal("application/vnd.event"))
+			Ω(action.Stream.ViewName).Should(Equal("default"))
+			Ω(action.Responses).Should(HaveKey("OK"))
+			Ω(action.Responses["OK"].MediaType).Should(Equal(EventStreamMediaType))
+			Ω(action.StreamResponse(action.Responses["OK"])).Should(BeTrue())
+		})
+
+		Context("using an unknown view", func() {
+			BeforeEach(func() {
+				viewName = "unknown"
+			})
+
+			It("produces an error", func() {
+				Ω(dslengine.Errors).Should(HaveOccurred())
+			})
+		})
+	})
+
+	Context("with websocket messages", func() {
+		var scheme string
+
+		BeforeEach(func() {
+			dslengine.Reset()
+			scheme = "ws"
+		})
+
+		JustBeforeEach(func() {
+			msg := Type("message", func() {
+				Attribute("text")
+			})
+			Resource("foo", func() {
+				Action("bar", func() {
+					Routing(GET(""))
+					Scheme(scheme)
+					InboundMessage(msg)
+					OutboundMessage(func() {
+						Attribute("author")
+						Required("author")
+					})
+				})
+			})
+			dslengine.Run()
+		})
+
+		It("sets the message types", func() {
+			Ω(dslengine.Errors).ShouldNot(HaveOccurred())
+			action := Design.Resources["foo"].Actions["bar"]
+			Ω(action.InboundMessage).Should(Equal(Design.Types["message"]))
+			Ω(action.OutboundMessage).ShouldNot(BeNil())
+			Ω(action.OutboundMessage.TypeName).Should(Equal("BarFooOutboundMessage"))
+			Ω(action.OutboundMessage.Type.ToObject()).Should(HaveKey("author"))
+		})
+
+		Context("on an action that does not use websocket", func() {
+			BeforeEach(func() {
+				scheme = "http"
+			})
+
+			It("produces an error", func() {
+				Ω(dslengine.Errors).Should(HaveOccurred())
+			})
+		})
+	})
+
+})
+
+var _ = Describe("Idempotent", func() {
+	var ttl time.Duration
+	var required bool
+	var verb string
+	var action *ActionDefinition
+
+	BeforeEach(func() {
+		dslengine.Reset()
+		ttl = time.Hour
+		required = false
+		verb = "POST"
+	})
+
+	JustBeforeEach(func() {
+		Resource("foo", func() {
+			Action("bar", func() {
+				Routing(&RouteDefinition{Verb: verb, Path: "/"})
+				Idempotent(ttl, required)
+			})
+		})
+		dslengine.Run()
+		action = Design.Resources["foo"].Actions["bar"]
+	})
+
+	It("declares the action idempotency", func() {
+		Ω(dslengine.Errors).ShouldNot(HaveOccurred())
+		i, err := action.Idempotency()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(i).Should(Equal(&IdempotencyDefinition{TTL: time.Hour}))
+	})
+
+	Context("with required keys", func() {
+		BeforeEach(func() {
+			required = true
+		})
+
+		It("requires the idempotency key", func() {
+			Ω(dslengine.Errors).ShouldNot(HaveOccurred())
+			i, err := action.Idempotency()
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(i).Should(Equal(&IdempotencyDefinition{TTL: time.Hour, Required: true}))
+		})
+	})
+
+	Context("with an invalid TTL", func() {
+		BeforeEach(func() {
+			ttl = 0
+		})
+
+		It("produces an error", func() {
+			Ω(dslengine.Errors).Should(HaveOccurred())
+			Ω(dslengine.Errors.Error()).Should(ContainSubstring("invalid idempotency TTL"))
+		})
+	})
+
+	Context("on a GET action", func() {
+		BeforeEach(func() {
+			verb = "GET"
+		})
+
+		It("produces an error", func() {
+			Ω(dslengine.Errors).Should(HaveOccurred())
+		})
+	})
+})
+
+var _ = Describe("Cache", func() {
+	var directives string
+	var dsl func()
+	var action *ActionDefinition
+
+	BeforeEach(func() {
+		dslengine.Reset()
+		directives = "Public,max-age=60"
+		dsl = nil
+	})
+
+	JustBeforeEach(func() {
+		Resource("foo", func() {
+			Action("bar", func() {
+				Routing(GET("/"))
+				if dsl == nil {
+					Cache(directives)
+				} else {
+					Cache(directives, dsl)
+				}
+			})
+		})
+		dslengine.Run()
+		action = Design.Resources["foo"].Actions["bar"]
+	})
+
+	It("declares the action cacheable", func() {
+		Ω(dslengine.Errors).ShouldNot(HaveOccurred())
+		c, err := action.Cache()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(c).Should(Equal(&CacheDefinition{Directives: "public, max-age=60", ETag: StrongETag}))
+	})
+
+	Context("with an ETag strategy", func() {
+		BeforeEach(func() {
+			dsl = func() {
+				ETag(WeakETag)
+			}
+		})
+
+		It("sets the ETag strategy", func() {
+			Ω(dslengine.Errors).ShouldNot(HaveOccurred())
+			c, err := action.Cache()
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(c.ETag).Should(Equal(WeakETag))
+		})
+	})
+
+	Context("with an invalid ETag strategy", func() {
+		BeforeEach(func() {
+			dsl = func() {
+				ETag("sometimes")
+			}
+		})
+
+		It("produces an error", func() {
+			Ω(dslengine.Errors).Should(HaveOccurred())
+			Ω(dslengine.Errors.Error()).Should(ContainSubstring("invalid ETag strategy"))
+		})
+	})
+
+	Context("with invalid directives", func() {
+		BeforeEach(func() {
+			directives = "max-age=forever"
+		})
+
+		It("produces an error", func() {
+			Ω(dslengine.Errors).Should(HaveOccurred())
+			Ω(dslengine.Errors.Error()).Should(ContainSubstring("max-age"))
+		})
+	})
+
+	Context("with an ETag outside of Cache", func() {
+		JustBeforeEach(func() {
+			dslengine.Reset()
+			Resource("foo", func() {
+				Action("bar", func() {
+					Routing(GET("/"))
+					ETag(WeakETag)
+				})
+			})
+			dslengine.Run()
+		})
+
+		It("produces an error", func() {
+			Ω(dslengine.Errors).Should(HaveOccurred())
+		})
+	})
 })
@@ -64,6 +64,7 @@ import (
 //			MediaType(arg2)
 //		})
 //              NoExample()                             // Prevent automatic generation of examples
+//		HealthCheck()				// Expose the /healthz and /readyz endpoints
 //		Trait("Authenticated", func() {		// Traits define DSL that can be run anywhere
 //			Headers(func() {
 //				Header("header")
@@ -168,6 +169,7 @@ func BasePath(val string) {
 //                Expose("X-Time")                     // One or more headers exposed to clients
 //                MaxAge(600)                          // How long to cache a preflight request response
 //                Credentials()                        // Sets Access-Control-Allow-Credentials header
+//                PrivateNetwork()                     // Allows private network access preflight requests
 //        })
 //
 //        Origin("/(api|swagger)[.]goa[.]design/", func() {}) // Define CORS policy with a regular expression
@@ -239,6 +241,17 @@ func Credentials() {
 	}
 }
 
+// PrivateNetwork can be used in: Origin
+//
+// PrivateNetwork allows requests from public networks to reach private network services by
+// setting the Access-Control-Allow-Private-Network header in response to private network
+// access preflight requests.
+func PrivateNetwork() {
+	if cors, ok := corsDefinition(); ok {
+		cors.PrivateNetwork = true
+	}
+}
+
 // TermsOfService can be used in: API
 //
 // TermsOfService describes the API terms of services or links to them.
@@ -248,6 +261,21 @@ func TermsOfService(terms string) {
 	}
 }
 
+// HealthCheck can be used in: API
+//
+// HealthCheck indicates that the API exposes the /healthz and /readyz endpoints implemented by the
+// github.com/goadesign/goa/health package. The generated main mounts the endpoints and the
+// generated Swagger specification documents them. Example:
+//
+//	var _ = API("cellar", func() {
+//		HealthCheck()
+//	})
+func HealthCheck() {
+	if a, ok := apiDefinition(); ok {
+		a.HealthCheck = true
+	}
+}
+
 // Regular expression used to validate RFC1035 hostnames*/
 var hostnameRegex = regexp.MustCompile(`^[[:alnum:]][[:alnum:]\-]{0,61}[[:alnum:]]|[[:alpha:]]$`)
 
@@ -104,6 +104,18 @@ var _ = Describe("API", func() {
 			})
 		})
 
+		Context("with a health check", func() {
+			BeforeEach(func() {
+				dsl = func() {
+					HealthCheck()
+				}
+			})
+
+			It("enables the health check endpoints", func() {
+				Ω(Design.HealthCheck).Should(BeTrue())
+			})
+		})
+
 		Context("with a terms of service", func() {
 			const terms = "terms"
 
@@ -1,6 +1,8 @@
 package apidsl
 
 import (
+	"strings"
+
 	"github.com/goadesign/goa/design"
 	"github.com/goadesign/goa/dslengine"
 )
@@ -12,8 +14,9 @@ import (
 // API level, it will apply to all resources by default, following the same logic.
 //
 // The scheme refers to previous definitions of either OAuth2Security, BasicAuthSecurity,
-// APIKeySecurity or JWTSecurity.  It can be a string, corresponding to the first parameter of
-// those definitions, or a SecuritySchemeDefinition, returned by those same functions. Examples:
+// APIKeySecurity, JWTSecurity or HTTPSignatureSecurity.  It can be a string, corresponding to the
+// first parameter of those definitions, or a SecuritySchemeDefinition, returned by those same
+// functions. Examples:
 //
 //    Security(BasicAuth)
 //
@@ -264,6 +267,72 @@ func JWTSecurity(name string, dsl ...func()) *design.SecuritySchemeDefinition {
 	return def
 }
 
+// HTTPSignatureSecurity is a top level DSL.
+// HTTPSignatureSecurity defines a security scheme where the clients sign the requests with a
+// shared secret identified by a key ID. The signature is sent in the "Signature" header as
+// described in draft-cavage-http-signatures and covers the headers listed with SignedHeaders,
+// "(request-target)", "host", "date" and "digest" by default. The "digest" header contains the
+// hash of the request body so that the signature covers the body as well.
+//
+// Example:
+//
+//    HTTPSignatureSecurity("signed", func() {
+//        Description("Requests signed with HMAC-SHA256")
+//        SignedHeaders("(request-target)", "host", "date", "digest", "content-type")
+//    })
+//
+func HTTPSignatureSecurity(name string, dsl ...func()) *design.SecuritySchemeDefinition {
+	switch dslengine.CurrentDefinition().(type) {
+	case *design.APIDefinition, *dslengine.TopLevelDefinition:
+	default:
+		dslengine.IncompatibleDSL()
+		return nil
+	}
+
+	if securitySchemeRedefined(name) {
+		return nil
+	}
+
+	def := &design.SecuritySchemeDefinition{
+		SchemeName:    name,
+		Kind:          design.HTTPSignatureSecurityKind,
+		Type:          "apiKey",
+		In:            "header",
+		Name:          "Signature",
+		SignedHeaders: []string{"(request-target)", "host", "date", "digest"},
+	}
+
+	if len(dsl) != 0 {
+		def.DSLFunc = dsl[0]
+	}
+
+	design.Design.SecuritySchemes = append(design.Design.SecuritySchemes, def)
+
+	return def
+}
+
+// SignedHeaders can be used in: HTTPSignatureSecurity
+//
+// SignedHeaders sets the list of headers covered by the request signatures, the names are case
+// insensitive. The list must include "date", the special "(request-target)" name stands for the
+// request method and path.
+func SignedHeaders(headers ...string) {
+	if parent, ok := dslengine.CurrentDefinition().(*design.SecuritySchemeDefinition); ok {
+		if parent.Kind == design.HTTPSignatureSecurityKind {
+			if len(headers) == 0 {
+				dslengine.ReportError("signed headers must not be empty")
+				return
+			}
+			parent.SignedHeaders = make([]string, len(headers))
+			for i, h := range headers {
+				parent.SignedHeaders[i] = strings.ToLower(h)
+			}
+			return
+		}
+	}
+	dslengine.IncompatibleDSL()
+}
+
 // Scope can be used in: Security, JWTSecurity, OAuth2Security
 //
 // Scope defines an authorization scope. Used within SecurityScheme, a description may be provided
@@ -299,7 +368,7 @@ func inHeader(headerName string) {
 	if current, ok := dslengine.CurrentDefinition().(*design.SecuritySchemeDefinition); ok {
 		if current.Kind == design.APIKeySecurityKind || current.Kind == design.JWTSecurityKind {
 			if current.In != "" {
-				dslengine.ReportError("'In' previously defined through Header or Query")
+				dslengine.ReportError("'In' previously defined through Header, Query or Cookie")
 				return
 			}
 			current.In = "header"
@@ -318,7 +387,7 @@ func Query(parameterName string) {
 	if current, ok := dslengine.CurrentDefinition().(*design.SecuritySchemeDefinition); ok {
 		if current.Kind == design.APIKeySecurityKind || current.Kind == design.JWTSecurityKind {
 			if current.In != "" {
-				dslengine.ReportError("'In' previously defined through Header or Query")
+				dslengine.ReportError("'In' previously defined through Header, Query or Cookie")
 				return
 			}
 			current.In = "query"
@@ -329,6 +398,25 @@ func Query(parameterName string) {
 	dslengine.IncompatibleDSL()
 }
 
+// Cookie can be used in: JWTSecurity
+//
+// Cookie defines that a JWTSecurity implementation must check in the cookie named "cookieName" to
+// get the token.
+func Cookie(cookieName string) {
+	if current, ok := dslengine.CurrentDefinition().(*design.SecuritySchemeDefinition); ok {
+		if current.Kind == design.JWTSecurityKind {
+			if current.In != "" {
+				dslengine.ReportError("'In' previously defined through Header, Query or Cookie")
+				return
+			}
+			current.In = "cookie"
+			current.Name = cookieName
+			return
+		}
+	}
+	dslengine.IncompatibleDSL()
+}
+
 // AccessCodeFlow can be used in: OAuth2Security
 //
 // AccessCodeFlow defines an "access code" OAuth2 flow.  Use within an OAuth2Security definition.
@@ -175,6 +175,95 @@ var _ = Describe("Security", func() {
 
 	})
 
+	Context("with JWT security", func() {
+		It("should read the token from a cookie", func() {
+			API("", func() {
+				JWTSecurity("jwt", func() {
+					Cookie("token")
+				})
+			})
+			dslengine.Run()
+
+			Ω(dslengine.Errors).ShouldNot(HaveOccurred())
+			Ω(Design.SecuritySchemes).Should(HaveLen(1))
+			Ω(Design.SecuritySchemes[0].In).Should(Equal("cookie"))
+			Ω(Design.SecuritySchemes[0].Name).Should(Equal("token"))
+		})
+
+		It("should fail because of duplicate In declaration", func() {
+			API("", func() {
+				JWTSecurity("jwt", func() {
+					Header("Authorization")
+					Cookie("token")
+				})
+			})
+			dslengine.Run()
+			Ω(dslengine.Errors).Should(HaveOccurred())
+		})
+
+		It("should fail because of invalid declaration of Cookie", func() {
+			API("", func() {
+				APIKeySecurity("session", func() {
+					Cookie("session_id")
+				})
+			})
+			dslengine.Run()
+			Ω(dslengine.Errors).Should(HaveOccurred())
+		})
+	})
+
+	Context("with HTTP signature security", func() {
+		It("should default the signed headers", func() {
+			API("", func() {
+				HTTPSignatureSecurity("signed", func() {
+					Description("desc")
+				})
+			})
+			dslengine.Run()
+
+			Ω(dslengine.Errors).ShouldNot(HaveOccurred())
+			Ω(Design.SecuritySchemes).Should(HaveLen(1))
+			scheme := Design.SecuritySchemes[0]
+			Ω(scheme.Kind).Should(Equal(HTTPSignatureSecurityKind))
+			Ω(scheme.Context()).Should(Equal("HTTPSignatureSecurity"))
+			Ω(scheme.In).Should(Equal("header"))
+			Ω(scheme.Name).Should(Equal("Signature"))
+			Ω(scheme.SignedHeaders).Should(Equal([]string{"(request-target)", "host", "date", "digest"}))
+		})
+
+		It("should pass with custom signed headers", func() {
+			API("", func() {
+				HTTPSignatureSecurity("signed", func() {
+					SignedHeaders("(request-target)", "Date", "Digest", "Content-Type")
+				})
+			})
+			dslengine.Run()
+
+			Ω(dslengine.Errors).ShouldNot(HaveOccurred())
+			Ω(Design.SecuritySchemes[0].SignedHeaders).Should(Equal([]string{"(request-target)", "date", "digest", "content-type"}))
+		})
+
+		It("should fail when the signed headers do not include the date", func() {
+			API("", func() {
+				HTTPSignatureSecurity("signed", func() {
+					SignedHeaders("(request-target)", "digest")
+				})
+			})
+			dslengine.Run()
+			Ω(dslengine.Errors).Should(HaveOccurred())
+		})
+
+		It("should fail because of invalid declaration of SignedHeaders", func() {
+			API("", func() {
+				APIKeySecurity("key", func() {
+					SignedHeaders("date")
+				})
+			})
+			dslengine.Run()
+			Ω(dslengine.Errors).Should(HaveOccurred())
+		})
+	})
+
 	Context("with resources and actions", func() {
 		It("should fallback properly to lower-level security", func() {
 			API("", func() {
@@ -0,0 +1,124 @@
+package design
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// List of the metadata keys used to declare that the responses of an action may be cached. The
+// keys are set by the Cache and ETag DSL functions, e.g.:
+//
+//	Action("show", func() {
+//		Cache("public, max-age=60", func() {
+//			ETag(WeakETag)
+//		})
+//	})
+const (
+	// CacheMetadataKey marks the action as cacheable. The optional value lists the
+	// Cache-Control directives set on successful responses, e.g. "private, max-age=60".
+	CacheMetadataKey = "cache"
+
+	// CacheETagMetadataKey sets how the ETag of successful responses is computed, one of
+	// "strong" (the default), "weak" or "none" (only validators set explicitly by the
+	// action are used).
+	CacheETagMetadataKey = "cache:etag"
+)
+
+// List of the ETag strategies.
+const (
+	// StrongETag computes strong entity tags from the encoded response body.
+	StrongETag = "strong"
+
+	// WeakETag computes weak entity tags from the encoded response body.
+	WeakETag = "weak"
+
+	// NoETag disables the computation of entity tags.
+	NoETag = "none"
+)
+
+// cacheDirectives lists the Cache-Control response directives and whether they take a number of
+// seconds as argument.
+var cacheDirectives = map[string]bool{
+	"public":                 false,
+	"private":                false,
+	"no-cache":               false,
+	"no-store":               false,
+	"no-transform":           false,
+	"must-revalidate":        false,
+	"proxy-revalidate":       false,
+	"must-understand":        false,
+	"immutable":              false,
+	"max-age":                true,
+	"s-maxage":               true,
+	"stale-while-revalidate": true,
+	"stale-if-error":         true,
+}
+
+// CacheDefinition describes how the responses of an action may be cached.
+type CacheDefinition struct {
+	// Directives is the value of the Cache-Control header, may be empty.
+	Directives string
+	// ETag is StrongETag, WeakETag or NoETag.
+	ETag string
+}
+
+// Cache returns the cache definition declared in the action metadata or nil if the action is
+// not cacheable. It returns an error if the metadata is invalid.
+func (a *ActionDefinition) Cache() (*CacheDefinition, error) {
+	vals, ok := a.Metadata[CacheMetadataKey]
+	if !ok {
+		return nil, nil
+	}
+	c := &CacheDefinition{ETag: StrongETag}
+	if len(vals) > 0 {
+		directives, err := ParseCacheControl(vals[0])
+		if err != nil {
+			return nil, err
+		}
+		c.Directives = directives
+	}
+	if vals := a.Metadata[CacheETagMetadataKey]; len(vals) > 0 {
+		switch vals[0] {
+		case StrongETag, WeakETag, NoETag:
+			c.ETag = vals[0]
+		default:
+			return nil, fmt.Errorf("invalid %s metadata %q, must be %q, %q or %q", CacheETagMetadataKey, vals[0], StrongETag, WeakETag, NoETag)
+		}
+	}
+	return c, nil
+}
+
+// ParseCacheControl validates the given Cache-Control response directives and returns them in
+// canonical form, e.g. "Public,max-age=60" returns "public, max-age=60".
+func ParseCacheControl(directives string) (string, error) {
+	if strings.TrimSpace(directives) == "" {
+		return "", nil
+	}
+	elems := strings.Split(directives, ",")
+	res := make([]string, len(elems))
+	for i, e := range elems {
+		parts := strings.SplitN(strings.TrimSpace(e), "=", 2)
+		name := strings.ToLower(strings.TrimSpace(parts[0]))
+		numeric, ok := cacheDirectives[name]
+		if !ok {
+			return "", fmt.Errorf("invalid %s metadata %q, unknown directive %q", CacheMetadataKey, directives, name)
+		}
+		if !numeric {
+			if len(parts) > 1 {
+				return "", fmt.Errorf("invalid %s metadata %q, directive %q takes no argument", CacheMetadataKey, directives, name)
+			}
+			res[i] = name
+			continue
+		}
+		if len(parts) < 2 {
+			return "", fmt.Errorf("invalid %s metadata %q, directive %q requires a number of seconds", CacheMetadataKey, directives, name)
+		}
+		secs, err := strconv.ParseUint(strings.TrimSpace(parts[1]), 10, 32)
+		if err != nil {
+			return "", fmt.Errorf("invalid %s metadata %q, directive %q requires a number of seconds", CacheMetadataKey, directives, name)
+		}
+		res[i] = fmt.Sprintf("%s=%d", name, secs)
+	}
+	return strings.Join(res, ", "), nil
+}
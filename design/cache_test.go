@@ -0,0 +1,85 @@
+package design_test
+
+import (
+	. "github.com/goadesign/goa/design"
+	"github.com/goadesign/goa/dslengine"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cache", func() {
+	var metadata dslengine.MetadataDefinition
+
+	var c *CacheDefinition
+	var err error
+
+	BeforeEach(func() {
+		metadata = dslengine.MetadataDefinition{CacheMetadataKey: {"Public,max-age=60"}}
+	})
+
+	JustBeforeEach(func() {
+		action := &ActionDefinition{
+			Name:     "show",
+			Parent:   &ResourceDefinition{Name: "bottle"},
+			Metadata: metadata,
+		}
+		c, err = action.Cache()
+	})
+
+	It("uses strong ETags by default and canonicalizes the directives", func() {
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(c).Should(Equal(&CacheDefinition{Directives: "public, max-age=60", ETag: StrongETag}))
+	})
+
+	Context("with no metadata", func() {
+		BeforeEach(func() {
+			metadata = nil
+		})
+
+		It("returns nil", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(c).Should(BeNil())
+		})
+	})
+
+	Context("with no directive", func() {
+		BeforeEach(func() {
+			metadata = dslengine.MetadataDefinition{CacheMetadataKey: nil, CacheETagMetadataKey: {WeakETag}}
+		})
+
+		It("only configures the ETag", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(c).Should(Equal(&CacheDefinition{ETag: WeakETag}))
+		})
+	})
+
+	Context("with an unknown directive", func() {
+		BeforeEach(func() {
+			metadata[CacheMetadataKey] = []string{"public, forever"}
+		})
+
+		It("returns an error", func() {
+			Ω(err).Should(HaveOccurred())
+		})
+	})
+
+	Context("with an invalid max-age", func() {
+		BeforeEach(func() {
+			metadata[CacheMetadataKey] = []string{"max-age=soon"}
+		})
+
+		It("returns an error", func() {
+			Ω(err).Should(HaveOccurred())
+		})
+	})
+
+	Context("with an invalid ETag strategy", func() {
+		BeforeEach(func() {
+			metadata[CacheETagMetadataKey] = []string{"md5"}
+		})
+
+		It("returns an error", func() {
+			Ω(err).Should(HaveOccurred())
+		})
+	})
+})
@@ -74,6 +74,8 @@ type (
 		Security *SecurityDefinition
 		// NoExamples indicates whether to bypass automatic example generation.
 		NoExamples bool
+		// HealthCheck indicates whether the API exposes the health check endpoints.
+		HealthCheck bool
 
 		// rand is the random generator used to generate examples.
 		rand *RandomGenerator
@@ -164,6 +166,9 @@ type (
 		Credentials bool
 		// Sets Whether the Origin string is a regular expression
 		Regexp bool
+		// Sets Access-Control-Allow-Private-Network header in response to private network
+		// access preflight requests
+		PrivateNetwork bool
 	}
 
 	// EncodingDefinition defines an encoder supported by the API.
@@ -253,6 +258,22 @@ type (
 		Metadata dslengine.MetadataDefinition
 		// Security defines security requirements for the action
 		Security *SecurityDefinition
+		// Stream describes the server-sent events sent by the action if any
+		Stream *StreamDefinition
+		// InboundMessage is the type of the messages sent by clients to websocket actions
+		InboundMessage *UserTypeDefinition
+		// OutboundMessage is the type of the messages sent by websocket actions to clients
+		OutboundMessage *UserTypeDefinition
+	}
+
+	// StreamDefinition describes the events sent by an action that streams server-sent events.
+	StreamDefinition struct {
+		// Identifier of the media type used to render the events data
+		MediaType string
+		// Name of the view used to render the events data, "default" if empty
+		ViewName string
+		// Parent action
+		Parent *ActionDefinition
 	}
 
 	// FileServerDefinition defines an endpoint that servers static assets.
@@ -571,6 +592,22 @@ func (a *APIDefinition) IterateUserTypes(it UserTypeIterator) error {
 	return nil
 }
 
+// IterateInlineMessages calls the given iterator passing in each websocket message type defined
+// inline in an action, that is not declared with Type. Iteration stops if an iterator returns an
+// error and in this case IterateInlineMessages returns that error.
+func (a *APIDefinition) IterateInlineMessages(it UserTypeIterator) error {
+	return a.IterateResources(func(r *ResourceDefinition) error {
+		return r.IterateActions(func(act *ActionDefinition) error {
+			return act.IterateMessages(func(m *UserTypeDefinition) error {
+				if _, ok := a.Types[m.TypeName]; ok {
+					return nil
+				}
+				return it(m)
+			})
+		})
+	})
+}
+
 // IterateResponses calls the given iterator passing in each response sorted in alphabetical order.
 // Iteration stops if an iterator returns an error and in this case IterateResponses returns that
 // error.
@@ -1445,6 +1482,22 @@ func (r *ResponseTemplateDefinition) Context() string {
 	return "unnamed response template"
 }
 
+// Context returns the generic definition name used in error messages.
+func (s *StreamDefinition) Context() string {
+	if s.Parent != nil {
+		return s.Parent.Context() + " stream"
+	}
+	return "stream"
+}
+
+// EffectiveViewName returns the name of the view used to render the events data.
+func (s *StreamDefinition) EffectiveViewName() string {
+	if s.ViewName == "" {
+		return "default"
+	}
+	return s.ViewName
+}
+
 // Context returns the generic definition name used in error messages.
 func (a *ActionDefinition) Context() string {
 	var prefix, suffix string
@@ -1542,6 +1595,21 @@ func (a *ActionDefinition) EffectiveSchemes() []string {
 	return schemes
 }
 
+// IterateMessages calls the given iterator passing in the inbound message type first and then the
+// outbound message type if defined. Iteration stops if an iterator returns an error and in this
+// case IterateMessages returns that error.
+func (a *ActionDefinition) IterateMessages(it UserTypeIterator) error {
+	for _, m := range []*UserTypeDefinition{a.InboundMessage, a.OutboundMessage} {
+		if m == nil {
+			continue
+		}
+		if err := it(m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // WebSocket returns true if the action scheme is "ws" or "wss" or both (directly or inherited
 // from the resource or API)
 func (a *ActionDefinition) WebSocket() bool {
@@ -1574,8 +1642,15 @@ func (a *ActionDefinition) Finalize() {
 	if a.Payload != nil {
 		a.Payload.Finalize()
 	}
+	if a.InboundMessage != nil {
+		a.InboundMessage.Finalize()
+	}
+	if a.OutboundMessage != nil {
+		a.OutboundMessage.Finalize()
+	}
 
 	a.mergeResponses()
+	a.initStreamResponse()
 	a.initImplicitParams()
 	a.initQueryParams()
 }
@@ -1587,6 +1662,12 @@ func (a *ActionDefinition) UserTypes() map[string]*UserTypeDefinition {
 	if a.Payload != nil {
 		allp["__payload__"] = &AttributeDefinition{Type: a.Payload}
 	}
+	if a.InboundMessage != nil {
+		allp["__inbound__"] = &AttributeDefinition{Type: a.InboundMessage}
+	}
+	if a.OutboundMessage != nil {
+		allp["__outbound__"] = &AttributeDefinition{Type: a.OutboundMessage}
+	}
 	for n, ut := range UserTypes(allp) {
 		types[n] = ut
 	}
@@ -1598,6 +1679,14 @@ func (a *ActionDefinition) UserTypes() map[string]*UserTypeDefinition {
 			}
 		}
 	}
+	if a.Stream != nil {
+		if mt := Design.MediaTypeWithIdentifier(a.Stream.MediaType); mt != nil {
+			types[mt.TypeName] = mt.UserTypeDefinition
+			for n, ut := range UserTypes(mt.UserTypeDefinition) {
+				types[n] = ut
+			}
+		}
+	}
 	if len(types) == 0 {
 		return nil
 	}
@@ -1662,6 +1751,34 @@ func (a *ActionDefinition) mergeResponses() {
 	}
 }
 
+// initStreamResponse adds the "OK" response used to stream the server-sent events if the action
+// streams events and does not define a response with status code 200.
+func (a *ActionDefinition) initStreamResponse() {
+	if a.Stream == nil {
+		return
+	}
+	for _, r := range a.Responses {
+		if r.Status == 200 {
+			return
+		}
+	}
+	if a.Responses == nil {
+		a.Responses = make(map[string]*ResponseDefinition)
+	}
+	a.Responses["OK"] = &ResponseDefinition{
+		Name:        "OK",
+		Status:      200,
+		Description: "Stream of server-sent events",
+		MediaType:   EventStreamMediaType,
+		Parent:      a,
+	}
+}
+
+// StreamResponse returns true if r is the response used to stream the action server-sent events.
+func (a *ActionDefinition) StreamResponse(r *ResponseDefinition) bool {
+	return a.Stream != nil && r.Status == 200 && r.MediaType == EventStreamMediaType
+}
+
 // initImplicitParams creates params for path segments that don't have one.
 func (a *ActionDefinition) initImplicitParams() {
 	for _, ro := range a.Routes {
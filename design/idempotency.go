@@ -0,0 +1,65 @@
+package design
+
+import (
+	"fmt"
+	"time"
+)
+
+// List of the metadata keys used to declare that an action supports idempotency keys. The
+// apidsl Idempotent function sets them after validating its arguments:
+//
+//	Action("create", func() {
+//		Idempotent(24*time.Hour, true)
+//	})
+const (
+	// IdempotencyMetadataKey enables the replay of the responses of requests made with the
+	// Idempotency-Key header. The optional value is the duration responses are stored for,
+	// it defaults to 24h.
+	IdempotencyMetadataKey = "idempotency"
+
+	// IdempotencyRequiredMetadataKey causes requests that do not include the Idempotency-Key
+	// header to be rejected.
+	IdempotencyRequiredMetadataKey = "idempotency:required"
+)
+
+// DefaultIdempotencyTTL is the default duration responses are stored for.
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// IdempotencyDefinition describes how an action handles idempotency keys.
+type IdempotencyDefinition struct {
+	// TTL is the duration responses are stored for.
+	TTL time.Duration
+	// Required is true if requests must include an idempotency key.
+	Required bool
+}
+
+// Idempotency returns the idempotency definition declared in the action metadata or nil if the
+// action does not support idempotency keys. It returns an error if the metadata is invalid.
+func (a *ActionDefinition) Idempotency() (*IdempotencyDefinition, error) {
+	vals, ok := a.Metadata[IdempotencyMetadataKey]
+	if !ok {
+		return nil, nil
+	}
+	i := &IdempotencyDefinition{TTL: DefaultIdempotencyTTL}
+	if len(vals) > 0 && vals[0] != "" {
+		ttl, err := time.ParseDuration(vals[0])
+		if err != nil || ttl <= 0 {
+			return nil, fmt.Errorf("invalid %s metadata %q, must be a positive duration", IdempotencyMetadataKey, vals[0])
+		}
+		i.TTL = ttl
+	}
+	_, i.Required = a.Metadata[IdempotencyRequiredMetadataKey]
+	if len(a.Routes) > 0 {
+		safe := true
+		for _, r := range a.Routes {
+			if r.Verb != "GET" && r.Verb != "HEAD" && r.Verb != "OPTIONS" {
+				safe = false
+				break
+			}
+		}
+		if safe {
+			return nil, fmt.Errorf("%s metadata requires a route with a method other than GET, HEAD or OPTIONS", IdempotencyMetadataKey)
+		}
+	}
+	return i, nil
+}
@@ -0,0 +1,81 @@
+package design_test
+
+import (
+	"time"
+
+	. "github.com/goadesign/goa/design"
+	"github.com/goadesign/goa/dslengine"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Idempotency", func() {
+	var metadata dslengine.MetadataDefinition
+	var verb string
+
+	var i *IdempotencyDefinition
+	var err error
+
+	BeforeEach(func() {
+		metadata = dslengine.MetadataDefinition{IdempotencyMetadataKey: nil}
+		verb = "POST"
+	})
+
+	JustBeforeEach(func() {
+		action := &ActionDefinition{
+			Name:     "create",
+			Parent:   &ResourceDefinition{Name: "payment"},
+			Metadata: metadata,
+		}
+		action.Routes = []*RouteDefinition{{Verb: verb, Path: "/payments", Parent: action}}
+		i, err = action.Idempotency()
+	})
+
+	It("stores responses for 24 hours by default", func() {
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(i).Should(Equal(&IdempotencyDefinition{TTL: DefaultIdempotencyTTL}))
+	})
+
+	Context("with no metadata", func() {
+		BeforeEach(func() {
+			metadata = nil
+		})
+
+		It("returns nil", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(i).Should(BeNil())
+		})
+	})
+
+	Context("with all the metadata", func() {
+		BeforeEach(func() {
+			metadata[IdempotencyMetadataKey] = []string{"1h"}
+			metadata[IdempotencyRequiredMetadataKey] = nil
+		})
+
+		It("overrides the defaults", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(i).Should(Equal(&IdempotencyDefinition{TTL: time.Hour, Required: true}))
+		})
+	})
+
+	Context("with an invalid TTL", func() {
+		BeforeEach(func() {
+			metadata[IdempotencyMetadataKey] = []string{"-1h"}
+		})
+
+		It("returns an error", func() {
+			Ω(err).Should(HaveOccurred())
+		})
+	})
+
+	Context("with a GET action", func() {
+		BeforeEach(func() {
+			verb = "GET"
+		})
+
+		It("returns an error", func() {
+			Ω(err).Should(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,180 @@
+package design
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// List of the metadata keys used to declare the rate limit of actions, e.g.:
+//
+//	Action("show", func() {
+//		Metadata("ratelimit", "100/1m")
+//		Metadata("ratelimit:key", "jwt")
+//	})
+const (
+	// RateLimitMetadataKey sets the number of requests allowed per period with the
+	// syntax "<requests>/<period>" where period is a duration such as "1m" or "30s". The unit
+	// may be given alone to denote a period of one unit, e.g. "100/m".
+	RateLimitMetadataKey = "ratelimit"
+
+	// RateLimitKeyMetadataKey sets the key used to identify clients, one of "ip" (the
+	// default), "apikey" (the API key of the action APIKey security scheme), "jwt" (the subject
+	// of the JWT validated by the action JWT security scheme), "header:<name>" or
+	// "custom:<name>" (a key function registered at runtime with ratelimit.RegisterKey).
+	RateLimitKeyMetadataKey = "ratelimit:key"
+
+	// RateLimitAlgorithmMetadataKey sets the limiting algorithm, one of "token-bucket" (the
+	// default) or "sliding-window".
+	RateLimitAlgorithmMetadataKey = "ratelimit:algorithm"
+
+	// RateLimitBurstMetadataKey sets the capacity of the token bucket, it defaults to the
+	// number of requests allowed per period.
+	RateLimitBurstMetadataKey = "ratelimit:burst"
+)
+
+// List of the rate limit algorithms.
+const (
+	// TokenBucket refills the allowance continuously and allows bursts up to the bucket
+	// capacity.
+	TokenBucket = "token-bucket"
+
+	// SlidingWindow counts the requests made during the last period.
+	SlidingWindow = "sliding-window"
+)
+
+// List of the rate limit keys.
+const (
+	// RateLimitKeyIP identifies clients by IP address.
+	RateLimitKeyIP = "ip"
+
+	// RateLimitKeyAPIKey identifies clients by API key.
+	RateLimitKeyAPIKey = "apikey"
+
+	// RateLimitKeyJWT identifies clients by JWT subject.
+	RateLimitKeyJWT = "jwt"
+
+	// RateLimitKeyHeader is the prefix of keys that identify clients by request header.
+	RateLimitKeyHeader = "header:"
+
+	// RateLimitKeyCustom is the prefix of keys that identify clients with runtime key
+	// functions.
+	RateLimitKeyCustom = "custom:"
+)
+
+// RateLimitDefinition describes the rate limit of an action.
+type RateLimitDefinition struct {
+	// Requests is the number of requests allowed per period.
+	Requests int
+	// Period is the duration of the period.
+	Period time.Duration
+	// Burst is the capacity of the token bucket.
+	Burst int
+	// Algorithm is TokenBucket or SlidingWindow.
+	Algorithm string
+	// Key identifies the clients, see RateLimitKeyMetadataKey.
+	Key string
+}
+
+// RateLimit returns the rate limit declared in the action metadata or nil if the action is not
+// rate limited. It returns an error if the metadata is invalid.
+func (a *ActionDefinition) RateLimit() (*RateLimitDefinition, error) {
+	vals, ok := a.Metadata[RateLimitMetadataKey]
+	if !ok || len(vals) == 0 {
+		return nil, nil
+	}
+	requests, period, err := ParseRate(vals[0])
+	if err != nil {
+		return nil, err
+	}
+	rl := &RateLimitDefinition{
+		Requests:  requests,
+		Period:    period,
+		Burst:     requests,
+		Algorithm: TokenBucket,
+		Key:       RateLimitKeyIP,
+	}
+	if vals := a.Metadata[RateLimitBurstMetadataKey]; len(vals) > 0 {
+		burst, err := strconv.Atoi(vals[0])
+		if err != nil || burst < 1 {
+			return nil, fmt.Errorf("invalid %s metadata %q, must be a positive integer", RateLimitBurstMetadataKey, vals[0])
+		}
+		rl.Burst = burst
+	}
+	if vals := a.Metadata[RateLimitAlgorithmMetadataKey]; len(vals) > 0 {
+		switch vals[0] {
+		case TokenBucket, SlidingWindow:
+			rl.Algorithm = vals[0]
+		default:
+			return nil, fmt.Errorf("invalid %s metadata %q, must be %q or %q", RateLimitAlgorithmMetadataKey, vals[0], TokenBucket, SlidingWindow)
+		}
+	}
+	if vals := a.Metadata[RateLimitKeyMetadataKey]; len(vals) > 0 {
+		rl.Key = vals[0]
+	}
+	// The action security is only inherited once finalized, look it up so that the metadata
+	// may be validated.
+	sec := a.Security
+	if sec == nil && a.Parent != nil {
+		sec = a.Parent.Security
+	}
+	if sec == nil && Design != nil {
+		sec = Design.Security
+	}
+	switch {
+	case rl.Key == RateLimitKeyIP:
+	case rl.Key == RateLimitKeyAPIKey:
+		if sec == nil || sec.Scheme.Kind != APIKeySecurityKind {
+			return nil, fmt.Errorf("%s metadata %q requires an APIKey security scheme", RateLimitKeyMetadataKey, rl.Key)
+		}
+	case rl.Key == RateLimitKeyJWT:
+		if sec == nil || sec.Scheme.Kind != JWTSecurityKind {
+			return nil, fmt.Errorf("%s metadata %q requires a JWT security scheme", RateLimitKeyMetadataKey, rl.Key)
+		}
+	case strings.HasPrefix(rl.Key, RateLimitKeyHeader) && len(rl.Key) > len(RateLimitKeyHeader):
+	case strings.HasPrefix(rl.Key, RateLimitKeyCustom) && len(rl.Key) > len(RateLimitKeyCustom):
+	default:
+		return nil, fmt.Errorf("invalid %s metadata %q", RateLimitKeyMetadataKey, rl.Key)
+	}
+	return rl, nil
+}
+
+// HeaderName returns the name of the header used to identify clients if the key is a header key,
+// the empty string otherwise.
+func (rl *RateLimitDefinition) HeaderName() string {
+	if !strings.HasPrefix(rl.Key, RateLimitKeyHeader) {
+		return ""
+	}
+	return rl.Key[len(RateLimitKeyHeader):]
+}
+
+// CustomName returns the name of the runtime key function used to identify clients if the key is
+// a custom key, the empty string otherwise.
+func (rl *RateLimitDefinition) CustomName() string {
+	if !strings.HasPrefix(rl.Key, RateLimitKeyCustom) {
+		return ""
+	}
+	return rl.Key[len(RateLimitKeyCustom):]
+}
+
+// ParseRate parses a rate of the form "<requests>/<period>", e.g. "100/1m" or "10/s".
+func ParseRate(rate string) (int, time.Duration, error) {
+	elems := strings.SplitN(rate, "/", 2)
+	if len(elems) != 2 {
+		return 0, 0, fmt.Errorf("invalid rate %q, must be of the form <requests>/<period>", rate)
+	}
+	requests, err := strconv.Atoi(strings.TrimSpace(elems[0]))
+	if err != nil || requests < 1 {
+		return 0, 0, fmt.Errorf("invalid rate %q, the number of requests must be a positive integer", rate)
+	}
+	p := strings.TrimSpace(elems[1])
+	if p != "" && (p[0] < '0' || p[0] > '9') {
+		p = "1" + p
+	}
+	period, err := time.ParseDuration(p)
+	if err != nil || period <= 0 {
+		return 0, 0, fmt.Errorf("invalid rate %q, the period must be a positive duration", rate)
+	}
+	return requests, period, nil
+}
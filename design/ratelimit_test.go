@@ -0,0 +1,128 @@
+package design_test
+
+import (
+	"time"
+
+	. "github.com/goadesign/goa/design"
+	"github.com/goadesign/goa/dslengine"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RateLimit", func() {
+	var metadata dslengine.MetadataDefinition
+	var security *SecurityDefinition
+
+	var rl *RateLimitDefinition
+	var err error
+
+	BeforeEach(func() {
+		metadata = dslengine.MetadataDefinition{RateLimitMetadataKey: {"100/1m"}}
+		security = nil
+	})
+
+	JustBeforeEach(func() {
+		action := &ActionDefinition{
+			Name:     "show",
+			Parent:   &ResourceDefinition{Name: "bottle"},
+			Metadata: metadata,
+			Security: security,
+		}
+		rl, err = action.RateLimit()
+	})
+
+	It("uses the token bucket algorithm and the client IP by default", func() {
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(rl).Should(Equal(&RateLimitDefinition{
+			Requests:  100,
+			Period:    time.Minute,
+			Burst:     100,
+			Algorithm: TokenBucket,
+			Key:       RateLimitKeyIP,
+		}))
+	})
+
+	Context("with no metadata", func() {
+		BeforeEach(func() {
+			metadata = nil
+		})
+
+		It("returns nil", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(rl).Should(BeNil())
+		})
+	})
+
+	Context("with all the metadata", func() {
+		BeforeEach(func() {
+			metadata[RateLimitBurstMetadataKey] = []string{"10"}
+			metadata[RateLimitAlgorithmMetadataKey] = []string{SlidingWindow}
+			metadata[RateLimitKeyMetadataKey] = []string{"header:X-Tenant"}
+		})
+
+		It("overrides the defaults", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(rl.Burst).Should(Equal(10))
+			Ω(rl.Algorithm).Should(Equal(SlidingWindow))
+			Ω(rl.HeaderName()).Should(Equal("X-Tenant"))
+			Ω(rl.CustomName()).Should(BeEmpty())
+		})
+	})
+
+	Context("with a JWT key and no JWT security", func() {
+		BeforeEach(func() {
+			metadata[RateLimitKeyMetadataKey] = []string{RateLimitKeyJWT}
+		})
+
+		It("returns an error", func() {
+			Ω(err).Should(HaveOccurred())
+		})
+
+		Context("with JWT security", func() {
+			BeforeEach(func() {
+				security = &SecurityDefinition{Scheme: &SecuritySchemeDefinition{Kind: JWTSecurityKind}}
+			})
+
+			It("succeeds", func() {
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(rl.Key).Should(Equal(RateLimitKeyJWT))
+			})
+		})
+	})
+
+	Context("with an invalid algorithm", func() {
+		BeforeEach(func() {
+			metadata[RateLimitAlgorithmMetadataKey] = []string{"leaky"}
+		})
+
+		It("returns an error", func() {
+			Ω(err).Should(HaveOccurred())
+		})
+	})
+})
+
+var _ = Describe("ParseRate", func() {
+	It("parses valid rates", func() {
+		valid := map[string]struct {
+			requests int
+			period   time.Duration
+		}{
+			"100/1m":  {100, time.Minute},
+			"10/s":    {10, time.Second},
+			"5 / 30s": {5, 30 * time.Second},
+		}
+		for rate, expected := range valid {
+			requests, period, err := ParseRate(rate)
+			Ω(err).ShouldNot(HaveOccurred(), rate)
+			Ω(requests).Should(Equal(expected.requests), rate)
+			Ω(period).Should(Equal(expected.period), rate)
+		}
+	})
+
+	It("rejects invalid rates", func() {
+		for _, rate := range []string{"100", "-1/m", "10/forever", "10/0s"} {
+			_, _, err := ParseRate(rate)
+			Ω(err).Should(HaveOccurred(), rate)
+		}
+	})
+})
@@ -22,6 +22,9 @@ const (
 	JWTSecurityKind
 	// NoSecurityKind means to have no security for this endpoint.
 	NoSecurityKind
+	// HTTPSignatureSecurityKind means an "apiKey" security type where the requests are signed
+	// with a shared secret, see the HTTPSignatureSecurity DSL.
+	HTTPSignatureSecurityKind
 )
 
 // SecurityDefinition defines security requirements for an Action
@@ -69,6 +72,8 @@ type SecuritySchemeDefinition struct {
 	TokenURL string `json:"token_url,omitempty"`
 	// AuthorizationURL holds URL for retrieving authorization codes with oauth2
 	AuthorizationURL string `json:"authorization_url,omitempty"`
+	// SignedHeaders lists the headers covered by the signatures of HTTP signature schemes.
+	SignedHeaders []string `json:"signed_headers,omitempty"`
 	// Metadata is a list of key/value pairs
 	Metadata dslengine.MetadataDefinition
 }
@@ -90,12 +95,27 @@ func (s *SecuritySchemeDefinition) Context() string {
 		dslFunc = "APIKeySecurity"
 	case JWTSecurityKind:
 		dslFunc = "JWTSecurity"
+	case HTTPSignatureSecurityKind:
+		dslFunc = "HTTPSignatureSecurity"
 	}
 	return dslFunc
 }
 
-// Validate ensures that TokenURL and AuthorizationURL are valid URLs.
+// Validate ensures that TokenURL and AuthorizationURL are valid URLs and that the signatures of
+// HTTP signature schemes cover the date header.
 func (s *SecuritySchemeDefinition) Validate() error {
+	if s.Kind == HTTPSignatureSecurityKind {
+		covered := false
+		for _, h := range s.SignedHeaders {
+			if h == "date" {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return fmt.Errorf("signed headers of scheme %q must include \"date\"", s.SchemeName)
+		}
+	}
 	_, err := url.Parse(s.TokenURL)
 	if err != nil {
 		return fmt.Errorf("invalid token URL %#v: %s", s.TokenURL, err)
@@ -350,6 +350,37 @@ func (a *ActionDefinition) Validate() *dslengine.ValidationErrors {
 	if a.Parent == nil {
 		verr.Add(a, "missing parent resource")
 	}
+	if _, err := a.RateLimit(); err != nil {
+		verr.Add(a, "%s", err)
+	}
+	if _, err := a.Cache(); err != nil {
+		verr.Add(a, "%s", err)
+	}
+	if _, err := a.Idempotency(); err != nil {
+		verr.Add(a, "%s", err)
+	}
+	if a.Stream != nil {
+		verr.Merge(a.Stream.Validate())
+	}
+	for i, m := range []*UserTypeDefinition{a.InboundMessage, a.OutboundMessage} {
+		if m == nil {
+			continue
+		}
+		kind := "inbound"
+		if i == 1 {
+			kind = "outbound"
+		}
+		if !a.WebSocket() {
+			verr.Add(a, "%s message defined on action that does not use the ws or wss scheme", kind)
+		}
+		if !m.IsObject() {
+			verr.Add(a, "%s message type %s must be an object", kind, m.TypeName)
+		}
+		verr.Merge(m.Validate(kind+" message", a))
+		if HasFile(m.Type) {
+			verr.Add(a, "%s message type %s cannot contain a file", kind, m.TypeName)
+		}
+	}
 	if a.Params != nil {
 		for n, p := range a.Params.Type.ToObject() {
 			if p.Type.IsPrimitive() {
@@ -373,6 +404,22 @@ func (a *ActionDefinition) Validate() *dslengine.ValidationErrors {
 	return verr.AsError()
 }
 
+// Validate checks that the stream media type and view exist and that the parent action is not a
+// websocket action.
+func (s *StreamDefinition) Validate() *dslengine.ValidationErrors {
+	verr := new(dslengine.ValidationErrors)
+	mt := Design.MediaTypeWithIdentifier(s.MediaType)
+	if mt == nil {
+		verr.Add(s, "unknown stream media type %#v", s.MediaType)
+	} else if _, ok := mt.Views[s.EffectiveViewName()]; !ok {
+		verr.Add(s, "unknown view %#v for media type %#v", s.EffectiveViewName(), s.MediaType)
+	}
+	if s.Parent != nil && s.Parent.WebSocket() {
+		verr.Add(s, "websocket actions cannot stream server-sent events")
+	}
+	return verr.AsError()
+}
+
 // Validate checks the file server is properly initialized.
 func (f *FileServerDefinition) Validate() *dslengine.ValidationErrors {
 	verr := new(dslengine.ValidationErrors)
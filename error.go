@@ -58,6 +58,10 @@ var (
 	// MaxRequestBodyLength bytes.
 	ErrRequestBodyTooLarge = NewErrorClass("request_too_large", 413)
 
+	// ErrUnsupportedMediaType is the error produced when a request body uses a content coding
+	// that the service cannot decode, see Service.ContentDecoders.
+	ErrUnsupportedMediaType = NewErrorClass("unsupported_media_type", 415)
+
 	// ErrNoAuthMiddleware is the error produced when no auth middleware is mounted for a
 	// security scheme defined in the design.
 	ErrNoAuthMiddleware = NewErrorClass("no_auth_middleware", 500)
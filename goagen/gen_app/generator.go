@@ -6,6 +6,8 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
+	"time"
 
 	"github.com/goadesign/goa/design"
 	"github.com/goadesign/goa/goagen/codegen"
@@ -144,6 +146,7 @@ func (g *Generator) generateContexts() (err error) {
 	}()
 	title := fmt.Sprintf("%s: Application Contexts", g.API.Context())
 	imports := []*codegen.ImportSpec{
+		codegen.SimpleImport("encoding/json"),
 		codegen.SimpleImport("fmt"),
 		codegen.SimpleImport("net/http"),
 		codegen.SimpleImport("strconv"),
@@ -151,8 +154,10 @@ func (g *Generator) generateContexts() (err error) {
 		codegen.SimpleImport("time"),
 		codegen.SimpleImport("unicode/utf8"),
 		codegen.SimpleImport("github.com/goadesign/goa"),
+		codegen.SimpleImport("github.com/goadesign/goa/middleware/httpcache"),
 		codegen.NewImport("uuid", "github.com/satori/go.uuid"),
 		codegen.SimpleImport("context"),
+		codegen.SimpleImport("golang.org/x/net/websocket"),
 	}
 	g.API.IterateResources(func(r *design.ResourceDefinition) error {
 		return r.IterateActions(func(a *design.ActionDefinition) error {
@@ -184,6 +189,10 @@ func (g *Generator) generateContexts() (err error) {
 			if headers != nil && len(headers.Type.ToObject()) == 0 {
 				headers = nil // So that {{if .Headers}} returns false in templates
 			}
+			cache, err := a.Cache()
+			if err != nil {
+				return fmt.Errorf("action %s of resource %s: %s", a.Name, r.Name, err)
+			}
 			params := a.AllParams()
 			if params != nil && len(params.Type.ToObject()) == 0 {
 				params = nil // So that {{if .Params}} returns false in templates
@@ -191,7 +200,7 @@ func (g *Generator) generateContexts() (err error) {
 
 			non101 := make(map[string]*design.ResponseDefinition)
 			for k, v := range a.Responses {
-				if v.Status != 101 {
+				if v.Status != 101 && !a.StreamResponse(v) {
 					non101[k] = v
 				}
 			}
@@ -207,6 +216,10 @@ func (g *Generator) generateContexts() (err error) {
 				API:          g.API,
 				DefaultPkg:   g.Target,
 				Security:     a.Security,
+				Stream:       a.Stream,
+				Inbound:      a.InboundMessage,
+				Outbound:     a.OutboundMessage,
+				Cache:        cache,
 			}
 			return ctxWr.Execute(&ctxData)
 		})
@@ -241,7 +254,10 @@ func (g *Generator) generateControllers() (err error) {
 		codegen.SimpleImport("context"),
 		codegen.SimpleImport("github.com/goadesign/goa"),
 		codegen.SimpleImport("github.com/goadesign/goa/cors"),
-		codegen.SimpleImport("regexp"),
+		codegen.SimpleImport("github.com/goadesign/goa/middleware/httpcache"),
+		codegen.SimpleImport("github.com/goadesign/goa/middleware/idempotency"),
+		codegen.SimpleImport("github.com/goadesign/goa/middleware/ratelimit"),
+		codegen.SimpleImport("github.com/goadesign/goa/middleware/security/httpsig"),
 		codegen.SimpleImport("strconv"),
 		codegen.SimpleImport("time"),
 		codegen.NewImport("uuid", "github.com/satori/go.uuid"),
@@ -280,7 +296,7 @@ func (g *Generator) generateControllers() (err error) {
 
 	g.genfiles = append(g.genfiles, ctlFile)
 	var controllersData []*ControllerTemplateData
-	g.API.IterateResources(func(r *design.ResourceDefinition) error {
+	err = g.API.IterateResources(func(r *design.ResourceDefinition) error {
 		// Create file servers for all directory file servers that serve index.html.
 		fileServers := r.FileServers
 		for _, fs := range r.FileServers {
@@ -304,9 +320,21 @@ func (g *Generator) generateControllers() (err error) {
 			PreflightPaths: r.PreflightPaths(),
 			FileServers:    fileServers,
 		}
-		r.IterateActions(func(a *design.ActionDefinition) error {
+		err := r.IterateActions(func(a *design.ActionDefinition) error {
 			context := fmt.Sprintf("%s%sContext", codegen.Goify(a.Name, true), codegen.Goify(r.Name, true))
 			unmarshal := fmt.Sprintf("unmarshal%s%sPayload", codegen.Goify(a.Name, true), codegen.Goify(r.Name, true))
+			rateLimit, err := rateLimitCode(a)
+			if err != nil {
+				return err
+			}
+			cache, err := cacheCode(a)
+			if err != nil {
+				return err
+			}
+			idempotency, err := idempotencyCode(a)
+			if err != nil {
+				return err
+			}
 			action := map[string]interface{}{
 				"Name":             codegen.Goify(a.Name, true),
 				"DesignName":       a.Name,
@@ -317,10 +345,18 @@ func (g *Generator) generateControllers() (err error) {
 				"PayloadOptional":  a.PayloadOptional,
 				"PayloadMultipart": a.PayloadMultipart,
 				"Security":         a.Security,
+				"RateLimit":        rateLimit,
+				"Cache":            cache,
+				"Idempotency":      idempotency,
+				"Stream":           a.Stream != nil,
+				"CaptureBody":      a.Security != nil && a.Security.Scheme.Kind == design.HTTPSignatureSecurityKind,
 			}
 			data.Actions = append(data.Actions, action)
 			return nil
 		})
+		if err != nil {
+			return err
+		}
 		if len(data.Actions) > 0 || len(data.FileServers) > 0 {
 			data.Encoders = encoders
 			data.Decoders = decoders
@@ -329,10 +365,111 @@ func (g *Generator) generateControllers() (err error) {
 		}
 		return nil
 	})
+	if err != nil {
+		return
+	}
 	err = ctlWr.Execute(controllersData)
 	return
 }
 
+// rateLimitCode returns the code that builds the rate limit middleware declared in the action
+// metadata or the empty string if the action is not rate limited.
+func rateLimitCode(a *design.ActionDefinition) (string, error) {
+	rl, err := a.RateLimit()
+	if err != nil {
+		return "", fmt.Errorf("action %s of resource %s: %s", a.Name, a.Parent.Name, err)
+	}
+	if rl == nil {
+		return "", nil
+	}
+	var limiter string
+	switch rl.Algorithm {
+	case design.SlidingWindow:
+		limiter = fmt.Sprintf("ratelimit.NewSlidingWindow(%d, %s)", rl.Requests, durationCode(rl.Period))
+	default:
+		limiter = fmt.Sprintf("ratelimit.NewTokenBucket(%d, %s, %d)", rl.Requests, durationCode(rl.Period), rl.Burst)
+	}
+	var key string
+	switch {
+	case rl.Key == design.RateLimitKeyAPIKey:
+		key = fmt.Sprintf("ratelimit.APIKey(New%sSecurity())", codegen.Goify(a.Security.Scheme.SchemeName, true))
+	case rl.Key == design.RateLimitKeyJWT:
+		key = "ratelimit.JWTSubject"
+	case rl.HeaderName() != "":
+		key = fmt.Sprintf("ratelimit.Header(%q)", rl.HeaderName())
+	case rl.CustomName() != "":
+		key = fmt.Sprintf("ratelimit.Custom(%q)", rl.CustomName())
+	default:
+		key = "ratelimit.ClientIP"
+	}
+	return fmt.Sprintf("ratelimit.New(%s, %s)", limiter, key), nil
+}
+
+// cacheCode returns the code that builds the HTTP caching middleware declared in the action
+// metadata or the empty string if the action is not cacheable.
+func cacheCode(a *design.ActionDefinition) (string, error) {
+	c, err := a.Cache()
+	if err != nil {
+		return "", fmt.Errorf("action %s of resource %s: %s", a.Name, a.Parent.Name, err)
+	}
+	if c == nil {
+		return "", nil
+	}
+	var opts []string
+	if c.Directives != "" {
+		opts = append(opts, fmt.Sprintf("httpcache.CacheControl(%q)", c.Directives))
+	}
+	switch c.ETag {
+	case design.WeakETag:
+		opts = append(opts, "httpcache.ETags(httpcache.WeakETag)")
+	case design.NoETag:
+		opts = append(opts, "httpcache.ETags(httpcache.NoETag)")
+	}
+	return fmt.Sprintf("httpcache.New(%s)", strings.Join(opts, ", ")), nil
+}
+
+// idempotencyCode returns the code that builds the idempotency middleware declared in the action
+// metadata or the empty string if the action does not support idempotency keys.
+func idempotencyCode(a *design.ActionDefinition) (string, error) {
+	i, err := a.Idempotency()
+	if err != nil {
+		return "", fmt.Errorf("action %s of resource %s: %s", a.Name, a.Parent.Name, err)
+	}
+	if i == nil {
+		return "", nil
+	}
+	opts := []string{"idempotency.DefaultStore"}
+	if i.TTL != design.DefaultIdempotencyTTL {
+		opts = append(opts, fmt.Sprintf("idempotency.TTL(%s)", durationCode(i.TTL)))
+	}
+	if i.Required {
+		opts = append(opts, "idempotency.Required()")
+	}
+	return fmt.Sprintf("idempotency.New(%s)", strings.Join(opts, ", ")), nil
+}
+
+// durationCode returns the code of a time.Duration value using the largest unit that divides d.
+func durationCode(d time.Duration) string {
+	units := []struct {
+		unit time.Duration
+		name string
+	}{
+		{time.Hour, "time.Hour"},
+		{time.Minute, "time.Minute"},
+		{time.Second, "time.Second"},
+		{time.Millisecond, "time.Millisecond"},
+	}
+	for _, u := range units {
+		if d%u.unit == 0 {
+			if d == u.unit {
+				return u.name
+			}
+			return fmt.Sprintf("%d*%s", d/u.unit, u.name)
+		}
+	}
+	return fmt.Sprintf("time.Duration(%d)", d)
+}
+
 // generateControllers iterates through the API resources and generates the low level
 // controllers.
 func (g *Generator) generateSecurity() (err error) {
@@ -363,6 +500,9 @@ func (g *Generator) generateSecurity() (err error) {
 		codegen.SimpleImport("errors"),
 		codegen.SimpleImport("context"),
 		codegen.SimpleImport("github.com/goadesign/goa"),
+		codegen.SimpleImport("github.com/goadesign/goa/middleware/security/apikey"),
+		codegen.SimpleImport("github.com/goadesign/goa/middleware/security/httpsig"),
+		codegen.SimpleImport("github.com/goadesign/goa/middleware/security/oauth2"),
 	}
 	if err = secWr.WriteHeader(title, g.Target, imports); err != nil {
 		return err
@@ -501,6 +641,10 @@ func (g *Generator) generateUserTypes() (err error) {
 	for _, v := range g.API.Types {
 		imports = codegen.AttributeImports(v.AttributeDefinition, imports, nil)
 	}
+	g.API.IterateInlineMessages(func(m *design.UserTypeDefinition) error {
+		imports = codegen.AttributeImports(m.AttributeDefinition, imports, nil)
+		return nil
+	})
 	if err = utWr.WriteHeader(title, g.Target, imports); err != nil {
 		return err
 	}
@@ -508,5 +652,11 @@ func (g *Generator) generateUserTypes() (err error) {
 	err = g.API.IterateUserTypes(func(t *design.UserTypeDefinition) error {
 		return utWr.Execute(t)
 	})
+	if err != nil {
+		return
+	}
+	err = g.API.IterateInlineMessages(func(m *design.UserTypeDefinition) error {
+		return utWr.Execute(m)
+	})
 	return
 }
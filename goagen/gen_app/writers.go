@@ -85,13 +85,17 @@ type (
 		API          *design.APIDefinition
 		DefaultPkg   string
 		Security     *design.SecurityDefinition
+		Stream       *design.StreamDefinition
+		Inbound      *design.UserTypeDefinition // Type of messages sent by websocket clients
+		Outbound     *design.UserTypeDefinition // Type of messages sent to websocket clients
+		Cache        *design.CacheDefinition    // HTTP caching, nil if the action is not cacheable
 	}
 
 	// ControllerTemplateData contains the information required to generate an action handler.
 	ControllerTemplateData struct {
 		API            *design.APIDefinition          // API definition
 		Resource       string                         // Lower case plural resource name, e.g. "bottles"
-		Actions        []map[string]interface{}       // Array of actions, each action has keys "Name", "DesignName", "Routes", "Context" and "Unmarshal"
+		Actions        []map[string]interface{}       // Array of actions, each action has keys "Name", "DesignName", "Routes", "Context", "Unmarshal", "RateLimit", "Cache" and "Idempotency"
 		FileServers    []*design.FileServerDefinition // File servers
 		Encoders       []*EncoderTemplateData         // Encoder data
 		Decoders       []*EncoderTemplateData         // Decoder data
@@ -219,6 +223,11 @@ func (w *ContextsWriter) Execute(data *ContextTemplateData) error {
 	if err := w.ExecuteTemplate("new", ctxNewT, fn, data); err != nil {
 		return err
 	}
+	if data.Cache != nil {
+		if err := w.ExecuteTemplate("cache", ctxCacheT, nil, data); err != nil {
+			return err
+		}
+	}
 	if data.Payload != nil {
 		found := false
 		for _, t := range design.Design.Types {
@@ -237,6 +246,33 @@ func (w *ContextsWriter) Execute(data *ContextTemplateData) error {
 			}
 		}
 	}
+	if data.Inbound != nil || data.Outbound != nil {
+		fn := template.FuncMap{
+			"finalizeCode":   w.Finalizer.Code,
+			"validationCode": w.Validator.Code,
+		}
+		if err := w.ExecuteTemplate("conn", ctxConnT, fn, data); err != nil {
+			return err
+		}
+	}
+	if data.Stream != nil {
+		mt := design.Design.MediaTypeWithIdentifier(data.Stream.MediaType)
+		if mt == nil {
+			return fmt.Errorf("unknown stream media type %s", data.Stream.MediaType)
+		}
+		projected, _, err := mt.Project(data.Stream.EffectiveViewName())
+		if err != nil {
+			return err
+		}
+		streamData := map[string]interface{}{
+			"Context":   data,
+			"Projected": projected,
+			"Name":      codegen.Goify(data.ActionName, true) + codegen.Goify(data.ResourceName, true) + "Stream",
+		}
+		if err := w.ExecuteTemplate("stream", ctxStreamT, nil, streamData); err != nil {
+			return err
+		}
+	}
 	return data.IterateResponses(func(resp *design.ResponseDefinition) error {
 		respData := map[string]interface{}{
 			"Context":  data,
@@ -464,6 +500,7 @@ type {{ .Name }} struct {
 {{ end }}{{ end }}{{ end }}{{ if .Params }}{{ range $name, $att := .Params.Type.ToObject }}{{/*
 */}}	{{ goifyatt $att $name true }} {{ if and $att.Type.IsPrimitive ($.Params.IsPrimitivePointer $name) }}*{{ end }}{{ gotyperef .Type nil 0 false }}
 {{ end }}{{ end }}{{ if .Payload }}	Payload {{ gotyperef .Payload nil 0 false }}
+{{ end }}{{ if .Stream }}	stream *goa.EventStream
 {{ end }}}
 `
 	// coerceT generates the code that coerces the generic deserialized
@@ -614,6 +651,26 @@ func (ctx *{{ .Context.Name }}) {{ goify .RespName true }}(r {{ gotyperef .Proje
 	}
 {{ end }}	return ctx.ResponseData.Service.Send(ctx.Context, {{ .Response.Status }}, r)
 }
+`
+
+	// ctxCacheT generates the helpers that set the response validators of cacheable actions.
+	// template input: *ContextTemplateData
+	ctxCacheT = `// SetETag sets the ETag response header, weak indicates whether the entity tag is a weak
+// validator.
+func (ctx *{{ .Name }}) SetETag(etag string, weak bool) {
+	httpcache.SetETag(ctx.ResponseData.Header(), etag, weak)
+}
+
+// SetLastModified sets the Last-Modified response header.
+func (ctx *{{ .Name }}) SetLastModified(t time.Time) {
+	httpcache.SetLastModified(ctx.ResponseData.Header(), t)
+}
+
+// CheckPreconditions evaluates the request conditional headers against the validators set with
+// SetETag and SetLastModified and returns an error resulting in a 412 response if they fail.
+func (ctx *{{ .Name }}) CheckPreconditions() error {
+	return httpcache.CheckPreconditions(ctx.Request, ctx.ResponseData.Header())
+}
 `
 
 	// ctxTRespT generates the response helpers for responses with overridden types.
@@ -640,6 +697,81 @@ func (ctx *{{ .Context.Name }}) {{ goify .Response.Name true }}({{ if .Response.
 	return err{{ else }}
 	return nil{{ end }}
 }
+`
+
+	// ctxConnT generates the typed websocket connection of actions that define messages.
+	// template input: *ContextTemplateData
+	ctxConnT = `{{ $name := printf "%s%sConn" (goify .ActionName true) (goify .ResourceName true) }}{{/*
+*/}}// {{ $name }} wraps the websocket connection of the {{ .ResourceName }} {{ .ActionName }} action to exchange typed messages.
+type {{ $name }} struct {
+	*websocket.Conn
+}
+
+// Conn wraps the given websocket connection to exchange the messages defined in the design.
+func (ctx *{{ .Name }}) Conn(ws *websocket.Conn) *{{ $name }} {
+	return &{{ $name }}{Conn: ws}
+}
+{{ if .Inbound }}{{ $msg := .Inbound }}
+// Receive reads the next message sent by the client, validates it and returns it.
+func (c *{{ $name }}) Receive() ({{ gotyperef $msg $msg.AllRequired 0 false }}, error) {
+	var raw {{ gotypename $msg $msg.AllRequired 0 true }}
+	if err := websocket.JSON.Receive(c.Conn, &raw); err != nil {
+		return nil, err
+	}
+{{ if finalizeCode $msg.AttributeDefinition "ut" 1 }}	raw.Finalize()
+{{ end }}{{ if validationCode $msg.AttributeDefinition false false false "ut" "request" 1 true }}	if err := raw.Validate(); err != nil {
+		return nil, err
+	}
+{{ end }}	return raw.Publicize(), nil
+}
+{{ end }}{{ if .Outbound }}{{ $msg := .Outbound }}
+// Send validates the given message and sends it to the client.
+func (c *{{ $name }}) Send(m {{ gotyperef $msg $msg.AllRequired 0 false }}) error {
+{{ if validationCode $msg.AttributeDefinition false false false "ut" "type" 1 false }}	if err := m.Validate(); err != nil {
+		return err
+	}
+{{ end }}	return websocket.JSON.Send(c.Conn, m)
+}
+{{ end }}`
+
+	// ctxStreamT generates the server-sent events stream type and context helpers.
+	// template input: map[string]interface{}
+	ctxStreamT = `// {{ .Name }} sends the server-sent events of the {{ .Context.ResourceName }} {{ .Context.ActionName }} action.
+type {{ .Name }} struct {
+	*goa.EventStream
+}
+
+// Stream writes the server-sent events response headers and returns the stream used to send
+// events. The stream is closed when the client disconnects or when the action returns.
+func (ctx *{{ .Context.Name }}) Stream() (*{{ .Name }}, error) {
+	s, err := goa.NewEventStream(ctx.Context, goa.EventStreamKeepAlive)
+	if err != nil {
+		return nil, err
+	}
+	ctx.stream = s
+	return &{{ .Name }}{EventStream: s}, nil
+}
+
+// closeStream closes the stream created by Stream if any.
+func (ctx *{{ .Context.Name }}) closeStream() {
+	if ctx.stream != nil {
+		ctx.stream.Close()
+	}
+}
+
+// LastEventID returns the ID of the last event received by a reconnecting client, empty if none.
+func (ctx *{{ .Context.Name }}) LastEventID() string {
+	return ctx.RequestData.Header.Get("Last-Event-ID")
+}
+
+// Send sends an event with the given ID and type (both optional) whose data is the JSON encoding of r.
+func (s *{{ .Name }}) Send(id, event string, r {{ gotyperef .Projected .Projected.AllRequired 0 false }}) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return s.EventStream.Send(&goa.Event{ID: id, Event: event, Data: data})
+}
 `
 
 	// payloadT generates the payload type definition GoGenerator
@@ -731,11 +863,15 @@ func Mount{{ .Resource }}Controller(service *goa.Service, ctrl {{ .Resource }}Co
 {{ if not .PayloadOptional }}		} else {
 			return goa.MissingPayloadError()
 {{ end }}		}
+{{ end }}{{ if .Stream }}		defer rctx.closeStream()
 {{ end }}		return ctrl.{{ .Name }}(rctx)
 	}
-{{ if .Security }}	h = handleSecurity({{ printf "%q" .Security.Scheme.SchemeName }}, h{{ range .Security.Scopes }}, {{ printf "%q" . }}{{ end }})
+{{ with .Cache }}	h = {{ . }}(h)
+{{ end }}{{ with .Idempotency }}	h = {{ . }}(h)
+{{ end }}{{ with .RateLimit }}	h = {{ . }}(h)
+{{ end }}{{ if .Security }}	h = handleSecurity({{ printf "%q" .Security.Scheme.SchemeName }}, h{{ range .Security.Scopes }}, {{ printf "%q" . }}{{ end }})
 {{ end }}{{ if $.Origins }}	h = handle{{ $res }}Origin(h)
-{{ end }}{{ range .Routes }}	service.Mux.Handle("{{ .Verb }}", {{ printf "%q" .FullPath }}, ctrl.MuxHandler({{ printf "%q" $action.DesignName }}, h, {{ if $action.Payload }}{{ $action.Unmarshal }}{{ else }}nil{{ end }}))
+{{ end }}{{ range .Routes }}	service.Mux.Handle("{{ .Verb }}", {{ printf "%q" .FullPath }}, ctrl.MuxHandler({{ printf "%q" $action.DesignName }}, h, {{ if not $action.Payload }}nil{{ else if $action.CaptureBody }}httpsig.CaptureBody({{ $action.Unmarshal }}){{ else }}{{ $action.Unmarshal }}{{ end }}))
 	service.LogInfo("mount", "ctrl", {{ printf "%q" $res }}, "action", {{ printf "%q" $action.Name }}, "route", {{ printf "%q" (printf "%s %s" .Verb .FullPath) }}{{ with $action.Security }}, "security", {{ printf "%q" .Scheme.SchemeName }}{{ end }})
 {{ end }}{{ end }}{{ range .FileServers }}
 	h = ctrl.FileHandler({{ printf "%q" .RequestPath }}, {{ printf "%q" .FilePath }})
@@ -746,35 +882,26 @@ func Mount{{ .Resource }}Controller(service *goa.Service, ctrl {{ .Resource }}Co
 {{ end }}}
 `
 
-	// handleCORST generates the code that checks whether a CORS request is authorized
+	// handleCORST generates the CORS policies and the code that applies them.
 	// template input: *ControllerTemplateData
-	handleCORST = `// handle{{ .Resource }}Origin applies the CORS response headers corresponding to the origin.
+	handleCORST = `// {{ .Resource }}CORSPolicies lists the CORS policies of the {{ .Resource }} resource. The policies
+// may be modified before calling Mount{{ .Resource }}Controller.
+var {{ .Resource }}CORSPolicies = []*cors.Policy{
+{{ range .Origins }}	{
+		Origin: {{ printf "%q" .Origin }},
+{{ if .Regexp }}		Regexp: true,
+{{ end }}{{ if .Headers }}		Headers: []string{ {{- range $i, $h := .Headers }}{{ if $i }}, {{ end }}{{ printf "%q" $h }}{{ end }}},
+{{ end }}{{ if .Methods }}		Methods: []string{ {{- range $i, $m := .Methods }}{{ if $i }}, {{ end }}{{ printf "%q" $m }}{{ end }}},
+{{ end }}{{ if .Exposed }}		Exposed: []string{ {{- range $i, $e := .Exposed }}{{ if $i }}, {{ end }}{{ printf "%q" $e }}{{ end }}},
+{{ end }}{{ if gt .MaxAge 0 }}		MaxAge: {{ .MaxAge }},
+{{ end }}{{ if .Credentials }}		Credentials: true,
+{{ end }}{{ if .PrivateNetwork }}		PrivateNetwork: true,
+{{ end }}	},
+{{ end }}}
+
+// handle{{ .Resource }}Origin applies the CORS response headers corresponding to the origin.
 func handle{{ .Resource }}Origin(h goa.Handler) goa.Handler {
-{{ range $i, $policy := .Origins }}{{ if $policy.Regexp }}	spec{{$i}} := regexp.MustCompile({{ printf "%q" $policy.Origin }})
-{{ end }}{{ end }}
-	return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
-		origin := req.Header.Get("Origin")
-		if origin == "" {
-			// Not a CORS request
-			return h(ctx, rw, req)
-		}
-{{ range $i, $policy := .Origins }}		{{ if $policy.Regexp }}if cors.MatchOriginRegexp(origin, spec{{$i}}){{else}}if cors.MatchOrigin(origin, {{ printf "%q" $policy.Origin }}){{end}} {
-			ctx = goa.WithLogContext(ctx, "origin", origin)
-			rw.Header().Set("Access-Control-Allow-Origin", origin)
-{{ if not (eq $policy.Origin "*") }}			rw.Header().Set("Vary", "Origin")
-{{ end }}{{ if $policy.Exposed }}			rw.Header().Set("Access-Control-Expose-Headers", "{{ join $policy.Exposed ", " }}")
-{{ end }}{{ if gt $policy.MaxAge 0 }}			rw.Header().Set("Access-Control-Max-Age", "{{ $policy.MaxAge }}")
-{{ end }}			rw.Header().Set("Access-Control-Allow-Credentials", "{{ $policy.Credentials }}")
-			if acrm := req.Header.Get("Access-Control-Request-Method"); acrm != "" {
-				// We are handling a preflight request
-{{ if $policy.Methods }}				rw.Header().Set("Access-Control-Allow-Methods", "{{ join $policy.Methods ", " }}")
-{{ end }}{{ if $policy.Headers }}				rw.Header().Set("Access-Control-Allow-Headers", "{{ join $policy.Headers ", " }}")
-{{ end }}			}
-			return h(ctx, rw, req)
-		}
-{{ end }}
-		return h(ctx, rw, req)
-	}
+	return cors.Middleware({{ .Resource }}CORSPolicies...)(h)
 }
 `
 
@@ -889,7 +1016,28 @@ type (
 func {{ $funcName }}(service *goa.Service, middleware goa.Middleware) {
 	service.Context = context.WithValue(service.Context, authMiddlewareKey({{ printf "%q" .SchemeName }}), middleware)
 }
-
+{{ if eq .Context "OAuth2Security" }}
+{{ $useFuncName := $funcName }}{{ $funcName := printf "Use%sOAuth2Middleware" (goify .SchemeName true) }}{{/*
+*/}}// {{ $funcName }} mounts the OAuth2 resource server middleware that validates the access tokens
+// with validator onto the service, see package github.com/goadesign/goa/middleware/security/oauth2.
+func {{ $funcName }}(service *goa.Service, validator oauth2.Validator, opts ...oauth2.Option) {
+	{{ $useFuncName }}(service, oauth2.New(validator, opts...))
+}
+{{ else if eq .Context "APIKeySecurity" }}
+{{ $useFuncName := $funcName }}{{ $funcName := printf "Use%sAPIKeyMiddleware" (goify .SchemeName true) }}{{/*
+*/}}// {{ $funcName }} mounts the API key middleware that looks up the keys in store onto the
+// service, see package github.com/goadesign/goa/middleware/security/apikey.
+func {{ $funcName }}(service *goa.Service, store apikey.KeyStore) {
+	{{ $useFuncName }}(service, apikey.New(store, New{{ goify .SchemeName true }}Security()))
+}
+{{ else if eq .Context "HTTPSignatureSecurity" }}
+{{ $useFuncName := $funcName }}{{ $funcName := printf "Use%sHTTPSignatureMiddleware" (goify .SchemeName true) }}{{/*
+*/}}// {{ $funcName }} mounts the middleware that verifies the request signatures with the keys
+// looked up in store onto the service, see package github.com/goadesign/goa/middleware/security/httpsig.
+func {{ $funcName }}(service *goa.Service, store httpsig.KeyStore, opts ...httpsig.Option) {
+	{{ $useFuncName }}(service, httpsig.New(store, New{{ goify .SchemeName true }}Security(), opts...))
+}
+{{ end }}
 {{ $funcName := printf "New%sSecurity" (goify .SchemeName true) }}// {{ $funcName }} creates a {{ .SchemeName }} security definition.
 func {{ $funcName }}() *goa.{{ .Context }} {
 	def := goa.{{ .Context }}{
@@ -906,13 +1054,15 @@ func {{ $funcName }}() *goa.{{ .Context }} {
 */}}		},{{ end }}{{/*
 */}}{{ else if eq .Context "BasicAuthSecurity" }}{{/*
 */}}{{ else if eq .Context "JWTSecurity" }}{{/*
-*/}}		In:   {{ if eq .In "header" }}goa.LocHeader{{ else }}goa.LocQuery{{ end }},
+*/}}		In:   {{ if eq .In "header" }}goa.LocHeader{{ else if eq .In "cookie" }}goa.LocCookie{{ else }}goa.LocQuery{{ end }},
 		Name:             {{ printf "%q" .Name }},
 		TokenURL:         {{ printf "%q" .TokenURL }},{{ with .Scopes }}
 		Scopes: map[string]string{
 {{ range $k, $v := . }}			{{ printf "%q" $k }}: {{ printf "%q" $v }},
 {{ end }}{{/*
 */}}		},{{ end }}
+{{ else if eq .Context "HTTPSignatureSecurity" }}{{/*
+*/}}		Headers: []string{ {{- range $i, $h := .SignedHeaders }}{{ if $i }}, {{ end }}{{ printf "%q" $h }}{{ end -}} },
 {{ end }}{{/*
 */}}	}
 {{ if .Description }} def.Description = {{ printf "%q" .Description }}
@@ -54,6 +54,9 @@ var _ = Describe("ContextsWriter", func() {
 			var payload *design.UserTypeDefinition
 			var responses map[string]*design.ResponseDefinition
 			var routes []*design.RouteDefinition
+			var stream *design.StreamDefinition
+			var inbound, outbound *design.UserTypeDefinition
+			var cache *design.CacheDefinition
 
 			var data *genapp.ContextTemplateData
 
@@ -63,6 +66,10 @@ var _ = Describe("ContextsWriter", func() {
 				payload = nil
 				responses = nil
 				routes = nil
+				stream = nil
+				inbound = nil
+				outbound = nil
+				cache = nil
 				data = nil
 			})
 
@@ -78,6 +85,10 @@ var _ = Describe("ContextsWriter", func() {
 					Routes:       routes,
 					API:          design.Design,
 					DefaultPkg:   "",
+					Stream:       stream,
+					Inbound:      inbound,
+					Outbound:     outbound,
+					Cache:        cache,
 				}
 			})
 
@@ -91,6 +102,22 @@ var _ = Describe("ContextsWriter", func() {
 					Ω(written).ShouldNot(BeEmpty())
 					Ω(written).Should(ContainSubstring(emptyContext))
 					Ω(written).Should(ContainSubstring(emptyContextFactory))
+					Ω(written).ShouldNot(ContainSubstring("SetETag"))
+				})
+			})
+
+			Context("with a cacheable action", func() {
+				BeforeEach(func() {
+					cache = &design.CacheDefinition{Directives: "public, max-age=60", ETag: design.StrongETag}
+				})
+
+				It("writes the validator helpers", func() {
+					err := writer.Execute(data)
+					Ω(err).ShouldNot(HaveOccurred())
+					b, err := ioutil.ReadFile(filename)
+					Ω(err).ShouldNot(HaveOccurred())
+					written := string(b)
+					Ω(written).Should(ContainSubstring(cacheContextHelpers))
 				})
 			})
 
@@ -180,6 +207,68 @@ var _ = Describe("ContextsWriter", func() {
 				})
 			})
 
+			Context("with a stream", func() {
+				BeforeEach(func() {
+					mediaType := &design.MediaTypeDefinition{
+						UserTypeDefinition: &design.UserTypeDefinition{
+							AttributeDefinition: &design.AttributeDefinition{
+								Type: design.Object{"foo": {Type: design.String}},
+							},
+							TypeName: "Event",
+						},
+						Identifier: "application/vnd.goa.event",
+					}
+					defView := &design.ViewDefinition{
+						AttributeDefinition: mediaType.AttributeDefinition,
+						Name:                "default",
+						Parent:              mediaType,
+					}
+					mediaType.Views = map[string]*design.ViewDefinition{"default": defView}
+					design.Design = new(design.APIDefinition)
+					design.Design.MediaTypes = map[string]*design.MediaTypeDefinition{
+						design.CanonicalIdentifier(mediaType.Identifier): mediaType,
+					}
+					design.ProjectedMediaTypes = make(map[string]*design.MediaTypeDefinition)
+					stream = &design.StreamDefinition{MediaType: mediaType.Identifier}
+				})
+
+				It("writes the stream type and context helpers", func() {
+					err := writer.Execute(data)
+					Ω(err).ShouldNot(HaveOccurred())
+					b, err := ioutil.ReadFile(filename)
+					Ω(err).ShouldNot(HaveOccurred())
+					written := string(b)
+					Ω(written).Should(ContainSubstring(streamContext))
+				})
+			})
+
+			Context("with websocket messages", func() {
+				BeforeEach(func() {
+					inbound = &design.UserTypeDefinition{
+						AttributeDefinition: &design.AttributeDefinition{
+							Type:       design.Object{"text": {Type: design.String}},
+							Validation: &dslengine.ValidationDefinition{Required: []string{"text"}},
+						},
+						TypeName: "Message",
+					}
+					outbound = &design.UserTypeDefinition{
+						AttributeDefinition: &design.AttributeDefinition{
+							Type: design.Object{"author": {Type: design.String}},
+						},
+						TypeName: "Event",
+					}
+				})
+
+				It("writes the typed connection", func() {
+					err := writer.Execute(data)
+					Ω(err).ShouldNot(HaveOccurred())
+					b, err := ioutil.ReadFile(filename)
+					Ω(err).ShouldNot(HaveOccurred())
+					written := string(b)
+					Ω(written).Should(ContainSubstring(connContext))
+				})
+			})
+
 			Context("with an integer param", func() {
 				var (
 					intParam   *design.AttributeDefinition
@@ -995,7 +1084,8 @@ var _ = Describe("ControllersWriter", func() {
 
 		Context("with data", func() {
 			var multipart bool
-			var actions, verbs, paths, contexts, unmarshals []string
+			var actions, verbs, paths, contexts, unmarshals, rateLimits, caches, idempotencies []string
+			var streams, captures []bool
 			var payloads []*design.UserTypeDefinition
 			var encoders, decoders []*genapp.EncoderTemplateData
 			var origins []*design.CORSDefinition
@@ -1009,6 +1099,11 @@ var _ = Describe("ControllersWriter", func() {
 				paths = nil
 				contexts = nil
 				unmarshals = nil
+				rateLimits = nil
+				caches = nil
+				idempotencies = nil
+				streams = nil
+				captures = nil
 				payloads = nil
 				encoders = nil
 				decoders = nil
@@ -1024,11 +1119,22 @@ var _ = Describe("ControllersWriter", func() {
 				}
 				as := make([]map[string]interface{}, len(actions))
 				for i, a := range actions {
-					var unmarshal string
+					var unmarshal, rateLimit, cache, idempotency string
 					var payload *design.UserTypeDefinition
 					if i < len(unmarshals) {
 						unmarshal = unmarshals[i]
 					}
+					if i < len(rateLimits) {
+						rateLimit = rateLimits[i]
+					}
+					if i < len(caches) {
+						cache = caches[i]
+					}
+					if i < len(idempotencies) {
+						idempotency = idempotencies[i]
+					}
+					stream := i < len(streams) && streams[i]
+					capture := i < len(captures) && captures[i]
 					if i < len(payloads) {
 						payload = payloads[i]
 					}
@@ -1044,6 +1150,11 @@ var _ = Describe("ControllersWriter", func() {
 						"Unmarshal":        unmarshal,
 						"Payload":          payload,
 						"PayloadMultipart": multipart,
+						"RateLimit":        rateLimit,
+						"Cache":            cache,
+						"Idempotency":      idempotency,
+						"Stream":           stream,
+						"CaptureBody":      capture,
 					}
 				}
 				if len(as) > 0 {
@@ -1088,6 +1199,113 @@ var _ = Describe("ControllersWriter", func() {
 				})
 			})
 
+			Context("with a rate limited action", func() {
+				BeforeEach(func() {
+					actions = []string{"list"}
+					verbs = []string{"GET"}
+					paths = []string{"/accounts/:accountID/bottles"}
+					contexts = []string{"ListBottleContext"}
+					rateLimits = []string{"ratelimit.New(ratelimit.NewTokenBucket(100, time.Minute, 100), ratelimit.ClientIP)"}
+				})
+
+				It("wraps the handler with the rate limit middleware", func() {
+					err := writer.Execute(data)
+					Ω(err).ShouldNot(HaveOccurred())
+					b, err := ioutil.ReadFile(filename)
+					Ω(err).ShouldNot(HaveOccurred())
+					written := string(b)
+					Ω(written).Should(ContainSubstring(rateLimitedMount))
+				})
+			})
+
+			Context("with a cacheable action", func() {
+				BeforeEach(func() {
+					actions = []string{"list"}
+					verbs = []string{"GET"}
+					paths = []string{"/accounts/:accountID/bottles"}
+					contexts = []string{"ListBottleContext"}
+					caches = []string{`httpcache.New(httpcache.CacheControl("public, max-age=60"))`}
+					rateLimits = []string{"ratelimit.New(ratelimit.NewTokenBucket(100, time.Minute, 100), ratelimit.ClientIP)"}
+				})
+
+				It("wraps the handler with the caching middleware", func() {
+					err := writer.Execute(data)
+					Ω(err).ShouldNot(HaveOccurred())
+					b, err := ioutil.ReadFile(filename)
+					Ω(err).ShouldNot(HaveOccurred())
+					written := string(b)
+					Ω(written).Should(ContainSubstring(cachedMount))
+				})
+			})
+
+			Context("with an action supporting idempotency keys", func() {
+				BeforeEach(func() {
+					actions = []string{"create"}
+					verbs = []string{"POST"}
+					paths = []string{"/accounts/:accountID/bottles"}
+					contexts = []string{"CreateBottleContext"}
+					idempotencies = []string{"idempotency.New(idempotency.DefaultStore, idempotency.Required())"}
+				})
+
+				It("wraps the handler with the idempotency middleware", func() {
+					err := writer.Execute(data)
+					Ω(err).ShouldNot(HaveOccurred())
+					b, err := ioutil.ReadFile(filename)
+					Ω(err).ShouldNot(HaveOccurred())
+					written := string(b)
+					Ω(written).Should(ContainSubstring(idempotentMount))
+				})
+			})
+
+			Context("with an action streaming server-sent events", func() {
+				BeforeEach(func() {
+					actions = []string{"list"}
+					verbs = []string{"GET"}
+					paths = []string{"/accounts/:accountID/bottles"}
+					contexts = []string{"ListBottleContext"}
+					streams = []bool{true}
+				})
+
+				It("closes the stream when the action returns", func() {
+					err := writer.Execute(data)
+					Ω(err).ShouldNot(HaveOccurred())
+					b, err := ioutil.ReadFile(filename)
+					Ω(err).ShouldNot(HaveOccurred())
+					written := string(b)
+					Ω(written).Should(ContainSubstring(streamMount))
+				})
+			})
+
+			Context("with an action secured with a HTTP signature scheme", func() {
+				BeforeEach(func() {
+					actions = []string{"create"}
+					verbs = []string{"POST"}
+					paths = []string{"/accounts/:accountID/bottles"}
+					contexts = []string{"CreateBottleContext"}
+					unmarshals = []string{"unmarshalCreateBottlePayload"}
+					payloads = []*design.UserTypeDefinition{
+						{
+							TypeName: "CreateBottlePayload",
+							AttributeDefinition: &design.AttributeDefinition{
+								Type: design.Object{
+									"name": &design.AttributeDefinition{Type: design.String},
+								},
+							},
+						},
+					}
+					captures = []bool{true}
+				})
+
+				It("captures the request body before unmarshaling it", func() {
+					err := writer.Execute(data)
+					Ω(err).ShouldNot(HaveOccurred())
+					b, err := ioutil.ReadFile(filename)
+					Ω(err).ShouldNot(HaveOccurred())
+					written := string(b)
+					Ω(written).Should(ContainSubstring(signedMount))
+				})
+			})
+
 			Context("with actions that take a payload", func() {
 				BeforeEach(func() {
 					actions = []string{"list"}
@@ -1454,6 +1672,104 @@ var _ = Describe("HrefWriter", func() {
 	})
 })
 
+var _ = Describe("SecurityWriter", func() {
+	var writer *genapp.SecurityWriter
+	var workspace *codegen.Workspace
+	var filename string
+
+	BeforeEach(func() {
+		var err error
+		workspace, err = codegen.NewWorkspace("test")
+		Ω(err).ShouldNot(HaveOccurred())
+		pkg, err := workspace.NewPackage("controllers")
+		Ω(err).ShouldNot(HaveOccurred())
+		src, err := pkg.CreateSourceFile("test.go")
+		Ω(err).ShouldNot(HaveOccurred())
+		defer src.Close()
+		filename = src.Abs()
+		writer, err = genapp.NewSecurityWriter(filename)
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		workspace.Delete()
+	})
+
+	Context("with an OAuth2 security scheme", func() {
+		BeforeEach(func() {
+			schemes := []*design.SecuritySchemeDefinition{{
+				SchemeName: "googAuth",
+				Kind:       design.OAuth2SecurityKind,
+				Flow:       "accessCode",
+				TokenURL:   "https://example.com/token",
+			}}
+			Ω(writer.Execute(schemes)).ShouldNot(HaveOccurred())
+		})
+
+		It("generates the OAuth2 middleware helper", func() {
+			b, err := ioutil.ReadFile(filename)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(b)).Should(ContainSubstring(oauth2MiddlewareHelper))
+		})
+	})
+
+	Context("with an API key security scheme", func() {
+		BeforeEach(func() {
+			schemes := []*design.SecuritySchemeDefinition{{
+				SchemeName: "api_key",
+				Kind:       design.APIKeySecurityKind,
+				In:         "header",
+				Name:       "X-API-Key",
+			}}
+			Ω(writer.Execute(schemes)).ShouldNot(HaveOccurred())
+		})
+
+		It("generates the API key middleware helper", func() {
+			b, err := ioutil.ReadFile(filename)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(b)).Should(ContainSubstring(apiKeyMiddlewareHelper))
+		})
+	})
+
+	Context("with a JWT security scheme read from a cookie", func() {
+		BeforeEach(func() {
+			schemes := []*design.SecuritySchemeDefinition{{
+				SchemeName: "jwt",
+				Kind:       design.JWTSecurityKind,
+				In:         "cookie",
+				Name:       "token",
+			}}
+			Ω(writer.Execute(schemes)).ShouldNot(HaveOccurred())
+		})
+
+		It("generates the security definition", func() {
+			b, err := ioutil.ReadFile(filename)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(b)).Should(ContainSubstring("In:   goa.LocCookie,"))
+		})
+	})
+
+	Context("with an HTTP signature security scheme", func() {
+		BeforeEach(func() {
+			schemes := []*design.SecuritySchemeDefinition{{
+				SchemeName:    "signed",
+				Kind:          design.HTTPSignatureSecurityKind,
+				In:            "header",
+				Name:          "Signature",
+				SignedHeaders: []string{"(request-target)", "date", "digest"},
+			}}
+			Ω(writer.Execute(schemes)).ShouldNot(HaveOccurred())
+		})
+
+		It("generates the security definition and middleware helper", func() {
+			b, err := ioutil.ReadFile(filename)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(b)).Should(ContainSubstring(httpSignatureMiddlewareHelper))
+			Ω(string(b)).Should(ContainSubstring(httpSignatureSecurity))
+		})
+	})
+})
+
 var _ = Describe("UserTypesWriter", func() {
 	var writer *genapp.UserTypesWriter
 	var workspace *codegen.Workspace
@@ -2362,6 +2678,51 @@ type PublicController interface {
 
 	fileServerOptionsHandler = `service.Mux.Handle("OPTIONS", "/public/star\\*star/*filepath", ctrl.MuxHandler("preflight", handlePublicOrigin(cors.HandlePreflight()), nil))`
 
+	rateLimitedMount = `		return ctrl.List(rctx)
+	}
+	h = ratelimit.New(ratelimit.NewTokenBucket(100, time.Minute, 100), ratelimit.ClientIP)(h)
+	service.Mux.Handle("GET", "/accounts/:accountID/bottles", ctrl.MuxHandler("list", h, nil))
+`
+
+	cachedMount = `		return ctrl.List(rctx)
+	}
+	h = httpcache.New(httpcache.CacheControl("public, max-age=60"))(h)
+	h = ratelimit.New(ratelimit.NewTokenBucket(100, time.Minute, 100), ratelimit.ClientIP)(h)
+	service.Mux.Handle("GET", "/accounts/:accountID/bottles", ctrl.MuxHandler("list", h, nil))
+`
+
+	streamMount = `		defer rctx.closeStream()
+		return ctrl.List(rctx)
+	}
+`
+
+	signedMount = `	service.Mux.Handle("POST", "/accounts/:accountID/bottles", ctrl.MuxHandler("create", h, httpsig.CaptureBody(unmarshalCreateBottlePayload)))
+`
+
+	idempotentMount = `		return ctrl.Create(rctx)
+	}
+	h = idempotency.New(idempotency.DefaultStore, idempotency.Required())(h)
+	service.Mux.Handle("POST", "/accounts/:accountID/bottles", ctrl.MuxHandler("create", h, nil))
+`
+
+	cacheContextHelpers = `// SetETag sets the ETag response header, weak indicates whether the entity tag is a weak
+// validator.
+func (ctx *ListBottleContext) SetETag(etag string, weak bool) {
+	httpcache.SetETag(ctx.ResponseData.Header(), etag, weak)
+}
+
+// SetLastModified sets the Last-Modified response header.
+func (ctx *ListBottleContext) SetLastModified(t time.Time) {
+	httpcache.SetLastModified(ctx.ResponseData.Header(), t)
+}
+
+// CheckPreconditions evaluates the request conditional headers against the validators set with
+// SetETag and SetLastModified and returns an error resulting in a 412 response if they fail.
+func (ctx *ListBottleContext) CheckPreconditions() error {
+	return httpcache.CheckPreconditions(ctx.Request, ctx.ResponseData.Header())
+}
+`
+
 	simpleController = `// BottlesController is the controller interface for the Bottles actions.
 type BottlesController interface {
 	goa.Muxer
@@ -2373,84 +2734,50 @@ type BottlesController interface {
 	h = handleBottlesOrigin(h)
 	service.Mux.Handle`
 
-	originsHandler = `// handleBottlesOrigin applies the CORS response headers corresponding to the origin.
+	originsHandler = `// BottlesCORSPolicies lists the CORS policies of the Bottles resource. The policies
+// may be modified before calling MountBottlesController.
+var BottlesCORSPolicies = []*cors.Policy{
+	{
+		Origin: "here.example.com",
+		Headers: []string{"X-One", "X-Two"},
+		Methods: []string{"GET", "POST"},
+		Exposed: []string{"X-Three"},
+		Credentials: true,
+	},
+	{
+		Origin: "there.example.com",
+		Headers: []string{"*"},
+		Methods: []string{"*"},
+	},
+}
+
+// handleBottlesOrigin applies the CORS response headers corresponding to the origin.
 func handleBottlesOrigin(h goa.Handler) goa.Handler {
-
-	return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
-		origin := req.Header.Get("Origin")
-		if origin == "" {
-			// Not a CORS request
-			return h(ctx, rw, req)
-		}
-		if cors.MatchOrigin(origin, "here.example.com") {
-			ctx = goa.WithLogContext(ctx, "origin", origin)
-			rw.Header().Set("Access-Control-Allow-Origin", origin)
-			rw.Header().Set("Vary", "Origin")
-			rw.Header().Set("Access-Control-Expose-Headers", "X-Three")
-			rw.Header().Set("Access-Control-Allow-Credentials", "true")
-			if acrm := req.Header.Get("Access-Control-Request-Method"); acrm != "" {
-				// We are handling a preflight request
-				rw.Header().Set("Access-Control-Allow-Methods", "GET, POST")
-				rw.Header().Set("Access-Control-Allow-Headers", "X-One, X-Two")
-			}
-			return h(ctx, rw, req)
-		}
-		if cors.MatchOrigin(origin, "there.example.com") {
-			ctx = goa.WithLogContext(ctx, "origin", origin)
-			rw.Header().Set("Access-Control-Allow-Origin", origin)
-			rw.Header().Set("Vary", "Origin")
-			rw.Header().Set("Access-Control-Allow-Credentials", "false")
-			if acrm := req.Header.Get("Access-Control-Request-Method"); acrm != "" {
-				// We are handling a preflight request
-				rw.Header().Set("Access-Control-Allow-Methods", "*")
-				rw.Header().Set("Access-Control-Allow-Headers", "*")
-			}
-			return h(ctx, rw, req)
-		}
-
-		return h(ctx, rw, req)
-	}
+	return cors.Middleware(BottlesCORSPolicies...)(h)
 }
 `
 
-	regexpOriginsHandler = `// handleBottlesOrigin applies the CORS response headers corresponding to the origin.
+	regexpOriginsHandler = `// BottlesCORSPolicies lists the CORS policies of the Bottles resource. The policies
+// may be modified before calling MountBottlesController.
+var BottlesCORSPolicies = []*cors.Policy{
+	{
+		Origin: "[here|there].example.com",
+		Regexp: true,
+		Headers: []string{"X-One", "X-Two"},
+		Methods: []string{"GET", "POST"},
+		Exposed: []string{"X-Three"},
+		Credentials: true,
+	},
+	{
+		Origin: "there.example.com",
+		Headers: []string{"*"},
+		Methods: []string{"*"},
+	},
+}
+
+// handleBottlesOrigin applies the CORS response headers corresponding to the origin.
 func handleBottlesOrigin(h goa.Handler) goa.Handler {
-	spec0 := regexp.MustCompile("[here|there].example.com")
-
-	return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
-		origin := req.Header.Get("Origin")
-		if origin == "" {
-			// Not a CORS request
-			return h(ctx, rw, req)
-		}
-		if cors.MatchOriginRegexp(origin, spec0) {
-			ctx = goa.WithLogContext(ctx, "origin", origin)
-			rw.Header().Set("Access-Control-Allow-Origin", origin)
-			rw.Header().Set("Vary", "Origin")
-			rw.Header().Set("Access-Control-Expose-Headers", "X-Three")
-			rw.Header().Set("Access-Control-Allow-Credentials", "true")
-			if acrm := req.Header.Get("Access-Control-Request-Method"); acrm != "" {
-				// We are handling a preflight request
-				rw.Header().Set("Access-Control-Allow-Methods", "GET, POST")
-				rw.Header().Set("Access-Control-Allow-Headers", "X-One, X-Two")
-			}
-			return h(ctx, rw, req)
-		}
-		if cors.MatchOrigin(origin, "there.example.com") {
-			ctx = goa.WithLogContext(ctx, "origin", origin)
-			rw.Header().Set("Access-Control-Allow-Origin", origin)
-			rw.Header().Set("Vary", "Origin")
-			rw.Header().Set("Access-Control-Allow-Credentials", "false")
-			if acrm := req.Header.Get("Access-Control-Request-Method"); acrm != "" {
-				// We are handling a preflight request
-				rw.Header().Set("Access-Control-Allow-Methods", "*")
-				rw.Header().Set("Access-Control-Allow-Headers", "*")
-			}
-			return h(ctx, rw, req)
-		}
-
-		return h(ctx, rw, req)
-	}
+	return cors.Middleware(BottlesCORSPolicies...)(h)
 }
 `
 
@@ -2607,5 +2934,100 @@ type ComplexPayload struct {
 	Misc map[int]*MiscPayload ` + "`" + `form:"misc,omitempty" json:"misc,omitempty" yaml:"misc,omitempty" xml:"misc,omitempty"` + "`" + `
 	Name *string ` + "`" + `form:"name,omitempty" json:"name,omitempty" yaml:"name,omitempty" xml:"name,omitempty"` + "`" + `
 }
+`
+
+	streamContext = `// ListBottlesStream sends the server-sent events of the bottles list action.
+type ListBottlesStream struct {
+	*goa.EventStream
+}
+
+// Stream writes the server-sent events response headers and returns the stream used to send
+// events. The stream is closed when the client disconnects or when the action returns.
+func (ctx *ListBottleContext) Stream() (*ListBottlesStream, error) {
+	s, err := goa.NewEventStream(ctx.Context, goa.EventStreamKeepAlive)
+	if err != nil {
+		return nil, err
+	}
+	ctx.stream = s
+	return &ListBottlesStream{EventStream: s}, nil
+}
+
+// closeStream closes the stream created by Stream if any.
+func (ctx *ListBottleContext) closeStream() {
+	if ctx.stream != nil {
+		ctx.stream.Close()
+	}
+}
+
+// LastEventID returns the ID of the last event received by a reconnecting client, empty if none.
+func (ctx *ListBottleContext) LastEventID() string {
+	return ctx.RequestData.Header.Get("Last-Event-ID")
+}
+
+// Send sends an event with the given ID and type (both optional) whose data is the JSON encoding of r.
+func (s *ListBottlesStream) Send(id, event string, r *Event) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return s.EventStream.Send(&goa.Event{ID: id, Event: event, Data: data})
+}
+`
+
+	connContext = `// ListBottlesConn wraps the websocket connection of the bottles list action to exchange typed messages.
+type ListBottlesConn struct {
+	*websocket.Conn
+}
+
+// Conn wraps the given websocket connection to exchange the messages defined in the design.
+func (ctx *ListBottleContext) Conn(ws *websocket.Conn) *ListBottlesConn {
+	return &ListBottlesConn{Conn: ws}
+}
+
+// Receive reads the next message sent by the client, validates it and returns it.
+func (c *ListBottlesConn) Receive() (*Message, error) {
+	var raw message
+	if err := websocket.JSON.Receive(c.Conn, &raw); err != nil {
+		return nil, err
+	}
+	if err := raw.Validate(); err != nil {
+		return nil, err
+	}
+	return raw.Publicize(), nil
+}
+
+// Send validates the given message and sends it to the client.
+func (c *ListBottlesConn) Send(m *Event) error {
+	return websocket.JSON.Send(c.Conn, m)
+}
+`
+
+	apiKeyMiddlewareHelper = `// UseAPIKeyAPIKeyMiddleware mounts the API key middleware that looks up the keys in store onto the
+// service, see package github.com/goadesign/goa/middleware/security/apikey.
+func UseAPIKeyAPIKeyMiddleware(service *goa.Service, store apikey.KeyStore) {
+	UseAPIKeyMiddleware(service, apikey.New(store, NewAPIKeySecurity()))
+}
+`
+
+	httpSignatureMiddlewareHelper = `// UseSignedHTTPSignatureMiddleware mounts the middleware that verifies the request signatures with the keys
+// looked up in store onto the service, see package github.com/goadesign/goa/middleware/security/httpsig.
+func UseSignedHTTPSignatureMiddleware(service *goa.Service, store httpsig.KeyStore, opts ...httpsig.Option) {
+	UseSignedMiddleware(service, httpsig.New(store, NewSignedSecurity(), opts...))
+}
+`
+
+	httpSignatureSecurity = `func NewSignedSecurity() *goa.HTTPSignatureSecurity {
+	def := goa.HTTPSignatureSecurity{
+		Headers: []string{"(request-target)", "date", "digest"},
+	}
+	return &def
+}
+`
+
+	oauth2MiddlewareHelper = `// UseGoogAuthOAuth2Middleware mounts the OAuth2 resource server middleware that validates the access tokens
+// with validator onto the service, see package github.com/goadesign/goa/middleware/security/oauth2.
+func UseGoogAuthOAuth2Middleware(service *goa.Service, validator oauth2.Validator, opts ...oauth2.Option) {
+	UseGoogAuthMiddleware(service, oauth2.New(validator, opts...))
+}
 `
 )
@@ -59,9 +59,14 @@ func (g *Generator) generateMain(mainFile string, clientPkg, cliPkg string, func
 	hasBasicAuthSigners := false
 	hasAPIKeySigners := false
 	hasTokenSigners := false
+	hasHTTPSignatureSigners := false
 	for _, s := range g.API.SecuritySchemes {
 		if signerType(s) != "" {
 			hasSigners = true
+			if s.Kind == design.HTTPSignatureSecurityKind {
+				hasHTTPSignatureSigners = true
+				continue
+			}
 			switch s.Type {
 			case "basic":
 				hasBasicAuthSigners = true
@@ -74,21 +79,23 @@ func (g *Generator) generateMain(mainFile string, clientPkg, cliPkg string, func
 	}
 
 	data := struct {
-		API                 *design.APIDefinition
-		Version             string
-		Package             string
-		HasSigners          bool
-		HasBasicAuthSigners bool
-		HasAPIKeySigners    bool
-		HasTokenSigners     bool
+		API                     *design.APIDefinition
+		Version                 string
+		Package                 string
+		HasSigners              bool
+		HasBasicAuthSigners     bool
+		HasAPIKeySigners        bool
+		HasTokenSigners         bool
+		HasHTTPSignatureSigners bool
 	}{
-		API:                 g.API,
-		Version:             version,
-		Package:             g.Target,
-		HasSigners:          hasSigners,
-		HasBasicAuthSigners: hasBasicAuthSigners,
-		HasAPIKeySigners:    hasAPIKeySigners,
-		HasTokenSigners:     hasTokenSigners,
+		API:                     g.API,
+		Version:                 version,
+		Package:                 g.Target,
+		HasSigners:              hasSigners,
+		HasBasicAuthSigners:     hasBasicAuthSigners,
+		HasAPIKeySigners:        hasAPIKeySigners,
+		HasTokenSigners:         hasTokenSigners,
+		HasHTTPSignatureSigners: hasHTTPSignatureSigners,
 	}
 	err = file.ExecuteTemplate("main", mainTmpl, funcs, data)
 	return
@@ -523,6 +530,9 @@ func routes(action *design.ActionDefinition) string {
 // signerSignature returns the callee signature for the signer factory function for the given security
 // scheme.
 func signerSignature(sec *design.SecuritySchemeDefinition) string {
+	if sec.Kind == design.HTTPSignatureSecurityKind {
+		return "keyID, secret string"
+	}
 	switch sec.Type {
 	case "basic":
 		return "user, pass string"
@@ -540,6 +550,9 @@ func signerSignature(sec *design.SecuritySchemeDefinition) string {
 // signerArgs returns the caller signature for the signer factory function for the given security
 // scheme.
 func signerArgs(sec *design.SecuritySchemeDefinition) string {
+	if sec.Kind == design.HTTPSignatureSecurityKind {
+		return "keyID, secret"
+	}
 	switch sec.Type {
 	case "basic":
 		return "user, pass"
@@ -639,6 +652,9 @@ func main() {
 {{ end }}{{ if .HasTokenSigners }} var token, typ string
 	app.PersistentFlags().StringVar(&token, "token", "", "Token used for authentication")
 	app.PersistentFlags().StringVar(&typ, "token-type", "Bearer", "Token type used for authentication")
+{{ end }}{{ if .HasHTTPSignatureSigners }} var keyID, secret string
+	app.PersistentFlags().StringVar(&keyID, "key-id", "", "ID of the key used to sign requests")
+	app.PersistentFlags().StringVar(&secret, "secret", "", "Secret used to sign requests")
 {{ end }}
 	// Parse flags and setup signers
 	app.ParseFlags(os.Args)
@@ -675,7 +691,12 @@ func newHTTPClient() *http.Client {
 // new{{ goify $security.SchemeName true }}Signer returns the request signer used for authenticating
 // against the {{ $security.SchemeName }} security scheme.
 func new{{ goify $security.SchemeName true }}Signer({{ signerSignature $security }}) goaclient.Signer {
-{{ if eq .Type "basic" }}	return &goaclient.BasicSigner{
+{{ if eq .Context "HTTPSignatureSecurity" }}	return &goaclient.HTTPSignatureSigner{
+		KeyID: keyID,
+		Secret: []byte(secret),
+		Headers: []string{ {{- range $i, $h := .SignedHeaders }}{{ if $i }}, {{ end }}{{ printf "%q" $h }}{{ end -}} },
+	}
+{{ else if eq .Type "basic" }}	return &goaclient.BasicSigner{
 		Username: user,
 		Password: pass,
 	}
@@ -491,4 +491,61 @@ var _ = Describe("Generate", func() {
 			Ω(content).Should(ContainSubstring("c.SetJWT1Signer(jwt1Signer)"))
 		})
 	})
+
+	Context("with an action secured with HTTP signatures", func() {
+		BeforeEach(func() {
+			codegen.TempCount = 0
+			securitySchemeDef := &design.SecuritySchemeDefinition{
+				SchemeName:    "signed",
+				Kind:          design.HTTPSignatureSecurityKind,
+				Type:          "apiKey",
+				In:            "header",
+				Name:          "Signature",
+				SignedHeaders: []string{"(request-target)", "date", "digest"},
+			}
+			design.Design = &design.APIDefinition{
+				Name:        "testapi",
+				Title:       "dummy API with no resource",
+				Description: "I told you it's dummy",
+				Consumes:    design.DefaultEncoders,
+				SecuritySchemes: []*design.SecuritySchemeDefinition{
+					securitySchemeDef,
+				},
+				Resources: map[string]*design.ResourceDefinition{
+					"foo": {
+						Name: "foo",
+						Actions: map[string]*design.ActionDefinition{
+							"show": {
+								Name: "show",
+								Routes: []*design.RouteDefinition{
+									{
+										Verb: "GET",
+										Path: "",
+									},
+								},
+								Security: &design.SecurityDefinition{
+									Scheme: securitySchemeDef,
+								},
+							},
+						},
+					},
+				},
+			}
+			fooRes := design.Design.Resources["foo"]
+			showAct := fooRes.Actions["show"]
+			showAct.Parent = fooRes
+			showAct.Routes[0].Parent = showAct
+		})
+
+		It("generates the HTTP signature signer from main", func() {
+			Ω(genErr).Should(BeNil())
+			c, err := ioutil.ReadFile(filepath.Join(outDir, "tool", "testapi-cli", "main.go"))
+			content := string(c)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(content).Should(ContainSubstring(`app.PersistentFlags().StringVar(&keyID, "key-id", "", "ID of the key used to sign requests")`))
+			Ω(content).Should(ContainSubstring("signedSigner := newSignedSigner(keyID, secret)"))
+			Ω(content).Should(ContainSubstring(`Headers: []string{"(request-target)", "date", "digest"},`))
+			Ω(content).ShouldNot(ContainSubstring("APIKeySigner"))
+		})
+	})
 })
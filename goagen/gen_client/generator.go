@@ -346,6 +346,8 @@ func (g *Generator) generateResourceClient(pkgDir string, res *design.ResourceDe
 		codegen.SimpleImport("time"),
 		codegen.SimpleImport("context"),
 		codegen.SimpleImport("golang.org/x/net/websocket"),
+		codegen.SimpleImport("github.com/goadesign/goa"),
+		codegen.NewImport("goaclient", "github.com/goadesign/goa/client"),
 		codegen.NewImport("uuid", "github.com/goadesign/goa/uuid"),
 	}
 	title := fmt.Sprintf("%s: %s Resource Client", g.API.Context(), res.Name)
@@ -526,12 +528,69 @@ func (g *Generator) generateActionClient(action *design.ActionDefinition, file *
 		Headers:            headers,
 	}
 	if action.WebSocket() {
-		return clientsWSTmpl.Execute(file, data)
+		if err := clientsWSTmpl.Execute(file, data); err != nil {
+			return err
+		}
+		if action.InboundMessage != nil || action.OutboundMessage != nil {
+			return g.generateConn(action, file, funcs)
+		}
+		return nil
 	}
 	if err := clientsTmpl.Execute(file, data); err != nil {
 		return err
 	}
-	return requestsTmpl.Execute(file, data)
+	if err := requestsTmpl.Execute(file, data); err != nil {
+		return err
+	}
+	if action.Stream != nil {
+		return g.generateEventReader(action, file, funcs)
+	}
+	return nil
+}
+
+// generateConn generates the typed websocket connection used to exchange the messages of the
+// given action.
+func (g *Generator) generateConn(action *design.ActionDefinition, file *codegen.SourceFile, funcs template.FuncMap) error {
+	connTmpl := template.Must(template.New("conn").Funcs(funcs).Funcs(template.FuncMap{
+		"finalizeCode":   codegen.NewFinalizer().Code,
+		"validationCode": codegen.NewValidator().Code,
+	}).Parse(connTmpl))
+	data := struct {
+		Name         string
+		ResourceName string
+		Inbound      *design.UserTypeDefinition
+		Outbound     *design.UserTypeDefinition
+	}{
+		Name:         action.Name,
+		ResourceName: action.Parent.Name,
+		Inbound:      action.InboundMessage,
+		Outbound:     action.OutboundMessage,
+	}
+	return connTmpl.Execute(file, data)
+}
+
+// generateEventReader generates the reader of the server-sent events streamed by the given
+// action.
+func (g *Generator) generateEventReader(action *design.ActionDefinition, file *codegen.SourceFile, funcs template.FuncMap) error {
+	eventReaderTmpl := template.Must(template.New("eventreader").Funcs(funcs).Parse(eventReaderTmpl))
+	mt := design.Design.MediaTypeWithIdentifier(action.Stream.MediaType)
+	if mt == nil {
+		return fmt.Errorf("unknown stream media type %s", action.Stream.MediaType)
+	}
+	projected, _, err := mt.Project(action.Stream.EffectiveViewName())
+	if err != nil {
+		return err
+	}
+	data := struct {
+		Name         string
+		ResourceName string
+		Projected    *design.MediaTypeDefinition
+	}{
+		Name:         action.Name,
+		ResourceName: action.Parent.Name,
+		Projected:    projected,
+	}
+	return eventReaderTmpl.Execute(file, data)
 }
 
 // fileServerMethod returns the name of the client method for downloading assets served by the given
@@ -652,6 +711,10 @@ func (g *Generator) generateUserTypes(pkgDir string) (err error) {
 	for _, v := range g.API.Types {
 		imports = codegen.AttributeImports(v.AttributeDefinition, imports, nil)
 	}
+	g.API.IterateInlineMessages(func(m *design.UserTypeDefinition) error {
+		imports = codegen.AttributeImports(m.AttributeDefinition, imports, nil)
+		return nil
+	})
 	if err = utWr.WriteHeader(title, g.Target, imports); err != nil {
 		return err
 	}
@@ -665,6 +728,12 @@ func (g *Generator) generateUserTypes(pkgDir string) (err error) {
 		}
 		return utWr.Execute(t)
 	})
+	if err != nil {
+		return
+	}
+	err = g.API.IterateInlineMessages(func(m *design.UserTypeDefinition) error {
+		return utWr.Execute(m)
+	})
 	return
 }
 
@@ -846,6 +915,8 @@ func signerType(scheme *design.SecuritySchemeDefinition) string {
 		return "goaclient.APIKeySigner"
 	case design.BasicAuthSecurityKind:
 		return "goaclient.BasicSigner"
+	case design.HTTPSignatureSecurityKind:
+		return "goaclient.HTTPSignatureSigner"
 	}
 	return ""
 }
@@ -974,10 +1045,69 @@ func (c *Client) {{ $funcName }}(ctx context.Context, path string{{ if .Params }
 	if err != nil {
 		return nil, err
 	}
-	return c.Client.Do(ctx, req)
+	return c.Client.Do(goaclient.WithAction(ctx, "{{ .ResourceName }}", "{{ .Name }}"), req)
+}
+`
+
+	eventReaderTmpl = `{{ $name := goify (printf "%s%sEventReader" .Name (title .ResourceName)) true }}{{/*
+*/}}// {{ $name }} reads the server-sent events streamed by the {{ .Name }} action of the {{ .ResourceName }} resource.
+type {{ $name }} struct {
+	*goaclient.EventReader
+}
+
+// New{{ $name }} returns a reader for the events streamed in the body of resp.
+func (c *Client) New{{ $name }}(resp *http.Response) *{{ $name }} {
+	return &{{ $name }}{EventReader: goaclient.NewEventReader(resp.Body)}
+}
+
+// Next blocks until the next event is received and returns it together with its decoded data.
+// Next returns io.EOF once the stream ends.
+func (r *{{ $name }}) Next() (*goa.Event, {{ gotyperef .Projected .Projected.AllRequired 0 false }}, error) {
+	ev, err := r.EventReader.Next()
+	if err != nil {
+		return nil, nil, err
+	}
+	var decoded {{ gotypename .Projected .Projected.AllRequired 0 false }}
+	if err := json.Unmarshal(ev.Data, &decoded); err != nil {
+		return ev, nil, err
+	}
+	return ev, {{ if .Projected.IsObject }}&{{ end }}decoded, nil
 }
 `
 
+	connTmpl = `{{ $name := goify (printf "%s%sConn" .Name (title .ResourceName)) true }}{{/*
+*/}}// {{ $name }} wraps the websocket connection to the {{ .Name }} action of the {{ .ResourceName }} resource to exchange typed messages.
+type {{ $name }} struct {
+	*websocket.Conn
+}
+
+// New{{ $name }} wraps the given websocket connection to exchange the messages defined in the design.
+func (c *Client) New{{ $name }}(ws *websocket.Conn) *{{ $name }} {
+	return &{{ $name }}{Conn: ws}
+}
+{{ if .Inbound }}{{ $msg := .Inbound }}
+// Send validates the given message and sends it to the service.
+func (c *{{ $name }}) Send(m {{ gotyperef $msg $msg.AllRequired 0 false }}) error {
+{{ if validationCode $msg.AttributeDefinition false false false "ut" "type" 1 false }}	if err := m.Validate(); err != nil {
+		return err
+	}
+{{ end }}	return websocket.JSON.Send(c.Conn, m)
+}
+{{ end }}{{ if .Outbound }}{{ $msg := .Outbound }}
+// Receive reads the next message sent by the service, validates it and returns it.
+func (c *{{ $name }}) Receive() ({{ gotyperef $msg $msg.AllRequired 0 false }}, error) {
+	var raw {{ gotypename $msg $msg.AllRequired 0 true }}
+	if err := websocket.JSON.Receive(c.Conn, &raw); err != nil {
+		return nil, err
+	}
+{{ if finalizeCode $msg.AttributeDefinition "ut" 1 }}	raw.Finalize()
+{{ end }}{{ if validationCode $msg.AttributeDefinition false false false "ut" "request" 1 true }}	if err := raw.Validate(); err != nil {
+		return nil, err
+	}
+{{ end }}	return raw.Publicize(), nil
+}
+{{ end }}`
+
 	clientsWSTmpl = `{{ $funcName := goify (printf "%s%s" .Name (title .ResourceName)) true }}{{ $desc := .Description }}{{/*
 */}}{{ if $desc }}{{ multiComment $desc }}{{ else }}// {{ $funcName }} establishes a websocket connection to the {{ .Name }} action endpoint of the {{ .ResourceName }} resource{{ end }}
 func (c *Client) {{ $funcName }}(ctx context.Context, path string{{ if .Params }}, {{ .Params }}{{ end }}) (*websocket.Conn, error) {
@@ -228,6 +228,13 @@ var _ = Describe("Generate", func() {
 			Ω(content).Should(ContainSubstring(`param3 := bat.String()`))
 			Ω(content).Should(ContainSubstring(`fmt.Sprintf("/foo/%s/bar/%s/baz/%s/bat/%s", param0, param1, param2, param3)`))
 		})
+
+		It("records the resource and action in the request context", func() {
+			Ω(genErr).Should(BeNil())
+			c, err := ioutil.ReadFile(filepath.Join(outDir, "client", "foo.go"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(c)).Should(ContainSubstring(`return c.Client.Do(goaclient.WithAction(ctx, "foo", "show"), req)`))
+		})
 	})
 
 	Context("with jsonapi like querystring params", func() {
@@ -0,0 +1,490 @@
+package gendiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Kinds of changes.
+const (
+	// Added is the kind of changes that add an element to the design.
+	Added ChangeKind = "added"
+	// Removed is the kind of changes that remove an element from the design.
+	Removed ChangeKind = "removed"
+	// Changed is the kind of changes that modify an existing element of the design.
+	Changed ChangeKind = "changed"
+)
+
+type (
+	// ChangeKind describes the nature of a change.
+	ChangeKind string
+
+	// Change describes a single difference between two versions of a design.
+	Change struct {
+		// Kind is the kind of change.
+		Kind ChangeKind `json:"kind"`
+		// Path identifies the changed element, e.g.
+		// "resources/bottle/actions/show/params/id".
+		Path string `json:"path"`
+		// Message describes the change.
+		Message string `json:"message"`
+		// Breaking is true if the change may break existing clients.
+		Breaking bool `json:"breaking"`
+	}
+
+	// Report lists the changes between two versions of a design.
+	Report struct {
+		// Changes lists the changes sorted by path.
+		Changes []*Change `json:"changes"`
+		// Breaking is the number of breaking changes.
+		Breaking int `json:"breaking"`
+	}
+
+	// differ accumulates the changes while walking the two snapshots.
+	differ struct {
+		old, new *Snapshot
+		changes  []*Change
+		// seen records the pairs of types being compared to stop recursion.
+		seen map[string]bool
+	}
+)
+
+// Compare returns the changes made to the old design to produce the new design. Changes to the
+// parameters, headers and payloads are breaking if they restrict the values that clients may
+// send. Changes to the media types are breaking if they relax the guarantees that clients may
+// rely on.
+func Compare(old, new *Snapshot) *Report {
+	d := &differ{old: old, new: new}
+	d.resources()
+	d.mediaTypes()
+	sort.SliceStable(d.changes, func(i, j int) bool { return d.changes[i].Path < d.changes[j].Path })
+	r := &Report{Changes: d.changes}
+	if r.Changes == nil {
+		r.Changes = []*Change{}
+	}
+	for _, c := range r.Changes {
+		if c.Breaking {
+			r.Breaking++
+		}
+	}
+	return r
+}
+
+// HasBreakingChanges returns true if the report contains at least one breaking change.
+func (r *Report) HasBreakingChanges() bool {
+	return r.Breaking > 0
+}
+
+// String returns a human readable representation of the report, one change per line.
+func (r *Report) String() string {
+	if len(r.Changes) == 0 {
+		return "no changes\n"
+	}
+	var lines []string
+	for _, c := range r.Changes {
+		prefix := "         "
+		if c.Breaking {
+			prefix = "BREAKING "
+		}
+		lines = append(lines, fmt.Sprintf("%s%-8s %s: %s", prefix, c.Kind, c.Path, c.Message))
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// String returns a human readable representation of the change.
+func (c *Change) String() string {
+	return fmt.Sprintf("%s %s: %s", c.Kind, c.Path, c.Message)
+}
+
+// add records a change.
+func (d *differ) add(kind ChangeKind, path string, breaking bool, format string, args ...interface{}) {
+	d.changes = append(d.changes, &Change{
+		Kind:     kind,
+		Path:     path,
+		Message:  fmt.Sprintf(format, args...),
+		Breaking: breaking,
+	})
+}
+
+// resources compares the resources and their actions.
+func (d *differ) resources() {
+	for _, name := range union(resourceNames(d.old.Resources), resourceNames(d.new.Resources)) {
+		path := "resources/" + name
+		o, n := d.old.Resources[name], d.new.Resources[name]
+		switch {
+		case n == nil:
+			d.add(Removed, path, true, "resource removed")
+		case o == nil:
+			d.add(Added, path, false, "resource added")
+		default:
+			d.actions(path, o, n)
+		}
+	}
+}
+
+// actions compares the actions of a resource.
+func (d *differ) actions(path string, old, new *Resource) {
+	for _, name := range union(actionNames(old.Actions), actionNames(new.Actions)) {
+		apath := path + "/actions/" + name
+		o, n := old.Actions[name], new.Actions[name]
+		switch {
+		case n == nil:
+			d.add(Removed, apath, true, "action removed")
+		case o == nil:
+			d.add(Added, apath, false, "action added")
+		default:
+			d.action(apath, o, n)
+		}
+	}
+}
+
+// action compares two versions of an action.
+func (d *differ) action(path string, old, new *Action) {
+	for _, r := range old.Routes {
+		if !contains(new.Routes, r) {
+			d.add(Removed, path+"/routes", true, "route %s removed", r)
+		}
+	}
+	for _, r := range new.Routes {
+		if !contains(old.Routes, r) {
+			d.add(Added, path+"/routes", false, "route %s added", r)
+		}
+	}
+	d.inputs(path+"/params", old.Params, new.Params)
+	d.inputs(path+"/headers", old.Headers, new.Headers)
+
+	ppath := path + "/payload"
+	switch {
+	case old.Payload == nil && new.Payload != nil:
+		d.add(Added, ppath, !new.PayloadOptional, "payload added")
+	case old.Payload != nil && new.Payload == nil:
+		d.add(Removed, ppath, true, "payload removed")
+	case old.Payload != nil:
+		if old.PayloadOptional && !new.PayloadOptional {
+			d.add(Changed, ppath, true, "payload is now required")
+		} else if !old.PayloadOptional && new.PayloadOptional {
+			d.add(Changed, ppath, false, "payload is now optional")
+		}
+		d.attribute(ppath, old.Payload, new.Payload, true)
+	}
+
+	for _, name := range union(responseNames(old.Responses), responseNames(new.Responses)) {
+		rpath := path + "/responses/" + name
+		o, n := old.Responses[name], new.Responses[name]
+		switch {
+		case n == nil:
+			d.add(Removed, rpath, true, "response removed")
+		case o == nil:
+			d.add(Added, rpath, false, "response added")
+		default:
+			if o.Status != n.Status {
+				d.add(Changed, rpath, true, "status changed from %d to %d", o.Status, n.Status)
+			}
+			if o.MediaType != n.MediaType {
+				d.add(Changed, rpath, true, "media type changed from %q to %q", o.MediaType, n.MediaType)
+			} else if o.View != n.View {
+				d.add(Changed, rpath, true, "view changed from %q to %q", o.View, n.View)
+			}
+		}
+	}
+}
+
+// inputs compares the params or headers of an action.
+func (d *differ) inputs(path string, old, new *Attribute) {
+	if old == nil {
+		old = &Attribute{Type: "object"}
+	}
+	if new == nil {
+		new = &Attribute{Type: "object"}
+	}
+	d.attribute(path, old, new, true)
+}
+
+// mediaTypes compares the media types and their views.
+func (d *differ) mediaTypes() {
+	for _, id := range union(mediaTypeIDs(d.old.MediaTypes), mediaTypeIDs(d.new.MediaTypes)) {
+		path := "media_types/" + id
+		o, n := d.old.MediaTypes[id], d.new.MediaTypes[id]
+		switch {
+		case n == nil:
+			d.add(Removed, path, true, "media type removed")
+			continue
+		case o == nil:
+			d.add(Added, path, false, "media type added")
+			continue
+		}
+		for _, v := range union(viewNames(o.Views), viewNames(n.Views)) {
+			vpath := path + "/views/" + v
+			ov, ok := o.Views[v]
+			nv, nok := n.Views[v]
+			switch {
+			case !nok:
+				d.add(Removed, vpath, true, "view removed")
+				continue
+			case !ok:
+				d.add(Added, vpath, false, "view added")
+				continue
+			}
+			for _, a := range ov {
+				if !contains(nv, a) {
+					d.add(Removed, vpath+"/"+a, true, "attribute removed from view")
+				}
+			}
+			for _, a := range nv {
+				if !contains(ov, a) {
+					d.add(Added, vpath+"/"+a, false, "attribute added to view")
+				}
+			}
+		}
+		d.attribute(path+"/attributes", &Attribute{Ref: o.TypeName}, &Attribute{Ref: n.TypeName}, false)
+	}
+}
+
+// attribute compares two versions of an attribute. input is true if the attribute describes
+// data sent by clients, false if it describes data sent to clients.
+func (d *differ) attribute(path string, old, new *Attribute, input bool) {
+	if old.Ref != "" || new.Ref != "" {
+		key := fmt.Sprintf("%s|%s|%v", old.Ref, new.Ref, input)
+		if d.seen[key] {
+			return
+		}
+		if d.seen == nil {
+			d.seen = make(map[string]bool)
+		}
+		d.seen[key] = true
+		defer delete(d.seen, key)
+	}
+	ot, nt := d.resolve(d.old, old), d.resolve(d.new, new)
+	if ot.Type != nt.Type {
+		d.add(Changed, path, true, "type changed from %s to %s", typeName(old, ot), typeName(new, nt))
+		return
+	}
+	d.validation(path, old.Validation, new.Validation, input)
+	if ot != old || nt != new {
+		d.validation(path, ot.Validation, nt.Validation, input)
+	}
+
+	switch ot.Type {
+	case "object":
+		for _, n := range union(attributeNames(ot.Attributes), attributeNames(nt.Attributes)) {
+			apath := path + "/" + n
+			oa, na := ot.Attributes[n], nt.Attributes[n]
+			oreq, nreq := contains(ot.Required, n), contains(nt.Required, n)
+			switch {
+			case na == nil:
+				d.add(Removed, apath, true, "attribute removed")
+			case oa == nil:
+				d.add(Added, apath, input && nreq, "attribute added")
+			default:
+				if input && !oreq && nreq {
+					d.add(Changed, apath, true, "attribute is now required")
+				} else if !input && oreq && !nreq {
+					d.add(Changed, apath, true, "attribute is no longer required")
+				} else if oreq != nreq {
+					d.add(Changed, apath, false, "attribute required changed from %v to %v", oreq, nreq)
+				}
+				d.attribute(apath, oa, na, input)
+			}
+		}
+	case "array":
+		d.attribute(path+"/elem", ot.Elem, nt.Elem, input)
+	case "hash":
+		d.attribute(path+"/key", ot.Key, nt.Key, input)
+		d.attribute(path+"/elem", ot.Elem, nt.Elem, input)
+	}
+}
+
+// resolve returns the definition of the type referenced by att if any, att otherwise.
+func (d *differ) resolve(s *Snapshot, att *Attribute) *Attribute {
+	if att.Ref == "" {
+		return att
+	}
+	if t, ok := s.Types[att.Ref]; ok {
+		return t
+	}
+	return &Attribute{}
+}
+
+// validation compares two sets of validations. Restricting the values is breaking for inputs
+// while relaxing the validations is breaking for outputs.
+func (d *differ) validation(path string, old, new *Validation, input bool) {
+	if old == nil {
+		old = &Validation{}
+	}
+	if new == nil {
+		new = &Validation{}
+	}
+	report := func(name string, tightened, loosened bool, format string, args ...interface{}) {
+		if !tightened && !loosened {
+			return
+		}
+		breaking := input && tightened || !input && loosened
+		d.add(Changed, path, breaking, name+" "+format, args...)
+	}
+
+	ov, nv := values(old.Values), values(new.Values)
+	switch {
+	case len(ov) == 0 && len(nv) > 0:
+		report("enum", true, false, "added: %s", strings.Join(nv, ", "))
+	case len(ov) > 0 && len(nv) == 0:
+		report("enum", false, true, "removed")
+	default:
+		var removed, added []string
+		for _, v := range ov {
+			if !contains(nv, v) {
+				removed = append(removed, v)
+			}
+		}
+		for _, v := range nv {
+			if !contains(ov, v) {
+				added = append(added, v)
+			}
+		}
+		if len(removed) > 0 {
+			report("enum", true, false, "values removed: %s", strings.Join(removed, ", "))
+		}
+		if len(added) > 0 {
+			report("enum", false, true, "values added: %s", strings.Join(added, ", "))
+		}
+	}
+
+	if old.Format != new.Format {
+		report("format", new.Format != "", old.Format != "", "changed from %q to %q", old.Format, new.Format)
+	}
+	if old.Pattern != new.Pattern {
+		report("pattern", new.Pattern != "", old.Pattern != "", "changed from %q to %q", old.Pattern, new.Pattern)
+	}
+	lower := func(name string, o, n *float64) {
+		switch {
+		case o == nil && n != nil:
+			report(name, true, false, "added: %v", *n)
+		case o != nil && n == nil:
+			report(name, false, true, "removed")
+		case o != nil && *o != *n:
+			report(name, *n > *o, *n < *o, "changed from %v to %v", *o, *n)
+		}
+	}
+	upper := func(name string, o, n *float64) {
+		switch {
+		case o == nil && n != nil:
+			report(name, true, false, "added: %v", *n)
+		case o != nil && n == nil:
+			report(name, false, true, "removed")
+		case o != nil && *o != *n:
+			report(name, *n < *o, *n > *o, "changed from %v to %v", *o, *n)
+		}
+	}
+	lower("minimum", old.Minimum, new.Minimum)
+	upper("maximum", old.Maximum, new.Maximum)
+	lower("min length", float(old.MinLength), float(new.MinLength))
+	upper("max length", float(old.MaxLength), float(new.MaxLength))
+}
+
+// typeName returns the name of the type described by att using its definition def.
+func typeName(att, def *Attribute) string {
+	if att.Ref != "" {
+		return att.Ref
+	}
+	return def.Type
+}
+
+// values returns the JSON representations of the given enum values so that values read from
+// JSON snapshots compare equal to values recorded from designs.
+func values(vals []interface{}) []string {
+	res := make([]string, len(vals))
+	for i, v := range vals {
+		b, err := json.Marshal(v)
+		if err != nil {
+			res[i] = fmt.Sprint(v)
+			continue
+		}
+		res[i] = string(b)
+	}
+	return res
+}
+
+// float converts a length validation into a float pointer.
+func float(v *int) *float64 {
+	if v == nil {
+		return nil
+	}
+	f := float64(*v)
+	return &f
+}
+
+// contains returns true if vals contains val.
+func contains(vals []string, val string) bool {
+	for _, v := range vals {
+		if v == val {
+			return true
+		}
+	}
+	return false
+}
+
+// union returns the sorted union of a and b.
+func union(a, b []string) []string {
+	m := make(map[string]bool, len(a)+len(b))
+	for _, v := range a {
+		m[v] = true
+	}
+	for _, v := range b {
+		m[v] = true
+	}
+	res := make([]string, 0, len(m))
+	for v := range m {
+		res = append(res, v)
+	}
+	sort.Strings(res)
+	return res
+}
+
+func resourceNames(m map[string]*Resource) []string {
+	var res []string
+	for n := range m {
+		res = append(res, n)
+	}
+	return res
+}
+
+func actionNames(m map[string]*Action) []string {
+	var res []string
+	for n := range m {
+		res = append(res, n)
+	}
+	return res
+}
+
+func responseNames(m map[string]*Response) []string {
+	var res []string
+	for n := range m {
+		res = append(res, n)
+	}
+	return res
+}
+
+func mediaTypeIDs(m map[string]*MediaType) []string {
+	var res []string
+	for n := range m {
+		res = append(res, n)
+	}
+	return res
+}
+
+func viewNames(m map[string][]string) []string {
+	var res []string
+	for n := range m {
+		res = append(res, n)
+	}
+	return res
+}
+
+func attributeNames(m map[string]*Attribute) []string {
+	var res []string
+	for n := range m {
+		res = append(res, n)
+	}
+	return res
+}
@@ -0,0 +1,220 @@
+package gendiff_test
+
+import (
+	"encoding/json"
+
+	. "github.com/goadesign/goa/design"
+	. "github.com/goadesign/goa/design/apidsl"
+	"github.com/goadesign/goa/dslengine"
+	"github.com/goadesign/goa/goagen/gen_diff"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// snapshot runs the given design and returns its snapshot after a JSON round trip.
+func snapshot(dsl func()) *gendiff.Snapshot {
+	dslengine.Reset()
+	dsl()
+	Ω(dslengine.Run()).ShouldNot(HaveOccurred())
+	b, err := json.Marshal(gendiff.NewSnapshot(Design))
+	Ω(err).ShouldNot(HaveOccurred())
+	var s gendiff.Snapshot
+	Ω(json.Unmarshal(b, &s)).ShouldNot(HaveOccurred())
+	return &s
+}
+
+// bottleDesign returns a design whose elements may be customized via the given functions.
+func bottleDesign(payload, view, params func(), route string) func() {
+	return func() {
+		API("cellar", nil)
+		bottle := MediaType("application/vnd.bottle+json", func() {
+			Attributes(func() {
+				Attribute("id", Integer)
+				Attribute("name", String)
+				Attribute("vintage", Integer)
+				Required("id", "name")
+			})
+			View("default", view)
+		})
+		Resource("bottle", func() {
+			DefaultMedia(bottle)
+			Action("show", func() {
+				Routing(GET(route))
+				Params(params)
+				Response(OK)
+			})
+			Action("create", func() {
+				Routing(POST("/bottles"))
+				Payload(payload)
+				Response(Created)
+			})
+		})
+	}
+}
+
+var _ = Describe("Compare", func() {
+	var (
+		payload, view, params func()
+		route                 string
+		newDesign             func()
+		report                *gendiff.Report
+	)
+
+	BeforeEach(func() {
+		payload = func() {
+			Attribute("name", String, func() { MinLength(2) })
+			Attribute("vintage", Integer, func() { Minimum(1900) })
+			Attribute("color", String, func() { Enum("red", "white") })
+			Required("name")
+		}
+		view = func() {
+			Attribute("id")
+			Attribute("name")
+			Attribute("vintage")
+		}
+		params = func() {
+			Param("id", Integer)
+		}
+		route = "/bottles/:id"
+		newDesign = nil
+	})
+
+	JustBeforeEach(func() {
+		old := snapshot(bottleDesign(payload, view, params, route))
+		if newDesign == nil {
+			newDesign = bottleDesign(payload, view, params, route)
+		}
+		report = gendiff.Compare(old, snapshot(newDesign))
+	})
+
+	Context("with identical designs", func() {
+		It("reports no change", func() {
+			Ω(report.Changes).Should(BeEmpty())
+			Ω(report.HasBreakingChanges()).Should(BeFalse())
+		})
+	})
+
+	Context("with a tightened payload validation", func() {
+		BeforeEach(func() {
+			newDesign = bottleDesign(func() {
+				Attribute("name", String, func() { MinLength(3) })
+				Attribute("vintage", Integer, func() { Minimum(1900) })
+				Attribute("color", String, func() { Enum("red", "white") })
+				Required("name")
+			}, view, params, route)
+		})
+
+		It("reports a breaking change", func() {
+			Ω(report.Changes).Should(HaveLen(1))
+			c := report.Changes[0]
+			Ω(c.Kind).Should(Equal(gendiff.Changed))
+			Ω(c.Path).Should(Equal("resources/bottle/actions/create/payload/name"))
+			Ω(c.Message).Should(Equal("min length changed from 2 to 3"))
+			Ω(c.Breaking).Should(BeTrue())
+			Ω(report.Breaking).Should(Equal(1))
+		})
+	})
+
+	Context("with relaxed payload validations and a new optional attribute", func() {
+		BeforeEach(func() {
+			newDesign = bottleDesign(func() {
+				Attribute("name", String)
+				Attribute("vintage", Integer, func() { Minimum(1800) })
+				Attribute("color", String, func() { Enum("red", "white", "rose") })
+				Attribute("review", String)
+				Required("name")
+			}, view, params, route)
+		})
+
+		It("reports non breaking changes", func() {
+			Ω(report.Changes).Should(HaveLen(4))
+			Ω(report.HasBreakingChanges()).Should(BeFalse())
+		})
+	})
+
+	Context("with a new required payload attribute", func() {
+		BeforeEach(func() {
+			newDesign = bottleDesign(func() {
+				Attribute("name", String, func() { MinLength(2) })
+				Attribute("vintage", Integer, func() { Minimum(1900) })
+				Attribute("color", String, func() { Enum("red", "white") })
+				Required("name", "vintage")
+			}, view, params, route)
+		})
+
+		It("reports a breaking change", func() {
+			Ω(report.Changes).Should(HaveLen(1))
+			Ω(report.Changes[0].Path).Should(Equal("resources/bottle/actions/create/payload/vintage"))
+			Ω(report.Changes[0].Message).Should(Equal("attribute is now required"))
+			Ω(report.Changes[0].Breaking).Should(BeTrue())
+		})
+	})
+
+	Context("with a changed route", func() {
+		BeforeEach(func() {
+			newDesign = bottleDesign(payload, view, params, "/wines/:id")
+		})
+
+		It("reports the removed and added routes", func() {
+			Ω(report.Changes).Should(HaveLen(2))
+			Ω(report.Changes[0].Message).Should(Equal("route GET /bottles/:id removed"))
+			Ω(report.Changes[0].Breaking).Should(BeTrue())
+			Ω(report.Changes[1].Message).Should(Equal("route GET /wines/:id added"))
+			Ω(report.Changes[1].Breaking).Should(BeFalse())
+		})
+	})
+
+	Context("with a new optional param", func() {
+		BeforeEach(func() {
+			newDesign = bottleDesign(payload, view, func() {
+				Param("id", Integer)
+				Param("fields", String)
+			}, route)
+		})
+
+		It("reports a non breaking addition", func() {
+			Ω(report.Changes).Should(HaveLen(1))
+			Ω(report.Changes[0].Kind).Should(Equal(gendiff.Added))
+			Ω(report.Changes[0].Path).Should(Equal("resources/bottle/actions/show/params/fields"))
+			Ω(report.Changes[0].Breaking).Should(BeFalse())
+		})
+	})
+
+	Context("with an attribute removed from a view", func() {
+		BeforeEach(func() {
+			newDesign = bottleDesign(payload, func() {
+				Attribute("id")
+				Attribute("name")
+			}, params, route)
+		})
+
+		It("reports a breaking change", func() {
+			Ω(report.Changes).Should(HaveLen(1))
+			Ω(report.Changes[0].Kind).Should(Equal(gendiff.Removed))
+			Ω(report.Changes[0].Path).Should(Equal("media_types/application/vnd.bottle+json/views/default/vintage"))
+			Ω(report.Changes[0].Breaking).Should(BeTrue())
+		})
+	})
+
+	Context("with a removed action", func() {
+		BeforeEach(func() {
+			newDesign = func() {
+				API("cellar", nil)
+				Resource("bottle", func() {
+					Action("create", func() {
+						Routing(POST("/bottles"))
+						Payload(payload)
+						Response(Created)
+					})
+				})
+			}
+		})
+
+		It("reports the removed action and media type", func() {
+			Ω(report.Changes).Should(HaveLen(2))
+			Ω(report.Changes[0].Path).Should(Equal("media_types/application/vnd.bottle+json"))
+			Ω(report.Changes[1].Path).Should(Equal("resources/bottle/actions/show"))
+			Ω(report.Breaking).Should(Equal(2))
+		})
+	})
+})
@@ -0,0 +1,7 @@
+/*
+Package gendiff provides a generator that records the parts of a goa API design that affect its
+clients and a tool to compare two such records. The comparison reports the resources, actions,
+routes, parameters, payloads, media type views and validations that were added, removed or changed
+between two versions of a design and flags the changes that may break existing clients.
+*/
+package gendiff
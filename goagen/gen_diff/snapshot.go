@@ -0,0 +1,236 @@
+package gendiff
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/goadesign/goa/design"
+	"github.com/goadesign/goa/dslengine"
+)
+
+type (
+	// Snapshot describes the parts of an API design that affect its clients. Snapshots can be
+	// serialized to JSON so that designs compiled in different processes can be compared.
+	Snapshot struct {
+		// API is the name of the API.
+		API string `json:"api"`
+		// Resources lists the API resources indexed by name.
+		Resources map[string]*Resource `json:"resources,omitempty"`
+		// MediaTypes lists the API media types indexed by identifier.
+		MediaTypes map[string]*MediaType `json:"media_types,omitempty"`
+		// Types lists the user and media types referenced by the API indexed by type name.
+		Types map[string]*Attribute `json:"types,omitempty"`
+	}
+
+	// Resource describes a resource.
+	Resource struct {
+		// Actions lists the resource actions indexed by name.
+		Actions map[string]*Action `json:"actions,omitempty"`
+	}
+
+	// Action describes an action.
+	Action struct {
+		// Routes lists the action routes, each route consists of the HTTP method and the
+		// full path separated by a space.
+		Routes []string `json:"routes,omitempty"`
+		// Params describes the path and query string parameters.
+		Params *Attribute `json:"params,omitempty"`
+		// Headers describes the request headers.
+		Headers *Attribute `json:"headers,omitempty"`
+		// Payload describes the request payload.
+		Payload *Attribute `json:"payload,omitempty"`
+		// PayloadOptional is true if the request payload is optional.
+		PayloadOptional bool `json:"payload_optional,omitempty"`
+		// Responses lists the action responses indexed by name.
+		Responses map[string]*Response `json:"responses,omitempty"`
+	}
+
+	// Response describes an action response.
+	Response struct {
+		// Status is the response HTTP status code.
+		Status int `json:"status"`
+		// MediaType is the identifier of the response media type if any.
+		MediaType string `json:"media_type,omitempty"`
+		// View is the name of the view used to render the response media type if any.
+		View string `json:"view,omitempty"`
+	}
+
+	// MediaType describes a media type.
+	MediaType struct {
+		// TypeName is the name of the media type entry in the snapshot types.
+		TypeName string `json:"type_name"`
+		// Views lists the names of the attributes rendered by each view indexed by view
+		// name.
+		Views map[string][]string `json:"views,omitempty"`
+	}
+
+	// Attribute describes an attribute data structure and validations.
+	Attribute struct {
+		// Type is the name of the attribute type: one of the primitive type names,
+		// "object", "array" or "hash". Type is empty if the attribute uses a user or media
+		// type.
+		Type string `json:"type,omitempty"`
+		// Ref is the name of the user or media type used by the attribute if any.
+		Ref string `json:"ref,omitempty"`
+		// Attributes lists the child attributes of object attributes.
+		Attributes map[string]*Attribute `json:"attributes,omitempty"`
+		// Required lists the names of the required child attributes.
+		Required []string `json:"required,omitempty"`
+		// Key describes the keys of hash attributes.
+		Key *Attribute `json:"key,omitempty"`
+		// Elem describes the elements of array and hash attributes.
+		Elem *Attribute `json:"elem,omitempty"`
+		// Validation lists the attribute validations.
+		Validation *Validation `json:"validation,omitempty"`
+	}
+
+	// Validation lists the validations that apply to an attribute value.
+	Validation struct {
+		Values    []interface{} `json:"values,omitempty"`
+		Format    string        `json:"format,omitempty"`
+		Pattern   string        `json:"pattern,omitempty"`
+		Minimum   *float64      `json:"minimum,omitempty"`
+		Maximum   *float64      `json:"maximum,omitempty"`
+		MinLength *int          `json:"min_length,omitempty"`
+		MaxLength *int          `json:"max_length,omitempty"`
+	}
+)
+
+// NewSnapshot records the given API design. The design must have been run by the DSL engine.
+func NewSnapshot(api *design.APIDefinition) *Snapshot {
+	s := &Snapshot{
+		API:        api.Name,
+		Resources:  make(map[string]*Resource),
+		MediaTypes: make(map[string]*MediaType),
+		Types:      make(map[string]*Attribute),
+	}
+	api.IterateResources(func(r *design.ResourceDefinition) error {
+		res := &Resource{Actions: make(map[string]*Action)}
+		r.IterateActions(func(a *design.ActionDefinition) error {
+			res.Actions[a.Name] = s.action(a)
+			return nil
+		})
+		s.Resources[r.Name] = res
+		return nil
+	})
+	api.IterateMediaTypes(func(mt *design.MediaTypeDefinition) error {
+		s.mediaType(mt)
+		return nil
+	})
+	return s
+}
+
+// LoadSnapshot reads the snapshot stored in the given JSON file.
+func LoadSnapshot(path string) (*Snapshot, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s Snapshot
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// action records the given action.
+func (s *Snapshot) action(a *design.ActionDefinition) *Action {
+	act := &Action{
+		PayloadOptional: a.PayloadOptional,
+		Responses:       make(map[string]*Response),
+	}
+	for _, r := range a.Routes {
+		act.Routes = append(act.Routes, r.Verb+" "+r.FullPath())
+	}
+	if params := a.AllParams(); len(params.Type.ToObject()) > 0 {
+		act.Params = s.attribute(params)
+	}
+	if a.Headers != nil && len(a.Headers.Type.ToObject()) > 0 {
+		act.Headers = s.attribute(a.Headers)
+	}
+	if a.Payload != nil {
+		act.Payload = s.attribute(&design.AttributeDefinition{Type: a.Payload})
+	}
+	for n, r := range a.Responses {
+		act.Responses[n] = &Response{Status: r.Status, MediaType: r.MediaType, View: r.ViewName}
+	}
+	return act
+}
+
+// mediaType records the given media type.
+func (s *Snapshot) mediaType(mt *design.MediaTypeDefinition) {
+	m := &MediaType{TypeName: mt.TypeName, Views: make(map[string][]string)}
+	s.userType(mt.UserTypeDefinition)
+	mt.IterateViews(func(v *design.ViewDefinition) error {
+		names := []string{}
+		v.Type.ToObject().IterateAttributes(func(n string, _ *design.AttributeDefinition) error {
+			names = append(names, n)
+			return nil
+		})
+		m.Views[v.Name] = names
+		return nil
+	})
+	s.MediaTypes[mt.Identifier] = m
+}
+
+// userType records the given user type if not already recorded.
+func (s *Snapshot) userType(ut *design.UserTypeDefinition) {
+	if _, ok := s.Types[ut.TypeName]; ok {
+		return
+	}
+	// Record a placeholder first to stop recursion on recursive types.
+	s.Types[ut.TypeName] = &Attribute{}
+	s.Types[ut.TypeName] = s.attribute(ut.AttributeDefinition)
+}
+
+// attribute records the given attribute.
+func (s *Snapshot) attribute(att *design.AttributeDefinition) *Attribute {
+	res := &Attribute{Validation: validation(att.Validation)}
+	switch actual := att.Type.(type) {
+	case *design.MediaTypeDefinition:
+		s.userType(actual.UserTypeDefinition)
+		res.Ref = actual.TypeName
+	case *design.UserTypeDefinition:
+		s.userType(actual)
+		res.Ref = actual.TypeName
+	case design.Object:
+		res.Type = actual.Name()
+		res.Attributes = make(map[string]*Attribute, len(actual))
+		for n, child := range actual {
+			res.Attributes[n] = s.attribute(child)
+		}
+		if att.Validation != nil {
+			res.Required = att.Validation.Required
+		}
+	case *design.Array:
+		res.Type = actual.Name()
+		res.Elem = s.attribute(actual.ElemType)
+	case *design.Hash:
+		res.Type = actual.Name()
+		res.Key = s.attribute(actual.KeyType)
+		res.Elem = s.attribute(actual.ElemType)
+	case design.DataType:
+		res.Type = actual.Name()
+	}
+	return res
+}
+
+// validation records the given validations, it returns nil if there is no value validation.
+func validation(v *dslengine.ValidationDefinition) *Validation {
+	if v == nil {
+		return nil
+	}
+	if v.Values == nil && v.Format == "" && v.Pattern == "" && v.Minimum == nil &&
+		v.Maximum == nil && v.MinLength == nil && v.MaxLength == nil {
+		return nil
+	}
+	return &Validation{
+		Values:    v.Values,
+		Format:    v.Format,
+		Pattern:   v.Pattern,
+		Minimum:   v.Minimum,
+		Maximum:   v.Maximum,
+		MinLength: v.MinLength,
+		MaxLength: v.MaxLength,
+	}
+}
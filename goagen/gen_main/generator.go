@@ -289,8 +289,11 @@ func (g *Generator) createMainFile(mainFile string, funcs template.FuncMap) (err
 	}
 	appPkg := path.Join(outPkg, "app")
 	imports := []*codegen.ImportSpec{
+		codegen.SimpleImport("os"),
+		codegen.SimpleImport("syscall"),
 		codegen.SimpleImport("time"),
 		codegen.SimpleImport("github.com/goadesign/goa"),
+		codegen.SimpleImport("github.com/goadesign/goa/health"),
 		codegen.SimpleImport("github.com/goadesign/goa/middleware"),
 		codegen.SimpleImport(appPkg),
 	}
@@ -464,7 +467,12 @@ func main() {
 {{ range $name, $res := $api.Resources }}{{ $name := goify $res.Name true }} // Mount "{{$res.Name}}" controller
 	{{ $tmp := tempvar }}{{ $tmp }} := New{{ $name }}Controller(service)
 	{{ targetPkg }}.Mount{{ $name }}Controller(service, {{ $tmp }})
+{{ end }}{{ if .API.HealthCheck }}
+	// Mount health check endpoints, pass checkers to report on the service dependencies
+	health.Mount(service)
 {{ end }}
+	// Shut down gracefully on SIGINT and SIGTERM
+	service.ShutdownOnSignal(30*time.Second, os.Interrupt, syscall.SIGTERM)
 
 {{ if .TLS }}
 	// Start service
@@ -53,6 +53,7 @@ var _ = Describe("Generate", func() {
 			content, err := ioutil.ReadFile(filepath.Join(outDir, "main.go"))
 			Ω(err).ShouldNot(HaveOccurred())
 			Ω(len(strings.Split(string(content), "\n"))).Should(BeNumerically(">=", 16))
+			Ω(string(content)).Should(ContainSubstring(shutdownOnSignalCode))
 			Ω(string(content)).Should(ContainSubstring(listenAndServeCode))
 			_, err = gexec.Build(testgenPackagePath)
 			Ω(err).ShouldNot(HaveOccurred())
@@ -236,6 +237,11 @@ var _ = Describe("NewGenerator", func() {
 	})
 })
 
+const shutdownOnSignalCode = `
+	// Shut down gracefully on SIGINT and SIGTERM
+	service.ShutdownOnSignal(30*time.Second, os.Interrupt, syscall.SIGTERM)
+`
+
 const listenAndServeCode = `
 	if err := service.ListenAndServe(":8080"); err != nil {
 		service.LogError("startup", "err", err)
@@ -0,0 +1,9 @@
+/*
+Package genopenapi3 provides a generator for the OpenAPI 3.0 specification of a goa API.
+The generator walks the same API design as the Swagger 2.0 generator (see package genswagger) and
+produces the "openapi.json" and "openapi.yaml" files. The generator honors the "swagger:*" metadata
+keys so that designs do not need to change to produce OpenAPI 3.0 specifications.
+See https://github.com/OAI/OpenAPI-Specification/blob/master/versions/3.0.0.md for more information
+about the OpenAPI 3.0 specification.
+*/
+package genopenapi3
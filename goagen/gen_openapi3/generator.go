@@ -0,0 +1,117 @@
+package genopenapi3
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/goadesign/goa/design"
+	"github.com/goadesign/goa/goagen/codegen"
+	"github.com/goadesign/goa/goagen/utils"
+)
+
+//NewGenerator returns an initialized instance of an OpenAPI 3.0 Generator
+func NewGenerator(options ...Option) *Generator {
+	g := &Generator{}
+
+	for _, option := range options {
+		option(g)
+	}
+
+	return g
+}
+
+// Generator is the OpenAPI 3.0 specification generator.
+type Generator struct {
+	API      *design.APIDefinition // The API definition
+	OutDir   string                // Path to output directory
+	genfiles []string              // Generated files
+}
+
+// Generate is the generator entry point called by the meta generator.
+func Generate() (files []string, err error) {
+	var outDir, ver string
+
+	set := flag.NewFlagSet("openapi3", flag.PanicOnError)
+	set.StringVar(&outDir, "out", "", "")
+	set.StringVar(&ver, "version", "", "")
+	set.String("design", "", "")
+	set.Parse(os.Args[1:])
+
+	if err := codegen.CheckVersion(ver); err != nil {
+		return nil, err
+	}
+
+	g := &Generator{OutDir: outDir, API: design.Design}
+
+	return g.Generate()
+}
+
+// Generate produces the OpenAPI 3.0 specification files.
+func (g *Generator) Generate() (_ []string, err error) {
+	if g.API == nil {
+		return nil, fmt.Errorf("missing API definition, make sure design is properly initialized")
+	}
+
+	go utils.Catch(nil, func() { g.Cleanup() })
+
+	defer func() {
+		if err != nil {
+			g.Cleanup()
+		}
+	}()
+
+	s, err := New(g.API)
+	if err != nil {
+		return nil, err
+	}
+
+	openapiDir := filepath.Join(g.OutDir, "openapi")
+	os.RemoveAll(openapiDir)
+	if err = os.MkdirAll(openapiDir, 0755); err != nil {
+		return nil, err
+	}
+	g.genfiles = append(g.genfiles, openapiDir)
+
+	// JSON
+	rawJSON, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	openapiFile := filepath.Join(openapiDir, "openapi.json")
+	if err := ioutil.WriteFile(openapiFile, rawJSON, 0644); err != nil {
+		return nil, err
+	}
+	g.genfiles = append(g.genfiles, openapiFile)
+
+	// YAML
+	var yamlSource interface{}
+	if err = json.Unmarshal(rawJSON, &yamlSource); err != nil {
+		return nil, err
+	}
+
+	rawYAML, err := yaml.Marshal(yamlSource)
+	if err != nil {
+		return nil, err
+	}
+	openapiFile = filepath.Join(openapiDir, "openapi.yaml")
+	if err := ioutil.WriteFile(openapiFile, rawYAML, 0644); err != nil {
+		return nil, err
+	}
+	g.genfiles = append(g.genfiles, openapiFile)
+
+	return g.genfiles, nil
+}
+
+// Cleanup removes all the files generated by this generator during the last invokation of Generate.
+func (g *Generator) Cleanup() {
+	for _, f := range g.genfiles {
+		os.Remove(f)
+	}
+	g.genfiles = nil
+}
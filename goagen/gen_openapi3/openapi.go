@@ -0,0 +1,1207 @@
+package genopenapi3
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/goadesign/goa/design"
+	"github.com/goadesign/goa/dslengine"
+	"github.com/goadesign/goa/goagen/gen_schema"
+)
+
+type (
+	// OpenAPI represents an instance of an OpenAPI 3.0 document.
+	// See https://github.com/OAI/OpenAPI-Specification/blob/master/versions/3.0.0.md
+	OpenAPI struct {
+		OpenAPI      string                 `json:"openapi"`
+		Info         *Info                  `json:"info"`
+		Servers      []*Server              `json:"servers,omitempty"`
+		Paths        map[string]interface{} `json:"paths"`
+		Components   *Components            `json:"components,omitempty"`
+		Tags         []*Tag                 `json:"tags,omitempty"`
+		ExternalDocs *ExternalDocs          `json:"externalDocs,omitempty"`
+	}
+
+	// Info provides metadata about the API. The metadata can be used by the clients if needed,
+	// and can be presented in editing or documentation generation tools for convenience.
+	Info struct {
+		Title          string                    `json:"title"`
+		Description    string                    `json:"description,omitempty"`
+		TermsOfService string                    `json:"termsOfService,omitempty"`
+		Contact        *design.ContactDefinition `json:"contact,omitempty"`
+		License        *design.LicenseDefinition `json:"license,omitempty"`
+		Version        string                    `json:"version"`
+		Extensions     map[string]interface{}    `json:"-"`
+	}
+
+	// Server represents a server hosting the API.
+	Server struct {
+		// URL to the target host, may be relative to the location of the OpenAPI document.
+		URL string `json:"url"`
+		// Description is an optional string describing the host.
+		Description string `json:"description,omitempty"`
+	}
+
+	// Components holds a set of reusable objects referenced from elsewhere in the document.
+	Components struct {
+		// Schemas holds the reusable schemas indexed by name.
+		Schemas map[string]*genschema.JSONSchema `json:"schemas,omitempty"`
+		// Responses holds the reusable responses indexed by name.
+		Responses map[string]*Response `json:"responses,omitempty"`
+		// SecuritySchemes holds the reusable security schemes indexed by name.
+		SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+	}
+
+	// Path holds the relative paths to the individual endpoints.
+	Path struct {
+		// Ref allows for an external definition of this path item.
+		Ref string `json:"$ref,omitempty"`
+		// Get defines a GET operation on this path.
+		Get *Operation `json:"get,omitempty"`
+		// Put defines a PUT operation on this path.
+		Put *Operation `json:"put,omitempty"`
+		// Post defines a POST operation on this path.
+		Post *Operation `json:"post,omitempty"`
+		// Delete defines a DELETE operation on this path.
+		Delete *Operation `json:"delete,omitempty"`
+		// Options defines a OPTIONS operation on this path.
+		Options *Operation `json:"options,omitempty"`
+		// Head defines a HEAD operation on this path.
+		Head *Operation `json:"head,omitempty"`
+		// Patch defines a PATCH operation on this path.
+		Patch *Operation `json:"patch,omitempty"`
+		// Trace defines a TRACE operation on this path.
+		Trace *Operation `json:"trace,omitempty"`
+		// Parameters is the list of parameters that are applicable for all the operations
+		// described under this path.
+		Parameters []*Parameter `json:"parameters,omitempty"`
+		// Extensions defines the specification extensions.
+		Extensions map[string]interface{} `json:"-"`
+	}
+
+	// Operation describes a single API operation on a path.
+	Operation struct {
+		// Tags is a list of tags for API documentation control. Tags can be used for
+		// logical grouping of operations by resources or any other qualifier.
+		Tags []string `json:"tags,omitempty"`
+		// Summary is a short summary of what the operation does.
+		Summary string `json:"summary,omitempty"`
+		// Description is a verbose explanation of the operation behavior.
+		// CommonMark syntax can be used for rich text representation.
+		Description string `json:"description,omitempty"`
+		// ExternalDocs points to additional external documentation for this operation.
+		ExternalDocs *ExternalDocs `json:"externalDocs,omitempty"`
+		// OperationID is a unique string used to identify the operation.
+		OperationID string `json:"operationId,omitempty"`
+		// Parameters is a list of parameters that are applicable for this operation.
+		Parameters []*Parameter `json:"parameters,omitempty"`
+		// RequestBody describes the request body applicable for this operation.
+		RequestBody *RequestBody `json:"requestBody,omitempty"`
+		// Responses is the list of possible responses as they are returned from executing
+		// this operation.
+		Responses map[string]*Response `json:"responses"`
+		// Deprecated declares this operation to be deprecated.
+		Deprecated bool `json:"deprecated,omitempty"`
+		// Security is a declaration of which security schemes are applied for this operation.
+		Security []map[string][]string `json:"security,omitempty"`
+		// Servers overrides the API servers for this operation.
+		Servers []*Server `json:"servers,omitempty"`
+		// Extensions defines the specification extensions.
+		Extensions map[string]interface{} `json:"-"`
+	}
+
+	// Parameter describes a single operation parameter.
+	Parameter struct {
+		// Name of the parameter. Parameter names are case sensitive.
+		Name string `json:"name"`
+		// In is the location of the parameter.
+		// Possible values are "query", "header", "path" or "cookie".
+		In string `json:"in"`
+		// Description is a brief description of the parameter.
+		// CommonMark syntax can be used for rich text representation.
+		Description string `json:"description,omitempty"`
+		// Required determines whether this parameter is mandatory.
+		Required bool `json:"required"`
+		// AllowEmptyValue sets the ability to pass empty-valued parameters.
+		AllowEmptyValue bool `json:"allowEmptyValue,omitempty"`
+		// Style describes how the parameter value will be serialized.
+		Style string `json:"style,omitempty"`
+		// Explode causes array parameters to generate separate parameters for each value
+		// of the array when true.
+		Explode *bool `json:"explode,omitempty"`
+		// Schema defines the type used for the parameter.
+		Schema *genschema.JSONSchema `json:"schema,omitempty"`
+		// Extensions defines the specification extensions.
+		Extensions map[string]interface{} `json:"-"`
+	}
+
+	// RequestBody describes a single request body.
+	RequestBody struct {
+		// Description is a brief description of the request body.
+		Description string `json:"description,omitempty"`
+		// Content lists the body schemas indexed by media type.
+		Content map[string]*MediaType `json:"content"`
+		// Required determines if the request body is required in the request.
+		Required bool `json:"required,omitempty"`
+	}
+
+	// MediaType provides schema and examples for the media type identified by its key.
+	MediaType struct {
+		// Schema defines the type used for the content.
+		Schema *genschema.JSONSchema `json:"schema,omitempty"`
+	}
+
+	// Response describes an operation response.
+	Response struct {
+		// Description of the response. CommonMark syntax can be used for rich text
+		// representation.
+		Description string `json:"description"`
+		// Headers is a list of headers that are sent with the response.
+		Headers map[string]*Header `json:"headers,omitempty"`
+		// Content lists the response body schemas indexed by media type.
+		Content map[string]*MediaType `json:"content,omitempty"`
+		// Extensions defines the specification extensions.
+		Extensions map[string]interface{} `json:"-"`
+	}
+
+	// Header represents a response header.
+	Header struct {
+		// Description is a brief description of the header.
+		// CommonMark syntax can be used for rich text representation.
+		Description string `json:"description,omitempty"`
+		// Required determines whether this header is mandatory.
+		Required bool `json:"required,omitempty"`
+		// Schema defines the type used for the header.
+		Schema *genschema.JSONSchema `json:"schema,omitempty"`
+	}
+
+	// SecurityScheme defines a security scheme that can be used by the operations.
+	// Supported schemes are HTTP authentication, an API key (either as a header or as a query
+	// parameter) and OAuth2's common flows (implicit, password, client credentials and
+	// authorization code).
+	SecurityScheme struct {
+		// Type of the security scheme. Valid values are "apiKey", "http", "oauth2" or
+		// "openIdConnect".
+		Type string `json:"type"`
+		// Description for security scheme
+		Description string `json:"description,omitempty"`
+		// Name of the header, query or cookie parameter to be used when type is "apiKey".
+		Name string `json:"name,omitempty"`
+		// In is the location of the API key when type is "apiKey".
+		// Valid values are "query", "header" or "cookie".
+		In string `json:"in,omitempty"`
+		// Scheme is the name of the HTTP Authorization scheme when type is "http".
+		Scheme string `json:"scheme,omitempty"`
+		// BearerFormat is a hint to the client to identify how the bearer token is
+		// formatted.
+		BearerFormat string `json:"bearerFormat,omitempty"`
+		// Flows contains configuration information for the flow types supported when type
+		// is "oauth2".
+		Flows *OAuthFlows `json:"flows,omitempty"`
+		// Extensions defines the specification extensions.
+		Extensions map[string]interface{} `json:"-"`
+	}
+
+	// OAuthFlows allows configuration of the supported OAuth2 flows.
+	OAuthFlows struct {
+		// Implicit configures the OAuth2 implicit flow.
+		Implicit *OAuthFlow `json:"implicit,omitempty"`
+		// Password configures the OAuth2 resource owner password flow.
+		Password *OAuthFlow `json:"password,omitempty"`
+		// ClientCredentials configures the OAuth2 client credentials flow.
+		ClientCredentials *OAuthFlow `json:"clientCredentials,omitempty"`
+		// AuthorizationCode configures the OAuth2 authorization code flow.
+		AuthorizationCode *OAuthFlow `json:"authorizationCode,omitempty"`
+	}
+
+	// OAuthFlow contains the configuration details of a supported OAuth2 flow.
+	OAuthFlow struct {
+		// AuthorizationURL is the authorization URL to be used for this flow.
+		AuthorizationURL string `json:"authorizationUrl,omitempty"`
+		// TokenURL is the token URL to be used for this flow.
+		TokenURL string `json:"tokenUrl,omitempty"`
+		// Scopes list the available scopes for the OAuth2 security scheme.
+		Scopes map[string]string `json:"scopes"`
+	}
+
+	// ExternalDocs allows referencing an external resource for extended documentation.
+	ExternalDocs struct {
+		// Description is a short description of the target documentation.
+		// CommonMark syntax can be used for rich text representation.
+		Description string `json:"description,omitempty"`
+		// URL for the target documentation.
+		URL string `json:"url"`
+	}
+
+	// Tag allows adding meta data to a single tag that is used by the Operation Object. It is
+	// not mandatory to have a Tag Object per tag used there.
+	Tag struct {
+		// Name of the tag.
+		Name string `json:"name,omitempty"`
+		// Description is a short description of the tag.
+		// CommonMark syntax can be used for rich text representation.
+		Description string `json:"description,omitempty"`
+		// ExternalDocs is additional external documentation for this tag.
+		ExternalDocs *ExternalDocs `json:"externalDocs,omitempty"`
+		// Extensions defines the specification extensions.
+		Extensions map[string]interface{} `json:"-"`
+	}
+
+	// These types are used in marshalJSON() to avoid recursive call of json.Marshal().
+	_Info           Info
+	_Path           Path
+	_Operation      Operation
+	_Parameter      Parameter
+	_Response       Response
+	_SecurityScheme SecurityScheme
+	_Tag            Tag
+)
+
+const (
+	// schemasRef is the prefix of references to schemas defined in the components.
+	schemasRef = "#/components/schemas/"
+
+	// definitionsRef is the prefix of references produced by the JSON schema generator.
+	definitionsRef = "#/definitions/"
+)
+
+func marshalJSON(v interface{}, extensions map[string]interface{}) ([]byte, error) {
+	marshaled, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if len(extensions) == 0 {
+		return marshaled, nil
+	}
+	var unmarshaled interface{}
+	if err := json.Unmarshal(marshaled, &unmarshaled); err != nil {
+		return nil, err
+	}
+	asserted := unmarshaled.(map[string]interface{})
+	for k, v := range extensions {
+		asserted[k] = v
+	}
+	merged, err := json.Marshal(asserted)
+	if err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+// MarshalJSON returns the JSON encoding of i.
+func (i Info) MarshalJSON() ([]byte, error) {
+	return marshalJSON(_Info(i), i.Extensions)
+}
+
+// MarshalJSON returns the JSON encoding of p.
+func (p Path) MarshalJSON() ([]byte, error) {
+	return marshalJSON(_Path(p), p.Extensions)
+}
+
+// MarshalJSON returns the JSON encoding of o.
+func (o Operation) MarshalJSON() ([]byte, error) {
+	return marshalJSON(_Operation(o), o.Extensions)
+}
+
+// MarshalJSON returns the JSON encoding of p.
+func (p Parameter) MarshalJSON() ([]byte, error) {
+	return marshalJSON(_Parameter(p), p.Extensions)
+}
+
+// MarshalJSON returns the JSON encoding of r.
+func (r Response) MarshalJSON() ([]byte, error) {
+	return marshalJSON(_Response(r), r.Extensions)
+}
+
+// MarshalJSON returns the JSON encoding of s.
+func (s SecurityScheme) MarshalJSON() ([]byte, error) {
+	return marshalJSON(_SecurityScheme(s), s.Extensions)
+}
+
+// MarshalJSON returns the JSON encoding of t.
+func (t Tag) MarshalJSON() ([]byte, error) {
+	return marshalJSON(_Tag(t), t.Extensions)
+}
+
+// New creates an OpenAPI 3.0 document from an API definition.
+func New(api *design.APIDefinition) (*OpenAPI, error) {
+	if api == nil {
+		return nil, nil
+	}
+	basePath := api.BasePath
+	if hasAbsoluteRoutes(api) || len(design.ExtractWildcards(basePath)) > 0 {
+		// OpenAPI server URLs cannot contain the base path in this case so let the paths
+		// contain the full request path.
+		basePath = ""
+	}
+	o := &OpenAPI{
+		OpenAPI: "3.0.0",
+		Info: &Info{
+			Title:          api.Title,
+			Description:    api.Description,
+			TermsOfService: api.TermsOfService,
+			Contact:        api.Contact,
+			License:        api.License,
+			Version:        api.Version,
+			Extensions:     extensionsFromDefinition(api.Metadata),
+		},
+		Servers:      serversFromDefinition(api, basePath),
+		Paths:        make(map[string]interface{}),
+		Components:   &Components{SecuritySchemes: securitySchemesFromDefinition(api.SecuritySchemes)},
+		Tags:         tagsFromDefinition(api.Metadata),
+		ExternalDocs: docsFromDefinition(api.Docs),
+	}
+
+	err := api.IterateResponses(func(r *design.ResponseDefinition) error {
+		res, err := responseSpecFromDefinition(api, r)
+		if err != nil {
+			return err
+		}
+		if o.Components.Responses == nil {
+			o.Components.Responses = make(map[string]*Response)
+		}
+		o.Components.Responses[r.Name] = res
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = api.IterateResources(func(res *design.ResourceDefinition) error {
+		for k, v := range extensionsFromDefinition(res.Metadata) {
+			o.Paths[k] = v
+		}
+		err := res.IterateFileServers(func(fs *design.FileServerDefinition) error {
+			if !mustGenerate(fs.Metadata) {
+				return nil
+			}
+			return buildPathFromFileServer(o, api, fs, basePath)
+		})
+		if err != nil {
+			return err
+		}
+		return res.IterateActions(func(a *design.ActionDefinition) error {
+			if !mustGenerate(a.Metadata) {
+				return nil
+			}
+			for _, route := range a.Routes {
+				if err := buildPathFromDefinition(o, api, route, basePath); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if api.HealthCheck {
+		buildHealthPaths(o, api)
+	}
+	if len(genschema.Definitions) > 0 {
+		o.Components.Schemas = make(map[string]*genschema.JSONSchema)
+		for n, d := range genschema.Definitions {
+			o.Components.Schemas[n] = d
+		}
+	}
+	seen := make(map[*genschema.JSONSchema]bool)
+	for _, s := range o.schemas() {
+		toOpenAPISchema(s, seen)
+	}
+	return o, nil
+}
+
+// schemas returns all the schemas used in the document.
+func (o *OpenAPI) schemas() []*genschema.JSONSchema {
+	var res []*genschema.JSONSchema
+	for _, s := range o.Components.Schemas {
+		res = append(res, s)
+	}
+	responseSchemas := func(r *Response) {
+		for _, h := range r.Headers {
+			res = append(res, h.Schema)
+		}
+		for _, c := range r.Content {
+			res = append(res, c.Schema)
+		}
+	}
+	for _, r := range o.Components.Responses {
+		responseSchemas(r)
+	}
+	for _, v := range o.Paths {
+		p, ok := v.(*Path)
+		if !ok {
+			continue
+		}
+		for _, op := range []*Operation{p.Get, p.Put, p.Post, p.Delete, p.Options, p.Head, p.Patch, p.Trace} {
+			if op == nil {
+				continue
+			}
+			for _, param := range op.Parameters {
+				res = append(res, param.Schema)
+			}
+			if op.RequestBody != nil {
+				for _, c := range op.RequestBody.Content {
+					res = append(res, c.Schema)
+				}
+			}
+			for _, r := range op.Responses {
+				responseSchemas(r)
+			}
+			if msgs, ok := op.Extensions["x-websocket-messages"].(map[string]*genschema.JSONSchema); ok {
+				for _, m := range msgs {
+					res = append(res, m)
+				}
+			}
+		}
+	}
+	return res
+}
+
+// mustGenerate returns true if the metadata indicates that a specification should be generated,
+// false otherwise. It honors the "swagger:generate" metadata key.
+func mustGenerate(meta dslengine.MetadataDefinition) bool {
+	if m, ok := meta["swagger:generate"]; ok {
+		if len(m) > 0 && m[0] == "false" {
+			return false
+		}
+	}
+	return true
+}
+
+// hasAbsoluteRoutes returns true if any action exposed by the API uses an absolute route of if the
+// API has file servers or health check endpoints.
+func hasAbsoluteRoutes(api *design.APIDefinition) bool {
+	if api.HealthCheck {
+		return true
+	}
+	for _, res := range api.Resources {
+		for _, fs := range res.FileServers {
+			if mustGenerate(fs.Metadata) {
+				return true
+			}
+		}
+		for _, a := range res.Actions {
+			if !mustGenerate(a.Metadata) {
+				continue
+			}
+			for _, ro := range a.Routes {
+				if ro.IsAbsolute() {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// serversFromDefinition computes the API servers from the API host, schemes and base path.
+func serversFromDefinition(api *design.APIDefinition, basePath string) []*Server {
+	if api.Host == "" {
+		if basePath == "" || basePath == "/" {
+			return nil
+		}
+		return []*Server{{URL: basePath}}
+	}
+	schemes := api.Schemes
+	if len(schemes) == 0 {
+		schemes = []string{"http"}
+	}
+	servers := make([]*Server, len(schemes))
+	for i, scheme := range schemes {
+		servers[i] = &Server{URL: fmt.Sprintf("%s://%s%s", scheme, api.Host, basePath)}
+	}
+	return servers
+}
+
+func securitySchemesFromDefinition(schemes []*design.SecuritySchemeDefinition) map[string]*SecurityScheme {
+	if len(schemes) == 0 {
+		return nil
+	}
+
+	defs := make(map[string]*SecurityScheme)
+	for _, scheme := range schemes {
+		def := &SecurityScheme{
+			Description: scheme.Description,
+			Extensions:  extensionsFromDefinition(scheme.Metadata),
+		}
+		switch scheme.Kind {
+		case design.BasicAuthSecurityKind:
+			def.Type = "http"
+			def.Scheme = "basic"
+		case design.APIKeySecurityKind:
+			def.Type = "apiKey"
+			def.Name = scheme.Name
+			def.In = scheme.In
+		case design.HTTPSignatureSecurityKind:
+			def.Type = "apiKey"
+			def.Name = scheme.Name
+			def.In = scheme.In
+			def.Description = httpSignatureDescription(scheme)
+		case design.JWTSecurityKind:
+			if scheme.In == "header" || scheme.In == "" {
+				def.Type = "http"
+				def.Scheme = "bearer"
+				def.BearerFormat = "JWT"
+			} else {
+				// Bearer tokens are read from the Authorization header, use an API key
+				// to describe tokens read from query strings or cookies.
+				def.Type = "apiKey"
+				def.Name = scheme.Name
+				def.In = scheme.In
+			}
+			if scheme.TokenURL != "" {
+				def.Description += fmt.Sprintf("\n\n**Token URL**: %s", scheme.TokenURL)
+			}
+			if len(scheme.Scopes) != 0 {
+				def.Description += fmt.Sprintf("\n\n**Security Scopes**:\n%s", scopesMapList(scheme.Scopes))
+			}
+		case design.OAuth2SecurityKind:
+			def.Type = "oauth2"
+			scopes := scheme.Scopes
+			if scopes == nil {
+				scopes = make(map[string]string)
+			}
+			flow := &OAuthFlow{
+				AuthorizationURL: scheme.AuthorizationURL,
+				TokenURL:         scheme.TokenURL,
+				Scopes:           scopes,
+			}
+			switch scheme.Flow {
+			case "implicit":
+				flow.TokenURL = ""
+				def.Flows = &OAuthFlows{Implicit: flow}
+			case "password":
+				flow.AuthorizationURL = ""
+				def.Flows = &OAuthFlows{Password: flow}
+			case "application":
+				flow.AuthorizationURL = ""
+				def.Flows = &OAuthFlows{ClientCredentials: flow}
+			case "accessCode":
+				def.Flows = &OAuthFlows{AuthorizationCode: flow}
+			}
+		default:
+			def.Type = scheme.Type
+		}
+		defs[scheme.SchemeName] = def
+	}
+	return defs
+}
+
+// httpSignatureDescription returns the description of an HTTP signature scheme: the description
+// given in the design or a generic one if there is none, followed by the list of signed headers.
+func httpSignatureDescription(scheme *design.SecuritySchemeDefinition) string {
+	desc := scheme.Description
+	if desc == "" {
+		desc = "Requests are signed as described in draft-cavage-http-signatures."
+	}
+	return fmt.Sprintf("%s\n\n**Signed headers**: `%s`", desc, strings.Join(scheme.SignedHeaders, " "))
+}
+
+func scopesMapList(scopes map[string]string) string {
+	names := []string{}
+	for name := range scopes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := []string{}
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("  * `%s`: %s", name, scopes[name]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func tagsFromDefinition(mdata dslengine.MetadataDefinition) (tags []*Tag) {
+	var keys []string
+	for k := range mdata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		chunks := strings.Split(key, ":")
+		if len(chunks) != 3 {
+			continue
+		}
+		if chunks[0] != "swagger" || chunks[1] != "tag" {
+			continue
+		}
+
+		tag := &Tag{Name: chunks[2]}
+		if desc := mdata[fmt.Sprintf("%s:desc", key)]; len(desc) != 0 {
+			tag.Description = desc[0]
+		}
+
+		hasDocs := false
+		docs := &ExternalDocs{}
+		if u := mdata[fmt.Sprintf("%s:url", key)]; len(u) != 0 {
+			docs.URL = u[0]
+			hasDocs = true
+		}
+		if desc := mdata[fmt.Sprintf("%s:url:desc", key)]; len(desc) != 0 {
+			docs.Description = desc[0]
+			hasDocs = true
+		}
+		if hasDocs {
+			tag.ExternalDocs = docs
+		}
+
+		tag.Extensions = extensionsFromDefinition(mdata)
+
+		tags = append(tags, tag)
+	}
+
+	return
+}
+
+func tagNamesFromDefinitions(mdatas ...dslengine.MetadataDefinition) (tagNames []string) {
+	for _, mdata := range mdatas {
+		tags := tagsFromDefinition(mdata)
+		for _, tag := range tags {
+			tagNames = append(tagNames, tag.Name)
+		}
+	}
+	return
+}
+
+func summaryFromDefinition(name string, metadata dslengine.MetadataDefinition) string {
+	if mdata, ok := metadata["swagger:summary"]; ok && len(mdata) > 0 {
+		return mdata[0]
+	}
+	return name
+}
+
+func extensionsFromDefinition(mdata dslengine.MetadataDefinition) map[string]interface{} {
+	extensions := make(map[string]interface{})
+	for key, value := range mdata {
+		chunks := strings.Split(key, ":")
+		if len(chunks) != 3 {
+			continue
+		}
+		if chunks[0] != "swagger" || chunks[1] != "extension" {
+			continue
+		}
+		if !strings.HasPrefix(chunks[2], "x-") {
+			continue
+		}
+		val := value[0]
+		ival := interface{}(val)
+		if err := json.Unmarshal([]byte(val), &ival); err != nil {
+			extensions[chunks[2]] = val
+			continue
+		}
+		extensions[chunks[2]] = ival
+	}
+	if len(extensions) == 0 {
+		return nil
+	}
+	return extensions
+}
+
+func paramsFromDefinition(api *design.APIDefinition, params *design.AttributeDefinition, path string) ([]*Parameter, error) {
+	if params == nil {
+		return nil, nil
+	}
+	obj := params.Type.ToObject()
+	if obj == nil {
+		return nil, fmt.Errorf("invalid parameters definition, not an object")
+	}
+	res := make([]*Parameter, len(obj))
+	i := 0
+	wildcards := design.ExtractWildcards(path)
+	obj.IterateAttributes(func(n string, at *design.AttributeDefinition) error {
+		in := "query"
+		required := params.IsRequired(n)
+		for _, w := range wildcards {
+			if n == w {
+				in = "path"
+				required = true
+				break
+			}
+		}
+		res[i] = paramFor(api, at, n, in, required)
+		i++
+		return nil
+	})
+	return res, nil
+}
+
+func paramsFromHeaders(api *design.APIDefinition, action *design.ActionDefinition) []*Parameter {
+	params := []*Parameter{}
+	action.IterateHeaders(func(name string, required bool, header *design.AttributeDefinition) error {
+		params = append(params, paramFor(api, header, name, "header", required))
+		return nil
+	})
+	return params
+}
+
+func paramFor(api *design.APIDefinition, at *design.AttributeDefinition, name, in string, required bool) *Parameter {
+	p := &Parameter{
+		In:          in,
+		Name:        name,
+		Description: at.Description,
+		Required:    required,
+		Schema:      genschema.AttributeSchema(api, at),
+		Extensions:  extensionsFromDefinition(at.Metadata),
+	}
+	// The parameter description is carried by the parameter itself.
+	p.Schema.Description = ""
+	if in == "query" && at.Type.IsArray() {
+		explode := true
+		p.Style = "form"
+		p.Explode = &explode
+	}
+	return p
+}
+
+// toStringMap converts map[interface{}]interface{} to a map[string]interface{} when possible.
+func toStringMap(val interface{}) interface{} {
+	switch actual := val.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{})
+		for k, v := range actual {
+			m[toString(k)] = toStringMap(v)
+		}
+		return m
+	case []interface{}:
+		mapSlice := make([]interface{}, len(actual))
+		for i, e := range actual {
+			mapSlice[i] = toStringMap(e)
+		}
+		return mapSlice
+	default:
+		return actual
+	}
+}
+
+// toString returns the string representation of the given type.
+func toString(val interface{}) string {
+	switch actual := val.(type) {
+	case string:
+		return actual
+	case int:
+		return strconv.Itoa(actual)
+	case float64:
+		return strconv.FormatFloat(actual, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(actual)
+	default:
+		panic("unexpected key type")
+	}
+}
+
+// requestBodyFromDefinition computes the request body of the given action. The payload schema is
+// listed under each MIME type the API consumes.
+func requestBodyFromDefinition(api *design.APIDefinition, action *design.ActionDefinition) *RequestBody {
+	if action.Payload == nil {
+		return nil
+	}
+	schema := genschema.TypeSchema(api, action.Payload)
+	content := make(map[string]*MediaType)
+	if action.PayloadMultipart {
+		content["multipart/form-data"] = &MediaType{Schema: schema}
+	} else {
+		for _, c := range api.Consumes {
+			for _, mt := range c.MIMETypes {
+				content[mt] = &MediaType{Schema: schema}
+			}
+		}
+		if len(content) == 0 {
+			content["application/json"] = &MediaType{Schema: schema}
+		}
+	}
+	return &RequestBody{
+		Description: action.Payload.Description,
+		Content:     content,
+		Required:    !action.PayloadOptional,
+	}
+}
+
+// messagesFromDefinition returns the schemas of the websocket messages of the given action indexed
+// by direction ("inbound" or "outbound"), nil if the action does not define messages.
+func messagesFromDefinition(api *design.APIDefinition, action *design.ActionDefinition) map[string]*genschema.JSONSchema {
+	if action.InboundMessage == nil && action.OutboundMessage == nil {
+		return nil
+	}
+	msgs := make(map[string]*genschema.JSONSchema)
+	if action.InboundMessage != nil {
+		msgs["inbound"] = genschema.TypeSchema(api, action.InboundMessage)
+	}
+	if action.OutboundMessage != nil {
+		msgs["outbound"] = genschema.TypeSchema(api, action.OutboundMessage)
+	}
+	return msgs
+}
+
+// responseSchema computes the schema of a response body. Responses that do not specify a view
+// and whose media type defines multiple views use a "oneOf" schema listing all the views.
+func responseSchema(api *design.APIDefinition, mt *design.MediaTypeDefinition, view string) *genschema.JSONSchema {
+	schema := genschema.NewJSONSchema()
+	if view != "" || len(mt.Views) < 2 {
+		if view == "" {
+			view = design.DefaultView
+		}
+		schema.Ref = genschema.MediaTypeRef(api, mt, view)
+		return schema
+	}
+	names := make([]string, 0, len(mt.Views))
+	for n := range mt.Views {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		s := genschema.NewJSONSchema()
+		s.Ref = genschema.MediaTypeRef(api, mt, n)
+		schema.OneOf = append(schema.OneOf, s)
+	}
+	return schema
+}
+
+func responseSpecFromDefinition(api *design.APIDefinition, r *design.ResponseDefinition) (*Response, error) {
+	var content map[string]*MediaType
+	if r.MediaType != "" {
+		mediaType := &MediaType{}
+		if mt, ok := api.MediaTypes[design.CanonicalIdentifier(r.MediaType)]; ok {
+			mediaType.Schema = responseSchema(api, mt, r.ViewName)
+		}
+		content = map[string]*MediaType{r.MediaType: mediaType}
+	}
+	headers, err := headersFromDefinition(api, r.Headers)
+	if err != nil {
+		return nil, err
+	}
+	desc := r.Description
+	if desc == "" {
+		desc = http.StatusText(r.Status)
+	}
+	return &Response{
+		Description: desc,
+		Content:     content,
+		Headers:     headers,
+		Extensions:  extensionsFromDefinition(r.Metadata),
+	}, nil
+}
+
+func responseFromDefinition(o *OpenAPI, api *design.APIDefinition, r *design.ResponseDefinition) (*Response, error) {
+	response, err := responseSpecFromDefinition(api, r)
+	if err != nil {
+		return nil, err
+	}
+	if r.Standard {
+		if o.Components.Responses == nil {
+			o.Components.Responses = make(map[string]*Response)
+		}
+		if _, ok := o.Components.Responses[r.Name]; !ok {
+			o.Components.Responses[r.Name] = response
+		}
+	}
+	return response, nil
+}
+
+func headersFromDefinition(api *design.APIDefinition, headers *design.AttributeDefinition) (map[string]*Header, error) {
+	if headers == nil {
+		return nil, nil
+	}
+	obj := headers.Type.ToObject()
+	if obj == nil {
+		return nil, fmt.Errorf("invalid headers definition, not an object")
+	}
+	res := make(map[string]*Header)
+	obj.IterateAttributes(func(n string, at *design.AttributeDefinition) error {
+		schema := genschema.AttributeSchema(api, at)
+		schema.Description = ""
+		res[n] = &Header{
+			Description: at.Description,
+			Required:    headers.IsRequired(n),
+			Schema:      schema,
+		}
+		return nil
+	})
+	return res, nil
+}
+
+func buildPathFromFileServer(o *OpenAPI, api *design.APIDefinition, fs *design.FileServerDefinition, basePath string) error {
+	wcs := design.ExtractWildcards(fs.RequestPath)
+	var param []*Parameter
+	if len(wcs) > 0 {
+		param = []*Parameter{{
+			In:          "path",
+			Name:        wcs[0],
+			Description: "Relative file path",
+			Required:    true,
+			Schema:      &genschema.JSONSchema{Type: genschema.JSONString},
+		}}
+	}
+
+	responses := map[string]*Response{
+		"200": {
+			Description: "File downloaded",
+			Content: map[string]*MediaType{
+				"*/*": {Schema: &genschema.JSONSchema{Type: genschema.JSONString, Format: "binary"}},
+			},
+		},
+	}
+	if len(wcs) > 0 {
+		responses["404"] = &Response{
+			Description: "File not found",
+			Content: map[string]*MediaType{
+				design.ErrorMediaIdentifier: {Schema: genschema.TypeSchema(api, design.ErrorMedia)},
+			},
+		}
+	}
+
+	operation := &Operation{
+		Description:  fs.Description,
+		Summary:      summaryFromDefinition(fmt.Sprintf("Download %s", fs.FilePath), fs.Metadata),
+		ExternalDocs: docsFromDefinition(fs.Docs),
+		OperationID:  fmt.Sprintf("%s#%s", fs.Parent.Name, fs.RequestPath),
+		Parameters:   param,
+		Responses:    responses,
+	}
+
+	applySecurity(operation, fs.Security)
+
+	p := pathFor(o, fs.RequestPath, basePath)
+	p.Get = operation
+	p.Extensions = extensionsFromDefinition(fs.Metadata)
+
+	return nil
+}
+
+// buildHealthPaths adds the liveness and readiness endpoints to the document.
+func buildHealthPaths(o *OpenAPI, api *design.APIDefinition) {
+	content := map[string]*MediaType{
+		design.HealthReportMediaIdentifier: {Schema: genschema.TypeSchema(api, design.HealthReportMedia)},
+	}
+	endpoints := []struct{ name, path, summary string }{
+		{"healthz", design.HealthLivenessPath, "Liveness check"},
+		{"readyz", design.HealthReadinessPath, "Readiness check"},
+	}
+	for _, e := range endpoints {
+		p := pathFor(o, e.path, "")
+		p.Get = &Operation{
+			Tags:        []string{"health"},
+			Summary:     e.summary,
+			OperationID: "health#" + e.name,
+			Responses: map[string]*Response{
+				"200": {Description: "All checks succeeded", Content: content},
+				"503": {Description: "At least one check failed", Content: content},
+			},
+		}
+	}
+}
+
+// rateLimitResponse returns the response sent by rate limited actions to clients that exceed the
+// limit.
+func rateLimitResponse(api *design.APIDefinition) *Response {
+	header := func(description string) *Header {
+		return &Header{Description: description, Schema: &genschema.JSONSchema{Type: genschema.JSONInteger}}
+	}
+	return &Response{
+		Description: "Too many requests",
+		Headers: map[string]*Header{
+			"Retry-After":           header("Number of seconds to wait before retrying"),
+			"X-RateLimit-Limit":     header("Maximum number of requests allowed at once"),
+			"X-RateLimit-Remaining": header("Number of requests remaining"),
+			"X-RateLimit-Reset":     header("Number of seconds until the allowance is fully restored"),
+		},
+		Content: map[string]*MediaType{
+			design.ErrorMediaIdentifier: {Schema: genschema.TypeSchema(api, design.ErrorMedia)},
+		},
+	}
+}
+
+func buildPathFromDefinition(o *OpenAPI, api *design.APIDefinition, route *design.RouteDefinition, basePath string) error {
+	action := route.Parent
+
+	tagNames := tagNamesFromDefinitions(action.Parent.Metadata, action.Metadata)
+	if len(tagNames) == 0 {
+		// By default tag with resource name
+		tagNames = []string{route.Parent.Parent.Name}
+	}
+	params, err := paramsFromDefinition(api, action.AllParams(), route.FullPath())
+	if err != nil {
+		return err
+	}
+
+	params = append(params, paramsFromHeaders(api, action)...)
+
+	responses := make(map[string]*Response, len(action.Responses))
+	for _, r := range action.Responses {
+		resp, err := responseFromDefinition(o, api, r)
+		if err != nil {
+			return err
+		}
+		responses[strconv.Itoa(r.Status)] = resp
+	}
+	if len(responses) == 0 {
+		responses["default"] = &Response{Description: "Default response"}
+	}
+	rl, err := action.RateLimit()
+	if err != nil {
+		return err
+	}
+	if _, ok := responses["429"]; rl != nil && !ok {
+		responses["429"] = rateLimitResponse(api)
+	}
+
+	operationID := fmt.Sprintf("%s#%s", action.Parent.Name, action.Name)
+	index := 0
+	for i, rt := range action.Routes {
+		if rt == route {
+			index = i
+			break
+		}
+	}
+	if index > 0 {
+		operationID = fmt.Sprintf("%s#%d", operationID, index)
+	}
+
+	operation := &Operation{
+		Tags:         tagNames,
+		Description:  action.Description,
+		Summary:      summaryFromDefinition(action.Name+" "+action.Parent.Name, action.Metadata),
+		ExternalDocs: docsFromDefinition(action.Docs),
+		OperationID:  operationID,
+		Parameters:   params,
+		RequestBody:  requestBodyFromDefinition(api, action),
+		Responses:    responses,
+		Extensions:   extensionsFromDefinition(route.Metadata),
+	}
+
+	if msgs := messagesFromDefinition(api, action); msgs != nil {
+		if operation.Extensions == nil {
+			operation.Extensions = make(map[string]interface{})
+		}
+		operation.Extensions["x-websocket-messages"] = msgs
+	}
+
+	if len(action.Schemes) > 0 && api.Host != "" {
+		for _, scheme := range action.Schemes {
+			operation.Servers = append(operation.Servers,
+				&Server{URL: fmt.Sprintf("%s://%s%s", scheme, api.Host, basePath)})
+		}
+	}
+
+	applySecurity(operation, action.Security)
+
+	p := pathFor(o, route.FullPath(), basePath)
+	switch route.Verb {
+	case "GET":
+		p.Get = operation
+	case "PUT":
+		p.Put = operation
+	case "POST":
+		p.Post = operation
+	case "DELETE":
+		p.Delete = operation
+	case "OPTIONS":
+		p.Options = operation
+	case "HEAD":
+		p.Head = operation
+	case "PATCH":
+		p.Patch = operation
+	case "TRACE":
+		p.Trace = operation
+	}
+	p.Extensions = extensionsFromDefinition(action.Metadata)
+	return nil
+}
+
+// pathFor returns the path item for the given request path relative to the given base path,
+// creating it if needed.
+func pathFor(o *OpenAPI, requestPath, basePath string) *Path {
+	key := design.WildcardRegex.ReplaceAllStringFunc(
+		requestPath,
+		func(w string) string {
+			return fmt.Sprintf("/{%s}", w[2:])
+		},
+	)
+	if basePath != "" && basePath != "/" {
+		key = strings.TrimPrefix(key, basePath)
+	}
+	if key == "" {
+		key = "/"
+	}
+	if path, ok := o.Paths[key]; ok {
+		return path.(*Path)
+	}
+	path := new(Path)
+	o.Paths[key] = path
+	return path
+}
+
+func applySecurity(operation *Operation, security *design.SecurityDefinition) {
+	if security != nil && security.Scheme.Kind != design.NoSecurityKind {
+		if security.Scheme.Kind == design.JWTSecurityKind && len(security.Scopes) > 0 {
+			if operation.Description != "" {
+				operation.Description += "\n\n"
+			}
+			operation.Description += fmt.Sprintf("Required security scopes:\n%s", scopesList(security.Scopes))
+		}
+		scopes := security.Scopes
+		if scopes == nil {
+			scopes = make([]string, 0)
+		}
+		operation.Security = []map[string][]string{{security.Scheme.SchemeName: scopes}}
+	}
+}
+
+func scopesList(scopes []string) string {
+	sort.Strings(scopes)
+
+	var lines []string
+	for _, scope := range scopes {
+		lines = append(lines, fmt.Sprintf("  * `%s`", scope))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func docsFromDefinition(docs *design.DocsDefinition) *ExternalDocs {
+	if docs == nil {
+		return nil
+	}
+	return &ExternalDocs{
+		Description: docs.Description,
+		URL:         docs.URL,
+	}
+}
+
+// toOpenAPISchema adapts a JSON schema produced by the JSON schema generator to the subset
+// supported by OpenAPI 3.0: references point to the components section and hyper-schema fields
+// are removed.
+func toOpenAPISchema(s *genschema.JSONSchema, seen map[*genschema.JSONSchema]bool) {
+	if s == nil || seen[s] {
+		return
+	}
+	seen[s] = true
+	if strings.HasPrefix(s.Ref, definitionsRef) {
+		s.Ref = schemasRef + strings.TrimPrefix(s.Ref, definitionsRef)
+	}
+	if s.Type == genschema.JSONFile {
+		s.Type = genschema.JSONString
+		s.Format = "binary"
+	}
+	s.Schema = ""
+	s.ID = ""
+	s.Media = nil
+	s.Links = nil
+	s.PathStart = ""
+	s.DefaultValue = toStringMap(s.DefaultValue)
+	toOpenAPISchema(s.Items, seen)
+	for _, p := range s.Properties {
+		toOpenAPISchema(p, seen)
+	}
+	for _, d := range s.Definitions {
+		toOpenAPISchema(d, seen)
+	}
+	for _, a := range s.AnyOf {
+		toOpenAPISchema(a, seen)
+	}
+	for _, a := range s.OneOf {
+		toOpenAPISchema(a, seen)
+	}
+}
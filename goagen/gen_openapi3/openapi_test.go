@@ -0,0 +1,396 @@
+package genopenapi3_test
+
+import (
+	"encoding/json"
+
+	. "github.com/goadesign/goa/design"
+	. "github.com/goadesign/goa/design/apidsl"
+	"github.com/goadesign/goa/dslengine"
+	"github.com/goadesign/goa/goagen/gen_openapi3"
+	"github.com/goadesign/goa/goagen/gen_schema"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("New", func() {
+	var spec *genopenapi3.OpenAPI
+	var newErr error
+
+	BeforeEach(func() {
+		spec = nil
+		newErr = nil
+		dslengine.Reset()
+		genschema.Definitions = make(map[string]*genschema.JSONSchema)
+	})
+
+	JustBeforeEach(func() {
+		err := dslengine.Run()
+		Ω(err).ShouldNot(HaveOccurred())
+		spec, newErr = genopenapi3.New(Design)
+	})
+
+	Context("with a valid API definition", func() {
+		const (
+			title    = "title"
+			host     = "goa.design"
+			basePath = "/base"
+		)
+
+		BeforeEach(func() {
+			API("test", func() {
+				Title(title)
+				Host(host)
+				Scheme("http", "https")
+				BasePath(basePath)
+				Metadata("swagger:tag:tag")
+				Metadata("swagger:tag:tag:desc", "Tag desc.")
+			})
+		})
+
+		It("sets the basic fields", func() {
+			Ω(newErr).ShouldNot(HaveOccurred())
+			Ω(spec.OpenAPI).Should(Equal("3.0.0"))
+			Ω(spec.Info.Title).Should(Equal(title))
+			Ω(spec.Tags).Should(Equal([]*genopenapi3.Tag{{Name: "tag", Description: "Tag desc."}}))
+		})
+
+		It("computes the servers from the host, schemes and base path", func() {
+			Ω(spec.Servers).Should(Equal([]*genopenapi3.Server{
+				{URL: "http://goa.design/base"},
+				{URL: "https://goa.design/base"},
+			}))
+		})
+
+		It("serializes into JSON", func() {
+			b, err := json.Marshal(spec)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(b)).Should(ContainSubstring(`"openapi":"3.0.0"`))
+		})
+	})
+
+	Context("with resources", func() {
+		BeforeEach(func() {
+			API("test", func() {
+				Consumes("application/json")
+				Consumes("application/xml")
+			})
+			BottleMedia := MediaType("application/vnd.goa.example.bottle", func() {
+				Attributes(func() {
+					Attribute("id", Integer)
+					Attribute("name", String)
+					Attribute("payload", "BottlePayload")
+				})
+				View("default", func() {
+					Attribute("id")
+					Attribute("name")
+				})
+				View("tiny", func() {
+					Attribute("id")
+				})
+			})
+			BottlePayload := Type("BottlePayload", func() {
+				Attribute("name", String, func() {
+					MinLength(1)
+				})
+				Required("name")
+			})
+			Resource("bottle", func() {
+				Action("show", func() {
+					Routing(GET("/:id"))
+					Params(func() {
+						Param("id", Integer)
+						Param("tags", ArrayOf(String))
+					})
+					Headers(func() {
+						Header("X-Account", String)
+						Required("X-Account")
+					})
+					Response(OK, BottleMedia)
+					Response(NotFound)
+				})
+				Action("update", func() {
+					Metadata("swagger:summary", "Update a bottle")
+					Routing(PUT("/:id"))
+					Payload(BottlePayload)
+					Response(OK, func() {
+						Media(BottleMedia, "tiny")
+					})
+				})
+				Action("hidden", func() {
+					Metadata("swagger:generate", "false")
+					Routing(GET("/hidden"))
+				})
+			})
+		})
+
+		It("generates path and query parameters", func() {
+			Ω(newErr).ShouldNot(HaveOccurred())
+			Ω(spec.Paths).Should(HaveKey("/{id}"))
+			get := spec.Paths["/{id}"].(*genopenapi3.Path).Get
+			Ω(get).ShouldNot(BeNil())
+			Ω(get.OperationID).Should(Equal("bottle#show"))
+			Ω(get.Parameters).Should(HaveLen(3))
+			params := make(map[string]*genopenapi3.Parameter)
+			for _, p := range get.Parameters {
+				params[p.Name] = p
+			}
+			Ω(params["id"].In).Should(Equal("path"))
+			Ω(params["id"].Required).Should(BeTrue())
+			Ω(params["id"].Schema.Type).Should(Equal(genschema.JSONType(genschema.JSONInteger)))
+			Ω(params["tags"].In).Should(Equal("query"))
+			Ω(params["tags"].Style).Should(Equal("form"))
+			Ω(*params["tags"].Explode).Should(BeTrue())
+			Ω(params["X-Account"].In).Should(Equal("header"))
+			Ω(params["X-Account"].Required).Should(BeTrue())
+		})
+
+		It("uses oneOf for responses rendering multi-view media types", func() {
+			get := spec.Paths["/{id}"].(*genopenapi3.Path).Get
+			Ω(get.Responses).Should(HaveKey("200"))
+			content := get.Responses["200"].Content
+			Ω(content).Should(HaveKey("application/vnd.goa.example.bottle"))
+			schema := content["application/vnd.goa.example.bottle"].Schema
+			Ω(schema.Ref).Should(BeEmpty())
+			Ω(schema.OneOf).Should(HaveLen(2))
+			Ω(schema.OneOf[0].Ref).Should(Equal("#/components/schemas/GoaExampleBottle"))
+			Ω(schema.OneOf[1].Ref).Should(Equal("#/components/schemas/GoaExampleBottleTiny"))
+		})
+
+		It("references the view when the response specifies one", func() {
+			put := spec.Paths["/{id}"].(*genopenapi3.Path).Put
+			Ω(put).ShouldNot(BeNil())
+			Ω(put.Summary).Should(Equal("Update a bottle"))
+			schema := put.Responses["200"].Content["application/vnd.goa.example.bottle"].Schema
+			Ω(schema.Ref).Should(Equal("#/components/schemas/GoaExampleBottleTiny"))
+		})
+
+		It("lists the request body under each consumed MIME type", func() {
+			put := spec.Paths["/{id}"].(*genopenapi3.Path).Put
+			Ω(put.RequestBody).ShouldNot(BeNil())
+			Ω(put.RequestBody.Required).Should(BeTrue())
+			Ω(put.RequestBody.Content).Should(HaveLen(2))
+			Ω(put.RequestBody.Content).Should(HaveKey("application/json"))
+			Ω(put.RequestBody.Content).Should(HaveKey("application/xml"))
+			Ω(put.RequestBody.Content["application/json"].Schema.Ref).Should(Equal("#/components/schemas/BottlePayload"))
+		})
+
+		It("generates the component schemas with rewritten references", func() {
+			Ω(spec.Components.Schemas).Should(HaveKey("BottlePayload"))
+			Ω(spec.Components.Schemas).Should(HaveKey("GoaExampleBottle"))
+			Ω(spec.Components.Schemas["GoaExampleBottle"].Media).Should(BeNil())
+			b, err := json.Marshal(spec)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(b)).ShouldNot(ContainSubstring("#/definitions/"))
+		})
+
+		It("honors the swagger:generate metadata", func() {
+			Ω(spec.Paths).ShouldNot(HaveKey("/hidden"))
+		})
+	})
+
+	Context("with security schemes", func() {
+		BeforeEach(func() {
+			API("test", func() {
+				BasicAuthSecurity("basic")
+				APIKeySecurity("key", func() {
+					Header("X-API-Key")
+				})
+				JWTSecurity("jwt_cookie", func() {
+					Cookie("token")
+				})
+				JWTSecurity("jwt", func() {
+					Header("Authorization")
+					Scope("api:read", "Read access")
+				})
+				OAuth2Security("oauth2", func() {
+					AccessCodeFlow("http://example.com/auth", "http://example.com/token")
+					Scope("api:write", "Write access")
+				})
+				OAuth2Security("implicit", func() {
+					ImplicitFlow("http://example.com/auth")
+				})
+				HTTPSignatureSecurity("signed")
+				HTTPSignatureSecurity("described", func() {
+					Description("Requests signed with Ed25519")
+				})
+			})
+			Resource("res", func() {
+				Action("act", func() {
+					Security("jwt", func() {
+						Scope("api:read")
+					})
+					Routing(GET("/"))
+				})
+			})
+		})
+
+		It("maps basic auth to the HTTP basic scheme", func() {
+			Ω(newErr).ShouldNot(HaveOccurred())
+			basic := spec.Components.SecuritySchemes["basic"]
+			Ω(basic.Type).Should(Equal("http"))
+			Ω(basic.Scheme).Should(Equal("basic"))
+		})
+
+		It("maps API keys", func() {
+			key := spec.Components.SecuritySchemes["key"]
+			Ω(key.Type).Should(Equal("apiKey"))
+			Ω(key.In).Should(Equal("header"))
+			Ω(key.Name).Should(Equal("X-API-Key"))
+		})
+
+		It("maps HTTP signatures to API keys in the Signature header", func() {
+			signed := spec.Components.SecuritySchemes["signed"]
+			Ω(signed.Type).Should(Equal("apiKey"))
+			Ω(signed.In).Should(Equal("header"))
+			Ω(signed.Name).Should(Equal("Signature"))
+			Ω(signed.Description).Should(ContainSubstring("`(request-target) host date digest`"))
+			Ω(signed.Description).ShouldNot(ContainSubstring("HMAC"))
+		})
+
+		It("uses the description of HTTP signature schemes", func() {
+			described := spec.Components.SecuritySchemes["described"]
+			Ω(described.Description).Should(HavePrefix("Requests signed with Ed25519\n\n"))
+			Ω(described.Description).Should(ContainSubstring("`(request-target) host date digest`"))
+		})
+
+		It("maps JWT to the HTTP bearer scheme", func() {
+			jwt := spec.Components.SecuritySchemes["jwt"]
+			Ω(jwt.Type).Should(Equal("http"))
+			Ω(jwt.Scheme).Should(Equal("bearer"))
+			Ω(jwt.BearerFormat).Should(Equal("JWT"))
+			Ω(jwt.Description).Should(ContainSubstring("api:read"))
+		})
+
+		It("maps JWT read from cookies to API keys", func() {
+			jwt := spec.Components.SecuritySchemes["jwt_cookie"]
+			Ω(jwt.Type).Should(Equal("apiKey"))
+			Ω(jwt.In).Should(Equal("cookie"))
+			Ω(jwt.Name).Should(Equal("token"))
+		})
+
+		It("maps the OAuth2 flows", func() {
+			oauth2 := spec.Components.SecuritySchemes["oauth2"]
+			Ω(oauth2.Type).Should(Equal("oauth2"))
+			Ω(oauth2.Flows.AuthorizationCode).Should(Equal(&genopenapi3.OAuthFlow{
+				AuthorizationURL: "http://example.com/auth",
+				TokenURL:         "http://example.com/token",
+				Scopes:           map[string]string{"api:write": "Write access"},
+			}))
+			implicit := spec.Components.SecuritySchemes["implicit"]
+			Ω(implicit.Flows.Implicit).Should(Equal(&genopenapi3.OAuthFlow{
+				AuthorizationURL: "http://example.com/auth",
+				Scopes:           map[string]string{},
+			}))
+		})
+
+		It("sets the operation security requirements", func() {
+			get := spec.Paths["/"].(*genopenapi3.Path).Get
+			Ω(get.Security).Should(Equal([]map[string][]string{{"jwt": {"api:read"}}}))
+		})
+	})
+	Context("with websocket messages", func() {
+		BeforeEach(func() {
+			API("test", func() {})
+			msg := Type("message", func() {
+				Attribute("text")
+			})
+			Resource("res", func() {
+				Action("chat", func() {
+					Routing(GET("/chat"))
+					Scheme("ws")
+					InboundMessage(msg)
+					Response(SwitchingProtocols)
+				})
+			})
+		})
+
+		It("documents the messages in a vendor extension", func() {
+			Ω(newErr).ShouldNot(HaveOccurred())
+			get := spec.Paths["/chat"].(*genopenapi3.Path).Get
+			Ω(get.Extensions).Should(HaveKey("x-websocket-messages"))
+			msgs := get.Extensions["x-websocket-messages"].(map[string]*genschema.JSONSchema)
+			Ω(msgs).Should(HaveKey("inbound"))
+			Ω(msgs).ShouldNot(HaveKey("outbound"))
+			Ω(msgs["inbound"].Ref).Should(Equal("#/components/schemas/message"))
+			Ω(spec.Components.Schemas).Should(HaveKey("message"))
+		})
+	})
+
+	Context("with health checks", func() {
+		BeforeEach(func() {
+			API("test", func() {
+				BasePath("/api")
+				HealthCheck()
+			})
+			Resource("res", func() {
+				Action("list", func() {
+					Routing(GET("/items"))
+					Response(OK)
+				})
+			})
+		})
+
+		It("documents the liveness and readiness endpoints", func() {
+			Ω(newErr).ShouldNot(HaveOccurred())
+			Ω(spec.Paths).Should(HaveKey("/api/items"))
+			for _, path := range []string{"/healthz", "/readyz"} {
+				Ω(spec.Paths).Should(HaveKey(path))
+				get := spec.Paths[path].(*genopenapi3.Path).Get
+				Ω(get).ShouldNot(BeNil())
+				Ω(get.Responses).Should(HaveKey("200"))
+				Ω(get.Responses).Should(HaveKey("503"))
+				Ω(get.Responses["503"].Content).Should(HaveKey(HealthReportMediaIdentifier))
+			}
+			Ω(spec.Components.Schemas).Should(HaveKey("health-report"))
+		})
+	})
+
+	Context("without health checks", func() {
+		BeforeEach(func() {
+			API("test", func() {
+				BasePath("/api")
+			})
+			Resource("res", func() {
+				Action("list", func() {
+					Routing(GET("/items"))
+					Response(OK)
+				})
+			})
+		})
+
+		It("does not document the health check endpoints", func() {
+			Ω(newErr).ShouldNot(HaveOccurred())
+			Ω(spec.Paths).ShouldNot(HaveKey("/healthz"))
+			Ω(spec.Paths).ShouldNot(HaveKey("/readyz"))
+			Ω(spec.Paths).Should(HaveKey("/items"))
+		})
+	})
+
+	Context("with a rate limited action", func() {
+		BeforeEach(func() {
+			API("test", func() {})
+			Resource("res", func() {
+				Action("list", func() {
+					Routing(GET("/items"))
+					Metadata("ratelimit", "100/1m")
+					Response(OK)
+				})
+				Action("show", func() {
+					Routing(GET("/items/:id"))
+					Response(OK)
+				})
+			})
+		})
+
+		It("documents the 429 response", func() {
+			Ω(newErr).ShouldNot(HaveOccurred())
+			list := spec.Paths["/items"].(*genopenapi3.Path).Get
+			Ω(list.Responses).Should(HaveKey("429"))
+			Ω(list.Responses["429"].Headers).Should(HaveKey("Retry-After"))
+			Ω(list.Responses["429"].Headers).Should(HaveKey("X-RateLimit-Remaining"))
+			Ω(list.Responses["429"].Content).Should(HaveKey(ErrorMediaIdentifier))
+			show := spec.Paths["/items/{id}"].(*genopenapi3.Path).Get
+			Ω(show.Responses).ShouldNot(HaveKey("429"))
+		})
+	})
+})
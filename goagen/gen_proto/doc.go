@@ -0,0 +1,18 @@
+/*
+Package genproto provides a generator for the Protocol Buffers definitions and gRPC adapters of a goa
+API. The generator produces a ".proto" file that declares one message per user type and media type
+and one service per resource with one RPC per action. The services are named after the resources
+with the "Service" suffix so that they do not collide with the messages, e.g. service
+"BottleService" for resource "bottle" and message "Bottle" for media type "Bottle". The field
+numbers may be set explicitly with the "proto:field" attribute metadata, fields without explicit
+numbers are numbered in alphabetical order of their names.
+
+The generator also produces the "grpcserver" package which implements the gRPC services by
+dispatching the calls to the HTTP handlers mounted on the goa service so that the same controller
+implementations serve both HTTP and gRPC clients. The package relies on the Go code generated by
+protoc from the ".proto" file using the protoc-gen-go and protoc-gen-go-grpc plugins.
+
+Actions that cannot be mapped to unary RPCs (websocket, server-sent events and multipart actions)
+are listed as comments in the generated ".proto" file.
+*/
+package genproto
@@ -0,0 +1,509 @@
+package genproto
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/goadesign/goa/design"
+	"github.com/goadesign/goa/goagen/codegen"
+	"github.com/goadesign/goa/goagen/utils"
+	"github.com/goadesign/goa/version"
+)
+
+//NewGenerator returns an initialized instance of a Protocol Buffers Generator
+func NewGenerator(options ...Option) *Generator {
+	g := &Generator{}
+
+	for _, option := range options {
+		option(g)
+	}
+
+	return g
+}
+
+// Generator is the Protocol Buffers definitions and gRPC adapters generator.
+type Generator struct {
+	API      *design.APIDefinition // The API definition
+	OutDir   string                // Path to output directory
+	Target   string                // Name of Go package generated by protoc
+	genfiles []string              // Generated files
+}
+
+// Generate is the generator entry point called by the meta generator.
+func Generate() (files []string, err error) {
+	var outDir, target, ver string
+
+	set := flag.NewFlagSet("proto", flag.PanicOnError)
+	set.StringVar(&outDir, "out", "", "")
+	set.StringVar(&target, "pkg", "pb", "")
+	set.StringVar(&ver, "version", "", "")
+	set.String("design", "", "")
+	set.Parse(os.Args[1:])
+
+	if err := codegen.CheckVersion(ver); err != nil {
+		return nil, err
+	}
+
+	g := &Generator{OutDir: outDir, Target: target, API: design.Design}
+
+	return g.Generate()
+}
+
+// Generate produces the ".proto" file and the gRPC adapters package.
+func (g *Generator) Generate() (_ []string, err error) {
+	if g.API == nil {
+		return nil, fmt.Errorf("missing API definition, make sure design is properly initialized")
+	}
+
+	go utils.Catch(nil, func() { g.Cleanup() })
+
+	defer func() {
+		if err != nil {
+			g.Cleanup()
+		}
+	}()
+
+	if g.Target == "" {
+		g.Target = "pb"
+	}
+
+	// Setup output directories
+	pbDir := filepath.Join(g.OutDir, g.Target)
+	if err = os.MkdirAll(pbDir, 0755); err != nil {
+		return nil, err
+	}
+	serverDir := filepath.Join(g.OutDir, "grpcserver")
+	if err = os.RemoveAll(serverDir); err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(serverDir, 0755); err != nil {
+		return nil, err
+	}
+	g.genfiles = append(g.genfiles, serverDir)
+
+	pbPkg, err := codegen.PackagePath(pbDir)
+	if err != nil {
+		return nil, err
+	}
+	name := codegen.SnakeCase(codegen.Goify(g.API.Name, true))
+	f, err := NewFile(g.API, name, pbPkg)
+	if err != nil {
+		return nil, err
+	}
+
+	// Generate <api>.proto
+	protoFile := filepath.Join(pbDir, name+".proto")
+	if err = g.generateProto(protoFile, f); err != nil {
+		return nil, err
+	}
+	g.genfiles = append(g.genfiles, protoFile)
+
+	// Generate grpcserver/<resource>.go
+	for _, svc := range f.Services {
+		if len(svc.RPCs) == 0 {
+			continue
+		}
+		filename := filepath.Join(serverDir, codegen.SnakeCase(svc.Name)+".go")
+		if err = g.generateServer(filename, f, svc); err != nil {
+			return nil, err
+		}
+	}
+
+	// Generate grpcserver/helpers.go
+	if err = g.generateHelpers(filepath.Join(serverDir, "helpers.go"), f); err != nil {
+		return nil, err
+	}
+
+	return g.genfiles, nil
+}
+
+// Cleanup removes all the files generated by this generator during the last invokation of Generate.
+func (g *Generator) Cleanup() {
+	for _, f := range g.genfiles {
+		os.RemoveAll(f)
+	}
+	g.genfiles = nil
+}
+
+func (g *Generator) generateProto(filename string, f *File) error {
+	w, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	tmpl := template.Must(template.New("proto").Funcs(template.FuncMap{
+		"commandLine": codegen.CommandLine,
+		"doc":         doc,
+	}).Parse(protoT))
+	data := map[string]interface{}{
+		"ToolVersion": version.String(),
+		"API":         g.API,
+		"File":        f,
+		"GoName":      g.Target,
+	}
+	return tmpl.Execute(w, data)
+}
+
+func (g *Generator) generateServer(filename string, f *File, svc *Service) (err error) {
+	var file *codegen.SourceFile
+	file, err = codegen.SourceFileFor(filename)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		file.Close()
+		if err == nil {
+			err = file.FormatCode()
+		}
+	}()
+	title := fmt.Sprintf("%s: %s gRPC adapter", g.API.Context(), svc.Name)
+	imports := []*codegen.ImportSpec{
+		codegen.SimpleImport("context"),
+		codegen.SimpleImport("fmt"),
+		codegen.SimpleImport("net/http"),
+		codegen.SimpleImport("net/url"),
+		codegen.SimpleImport("github.com/goadesign/goa"),
+		codegen.NewImport(g.Target, f.GoPackage),
+		codegen.SimpleImport("google.golang.org/protobuf/types/known/emptypb"),
+	}
+	if err = file.WriteHeader(title, "grpcserver", imports); err != nil {
+		return err
+	}
+	data := map[string]interface{}{
+		"Service": svc,
+		"PB":      g.Target,
+	}
+	return file.ExecuteTemplate("server", serverT, g.funcs(), data)
+}
+
+func (g *Generator) generateHelpers(filename string, f *File) (err error) {
+	var file *codegen.SourceFile
+	file, err = codegen.SourceFileFor(filename)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		file.Close()
+		if err == nil {
+			err = file.FormatCode()
+		}
+	}()
+	title := fmt.Sprintf("%s: gRPC adapters helpers", g.API.Context())
+	imports := []*codegen.ImportSpec{
+		codegen.SimpleImport("bytes"),
+		codegen.SimpleImport("context"),
+		codegen.SimpleImport("encoding/json"),
+		codegen.SimpleImport("fmt"),
+		codegen.SimpleImport("net/http"),
+		codegen.SimpleImport("net/url"),
+		codegen.SimpleImport("reflect"),
+		codegen.SimpleImport("strings"),
+		codegen.SimpleImport("github.com/goadesign/goa"),
+		codegen.NewImport(g.Target, f.GoPackage),
+		codegen.SimpleImport("google.golang.org/grpc"),
+		codegen.SimpleImport("google.golang.org/grpc/codes"),
+		codegen.SimpleImport("google.golang.org/grpc/metadata"),
+		codegen.SimpleImport("google.golang.org/grpc/status"),
+		codegen.SimpleImport("google.golang.org/protobuf/encoding/protojson"),
+		codegen.SimpleImport("google.golang.org/protobuf/proto"),
+	}
+	if err = file.WriteHeader(title, "grpcserver", imports); err != nil {
+		return err
+	}
+	data := map[string]interface{}{
+		"File": f,
+		"PB":   g.Target,
+	}
+	return file.ExecuteTemplate("helpers", helpersT, g.funcs(), data)
+}
+
+func (g *Generator) funcs() template.FuncMap {
+	return template.FuncMap{
+		"bodyCode":  bodyCode,
+		"paramCode": paramCode,
+		"pathCode":  pathCode,
+	}
+}
+
+// doc returns the protocol buffer comment for the given text indented with the given prefix.
+func doc(prefix, text string) string {
+	if text == "" {
+		return ""
+	}
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(prefix+"// "+l, " ")
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// pathCode returns the Go expression that computes the request path of the given RPC.
+func pathCode(r *RPC) string {
+	if len(r.PathParams) == 0 {
+		return fmt.Sprintf("%q", r.PathFormat)
+	}
+	args := make([]string, len(r.PathParams))
+	for i, fd := range r.PathParams {
+		args[i] = fmt.Sprintf("pathParam(req.%s, %t)", fd.GoName, fd.Wildcard)
+	}
+	return fmt.Sprintf("fmt.Sprintf(%q, %s)", r.PathFormat, strings.Join(args, ", "))
+}
+
+// paramCode returns the Go statements that add the value of the given request field to the given
+// url.Values or http.Header variable.
+func paramCode(values string, fd *Field) string {
+	ref := "req." + fd.GoName
+	switch {
+	case fd.Repeated:
+		return fmt.Sprintf("for _, v := range %s {\n\t%s.Add(%q, fmt.Sprint(v))\n}", ref, values, fd.AttName)
+	case fd.Optional:
+		return fmt.Sprintf("if %s != nil {\n\t%s.Set(%q, fmt.Sprint(*%s))\n}", ref, values, fd.AttName, ref)
+	}
+	return fmt.Sprintf("%s.Set(%q, fmt.Sprint(%s))", values, fd.AttName, ref)
+}
+
+// bodyCode returns the Go statements that assign the JSON representation of the given field value
+// to target.
+func bodyCode(target, ref string, fd *Field) string {
+	switch {
+	case fd.KeyType != "" && fd.Message != nil:
+		return fmt.Sprintf("if %s != nil {\n\tm := make(map[%s]interface{}, len(%s))\n\tfor k, e := range %s {\n\t\tm[k] = toBody%s(e)\n\t}\n\t%s = m\n}",
+			ref, fd.GoKeyType(), ref, ref, fd.Message.Name, target)
+	case fd.Repeated && fd.Message != nil:
+		return fmt.Sprintf("if %s != nil {\n\titems := make([]interface{}, len(%s))\n\tfor i, e := range %s {\n\t\titems[i] = toBody%s(e)\n\t}\n\t%s = items\n}",
+			ref, ref, ref, fd.Message.Name, target)
+	case fd.Message != nil:
+		return fmt.Sprintf("if %s != nil {\n\t%s = toBody%s(%s)\n}", ref, target, fd.Message.Name, ref)
+	case fd.Repeated || fd.KeyType != "":
+		return fmt.Sprintf("if %s != nil {\n\t%s = %s\n}", ref, target, ref)
+	case fd.Optional:
+		return fmt.Sprintf("if %s != nil {\n\t%s = *%s\n}", ref, target, ref)
+	}
+	return fmt.Sprintf("%s = %s", target, ref)
+}
+
+const protoT = `// Code generated by goagen {{.ToolVersion}}, DO NOT EDIT.
+//
+// API {{printf "%q" .API.Name}}: Protocol Buffers definitions
+//
+// Command:
+{{doc "" commandLine}}
+syntax = "proto3";
+
+package {{.File.Package}};
+
+option go_package = "{{.File.GoPackage}};{{.GoName}}";
+{{if .File.HasEmpty}}
+import "google/protobuf/empty.proto";
+{{end}}{{range .File.Services}}
+{{doc "" .Description}}{{if .RPCs}}service {{.Name}} {
+{{range .RPCs}}{{doc "\t" .Description}}	rpc {{.Name}}({{.Request.Name}}) returns ({{.Response}});
+{{end}}{{range .Skipped}}	// {{.}}
+{{end}}}
+{{else}}// Service {{.Name}} has no RPC.
+{{range .Skipped}}// {{.}}
+{{end}}{{end}}{{end}}{{range .File.Messages}}
+{{doc "" .Description}}message {{.Name}} {
+{{range .Fields}}{{doc "\t" .Description}}	{{.Declaration}}
+{{end}}{{range .Skipped}}	// {{.}}
+{{end}}}
+{{end}}`
+
+const serverT = `{{$pb := .PB}}{{$svc := .Service}}// {{$svc.Name}}Server implements the {{$pb}}.{{$svc.Name}}Server interface by dispatching the
+// calls to the HTTP handlers mounted on the service.
+type {{$svc.Name}}Server struct {
+	{{$pb}}.Unimplemented{{$svc.Name}}Server
+	service *goa.Service
+}
+
+// New{{$svc.Name}}Server returns a gRPC server that dispatches calls to the given service.
+func New{{$svc.Name}}Server(service *goa.Service) *{{$svc.Name}}Server {
+	return &{{$svc.Name}}Server{service: service}
+}
+{{range $svc.RPCs}}
+// {{.Name}} dispatches the call to the {{.Verb}} {{.Path}} handler.
+func (s *{{$svc.Name}}Server) {{.Name}}(ctx context.Context, req *{{$pb}}.{{.Request.Name}}) (*{{if .ResponseMessage}}{{$pb}}.{{.Response}}{{else}}emptypb.Empty{{end}}, error) {
+	path := {{pathCode .}}
+	query := url.Values{}
+{{range .QueryParams}}	{{paramCode "query" .}}
+{{end}}	header := http.Header{}
+{{range .Headers}}	{{paramCode "header" .}}
+{{end}}	var body interface{}
+{{if .Payload}}	{{bodyCode "body" (printf "req.%s" .Payload.GoName) .Payload}}
+{{end}}{{if .ResponseMessage}}	res := &{{$pb}}.{{.Response}}{}
+	if err := dispatch(ctx, s.service, "{{.Verb}}", path, query, header, body, res, {{.Collection}}); err != nil {
+		return nil, err
+	}
+	return res, nil
+{{else}}	if err := dispatch(ctx, s.service, "{{.Verb}}", path, query, header, body, nil, false); err != nil {
+		return nil, err
+	}
+	return &emptypb.Empty{}, nil
+{{end}}}
+{{end}}`
+
+const helpersT = `{{$pb := .PB}}// Register registers the gRPC servers of all the API resources with the given gRPC server.
+func Register(server *grpc.Server, service *goa.Service) {
+{{range .File.Services}}{{if .RPCs}}	{{$pb}}.Register{{.Name}}Server(server, New{{.Name}}Server(service))
+{{end}}{{end}}}
+
+// unmarshaler decodes the response bodies, the bodies may contain attributes that are not mapped to
+// message fields.
+var unmarshaler = protojson.UnmarshalOptions{DiscardUnknown: true}
+
+// responseWriter records the response written by the service handlers.
+type responseWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+// dispatch sends the HTTP request built from a gRPC call to the service handlers and decodes the
+// response body into res. The body of collection responses is decoded into the "items" field of
+// res. The incoming gRPC metadata is forwarded as HTTP headers.
+func dispatch(ctx context.Context, service *goa.Service, method, path string, query url.Values, header http.Header, body interface{}, res proto.Message, collection bool) error {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for k, vals := range md {
+			if strings.HasPrefix(k, ":") || strings.HasPrefix(k, "grpc-") || k == "content-type" {
+				continue
+			}
+			if _, ok := header[http.CanonicalHeaderKey(k)]; ok {
+				continue
+			}
+			for _, v := range vals {
+				header.Add(k, v)
+			}
+		}
+	}
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+		header.Set("Content-Type", "application/json")
+	}
+	header.Set("Accept", "application/json")
+	u := url.URL{Path: path, RawQuery: query.Encode()}
+	r, err := http.NewRequest(method, u.String(), &buf)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	r.Header = header
+	r = r.WithContext(ctx)
+	rw := &responseWriter{header: make(http.Header), status: http.StatusOK}
+	service.Mux.ServeHTTP(rw, r)
+	if rw.status < 200 || rw.status > 299 {
+		return status.Error(grpcCode(rw.status), errorMessage(rw))
+	}
+	if res == nil || rw.body.Len() == 0 {
+		return nil
+	}
+	b := rw.body.Bytes()
+	if collection {
+		b = append(append([]byte(` + "`" + `{"items":` + "`" + `), b...), '}')
+	}
+	if err := unmarshaler.Unmarshal(b, res); err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
+}
+
+// Header implements http.ResponseWriter.
+func (w *responseWriter) Header() http.Header {
+	return w.header
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (w *responseWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+// Write implements http.ResponseWriter.
+func (w *responseWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+// grpcCode maps HTTP status codes to gRPC status codes.
+func grpcCode(code int) codes.Code {
+	switch code {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	}
+	if code >= 500 {
+		return codes.Internal
+	}
+	return codes.Unknown
+}
+
+// errorMessage returns the detail of goa errors or the response body.
+func errorMessage(rw *responseWriter) string {
+	var e struct {
+		Detail string ` + "`json:\"detail\"`" + `
+	}
+	if err := json.Unmarshal(rw.body.Bytes(), &e); err == nil && e.Detail != "" {
+		return e.Detail
+	}
+	if msg := strings.TrimSpace(rw.body.String()); msg != "" {
+		return msg
+	}
+	return http.StatusText(rw.status)
+}
+
+// pathParam returns the path segment for the given path parameter value. Array values are
+// separated with commas.
+func pathParam(v interface{}, wildcard bool) string {
+	val := reflect.ValueOf(v)
+	if val.Kind() == reflect.Slice {
+		elems := make([]string, val.Len())
+		for i := 0; i < val.Len(); i++ {
+			elems[i] = fmt.Sprint(val.Index(i).Interface())
+		}
+		return escape(strings.Join(elems, ","), wildcard)
+	}
+	return escape(fmt.Sprint(v), wildcard)
+}
+
+// escape escapes the given path segment, wildcard values may contain slashes.
+func escape(s string, wildcard bool) string {
+	if !wildcard {
+		return url.PathEscape(s)
+	}
+	segments := strings.Split(s, "/")
+	for i, seg := range segments {
+		segments[i] = url.PathEscape(seg)
+	}
+	return strings.Join(segments, "/")
+}
+{{range .File.BodyMessages}}
+// toBody{{.Name}} returns the JSON representation of v.
+func toBody{{.Name}}(v *{{$pb}}.{{.Name}}) map[string]interface{} {
+	if v == nil {
+		return nil
+	}
+	body := make(map[string]interface{})
+{{range .Fields}}	{{bodyCode (printf "body[%q]" .AttName) (printf "v.%s" .GoName) .}}
+{{end}}	return body
+}
+{{end}}`
@@ -0,0 +1,27 @@
+package genproto
+
+import "github.com/goadesign/goa/design"
+
+//Option a generator option definition
+type Option func(*Generator)
+
+//API The API definition
+func API(API *design.APIDefinition) Option {
+	return func(g *Generator) {
+		g.API = API
+	}
+}
+
+//OutDir Path to output directory
+func OutDir(outDir string) Option {
+	return func(g *Generator) {
+		g.OutDir = outDir
+	}
+}
+
+//Target Name of the Go package generated by protoc
+func Target(target string) Option {
+	return func(g *Generator) {
+		g.Target = target
+	}
+}
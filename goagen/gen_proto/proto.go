@@ -0,0 +1,724 @@
+package genproto
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/goadesign/goa/design"
+	"github.com/goadesign/goa/goagen/codegen"
+)
+
+// FieldMetadataKey is the name of the attribute metadata key used to set the protocol buffer field
+// number of the attribute, for example:
+//
+//	Attribute("name", String, func() {
+//		Metadata("proto:field", "3")
+//	})
+const FieldMetadataKey = "proto:field"
+
+// EmptyMessage is the name of the message returned by RPCs whose action responses have no body.
+const EmptyMessage = "google.protobuf.Empty"
+
+type (
+	// File describes a protocol buffer definition file.
+	File struct {
+		// Package is the protocol buffer package name.
+		Package string
+		// GoPackage is the import path of the Go package generated by protoc.
+		GoPackage string
+		// Messages lists the messages sorted by name.
+		Messages []*Message
+		// Services lists the services, one per resource.
+		Services []*Service
+	}
+
+	// Message describes a protocol buffer message.
+	Message struct {
+		// Name is the message name.
+		Name string
+		// Description is the message description if any.
+		Description string
+		// Fields lists the message fields sorted by number.
+		Fields []*Field
+		// Skipped lists the reasons why attributes were not mapped to fields.
+		Skipped []string
+	}
+
+	// Field describes a message field.
+	Field struct {
+		// Name is the protocol buffer field name.
+		Name string
+		// GoName is the name of the struct field generated by protoc.
+		GoName string
+		// AttName is the name of the design attribute (or HTTP header) mapped by the field.
+		AttName string
+		// Description is the field description if any.
+		Description string
+		// Type is the protocol buffer scalar type or the name of the message type of the
+		// field, elements or map values.
+		Type string
+		// KeyType is the protocol buffer type of map keys, empty if the field is not a map.
+		KeyType string
+		// Number is the field number.
+		Number int
+		// Repeated is true if the field is a repeated field.
+		Repeated bool
+		// Optional is true if the field is a scalar field with explicit presence.
+		Optional bool
+		// Message is the message type of the field, elements or map values if any.
+		Message *Message
+		// Wildcard is true if the field maps a catch-all path parameter.
+		Wildcard bool
+	}
+
+	// Service describes a protocol buffer service.
+	Service struct {
+		// Name is the service name.
+		Name string
+		// Description is the service description if any.
+		Description string
+		// RPCs lists the service RPCs.
+		RPCs []*RPC
+		// Skipped lists the reasons why actions were not mapped to RPCs.
+		Skipped []string
+	}
+
+	// RPC describes a service RPC and the HTTP request it maps to.
+	RPC struct {
+		// Name is the RPC name.
+		Name string
+		// Description is the RPC description if any.
+		Description string
+		// Request is the RPC request message.
+		Request *Message
+		// Response is the name of the RPC response message.
+		Response string
+		// ResponseMessage is the RPC response message, nil if Response is EmptyMessage.
+		ResponseMessage *Message
+		// Collection is true if the action response is a collection, in this case
+		// ResponseMessage wraps the elements in its "items" field.
+		Collection bool
+		// Verb is the HTTP method of the action route.
+		Verb string
+		// Path is the action route path.
+		Path string
+		// PathFormat is the action route path with wildcards replaced with %s.
+		PathFormat string
+		// PathParams lists the request fields mapped to path parameters in order.
+		PathParams []*Field
+		// QueryParams lists the request fields mapped to query string parameters.
+		QueryParams []*Field
+		// Headers lists the request fields mapped to HTTP headers.
+		Headers []*Field
+		// Payload is the request field mapped to the request body if any.
+		Payload *Field
+	}
+
+	// builder builds the file messages.
+	builder struct {
+		messages map[string]*Message
+	}
+)
+
+// validName matches valid protocol buffer identifiers.
+var validName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// NewFile builds the protocol buffer definitions for the given API. pkg is the protocol buffer
+// package name and goPkg the import path of the Go package generated by protoc.
+func NewFile(api *design.APIDefinition, pkg, goPkg string) (*File, error) {
+	b := &builder{messages: make(map[string]*Message)}
+	f := &File{Package: pkg, GoPackage: goPkg}
+	err := api.IterateResources(func(r *design.ResourceDefinition) error {
+		// Suffix the service names so that they do not collide with the names of the
+		// messages built from the media types, e.g. resource "bottle" and media type "Bottle".
+		svc := &Service{Name: codegen.Goify(r.Name, true) + "Service", Description: r.Description}
+		err := r.IterateActions(func(a *design.ActionDefinition) error {
+			rpc, reason, err := b.rpc(a)
+			if err != nil {
+				return err
+			}
+			if reason != "" {
+				svc.Skipped = append(svc.Skipped, fmt.Sprintf("action %s: %s", a.Name, reason))
+				return nil
+			}
+			svc.RPCs = append(svc.RPCs, rpc)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		f.Services = append(f.Services, svc)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(b.messages))
+	for n := range b.messages {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		f.Messages = append(f.Messages, b.messages[n])
+	}
+	for _, svc := range f.Services {
+		if _, ok := b.messages[svc.Name]; ok {
+			return nil, fmt.Errorf("service %s conflicts with the message of the same name", svc.Name)
+		}
+	}
+	return f, nil
+}
+
+// HasEmpty returns true if at least one RPC returns EmptyMessage.
+func (f *File) HasEmpty() bool {
+	for _, s := range f.Services {
+		for _, r := range s.RPCs {
+			if r.Response == EmptyMessage {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// BodyMessages returns the messages used to build request bodies sorted by name.
+func (f *File) BodyMessages() []*Message {
+	seen := make(map[string]*Message)
+	var collect func(m *Message)
+	collect = func(m *Message) {
+		if _, ok := seen[m.Name]; ok {
+			return
+		}
+		seen[m.Name] = m
+		for _, fd := range m.Fields {
+			if fd.Message != nil {
+				collect(fd.Message)
+			}
+		}
+	}
+	for _, s := range f.Services {
+		for _, r := range s.RPCs {
+			if r.Payload != nil && r.Payload.Message != nil {
+				collect(r.Payload.Message)
+			}
+		}
+	}
+	var res []*Message
+	for _, m := range f.Messages {
+		if _, ok := seen[m.Name]; ok {
+			res = append(res, m)
+		}
+	}
+	return res
+}
+
+// Declaration returns the field declaration used in the proto file.
+func (f *Field) Declaration() string {
+	var typ string
+	switch {
+	case f.KeyType != "":
+		typ = fmt.Sprintf("map<%s, %s>", f.KeyType, f.Type)
+	case f.Repeated:
+		typ = "repeated " + f.Type
+	case f.Optional:
+		typ = "optional " + f.Type
+	default:
+		typ = f.Type
+	}
+	return fmt.Sprintf("%s %s = %d;", typ, f.Name, f.Number)
+}
+
+// GoKeyType returns the Go type of the map keys generated by protoc.
+func (f *Field) GoKeyType() string {
+	return goScalar(f.KeyType)
+}
+
+// rpc builds the RPC for the given action. It returns a non empty reason if the action cannot be
+// mapped to a unary RPC.
+func (b *builder) rpc(a *design.ActionDefinition) (*RPC, string, error) {
+	if a.WebSocket() {
+		return nil, "websocket actions are not supported", nil
+	}
+	if a.Stream != nil {
+		return nil, "server-sent events actions are not supported", nil
+	}
+	if a.PayloadMultipart {
+		return nil, "multipart payloads are not supported", nil
+	}
+	if len(a.Routes) == 0 {
+		return nil, "no route", nil
+	}
+	name := codegen.Goify(a.Name, true) + codegen.Goify(a.Parent.Name, true)
+	route := a.Routes[0]
+	rpc := &RPC{
+		Name:        codegen.Goify(a.Name, true),
+		Description: a.Description,
+		Verb:        route.Verb,
+		Path:        route.FullPath(),
+		PathFormat:  design.WildcardRegex.ReplaceAllLiteralString(route.FullPath(), "/%s"),
+	}
+
+	// Response
+	resp, reason := b.response(a)
+	if reason != "" {
+		return nil, reason, nil
+	}
+	if resp == nil {
+		rpc.Response = EmptyMessage
+	} else {
+		rpc.Response = resp.name
+		rpc.ResponseMessage = resp.msg
+		rpc.Collection = resp.collection
+	}
+
+	// Request
+	req := &Message{Name: name + "Request", Description: fmt.Sprintf("%s is the request of the %s RPC.", name+"Request", rpc.Name)}
+	var fields []*Field
+	atts := make(map[*Field]*design.AttributeDefinition)
+	used := make(map[string]bool)
+	params := a.AllParams()
+	pathParams := route.Params()
+	isPath := func(n string) bool {
+		for _, p := range pathParams {
+			if p == n {
+				return true
+			}
+		}
+		return false
+	}
+	otherPathParams := make(map[string]bool)
+	for _, r := range a.Routes[1:] {
+		for _, p := range r.Params() {
+			otherPathParams[p] = true
+		}
+	}
+	for _, n := range sortedNames(params.Type.ToObject()) {
+		att := params.Type.ToObject()[n]
+		if otherPathParams[n] && !isPath(n) {
+			req.Skipped = append(req.Skipped, fmt.Sprintf("param %q: path parameter of another route", n))
+			continue
+		}
+		fd, reason, err := b.field(req, protoName(n), n, att, isPath(n) || params.IsRequired(n))
+		if err != nil {
+			return nil, "", err
+		}
+		if reason != "" {
+			req.Skipped = append(req.Skipped, fmt.Sprintf("param %q: %s", n, reason))
+			continue
+		}
+		if fd.Message != nil || fd.KeyType != "" {
+			req.Skipped = append(req.Skipped, fmt.Sprintf("param %q: only primitive and array params are supported", n))
+			continue
+		}
+		used[fd.Name] = true
+		fields = append(fields, fd)
+		atts[fd] = att
+		if isPath(n) {
+			continue
+		}
+		rpc.QueryParams = append(rpc.QueryParams, fd)
+	}
+	for _, p := range pathParams {
+		for _, fd := range fields {
+			if fd.AttName == p {
+				fd.Wildcard = strings.Contains(route.FullPath(), "*"+p)
+				rpc.PathParams = append(rpc.PathParams, fd)
+			}
+		}
+	}
+	err := a.IterateHeaders(func(n string, required bool, att *design.AttributeDefinition) error {
+		pn := strings.ToLower(protoName(n))
+		if used[pn] {
+			req.Skipped = append(req.Skipped, fmt.Sprintf("header %q: field %s already used by a param", n, pn))
+			return nil
+		}
+		fd, reason, err := b.field(req, pn, n, att, required)
+		if err != nil {
+			return err
+		}
+		if reason == "" && (fd.Message != nil || fd.KeyType != "") {
+			reason = "only primitive and array headers are supported"
+		}
+		if reason != "" {
+			req.Skipped = append(req.Skipped, fmt.Sprintf("header %q: %s", n, reason))
+			return nil
+		}
+		used[pn] = true
+		fields = append(fields, fd)
+		atts[fd] = att
+		rpc.Headers = append(rpc.Headers, fd)
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	if a.Payload != nil {
+		pn := "payload"
+		if used[pn] {
+			return nil, "", fmt.Errorf("%s: param or header name %q conflicts with the payload field", a.Context(), pn)
+		}
+		fd, reason, err := b.field(req, pn, pn, &design.AttributeDefinition{Type: a.Payload, Description: a.Payload.Description}, !a.PayloadOptional)
+		if err != nil {
+			return nil, "", err
+		}
+		if reason != "" {
+			return nil, "payload " + reason, nil
+		}
+		fields = append(fields, fd)
+		atts[fd] = a.Payload.AttributeDefinition
+		rpc.Payload = fd
+	}
+	if err := numberFields(req.Name, fields, atts); err != nil {
+		return nil, "", err
+	}
+	req.Fields = fields
+	sortFields(req)
+	b.messages[req.Name] = req
+	rpc.Request = req
+	return rpc, "", nil
+}
+
+// response describes a RPC response.
+type response struct {
+	name       string
+	msg        *Message
+	collection bool
+}
+
+// response computes the RPC response from the first successful action response. It returns nil if
+// the response has no body.
+func (b *builder) response(a *design.ActionDefinition) (*response, string) {
+	var success *design.ResponseDefinition
+	a.IterateResponses(func(r *design.ResponseDefinition) error {
+		if r.Status >= 200 && r.Status < 300 && (success == nil || r.Status < success.Status) {
+			success = r
+		}
+		return nil
+	})
+	if success == nil {
+		return nil, ""
+	}
+	var dt design.DataType
+	if success.Type != nil {
+		dt = success.Type
+		if mt, ok := dt.(*design.MediaTypeDefinition); ok {
+			view := success.ViewName
+			if view == "" {
+				view = "default"
+			}
+			p, _, err := mt.Project(view)
+			if err != nil {
+				return nil, err.Error()
+			}
+			dt = p
+		}
+	} else if mt := design.Design.MediaTypeWithIdentifier(success.MediaType); mt != nil {
+		view := success.ViewName
+		if view == "" {
+			view = "default"
+		}
+		p, _, err := mt.Project(view)
+		if err != nil {
+			return nil, err.Error()
+		}
+		dt = p
+	}
+	if dt == nil {
+		return nil, ""
+	}
+	name := typeName(dt)
+	if name == "" {
+		return nil, "response must be a user type, a media type or a collection"
+	}
+	if arr := dt.ToArray(); arr != nil {
+		elem := arr.ElemType
+		en := typeName(elem.Type)
+		if en == "" || !elem.Type.IsObject() {
+			return nil, "collection responses must contain objects"
+		}
+		em, err := b.message(en, elem)
+		if err != nil {
+			return nil, err.Error()
+		}
+		wrapper := &Message{
+			Name:        name,
+			Description: fmt.Sprintf("%s wraps a collection of %s.", name, en),
+			Fields:      []*Field{{Name: "items", GoName: "Items", AttName: "items", Type: em.Name, Number: 1, Repeated: true, Message: em}},
+		}
+		b.messages[name] = wrapper
+		return &response{name: name, msg: wrapper, collection: true}, ""
+	}
+	if !dt.IsObject() {
+		return nil, "response must be an object or a collection"
+	}
+	m, err := b.message(name, &design.AttributeDefinition{Type: dt})
+	if err != nil {
+		return nil, err.Error()
+	}
+	return &response{name: name, msg: m}, ""
+}
+
+// message returns the message with the given name describing the given object attribute,
+// building it if needed.
+func (b *builder) message(name string, att *design.AttributeDefinition) (*Message, error) {
+	if m, ok := b.messages[name]; ok {
+		return m, nil
+	}
+	m := &Message{Name: name, Description: att.Description}
+	if ut, ok := att.Type.(*design.UserTypeDefinition); ok && m.Description == "" {
+		m.Description = ut.Description
+	}
+	if mt, ok := att.Type.(*design.MediaTypeDefinition); ok && m.Description == "" {
+		m.Description = mt.Description
+	}
+	// Register first so recursive types terminate.
+	b.messages[name] = m
+	obj := att.Type.ToObject()
+	var fields []*Field
+	atts := make(map[*Field]*design.AttributeDefinition)
+	for _, n := range sortedNames(obj) {
+		if !validName.MatchString(n) {
+			return nil, fmt.Errorf("type %s: attribute name %q is not a valid protocol buffer field name", name, n)
+		}
+		child := obj[n]
+		required := att.IsRequired(n)
+		if ut, ok := att.Type.(*design.UserTypeDefinition); ok {
+			required = required || ut.IsRequired(n)
+		}
+		if mt, ok := att.Type.(*design.MediaTypeDefinition); ok {
+			required = required || mt.IsRequired(n)
+		}
+		fd, reason, err := b.field(m, n, n, child, required)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			m.Skipped = append(m.Skipped, fmt.Sprintf("attribute %q: %s", n, reason))
+			continue
+		}
+		fields = append(fields, fd)
+		atts[fd] = child
+	}
+	if err := numberFields(name, fields, atts); err != nil {
+		return nil, err
+	}
+	m.Fields = fields
+	sortFields(m)
+	return m, nil
+}
+
+// field builds the field with the given name describing the given attribute. It returns a non
+// empty reason if the attribute type cannot be represented.
+func (b *builder) field(parent *Message, name, attName string, att *design.AttributeDefinition, required bool) (*Field, string, error) {
+	fd := &Field{Name: name, GoName: goName(name), AttName: attName, Description: att.Description}
+	if !att.Type.IsObject() {
+		att = underlying(att)
+	}
+	switch actual := att.Type.(type) {
+	case *design.Array:
+		elem := actual.ElemType
+		if elem.Type.IsArray() || elem.Type.IsHash() {
+			return nil, "arrays of arrays or hashes are not supported", nil
+		}
+		typ, msg, reason, err := b.elemType(parent, name, elem)
+		if err != nil || reason != "" {
+			return nil, reason, err
+		}
+		fd.Type, fd.Message, fd.Repeated = typ, msg, true
+	case *design.Hash:
+		var key string
+		switch actual.KeyType.Type.Kind() {
+		case design.StringKind, design.DateTimeKind, design.UUIDKind:
+			key = "string"
+		case design.IntegerKind:
+			key = "int64"
+		default:
+			return nil, "hash keys must be strings or integers", nil
+		}
+		elem := actual.ElemType
+		if elem.Type.IsArray() || elem.Type.IsHash() {
+			return nil, "hashes of arrays or hashes are not supported", nil
+		}
+		typ, msg, reason, err := b.elemType(parent, name, elem)
+		if err != nil || reason != "" {
+			return nil, reason, err
+		}
+		fd.KeyType, fd.Type, fd.Message = key, typ, msg
+	default:
+		typ, msg, reason, err := b.elemType(parent, name, att)
+		if err != nil || reason != "" {
+			return nil, reason, err
+		}
+		fd.Type, fd.Message = typ, msg
+		fd.Optional = msg == nil && !required
+	}
+	return fd, "", nil
+}
+
+// elemType returns the protocol buffer type of the given non-container attribute.
+func (b *builder) elemType(parent *Message, name string, att *design.AttributeDefinition) (string, *Message, string, error) {
+	switch att.Type.Kind() {
+	case design.BooleanKind:
+		return "bool", nil, "", nil
+	case design.IntegerKind:
+		return "int64", nil, "", nil
+	case design.NumberKind:
+		return "double", nil, "", nil
+	case design.StringKind, design.DateTimeKind, design.UUIDKind:
+		return "string", nil, "", nil
+	case design.FileKind:
+		return "", nil, "files are not supported", nil
+	case design.AnyKind:
+		return "", nil, "Any is not supported", nil
+	case design.ObjectKind, design.UserTypeKind, design.MediaTypeKind:
+		mn := typeName(att.Type)
+		if mn == "" {
+			mn = parent.Name + codegen.Goify(name, true)
+		}
+		m, err := b.message(mn, att)
+		if err != nil {
+			return "", nil, "", err
+		}
+		return m.Name, m, "", nil
+	}
+	return "", nil, fmt.Sprintf("unsupported type %s", att.Type.Name()), nil
+}
+
+// numberFields assigns the field numbers, honoring the numbers set with the "proto:field"
+// attribute metadata.
+func numberFields(msg string, fields []*Field, atts map[*Field]*design.AttributeDefinition) error {
+	taken := make(map[int]string)
+	for _, fd := range fields {
+		vals, ok := atts[fd].Metadata[FieldMetadataKey]
+		if !ok || len(vals) == 0 {
+			continue
+		}
+		n, err := strconv.Atoi(vals[0])
+		if err != nil || n < 1 || n > 536870911 {
+			return fmt.Errorf("%s.%s: invalid field number %q", msg, fd.Name, vals[0])
+		}
+		if n >= 19000 && n <= 19999 {
+			return fmt.Errorf("%s.%s: field number %d is reserved by protocol buffers", msg, fd.Name, n)
+		}
+		if other, ok := taken[n]; ok {
+			return fmt.Errorf("%s: fields %s and %s use the same number %d", msg, other, fd.Name, n)
+		}
+		taken[n] = fd.Name
+		fd.Number = n
+	}
+	next := 1
+	for _, fd := range fields {
+		if fd.Number != 0 {
+			continue
+		}
+		for {
+			if _, ok := taken[next]; !ok {
+				break
+			}
+			next++
+		}
+		fd.Number = next
+		taken[next] = fd.Name
+	}
+	return nil
+}
+
+// underlying returns the attribute wrapped by user types and media types that are not objects.
+func underlying(att *design.AttributeDefinition) *design.AttributeDefinition {
+	for {
+		switch actual := att.Type.(type) {
+		case *design.UserTypeDefinition:
+			att = actual.AttributeDefinition
+		case *design.MediaTypeDefinition:
+			att = actual.AttributeDefinition
+		default:
+			return att
+		}
+	}
+}
+
+// sortFields sorts the message fields by number.
+func sortFields(m *Message) {
+	sort.Slice(m.Fields, func(i, j int) bool { return m.Fields[i].Number < m.Fields[j].Number })
+}
+
+// typeName returns the message name of user types and media types, empty otherwise.
+func typeName(dt design.DataType) string {
+	switch actual := dt.(type) {
+	case *design.UserTypeDefinition:
+		return codegen.Goify(actual.TypeName, true)
+	case *design.MediaTypeDefinition:
+		return codegen.Goify(actual.TypeName, true)
+	}
+	return ""
+}
+
+// sortedNames returns the names of the object attributes in alphabetical order.
+func sortedNames(o design.Object) []string {
+	names := make([]string, 0, len(o))
+	for n := range o {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// protoName returns a valid protocol buffer field name for the given param or header name.
+func protoName(n string) string {
+	res := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' {
+			return r
+		}
+		return '_'
+	}, n)
+	if res == "" || res[0] >= '0' && res[0] <= '9' {
+		res = "_" + res
+	}
+	return strings.ToLower(res[:1]) + res[1:]
+}
+
+// goName returns the name of the Go struct field generated by protoc for the given field name.
+func goName(s string) string {
+	var t []byte
+	i := 0
+	if s[0] == '_' {
+		t = append(t, 'X')
+		i++
+	}
+	for ; i < len(s); i++ {
+		c := s[i]
+		if c == '_' && i+1 < len(s) && isLower(s[i+1]) {
+			continue
+		}
+		if isDigit(c) {
+			t = append(t, c)
+			continue
+		}
+		if isLower(c) {
+			c ^= ' '
+		}
+		t = append(t, c)
+		for i+1 < len(s) && isLower(s[i+1]) {
+			i++
+			t = append(t, s[i])
+		}
+	}
+	return string(t)
+}
+
+// goScalar returns the Go type generated by protoc for the given scalar type.
+func goScalar(t string) string {
+	switch t {
+	case "double":
+		return "float64"
+	case "bytes":
+		return "[]byte"
+	}
+	return t
+}
+
+func isLower(c byte) bool { return 'a' <= c && c <= 'z' }
+func isDigit(c byte) bool { return '0' <= c && c <= '9' }
@@ -0,0 +1,198 @@
+package genproto_test
+
+import (
+	. "github.com/goadesign/goa/design"
+	. "github.com/goadesign/goa/design/apidsl"
+	"github.com/goadesign/goa/dslengine"
+	"github.com/goadesign/goa/goagen/gen_proto"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NewFile", func() {
+	var file *genproto.File
+	var newErr error
+
+	BeforeEach(func() {
+		file = nil
+		newErr = nil
+		dslengine.Reset()
+	})
+
+	JustBeforeEach(func() {
+		err := dslengine.Run()
+		Ω(err).ShouldNot(HaveOccurred())
+		file, newErr = genproto.NewFile(Design, "cellar", "example.com/cellar/pb")
+	})
+
+	Context("with resources", func() {
+		BeforeEach(func() {
+			API("cellar", func() {})
+			bottle := MediaType("application/vnd.bottle", func() {
+				Attributes(func() {
+					Attribute("id", Integer)
+					Attribute("name", String)
+					Attribute("vintage", Integer, func() {
+						Metadata("proto:field", "10")
+					})
+					Attribute("tags", ArrayOf(String))
+					Required("id", "name")
+				})
+				View("default", func() {
+					Attribute("id")
+					Attribute("name")
+					Attribute("vintage")
+					Attribute("tags")
+				})
+			})
+			payload := Type("BottlePayload", func() {
+				Attribute("name", String)
+				Attribute("rating", Number)
+				Required("name")
+			})
+			Resource("bottle", func() {
+				BasePath("/bottles")
+				Action("show", func() {
+					Routing(GET("/:id"))
+					Params(func() {
+						Param("id", Integer)
+					})
+					Response(OK, bottle)
+				})
+				Action("list", func() {
+					Routing(GET(""))
+					Params(func() {
+						Param("limit", Integer)
+					})
+					Headers(func() {
+						Header("X-Request-Id")
+					})
+					Response(OK, CollectionOf(bottle))
+				})
+				Action("create", func() {
+					Routing(POST(""))
+					Payload(payload)
+					Response(Created)
+				})
+				Action("watch", func() {
+					Routing(GET("/ws"))
+					Scheme("ws")
+					Response(SwitchingProtocols)
+				})
+			})
+		})
+
+		It("creates one service per resource and one RPC per action", func() {
+			Ω(newErr).ShouldNot(HaveOccurred())
+			Ω(file.Package).Should(Equal("cellar"))
+			Ω(file.GoPackage).Should(Equal("example.com/cellar/pb"))
+			Ω(file.Services).Should(HaveLen(1))
+			svc := file.Services[0]
+			Ω(svc.Name).Should(Equal("BottleService"))
+			Ω(svc.RPCs).Should(HaveLen(3))
+			Ω(svc.RPCs[0].Name).Should(Equal("Create"))
+			Ω(svc.RPCs[1].Name).Should(Equal("List"))
+			Ω(svc.RPCs[2].Name).Should(Equal("Show"))
+			Ω(svc.Skipped).Should(Equal([]string{"action watch: websocket actions are not supported"}))
+		})
+
+		It("does not give services the names of messages", func() {
+			Ω(newErr).ShouldNot(HaveOccurred())
+			for _, m := range file.Messages {
+				Ω(m.Name).ShouldNot(Equal(file.Services[0].Name))
+			}
+			Ω(file.Messages).Should(ContainElement(WithTransform(func(m *genproto.Message) string { return m.Name }, Equal("Bottle"))))
+		})
+
+		It("maps media types to messages honoring explicit field numbers", func() {
+			show := file.Services[0].RPCs[2]
+			Ω(show.Response).Should(Equal("Bottle"))
+			m := show.ResponseMessage
+			Ω(m.Fields).Should(HaveLen(4))
+			Ω(m.Fields[0].Declaration()).Should(Equal("int64 id = 1;"))
+			Ω(m.Fields[1].Declaration()).Should(Equal("string name = 2;"))
+			Ω(m.Fields[2].Declaration()).Should(Equal("repeated string tags = 3;"))
+			Ω(m.Fields[3].Declaration()).Should(Equal("optional int64 vintage = 10;"))
+		})
+
+		It("maps the params to the request message", func() {
+			show := file.Services[0].RPCs[2]
+			Ω(show.Request.Name).Should(Equal("ShowBottleRequest"))
+			Ω(show.Verb).Should(Equal("GET"))
+			Ω(show.PathFormat).Should(Equal("/bottles/%s"))
+			Ω(show.PathParams).Should(HaveLen(1))
+			Ω(show.PathParams[0].Declaration()).Should(Equal("int64 id = 1;"))
+		})
+
+		It("maps collections to wrapper messages", func() {
+			list := file.Services[0].RPCs[1]
+			Ω(list.Collection).Should(BeTrue())
+			Ω(list.Response).Should(Equal("BottleCollection"))
+			Ω(list.ResponseMessage.Fields[0].Declaration()).Should(Equal("repeated Bottle items = 1;"))
+			Ω(list.QueryParams).Should(HaveLen(1))
+			Ω(list.QueryParams[0].Declaration()).Should(Equal("optional int64 limit = 1;"))
+			Ω(list.Headers).Should(HaveLen(1))
+			Ω(list.Headers[0].Name).Should(Equal("x_request_id"))
+			Ω(list.Headers[0].AttName).Should(Equal("X-Request-Id"))
+		})
+
+		It("maps payloads and empty responses", func() {
+			create := file.Services[0].RPCs[0]
+			Ω(create.Response).Should(Equal(genproto.EmptyMessage))
+			Ω(create.ResponseMessage).Should(BeNil())
+			Ω(create.Payload).ShouldNot(BeNil())
+			Ω(create.Payload.Declaration()).Should(Equal("BottlePayload payload = 1;"))
+			Ω(create.Payload.Message.Fields[0].Declaration()).Should(Equal("string name = 1;"))
+			Ω(create.Payload.Message.Fields[1].Declaration()).Should(Equal("optional double rating = 2;"))
+			Ω(file.HasEmpty()).Should(BeTrue())
+			Ω(file.BodyMessages()).Should(HaveLen(1))
+		})
+	})
+
+	Context("with a type named after a service", func() {
+		BeforeEach(func() {
+			API("cellar", func() {})
+			payload := Type("BottleService", func() {
+				Attribute("name", String)
+			})
+			Resource("bottle", func() {
+				Action("create", func() {
+					Routing(POST("/"))
+					Payload(payload)
+					Response(NoContent)
+				})
+			})
+		})
+
+		It("returns an error", func() {
+			Ω(newErr).Should(HaveOccurred())
+			Ω(newErr.Error()).Should(Equal("service BottleService conflicts with the message of the same name"))
+		})
+	})
+
+	Context("with duplicate field numbers", func() {
+		BeforeEach(func() {
+			API("cellar", func() {})
+			payload := Type("Payload", func() {
+				Attribute("a", String, func() {
+					Metadata("proto:field", "1")
+				})
+				Attribute("b", String, func() {
+					Metadata("proto:field", "1")
+				})
+			})
+			Resource("res", func() {
+				Action("act", func() {
+					Routing(POST("/"))
+					Payload(payload)
+					Response(NoContent)
+				})
+			})
+		})
+
+		It("returns an error", func() {
+			Ω(newErr).Should(HaveOccurred())
+			Ω(newErr.Error()).Should(ContainSubstring("same number 1"))
+		})
+	})
+})
@@ -47,6 +47,7 @@ type (
 
 		// Union
 		AnyOf []*JSONSchema `json:"anyOf,omitempty"`
+		OneOf []*JSONSchema `json:"oneOf,omitempty"`
 	}
 
 	// JSONType is the JSON type enum.
@@ -318,6 +319,14 @@ func TypeSchema(api *design.APIDefinition, t design.DataType) *JSONSchema {
 	return s
 }
 
+// AttributeSchema produces the JSON schema corresponding to the given attribute including its
+// description, default value, example and validations.
+func AttributeSchema(api *design.APIDefinition, at *design.AttributeDefinition) *JSONSchema {
+	s := NewJSONSchema()
+	buildAttributeSchema(api, s, at)
+	return s
+}
+
 type mergeItems []struct {
 	a, b   interface{}
 	needed bool
@@ -452,6 +452,9 @@ func New(api *design.APIDefinition) (*Swagger, error) {
 	if err != nil {
 		return nil, err
 	}
+	if api.HealthCheck {
+		buildHealthPaths(s, api)
+	}
 	if len(genschema.Definitions) > 0 {
 		s.Definitions = make(map[string]*genschema.JSONSchema)
 		for n, d := range genschema.Definitions {
@@ -476,10 +479,11 @@ func mustGenerate(meta dslengine.MetadataDefinition) bool {
 }
 
 // hasAbsoluteRoutes returns true if any action exposed by the API uses an absolute route of if the
-// API has file servers. This is needed as Swagger does not support exceptions to the base path so
-// if the API has any absolute route the base path must be "/" and all routes must be absolutes.
+// API has file servers or health check endpoints. This is needed as Swagger does not support
+// exceptions to the base path so if the API has any absolute route the base path must be "/" and
+// all routes must be absolutes.
 func hasAbsoluteRoutes(api *design.APIDefinition) bool {
-	hasAbsoluteRoutes := false
+	hasAbsoluteRoutes := api.HealthCheck
 	for _, res := range api.Resources {
 		for _, fs := range res.FileServers {
 			if !mustGenerate(fs.Metadata) {
@@ -516,6 +520,10 @@ func securityDefsFromDefinition(schemes []*design.SecuritySchemeDefinition) map[
 
 	defs := make(map[string]*SecurityDefinition)
 	for _, scheme := range schemes {
+		if scheme.In == "cookie" {
+			// Swagger 2.0 cannot describe credentials read from cookies.
+			continue
+		}
 		def := &SecurityDefinition{
 			Type:             scheme.Type,
 			Description:      scheme.Description,
@@ -527,6 +535,9 @@ func securityDefsFromDefinition(schemes []*design.SecuritySchemeDefinition) map[
 			Scopes:           scheme.Scopes,
 			Extensions:       extensionsFromDefinition(scheme.Metadata),
 		}
+		if scheme.Kind == design.HTTPSignatureSecurityKind {
+			def.Description = httpSignatureDescription(scheme)
+		}
 		if scheme.Kind == design.JWTSecurityKind {
 			if def.TokenURL != "" {
 				def.Description += fmt.Sprintf("\n\n**Token URL**: %s", def.TokenURL)
@@ -542,6 +553,16 @@ func securityDefsFromDefinition(schemes []*design.SecuritySchemeDefinition) map[
 	return defs
 }
 
+// httpSignatureDescription returns the description of an HTTP signature scheme: the description
+// given in the design or a generic one if there is none, followed by the list of signed headers.
+func httpSignatureDescription(scheme *design.SecuritySchemeDefinition) string {
+	desc := scheme.Description
+	if desc == "" {
+		desc = "Requests are signed as described in draft-cavage-http-signatures."
+	}
+	return fmt.Sprintf("%s\n\n**Signed headers**: `%s`", desc, strings.Join(scheme.SignedHeaders, " "))
+}
+
 func scopesMapList(scopes map[string]string) string {
 	names := []string{}
 	for name := range scopes {
@@ -905,6 +926,45 @@ func buildPathFromFileServer(s *Swagger, api *design.APIDefinition, fs *design.F
 	return nil
 }
 
+// buildHealthPaths adds the liveness and readiness endpoints to the specification.
+func buildHealthPaths(s *Swagger, api *design.APIDefinition) {
+	schema := genschema.TypeSchema(api, design.HealthReportMedia)
+	endpoints := []struct{ name, path, summary string }{
+		{"healthz", design.HealthLivenessPath, "Liveness check"},
+		{"readyz", design.HealthReadinessPath, "Readiness check"},
+	}
+	for _, e := range endpoints {
+		s.Paths[e.path] = &Path{
+			Get: &Operation{
+				Tags:        []string{"health"},
+				Summary:     e.summary,
+				OperationID: "health#" + e.name,
+				Produces:    []string{design.HealthReportMediaIdentifier},
+				Responses: map[string]*Response{
+					"200": {Description: "All checks succeeded", Schema: schema},
+					"503": {Description: "At least one check failed", Schema: schema},
+				},
+				Schemes: api.Schemes,
+			},
+		}
+	}
+}
+
+// rateLimitResponse returns the response sent by rate limited actions to clients that exceed the
+// limit.
+func rateLimitResponse(api *design.APIDefinition) *Response {
+	return &Response{
+		Description: "Too many requests",
+		Schema:      genschema.TypeSchema(api, design.ErrorMedia),
+		Headers: map[string]*Header{
+			"Retry-After":           {Type: "integer", Description: "Number of seconds to wait before retrying"},
+			"X-RateLimit-Limit":     {Type: "integer", Description: "Maximum number of requests allowed at once"},
+			"X-RateLimit-Remaining": {Type: "integer", Description: "Number of requests remaining"},
+			"X-RateLimit-Reset":     {Type: "integer", Description: "Number of seconds until the allowance is fully restored"},
+		},
+	}
+}
+
 func buildPathFromDefinition(s *Swagger, api *design.APIDefinition, route *design.RouteDefinition, basePath string) error {
 	action := route.Parent
 
@@ -928,6 +988,13 @@ func buildPathFromDefinition(s *Swagger, api *design.APIDefinition, route *desig
 		}
 		responses[strconv.Itoa(r.Status)] = resp
 	}
+	rl, err := action.RateLimit()
+	if err != nil {
+		return err
+	}
+	if _, ok := responses["429"]; rl != nil && !ok {
+		responses["429"] = rateLimitResponse(api)
+	}
 
 	consumesMultipart := false
 	if action.Payload != nil {
@@ -981,6 +1048,13 @@ func buildPathFromDefinition(s *Swagger, api *design.APIDefinition, route *desig
 		Extensions:   extensionsFromDefinition(route.Metadata),
 	}
 
+	if msgs := messagesFromDefinition(api, action); msgs != nil {
+		if operation.Extensions == nil {
+			operation.Extensions = make(map[string]interface{})
+		}
+		operation.Extensions["x-websocket-messages"] = msgs
+	}
+
 	if consumesMultipart {
 		operation.Consumes = append(operation.Consumes, "multipart/form-data")
 	}
@@ -992,6 +1066,22 @@ func buildPathFromDefinition(s *Swagger, api *design.APIDefinition, route *desig
 	return nil
 }
 
+// messagesFromDefinition returns the schemas of the websocket messages of the given action indexed
+// by direction ("inbound" or "outbound"), nil if the action does not define messages.
+func messagesFromDefinition(api *design.APIDefinition, action *design.ActionDefinition) map[string]*genschema.JSONSchema {
+	if action.InboundMessage == nil && action.OutboundMessage == nil {
+		return nil
+	}
+	msgs := make(map[string]*genschema.JSONSchema)
+	if action.InboundMessage != nil {
+		msgs["inbound"] = genschema.TypeSchema(api, action.InboundMessage)
+	}
+	if action.OutboundMessage != nil {
+		msgs["outbound"] = genschema.TypeSchema(api, action.OutboundMessage)
+	}
+	return msgs
+}
+
 func computeProduces(operation *Operation, s *Swagger, action *design.ActionDefinition) {
 	produces := make(map[string]struct{})
 	action.IterateResponses(func(resp *design.ResponseDefinition) error {
@@ -1071,7 +1161,7 @@ func computePaths(operation *Operation, s *Swagger, route *design.RouteDefinitio
 }
 
 func applySecurity(operation *Operation, security *design.SecurityDefinition) {
-	if security != nil && security.Scheme.Kind != design.NoSecurityKind {
+	if security != nil && security.Scheme.Kind != design.NoSecurityKind && security.Scheme.In != "cookie" {
 		if security.Scheme.Kind == design.JWTSecurityKind && len(security.Scopes) > 0 {
 			if operation.Description != "" {
 				operation.Description += "\n\n"
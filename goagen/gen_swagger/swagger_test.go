@@ -647,6 +647,101 @@ var _ = Describe("New", func() {
 			It("serializes into valid swagger JSON", func() { validateSwagger(swagger) })
 		})
 
+		Context("with health checks", func() {
+			BeforeEach(func() {
+				base := Design.DSLFunc
+				Design.DSLFunc = func() {
+					base()
+					HealthCheck()
+				}
+			})
+
+			It("documents the liveness and readiness endpoints", func() {
+				Ω(swagger.BasePath).Should(BeEmpty())
+				for _, path := range []string{"/healthz", "/readyz"} {
+					Ω(swagger.Paths).Should(HaveKey(path))
+					get := swagger.Paths[path].(*genswagger.Path).Get
+					Ω(get).ShouldNot(BeNil())
+					Ω(get.Produces).Should(Equal([]string{HealthReportMediaIdentifier}))
+					Ω(get.Responses).Should(HaveKey("200"))
+					Ω(get.Responses).Should(HaveKey("503"))
+				}
+				Ω(swagger.Definitions).Should(HaveKey("health-report"))
+			})
+
+			It("serializes into valid swagger JSON", func() { validateSwagger(swagger) })
+		})
+
+		Context("with a rate limited action", func() {
+			BeforeEach(func() {
+				Resource("res", func() {
+					Action("list", func() {
+						Routing(GET("/items"))
+						Metadata("ratelimit", "100/1m")
+						Response(OK)
+					})
+				})
+			})
+
+			It("documents the 429 response", func() {
+				get := swagger.Paths["/items"].(*genswagger.Path).Get
+				Ω(get.Responses).Should(HaveKey("429"))
+				Ω(get.Responses["429"].Headers).Should(HaveKey("Retry-After"))
+				Ω(get.Responses["429"].Headers).Should(HaveKey("X-RateLimit-Limit"))
+				Ω(get.Responses["429"].Headers).Should(HaveKey("X-RateLimit-Remaining"))
+				Ω(get.Responses["429"].Headers).Should(HaveKey("X-RateLimit-Reset"))
+			})
+
+			It("serializes into valid swagger JSON", func() { validateSwagger(swagger) })
+		})
+
+		Context("with an HTTP signature security scheme", func() {
+			BeforeEach(func() {
+				base := Design.DSLFunc
+				Design.DSLFunc = func() {
+					base()
+					HTTPSignatureSecurity("signed", func() {
+						Description("Requests signed with Ed25519")
+					})
+				}
+			})
+
+			It("describes the scheme and the signed headers", func() {
+				Ω(newErr).ShouldNot(HaveOccurred())
+				signed := swagger.SecurityDefinitions["signed"]
+				Ω(signed.Description).Should(Equal("Requests signed with Ed25519\n\n" +
+					"**Signed headers**: `(request-target) host date digest`"))
+			})
+		})
+
+		Context("with a security scheme read from a cookie", func() {
+			BeforeEach(func() {
+				Resource("res", func() {
+					Action("show", func() {
+						Routing(GET("/items"))
+						Security("jwt")
+						Response(OK)
+					})
+				})
+				base := Design.DSLFunc
+				Design.DSLFunc = func() {
+					base()
+					JWTSecurity("jwt", func() {
+						Cookie("token")
+					})
+				}
+			})
+
+			It("omits the scheme", func() {
+				Ω(newErr).ShouldNot(HaveOccurred())
+				Ω(swagger.SecurityDefinitions).ShouldNot(HaveKey("jwt"))
+				get := swagger.Paths["/items"].(*genswagger.Path).Get
+				Ω(get.Security).Should(BeEmpty())
+			})
+
+			It("serializes into valid swagger JSON", func() { validateSwagger(swagger) })
+		})
+
 		Context("with metadata", func() {
 			const gat = "gat"
 			const extension = `{"foo":"bar"}`
@@ -0,0 +1,12 @@
+/*
+Package gents provides a goa generator for a TypeScript client module.
+The generator produces two files under the "ts" directory:
+
+"types.ts" declares an interface for each user type and media type view, and an enum for each
+attribute with an Enum validation.
+
+"client.ts" exports a Client class with one method per action. The methods take the path
+parameters, the payload and the query string parameters typed from the design. The client relies
+on the axios (https://github.com/axios/axios) library to perform the actual HTTP requests.
+*/
+package gents
@@ -0,0 +1,411 @@
+package gents
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/goadesign/goa/design"
+	"github.com/goadesign/goa/goagen/codegen"
+	"github.com/goadesign/goa/goagen/utils"
+	"github.com/goadesign/goa/version"
+)
+
+//NewGenerator returns an initialized instance of a TypeScript Client Generator
+func NewGenerator(options ...Option) *Generator {
+	g := &Generator{}
+
+	for _, option := range options {
+		option(g)
+	}
+
+	return g
+}
+
+// Generator is the TypeScript client generator.
+type Generator struct {
+	API      *design.APIDefinition // The API definition
+	OutDir   string                // Destination directory
+	Timeout  time.Duration         // Timeout used by TypeScript client when making requests
+	Scheme   string                // Scheme used by TypeScript client
+	Host     string                // Host addressed by TypeScript client
+	genfiles []string              // Generated files
+}
+
+type (
+	// method describes a client method.
+	method struct {
+		Name        string
+		Description string
+		Verb        string
+		Path        string
+		URL         string
+		Params      []*param
+		Query       string
+		Payload     string
+		Response    string
+	}
+
+	// param describes a client method parameter.
+	param struct {
+		Name     string
+		Type     string
+		Optional bool
+	}
+)
+
+// Generate is the generator entry point called by the meta generator.
+func Generate() (files []string, err error) {
+	var (
+		outDir, ver  string
+		timeout      time.Duration
+		scheme, host string
+	)
+
+	set := flag.NewFlagSet("ts", flag.PanicOnError)
+	set.StringVar(&outDir, "out", "", "")
+	set.String("design", "", "")
+	set.DurationVar(&timeout, "timeout", time.Duration(20)*time.Second, "")
+	set.StringVar(&scheme, "scheme", "", "")
+	set.StringVar(&host, "host", "", "")
+	set.StringVar(&ver, "version", "", "")
+	set.Parse(os.Args[1:])
+
+	// First check compatibility
+	if err := codegen.CheckVersion(ver); err != nil {
+		return nil, err
+	}
+
+	// Now proceed
+	g := &Generator{OutDir: outDir, Timeout: timeout, Scheme: scheme, Host: host, API: design.Design}
+
+	return g.Generate()
+}
+
+// Generate produces the TypeScript type definitions and client.
+func (g *Generator) Generate() (_ []string, err error) {
+	if g.API == nil {
+		return nil, fmt.Errorf("missing API definition, make sure design is properly initialized")
+	}
+
+	go utils.Catch(nil, func() { g.Cleanup() })
+
+	defer func() {
+		if err != nil {
+			g.Cleanup()
+		}
+	}()
+
+	if g.Timeout == 0 {
+		g.Timeout = 20 * time.Second
+	}
+	if g.Scheme == "" && len(g.API.Schemes) > 0 {
+		g.Scheme = g.API.Schemes[0]
+	}
+	if g.Scheme == "" {
+		g.Scheme = "http"
+	}
+	if g.Host == "" {
+		g.Host = g.API.Host
+	}
+
+	outDir := filepath.Join(g.OutDir, "ts")
+	if err = os.RemoveAll(outDir); err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(outDir, 0755); err != nil {
+		return nil, err
+	}
+	g.genfiles = append(g.genfiles, outDir)
+
+	b := newTypeBuilder()
+	methods, err := g.buildMethods(b)
+	if err != nil {
+		return nil, err
+	}
+	var refs []string
+	hasQuery := false
+	for _, m := range methods {
+		hasQuery = hasQuery || m.Query != ""
+		for _, p := range m.Params {
+			refs = append(refs, p.Type)
+		}
+		refs = append(refs, m.Response)
+	}
+	imports := b.Referenced(refs...)
+
+	// Declare all user types and media type views, including the ones not used by actions.
+	g.API.IterateUserTypes(func(ut *design.UserTypeDefinition) error {
+		b.named(ut)
+		return nil
+	})
+	g.API.IterateMediaTypes(func(mt *design.MediaTypeDefinition) error {
+		if mt.IsError() {
+			return nil
+		}
+		return mt.IterateViews(func(v *design.ViewDefinition) error {
+			if p, err := project(mt, v.Name); err == nil {
+				b.named(p.UserTypeDefinition)
+			}
+			return nil
+		})
+	})
+
+	// Generate types.ts
+	typesFile := filepath.Join(outDir, "types.ts")
+	if err = g.generate(typesFile, typesT, map[string]interface{}{
+		"Declarations": b.Declarations(),
+	}); err != nil {
+		return nil, err
+	}
+
+	// Generate client.ts
+	urlPrefix := ""
+	if g.Host != "" {
+		urlPrefix = g.Scheme + "://" + g.Host
+	}
+	clientFile := filepath.Join(outDir, "client.ts")
+	if err = g.generate(clientFile, clientT, map[string]interface{}{
+		"Imports":   imports,
+		"Methods":   methods,
+		"HasQuery":  hasQuery,
+		"URLPrefix": urlPrefix,
+		"Timeout":   int64(g.Timeout / time.Millisecond),
+	}); err != nil {
+		return nil, err
+	}
+
+	return g.genfiles, nil
+}
+
+// Cleanup removes all the files generated by this generator during the last invokation of Generate.
+func (g *Generator) Cleanup() {
+	for _, f := range g.genfiles {
+		os.RemoveAll(f)
+	}
+	g.genfiles = nil
+}
+
+func (g *Generator) generate(filename, tmpl string, data map[string]interface{}) error {
+	w, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	g.genfiles = append(g.genfiles, filename)
+	data["API"] = g.API
+	data["ToolVersion"] = version.String()
+	t := template.Must(template.New("ts").Funcs(template.FuncMap{
+		"commandLine": codegen.CommandLine,
+		"comment":     codegen.Comment,
+		"doc":         doc,
+		"signature":   signature,
+	}).Parse(tmpl))
+	return t.Execute(w, data)
+}
+
+// buildMethods computes the client methods, one per action. Actions that cannot be called with a
+// single HTTP request (websocket and server-sent events actions) are skipped.
+func (g *Generator) buildMethods(b *typeBuilder) ([]*method, error) {
+	var methods []*method
+	err := g.API.IterateResources(func(res *design.ResourceDefinition) error {
+		return res.IterateActions(func(a *design.ActionDefinition) error {
+			if a.WebSocket() || a.Stream != nil || len(a.Routes) == 0 {
+				return nil
+			}
+			methods = append(methods, g.buildMethod(b, a))
+			return nil
+		})
+	})
+	return methods, err
+}
+
+func (g *Generator) buildMethod(b *typeBuilder, a *design.ActionDefinition) *method {
+	prefix := codegen.Goify(a.Name, true) + codegen.Goify(a.Parent.Name, true)
+	route := a.Routes[0]
+	m := &method{
+		Name:        codegen.Goify(a.Name, false) + codegen.Goify(a.Parent.Name, true),
+		Description: a.Description,
+		Verb:        route.Verb,
+		Path:        route.FullPath(),
+	}
+	if m.Description == "" {
+		m.Description = fmt.Sprintf("%s calls the %s action of the %s resource.", m.Name, a.Name, a.Parent.Name)
+	}
+
+	// Path params
+	all := a.AllParams().Type.ToObject()
+	names := make(map[string]string)
+	for _, n := range route.Params() {
+		att, ok := all[n]
+		if !ok {
+			att = &design.AttributeDefinition{Type: design.String}
+		}
+		pn := paramName(n)
+		names[n] = pn
+		m.Params = append(m.Params, &param{Name: pn, Type: b.Ref(att, prefix+codegen.Goify(n, true))})
+	}
+	url := strings.Replace(route.FullPath(), "`", "\\`", -1)
+	url = design.WildcardRegex.ReplaceAllStringFunc(url, func(w string) string {
+		n := w[2:]
+		if w[1] == '*' {
+			return fmt.Sprintf("/${encodeURI(String(%s))}", names[n])
+		}
+		return fmt.Sprintf("/${encodeURIComponent(String(%s))}", names[n])
+	})
+	m.URL = "`" + url + "`"
+
+	// Payload
+	if a.Payload != nil {
+		typ := "FormData"
+		if !a.PayloadMultipart {
+			typ = b.Ref(&design.AttributeDefinition{Type: a.Payload}, prefix+"Payload")
+		}
+		m.Payload = "payload"
+		m.Params = append(m.Params, &param{Name: "payload", Type: typ, Optional: a.PayloadOptional})
+	}
+
+	// Query string
+	if a.QueryParams != nil {
+		if obj := a.QueryParams.Type.ToObject(); len(obj) > 0 {
+			name := prefix + "Query"
+			b.decls[name] = fmt.Sprintf("/** %s lists the query string parameters of %s. */\nexport interface %s %s\n",
+				name, m.Name, name, b.object(obj, a.QueryParams, name, 0))
+			m.Query = "query"
+			m.Params = append(m.Params, &param{Name: "query", Type: name, Optional: len(a.QueryParams.AllRequired()) == 0})
+		}
+	}
+
+	m.Response = responseType(b, a, prefix)
+	return m
+}
+
+// responseType returns the TypeScript type of the body of the first successful response of the
+// action.
+func responseType(b *typeBuilder, a *design.ActionDefinition, prefix string) string {
+	var success *design.ResponseDefinition
+	a.IterateResponses(func(r *design.ResponseDefinition) error {
+		if r.Status >= 200 && r.Status < 300 && (success == nil || r.Status < success.Status) {
+			success = r
+		}
+		return nil
+	})
+	if success == nil {
+		return "any"
+	}
+	if success.Type != nil {
+		return b.Ref(&design.AttributeDefinition{Type: success.Type, View: success.ViewName}, prefix+"Response")
+	}
+	if mt := design.Design.MediaTypeWithIdentifier(success.MediaType); mt != nil {
+		return b.Ref(&design.AttributeDefinition{Type: mt, View: success.ViewName}, prefix+"Response")
+	}
+	if success.MediaType != "" {
+		return "any"
+	}
+	return "void"
+}
+
+// signature returns the parameter list of the given method. Optional parameters followed by
+// required parameters accept undefined instead.
+func signature(m *method) string {
+	params := make([]string, 0, len(m.Params)+1)
+	for i, p := range m.Params {
+		switch {
+		case !p.Optional:
+			params = append(params, fmt.Sprintf("%s: %s", p.Name, p.Type))
+		case hasRequired(m.Params[i+1:]):
+			params = append(params, fmt.Sprintf("%s: %s | undefined", p.Name, p.Type))
+		default:
+			params = append(params, fmt.Sprintf("%s?: %s", p.Name, p.Type))
+		}
+	}
+	params = append(params, "config?: AxiosRequestConfig")
+	return strings.Join(params, ", ")
+}
+
+func hasRequired(params []*param) bool {
+	for _, p := range params {
+		if !p.Optional {
+			return true
+		}
+	}
+	return false
+}
+
+const typesT = `// Code generated by goagen {{.ToolVersion}}, DO NOT EDIT.
+//
+// API {{printf "%q" .API.Name}}: TypeScript types
+//
+// Command:
+{{comment commandLine}}
+{{range .Declarations}}
+{{.}}{{end}}`
+
+const clientT = `// Code generated by goagen {{.ToolVersion}}, DO NOT EDIT.
+//
+// API {{printf "%q" .API.Name}}: TypeScript client
+//
+// Command:
+{{comment commandLine}}
+
+import axios, { AxiosInstance, AxiosPromise, AxiosRequestConfig } from 'axios';
+{{if .Imports}}import {
+{{range .Imports}}  {{.}},
+{{end}}} from './types';
+{{end}}
+export * from './types';
+{{if .HasQuery}}
+/**
+ * serializeQuery encodes the query string parameters. The values of array parameters are encoded
+ * by repeating the parameter name (e.g. tags=a&tags=b) as expected by goa services.
+ */
+function serializeQuery(params: { [key: string]: any }): string {
+  const parts: string[] = [];
+  Object.keys(params).forEach((key) => {
+    const value = params[key];
+    if (value === undefined || value === null) {
+      return;
+    }
+    (Array.isArray(value) ? value : [value]).forEach((v) => {
+      parts.push(encodeURIComponent(key) + '=' + encodeURIComponent(String(v)));
+    });
+  });
+  return parts.join('&');
+}
+{{end}}
+/**
+ * Client gives access to the {{.API.Name}} API. It uses the axios library for making the actual
+ * HTTP requests.
+ */
+export class Client {
+  /** urlPrefix is prepended to the path of all requests. */
+  urlPrefix: string;
+  /** timeout is the request timeout in milliseconds. */
+  timeout: number;
+  /** instance is the axios instance used to make the requests. */
+  instance: AxiosInstance;
+
+  constructor(urlPrefix: string = {{printf "%q" .URLPrefix}}, timeout: number = {{.Timeout}}, instance: AxiosInstance = axios) {
+    this.urlPrefix = urlPrefix;
+    this.timeout = timeout;
+    this.instance = instance;
+  }
+{{range .Methods}}
+{{doc "  " (printf "%s\n\n%s %s" .Description .Verb .Path)}}  {{.Name}}({{signature .}}): AxiosPromise<{{.Response}}> {
+    return this.instance.request<{{.Response}}>({
+      timeout: this.timeout,
+      url: this.urlPrefix + {{.URL}},
+      method: '{{.Verb}}',
+{{if .Query}}      params: {{.Query}},
+      paramsSerializer: serializeQuery,
+{{end}}{{if .Payload}}      data: {{.Payload}},
+{{end}}      responseType: 'json',
+      ...config,
+    });
+  }
+{{end}}}
+`
@@ -0,0 +1,153 @@
+package gents_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/goadesign/goa/design"
+	. "github.com/goadesign/goa/design/apidsl"
+	"github.com/goadesign/goa/dslengine"
+	"github.com/goadesign/goa/goagen/gen_ts"
+	"github.com/goadesign/goa/version"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Generate", func() {
+	var outDir string
+	var files []string
+	var genErr error
+	var types, client string
+
+	BeforeEach(func() {
+		var err error
+		outDir, err = ioutil.TempDir("", "gents")
+		Ω(err).ShouldNot(HaveOccurred())
+		os.Args = []string{"goagen", "--out=" + outDir, "--design=foo", "--version=" + version.String()}
+		dslengine.Reset()
+		ProjectedMediaTypes = make(MediaTypeRoot)
+	})
+
+	JustBeforeEach(func() {
+		err := dslengine.Run()
+		Ω(err).ShouldNot(HaveOccurred())
+		files, genErr = gents.Generate()
+		if genErr == nil {
+			content, err := ioutil.ReadFile(filepath.Join(outDir, "ts", "types.ts"))
+			Ω(err).ShouldNot(HaveOccurred())
+			types = string(content)
+			content, err = ioutil.ReadFile(filepath.Join(outDir, "ts", "client.ts"))
+			Ω(err).ShouldNot(HaveOccurred())
+			client = string(content)
+		}
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(outDir)
+	})
+
+	Context("with a design", func() {
+		BeforeEach(func() {
+			API("cellar", func() {
+				Host("localhost:8080")
+			})
+			bottle := MediaType("application/vnd.bottle", func() {
+				Description("A bottle of wine")
+				Attributes(func() {
+					Attribute("id", Integer)
+					Attribute("name", String, "Name of the wine")
+					Attribute("color", String, func() {
+						Enum("red", "white", "sparkling-wine")
+					})
+					Required("id", "name")
+				})
+				View("default", func() {
+					Attribute("id")
+					Attribute("name")
+					Attribute("color")
+				})
+				View("tiny", func() {
+					Attribute("id")
+				})
+			})
+			payload := Type("BottlePayload", func() {
+				Attribute("name", String)
+				Attribute("tags", ArrayOf(String))
+				Required("name")
+			})
+			Resource("bottle", func() {
+				BasePath("/bottles")
+				Action("show", func() {
+					Description("Retrieve a bottle")
+					Routing(GET("/:id"))
+					Params(func() {
+						Param("id", Integer)
+					})
+					Response(OK, bottle)
+				})
+				Action("list", func() {
+					Routing(GET(""))
+					Params(func() {
+						Param("limit", Integer)
+						Param("tags", ArrayOf(String))
+					})
+					Response(OK, func() {
+						Media(CollectionOf(bottle), "tiny")
+					})
+				})
+				Action("create", func() {
+					Routing(POST(""))
+					Payload(payload)
+					Response(Created)
+				})
+			})
+		})
+
+		It("generates the types and client files", func() {
+			Ω(genErr).ShouldNot(HaveOccurred())
+			Ω(files).Should(HaveLen(3))
+		})
+
+		It("declares interfaces for user types and media type views", func() {
+			Ω(types).Should(ContainSubstring("/** A bottle of wine (default view) */\nexport interface Bottle {\n  color?: BottleColor;\n  id: number;\n  /** Name of the wine */\n  name: string;\n}\n"))
+			Ω(types).Should(ContainSubstring("export interface BottleTiny {\n  id: number;\n}\n"))
+			Ω(types).Should(ContainSubstring("export type BottleTinyCollection = BottleTiny[];\n"))
+			Ω(types).Should(ContainSubstring("export interface BottlePayload {\n  name: string;\n  tags?: string[];\n}\n"))
+		})
+
+		It("declares enums", func() {
+			Ω(types).Should(ContainSubstring("export enum BottleColor {\n  Red = \"red\",\n  White = \"white\",\n  SparklingWine = \"sparkling-wine\",\n}\n"))
+		})
+
+		It("declares query string interfaces", func() {
+			Ω(types).Should(ContainSubstring("export interface ListBottleQuery {\n  limit?: number;\n  tags?: string[];\n}\n"))
+		})
+
+		It("generates typed client methods", func() {
+			Ω(client).Should(ContainSubstring(`constructor(urlPrefix: string = "http://localhost:8080"`))
+			Ω(client).Should(ContainSubstring("showBottle(id: number, config?: AxiosRequestConfig): AxiosPromise<Bottle> {"))
+			Ω(client).Should(ContainSubstring("url: this.urlPrefix + `/bottles/${encodeURIComponent(String(id))}`,"))
+			Ω(client).Should(ContainSubstring("listBottle(query?: ListBottleQuery, config?: AxiosRequestConfig): AxiosPromise<BottleTinyCollection> {"))
+			Ω(client).Should(ContainSubstring("params: query,\n      paramsSerializer: serializeQuery,\n"))
+			Ω(client).Should(ContainSubstring("createBottle(payload: BottlePayload, config?: AxiosRequestConfig): AxiosPromise<void> {"))
+			Ω(client).Should(ContainSubstring("data: payload,"))
+			Ω(client).Should(ContainSubstring("import {\n  Bottle,\n  BottlePayload,\n  BottleTinyCollection,\n  ListBottleQuery,\n} from './types';"))
+		})
+
+		It("repeats the names of array query string parameters", func() {
+			Ω(client).Should(ContainSubstring("function serializeQuery(params: { [key: string]: any }): string {"))
+			Ω(client).Should(ContainSubstring("(Array.isArray(value) ? value : [value]).forEach((v) => {\n      parts.push(encodeURIComponent(key) + '=' + encodeURIComponent(String(v)));"))
+		})
+	})
+})
+
+var _ = Describe("NewGenerator", func() {
+	It("sets the options", func() {
+		api := &APIDefinition{Name: "test api"}
+		g := gents.NewGenerator(gents.API(api), gents.OutDir("out"), gents.Host("localhost"))
+		Ω(g.API).Should(Equal(api))
+		Ω(g.OutDir).Should(Equal("out"))
+		Ω(g.Host).Should(Equal("localhost"))
+	})
+})
@@ -0,0 +1,42 @@
+package gents
+
+import "github.com/goadesign/goa/design"
+import "time"
+
+//Option a generator option definition
+type Option func(*Generator)
+
+//API The API definition
+func API(API *design.APIDefinition) Option {
+	return func(g *Generator) {
+		g.API = API
+	}
+}
+
+//OutDir Path to output directory
+func OutDir(outDir string) Option {
+	return func(g *Generator) {
+		g.OutDir = outDir
+	}
+}
+
+//Timeout Timeout used by TypeScript client when making requests
+func Timeout(timeout time.Duration) Option {
+	return func(g *Generator) {
+		g.Timeout = timeout
+	}
+}
+
+//Scheme Scheme used by TypeScript client
+func Scheme(scheme string) Option {
+	return func(g *Generator) {
+		g.Scheme = scheme
+	}
+}
+
+//Host addressed by TypeScript client
+func Host(host string) Option {
+	return func(g *Generator) {
+		g.Host = host
+	}
+}
@@ -0,0 +1,272 @@
+package gents
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/goadesign/goa/design"
+	"github.com/goadesign/goa/goagen/codegen"
+)
+
+// typeBuilder computes the TypeScript declarations of the API types.
+type typeBuilder struct {
+	// decls maps type names to their declarations.
+	decls map[string]string
+}
+
+// validIdent matches valid TypeScript identifiers.
+var validIdent = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// identRegex matches the identifiers in type expressions.
+var identRegex = regexp.MustCompile(`[A-Za-z_$][A-Za-z0-9_$]*`)
+
+// reserved lists the TypeScript reserved words that cannot be used as parameter names.
+var reserved = map[string]bool{
+	"break": true, "case": true, "catch": true, "class": true, "const": true, "continue": true,
+	"debugger": true, "default": true, "delete": true, "do": true, "else": true, "enum": true,
+	"export": true, "extends": true, "false": true, "finally": true, "for": true, "function": true,
+	"if": true, "import": true, "in": true, "instanceof": true, "new": true, "null": true,
+	"return": true, "super": true, "switch": true, "this": true, "throw": true, "true": true,
+	"try": true, "typeof": true, "var": true, "void": true, "while": true, "with": true,
+	"config": true, "payload": true, "query": true,
+}
+
+func newTypeBuilder() *typeBuilder {
+	return &typeBuilder{
+		decls: make(map[string]string),
+	}
+}
+
+// Declarations returns the type declarations sorted by type name.
+func (b *typeBuilder) Declarations() []string {
+	names := make([]string, 0, len(b.decls))
+	for n := range b.decls {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	res := make([]string, len(names))
+	for i, n := range names {
+		res[i] = b.decls[n]
+	}
+	return res
+}
+
+// Referenced returns the sorted names of the declared types that appear in the given type
+// expressions.
+func (b *typeBuilder) Referenced(types ...string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, t := range types {
+		for _, id := range identRegex.FindAllString(t, -1) {
+			if _, ok := b.decls[id]; ok && !seen[id] {
+				seen[id] = true
+				names = append(names, id)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// project returns the projection of the media type using the given view, or the media type itself
+// if it is already a projection.
+func project(mt *design.MediaTypeDefinition, view string) (*design.MediaTypeDefinition, error) {
+	for _, p := range design.ProjectedMediaTypes {
+		if p == mt {
+			return mt, nil
+		}
+	}
+	if view == "" {
+		view = design.DefaultView
+	}
+	p, _, err := mt.Project(view)
+	return p, err
+}
+
+// Ref returns the TypeScript type of the attribute. hint is used to name the enums declared for
+// attributes with enum validations.
+func (b *typeBuilder) Ref(att *design.AttributeDefinition, hint string) string {
+	return b.ref(att, hint, 0)
+}
+
+func (b *typeBuilder) ref(att *design.AttributeDefinition, hint string, depth int) string {
+	switch actual := att.Type.(type) {
+	case design.Primitive:
+		if att.Validation != nil && len(att.Validation.Values) > 0 {
+			return b.enum(actual, att.Validation.Values, hint)
+		}
+		return primitive(actual)
+	case *design.Array:
+		elem := b.ref(actual.ElemType, hint, depth)
+		if strings.ContainsAny(elem, " |{") {
+			return fmt.Sprintf("Array<%s>", elem)
+		}
+		return elem + "[]"
+	case *design.Hash:
+		return fmt.Sprintf("{ [key: string]: %s }", b.ref(actual.ElemType, hint+"Value", depth))
+	case design.Object:
+		return b.object(actual, att, hint, depth)
+	case *design.MediaTypeDefinition:
+		p, err := project(actual, att.View)
+		if err != nil {
+			return "any"
+		}
+		return b.named(p.UserTypeDefinition)
+	case *design.UserTypeDefinition:
+		return b.named(actual)
+	}
+	return "any"
+}
+
+// named declares the given user type if needed and returns its name.
+func (b *typeBuilder) named(ut *design.UserTypeDefinition) string {
+	name := codegen.Goify(ut.TypeName, true)
+	if _, ok := b.decls[name]; ok {
+		return name
+	}
+	// Register first so recursive types terminate.
+	b.decls[name] = ""
+	var decl string
+	if obj := ut.Type.ToObject(); obj != nil {
+		decl = fmt.Sprintf("%sexport interface %s %s\n", doc("", ut.Description), name, b.object(obj, ut.AttributeDefinition, name, 0))
+	} else {
+		decl = fmt.Sprintf("%sexport type %s = %s;\n", doc("", ut.Description), name, b.ref(ut.AttributeDefinition, name, 0))
+	}
+	b.decls[name] = decl
+	return name
+}
+
+// object returns the TypeScript type literal describing the given object.
+func (b *typeBuilder) object(obj design.Object, parent *design.AttributeDefinition, hint string, depth int) string {
+	if len(obj) == 0 {
+		return "{}"
+	}
+	names := make([]string, 0, len(obj))
+	for n := range obj {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	indent := strings.Repeat("  ", depth+1)
+	var buf strings.Builder
+	buf.WriteString("{\n")
+	for _, n := range names {
+		att := obj[n]
+		buf.WriteString(doc(indent, att.Description))
+		opt := "?"
+		if parent.IsRequired(n) {
+			opt = ""
+		}
+		fmt.Fprintf(&buf, "%s%s%s: %s;\n", indent, propName(n), opt, b.ref(att, hint+codegen.Goify(n, true), depth+1))
+	}
+	buf.WriteString(strings.Repeat("  ", depth) + "}")
+	return buf.String()
+}
+
+// enum declares the enum with the given values and returns its name.
+func (b *typeBuilder) enum(p design.Primitive, values []interface{}, hint string) string {
+	if p.Kind() == design.BooleanKind || p.Kind() == design.AnyKind {
+		lits := make([]string, len(values))
+		for i, v := range values {
+			lits[i] = literal(v)
+		}
+		return strings.Join(lits, " | ")
+	}
+	name := hint
+	for i := 1; ; i++ {
+		if _, ok := b.decls[name]; !ok {
+			break
+		}
+		name = fmt.Sprintf("%sEnum", hint)
+		if i > 1 {
+			name += fmt.Sprint(i)
+		}
+	}
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "export enum %s {\n", name)
+	seen := make(map[string]bool)
+	for _, v := range values {
+		member := codegen.Goify(fmt.Sprint(v), true)
+		if !validIdent.MatchString(member) {
+			member = "Value" + member
+		}
+		if !validIdent.MatchString(member) {
+			member = "Value"
+		}
+		m := member
+		for i := 2; seen[m]; i++ {
+			m = fmt.Sprintf("%s%d", member, i)
+		}
+		seen[m] = true
+		fmt.Fprintf(&buf, "  %s = %s,\n", m, literal(v))
+	}
+	buf.WriteString("}\n")
+	b.decls[name] = buf.String()
+	return name
+}
+
+// primitive returns the TypeScript type of the given primitive type.
+func primitive(p design.Primitive) string {
+	switch p.Kind() {
+	case design.BooleanKind:
+		return "boolean"
+	case design.IntegerKind, design.NumberKind:
+		return "number"
+	case design.StringKind, design.DateTimeKind, design.UUIDKind:
+		return "string"
+	case design.FileKind:
+		return "Blob"
+	}
+	return "any"
+}
+
+// literal returns the TypeScript literal for the given enum value.
+func literal(v interface{}) string {
+	switch actual := v.(type) {
+	case string:
+		return fmt.Sprintf("%q", actual)
+	case nil:
+		return "null"
+	}
+	return fmt.Sprint(v)
+}
+
+// propName returns the property name used in type literals, quoted if needed.
+func propName(n string) string {
+	if validIdent.MatchString(n) {
+		return n
+	}
+	return fmt.Sprintf("%q", n)
+}
+
+// paramName returns a valid TypeScript parameter name for the given param name.
+func paramName(n string) string {
+	name := codegen.Goify(n, false)
+	if !validIdent.MatchString(name) {
+		name = "_" + name
+	}
+	if reserved[name] {
+		name += "Param"
+	}
+	return name
+}
+
+// doc returns the TSDoc comment for the given text indented with the given prefix.
+func doc(indent, text string) string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return ""
+	}
+	lines := strings.Split(strings.Replace(text, "*/", "* /", -1), "\n")
+	if len(lines) == 1 {
+		return fmt.Sprintf("%s/** %s */\n", indent, lines[0])
+	}
+	var buf strings.Builder
+	buf.WriteString(indent + "/**\n")
+	for _, l := range lines {
+		buf.WriteString(strings.TrimRight(indent+" * "+l, " ") + "\n")
+	}
+	buf.WriteString(indent + " */\n")
+	return buf.String()
+}
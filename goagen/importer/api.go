@@ -0,0 +1,388 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/goadesign/goa/goagen/codegen"
+)
+
+const (
+	// tokenURLHeader precedes the token URL in the description of the JWT security schemes
+	// produced by goagen.
+	tokenURLHeader = "\n\n**Token URL**: "
+
+	// scopesHeader precedes the list of scopes in the description of the JWT security schemes
+	// produced by goagen.
+	scopesHeader = "\n\n**Security Scopes**:\n"
+)
+
+// scopeRegex matches the scope lines in the description of the JWT security schemes produced by
+// goagen.
+var scopeRegex = regexp.MustCompile("^  \\* `([^`]+)`: (.*)$")
+
+// api writes the API DSL.
+func (c *converter) api(w *bytes.Buffer) {
+	info := object(c.doc, "info")
+	title := str(info, "title")
+	name := codegen.SnakeCase(codegen.Goify(title, true))
+	if name == "" {
+		name = "api"
+	}
+	fmt.Fprintf(w, "\nvar _ = API(%q, func() {\n", name)
+	if title != "" {
+		fmt.Fprintf(w, "Title(%q)\n", title)
+	}
+	if d := str(info, "description"); d != "" {
+		fmt.Fprintf(w, "Description(%q)\n", d)
+	}
+	if v := str(info, "version"); v != "" {
+		fmt.Fprintf(w, "Version(%q)\n", v)
+	}
+	if t := str(info, "termsOfService"); t != "" {
+		fmt.Fprintf(w, "TermsOfService(%q)\n", t)
+	}
+	if contact := object(info, "contact"); contact != nil {
+		w.WriteString("Contact(func() {\n")
+		for _, f := range []struct{ key, fn string }{{"name", "Name"}, {"email", "Email"}, {"url", "URL"}} {
+			if v := str(contact, f.key); v != "" {
+				fmt.Fprintf(w, "%s(%q)\n", f.fn, v)
+			}
+		}
+		w.WriteString("})\n")
+	}
+	if license := object(info, "license"); license != nil {
+		w.WriteString("License(func() {\n")
+		for _, f := range []struct{ key, fn string }{{"name", "Name"}, {"url", "URL"}} {
+			if v := str(license, f.key); v != "" {
+				fmt.Fprintf(w, "%s(%q)\n", f.fn, v)
+			}
+		}
+		w.WriteString("})\n")
+	}
+	c.docs(w, object(c.doc, "externalDocs"))
+	host, basePath := c.server()
+	if host != "" {
+		fmt.Fprintf(w, "Host(%q)\n", host)
+	}
+	c.schemes = c.apiSchemes()
+	if len(c.schemes) > 0 {
+		fmt.Fprintf(w, "Scheme(%s)\n", quoteAll(c.schemes))
+	}
+	if basePath != "" && basePath != "/" {
+		fmt.Fprintf(w, "BasePath(%q)\n", c.routePath(basePath, "#/basePath"))
+	}
+	if consumes := c.consumes(); len(consumes) > 0 {
+		fmt.Fprintf(w, "Consumes(%s)\n", quoteAll(consumes))
+	}
+	if produces := strs(c.doc, "produces"); len(produces) > 0 {
+		fmt.Fprintf(w, "Produces(%s)\n", quoteAll(produces))
+	}
+	for _, x := range extensions(info) {
+		fmt.Fprintf(w, "Metadata(%q, %q)\n", "swagger:extension:"+x, jsonString(info[x]))
+	}
+	c.tags(w)
+	c.securitySchemes(w)
+	if sec, ok := c.doc["security"].([]interface{}); ok && len(sec) > 0 {
+		c.security(w, sec, "#/security")
+	}
+	for _, x := range extensions(c.doc) {
+		c.warn(pointer("#", x), "specification extensions are not supported")
+	}
+	w.WriteString("})\n")
+}
+
+// server returns the API host and base path.
+func (c *converter) server() (string, string) {
+	if !c.oas3 {
+		return str(c.doc, "host"), str(c.doc, "basePath")
+	}
+	servers := array(c.doc, "servers")
+	if len(servers) == 0 {
+		return "", ""
+	}
+	s, _ := servers[0].(map[string]interface{})
+	if _, ok := s["variables"]; ok {
+		c.warn("#/servers/0/variables", "server variables are not supported")
+	}
+	u, err := url.Parse(str(s, "url"))
+	if err != nil {
+		c.warn("#/servers/0/url", "invalid server URL: %s", err)
+		return "", ""
+	}
+	return u.Host, u.Path
+}
+
+// apiSchemes returns the API schemes.
+func (c *converter) apiSchemes() []string {
+	if !c.oas3 {
+		return strs(c.doc, "schemes")
+	}
+	var schemes []string
+	var host, path string
+	for i, s := range array(c.doc, "servers") {
+		sm, _ := s.(map[string]interface{})
+		u, err := url.Parse(str(sm, "url"))
+		if err != nil {
+			continue
+		}
+		if i == 0 {
+			host, path = u.Host, u.Path
+		} else if u.Host != host || u.Path != path {
+			c.warn(pointer("#/servers", fmt.Sprint(i)), "only servers that differ by their scheme are supported")
+			continue
+		}
+		if u.Scheme != "" {
+			schemes = appendUnique(schemes, u.Scheme)
+		}
+	}
+	return schemes
+}
+
+// consumes returns the MIME types of the request bodies the API accepts.
+func (c *converter) consumes() []string {
+	if !c.oas3 {
+		return strs(c.doc, "consumes")
+	}
+	var consumes []string
+	paths := object(c.doc, "paths")
+	for _, p := range keys(paths) {
+		for _, m := range methods {
+			rb, _ := c.resolve(object(object(object(paths, p), m), "requestBody"), "")
+			for _, ct := range keys(object(rb, "content")) {
+				if ct != "multipart/form-data" {
+					consumes = appendUnique(consumes, ct)
+				}
+			}
+		}
+	}
+	return consumes
+}
+
+// tags writes the metadata describing the specification tags.
+func (c *converter) tags(w *bytes.Buffer) {
+	for i, t := range array(c.doc, "tags") {
+		tm, _ := t.(map[string]interface{})
+		name := str(tm, "name")
+		if name == "" {
+			continue
+		}
+		key := "swagger:tag:" + name
+		fmt.Fprintf(w, "Metadata(%q)\n", key)
+		if d := str(tm, "description"); d != "" {
+			fmt.Fprintf(w, "Metadata(%q, %q)\n", key+":desc", d)
+		}
+		if docs := object(tm, "externalDocs"); docs != nil {
+			if u := str(docs, "url"); u != "" {
+				fmt.Fprintf(w, "Metadata(%q, %q)\n", key+":url", u)
+			}
+			if d := str(docs, "description"); d != "" {
+				fmt.Fprintf(w, "Metadata(%q, %q)\n", key+":url:desc", d)
+			}
+		}
+		for _, x := range extensions(tm) {
+			// goagen copies the API extensions to the tags it defines.
+			if reflect.DeepEqual(tm[x], object(c.doc, "info")[x]) {
+				continue
+			}
+			c.warn(pointer("#/tags", fmt.Sprint(i), x), "tag extensions are not supported")
+		}
+	}
+}
+
+// securitySchemes writes the DSL defining the API security schemes.
+func (c *converter) securitySchemes(w *bytes.Buffer) {
+	defs, ptr := object(c.doc, "securityDefinitions"), "#/securityDefinitions"
+	if c.oas3 {
+		defs, ptr = object(object(c.doc, "components"), "securitySchemes"), "#/components/securitySchemes"
+	}
+	for _, n := range keys(defs) {
+		def, sptr := c.resolve(object(defs, n), pointer(ptr, n))
+		if def == nil {
+			continue
+		}
+		var dsl bytes.Buffer
+		fn := ""
+		desc := str(def, "description")
+		switch typ := str(def, "type"); {
+		case typ == "basic", typ == "http" && strings.EqualFold(str(def, "scheme"), "basic"):
+			fn = "BasicAuthSecurity"
+		case typ == "http" && strings.EqualFold(str(def, "scheme"), "bearer"):
+			fn = "JWTSecurity"
+			var tokenURL string
+			var scopes [][2]string
+			desc, tokenURL, scopes = parseJWTDescription(desc)
+			dsl.WriteString("Header(\"Authorization\")\n")
+			jwtDSL(&dsl, tokenURL, scopes)
+		case typ == "apiKey":
+			in := str(def, "in")
+			if in != "header" && in != "query" {
+				c.warn(pointer(sptr, "in"), "API keys in %s are not supported", in)
+				continue
+			}
+			fn = "APIKeySecurity"
+			var tokenURL string
+			var scopes [][2]string
+			desc, tokenURL, scopes = parseJWTDescription(desc)
+			if tokenURL != "" || len(scopes) > 0 {
+				fn = "JWTSecurity"
+			}
+			fmt.Fprintf(&dsl, "%s(%q)\n", strings.Title(in), str(def, "name"))
+			jwtDSL(&dsl, tokenURL, scopes)
+		case typ == "oauth2":
+			fn = "OAuth2Security"
+			c.oauth2Flow(&dsl, def, sptr)
+		default:
+			c.warn(pointer(sptr, "type"), "security scheme type %q is not supported", typ)
+			continue
+		}
+		for _, x := range extensions(def) {
+			c.warn(pointer(sptr, x), "security scheme extensions are not supported")
+		}
+		body := dsl.String()
+		if desc != "" {
+			body = fmt.Sprintf("Description(%q)\n", desc) + body
+		}
+		if body == "" {
+			fmt.Fprintf(w, "%s(%q)\n", fn, n)
+			continue
+		}
+		fmt.Fprintf(w, "%s(%q, func() {\n%s})\n", fn, n, body)
+	}
+}
+
+// oauth2Flow writes the DSL describing the flow and scopes of an OAuth2 security scheme.
+func (c *converter) oauth2Flow(w *bytes.Buffer, def map[string]interface{}, ptr string) {
+	flow := def
+	var kind string
+	if c.oas3 {
+		flows := object(def, "flows")
+		for _, f := range []struct{ oas, swagger string }{
+			{"authorizationCode", "accessCode"},
+			{"implicit", "implicit"},
+			{"password", "password"},
+			{"clientCredentials", "application"},
+		} {
+			fm := object(flows, f.oas)
+			if fm == nil {
+				continue
+			}
+			if kind != "" {
+				c.warn(pointer(ptr, "flows", f.oas), "only one OAuth2 flow per security scheme is supported")
+				continue
+			}
+			flow, kind = fm, f.swagger
+		}
+	} else {
+		kind = str(def, "flow")
+	}
+	authURL, tokenURL := str(flow, "authorizationUrl"), str(flow, "tokenUrl")
+	switch kind {
+	case "accessCode":
+		fmt.Fprintf(w, "AccessCodeFlow(%q, %q)\n", authURL, tokenURL)
+	case "implicit":
+		fmt.Fprintf(w, "ImplicitFlow(%q)\n", authURL)
+	case "password":
+		fmt.Fprintf(w, "PasswordFlow(%q)\n", tokenURL)
+	case "application":
+		fmt.Fprintf(w, "ApplicationFlow(%q)\n", tokenURL)
+	default:
+		c.warn(ptr, "unsupported OAuth2 flow %q", kind)
+	}
+	scopes := object(flow, "scopes")
+	for _, s := range keys(scopes) {
+		if d, _ := scopes[s].(string); d != "" {
+			fmt.Fprintf(w, "Scope(%q, %q)\n", s, d)
+		} else {
+			fmt.Fprintf(w, "Scope(%q)\n", s)
+		}
+	}
+}
+
+// security writes the Security or NoSecurity DSL corresponding to the given security
+// requirements.
+func (c *converter) security(w *bytes.Buffer, reqs []interface{}, ptr string) {
+	if len(reqs) == 0 {
+		w.WriteString("NoSecurity()\n")
+		return
+	}
+	if len(reqs) > 1 {
+		c.warn(pointer(ptr, "1"), "alternative security requirements are not supported")
+	}
+	req, _ := reqs[0].(map[string]interface{})
+	names := keys(req)
+	if len(names) == 0 {
+		w.WriteString("NoSecurity()\n")
+		return
+	}
+	if len(names) > 1 {
+		c.warn(pointer(ptr, "0", names[1]), "combined security schemes are not supported")
+	}
+	var scopes []string
+	for _, s := range req[names[0]].([]interface{}) {
+		if sc, ok := s.(string); ok {
+			scopes = append(scopes, sc)
+		}
+	}
+	if len(scopes) == 0 {
+		fmt.Fprintf(w, "Security(%q)\n", names[0])
+		return
+	}
+	fmt.Fprintf(w, "Security(%q, func() {\n", names[0])
+	for _, s := range scopes {
+		fmt.Fprintf(w, "Scope(%q)\n", s)
+	}
+	w.WriteString("})\n")
+}
+
+// isJWT returns true if the security scheme with the given name maps to a JWT security scheme.
+func (c *converter) isJWT(name string) bool {
+	def := object(object(c.doc, "securityDefinitions"), name)
+	if c.oas3 {
+		def = object(object(object(c.doc, "components"), "securitySchemes"), name)
+		if str(def, "type") == "http" {
+			return strings.EqualFold(str(def, "scheme"), "bearer")
+		}
+	}
+	if str(def, "type") != "apiKey" {
+		return false
+	}
+	_, tokenURL, scopes := parseJWTDescription(str(def, "description"))
+	return tokenURL != "" || len(scopes) > 0
+}
+
+// parseJWTDescription extracts the token URL and scopes that goagen appends to the description
+// of JWT security schemes.
+func parseJWTDescription(desc string) (string, string, [][2]string) {
+	var (
+		tokenURL string
+		scopes   [][2]string
+	)
+	if i := strings.Index(desc, scopesHeader); i >= 0 {
+		for _, l := range strings.Split(desc[i+len(scopesHeader):], "\n") {
+			if m := scopeRegex.FindStringSubmatch(l); m != nil {
+				scopes = append(scopes, [2]string{m[1], m[2]})
+			}
+		}
+		desc = desc[:i]
+	}
+	if i := strings.Index(desc, tokenURLHeader); i >= 0 {
+		tokenURL = desc[i+len(tokenURLHeader):]
+		desc = desc[:i]
+	}
+	return desc, tokenURL, scopes
+}
+
+// jwtDSL writes the DSL describing the token URL and scopes of a JWT security scheme.
+func jwtDSL(w *bytes.Buffer, tokenURL string, scopes [][2]string) {
+	if tokenURL != "" {
+		fmt.Fprintf(w, "TokenURL(%q)\n", tokenURL)
+	}
+	for _, s := range scopes {
+		fmt.Fprintf(w, "Scope(%q, %q)\n", s[0], s[1])
+	}
+}
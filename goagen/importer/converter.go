@@ -0,0 +1,788 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"math"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/goadesign/goa/design"
+	"github.com/goadesign/goa/design/apidsl"
+	"github.com/goadesign/goa/goagen/codegen"
+)
+
+// mediaTypeTitlePrefix is the prefix of the title of the schemas produced by goagen for media
+// types.
+const mediaTypeTitlePrefix = "Mediatype identifier: "
+
+type (
+	// converter holds the state of a conversion.
+	converter struct {
+		doc        map[string]interface{}
+		oas3       bool
+		schemasPtr string
+		schemas    map[string]map[string]interface{}
+		refs       map[string]*typeRef
+		mediaTypes map[string]*mediaType
+		userTypes  map[string]*userType
+		resources  map[string]*resource
+		schemes    []string
+		vars       map[string]bool
+		usesDesign bool
+		warnings   []*Warning
+	}
+
+	// typeRef describes what a schema definition maps to in the design.
+	typeRef struct {
+		kind refKind
+		mt   *mediaType
+		view string
+		ut   *userType
+	}
+
+	// refKind enumerates the kinds of schema definitions.
+	refKind int
+
+	// mediaType describes a media type reconstructed from one or more schema definitions.
+	mediaType struct {
+		Identifier string
+		TypeName   string
+		VarName    string
+		views      map[string]string
+		links      map[string]string
+		promoted   bool
+		builtin    bool
+	}
+
+	// userType describes a type built from a schema definition.
+	userType struct {
+		Name    string
+		VarName string
+	}
+)
+
+const (
+	// userTypeRef identifies definitions that map to types.
+	userTypeRef refKind = iota + 1
+	// mediaTypeRef identifies definitions that map to a media type view.
+	mediaTypeRef
+	// collectionRef identifies definitions that map to a media type collection view.
+	collectionRef
+	// linksRef identifies definitions that describe media type links.
+	linksRef
+	// inlineRef identifies definitions that get inlined where referenced.
+	inlineRef
+)
+
+// reserved lists the identifiers exported by the dot imported design packages that may clash
+// with the names of the generated variables.
+var reserved = map[string]bool{
+	"ContentType":          true,
+	"DataType":             true,
+	"DefaultMedia":         true,
+	"ErrorMedia":           true,
+	"MediaType":            true,
+	"UnsupportedMediaType": true,
+}
+
+// newConverter validates the specification version and initializes a converter.
+func newConverter(doc map[string]interface{}) (*converter, error) {
+	c := &converter{
+		doc:        doc,
+		schemas:    make(map[string]map[string]interface{}),
+		refs:       make(map[string]*typeRef),
+		mediaTypes: make(map[string]*mediaType),
+		userTypes:  make(map[string]*userType),
+		resources:  make(map[string]*resource),
+		vars:       make(map[string]bool),
+	}
+	switch {
+	case str(doc, "swagger") == "2.0":
+		c.schemasPtr = "#/definitions"
+		for n, s := range object(doc, "definitions") {
+			if sm, ok := s.(map[string]interface{}); ok {
+				c.schemas[n] = sm
+			}
+		}
+	case strings.HasPrefix(str(doc, "openapi"), "3."):
+		c.oas3 = true
+		c.schemasPtr = "#/components/schemas"
+		for n, s := range object(object(doc, "components"), "schemas") {
+			if sm, ok := s.(map[string]interface{}); ok {
+				c.schemas[n] = sm
+			}
+		}
+	default:
+		return nil, fmt.Errorf(`unsupported specification, expected "swagger: 2.0" or "openapi: 3.x"`)
+	}
+	return c, nil
+}
+
+// convert produces the design package source code.
+func (c *converter) convert(pkg string) ([]byte, error) {
+	c.classify()
+
+	var body bytes.Buffer
+	c.api(&body)
+	c.paths()
+	for _, n := range sortedResources(c.resources) {
+		c.resource(&body, c.resources[n])
+	}
+	ids := make([]string, 0, len(c.mediaTypes))
+	for id := range c.mediaTypes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		c.mediaType(&body, c.mediaTypes[id])
+	}
+	names := make([]string, 0, len(c.userTypes))
+	for n := range c.userTypes {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		c.userType(&body, c.userTypes[n])
+	}
+
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "package %s\n\nimport (\n", pkg)
+	if c.usesDesign {
+		src.WriteString("\t. \"github.com/goadesign/goa/design\"\n")
+	}
+	src.WriteString("\t. \"github.com/goadesign/goa/design/apidsl\"\n)\n")
+	src.Write(body.Bytes())
+	code, err := format.Source(src.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format generated design: %s\n========\nContent:\n%s", err, src.String())
+	}
+	return code, nil
+}
+
+// warn records a warning for the element at the given JSON pointer.
+func (c *converter) warn(ptr, format string, args ...interface{}) {
+	c.warnings = append(c.warnings, &Warning{Pointer: ptr, Message: fmt.Sprintf(format, args...)})
+}
+
+// jsonString returns the JSON encoding of v.
+func jsonString(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// dsl returns the given identifier exported by the design package and records that the
+// package is used.
+func (c *converter) dsl(ident string) string {
+	c.usesDesign = true
+	return ident
+}
+
+// classify computes what each schema definition maps to in the design.
+func (c *converter) classify() {
+	names := make([]string, 0, len(c.schemas))
+	for n := range c.schemas {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	// Media types rendered by goagen are identified by their title.
+	type collection struct{ name, id, view string }
+	var collections []collection
+	for _, n := range names {
+		title := str(c.schemas[n], "title")
+		if !strings.HasPrefix(title, mediaTypeTitlePrefix) {
+			continue
+		}
+		base, params, err := mime.ParseMediaType(strings.TrimPrefix(title, mediaTypeTitlePrefix))
+		if err != nil {
+			c.warn(pointer(c.schemasPtr, n, "title"), "invalid media type identifier: %s", err)
+			continue
+		}
+		view := params["view"]
+		if view == "" {
+			view = design.DefaultView
+		}
+		delete(params, "view")
+		if params["type"] == "collection" {
+			delete(params, "type")
+			collections = append(collections, collection{n, mime.FormatMediaType(base, params), view})
+			continue
+		}
+		id := mime.FormatMediaType(base, params)
+		mt, ok := c.mediaTypes[id]
+		if !ok {
+			mt = &mediaType{
+				Identifier: id,
+				views:      make(map[string]string),
+				builtin:    id == design.ErrorMediaIdentifier,
+			}
+			c.mediaTypes[id] = mt
+		}
+		mt.views[view] = n
+		c.refs[n] = &typeRef{kind: mediaTypeRef, mt: mt, view: view}
+	}
+	for _, mt := range c.mediaTypes {
+		if n, ok := mt.views[design.DefaultView]; ok {
+			mt.TypeName = n
+		} else {
+			for v, n := range mt.views {
+				mt.TypeName = strings.TrimSuffix(n, strings.Title(v))
+				break
+			}
+		}
+	}
+	for _, col := range collections {
+		if mt, ok := c.mediaTypes[col.id]; ok {
+			c.refs[col.name] = &typeRef{kind: collectionRef, mt: mt, view: col.view}
+			continue
+		}
+		c.warn(pointer(c.schemasPtr, col.name), "collection of unknown media type %q is inlined", col.id)
+		c.refs[col.name] = &typeRef{kind: inlineRef}
+	}
+
+	// The links of goagen media types are rendered as a separate definition.
+	for _, id := range sortedMediaTypes(c.mediaTypes) {
+		mt := c.mediaTypes[id]
+		for _, v := range sortedViews(mt.views) {
+			links := object(object(c.schemas[mt.views[v]], "properties"), "links")
+			ln := refName(str(links, "$ref"), c.schemasPtr)
+			if ln != mt.TypeName+"Links" || c.refs[ln] != nil {
+				continue
+			}
+			c.refs[ln] = &typeRef{kind: linksRef, mt: mt}
+			mt.links = make(map[string]string)
+			props := object(c.schemas[ln], "properties")
+			for _, p := range keys(props) {
+				ref := c.refs[refName(str(object(props, p), "$ref"), c.schemasPtr)]
+				if ref == nil || ref.kind != mediaTypeRef {
+					c.warn(pointer(c.schemasPtr, ln, "properties", p), "link must reference a media type")
+					continue
+				}
+				mt.links[p] = ref.view
+			}
+		}
+	}
+
+	// Definitions used to render responses must be media types.
+	for _, r := range c.responseRefs() {
+		if _, ok := c.refs[r.name]; ok {
+			continue
+		}
+		s, ok := c.schemas[r.name]
+		if !ok || !isObject(s) {
+			continue
+		}
+		id := r.identifier
+		if id == "" {
+			id = "application/vnd." + codegen.KebabCase(r.name) + "+json"
+		}
+		if _, ok := c.mediaTypes[id]; ok {
+			id = "application/vnd." + codegen.KebabCase(r.name) + "+json"
+		}
+		mt := &mediaType{
+			Identifier: id,
+			TypeName:   r.name,
+			views:      map[string]string{design.DefaultView: r.name},
+			promoted:   true,
+		}
+		c.mediaTypes[id] = mt
+		c.refs[r.name] = &typeRef{kind: mediaTypeRef, mt: mt, view: design.DefaultView}
+	}
+
+	// The remaining object definitions are types, the others get inlined.
+	for _, n := range names {
+		if _, ok := c.refs[n]; ok {
+			continue
+		}
+		if isObject(c.schemas[n]) {
+			ut := &userType{Name: n}
+			c.userTypes[n] = ut
+			c.refs[n] = &typeRef{kind: userTypeRef, ut: ut}
+			continue
+		}
+		c.warn(pointer(c.schemasPtr, n), "definition is not an object, inlining it where referenced")
+		c.refs[n] = &typeRef{kind: inlineRef}
+	}
+
+	// Compute variable names last so that they are deterministic.
+	for _, id := range sortedMediaTypes(c.mediaTypes) {
+		if mt := c.mediaTypes[id]; !mt.builtin {
+			mt.VarName = c.varName(mt.TypeName, "Media")
+		}
+	}
+	for _, n := range names {
+		if ut, ok := c.userTypes[n]; ok {
+			ut.VarName = c.varName(n, "Type")
+		}
+	}
+}
+
+// varName returns a unique Go variable name built from the given name and suffix.
+func (c *converter) varName(name, suffix string) string {
+	base := codegen.Goify(name, true) + suffix
+	v := base
+	for i := 2; c.vars[v] || reserved[v]; i++ {
+		v = base + strconv.Itoa(i)
+	}
+	c.vars[v] = true
+	return v
+}
+
+// mediaType writes the DSL defining the given media type.
+func (c *converter) mediaType(w *bytes.Buffer, mt *mediaType) {
+	if mt.builtin {
+		return
+	}
+	views := sortedViews(mt.views)
+	main := c.schemas[mt.views[views[0]]]
+	ptr := pointer(c.schemasPtr, mt.views[views[0]])
+
+	// The media type attributes are the union of the attributes of all its views.
+	props := make(map[string]interface{})
+	ptrs := make(map[string]string)
+	var required []string
+	for _, v := range views {
+		s := c.schemas[mt.views[v]]
+		for n, p := range object(s, "properties") {
+			if _, ok := props[n]; ok {
+				continue
+			}
+			if n == "links" && mt.links != nil {
+				continue
+			}
+			props[n] = p
+			ptrs[n] = pointer(c.schemasPtr, mt.views[v], "properties", n)
+		}
+		for _, r := range strs(s, "required") {
+			required = appendUnique(required, r)
+		}
+	}
+	for l := range mt.links {
+		if _, ok := props[l]; !ok {
+			props[l] = map[string]interface{}{"$ref": c.schemasPtr + "/" + c.linkTarget(mt, l)}
+		}
+	}
+
+	desc := str(main, "description")
+	if !mt.promoted {
+		desc = strings.TrimSuffix(desc, " ("+views[0]+" view)")
+		if desc == mt.TypeName+" media type" {
+			desc = ""
+		}
+	}
+
+	fmt.Fprintf(w, "\nvar %s = MediaType(%q, func() {\n", mt.VarName, mt.Identifier)
+	if desc != "" {
+		fmt.Fprintf(w, "Description(%q)\n", desc)
+	}
+	fmt.Fprintf(w, "TypeName(%q)\n", mt.TypeName)
+	c.unsupported(main, ptr)
+	w.WriteString("Attributes(func() {\n")
+	for _, n := range keys(props) {
+		p, _ := props[n].(map[string]interface{})
+		c.attribute(w, "Attribute", n, p, ptrs[n], false)
+	}
+	c.required(w, required)
+	w.WriteString("})\n")
+	if len(mt.links) > 0 {
+		w.WriteString("Links(func() {\n")
+		for _, l := range sortedViews(mt.links) {
+			if v := mt.links[l]; v != "link" {
+				fmt.Fprintf(w, "Link(%q, %q)\n", l, v)
+			} else {
+				fmt.Fprintf(w, "Link(%q)\n", l)
+			}
+		}
+		w.WriteString("})\n")
+	}
+	for _, v := range views {
+		vprops := object(c.schemas[mt.views[v]], "properties")
+		fmt.Fprintf(w, "View(%q, func() {\n", v)
+		for _, n := range keys(vprops) {
+			p, _ := vprops[n].(map[string]interface{})
+			view := ""
+			if ref := c.refs[refName(str(p, "$ref"), c.schemasPtr)]; ref != nil &&
+				(ref.kind == mediaTypeRef || ref.kind == collectionRef) && ref.view != design.DefaultView {
+				view = ref.view
+			}
+			if view != "" {
+				fmt.Fprintf(w, "Attribute(%q, func() {\nView(%q)\n})\n", n, view)
+			} else {
+				fmt.Fprintf(w, "Attribute(%q)\n", n)
+			}
+		}
+		w.WriteString("})\n")
+	}
+	w.WriteString("})\n")
+}
+
+// linkTarget returns the name of the definition rendering the link l of mt.
+func (c *converter) linkTarget(mt *mediaType, l string) string {
+	for _, v := range sortedViews(mt.views) {
+		links := object(object(c.schemas[mt.views[v]], "properties"), "links")
+		ln := refName(str(links, "$ref"), c.schemasPtr)
+		if target := refName(str(object(object(c.schemas[ln], "properties"), l), "$ref"), c.schemasPtr); target != "" {
+			return target
+		}
+	}
+	return ""
+}
+
+// userType writes the DSL defining the given type.
+func (c *converter) userType(w *bytes.Buffer, ut *userType) {
+	s := c.schemas[ut.Name]
+	ptr := pointer(c.schemasPtr, ut.Name)
+	fmt.Fprintf(w, "\nvar %s = Type(%q, func() {\n", ut.VarName, ut.Name)
+	if desc := str(s, "description"); desc != "" {
+		fmt.Fprintf(w, "Description(%q)\n", desc)
+	}
+	c.unsupported(s, ptr)
+	c.members(w, "Attribute", s, ptr)
+	w.WriteString("})\n")
+}
+
+// members writes the DSL defining the properties of the object schema s.
+func (c *converter) members(w *bytes.Buffer, fn string, s map[string]interface{}, ptr string) {
+	props := object(s, "properties")
+	for _, n := range keys(props) {
+		p, _ := props[n].(map[string]interface{})
+		c.attribute(w, fn, n, p, pointer(ptr, "properties", n), true)
+	}
+	c.required(w, strs(s, "required"))
+}
+
+// required writes the Required DSL listing the given attribute names if any.
+func (c *converter) required(w *bytes.Buffer, names []string) {
+	if len(names) == 0 {
+		return
+	}
+	quoted := make([]string, len(names))
+	for i, n := range names {
+		quoted[i] = strconv.Quote(n)
+	}
+	fmt.Fprintf(w, "Required(%s)\n", strings.Join(quoted, ", "))
+}
+
+// attribute writes the DSL defining an attribute with the given name and schema using the DSL
+// function fn (Attribute, Param or Header). withView controls whether the attribute DSL sets the
+// view used to render attributes that reference media types.
+func (c *converter) attribute(w *bytes.Buffer, fn, name string, s map[string]interface{}, ptr string, withView bool) {
+	s, ptr = c.deref(s, ptr)
+	typ, view := c.typeExpr(s, ptr)
+	desc := str(s, "description")
+	var dsl bytes.Buffer
+	if typ == "" {
+		// inline object
+		if desc != "" {
+			fmt.Fprintf(&dsl, "Description(%q)\n", desc)
+		}
+		c.members(&dsl, "Attribute", s, ptr)
+	}
+	if view != "" && withView {
+		fmt.Fprintf(&dsl, "View(%q)\n", view)
+	}
+	// goa does not support examples for file attributes.
+	c.validations(&dsl, s, ptr, typ == "File")
+	for _, x := range extensions(s) {
+		if fn == "Param" || fn == "Header" {
+			fmt.Fprintf(&dsl, "Metadata(%q, %q)\n", "swagger:extension:"+x, jsonString(s[x]))
+		} else {
+			c.warn(pointer(ptr, x), "schema extensions are not supported")
+		}
+	}
+
+	args := []string{strconv.Quote(name)}
+	if typ != "" {
+		args = append(args, typ)
+		if desc != "" {
+			args = append(args, strconv.Quote(desc))
+		}
+	}
+	if dsl.Len() > 0 {
+		args = append(args, "func() {\n"+dsl.String()+"}")
+	}
+	fmt.Fprintf(w, "%s(%s)\n", fn, strings.Join(args, ", "))
+}
+
+// deref follows the references to inlined definitions.
+func (c *converter) deref(s map[string]interface{}, ptr string) (map[string]interface{}, string) {
+	for i := 0; i < 32; i++ {
+		n := refName(str(s, "$ref"), c.schemasPtr)
+		ref := c.refs[n]
+		if ref == nil || ref.kind != inlineRef {
+			return s, ptr
+		}
+		s, ptr = c.schemas[n], pointer(c.schemasPtr, n)
+	}
+	c.warn(ptr, "recursive inlined definition")
+	return map[string]interface{}{}, ptr
+}
+
+// typeExpr returns the Go expression of the type of the attribute described by s. It returns
+// the empty string if s describes an inline object. typeExpr also returns the name of the view
+// used to render the attribute if s references a media type view other than the default view.
+func (c *converter) typeExpr(s map[string]interface{}, ptr string) (string, string) {
+	s, ptr = c.deref(s, ptr)
+	if r, ok := s["$ref"].(string); ok {
+		n := refName(r, c.schemasPtr)
+		ref := c.refs[n]
+		if ref == nil {
+			c.warn(pointer(ptr, "$ref"), "unsupported reference %q", r)
+			return c.dsl("Any"), ""
+		}
+		view := ref.view
+		if view == design.DefaultView {
+			view = ""
+		}
+		switch ref.kind {
+		case userTypeRef:
+			return strconv.Quote(ref.ut.Name), ""
+		case mediaTypeRef:
+			if ref.mt.builtin {
+				return c.dsl("ErrorMedia"), ""
+			}
+			return strconv.Quote(ref.mt.Identifier), view
+		case collectionRef:
+			return fmt.Sprintf("CollectionOf(%q)", ref.mt.Identifier), view
+		default:
+			c.warn(pointer(ptr, "$ref"), "unsupported reference %q", r)
+			return c.dsl("Any"), ""
+		}
+	}
+	for _, k := range []string{"allOf", "oneOf", "anyOf", "not"} {
+		if _, ok := s[k]; ok {
+			c.warn(pointer(ptr, k), "%s schemas are not supported", k)
+			return c.dsl("Any"), ""
+		}
+	}
+	switch str(s, "type") {
+	case "string":
+		switch str(s, "format") {
+		case "date-time":
+			return c.dsl("DateTime"), ""
+		case "uuid":
+			return c.dsl("UUID"), ""
+		case "binary":
+			return c.dsl("File"), ""
+		}
+		return c.dsl("String"), ""
+	case "integer":
+		return c.dsl("Integer"), ""
+	case "number":
+		return c.dsl("Number"), ""
+	case "boolean":
+		return c.dsl("Boolean"), ""
+	case "file":
+		return c.dsl("File"), ""
+	case "array":
+		items, _ := s["items"].(map[string]interface{})
+		iptr := pointer(ptr, "items")
+		items, iptr = c.deref(items, iptr)
+		elem, view := c.typeExpr(items, iptr)
+		if elem == "" {
+			c.warn(iptr, "inline object array elements are not supported")
+			elem = c.dsl("Any")
+		}
+		var dsl bytes.Buffer
+		if view != "" {
+			fmt.Fprintf(&dsl, "View(%q)\n", view)
+		}
+		if _, ok := items["$ref"]; !ok {
+			if desc := str(items, "description"); desc != "" {
+				fmt.Fprintf(&dsl, "Description(%q)\n", desc)
+			}
+			c.validations(&dsl, items, iptr, true)
+		}
+		if dsl.Len() > 0 {
+			return fmt.Sprintf("ArrayOf(%s, func() {\n%s})", elem, dsl.String()), ""
+		}
+		return fmt.Sprintf("ArrayOf(%s)", elem), ""
+	case "object", "":
+		if ap, ok := s["additionalProperties"]; ok && ap != false {
+			elem := c.dsl("Any")
+			if aps, ok := ap.(map[string]interface{}); ok && len(aps) > 0 {
+				if elem, _ = c.typeExpr(aps, pointer(ptr, "additionalProperties")); elem == "" {
+					c.warn(pointer(ptr, "additionalProperties"), "inline object map values are not supported")
+					elem = c.dsl("Any")
+				}
+			}
+			return fmt.Sprintf("HashOf(%s, %s)", c.dsl("String"), elem), ""
+		}
+		if len(object(s, "properties")) > 0 {
+			return "", ""
+		}
+		return c.dsl("Any"), ""
+	default:
+		c.warn(pointer(ptr, "type"), "unsupported type %v", s["type"])
+		return c.dsl("Any"), ""
+	}
+}
+
+// validations writes the DSL corresponding to the validations, default value and example of s.
+func (c *converter) validations(w *bytes.Buffer, s map[string]interface{}, ptr string, noExample bool) {
+	typ := str(s, "type")
+	if vals := array(s, "enum"); len(vals) > 0 {
+		lits := make([]string, len(vals))
+		for i, v := range vals {
+			lits[i] = c.literal(v, s)
+		}
+		fmt.Fprintf(w, "Enum(%s)\n", strings.Join(lits, ", "))
+	}
+	if f := str(s, "format"); f != "" && typ == "string" {
+		switch {
+		case f == "date-time" || f == "uuid" || f == "binary":
+			// mapped to type
+		case isSupportedFormat(f):
+			fmt.Fprintf(w, "Format(%q)\n", f)
+		default:
+			c.warn(pointer(ptr, "format"), "unsupported format %q", f)
+		}
+	}
+	if p := str(s, "pattern"); p != "" {
+		fmt.Fprintf(w, "Pattern(%q)\n", p)
+	}
+	if m, ok := number(s, "minimum"); ok {
+		fmt.Fprintf(w, "Minimum(%s)\n", c.literal(m, s))
+	}
+	if m, ok := number(s, "maximum"); ok {
+		fmt.Fprintf(w, "Maximum(%s)\n", c.literal(m, s))
+	}
+	for _, k := range []string{"minLength", "minItems"} {
+		if m, ok := number(s, k); ok {
+			fmt.Fprintf(w, "MinLength(%d)\n", int(m))
+		}
+	}
+	for _, k := range []string{"maxLength", "maxItems"} {
+		if m, ok := number(s, k); ok {
+			fmt.Fprintf(w, "MaxLength(%d)\n", int(m))
+		}
+	}
+	if d, ok := s["default"]; ok {
+		fmt.Fprintf(w, "Default(%s)\n", c.literal(d, s))
+	}
+	if e, ok := s["example"]; ok && !noExample {
+		fmt.Fprintf(w, "Example(%s)\n", c.literal(e, s))
+	}
+	c.unsupported(s, ptr)
+}
+
+// unsupported records warnings for the schema keywords that have no DSL equivalent.
+func (c *converter) unsupported(s map[string]interface{}, ptr string) {
+	for _, k := range []string{"discriminator", "exclusiveMaximum", "exclusiveMinimum", "maxProperties",
+		"minProperties", "multipleOf", "nullable", "uniqueItems", "writeOnly"} {
+		if v, ok := s[k]; ok && v != false {
+			c.warn(pointer(ptr, k), "%s is not supported", k)
+		}
+	}
+}
+
+// literal returns the Go literal for the JSON value v of the attribute described by s.
+func (c *converter) literal(v interface{}, s map[string]interface{}) string {
+	switch actual := v.(type) {
+	case string:
+		return strconv.Quote(actual)
+	case bool:
+		return strconv.FormatBool(actual)
+	case float64:
+		if str(s, "type") == "integer" && actual == math.Trunc(actual) {
+			return strconv.FormatInt(int64(actual), 10)
+		}
+		lit := strconv.FormatFloat(actual, 'f', -1, 64)
+		if !strings.ContainsAny(lit, ".eE") {
+			lit += ".0"
+		}
+		return lit
+	case []interface{}:
+		items, _ := s["items"].(map[string]interface{})
+		items, _ = c.deref(items, "")
+		lits := make([]string, len(actual))
+		for i, e := range actual {
+			lits[i] = c.literal(e, items)
+		}
+		return "[]interface{}{" + strings.Join(lits, ", ") + "}"
+	case map[string]interface{}:
+		props := object(s, "properties")
+		lits := make([]string, 0, len(actual))
+		for _, k := range keys(actual) {
+			p, _ := props[k].(map[string]interface{})
+			p, _ = c.deref(p, "")
+			lits = append(lits, strconv.Quote(k)+": "+c.literal(actual[k], p))
+		}
+		return "map[string]interface{}{" + strings.Join(lits, ", ") + "}"
+	default:
+		return "nil"
+	}
+}
+
+// isObject returns true if s describes an object with properties.
+func isObject(s map[string]interface{}) bool {
+	if _, ok := s["$ref"]; ok {
+		return false
+	}
+	t := str(s, "type")
+	return (t == "object" || t == "") && len(object(s, "properties")) > 0
+}
+
+// isSupportedFormat returns true if the goa Format DSL supports f.
+func isSupportedFormat(f string) bool {
+	for _, sf := range apidsl.SupportedValidationFormats {
+		if sf == f {
+			return true
+		}
+	}
+	return false
+}
+
+// refName returns the name of the definition referenced by ref, the empty string if ref does
+// not reference a schema definition.
+func refName(ref, prefix string) string {
+	if !strings.HasPrefix(ref, prefix+"/") {
+		return ""
+	}
+	n := strings.TrimPrefix(ref, prefix+"/")
+	n = strings.Replace(n, "~1", "/", -1)
+	return strings.Replace(n, "~0", "~", -1)
+}
+
+// sortedViews returns the keys of the given map with the default view first.
+func sortedViews(m map[string]string) []string {
+	res := make([]string, 0, len(m))
+	for k := range m {
+		if k != design.DefaultView {
+			res = append(res, k)
+		}
+	}
+	sort.Strings(res)
+	if _, ok := m[design.DefaultView]; ok {
+		res = append([]string{design.DefaultView}, res...)
+	}
+	return res
+}
+
+// sortedMediaTypes returns the sorted identifiers of the given media types.
+func sortedMediaTypes(m map[string]*mediaType) []string {
+	res := make([]string, 0, len(m))
+	for k := range m {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// appendUnique appends s to vals if not already present.
+func appendUnique(vals []string, s string) []string {
+	for _, v := range vals {
+		if v == s {
+			return vals
+		}
+	}
+	return append(vals, s)
+}
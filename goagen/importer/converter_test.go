@@ -0,0 +1,313 @@
+package importer_test
+
+import (
+	"encoding/json"
+	"go/parser"
+	"go/token"
+
+	. "github.com/goadesign/goa/design"
+	. "github.com/goadesign/goa/design/apidsl"
+	"github.com/goadesign/goa/dslengine"
+	"github.com/goadesign/goa/goagen/gen_schema"
+	"github.com/goadesign/goa/goagen/gen_swagger"
+	"github.com/goadesign/goa/goagen/importer"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Convert", func() {
+	var (
+		spec     string
+		code     string
+		warnings []*importer.Warning
+		err      error
+	)
+
+	JustBeforeEach(func() {
+		var b []byte
+		b, warnings, err = importer.Convert([]byte(spec), "design")
+		code = string(b)
+	})
+
+	// parses checks that the generated code is valid Go.
+	parses := func() {
+		Ω(err).ShouldNot(HaveOccurred())
+		_, perr := parser.ParseFile(token.NewFileSet(), "design.go", code, 0)
+		Ω(perr).ShouldNot(HaveOccurred())
+	}
+
+	Context("with a Swagger 2.0 specification", func() {
+		BeforeEach(func() {
+			spec = petstore
+		})
+
+		It("generates the design", func() {
+			parses()
+			Ω(code).Should(ContainSubstring(`var _ = API("swagger_petstore", func() {`))
+			Ω(code).Should(ContainSubstring(`Host("petstore.swagger.io")`))
+			Ω(code).Should(ContainSubstring(`BasePath("/v1")`))
+			Ω(code).Should(ContainSubstring(`var _ = Resource("pets", func() {`))
+			Ω(code).Should(ContainSubstring(`Action("showPetById", func() {`))
+			Ω(code).Should(ContainSubstring(`Routing(GET("/pets/:petId"))`))
+			Ω(code).Should(ContainSubstring(`Param("limit", Integer, "How many items to return at one time (max 100)", func() {`))
+			Ω(code).Should(ContainSubstring(`Maximum(100)`))
+			Ω(code).Should(ContainSubstring(`Payload(PetMedia)`))
+			Ω(code).Should(ContainSubstring(`Media(CollectionOf(PetMedia))`))
+			Ω(code).Should(ContainSubstring(`var PetMedia = MediaType("application/vnd.pet+json", func() {`))
+		})
+
+		It("reports the unsupported constructs", func() {
+			Ω(warnings).Should(HaveLen(1))
+			Ω(warnings[0].Pointer).Should(Equal("#/paths/~1pets/get/responses/default"))
+		})
+	})
+
+	Context("with an OpenAPI 3 specification", func() {
+		BeforeEach(func() {
+			spec = petstore3
+		})
+
+		It("generates the design", func() {
+			parses()
+			Ω(code).Should(ContainSubstring(`Host("petstore.swagger.io")`))
+			Ω(code).Should(ContainSubstring(`OptionalPayload(PetMedia)`))
+			Ω(code).Should(ContainSubstring(`Header("x-next", String, "A link to the next page of responses")`))
+			Ω(code).Should(ContainSubstring(`Response(Created, func() {`))
+		})
+
+		It("reports the unsupported constructs with JSON pointers", func() {
+			pointers := make([]string, len(warnings))
+			for i, w := range warnings {
+				pointers[i] = w.Pointer
+			}
+			Ω(pointers).Should(ConsistOf(
+				"#/components/schemas/Pet/properties/tag/nullable",
+				"#/paths/~1pets/get/responses/default",
+				"#/paths/~1pets/post/callbacks",
+			))
+		})
+	})
+
+	Context("with an invalid specification", func() {
+		BeforeEach(func() {
+			spec = `{"swagger": "1.2"}`
+		})
+
+		It("returns an error", func() {
+			Ω(err).Should(HaveOccurred())
+		})
+	})
+
+	Context("with a specification generated by goagen", func() {
+		BeforeEach(func() {
+			dslengine.Reset()
+			genschema.Definitions = make(map[string]*genschema.JSONSchema)
+			API("cellar", func() {
+				Host("localhost:8081")
+				BasicAuthSecurity("basic")
+			})
+			bottle := MediaType("application/vnd.bottle+json", func() {
+				Attributes(func() {
+					Attribute("id", Integer, "ID of bottle")
+					Attribute("name", String, func() {
+						MinLength(2)
+					})
+					Required("id", "name")
+				})
+				View("default", func() {
+					Attribute("id")
+					Attribute("name")
+				})
+				View("tiny", func() {
+					Attribute("id")
+				})
+			})
+			Resource("bottle", func() {
+				DefaultMedia(bottle)
+				BasePath("/bottles")
+				Action("list", func() {
+					Routing(GET(""))
+					Response(OK, func() {
+						Media(CollectionOf(bottle), "tiny")
+					})
+				})
+				Action("show", func() {
+					Routing(GET("/:id"), GET("/:id/details"))
+					Params(func() {
+						Param("id", Integer)
+					})
+					Security("basic")
+					Response(OK)
+					Response(NotFound)
+				})
+			})
+			Ω(dslengine.Run()).ShouldNot(HaveOccurred())
+			swagger, err := genswagger.New(Design)
+			Ω(err).ShouldNot(HaveOccurred())
+			b, err := json.Marshal(swagger)
+			Ω(err).ShouldNot(HaveOccurred())
+			spec = string(b)
+		})
+
+		It("recovers the design", func() {
+			parses()
+			Ω(warnings).Should(BeEmpty())
+			Ω(code).Should(ContainSubstring(`BasicAuthSecurity("basic")`))
+			Ω(code).Should(ContainSubstring(`DefaultMedia(BottleMedia)`))
+			Ω(code).Should(ContainSubstring(`Routing(GET("/bottles/:id"), GET("/bottles/:id/details"))`))
+			Ω(code).Should(ContainSubstring(`Security("basic")`))
+			Ω(code).Should(ContainSubstring(`Media(CollectionOf(BottleMedia), "tiny")`))
+			Ω(code).Should(ContainSubstring(`Response(OK)`))
+			Ω(code).Should(ContainSubstring(`Response(NotFound)`))
+			Ω(code).Should(ContainSubstring(`View("tiny", func() {`))
+		})
+	})
+})
+
+const petstore = `swagger: "2.0"
+info:
+  version: 1.0.0
+  title: Swagger Petstore
+host: petstore.swagger.io
+basePath: /v1
+schemes:
+  - http
+consumes:
+  - application/json
+produces:
+  - application/json
+paths:
+  /pets:
+    get:
+      summary: List all pets
+      operationId: listPets
+      tags:
+        - pets
+      parameters:
+        - name: limit
+          in: query
+          description: How many items to return at one time (max 100)
+          required: false
+          type: integer
+          maximum: 100
+      responses:
+        "200":
+          description: A paged array of pets
+          schema:
+            type: array
+            items:
+              $ref: '#/definitions/Pet'
+        default:
+          description: unexpected error
+    post:
+      summary: Create a pet
+      operationId: createPets
+      tags:
+        - pets
+      parameters:
+        - name: pet
+          in: body
+          required: true
+          schema:
+            $ref: '#/definitions/Pet'
+      responses:
+        "201":
+          description: Null response
+  /pets/{petId}:
+    get:
+      summary: Info for a specific pet
+      operationId: showPetById
+      tags:
+        - pets
+      parameters:
+        - name: petId
+          in: path
+          required: true
+          description: The id of the pet to retrieve
+          type: string
+      responses:
+        "200":
+          description: Expected response to a valid request
+          schema:
+            $ref: '#/definitions/Pet'
+definitions:
+  Pet:
+    required:
+      - id
+      - name
+    properties:
+      id:
+        type: integer
+        format: int64
+      name:
+        type: string
+      tag:
+        type: string
+`
+
+const petstore3 = `openapi: "3.0.0"
+info:
+  version: 1.0.0
+  title: Swagger Petstore
+servers:
+  - url: http://petstore.swagger.io/v1
+paths:
+  /pets:
+    get:
+      summary: List all pets
+      operationId: listPets
+      tags:
+        - pets
+      responses:
+        "200":
+          description: A paged array of pets
+          headers:
+            x-next:
+              description: A link to the next page of responses
+              schema:
+                type: string
+          content:
+            application/json:
+              schema:
+                type: array
+                items:
+                  $ref: "#/components/schemas/Pet"
+        default:
+          description: unexpected error
+    post:
+      summary: Create a pet
+      operationId: createPets
+      tags:
+        - pets
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: "#/components/schemas/Pet"
+      callbacks:
+        created:
+          "{$request.body#/callback}":
+            post:
+              responses:
+                "200":
+                  description: OK
+      responses:
+        "201":
+          description: Null response
+components:
+  schemas:
+    Pet:
+      type: object
+      required:
+        - id
+        - name
+      properties:
+        id:
+          type: integer
+          format: int64
+        name:
+          type: string
+        tag:
+          type: string
+          nullable: true
+`
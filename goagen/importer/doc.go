@@ -0,0 +1,13 @@
+/*
+Package importer provides a tool that turns an existing Swagger 2.0 or OpenAPI 3 specification into
+a goa design package.
+
+The importer writes a single Go file containing the API, Resource, Action, MediaType and Type DSL
+that describes the specification. Running "goagen swagger" on the resulting design produces a
+specification equivalent to the one that was imported.
+
+Constructs that have no equivalent in the goa DSL (e.g. "allOf" schemas, cookie parameters or
+callbacks) are skipped and reported as warnings that include the JSON pointer of the offending
+element.
+*/
+package importer
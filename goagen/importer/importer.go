@@ -0,0 +1,110 @@
+package importer
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+//NewImporter returns an initialized instance of a Swagger/OpenAPI importer
+func NewImporter(options ...Option) *Importer {
+	i := &Importer{Target: "design"}
+
+	for _, option := range options {
+		option(i)
+	}
+
+	return i
+}
+
+type (
+	// Importer produces a goa design package from a Swagger 2.0 or OpenAPI 3 specification.
+	Importer struct {
+		Spec     string     // Path to the specification file
+		OutDir   string     // Path to output directory
+		Target   string     // Name of generated design package
+		Warnings []*Warning // Warnings produced by the last import
+		genfiles []string   // Generated files
+	}
+
+	// Warning describes a construct of the imported specification that could not be mapped to
+	// the goa DSL.
+	Warning struct {
+		// Pointer is the JSON pointer to the unsupported element, e.g.
+		// "#/paths/~1bottles/get/parameters/0".
+		Pointer string
+		// Message describes the issue.
+		Message string
+	}
+)
+
+// Import reads the specification and writes the corresponding design package. It returns the
+// list of generated files.
+func (i *Importer) Import() (_ []string, err error) {
+	defer func() {
+		if err != nil {
+			i.Cleanup()
+		}
+	}()
+
+	if i.Spec == "" {
+		return nil, fmt.Errorf("missing path to specification file")
+	}
+	data, err := ioutil.ReadFile(i.Spec)
+	if err != nil {
+		return nil, err
+	}
+	code, warnings, err := Convert(data, i.Target)
+	i.Warnings = warnings
+	if err != nil {
+		return nil, err
+	}
+
+	designDir := filepath.Join(i.OutDir, i.Target)
+	if err = os.MkdirAll(designDir, 0755); err != nil {
+		return nil, err
+	}
+	designFile := filepath.Join(designDir, "design.go")
+	if _, err = os.Stat(designFile); err == nil {
+		return nil, fmt.Errorf("%s already exists, remove it first to re-import the specification", designFile)
+	}
+	if err = ioutil.WriteFile(designFile, code, 0644); err != nil {
+		return nil, err
+	}
+	i.genfiles = append(i.genfiles, designFile)
+
+	return i.genfiles, nil
+}
+
+// Cleanup removes all the files generated by this importer during the last invokation of Import.
+func (i *Importer) Cleanup() {
+	for _, f := range i.genfiles {
+		os.Remove(f)
+	}
+	i.genfiles = nil
+}
+
+// String returns the warning pointer followed by its message.
+func (w *Warning) String() string {
+	return fmt.Sprintf("%s: %s", w.Pointer, w.Message)
+}
+
+// Convert produces the source code of the design package named pkg that describes the given
+// Swagger 2.0 or OpenAPI 3 specification. The specification may be encoded in JSON or YAML.
+// Convert also returns warnings for the elements of the specification it could not convert.
+func Convert(spec []byte, pkg string) ([]byte, []*Warning, error) {
+	doc, err := load(spec)
+	if err != nil {
+		return nil, nil, err
+	}
+	c, err := newConverter(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	code, err := c.convert(pkg)
+	if err != nil {
+		return nil, c.warnings, err
+	}
+	return code, c.warnings, nil
+}
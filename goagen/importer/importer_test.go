@@ -0,0 +1,105 @@
+package importer_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/goadesign/goa/goagen/importer"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NewImporter", func() {
+	var imp *importer.Importer
+
+	Context("with no option", func() {
+		BeforeEach(func() {
+			imp = importer.NewImporter()
+		})
+
+		It("defaults the target package name", func() {
+			Ω(imp.Target).Should(Equal("design"))
+		})
+	})
+
+	Context("with all options set", func() {
+		BeforeEach(func() {
+			imp = importer.NewImporter(
+				importer.Spec("swagger.json"),
+				importer.OutDir("out_dir"),
+				importer.Target("mydesign"),
+			)
+		})
+
+		It("has all public properties set with expected value", func() {
+			Ω(imp.Spec).Should(Equal("swagger.json"))
+			Ω(imp.OutDir).Should(Equal("out_dir"))
+			Ω(imp.Target).Should(Equal("mydesign"))
+		})
+	})
+})
+
+var _ = Describe("Import", func() {
+	var (
+		workDir string
+		spec    string
+		imp     *importer.Importer
+		files   []string
+		err     error
+	)
+
+	BeforeEach(func() {
+		workDir, err = ioutil.TempDir("", "importer")
+		Ω(err).ShouldNot(HaveOccurred())
+		spec = filepath.Join(workDir, "swagger.yaml")
+		Ω(ioutil.WriteFile(spec, []byte(petstore), 0644)).ShouldNot(HaveOccurred())
+		imp = importer.NewImporter(importer.Spec(spec), importer.OutDir(workDir))
+	})
+
+	JustBeforeEach(func() {
+		files, err = imp.Import()
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(workDir)
+	})
+
+	It("writes the design package", func() {
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(files).Should(Equal([]string{filepath.Join(workDir, "design", "design.go")}))
+		content, err := ioutil.ReadFile(files[0])
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(string(content)).Should(HavePrefix("package design\n"))
+	})
+
+	It("records the warnings", func() {
+		Ω(imp.Warnings).Should(HaveLen(1))
+		Ω(imp.Warnings[0].Pointer).Should(Equal("#/paths/~1pets/get/responses/default"))
+	})
+
+	Context("with an existing design file", func() {
+		BeforeEach(func() {
+			Ω(os.MkdirAll(filepath.Join(workDir, "design"), 0755)).ShouldNot(HaveOccurred())
+			f := filepath.Join(workDir, "design", "design.go")
+			Ω(ioutil.WriteFile(f, []byte("package design\n"), 0644)).ShouldNot(HaveOccurred())
+		})
+
+		It("does not overwrite it", func() {
+			Ω(err).Should(HaveOccurred())
+			content, _ := ioutil.ReadFile(filepath.Join(workDir, "design", "design.go"))
+			Ω(string(content)).Should(Equal("package design\n"))
+		})
+	})
+
+	Context("with no specification", func() {
+		BeforeEach(func() {
+			imp.Spec = ""
+		})
+
+		It("returns an error", func() {
+			Ω(err).Should(HaveOccurred())
+			Ω(files).Should(BeEmpty())
+		})
+	})
+})
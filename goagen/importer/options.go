@@ -0,0 +1,25 @@
+package importer
+
+//Option an importer option definition
+type Option func(*Importer)
+
+//Spec Path to the Swagger or OpenAPI specification file
+func Spec(spec string) Option {
+	return func(i *Importer) {
+		i.Spec = spec
+	}
+}
+
+//OutDir Path to output directory
+func OutDir(outDir string) Option {
+	return func(i *Importer) {
+		i.OutDir = outDir
+	}
+}
+
+//Target Name of generated design package
+func Target(target string) Option {
+	return func(i *Importer) {
+		i.Target = target
+	}
+}
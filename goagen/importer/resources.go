@@ -0,0 +1,870 @@
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/goadesign/goa/design"
+	"github.com/goadesign/goa/goagen/codegen"
+)
+
+type (
+	// resource groups the actions imported from the operations sharing the same resource
+	// name.
+	resource struct {
+		Name    string
+		actions map[string]*action
+	}
+
+	// action describes an action built from one or more operations.
+	action struct {
+		Name        string
+		Description string
+		Summary     string
+		Tags        []string
+		Docs        map[string]interface{}
+		Schemes     []string
+		Routes      []*route
+		Params      []*param
+		Headers     []*param
+		Payload     *payload
+		Responses   map[int]*response
+		Security    []interface{}
+		HasSecurity bool
+		Metadata    []*metadata
+		ptr         string
+	}
+
+	// route describes an action route.
+	route struct {
+		Verb     string
+		Path     string
+		Metadata []*metadata
+		index    int
+	}
+
+	// param describes a path, query string or header parameter.
+	param struct {
+		Name     string
+		In       string
+		Schema   map[string]interface{}
+		Required bool
+		ptr      string
+	}
+
+	// payload describes a request body.
+	payload struct {
+		Schema    map[string]interface{}
+		Required  bool
+		Multipart bool
+		ptr       string
+	}
+
+	// response describes an action response.
+	response struct {
+		Status      int
+		Description string
+		Identifier  string
+		Schema      map[string]interface{}
+		Headers     map[string]interface{}
+		Metadata    []*metadata
+		Standard    bool
+		ptr         string
+		schemaPtr   string
+		media       *string
+	}
+
+	// metadata is a metadata key and value pair.
+	metadata struct {
+		Key   string
+		Value string
+	}
+
+	// responseRef is a reference to a schema definition used to render a response.
+	responseRef struct {
+		name       string
+		identifier string
+	}
+)
+
+var (
+	// operationIDRegex matches the operation IDs produced by goagen.
+	operationIDRegex = regexp.MustCompile(`^([^#]+)#([^#]+)(?:#(\d+))?$`)
+
+	// pathParamRegex matches the path parameters in a specification path.
+	pathParamRegex = regexp.MustCompile(`{([^}]+)}`)
+
+	// methods lists the path item operations in the order they are processed.
+	methods = []string{"get", "put", "post", "delete", "options", "head", "patch"}
+
+	// statusNames indexes the design response names by HTTP status code.
+	statusNames = make(map[int]string)
+)
+
+func init() {
+	for n, r := range design.NewAPIDefinition().DefaultResponses {
+		statusNames[r.Status] = n
+	}
+}
+
+// paths builds the resources from the specification paths.
+func (c *converter) paths() {
+	paths := object(c.doc, "paths")
+	for _, p := range keys(paths) {
+		ptr := pointer("#/paths", p)
+		if strings.HasPrefix(p, "x-") {
+			c.warn(ptr, "paths extensions are not supported")
+			continue
+		}
+		item, _ := paths[p].(map[string]interface{})
+		if _, ok := item["$ref"]; ok {
+			c.warn(pointer(ptr, "$ref"), "path item references are not supported")
+			continue
+		}
+		for _, k := range []string{"servers", "trace"} {
+			if _, ok := item[k]; ok {
+				c.warn(pointer(ptr, k), "%s is not supported", k)
+			}
+		}
+		for _, m := range methods {
+			if op := object(item, m); op != nil {
+				c.operation(p, m, item, op, pointer(ptr, m))
+			}
+		}
+	}
+}
+
+// operation adds the action or route corresponding to the given path item operation.
+func (c *converter) operation(path, method string, item, op map[string]interface{}, ptr string) {
+	itemPtr := pointer("#/paths", path)
+	resName, actName, index := c.names(path, method, op)
+	res, ok := c.resources[resName]
+	if !ok {
+		res = &resource{Name: resName, actions: make(map[string]*action)}
+		c.resources[resName] = res
+	}
+	a, ok := res.actions[actName]
+	if ok && a.hasRoute(index) {
+		// Operation ID clash, keep both actions.
+		base := actName
+		for i := 2; ok; i++ {
+			actName = base + strconv.Itoa(i)
+			_, ok = res.actions[actName]
+		}
+	}
+	if !ok {
+		a = &action{Name: actName, Responses: make(map[int]*response), ptr: ptr}
+		res.actions[actName] = a
+	}
+
+	r := &route{Verb: strings.ToUpper(method), Path: c.routePath(path, itemPtr), index: index}
+	for _, x := range extensions(op) {
+		r.Metadata = append(r.Metadata, &metadata{"swagger:extension:" + x, jsonString(op[x])})
+	}
+	a.Routes = append(a.Routes, r)
+
+	// Parameters defined at the path level apply to all operations.
+	c.params(a, array(op, "parameters"), pointer(ptr, "parameters"))
+	c.params(a, array(item, "parameters"), pointer(itemPtr, "parameters"))
+	if len(a.Routes) > 1 {
+		// Additional route of an existing action.
+		return
+	}
+
+	a.Description = str(op, "description")
+	if s := str(op, "summary"); s != "" && s != actName+" "+resName {
+		a.Summary = s
+	}
+	for _, t := range strs(op, "tags") {
+		if t != resName || len(strs(op, "tags")) > 1 {
+			a.Tags = append(a.Tags, t)
+		}
+	}
+	a.Docs = object(op, "externalDocs")
+	a.Schemes = strs(op, "schemes")
+	for _, x := range extensions(item) {
+		a.Metadata = append(a.Metadata, &metadata{"swagger:extension:" + x, jsonString(item[x])})
+	}
+	if sec, ok := op["security"].([]interface{}); ok {
+		a.Security = sec
+		a.HasSecurity = true
+	}
+	if c.oas3 {
+		c.requestBody(a, op, ptr)
+	}
+	for _, k := range []string{"callbacks", "deprecated", "servers"} {
+		if v, ok := op[k]; ok && v != false {
+			c.warn(pointer(ptr, k), "%s is not supported", k)
+		}
+	}
+	resps := object(op, "responses")
+	for _, code := range keys(resps) {
+		c.response(a, code, resps[code], pointer(ptr, "responses", code))
+	}
+}
+
+// hasRoute returns true if the action has a route with the given index.
+func (a *action) hasRoute(index int) bool {
+	for _, r := range a.Routes {
+		if r.index == index {
+			return true
+		}
+	}
+	return false
+}
+
+// isImplicit returns true if p is a string path parameter with no other property than possibly a
+// generated example. The DSL
+// does not need to define these parameters, goa creates them from the action routes.
+func (a *action) isImplicit(p *param) bool {
+	if str(p.Schema, "type") != "string" {
+		return false
+	}
+	for k := range p.Schema {
+		switch k {
+		case "name", "in", "required", "type", "example":
+		default:
+			return false
+		}
+	}
+	for _, r := range a.Routes {
+		for _, s := range strings.Split(r.Path, "/") {
+			if s == ":"+p.Name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// names computes the resource and action names of an operation. It uses the operation ID if it
+// was produced by goagen, the operation ID and first tag otherwise. The returned index is the
+// index of the route as encoded in operation IDs produced by goagen.
+func (c *converter) names(path, method string, op map[string]interface{}) (string, string, int) {
+	id := str(op, "operationId")
+	if m := operationIDRegex.FindStringSubmatch(id); m != nil {
+		index, _ := strconv.Atoi(m[3])
+		return m[1], m[2], index
+	}
+	var res string
+	if tags := strs(op, "tags"); len(tags) > 0 {
+		res = tags[0]
+	} else {
+		for _, s := range strings.Split(path, "/") {
+			if s != "" && !strings.HasPrefix(s, "{") {
+				res = s
+				break
+			}
+		}
+	}
+	if res == "" {
+		res = "default"
+	}
+	act := id
+	if act == "" {
+		elems := []string{method}
+		for _, s := range strings.Split(path, "/") {
+			if s = strings.Trim(s, "{}"); s != "" {
+				elems = append(elems, s)
+			}
+		}
+		act = codegen.SnakeCase(codegen.Goify(strings.Join(elems, "_"), false))
+	}
+	return res, act, 0
+}
+
+// routePath converts the specification path into a goa route path.
+func (c *converter) routePath(path, ptr string) string {
+	return pathParamRegex.ReplaceAllStringFunc(path, func(p string) string {
+		i := strings.Index(path, p)
+		if i == 0 || path[i-1] != '/' || (i+len(p) < len(path) && path[i+len(p)] != '/') {
+			c.warn(ptr, "path parameter %s must span a complete path segment", p)
+		}
+		return ":" + p[1:len(p)-1]
+	})
+}
+
+// params records the given parameters in the action. Parameters that were already recorded
+// are skipped so that operation parameters override path item parameters.
+func (c *converter) params(a *action, params []interface{}, ptr string) {
+	for i, p := range params {
+		pptr := pointer(ptr, strconv.Itoa(i))
+		pm, _ := p.(map[string]interface{})
+		pm, pptr = c.resolve(pm, pptr)
+		if pm == nil {
+			continue
+		}
+		name, in := str(pm, "name"), str(pm, "in")
+		schema := pm
+		if c.oas3 {
+			if _, ok := pm["content"]; ok {
+				c.warn(pointer(pptr, "content"), "parameter content is not supported")
+				continue
+			}
+			schema = make(map[string]interface{})
+			for k, v := range object(pm, "schema") {
+				schema[k] = v
+			}
+			if d := str(pm, "description"); d != "" {
+				schema["description"] = d
+			}
+			for _, x := range extensions(pm) {
+				schema[x] = pm[x]
+			}
+		}
+		np := &param{Name: name, In: in, Schema: schema, Required: boolean(pm, "required"), ptr: pptr}
+		switch in {
+		case "path", "query":
+			if !hasParam(a.Params, name) {
+				a.Params = append(a.Params, np)
+			}
+		case "header":
+			if !hasParam(a.Headers, name) {
+				a.Headers = append(a.Headers, np)
+			}
+		case "body":
+			if a.Payload == nil {
+				a.Payload = &payload{Schema: object(pm, "schema"), Required: np.Required, ptr: pointer(pptr, "schema")}
+			}
+		case "formData":
+			if a.Payload == nil {
+				a.Payload = &payload{
+					Schema:    map[string]interface{}{"type": "object", "properties": map[string]interface{}{}},
+					Multipart: true,
+					Required:  true,
+					ptr:       pptr,
+				}
+			}
+			if !a.Payload.Multipart {
+				c.warn(pptr, "form parameters cannot be combined with a body parameter")
+				continue
+			}
+			a.Payload.Schema["properties"].(map[string]interface{})[name] = schema
+			if np.Required {
+				a.Payload.Schema["required"] = append(array(a.Payload.Schema, "required"), name)
+			}
+		default:
+			c.warn(pointer(pptr, "in"), "%s parameters are not supported", in)
+		}
+	}
+}
+
+// requestBody records the OpenAPI 3 operation request body in the action.
+func (c *converter) requestBody(a *action, op map[string]interface{}, ptr string) {
+	rb, rptr := c.resolve(object(op, "requestBody"), pointer(ptr, "requestBody"))
+	if rb == nil {
+		return
+	}
+	content := object(rb, "content")
+	ct := preferredContentType(content)
+	if ct == "" {
+		return
+	}
+	a.Payload = &payload{
+		Schema:    object(object(content, ct), "schema"),
+		Required:  boolean(rb, "required"),
+		Multipart: ct == "multipart/form-data",
+		ptr:       pointer(rptr, "content", ct, "schema"),
+	}
+}
+
+// response records the response with the given status code in the action.
+func (c *converter) response(a *action, code string, v interface{}, ptr string) {
+	if strings.HasPrefix(code, "x-") {
+		c.warn(ptr, "responses extensions are not supported")
+		return
+	}
+	status, err := strconv.Atoi(code)
+	if err != nil {
+		c.warn(ptr, "response %q is not supported, only explicit status codes are", code)
+		return
+	}
+	rm, _ := v.(map[string]interface{})
+	rm, ptr = c.resolve(rm, ptr)
+	if rm == nil {
+		return
+	}
+	r := &response{
+		Status:      status,
+		Description: str(rm, "description"),
+		Headers:     object(rm, "headers"),
+		ptr:         ptr,
+	}
+	// goagen lists the standard responses in the top level responses object.
+	standard := object(c.doc, "responses")
+	if c.oas3 {
+		standard = object(object(c.doc, "components"), "responses")
+	}
+	if n, ok := statusNames[status]; ok && reflect.DeepEqual(standard[n], v) {
+		r.Standard = true
+	}
+	for _, x := range extensions(rm) {
+		r.Metadata = append(r.Metadata, &metadata{"swagger:extension:" + x, jsonString(rm[x])})
+	}
+	if c.oas3 {
+		if _, ok := rm["links"]; ok {
+			c.warn(pointer(ptr, "links"), "response links are not supported")
+		}
+		content := object(rm, "content")
+		if ct := preferredContentType(content); ct != "" {
+			r.Identifier = ct
+			r.Schema = object(object(content, ct), "schema")
+			r.schemaPtr = pointer(ptr, "content", ct, "schema")
+		}
+	} else {
+		r.Schema = object(rm, "schema")
+		r.schemaPtr = pointer(ptr, "schema")
+	}
+	a.Responses[status] = r
+}
+
+// responseRefs returns the definitions referenced by the response schemas.
+func (c *converter) responseRefs() []*responseRef {
+	var refs []*responseRef
+	add := func(s map[string]interface{}, id string) {
+		if str(s, "type") == "array" {
+			s = object(s, "items")
+		}
+		if n := refName(str(s, "$ref"), c.schemasPtr); n != "" {
+			if isGenericContentType(id) {
+				id = ""
+			}
+			refs = append(refs, &responseRef{name: n, identifier: id})
+		}
+	}
+	paths := object(c.doc, "paths")
+	for _, p := range keys(paths) {
+		item := object(paths, p)
+		for _, m := range methods {
+			resps := object(object(item, m), "responses")
+			for _, code := range keys(resps) {
+				rm, _ := resps[code].(map[string]interface{})
+				rm, _ = c.resolve(rm, "")
+				if !c.oas3 {
+					add(object(rm, "schema"), "")
+					continue
+				}
+				content := object(rm, "content")
+				for _, ct := range keys(content) {
+					add(object(object(content, ct), "schema"), ct)
+				}
+			}
+		}
+	}
+	return refs
+}
+
+// resolve follows the local reference in m if there is one.
+func (c *converter) resolve(m map[string]interface{}, ptr string) (map[string]interface{}, string) {
+	for i := 0; m != nil && i < 32; i++ {
+		ref, ok := m["$ref"].(string)
+		if !ok {
+			return m, ptr
+		}
+		v, ok := lookup(c.doc, ref)
+		if !ok {
+			c.warn(pointer(ptr, "$ref"), "unsupported reference %q", ref)
+			return nil, ptr
+		}
+		m, _ = v.(map[string]interface{})
+		ptr = ref
+	}
+	return m, ptr
+}
+
+// resource writes the DSL defining the given resource.
+func (c *converter) resource(w *bytes.Buffer, res *resource) {
+	fmt.Fprintf(w, "\nvar _ = Resource(%q, func() {\n", res.Name)
+	names := make([]string, 0, len(res.actions))
+	for n := range res.actions {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	if media := c.defaultMedia(res, names); media != "" {
+		fmt.Fprintf(w, "DefaultMedia(%s)\n", media)
+	}
+	for _, n := range names {
+		c.action(w, res.actions[n])
+	}
+	w.WriteString("})\n")
+}
+
+// defaultMedia returns the arguments of the DefaultMedia DSL of the given resource. The standard
+// OK responses of a goa design use the resource default media type so the default media type can
+// be recovered when all these responses agree on it. defaultMedia marks the OK responses that
+// don't use it as non standard.
+func (c *converter) defaultMedia(res *resource, names []string) string {
+	var (
+		media string
+		oks   []*response
+		agree = true
+	)
+	for _, n := range names {
+		r, ok := res.actions[n].Responses[http.StatusOK]
+		if !ok || !r.Standard {
+			continue
+		}
+		m := c.responseMedia(r)
+		if len(oks) > 0 && m != media {
+			agree = false
+		}
+		media = m
+		oks = append(oks, r)
+	}
+	if !agree {
+		for _, r := range oks {
+			r.Standard = false
+		}
+		return ""
+	}
+	return media
+}
+
+// action writes the DSL defining the given action.
+func (c *converter) action(w *bytes.Buffer, a *action) {
+	fmt.Fprintf(w, "Action(%q, func() {\n", a.Name)
+	desc := a.Description
+	if a.HasSecurity && len(a.Security) > 0 {
+		if req, _ := a.Security[0].(map[string]interface{}); len(req) > 0 && c.isJWT(keys(req)[0]) {
+			if i := strings.Index(desc, "Required security scopes:\n"); i >= 0 {
+				desc = strings.TrimSuffix(desc[:i], "\n\n")
+			}
+		}
+	}
+	if desc != "" {
+		fmt.Fprintf(w, "Description(%q)\n", desc)
+	}
+	if a.Summary != "" {
+		fmt.Fprintf(w, "Metadata(\"swagger:summary\", %q)\n", a.Summary)
+	}
+	for _, t := range a.Tags {
+		fmt.Fprintf(w, "Metadata(%q)\n", "swagger:tag:"+t)
+	}
+	for _, m := range a.Metadata {
+		fmt.Fprintf(w, "Metadata(%q, %q)\n", m.Key, m.Value)
+	}
+	c.docs(w, a.Docs)
+	if len(a.Schemes) > 0 && !equalStrings(a.Schemes, c.schemes) {
+		fmt.Fprintf(w, "Scheme(%s)\n", quoteAll(a.Schemes))
+	}
+	sort.SliceStable(a.Routes, func(i, j int) bool { return a.Routes[i].index < a.Routes[j].index })
+	routes := make([]string, len(a.Routes))
+	for i, r := range a.Routes {
+		if len(r.Metadata) == 0 {
+			routes[i] = fmt.Sprintf("%s(%q)", r.Verb, r.Path)
+			continue
+		}
+		var meta bytes.Buffer
+		for _, m := range r.Metadata {
+			fmt.Fprintf(&meta, "Metadata(%q, %q)\n", m.Key, m.Value)
+		}
+		routes[i] = fmt.Sprintf("%s(%q, func() {\n%s})", r.Verb, r.Path, meta.String())
+	}
+	fmt.Fprintf(w, "Routing(%s)\n", strings.Join(routes, ", "))
+	var params []*param
+	for _, p := range a.Params {
+		if !a.isImplicit(p) {
+			params = append(params, p)
+		}
+	}
+	c.paramsDSL(w, "Params", "Param", params)
+	c.paramsDSL(w, "Headers", "Header", a.Headers)
+	c.payload(w, a.Payload)
+	if a.HasSecurity {
+		c.security(w, a.Security, pointer(a.ptr, "security"))
+	}
+	codes := make([]int, 0, len(a.Responses))
+	for code := range a.Responses {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		c.responseDSL(w, a.Responses[code])
+	}
+	w.WriteString("})\n")
+}
+
+// paramsDSL writes the Params or Headers DSL.
+func (c *converter) paramsDSL(w *bytes.Buffer, fn, attFn string, params []*param) {
+	if len(params) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "%s(func() {\n", fn)
+	var required []string
+	for _, p := range params {
+		c.attribute(w, attFn, p.Name, p.Schema, p.ptr, true)
+		if p.Required && p.In != "path" {
+			required = append(required, p.Name)
+		}
+	}
+	c.required(w, required)
+	w.WriteString("})\n")
+}
+
+// payload writes the Payload DSL.
+func (c *converter) payload(w *bytes.Buffer, p *payload) {
+	if p == nil {
+		return
+	}
+	fn := "Payload"
+	if !p.Required {
+		fn = "OptionalPayload"
+	}
+	if expr := c.payloadType(p); expr != "" {
+		fmt.Fprintf(w, "%s(%s)\n", fn, expr)
+	} else {
+		s, ptr := c.deref(p.Schema, p.ptr)
+		fmt.Fprintf(w, "%s(func() {\n", fn)
+		if desc := str(s, "description"); desc != "" {
+			fmt.Fprintf(w, "Description(%q)\n", desc)
+		}
+		c.members(w, "Attribute", s, ptr)
+		w.WriteString("})\n")
+	}
+	if p.Multipart {
+		w.WriteString("MultipartForm()\n")
+	}
+}
+
+// payloadType returns the Go expression of the payload type, the empty string if the payload is
+// an inline object.
+func (c *converter) payloadType(p *payload) string {
+	s, ptr := c.deref(p.Schema, p.ptr)
+	if ref := c.refs[refName(str(s, "$ref"), c.schemasPtr)]; ref != nil {
+		switch {
+		case ref.kind == userTypeRef:
+			return ref.ut.VarName
+		case ref.kind == mediaTypeRef && ref.mt.builtin:
+			return c.dsl("ErrorMedia")
+		case ref.kind == mediaTypeRef:
+			return ref.mt.VarName
+		}
+	}
+	typ, _ := c.typeExpr(s, ptr)
+	return typ
+}
+
+// responseDSL writes the Response DSL.
+func (c *converter) responseDSL(w *bytes.Buffer, r *response) {
+	var dsl bytes.Buffer
+	name, ok := statusNames[r.Status]
+	if ok {
+		name = c.dsl(name)
+	} else {
+		name = strconv.Quote(codegen.Goify(http.StatusText(r.Status), true))
+		if name == `""` {
+			name = strconv.Quote("Status" + strconv.Itoa(r.Status))
+		}
+		fmt.Fprintf(&dsl, "Status(%d)\n", r.Status)
+	}
+	if r.Standard {
+		fmt.Fprintf(w, "Response(%s)\n", name)
+		return
+	}
+	if r.Description != "" && r.Description != http.StatusText(r.Status) {
+		fmt.Fprintf(&dsl, "Description(%q)\n", r.Description)
+	}
+	if media := c.responseMedia(r); media != "" {
+		fmt.Fprintf(&dsl, "Media(%s)\n", media)
+	}
+	if len(r.Headers) > 0 {
+		dsl.WriteString("Headers(func() {\n")
+		for _, h := range keys(r.Headers) {
+			hm, _ := r.Headers[h].(map[string]interface{})
+			hptr := pointer(r.ptr, "headers", h)
+			hm, hptr = c.resolve(hm, hptr)
+			if c.oas3 {
+				schema := make(map[string]interface{})
+				for k, v := range object(hm, "schema") {
+					schema[k] = v
+				}
+				if d := str(hm, "description"); d != "" {
+					schema["description"] = d
+				}
+				hm = schema
+			}
+			c.attribute(&dsl, "Header", h, hm, hptr, true)
+		}
+		dsl.WriteString("})\n")
+	}
+	for _, m := range r.Metadata {
+		fmt.Fprintf(&dsl, "Metadata(%q, %q)\n", m.Key, m.Value)
+	}
+	if dsl.Len() == 0 {
+		fmt.Fprintf(w, "Response(%s)\n", name)
+		return
+	}
+	fmt.Fprintf(w, "Response(%s, func() {\n%s})\n", name, dsl.String())
+}
+
+// responseMedia returns the arguments of the Media DSL describing the response body, it computes
+// them only once.
+func (c *converter) responseMedia(r *response) string {
+	if r.media == nil {
+		m := c.media(r)
+		r.media = &m
+	}
+	return *r.media
+}
+
+// media returns the arguments of the Media DSL describing the response body.
+func (c *converter) media(r *response) string {
+	if r.Schema == nil {
+		return ""
+	}
+	s, ptr := c.deref(r.Schema, r.schemaPtr)
+	args := func(expr string, ref *typeRef) string {
+		if ref.view != "" && ref.view != design.DefaultView {
+			return fmt.Sprintf("%s, %q", expr, ref.view)
+		}
+		return expr
+	}
+	if ref := c.refs[refName(str(s, "$ref"), c.schemasPtr)]; ref != nil {
+		switch ref.kind {
+		case mediaTypeRef:
+			if ref.mt.builtin {
+				return c.dsl("ErrorMedia")
+			}
+			return args(ref.mt.VarName, ref)
+		case collectionRef:
+			return args(fmt.Sprintf("CollectionOf(%s)", ref.mt.VarName), ref)
+		}
+	}
+	if str(s, "type") == "array" {
+		items, _ := s["items"].(map[string]interface{})
+		if ref := c.refs[refName(str(items, "$ref"), c.schemasPtr)]; ref != nil && ref.kind == mediaTypeRef && !ref.mt.builtin {
+			return args(fmt.Sprintf("CollectionOf(%s)", ref.mt.VarName), ref)
+		}
+	}
+	if oneOf := array(s, "oneOf"); len(oneOf) > 0 {
+		// goagen openapi3 lists the views of the media type.
+		var (
+			first *typeRef
+			views []string
+		)
+		for _, o := range oneOf {
+			om, _ := o.(map[string]interface{})
+			ref := c.refs[refName(str(om, "$ref"), c.schemasPtr)]
+			if ref == nil || (ref.kind != mediaTypeRef && ref.kind != collectionRef) ||
+				(first != nil && (ref.mt != first.mt || ref.kind != first.kind)) {
+				first = nil
+				break
+			}
+			if first == nil {
+				first = ref
+			}
+			views = append(views, ref.view)
+		}
+		if first != nil && !first.mt.builtin {
+			if first.kind == collectionRef {
+				if len(views) == len(first.mt.views) {
+					return fmt.Sprintf("CollectionOf(%s)", first.mt.VarName)
+				}
+				var dsl bytes.Buffer
+				for _, v := range views {
+					fmt.Fprintf(&dsl, "View(%q)\n", v)
+				}
+				return fmt.Sprintf("CollectionOf(%s, func() {\n%s})", first.mt.VarName, dsl.String())
+			}
+			return first.mt.VarName
+		}
+	}
+	if r.Identifier != "" && isGenericContentType(r.Identifier) && len(s) == 0 {
+		return strconv.Quote(r.Identifier)
+	}
+	c.warn(ptr, "inline response schemas are not supported")
+	return ""
+}
+
+// docs writes the Docs DSL corresponding to the given external documentation object.
+func (c *converter) docs(w *bytes.Buffer, docs map[string]interface{}) {
+	if docs == nil {
+		return
+	}
+	w.WriteString("Docs(func() {\n")
+	if d := str(docs, "description"); d != "" {
+		fmt.Fprintf(w, "Description(%q)\n", d)
+	}
+	if u := str(docs, "url"); u != "" {
+		fmt.Fprintf(w, "URL(%q)\n", u)
+	}
+	w.WriteString("})\n")
+}
+
+// sortedResources returns the sorted names of the given resources.
+func sortedResources(m map[string]*resource) []string {
+	res := make([]string, 0, len(m))
+	for k := range m {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// preferredContentType returns the content type of the request or response body the importer
+// uses: JSON if available, the first content type in alphabetical order otherwise.
+func preferredContentType(content map[string]interface{}) string {
+	cts := keys(content)
+	for _, ct := range cts {
+		if ct == "application/json" {
+			return ct
+		}
+	}
+	for _, ct := range cts {
+		if strings.HasSuffix(ct, "json") {
+			return ct
+		}
+	}
+	if len(cts) > 0 {
+		return cts[0]
+	}
+	return ""
+}
+
+// isGenericContentType returns true if ct is not specific to a media type.
+func isGenericContentType(ct string) bool {
+	switch ct {
+	case "", "*/*", "application/json", "application/xml", "application/gob", "application/x-gob",
+		"text/plain", "text/xml", "text/html", "application/octet-stream":
+		return true
+	}
+	return false
+}
+
+// hasParam returns true if params contains a parameter with the given name.
+func hasParam(params []*param, name string) bool {
+	for _, p := range params {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// equalStrings returns true if a and b contain the same values in the same order.
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// quoteAll returns the comma separated list of the quoted values.
+func quoteAll(vals []string) string {
+	quoted := make([]string, len(vals))
+	for i, v := range vals {
+		quoted[i] = strconv.Quote(v)
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,168 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// load decodes the JSON or YAML specification into generic maps.
+func load(spec []byte) (map[string]interface{}, error) {
+	var raw interface{}
+	if err := json.Unmarshal(spec, &raw); err != nil {
+		if yerr := yaml.Unmarshal(spec, &raw); yerr != nil {
+			return nil, fmt.Errorf("failed to decode specification, not valid JSON (%s) or YAML (%s)", err, yerr)
+		}
+		var err error
+		if raw, err = normalize(raw); err != nil {
+			return nil, err
+		}
+	}
+	doc, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid specification, top level value must be an object")
+	}
+	return doc, nil
+}
+
+// normalize converts the maps produced by the YAML decoder into maps indexed by strings and the
+// integer values into float64 so that the rest of the code only deals with JSON values.
+func normalize(val interface{}) (interface{}, error) {
+	switch actual := val.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(actual))
+		for k, v := range actual {
+			var key string
+			switch kv := k.(type) {
+			case string:
+				key = kv
+			case int:
+				key = strconv.Itoa(kv)
+			case bool:
+				key = strconv.FormatBool(kv)
+			default:
+				return nil, fmt.Errorf("invalid specification, unsupported key %v", k)
+			}
+			nv, err := normalize(v)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = nv
+		}
+		return m, nil
+	case []interface{}:
+		s := make([]interface{}, len(actual))
+		for i, e := range actual {
+			ne, err := normalize(e)
+			if err != nil {
+				return nil, err
+			}
+			s[i] = ne
+		}
+		return s, nil
+	case int:
+		return float64(actual), nil
+	case int64:
+		return float64(actual), nil
+	case uint64:
+		return float64(actual), nil
+	default:
+		return actual, nil
+	}
+}
+
+// object returns the object stored under key in m, nil if there isn't one.
+func object(m map[string]interface{}, key string) map[string]interface{} {
+	o, _ := m[key].(map[string]interface{})
+	return o
+}
+
+// array returns the array stored under key in m, nil if there isn't one.
+func array(m map[string]interface{}, key string) []interface{} {
+	a, _ := m[key].([]interface{})
+	return a
+}
+
+// str returns the string stored under key in m, the empty string if there isn't one.
+func str(m map[string]interface{}, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
+
+// strs returns the strings stored in the array under key in m.
+func strs(m map[string]interface{}, key string) []string {
+	var res []string
+	for _, e := range array(m, key) {
+		if s, ok := e.(string); ok {
+			res = append(res, s)
+		}
+	}
+	return res
+}
+
+// boolean returns the boolean stored under key in m, false if there isn't one.
+func boolean(m map[string]interface{}, key string) bool {
+	b, _ := m[key].(bool)
+	return b
+}
+
+// number returns the number stored under key in m and true, false if there isn't one.
+func number(m map[string]interface{}, key string) (float64, bool) {
+	f, ok := m[key].(float64)
+	return f, ok
+}
+
+// keys returns the sorted keys of m.
+func keys(m map[string]interface{}) []string {
+	res := make([]string, 0, len(m))
+	for k := range m {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// extensions returns the sorted names of the specification extensions ("x-" fields) in m.
+func extensions(m map[string]interface{}) []string {
+	var res []string
+	for _, k := range keys(m) {
+		if strings.HasPrefix(k, "x-") {
+			res = append(res, k)
+		}
+	}
+	return res
+}
+
+// pointer appends the given reference tokens to the JSON pointer ptr escaping them as needed.
+func pointer(ptr string, tokens ...string) string {
+	for _, t := range tokens {
+		t = strings.Replace(t, "~", "~0", -1)
+		t = strings.Replace(t, "/", "~1", -1)
+		ptr += "/" + t
+	}
+	return ptr
+}
+
+// lookup returns the value the local JSON reference ref points to in doc.
+func lookup(doc map[string]interface{}, ref string) (interface{}, bool) {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, false
+	}
+	var cur interface{} = doc
+	for _, t := range strings.Split(ref[2:], "/") {
+		t = strings.Replace(t, "~1", "/", -1)
+		t = strings.Replace(t, "~0", "~", -1)
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[t]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
@@ -3,12 +3,15 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/goadesign/goa/goagen/codegen"
+	"github.com/goadesign/goa/goagen/gen_diff"
+	"github.com/goadesign/goa/goagen/importer"
 	"github.com/goadesign/goa/goagen/meta"
 	"github.com/goadesign/goa/goagen/utils"
 	"github.com/goadesign/goa/version"
@@ -110,6 +113,23 @@ package and tool and the Swagger specification for the API.
 	}
 	rootCmd.AddCommand(swaggerCmd)
 
+	// openapi3Cmd implements the "openapi3" command.
+	openapi3Cmd := &cobra.Command{
+		Use:   "openapi3",
+		Short: "Generate OpenAPI 3.0",
+		Run:   func(c *cobra.Command, _ []string) { files, err = run("genopenapi3", c) },
+	}
+	rootCmd.AddCommand(openapi3Cmd)
+
+	// protoCmd implements the "proto" command.
+	protoCmd := &cobra.Command{
+		Use:   "proto",
+		Short: "Generate Protocol Buffers definitions and gRPC adapters",
+		Run:   func(c *cobra.Command, _ []string) { files, err = run("genproto", c) },
+	}
+	protoCmd.Flags().StringVar(&pkg, "pkg", "pb", "Name of Go package generated by protoc from the Protocol Buffers definitions")
+	rootCmd.AddCommand(protoCmd)
+
 	// jsCmd implements the "js" command.
 	var (
 		timeout      = time.Duration(20) * time.Second
@@ -127,6 +147,17 @@ package and tool and the Swagger specification for the API.
 	jsCmd.Flags().BoolVar(&noexample, "noexample", false, `Skip generation of example HTML and controller`)
 	rootCmd.AddCommand(jsCmd)
 
+	// tsCmd implements the "ts" command.
+	tsCmd := &cobra.Command{
+		Use:   "ts",
+		Short: "Generate TypeScript client",
+		Run:   func(c *cobra.Command, _ []string) { files, err = run("gents", c) },
+	}
+	tsCmd.Flags().DurationVar(&timeout, "timeout", timeout, `the duration before the request times out.`)
+	tsCmd.Flags().StringVar(&scheme, "scheme", "", `the URL scheme used to make requests to the API, defaults to the scheme defined in the API design if any.`)
+	tsCmd.Flags().StringVar(&host, "host", "", `the API hostname, defaults to the hostname defined in the API design if any, requests use relative URLs if empty`)
+	rootCmd.AddCommand(tsCmd)
+
 	// schemaCmd implements the "schema" command.
 	schemaCmd := &cobra.Command{
 		Use:   "schema",
@@ -135,6 +166,37 @@ package and tool and the Swagger specification for the API.
 	}
 	rootCmd.AddCommand(schemaCmd)
 
+	// importCmd implements the "import" command.
+	var (
+		spec, target string
+	)
+	importCmd := &cobra.Command{
+		Use:   "import",
+		Short: "Generate design package from Swagger or OpenAPI 3 specification",
+		Run:   func(c *cobra.Command, _ []string) { files, err = runImport(c, spec, target) },
+	}
+	importCmd.Flags().StringVar(&spec, "spec", "", "path to the Swagger 2.0 or OpenAPI 3 specification `file` (JSON or YAML)")
+	importCmd.Flags().StringVar(&target, "pkg", "design", "name of the generated design `package`")
+	rootCmd.AddCommand(importCmd)
+
+	// diffCmd implements the "diff" command.
+	var (
+		oldDesign, newDesign string
+		jsonReport           bool
+	)
+	diffCmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Report changes between two versions of a design",
+		Long: `The diff command compares two design packages and reports the resources, actions, routes,
+parameters, payloads, media type views and validations that were added, removed or changed. The
+command exits with a non-zero status if any of the changes may break existing clients.`,
+		Run: func(c *cobra.Command, _ []string) { err = runDiff(c, oldDesign, newDesign, jsonReport) },
+	}
+	diffCmd.Flags().StringVar(&oldDesign, "old", "", "`import path` of the previous version of the design package")
+	diffCmd.Flags().StringVar(&newDesign, "new", "", "`import path` of the new version of the design package")
+	diffCmd.Flags().BoolVar(&jsonReport, "json", false, "print the report in JSON")
+	rootCmd.AddCommand(diffCmd)
+
 	// genCmd implements the "gen" command.
 	var (
 		pkgPath string
@@ -242,7 +304,9 @@ package and tool and the Swagger specification for the API.
 			rels[i] = f
 		}
 	}
-	fmt.Println(strings.Join(rels, "\n"))
+	if len(rels) > 0 {
+		fmt.Println(strings.Join(rels, "\n"))
+	}
 }
 
 func run(pkg string, c *cobra.Command) ([]string, error) {
@@ -258,6 +322,74 @@ func run(pkg string, c *cobra.Command) ([]string, error) {
 	return generate(pkgName, pkgPath, c, nil)
 }
 
+func runImport(c *cobra.Command, spec, target string) ([]string, error) {
+	outDir, err := filepath.Abs(c.Flag("out").Value.String())
+	if err != nil {
+		return nil, err
+	}
+	imp := importer.NewImporter(importer.Spec(spec), importer.OutDir(outDir), importer.Target(target))
+	files, err := imp.Import()
+	for _, w := range imp.Warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s\n", w)
+	}
+	return files, err
+}
+
+func runDiff(c *cobra.Command, oldDesign, newDesign string, jsonReport bool) error {
+	if oldDesign == "" || newDesign == "" {
+		return fmt.Errorf("both the --old and --new design package import paths are required")
+	}
+	oldSnapshot, err := snapshot(c, oldDesign)
+	if err != nil {
+		return err
+	}
+	newSnapshot, err := snapshot(c, newDesign)
+	if err != nil {
+		return err
+	}
+	report := gendiff.Compare(oldSnapshot, newSnapshot)
+	if jsonReport {
+		b, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(b))
+	} else {
+		fmt.Print(report.String())
+	}
+	if report.HasBreakingChanges() {
+		return fmt.Errorf("%d breaking change(s) found", report.Breaking)
+	}
+	return nil
+}
+
+// snapshot runs the gendiff generator on the given design package and loads the resulting
+// snapshot.
+func snapshot(c *cobra.Command, designPkg string) (*gendiff.Snapshot, error) {
+	outDir, err := ioutil.TempDir("", "goagen-diff")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(outDir)
+	flags := map[string]string{"out": outDir, "design": designPkg}
+	if f := c.Flag("debug"); f != nil && f.Changed {
+		flags["debug"] = f.Value.String()
+	}
+	gen, err := meta.NewGenerator(
+		"gendiff.Generate",
+		[]*codegen.ImportSpec{codegen.SimpleImport("github.com/goadesign/goa/goagen/gen_diff")},
+		flags,
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := gen.Generate(); err != nil {
+		return nil, err
+	}
+	return gendiff.LoadSnapshot(filepath.Join(outDir, gendiff.SnapshotFile))
+}
+
 func runGen(c *cobra.Command, args []string) ([]string, error) {
 	pkgPath := c.Flag("pkg-path").Value.String()
 	pkgSrcPath, err := codegen.PackageSourcePath(pkgPath)
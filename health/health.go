@@ -0,0 +1,194 @@
+/*
+Package health implements the liveness and readiness endpoints of goa services.
+
+Mount registers the /healthz and /readyz endpoints on a service. Both endpoints run the given
+checkers concurrently and respond with a JSON report of their results, /readyz also reports a
+failure once the service starts shutting down so that load balancers stop routing requests to it
+during the drain. The service DrainDelay field should be set to at least the interval at which
+the load balancers poll the readiness endpoint. The endpoints respond with status 200 if all the
+checks succeed and 503 otherwise.
+Usage:
+
+    db := health.NewChecker("db", time.Second, func(ctx context.Context) error {
+        return conn.PingContext(ctx)
+    })
+    health.Mount(service, db)
+    service.DrainDelay = 5 * time.Second
+*/
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/goadesign/goa"
+)
+
+type (
+	// Checker is the interface implemented by the health checkers.
+	Checker interface {
+		// Name identifies the checker in the health report.
+		Name() string
+		// Timeout is the maximum duration of the check. DefaultTimeout is used if
+		// Timeout returns 0.
+		Timeout() time.Duration
+		// Check returns an error if the checked dependency is unhealthy.
+		Check(context.Context) error
+	}
+
+	// Report is the health report written by the health endpoints.
+	Report struct {
+		// Status is StatusOK if all the checks succeeded, StatusFail otherwise.
+		Status string `json:"status"`
+		// Checks lists the results of the individual checks indexed by checker name.
+		Checks map[string]*Result `json:"checks,omitempty"`
+	}
+
+	// Result is the result of a single check.
+	Result struct {
+		// Status is StatusOK if the check succeeded, StatusFail otherwise.
+		Status string `json:"status"`
+		// Error is the reason why the check failed if it did.
+		Error string `json:"error,omitempty"`
+		// Duration is the time it took to run the check.
+		Duration string `json:"duration"`
+	}
+
+	// checker is the Checker implementation returned by NewChecker.
+	checker struct {
+		name    string
+		timeout time.Duration
+		check   func(context.Context) error
+	}
+)
+
+const (
+	// StatusOK is the status of successful checks.
+	StatusOK = "ok"
+	// StatusFail is the status of failed checks.
+	StatusFail = "fail"
+
+	// LivenessPath is the request path of the liveness endpoint.
+	LivenessPath = "/healthz"
+	// ReadinessPath is the request path of the readiness endpoint.
+	ReadinessPath = "/readyz"
+
+	// MediaType is the media type of the health reports.
+	MediaType = "application/vnd.goa.health-report"
+
+	// shutdownCheck is the name of the readiness check that fails during the shutdown drain.
+	shutdownCheck = "shutdown"
+)
+
+// DefaultTimeout is the timeout of the checkers whose Timeout method returns 0.
+var DefaultTimeout = 5 * time.Second
+
+// NewChecker returns a checker with the given name and timeout that calls check.
+func NewChecker(name string, timeout time.Duration, check func(context.Context) error) Checker {
+	return &checker{name: name, timeout: timeout, check: check}
+}
+
+// Mount mounts the liveness and readiness endpoints on the service. The endpoints run the given
+// checkers with the request context rather than the service context so that the checks still run
+// once the service context is canceled by Shutdown.
+func Mount(service *goa.Service, checkers ...Checker) {
+	ctrl := service.NewController("health")
+	liveness := func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		return write(ctx, Check(req.Context(), checkers...))
+	}
+	readiness := func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		report := Check(req.Context(), checkers...)
+		if service.ShuttingDown() {
+			report.Status = StatusFail
+			if report.Checks == nil {
+				report.Checks = make(map[string]*Result)
+			}
+			report.Checks[shutdownCheck] = &Result{Status: StatusFail, Error: "service is shutting down", Duration: "0s"}
+		}
+		return write(ctx, report)
+	}
+	service.Mux.Handle("GET", LivenessPath, ctrl.MuxHandler("healthz", liveness, nil))
+	service.LogInfo("mount", "ctrl", "health", "action", "healthz", "route", "GET "+LivenessPath)
+	service.Mux.Handle("GET", ReadinessPath, ctrl.MuxHandler("readyz", readiness, nil))
+	service.LogInfo("mount", "ctrl", "health", "action", "readyz", "route", "GET "+ReadinessPath)
+}
+
+// Check runs the checkers concurrently and aggregates their results.
+func Check(ctx context.Context, checkers ...Checker) *Report {
+	report := &Report{Status: StatusOK}
+	if len(checkers) == 0 {
+		return report
+	}
+	report.Checks = make(map[string]*Result, len(checkers))
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	for _, c := range checkers {
+		wg.Add(1)
+		go func(c Checker) {
+			defer wg.Done()
+			res := run(ctx, c)
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[c.Name()] = res
+			if res.Status != StatusOK {
+				report.Status = StatusFail
+			}
+		}(c)
+	}
+	wg.Wait()
+	return report
+}
+
+// Name returns the checker name.
+func (c *checker) Name() string { return c.name }
+
+// Timeout returns the checker timeout.
+func (c *checker) Timeout() time.Duration { return c.timeout }
+
+// Check runs the check.
+func (c *checker) Check(ctx context.Context) error { return c.check(ctx) }
+
+// run runs the given checker with its timeout. Checks that do not return by the deadline fail
+// even if they ignore the context.
+func run(ctx context.Context, c Checker) *Result {
+	timeout := c.Timeout()
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	errc := make(chan error, 1)
+	go func() { errc <- c.Check(ctx) }()
+	var err error
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	res := &Result{Status: StatusOK, Duration: time.Since(start).String()}
+	if err != nil {
+		res.Status = StatusFail
+		res.Error = err.Error()
+	}
+	return res
+}
+
+// write writes the report to the response, the status code is 503 if the report status is not
+// StatusOK.
+func write(ctx context.Context, report *Report) error {
+	rw := goa.ContextResponse(ctx)
+	rw.Header().Set("Content-Type", MediaType)
+	rw.Header().Set("Cache-Control", "no-store")
+	status := http.StatusOK
+	if report.Status != StatusOK {
+		status = http.StatusServiceUnavailable
+	}
+	rw.WriteHeader(status)
+	return json.NewEncoder(rw).Encode(report)
+}
@@ -0,0 +1,216 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/goadesign/goa"
+	"github.com/goadesign/goa/health"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Check", func() {
+	var checkers []health.Checker
+	var report *health.Report
+
+	BeforeEach(func() {
+		checkers = nil
+	})
+
+	JustBeforeEach(func() {
+		report = health.Check(context.Background(), checkers...)
+	})
+
+	Context("with no checker", func() {
+		It("reports success", func() {
+			Ω(report.Status).Should(Equal(health.StatusOK))
+			Ω(report.Checks).Should(BeEmpty())
+		})
+	})
+
+	Context("with successful checkers", func() {
+		BeforeEach(func() {
+			checkers = []health.Checker{
+				health.NewChecker("db", time.Second, func(context.Context) error { return nil }),
+				health.NewChecker("cache", 0, func(context.Context) error { return nil }),
+			}
+		})
+
+		It("reports success", func() {
+			Ω(report.Status).Should(Equal(health.StatusOK))
+			Ω(report.Checks).Should(HaveLen(2))
+			Ω(report.Checks["db"].Status).Should(Equal(health.StatusOK))
+			Ω(report.Checks["db"].Error).Should(BeEmpty())
+			Ω(report.Checks["db"].Duration).ShouldNot(BeEmpty())
+			Ω(report.Checks["cache"].Status).Should(Equal(health.StatusOK))
+		})
+	})
+
+	Context("with a failing checker", func() {
+		BeforeEach(func() {
+			checkers = []health.Checker{
+				health.NewChecker("db", time.Second, func(context.Context) error { return errors.New("connection refused") }),
+				health.NewChecker("cache", time.Second, func(context.Context) error { return nil }),
+			}
+		})
+
+		It("reports the failure", func() {
+			Ω(report.Status).Should(Equal(health.StatusFail))
+			Ω(report.Checks["db"].Status).Should(Equal(health.StatusFail))
+			Ω(report.Checks["db"].Error).Should(Equal("connection refused"))
+			Ω(report.Checks["cache"].Status).Should(Equal(health.StatusOK))
+		})
+	})
+
+	Context("with a checker that times out", func() {
+		BeforeEach(func() {
+			checkers = []health.Checker{
+				health.NewChecker("slow", 10*time.Millisecond, func(context.Context) error {
+					time.Sleep(time.Second)
+					return nil
+				}),
+			}
+		})
+
+		It("reports the failure without waiting for the checker", func() {
+			Ω(report.Status).Should(Equal(health.StatusFail))
+			Ω(report.Checks["slow"].Error).Should(Equal(context.DeadlineExceeded.Error()))
+		})
+	})
+})
+
+var _ = Describe("Mount", func() {
+	var service *goa.Service
+	var checkErr error
+	var path string
+	var rw *httptest.ResponseRecorder
+	var report health.Report
+
+	BeforeEach(func() {
+		service = goa.New("test")
+		service.WithLogger(goa.NewLogger(log.New(ioutil.Discard, "", 0)))
+		checkErr = nil
+		health.Mount(service, health.NewChecker("db", time.Second, func(context.Context) error {
+			return checkErr
+		}))
+	})
+
+	JustBeforeEach(func() {
+		rw = httptest.NewRecorder()
+		service.Mux.ServeHTTP(rw, httptest.NewRequest("GET", path, nil))
+		report = health.Report{}
+		Ω(json.Unmarshal(rw.Body.Bytes(), &report)).ShouldNot(HaveOccurred())
+	})
+
+	for _, p := range []string{health.LivenessPath, health.ReadinessPath} {
+		p := p
+
+		Context("requesting "+p, func() {
+			BeforeEach(func() {
+				path = p
+			})
+
+			It("responds with the report", func() {
+				Ω(rw.Code).Should(Equal(http.StatusOK))
+				Ω(rw.Header().Get("Content-Type")).Should(Equal(health.MediaType))
+				Ω(report.Status).Should(Equal(health.StatusOK))
+				Ω(report.Checks).Should(HaveKey("db"))
+			})
+
+			Context("with a failing check", func() {
+				BeforeEach(func() {
+					checkErr = errors.New("connection refused")
+				})
+
+				It("responds with 503", func() {
+					Ω(rw.Code).Should(Equal(http.StatusServiceUnavailable))
+					Ω(report.Status).Should(Equal(health.StatusFail))
+					Ω(report.Checks["db"].Error).Should(Equal("connection refused"))
+				})
+			})
+		})
+	}
+
+	Context("during the shutdown drain", func() {
+		BeforeEach(func() {
+			Ω(service.Shutdown(context.Background())).ShouldNot(HaveOccurred())
+		})
+
+		Context("requesting the liveness endpoint", func() {
+			BeforeEach(func() {
+				path = health.LivenessPath
+			})
+
+			It("reports success", func() {
+				Ω(rw.Code).Should(Equal(http.StatusOK))
+			})
+		})
+
+		Context("requesting the readiness endpoint", func() {
+			BeforeEach(func() {
+				path = health.ReadinessPath
+			})
+
+			It("reports a failure", func() {
+				Ω(rw.Code).Should(Equal(http.StatusServiceUnavailable))
+				Ω(report.Status).Should(Equal(health.StatusFail))
+				Ω(report.Checks).Should(HaveKey("shutdown"))
+			})
+		})
+	})
+})
+
+var _ = Describe("Readiness endpoint", func() {
+	var service *goa.Service
+	var addr string
+	var served chan error
+
+	BeforeEach(func() {
+		service = goa.New("test")
+		service.WithLogger(goa.NewLogger(log.New(ioutil.Discard, "", 0)))
+		service.DrainDelay = 200 * time.Millisecond
+		health.Mount(service)
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		Ω(err).ShouldNot(HaveOccurred())
+		addr = l.Addr().String()
+		served = make(chan error, 1)
+		go func() { served <- service.Serve(l) }()
+	})
+
+	AfterEach(func() {
+		Ω(service.Shutdown(context.Background())).ShouldNot(HaveOccurred())
+		Eventually(served).Should(Receive(BeNil()))
+	})
+
+	get := func(path string) (int, *health.Report) {
+		c := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+		resp, err := c.Get("http://" + addr + path)
+		Ω(err).ShouldNot(HaveOccurred())
+		defer resp.Body.Close()
+		var report health.Report
+		Ω(json.NewDecoder(resp.Body).Decode(&report)).ShouldNot(HaveOccurred())
+		return resp.StatusCode, &report
+	}
+
+	It("reports a failure over the listener during the shutdown drain", func() {
+		code, _ := get(health.ReadinessPath)
+		Ω(code).Should(Equal(http.StatusOK))
+
+		go service.Shutdown(context.Background())
+		Eventually(service.ShuttingDown).Should(BeTrue())
+
+		code, report := get(health.ReadinessPath)
+		Ω(code).Should(Equal(http.StatusServiceUnavailable))
+		Ω(report.Checks).Should(HaveKey("shutdown"))
+		code, _ = get(health.LivenessPath)
+		Ω(code).Should(Equal(http.StatusOK))
+	})
+})
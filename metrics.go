@@ -3,7 +3,9 @@
 package goa
 
 import (
+	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -40,6 +42,24 @@ type Collector interface {
 	SetGauge(key []string, val float32)
 }
 
+// LabeledCollector is the interface implemented by the collectors that support labels such as
+// *metrics.Metrics. Labeled metrics are flattened into the metric key when the collector does not
+// implement LabeledCollector.
+type LabeledCollector interface {
+	Collector
+	AddSampleWithLabels(key []string, val float32, labels []metrics.Label)
+	IncrCounterWithLabels(key []string, val float32, labels []metrics.Label)
+	MeasureSinceWithLabels(key []string, start time.Time, labels []metrics.Label)
+	SetGaugeWithLabels(key []string, val float32, labels []metrics.Label)
+}
+
+// actionMetrics records the metrics of the requests handled by a controller action.
+type actionMetrics struct {
+	labels   []metrics.Label
+	lock     sync.Mutex
+	inFlight int
+}
+
 func init() {
 	SetMetrics(NewNoOpCollector())
 }
@@ -53,6 +73,11 @@ func (*noOpCollecter) IncrCounter(key []string, val float32)      {}
 func (*noOpCollecter) MeasureSince(key []string, start time.Time) {}
 func (*noOpCollecter) SetGauge(key []string, val float32)         {}
 
+func (*noOpCollecter) AddSampleWithLabels(key []string, val float32, labels []metrics.Label)        {}
+func (*noOpCollecter) IncrCounterWithLabels(key []string, val float32, labels []metrics.Label)      {}
+func (*noOpCollecter) MeasureSinceWithLabels(key []string, start time.Time, labels []metrics.Label) {}
+func (*noOpCollecter) SetGaugeWithLabels(key []string, val float32, labels []metrics.Label)         {}
+
 // NewNoOpCollector returns a Collector that does no collection.
 func NewNoOpCollector() Collector {
 	return &noOpCollecter{}
@@ -148,6 +173,111 @@ func SetGauge(key []string, val float32) {
 	GetMetrics().SetGauge(key, val)
 }
 
+// AddSampleWithLabels adds a sample with the given labels to an aggregated metric
+// Usage:
+//     AddSampleWithLabels([]string{"my","namespace","key"}, 15.0, []metrics.Label{{Name: "action", Value: "show"}})
+func AddSampleWithLabels(key []string, val float32, labels []metrics.Label) {
+	normalizeKeys(key)
+
+	if m, ok := GetMetrics().(LabeledCollector); ok {
+		m.AddSampleWithLabels(key, val, labels)
+		return
+	}
+	GetMetrics().AddSample(flattenLabels(key, labels), val)
+}
+
+// IncrCounterWithLabels increments the counter named by `key` with the given labels
+// Usage:
+//     IncrCounterWithLabels([]string{"my","namespace","counter"}, 1.0, []metrics.Label{{Name: "action", Value: "show"}})
+func IncrCounterWithLabels(key []string, val float32, labels []metrics.Label) {
+	normalizeKeys(key)
+
+	if m, ok := GetMetrics().(LabeledCollector); ok {
+		m.IncrCounterWithLabels(key, val, labels)
+		return
+	}
+	GetMetrics().IncrCounter(flattenLabels(key, labels), val)
+}
+
+// MeasureSinceWithLabels creates a timing metric with the given labels that records
+// the duration of elapsed time since `start`
+// Usage:
+//     defer MeasureSinceWithLabels([]string{"my","namespace","action"}, time.Now(), labels)
+func MeasureSinceWithLabels(key []string, start time.Time, labels []metrics.Label) {
+	normalizeKeys(key)
+
+	if m, ok := GetMetrics().(LabeledCollector); ok {
+		m.MeasureSinceWithLabels(key, start, labels)
+		return
+	}
+	GetMetrics().MeasureSince(flattenLabels(key, labels), start)
+}
+
+// SetGaugeWithLabels sets the named gauge with the given labels to the specified value
+// Usage:
+//     SetGaugeWithLabels([]string{"my","namespace"}, 2.0, []metrics.Label{{Name: "action", Value: "show"}})
+func SetGaugeWithLabels(key []string, val float32, labels []metrics.Label) {
+	normalizeKeys(key)
+
+	if m, ok := GetMetrics().(LabeledCollector); ok {
+		m.SetGaugeWithLabels(key, val, labels)
+		return
+	}
+	GetMetrics().SetGauge(flattenLabels(key, labels), val)
+}
+
+// newActionMetrics returns the metrics recorder for the given controller action.
+func newActionMetrics(ctrl, action string) *actionMetrics {
+	return &actionMetrics{labels: []metrics.Label{
+		{Name: "controller", Value: ctrl},
+		{Name: "action", Value: action},
+	}}
+}
+
+// start records the start of a request and returns the function that records its completion.
+// The recorded metrics are:
+//
+//     goa.request.count     counter of handled requests by controller, action and status
+//     goa.request.duration  request latency by controller, action and status
+//     goa.request.inflight  gauge of the requests being handled by controller and action
+//     goa.request.size      request body size by controller and action
+//     goa.response.size     response body size by controller, action and status
+func (m *actionMetrics) start(req *http.Request) func(*ResponseData) {
+	start := time.Now()
+	m.setInFlight(1)
+	if req.ContentLength >= 0 {
+		AddSampleWithLabels([]string{"goa", "request", "size"}, float32(req.ContentLength), m.labels)
+	}
+	return func(resp *ResponseData) {
+		m.setInFlight(-1)
+		labels := append(m.labels[:len(m.labels):len(m.labels)], metrics.Label{Name: "status", Value: strconv.Itoa(resp.Status)})
+		IncrCounterWithLabels([]string{"goa", "request", "count"}, 1.0, labels)
+		MeasureSinceWithLabels([]string{"goa", "request", "duration"}, start, labels)
+		AddSampleWithLabels([]string{"goa", "response", "size"}, float32(resp.Length), labels)
+	}
+}
+
+// setInFlight updates the number of requests in flight and the corresponding gauge. The lock
+// guarantees that the gauge is set in the same order as the count is updated.
+func (m *actionMetrics) setInFlight(delta int) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.inFlight += delta
+	SetGaugeWithLabels([]string{"goa", "request", "inflight"}, float32(m.inFlight), m.labels)
+}
+
+// flattenLabels appends the normalized label values to the key for collectors that do not support
+// labels.
+func flattenLabels(key []string, labels []metrics.Label) []string {
+	flat := make([]string, len(key), len(key)+len(labels))
+	copy(flat, key)
+	for _, l := range labels {
+		flat = append(flat, l.Value)
+	}
+	normalizeKeys(flat)
+	return flat
+}
+
 // This function is used to make metric names safe for all metric services. Specifically, prometheus does
 // not support * or / in metric names.
 func normalizeKeys(key []string) {
@@ -3,6 +3,7 @@
 package goa
 
 import (
+	"net/http"
 	"time"
 )
 
@@ -15,3 +16,16 @@ func IncrCounter(key []string, val float32) {
 func MeasureSince(key []string, start time.Time) {
 	// Do nothing
 }
+
+// actionMetrics does nothing as metrics are not supported.
+type actionMetrics struct{}
+
+// newActionMetrics returns a no-op metrics recorder.
+func newActionMetrics(ctrl, action string) *actionMetrics {
+	return &actionMetrics{}
+}
+
+// start does nothing.
+func (m *actionMetrics) start(req *http.Request) func(*ResponseData) {
+	return func(*ResponseData) {}
+}
@@ -0,0 +1,285 @@
+// +build !js,!appengine
+
+package goa
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/armon/go-metrics"
+)
+
+// MetricsPath is the default request path of the metrics endpoint mounted by MountMetrics.
+const MetricsPath = "/metrics"
+
+// PrometheusContentType is the content type of the Prometheus text exposition format.
+const PrometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var (
+	// DefaultBuckets are the default upper bounds of the histogram buckets of the
+	// PrometheusSink samples. Timings are reported in milliseconds.
+	DefaultBuckets = []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+	// DefaultSizeBuckets are the default upper bounds of the histogram buckets of the
+	// PrometheusSink samples whose name ends with "_size". Sizes are reported in bytes.
+	DefaultSizeBuckets = []float64{64, 256, 1024, 4096, 16384, 65536, 262144, 1048576, 4194304}
+
+	// invalidPrometheusRE matches the characters that are not valid in Prometheus metric names.
+	invalidPrometheusRE = regexp.MustCompile(`[^a-zA-Z0-9_:]`)
+)
+
+type (
+	// PrometheusSink is a metrics.MetricSink that keeps the metrics in memory and exposes
+	// them in the Prometheus text format. Counters and gauges are exposed as such, samples
+	// (including timings) are exposed as histograms.
+	// Usage:
+	//
+	//	sink := goa.NewPrometheusSink()
+	//	conf := metrics.DefaultConfig("cellar")
+	//	conf.EnableHostname = false
+	//	m, _ := metrics.New(conf, sink)
+	//	goa.SetMetrics(m)
+	//	service.MountMetrics(goa.MetricsPath, sink)
+	PrometheusSink struct {
+		// Buckets are the upper bounds of the histogram buckets.
+		Buckets []float64
+		// SizeBuckets are the upper bounds of the histogram buckets of the metrics whose
+		// name ends with "_size".
+		SizeBuckets []float64
+
+		lock     sync.Mutex
+		families map[string]*promFamily
+	}
+
+	// promFamily is a metric and all its labeled series.
+	promFamily struct {
+		typ    string
+		series map[string]*promSeries
+	}
+
+	// promSeries is a single labeled series of a metric.
+	promSeries struct {
+		labels  string
+		value   float64
+		buckets []float64
+		counts  []uint64
+		sum     float64
+		count   uint64
+	}
+)
+
+// NewPrometheusSink returns a sink that uses the default histogram buckets.
+func NewPrometheusSink() *PrometheusSink {
+	return &PrometheusSink{
+		Buckets:     DefaultBuckets,
+		SizeBuckets: DefaultSizeBuckets,
+		families:    make(map[string]*promFamily),
+	}
+}
+
+// SetGauge implements metrics.MetricSink.
+func (s *PrometheusSink) SetGauge(key []string, val float32) {
+	s.SetGaugeWithLabels(key, val, nil)
+}
+
+// SetGaugeWithLabels implements metrics.MetricSink.
+func (s *PrometheusSink) SetGaugeWithLabels(key []string, val float32, labels []metrics.Label) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if ser := s.series("gauge", key, labels); ser != nil {
+		ser.value = float64(val)
+	}
+}
+
+// EmitKey implements metrics.MetricSink, the values are exposed as gauges.
+func (s *PrometheusSink) EmitKey(key []string, val float32) {
+	s.SetGaugeWithLabels(key, val, nil)
+}
+
+// IncrCounter implements metrics.MetricSink.
+func (s *PrometheusSink) IncrCounter(key []string, val float32) {
+	s.IncrCounterWithLabels(key, val, nil)
+}
+
+// IncrCounterWithLabels implements metrics.MetricSink.
+func (s *PrometheusSink) IncrCounterWithLabels(key []string, val float32, labels []metrics.Label) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if ser := s.series("counter", key, labels); ser != nil {
+		ser.value += float64(val)
+	}
+}
+
+// AddSample implements metrics.MetricSink.
+func (s *PrometheusSink) AddSample(key []string, val float32) {
+	s.AddSampleWithLabels(key, val, nil)
+}
+
+// AddSampleWithLabels implements metrics.MetricSink.
+func (s *PrometheusSink) AddSampleWithLabels(key []string, val float32, labels []metrics.Label) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	ser := s.series("histogram", key, labels)
+	if ser == nil {
+		return
+	}
+	v := float64(val)
+	for i, b := range ser.buckets {
+		if v <= b {
+			ser.counts[i]++
+		}
+	}
+	ser.sum += v
+	ser.count++
+}
+
+// WriteTo writes the metrics in the Prometheus text format.
+func (s *PrometheusSink) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	s.lock.Lock()
+	names := make([]string, 0, len(s.families))
+	for name := range s.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		f := s.families[name]
+		fmt.Fprintf(&buf, "# TYPE %s %s\n", name, f.typ)
+		keys := make([]string, 0, len(f.series))
+		for k := range f.series {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			ser := f.series[k]
+			if f.typ != "histogram" {
+				fmt.Fprintf(&buf, "%s%s %s\n", name, braceLabels(ser.labels), formatSample(ser.value))
+				continue
+			}
+			for i, b := range ser.buckets {
+				le := fmt.Sprintf("le=%q", formatSample(b))
+				fmt.Fprintf(&buf, "%s_bucket%s %d\n", name, braceLabels(joinLabels(ser.labels, le)), ser.counts[i])
+			}
+			fmt.Fprintf(&buf, "%s_bucket%s %d\n", name, braceLabels(joinLabels(ser.labels, `le="+Inf"`)), ser.count)
+			fmt.Fprintf(&buf, "%s_sum%s %s\n", name, braceLabels(ser.labels), formatSample(ser.sum))
+			fmt.Fprintf(&buf, "%s_count%s %d\n", name, braceLabels(ser.labels), ser.count)
+		}
+	}
+	s.lock.Unlock()
+	return buf.WriteTo(w)
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format to the response.
+func (s *PrometheusSink) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	rw.Header().Set("Content-Type", PrometheusContentType)
+	s.WriteTo(rw)
+}
+
+// MountMetrics mounts an endpoint that serves the metrics collected by sink in the Prometheus
+// text format under the given path, MetricsPath if path is empty. The sink must be the sink of
+// the collector given to SetMetrics.
+func (service *Service) MountMetrics(path string, sink *PrometheusSink) {
+	if path == "" {
+		path = MetricsPath
+	}
+	ctrl := service.NewController("metrics")
+	handler := func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		sink.ServeHTTP(rw, req)
+		return nil
+	}
+	service.Mux.Handle("GET", path, ctrl.MuxHandler("serve", handler, nil))
+	service.LogInfo("mount", "ctrl", "metrics", "action", "serve", "route", "GET "+path)
+}
+
+// series returns the series of the given metric, creating it if needed. It returns nil if the
+// metric was created with a different type.
+func (s *PrometheusSink) series(typ string, key []string, labels []metrics.Label) *promSeries {
+	if s.families == nil {
+		s.families = make(map[string]*promFamily)
+	}
+	name := prometheusName(key)
+	f, ok := s.families[name]
+	if !ok {
+		f = &promFamily{typ: typ, series: make(map[string]*promSeries)}
+		s.families[name] = f
+	}
+	if f.typ != typ {
+		return nil
+	}
+	ls := prometheusLabels(labels)
+	ser, ok := f.series[ls]
+	if !ok {
+		ser = &promSeries{labels: ls}
+		if typ == "histogram" {
+			ser.buckets = s.Buckets
+			if strings.HasSuffix(name, "_size") {
+				ser.buckets = s.SizeBuckets
+			}
+			ser.counts = make([]uint64, len(ser.buckets))
+		}
+		f.series[ls] = ser
+	}
+	return ser
+}
+
+// prometheusName returns a valid Prometheus metric name built from the key.
+func prometheusName(key []string) string {
+	name := invalidPrometheusRE.ReplaceAllString(strings.Join(key, "_"), "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
+// prometheusLabels returns the Prometheus representation of the labels sorted by name.
+func prometheusLabels(labels []metrics.Label) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	elems := make([]string, len(labels))
+	for i, l := range labels {
+		name := strings.Replace(prometheusName([]string{l.Name}), ":", "_", -1)
+		elems[i] = name + `="` + escapeLabelValue(l.Value) + `"`
+	}
+	sort.Strings(elems)
+	return strings.Join(elems, ",")
+}
+
+// escapeLabelValue escapes backslashes, double quotes and line feeds.
+func escapeLabelValue(v string) string {
+	v = strings.Replace(v, `\`, `\\`, -1)
+	v = strings.Replace(v, `"`, `\"`, -1)
+	return strings.Replace(v, "\n", `\n`, -1)
+}
+
+// formatSample formats a sample value.
+func formatSample(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// joinLabels joins the non-empty label strings.
+func joinLabels(labels ...string) string {
+	var elems []string
+	for _, l := range labels {
+		if l != "" {
+			elems = append(elems, l)
+		}
+	}
+	return strings.Join(elems, ",")
+}
+
+// braceLabels wraps non-empty labels in curly braces.
+func braceLabels(labels string) string {
+	if labels == "" {
+		return ""
+	}
+	return "{" + labels + "}"
+}
@@ -0,0 +1,127 @@
+package goa_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/armon/go-metrics"
+	"github.com/goadesign/goa"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("PrometheusSink", func() {
+	var sink *goa.PrometheusSink
+	var output string
+
+	BeforeEach(func() {
+		sink = goa.NewPrometheusSink()
+		sink.Buckets = []float64{1, 10}
+	})
+
+	JustBeforeEach(func() {
+		var buf bytes.Buffer
+		_, err := sink.WriteTo(&buf)
+		Ω(err).ShouldNot(HaveOccurred())
+		output = buf.String()
+	})
+
+	Context("with counters and gauges", func() {
+		BeforeEach(func() {
+			sink.IncrCounterWithLabels([]string{"req", "count"}, 1, []metrics.Label{{Name: "status", Value: "200"}})
+			sink.IncrCounterWithLabels([]string{"req", "count"}, 2, []metrics.Label{{Name: "status", Value: "200"}})
+			sink.SetGauge([]string{"in.flight"}, 3)
+			sink.SetGauge([]string{"in.flight"}, 4)
+		})
+
+		It("writes the current values", func() {
+			Ω(output).Should(Equal(strings.Join([]string{
+				"# TYPE in_flight gauge",
+				"in_flight 4",
+				"# TYPE req_count counter",
+				`req_count{status="200"} 3`,
+				"",
+			}, "\n")))
+		})
+	})
+
+	Context("with samples", func() {
+		BeforeEach(func() {
+			labels := []metrics.Label{{Name: "b", Value: "2"}, {Name: "a", Value: `q"\`}}
+			sink.AddSampleWithLabels([]string{"latency"}, 0.5, labels)
+			sink.AddSampleWithLabels([]string{"latency"}, 5, labels)
+			sink.AddSampleWithLabels([]string{"latency"}, 50, labels)
+		})
+
+		It("writes histograms", func() {
+			Ω(output).Should(Equal(strings.Join([]string{
+				"# TYPE latency histogram",
+				`latency_bucket{a="q\"\\",b="2",le="1"} 1`,
+				`latency_bucket{a="q\"\\",b="2",le="10"} 2`,
+				`latency_bucket{a="q\"\\",b="2",le="+Inf"} 3`,
+				`latency_sum{a="q\"\\",b="2"} 55.5`,
+				`latency_count{a="q\"\\",b="2"} 3`,
+				"",
+			}, "\n")))
+		})
+	})
+
+	Context("with a metric used with different types", func() {
+		BeforeEach(func() {
+			sink.IncrCounter([]string{"m"}, 1)
+			sink.SetGauge([]string{"m"}, 5)
+		})
+
+		It("keeps the first type", func() {
+			Ω(output).Should(Equal("# TYPE m counter\nm 1\n"))
+		})
+	})
+})
+
+var _ = Describe("MountMetrics", func() {
+	var service *goa.Service
+	var sink *goa.PrometheusSink
+
+	BeforeEach(func() {
+		service = goa.New("test")
+		service.WithLogger(goa.NewLogger(log.New(ioutil.Discard, "", 0)))
+		sink = goa.NewPrometheusSink()
+		conf := metrics.DefaultConfig("test")
+		conf.EnableHostname = false
+		conf.EnableRuntimeMetrics = false
+		metriks, err := metrics.New(conf, sink)
+		Ω(err).ShouldNot(HaveOccurred())
+		goa.SetMetrics(metriks)
+		ctrl := service.NewController("bottles")
+		service.Mux.Handle("GET", "/bottles", ctrl.MuxHandler("list", func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			rw.WriteHeader(200)
+			_, err := rw.Write([]byte("hello"))
+			return err
+		}, nil))
+		service.MountMetrics("", sink)
+	})
+
+	AfterEach(func() {
+		goa.SetMetrics(goa.NewNoOpCollector())
+	})
+
+	It("serves the request metrics", func() {
+		service.Mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/bottles", nil))
+		rw := httptest.NewRecorder()
+		service.Mux.ServeHTTP(rw, httptest.NewRequest("GET", goa.MetricsPath, nil))
+		Ω(rw.Code).Should(Equal(200))
+		Ω(rw.Header().Get("Content-Type")).Should(Equal(goa.PrometheusContentType))
+		body := rw.Body.String()
+		labels := `action="list",controller="bottles"`
+		Ω(body).Should(ContainSubstring(`test_goa_request_count{` + labels + `,status="200"} 1`))
+		Ω(body).Should(ContainSubstring(`test_goa_request_duration_count{` + labels + `,status="200"} 1`))
+		Ω(body).Should(ContainSubstring(`test_goa_request_inflight{` + labels + `} 0`))
+		Ω(body).Should(ContainSubstring(`test_goa_response_size_sum{` + labels + `,status="200"} 5`))
+		Ω(body).Should(ContainSubstring(`test_goa_request_inflight{action="serve",controller="metrics"} 1`))
+	})
+})
@@ -1,6 +1,7 @@
 package goa_test
 
 import (
+	"bytes"
 	"time"
 
 	"github.com/goadesign/goa"
@@ -121,3 +122,62 @@ var _ = Describe("Metrics", func() {
 		})
 	})
 })
+
+var _ = Describe("Labeled metrics", func() {
+	var labels []metrics.Label
+
+	BeforeEach(func() {
+		labels = []metrics.Label{{Name: "action", Value: "*/*"}}
+	})
+
+	AfterEach(func() {
+		goa.SetMetrics(goa.NewNoOpCollector())
+	})
+
+	Context("with a collector that supports labels", func() {
+		var sink *goa.PrometheusSink
+
+		BeforeEach(func() {
+			sink = goa.NewPrometheusSink()
+			conf := metrics.DefaultConfig("test")
+			conf.EnableHostname = false
+			conf.EnableRuntimeMetrics = false
+			metriks, err := metrics.New(conf, sink)
+			Ω(err).ShouldNot(HaveOccurred())
+			goa.SetMetrics(metriks)
+		})
+
+		It("keeps the labels", func() {
+			goa.IncrCounterWithLabels([]string{"foo", "bar"}, 1.0, labels)
+			var buf bytes.Buffer
+			sink.WriteTo(&buf)
+			Ω(buf.String()).Should(ContainSubstring(`test_foo_bar{action="*/*"} 1`))
+		})
+	})
+
+	Context("with a collector that does not support labels", func() {
+		var collector *keyCollector
+
+		BeforeEach(func() {
+			collector = &keyCollector{}
+			goa.SetMetrics(collector)
+		})
+
+		It("flattens the labels into the key", func() {
+			goa.IncrCounterWithLabels([]string{"foo", "bar"}, 1.0, labels)
+			goa.AddSampleWithLabels([]string{"foo", "baz"}, 1.0, labels)
+			Ω(collector.keys).Should(Equal([][]string{{"foo", "bar", "all"}, {"foo", "baz", "all"}}))
+		})
+	})
+})
+
+// keyCollector is a Collector that does not support labels and records the keys.
+type keyCollector struct {
+	keys [][]string
+}
+
+func (c *keyCollector) AddSample(key []string, val float32)        { c.keys = append(c.keys, key) }
+func (c *keyCollector) EmitKey(key []string, val float32)          { c.keys = append(c.keys, key) }
+func (c *keyCollector) IncrCounter(key []string, val float32)      { c.keys = append(c.keys, key) }
+func (c *keyCollector) MeasureSince(key []string, start time.Time) { c.keys = append(c.keys, key) }
+func (c *keyCollector) SetGauge(key []string, val float32)         { c.keys = append(c.keys, key) }
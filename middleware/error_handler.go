@@ -23,6 +23,9 @@ func ErrorHandler(service *goa.Service, verbose bool) goa.Middleware {
 				return nil
 			}
 			cause := cause(e)
+			if s := ContextSpan(ctx); s != nil {
+				s.RecordError(e)
+			}
 			status := http.StatusInternalServerError
 			var respBody interface{}
 			if err, ok := cause.(goa.ServiceError); ok {
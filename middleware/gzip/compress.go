@@ -0,0 +1,196 @@
+package gzip
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Content codings supported by the Compress middleware.
+const (
+	// EncodingGzip is the gzip content coding (RFC 1952).
+	EncodingGzip = "gzip"
+	// EncodingDeflate is the deflate content coding, the zlib format (RFC 1950).
+	EncodingDeflate = "deflate"
+	// EncodingBrotli is the brotli content coding (RFC 7932).
+	EncodingBrotli = "br"
+	// EncodingZstd is the zstd content coding (RFC 8878).
+	EncodingZstd = "zstd"
+)
+
+// compressor is implemented by the writers of all the supported content codings.
+type compressor interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+// poolKey identifies a pool of writers.
+type poolKey struct {
+	encoding string
+	level    int
+}
+
+// pools holds the writer pools indexed by encoding and level so that middlewares
+// configured identically share the same writers.
+var pools = struct {
+	sync.Mutex
+	m map[poolKey]*sync.Pool
+}{m: make(map[poolKey]*sync.Pool)}
+
+// defaultEncodings is the default list of content codings used by Compress in
+// order of preference.
+var defaultEncodings = []string{EncodingBrotli, EncodingZstd, EncodingGzip, EncodingDeflate}
+
+// defaultLevels are the compression levels used when none is given with Level.
+var defaultLevels = map[string]int{
+	EncodingGzip:    gzip.DefaultCompression,
+	EncodingDeflate: zlib.DefaultCompression,
+	EncodingBrotli:  brotli.DefaultCompression,
+	EncodingZstd:    3,
+}
+
+// Encodings sets the content codings used to compress responses in order of
+// preference. Overrides previous encodings.
+func Encodings(encodings ...string) Option {
+	return func(c *options) error {
+		if len(encodings) == 0 {
+			return fmt.Errorf("gzip: no encoding")
+		}
+		for _, enc := range encodings {
+			if _, ok := defaultLevels[enc]; !ok {
+				return fmt.Errorf("gzip: unsupported encoding %q", enc)
+			}
+		}
+		c.encodings = encodings
+		return nil
+	}
+}
+
+// Level sets the compression level of the given content coding. Valid levels
+// range from -2 (Huffman only) to 9 for gzip and deflate, 0 to 11 for brotli
+// and 1 to 22 for zstd.
+func Level(encoding string, level int) Option {
+	return func(c *options) error {
+		min, max := gzip.HuffmanOnly, gzip.BestCompression
+		switch encoding {
+		case EncodingGzip, EncodingDeflate:
+		case EncodingBrotli:
+			min, max = brotli.BestSpeed, brotli.BestCompression
+		case EncodingZstd:
+			min, max = 1, 22
+		default:
+			return fmt.Errorf("gzip: unsupported encoding %q", encoding)
+		}
+		if level < min || level > max {
+			return fmt.Errorf("gzip: invalid %s compression level %d", encoding, level)
+		}
+		c.levels[encoding] = level
+		return nil
+	}
+}
+
+// writerPool returns the pool of writers for the given encoding and level.
+func writerPool(encoding string, level int) *sync.Pool {
+	pools.Lock()
+	defer pools.Unlock()
+	key := poolKey{encoding, level}
+	if p, ok := pools.m[key]; ok {
+		return p
+	}
+	p := &sync.Pool{
+		New: func() interface{} {
+			cw, err := newCompressor(encoding, level)
+			if err != nil {
+				panic(err)
+			}
+			return cw
+		},
+	}
+	pools.m[key] = p
+	return p
+}
+
+// newCompressor creates a writer for the given encoding and level.
+func newCompressor(encoding string, level int) (compressor, error) {
+	switch encoding {
+	case EncodingGzip:
+		return gzip.NewWriterLevel(ioutil.Discard, level)
+	case EncodingDeflate:
+		return zlib.NewWriterLevel(ioutil.Discard, level)
+	case EncodingBrotli:
+		return brotli.NewWriterLevel(ioutil.Discard, level), nil
+	case EncodingZstd:
+		return zstd.NewWriter(nil,
+			zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)),
+			zstd.WithEncoderConcurrency(1))
+	}
+	return nil, fmt.Errorf("gzip: unsupported encoding %q", encoding)
+}
+
+// negotiate returns the content coding to use given the value of the request
+// Accept-Encoding header or the empty string if none of the configured
+// encodings is acceptable.
+func (o options) negotiate(acceptEncoding string) string {
+	if acceptEncoding == "" {
+		return ""
+	}
+	var (
+		qvalues  = make(map[string]float64)
+		wildcard = -1.0
+	)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, q := parseCoding(part)
+		switch coding {
+		case "":
+			continue
+		case "*":
+			wildcard = q
+		case "x-gzip":
+			qvalues[EncodingGzip] = q
+		default:
+			qvalues[coding] = q
+		}
+	}
+	var (
+		best  string
+		bestQ float64
+	)
+	for _, enc := range o.encodings {
+		q, ok := qvalues[enc]
+		if !ok {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = enc, q
+		}
+	}
+	return best
+}
+
+// parseCoding parses one element of the Accept-Encoding header and returns the
+// lowercase coding and its quality value.
+func parseCoding(s string) (string, float64) {
+	params := strings.Split(s, ";")
+	coding := strings.ToLower(strings.TrimSpace(params[0]))
+	q := 1.0
+	for _, p := range params[1:] {
+		p = strings.TrimSpace(p)
+		if len(p) < 2 || (p[0] != 'q' && p[0] != 'Q') || p[1] != '=' {
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(p[2:]), 64)
+		if err != nil || v < 0 || v > 1 {
+			return coding, 0
+		}
+		q = v
+	}
+	return coding, q
+}
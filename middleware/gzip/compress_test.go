@@ -0,0 +1,158 @@
+package gzip_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/goadesign/goa"
+	gzm "github.com/goadesign/goa/middleware/gzip"
+	"github.com/klauspost/compress/zstd"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Compress", func() {
+	var (
+		acceptEncoding string
+		options        []gzm.Option
+
+		rw  *TestResponseWriter
+		ctx context.Context
+	)
+
+	body := strings.Repeat("compress me! ", 100)
+
+	decompress := func(encoding string, b []byte) string {
+		var (
+			r   io.Reader
+			err error
+		)
+		switch encoding {
+		case "gzip":
+			r, err = gzip.NewReader(bytes.NewReader(b))
+		case "deflate":
+			r, err = zlib.NewReader(bytes.NewReader(b))
+		case "br":
+			r = brotli.NewReader(bytes.NewReader(b))
+		case "zstd":
+			var d *zstd.Decoder
+			d, err = zstd.NewReader(bytes.NewReader(b))
+			r = d
+		default:
+			r = bytes.NewReader(b)
+		}
+		Ω(err).ShouldNot(HaveOccurred())
+		res, err := ioutil.ReadAll(r)
+		Ω(err).ShouldNot(HaveOccurred())
+		return string(res)
+	}
+
+	BeforeEach(func() {
+		acceptEncoding = ""
+		options = nil
+	})
+
+	JustBeforeEach(func() {
+		req, err := http.NewRequest("GET", "/foo/bar", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		rw = &TestResponseWriter{ParentHeader: make(http.Header)}
+		ctx = goa.NewContext(nil, rw, req, nil)
+		h := func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			resp := goa.ContextResponse(ctx)
+			resp.Header().Set("Content-Type", "application/json")
+			resp.WriteHeader(http.StatusOK)
+			resp.Write([]byte(body))
+			return nil
+		}
+		Ω(gzm.Compress(options...)(h)(ctx, rw, req)).ShouldNot(HaveOccurred())
+	})
+
+	encodes := func(header, expected string, opts ...gzm.Option) {
+		Context("with Accept-Encoding "+header, func() {
+			BeforeEach(func() {
+				acceptEncoding = header
+				options = opts
+			})
+
+			It("encodes the response using "+expected, func() {
+				resp := goa.ContextResponse(ctx)
+				Ω(resp.Header().Get("Content-Encoding")).Should(Equal(expected))
+				Ω(resp.Header().Get("Vary")).Should(Equal("Accept-Encoding"))
+				Ω(rw.Status).Should(Equal(http.StatusOK))
+				Ω(decompress(expected, rw.Body)).Should(Equal(body))
+			})
+		})
+	}
+
+	encodes("gzip", "gzip")
+	encodes("x-gzip", "gzip")
+	encodes("deflate", "deflate")
+	encodes("br", "br")
+	encodes("zstd", "zstd")
+	encodes("gzip, deflate, br, zstd", "br")
+	encodes("gzip;q=0.5, deflate;q=0.2, zstd;q=0.8", "zstd")
+	encodes("GZIP; Q=1, br;q=0.9", "gzip")
+	encodes("br;q=0, *;q=0.5", "zstd")
+	encodes("*", "br")
+	encodes("gzip, deflate, br, zstd", "gzip", gzm.Encodings("gzip", "br"))
+	encodes("zstd, br;q=0.9", "zstd", gzm.Level("zstd", 19))
+	encodes("br", "br", gzm.Level("br", 11))
+
+	doesNotEncode := func(header string, opts ...gzm.Option) {
+		Context("with Accept-Encoding "+header, func() {
+			BeforeEach(func() {
+				acceptEncoding = header
+				options = opts
+			})
+
+			It("does not encode the response", func() {
+				resp := goa.ContextResponse(ctx)
+				Ω(resp.Header().Get("Content-Encoding")).Should(BeEmpty())
+				Ω(string(rw.Body)).Should(Equal(body))
+			})
+		})
+	}
+
+	doesNotEncode("")
+	doesNotEncode("identity")
+	doesNotEncode("compress")
+	doesNotEncode("gzip;q=0")
+	doesNotEncode("gzip;q=invalid")
+	doesNotEncode("*;q=0")
+	doesNotEncode("br", gzm.Encodings("gzip"))
+	doesNotEncode("br", gzm.OnlyStatusCodes(http.StatusCreated))
+	doesNotEncode("br", gzm.MinSize(len(body)+1))
+
+	It("weakens strong entity tags", func() {
+		req, _ := http.NewRequest("GET", "/foo/bar", nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		rw := &TestResponseWriter{ParentHeader: make(http.Header)}
+		ctx := goa.NewContext(nil, rw, req, nil)
+		h := func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			resp := goa.ContextResponse(ctx)
+			resp.Header().Set("Content-Type", "application/json")
+			resp.Header().Set("ETag", `"v42"`)
+			resp.Write([]byte(body))
+			return nil
+		}
+		Ω(gzm.Compress()(h)(ctx, rw, req)).ShouldNot(HaveOccurred())
+		Ω(rw.Header().Get("ETag")).Should(Equal(`W/"v42"`))
+	})
+
+	It("rejects invalid options", func() {
+		Ω(func() { gzm.Compress(gzm.Encodings()) }).Should(Panic())
+		Ω(func() { gzm.Compress(gzm.Encodings("compress")) }).Should(Panic())
+		Ω(func() { gzm.Compress(gzm.Level("compress", 1)) }).Should(Panic())
+		Ω(func() { gzm.Compress(gzm.Level("gzip", 10)) }).Should(Panic())
+		Ω(func() { gzm.Compress(gzm.Level("br", 12)) }).Should(Panic())
+		Ω(func() { gzm.Compress(gzm.Level("zstd", 0)) }).Should(Panic())
+	})
+})
@@ -0,0 +1,76 @@
+package gzip
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+
+	"github.com/goadesign/goa"
+)
+
+// Decompress configures the service so that request bodies encoded with gzip or deflate are
+// transparently inflated before the generated unmarshalers decode them. The controller
+// MaxRequestBodyLength applies to the inflated size which protects against decompression bombs.
+// Requests that use other content codings are rejected with goa.ErrUnsupportedMediaType. The
+// bodies of the requests sent to actions that have no payload are left untouched.
+// Usage:
+//
+//     service := goa.New("my api")
+//     gzip.Decompress(service)
+//
+func Decompress(service *goa.Service) {
+	if service.ContentDecoders == nil {
+		service.ContentDecoders = make(map[string]goa.ContentDecoder)
+	}
+	service.ContentDecoders[EncodingGzip] = GzipDecoder
+	service.ContentDecoders["x-gzip"] = GzipDecoder
+	service.ContentDecoders[EncodingDeflate] = DeflateDecoder
+}
+
+// GzipDecoder is the goa.ContentDecoder that inflates gzip encoded request bodies.
+func GzipDecoder(body io.ReadCloser) (io.ReadCloser, error) {
+	r, err := gzip.NewReader(body)
+	if err != nil {
+		return nil, err
+	}
+	return &decodedBody{Reader: r, decoder: r, body: body}, nil
+}
+
+// DeflateDecoder is the goa.ContentDecoder that inflates deflate encoded request bodies. The
+// "deflate" content coding uses the zlib format (RFC 1950) however some clients send raw
+// deflate data (RFC 1951), the decoder accepts both.
+func DeflateDecoder(body io.ReadCloser) (io.ReadCloser, error) {
+	br := bufio.NewReader(body)
+	if header, err := br.Peek(2); err == nil && isZlibHeader(header) {
+		r, err := zlib.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		return &decodedBody{Reader: r, decoder: r, body: body}, nil
+	}
+	r := flate.NewReader(br)
+	return &decodedBody{Reader: r, decoder: r, body: body}, nil
+}
+
+// decodedBody is the reader returned by the content decoders.
+type decodedBody struct {
+	io.Reader
+	decoder io.Closer
+	body    io.Closer
+}
+
+// Close closes both the decoder and the underlying request body.
+func (b *decodedBody) Close() error {
+	err := b.decoder.Close()
+	if cerr := b.body.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// isZlibHeader returns true if the two bytes are a valid zlib header using the deflate method.
+func isZlibHeader(h []byte) bool {
+	return h[0]&0x0f == 8 && (uint16(h[0])<<8|uint16(h[1]))%31 == 0
+}
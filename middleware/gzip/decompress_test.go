@@ -0,0 +1,200 @@
+package gzip_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/goadesign/goa"
+	gzm "github.com/goadesign/goa/middleware/gzip"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Decompress", func() {
+	var (
+		service  *goa.Service
+		maxLen   int64
+		encoding string
+		body     []byte
+		noUnm    bool
+
+		payload interface{}
+		raw     []byte
+		coding  string
+		err     error
+		rw      *httptest.ResponseRecorder
+	)
+
+	compress := func(newWriter func(io.Writer) io.WriteCloser, data string) []byte {
+		var buf bytes.Buffer
+		w := newWriter(&buf)
+		w.Write([]byte(data))
+		w.Close()
+		return buf.Bytes()
+	}
+	gzipWriter := func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }
+	zlibWriter := func(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) }
+	flateWriter := func(w io.Writer) io.WriteCloser {
+		fw, _ := flate.NewWriter(w, flate.DefaultCompression)
+		return fw
+	}
+
+	BeforeEach(func() {
+		service = goa.New("test")
+		service.WithLogger(goa.NewLogger(log.New(ioutil.Discard, "", 0)))
+		service.Decoder.Register(goa.NewJSONDecoder, "*/*")
+		gzm.Decompress(service)
+		maxLen = 0
+		encoding = "gzip"
+		body = compress(gzipWriter, `{"payload":42}`)
+		noUnm = false
+		payload = nil
+		raw = nil
+		coding = ""
+		err = nil
+	})
+
+	JustBeforeEach(func() {
+		ctrl := service.NewController("test")
+		if maxLen > 0 {
+			ctrl.MaxRequestBodyLength = maxLen
+		}
+		unmarshal := func(ctx context.Context, service *goa.Service, req *http.Request) error {
+			var p interface{}
+			if err := service.DecodeRequest(req, &p); err != nil {
+				return err
+			}
+			goa.ContextRequest(ctx).Payload = p
+			return nil
+		}
+		handler := func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			err = goa.ContextError(ctx)
+			payload = goa.ContextRequest(ctx).Payload
+			raw, _ = ioutil.ReadAll(req.Body)
+			coding = req.Header.Get("Content-Encoding")
+			return nil
+		}
+		if noUnm {
+			unmarshal = nil
+		}
+		req := httptest.NewRequest("POST", "/", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", encoding)
+		rw = httptest.NewRecorder()
+		ctrl.MuxHandler("create", handler, unmarshal)(rw, req, nil)
+	})
+
+	It("inflates gzip request bodies", func() {
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(payload).Should(Equal(map[string]interface{}{"payload": 42.0}))
+	})
+
+	Context("with zlib deflate request bodies", func() {
+		BeforeEach(func() {
+			encoding = "deflate"
+			body = compress(zlibWriter, `{"payload":42}`)
+		})
+
+		It("inflates the body", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(payload).Should(Equal(map[string]interface{}{"payload": 42.0}))
+		})
+	})
+
+	Context("with raw deflate request bodies", func() {
+		BeforeEach(func() {
+			encoding = "deflate"
+			body = compress(flateWriter, `{"payload":42}`)
+		})
+
+		It("inflates the body", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(payload).Should(Equal(map[string]interface{}{"payload": 42.0}))
+		})
+	})
+
+	Context("with multiple content codings", func() {
+		BeforeEach(func() {
+			encoding = "deflate, gzip"
+			body = compress(gzipWriter, string(compress(zlibWriter, `{"payload":42}`)))
+		})
+
+		It("decodes the codings in reverse order", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(payload).Should(Equal(map[string]interface{}{"payload": 42.0}))
+		})
+	})
+
+	Context("with an unsupported content coding", func() {
+		BeforeEach(func() {
+			encoding = "br"
+		})
+
+		It("returns a 415 error", func() {
+			Ω(err).Should(HaveOccurred())
+			Ω(err.(goa.ServiceError).ResponseStatus()).Should(Equal(http.StatusUnsupportedMediaType))
+			Ω(payload).Should(BeNil())
+			Ω(rw.Header().Get("Accept-Encoding")).Should(Equal("deflate, gzip, x-gzip"))
+		})
+	})
+
+	Context("with an invalid gzip body", func() {
+		BeforeEach(func() {
+			body = []byte(`{"payload":42}`)
+		})
+
+		It("returns a 400 error", func() {
+			Ω(err).Should(HaveOccurred())
+			Ω(err.(goa.ServiceError).ResponseStatus()).Should(Equal(http.StatusBadRequest))
+		})
+	})
+
+	Context("with an inflated body larger than MaxRequestBodyLength", func() {
+		BeforeEach(func() {
+			maxLen = 100
+			body = compress(gzipWriter, `{"payload":"`+strings.Repeat("a", 10000)+`"}`)
+			Ω(int64(len(body))).Should(BeNumerically("<", maxLen))
+		})
+
+		It("returns a 413 error", func() {
+			Ω(err).Should(HaveOccurred())
+			Ω(err.(goa.ServiceError).ResponseStatus()).Should(Equal(http.StatusRequestEntityTooLarge))
+		})
+	})
+
+	Context("with an action that has no payload", func() {
+		BeforeEach(func() {
+			noUnm = true
+			encoding = "br"
+			body = []byte("raw")
+		})
+
+		It("does not decode content codings", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(raw)).Should(Equal("raw"))
+			Ω(coding).Should(Equal("br"))
+		})
+	})
+
+	Context("with no content decoders", func() {
+		BeforeEach(func() {
+			service.ContentDecoders = nil
+			encoding = "br"
+			body = []byte(`{"payload":42}`)
+		})
+
+		It("does not decode content codings", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(payload).Should(Equal(map[string]interface{}{"payload": 42.0}))
+		})
+	})
+})
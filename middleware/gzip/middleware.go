@@ -2,9 +2,7 @@ package gzip
 
 import (
 	"bytes"
-	"compress/gzip"
 	"context"
-	"io/ioutil"
 	"net/http"
 	"strconv"
 	"strings"
@@ -13,25 +11,24 @@ import (
 	"github.com/goadesign/goa"
 )
 
-// These compression constants are copied from the compress/gzip package.
 const (
-	encodingGzip = "gzip"
-
 	headerAcceptEncoding  = "Accept-Encoding"
 	headerContentEncoding = "Content-Encoding"
 	headerContentLength   = "Content-Length"
 	headerContentType     = "Content-Type"
+	headerETag            = "ETag"
 	headerVary            = "Vary"
 	headerRange           = "Range"
 	headerAcceptRanges    = "Accept-Ranges"
 	headerSecWebSocketKey = "Sec-WebSocket-Key"
 )
 
-// gzipResponseWriter wraps the http.ResponseWriter to provide gzip
-// capabilities.
-type gzipResponseWriter struct {
+// compressResponseWriter wraps the http.ResponseWriter to provide
+// compression capabilities.
+type compressResponseWriter struct {
 	http.ResponseWriter
-	gzw            *gzip.Writer
+	encoding       string
+	cw             compressor
 	buf            bytes.Buffer
 	pool           *sync.Pool
 	statusCode     int
@@ -39,20 +36,20 @@ type gzipResponseWriter struct {
 	o              options
 }
 
-// Write writes bytes to the gzip.Writer. It will also set the Content-Type
+// Write writes bytes to the compressor. It will also set the Content-Type
 // header using the net/http library content type detection if the Content-Type
 // header was not set yet.
-func (grw *gzipResponseWriter) Write(b []byte) (int, error) {
+func (grw *compressResponseWriter) Write(b []byte) (int, error) {
 	if len(grw.Header().Get(headerContentType)) == 0 {
 		grw.Header().Set(headerContentType, http.DetectContentType(b))
 	}
 
-	// If we already decided to gzip, do that.
-	if grw.gzw != nil {
-		return grw.gzw.Write(b)
+	// If we already decided to compress, do that.
+	if grw.cw != nil {
+		return grw.cw.Write(b)
 	}
 
-	// If we have already decided not to gzip, do that.
+	// If we have already decided not to compress, do that.
 	if grw.shouldCompress != nil && !*grw.shouldCompress {
 		return grw.ResponseWriter.Write(b)
 	}
@@ -74,33 +71,36 @@ func (grw *gzipResponseWriter) Write(b []byte) (int, error) {
 		return grw.buf.Write(b)
 	}
 
-	// Reset our gzip writer to use the http.ResponseWriter
-	// Retrieve gzip writer from the pool. Reset it to use the ResponseWriter.
+	// Retrieve the compressor from the pool. Reset it to use the ResponseWriter.
 	// This allows us to re-use an already allocated buffer rather than
 	// allocating a new buffer for every request.
-	gz := grw.pool.Get().(*gzip.Writer)
+	cw := grw.pool.Get().(compressor)
 
-	// We must write header now
-	grw.Header().Set(headerContentEncoding, encodingGzip)
+	// We must write header now, strong entity tags no longer identify the
+	// compressed representation.
+	if etag := grw.Header().Get(headerETag); strings.HasPrefix(etag, `"`) {
+		grw.Header().Set(headerETag, "W/"+etag)
+	}
+	grw.Header().Set(headerContentEncoding, grw.encoding)
 	grw.Header().Set(headerVary, headerAcceptEncoding)
 	grw.Header().Del(headerContentLength)
 	grw.Header().Del(headerAcceptRanges)
 	grw.ResponseWriter.WriteHeader(grw.statusCode)
-	gz.Reset(grw.ResponseWriter)
-	grw.gzw = gz
+	cw.Reset(grw.ResponseWriter)
+	grw.cw = cw
 
 	// Write buffer
 	if grw.buf.Len() > 0 {
-		_, err := gz.Write(grw.buf.Bytes())
+		_, err := cw.Write(grw.buf.Bytes())
 		if err != nil {
 			return 0, err
 		}
 		grw.buf.Reset()
 	}
-	return gz.Write(b)
+	return cw.Write(b)
 }
 
-func (grw *gzipResponseWriter) WriteHeader(n int) {
+func (grw *compressResponseWriter) WriteHeader(n int) {
 	grw.statusCode = n
 }
 
@@ -114,6 +114,8 @@ type (
 		minSize      int
 		contentTypes []string
 		statusCodes  map[int]struct{}
+		encodings    []string
+		levels       map[string]int
 	}
 )
 
@@ -248,51 +250,67 @@ func IgnoreRange(b bool) Option {
 // appropriate headers. If the Content-Type is not set, it will be set by
 // calling http.DetectContentType on the data being written.
 func Middleware(level int, o ...Option) goa.Middleware {
+	o = append([]Option{Encodings(EncodingGzip), Level(EncodingGzip, level)}, o...)
+	return Compress(o...)
+}
+
+// Compress encodes the response using the content coding selected from the
+// request Accept-Encoding header and sets all the appropriate headers. The
+// coding with the highest quality value among the ones configured with
+// Encodings is used, ties are broken using the order given to Encodings.
+// By default Compress supports brotli, zstd, gzip and deflate in that order.
+// If the Content-Type is not set, it will be set by calling
+// http.DetectContentType on the data being written.
+func Compress(o ...Option) goa.Middleware {
 	opts := options{
 		ignoreRange:  true,
 		minSize:      256,
 		contentTypes: defaultContentTypes,
+		encodings:    defaultEncodings,
+		levels:       make(map[string]int, len(defaultLevels)),
 	}
 	opts.statusCodes = make(map[int]struct{}, len(defaultStatusCodes))
 	for _, v := range defaultStatusCodes {
 		opts.statusCodes[v] = struct{}{}
 	}
+	for k, v := range defaultLevels {
+		opts.levels[k] = v
+	}
 	for _, opt := range o {
 		err := opt(&opts)
 		if err != nil {
 			panic(err)
 		}
 	}
-	gzipPool := sync.Pool{
-		New: func() interface{} {
-			gz, err := gzip.NewWriterLevel(ioutil.Discard, level)
-			if err != nil {
-				panic(err)
-			}
-			return gz
-		},
+	pools := make(map[string]*sync.Pool, len(opts.encodings))
+	for _, enc := range opts.encodings {
+		pools[enc] = writerPool(enc, opts.levels[enc])
 	}
 	return func(h goa.Handler) goa.Handler {
 		return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) (err error) {
-			// Skip compression if the client doesn't accept gzip encoding, is
-			// requesting a WebSocket or the data is already compressed.
-			if !strings.Contains(req.Header.Get(headerAcceptEncoding), encodingGzip) ||
+			// Skip compression if the client doesn't accept any of the
+			// encodings, is requesting a WebSocket or the data is already
+			// compressed.
+			encoding := opts.negotiate(req.Header.Get(headerAcceptEncoding))
+			if encoding == "" ||
 				len(req.Header.Get(headerSecWebSocketKey)) > 0 ||
-				rw.Header().Get(headerContentEncoding) == encodingGzip ||
+				rw.Header().Get(headerContentEncoding) != "" ||
 				(!opts.ignoreRange && req.Header.Get(headerRange) != "") {
 				return h(ctx, rw, req)
 			}
+			pool := pools[encoding]
 
-			// Set the appropriate gzip headers.
+			// Set the appropriate compression headers.
 			resp := goa.ContextResponse(ctx)
 
 			// Get the original http.ResponseWriter
 			w := resp.SwitchWriter(nil)
 
-			// Wrap the original http.ResponseWriter with our gzipResponseWriter
-			grw := &gzipResponseWriter{
+			// Wrap the original http.ResponseWriter with our compressResponseWriter
+			grw := &compressResponseWriter{
 				ResponseWriter: w,
-				pool:           &gzipPool,
+				encoding:       encoding,
+				pool:           pool,
 				statusCode:     http.StatusOK,
 				o:              opts,
 			}
@@ -300,11 +318,11 @@ func Middleware(level int, o ...Option) goa.Middleware {
 			// Set the new http.ResponseWriter
 			resp.SwitchWriter(grw)
 
-			// We cannot do ranges, if possibly gzipped responses.
+			// We cannot do ranges, if possibly compressed responses.
 			req.Header.Del("Range")
 
-			// Call the next handler supplying the gzipResponseWriter instead of
-			// the original.
+			// Call the next handler supplying the compressResponseWriter
+			// instead of the original.
 			err = h(ctx, rw, req)
 			if err != nil {
 				return
@@ -319,11 +337,11 @@ func Middleware(level int, o ...Option) goa.Middleware {
 			}
 
 			// Flush compressor.
-			if grw.gzw != nil {
-				if err = grw.gzw.Close(); err != nil {
+			if grw.cw != nil {
+				if err = grw.cw.Close(); err != nil {
 					return
 				}
-				gzipPool.Put(grw.gzw)
+				pool.Put(grw.cw)
 				return
 			}
 			// No writes, set status code.
@@ -0,0 +1,192 @@
+/*
+Package httpcache implements a middleware that makes the responses of goa actions cacheable by
+HTTP clients and intermediaries.
+
+The middleware buffers the encoded body of successful GET and HEAD responses, sets the
+Cache-Control header and computes the ETag header from the body unless the action already set
+it. It then evaluates the conditional request headers (If-Match, If-None-Match,
+If-Modified-Since and If-Unmodified-Since) against the response validators and replies with 304
+Not Modified or rejects the request with ErrPreconditionFailed (412) when appropriate.
+Usage:
+
+    service.Use(httpcache.New(httpcache.CacheControl("private, max-age=60")))
+
+Actions that modify resources (PUT, PATCH, DELETE) must check preconditions before applying
+changes, they set the validators of the current state of the resource with SetETag or
+SetLastModified and call CheckPreconditions:
+
+    httpcache.SetETag(ctx.ResponseData.Header(), bottle.Version, false)
+    if err := httpcache.CheckPreconditions(ctx.Request, ctx.ResponseData.Header()); err != nil {
+        return err
+    }
+
+The middleware may also be applied to individual actions by declaring them cacheable in the
+design with the Cache DSL, the generated Mount functions then wire it automatically and the
+generated contexts expose the SetETag, SetLastModified and CheckPreconditions helpers:
+
+    Action("show", func() {
+        Cache("private, max-age=60", func() {
+            ETag(WeakETag)
+        })
+    })
+*/
+package httpcache
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/goadesign/goa"
+)
+
+// ETagStrategy defines how the middleware computes the ETag of responses.
+type ETagStrategy string
+
+const (
+	// StrongETag computes strong entity tags from the encoded response body.
+	StrongETag ETagStrategy = "strong"
+
+	// WeakETag computes weak entity tags from the encoded response body.
+	WeakETag ETagStrategy = "weak"
+
+	// NoETag disables the computation of entity tags, only the validators set explicitly by
+	// the handlers are used.
+	NoETag ETagStrategy = "none"
+)
+
+const (
+	headerCacheControl      = "Cache-Control"
+	headerContentLength     = "Content-Length"
+	headerContentType       = "Content-Type"
+	headerETag              = "ETag"
+	headerLastModified      = "Last-Modified"
+	headerIfMatch           = "If-Match"
+	headerIfNoneMatch       = "If-None-Match"
+	headerIfModifiedSince   = "If-Modified-Since"
+	headerIfUnmodifiedSince = "If-Unmodified-Since"
+)
+
+// ErrPreconditionFailed is the error returned when the preconditions of a request fail.
+var ErrPreconditionFailed = goa.NewErrorClass("precondition_failed", 412)
+
+type (
+	// Option allows to override default parameters.
+	Option func(*options) error
+
+	// options contains final options
+	options struct {
+		cacheControl string
+		etag         ETagStrategy
+	}
+)
+
+// CacheControl sets the Cache-Control header of successful responses that don't already define
+// it.
+func CacheControl(directives string) Option {
+	return func(o *options) error {
+		o.cacheControl = directives
+		return nil
+	}
+}
+
+// ETags sets the strategy used to compute the ETag header of responses, the default is
+// StrongETag.
+func ETags(strategy ETagStrategy) Option {
+	return func(o *options) error {
+		switch strategy {
+		case StrongETag, WeakETag, NoETag:
+			o.etag = strategy
+			return nil
+		}
+		return fmt.Errorf("httpcache: invalid ETag strategy %q", strategy)
+	}
+}
+
+// New returns a middleware that sets the cache headers of responses and answers conditional
+// requests. Requests made with methods other than GET and HEAD are passed through, the handlers
+// must call CheckPreconditions to evaluate their preconditions.
+func New(o ...Option) goa.Middleware {
+	opts := options{etag: StrongETag}
+	for _, opt := range o {
+		if err := opt(&opts); err != nil {
+			panic(err)
+		}
+	}
+	return func(h goa.Handler) goa.Handler {
+		return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			if req.Method != "GET" && req.Method != "HEAD" {
+				return h(ctx, rw, req)
+			}
+
+			// Buffer the response so that validators can be computed.
+			resp := goa.ContextResponse(ctx)
+			w := resp.SwitchWriter(nil)
+			bw := &bufferedWriter{ResponseWriter: w, status: http.StatusOK}
+			resp.SwitchWriter(bw)
+			err := h(ctx, rw, req)
+			resp.SwitchWriter(w)
+			if err != nil {
+				// Let the error handler write the response.
+				resp.Status, resp.Length = 0, 0
+				return err
+			}
+			if !bw.wroteHeader {
+				// No writes
+				return nil
+			}
+
+			header := w.Header()
+			if bw.status == http.StatusOK {
+				if opts.cacheControl != "" && header.Get(headerCacheControl) == "" {
+					header.Set(headerCacheControl, opts.cacheControl)
+				}
+				if header.Get(headerETag) == "" && opts.etag != NoETag {
+					SetETag(header, computeETag(bw.buf.Bytes()), opts.etag == WeakETag)
+				}
+				switch evaluate(req, header) {
+				case http.StatusNotModified:
+					header.Del(headerContentType)
+					header.Del(headerContentLength)
+					resp.Status, resp.Length = http.StatusNotModified, 0
+					w.WriteHeader(http.StatusNotModified)
+					return nil
+				case http.StatusPreconditionFailed:
+					header.Del(headerCacheControl)
+					header.Del(headerETag)
+					header.Del(headerLastModified)
+					resp.Status, resp.Length = 0, 0
+					return ErrPreconditionFailed("precondition failed")
+				}
+			}
+			if bw.buf.Len() > 0 {
+				header.Set(headerContentLength, strconv.Itoa(bw.buf.Len()))
+			}
+			w.WriteHeader(bw.status)
+			_, err = w.Write(bw.buf.Bytes())
+			return err
+		}
+	}
+}
+
+// bufferedWriter records the response status and body.
+type bufferedWriter struct {
+	http.ResponseWriter
+	buf         bytes.Buffer
+	status      int
+	wroteHeader bool
+}
+
+// WriteHeader records the response status code.
+func (w *bufferedWriter) WriteHeader(status int) {
+	w.status = status
+	w.wroteHeader = true
+}
+
+// Write buffers the response body.
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.buf.Write(b)
+}
@@ -0,0 +1,320 @@
+package httpcache_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/goadesign/goa"
+	"github.com/goadesign/goa/middleware/httpcache"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("New", func() {
+	const body = `[{"id":1,"name":"muscadet"}]`
+	var modified = time.Date(2017, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	var (
+		options      []httpcache.Option
+		method       string
+		reqHeader    map[string]string
+		status       int
+		etag         string
+		lastModified time.Time
+
+		rw   *httptest.ResponseRecorder
+		resp *goa.ResponseData
+		err  error
+	)
+
+	BeforeEach(func() {
+		options = []httpcache.Option{httpcache.CacheControl("private, max-age=60")}
+		method = "GET"
+		reqHeader = nil
+		status = http.StatusOK
+		etag = ""
+		lastModified = time.Time{}
+	})
+
+	JustBeforeEach(func() {
+		req := httptest.NewRequest(method, "/bottles", nil)
+		for k, v := range reqHeader {
+			req.Header.Set(k, v)
+		}
+		rw = httptest.NewRecorder()
+		ctx := goa.NewContext(context.Background(), rw, req, nil)
+		resp = goa.ContextResponse(ctx)
+		h := func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			if etag != "" {
+				httpcache.SetETag(rw.Header(), etag, false)
+			}
+			if !lastModified.IsZero() {
+				httpcache.SetLastModified(rw.Header(), lastModified)
+			}
+			if err := httpcache.CheckPreconditions(req, rw.Header()); err != nil {
+				return err
+			}
+			rw.Header().Set("Content-Type", "application/json")
+			rw.WriteHeader(status)
+			rw.Write([]byte(body))
+			return nil
+		}
+		err = httpcache.New(options...)(h)(ctx, resp, req)
+	})
+
+	It("computes a strong ETag and sets the cache headers", func() {
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(rw.Code).Should(Equal(http.StatusOK))
+		Ω(rw.Body.String()).Should(Equal(body))
+		Ω(rw.Header().Get("ETag")).Should(MatchRegexp(`^"[A-Za-z0-9_-]{22}"$`))
+		Ω(rw.Header().Get("Cache-Control")).Should(Equal("private, max-age=60"))
+		Ω(rw.Header().Get("Content-Length")).Should(Equal("28"))
+		Ω(resp.Status).Should(Equal(http.StatusOK))
+		Ω(resp.Length).Should(Equal(28))
+	})
+
+	Context("with the same body", func() {
+		var first string
+
+		BeforeEach(func() {
+			req := httptest.NewRequest("GET", "/bottles", nil)
+			rw := httptest.NewRecorder()
+			ctx := goa.NewContext(context.Background(), rw, req, nil)
+			h := func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+				rw.Write([]byte(body))
+				return nil
+			}
+			httpcache.New()(h)(ctx, goa.ContextResponse(ctx), req)
+			first = rw.Header().Get("ETag")
+		})
+
+		It("computes the same ETag", func() {
+			Ω(rw.Header().Get("ETag")).Should(Equal(first))
+		})
+	})
+
+	Context("with a HEAD request", func() {
+		var get string
+
+		BeforeEach(func() {
+			method = "HEAD"
+			req := httptest.NewRequest("GET", "/bottles", nil)
+			rw := httptest.NewRecorder()
+			ctx := goa.NewContext(context.Background(), rw, req, nil)
+			h := func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+				rw.Write([]byte(body))
+				return nil
+			}
+			httpcache.New()(h)(ctx, goa.ContextResponse(ctx), req)
+			get = rw.Header().Get("ETag")
+		})
+
+		It("computes the same ETag as GET", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(rw.Code).Should(Equal(http.StatusOK))
+			Ω(rw.Header().Get("ETag")).Should(Equal(get))
+			Ω(rw.Header().Get("Cache-Control")).Should(Equal("private, max-age=60"))
+		})
+
+		Context("and a matching If-None-Match header", func() {
+			BeforeEach(func() {
+				reqHeader = map[string]string{"If-None-Match": get}
+			})
+
+			It("returns 304", func() {
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(rw.Code).Should(Equal(http.StatusNotModified))
+				Ω(resp.Status).Should(Equal(http.StatusNotModified))
+			})
+		})
+	})
+
+	Context("with weak ETags", func() {
+		BeforeEach(func() {
+			options = append(options, httpcache.ETags(httpcache.WeakETag))
+		})
+
+		It("computes a weak ETag", func() {
+			Ω(rw.Header().Get("ETag")).Should(MatchRegexp(`^W/"[A-Za-z0-9_-]{22}"$`))
+		})
+	})
+
+	Context("with ETags disabled", func() {
+		BeforeEach(func() {
+			options = append(options, httpcache.ETags(httpcache.NoETag))
+		})
+
+		It("does not compute the ETag", func() {
+			Ω(rw.Header().Get("ETag")).Should(BeEmpty())
+		})
+	})
+
+	Context("with an explicit ETag", func() {
+		BeforeEach(func() {
+			etag = "v42"
+		})
+
+		It("keeps the ETag", func() {
+			Ω(rw.Header().Get("ETag")).Should(Equal(`"v42"`))
+		})
+
+		Context("and a matching If-None-Match header", func() {
+			BeforeEach(func() {
+				reqHeader = map[string]string{"If-None-Match": `"v41", W/"v42"`}
+			})
+
+			It("returns 304 with no body", func() {
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(rw.Code).Should(Equal(http.StatusNotModified))
+				Ω(rw.Body.Len()).Should(Equal(0))
+				Ω(rw.Header().Get("ETag")).Should(Equal(`"v42"`))
+				Ω(rw.Header().Get("Cache-Control")).Should(Equal("private, max-age=60"))
+				Ω(rw.Header().Get("Content-Type")).Should(BeEmpty())
+				Ω(resp.Status).Should(Equal(http.StatusNotModified))
+				Ω(resp.Length).Should(Equal(0))
+			})
+		})
+
+		Context("and a different If-None-Match header", func() {
+			BeforeEach(func() {
+				reqHeader = map[string]string{"If-None-Match": `"v41"`}
+			})
+
+			It("returns the response", func() {
+				Ω(rw.Code).Should(Equal(http.StatusOK))
+				Ω(rw.Body.String()).Should(Equal(body))
+			})
+		})
+
+		Context("and a failing If-Match header", func() {
+			BeforeEach(func() {
+				reqHeader = map[string]string{"If-Match": `"v41"`}
+			})
+
+			It("returns a 412 error", func() {
+				Ω(err).Should(HaveOccurred())
+				Ω(err.(goa.ServiceError).ResponseStatus()).Should(Equal(http.StatusPreconditionFailed))
+				Ω(rw.Body.Len()).Should(Equal(0))
+			})
+		})
+	})
+
+	Context("with Last-Modified", func() {
+		BeforeEach(func() {
+			lastModified = modified
+		})
+
+		Context("and a later If-Modified-Since header", func() {
+			BeforeEach(func() {
+				reqHeader = map[string]string{"If-Modified-Since": modified.Add(time.Hour).Format(http.TimeFormat)}
+			})
+
+			It("returns 304", func() {
+				Ω(rw.Code).Should(Equal(http.StatusNotModified))
+				Ω(rw.Header().Get("Last-Modified")).Should(Equal("Thu, 01 Jun 2017 12:00:00 GMT"))
+			})
+		})
+
+		Context("and an earlier If-Modified-Since header", func() {
+			BeforeEach(func() {
+				reqHeader = map[string]string{"If-Modified-Since": modified.Add(-time.Hour).Format(http.TimeFormat)}
+			})
+
+			It("returns the response", func() {
+				Ω(rw.Code).Should(Equal(http.StatusOK))
+			})
+		})
+
+		Context("and a non matching If-None-Match header", func() {
+			BeforeEach(func() {
+				reqHeader = map[string]string{
+					"If-None-Match":     `"other"`,
+					"If-Modified-Since": modified.Add(time.Hour).Format(http.TimeFormat),
+				}
+			})
+
+			It("ignores If-Modified-Since", func() {
+				Ω(rw.Code).Should(Equal(http.StatusOK))
+			})
+		})
+	})
+
+	Context("with a non 200 status", func() {
+		BeforeEach(func() {
+			status = http.StatusAccepted
+			reqHeader = map[string]string{"If-None-Match": "*"}
+		})
+
+		It("does not set the cache headers", func() {
+			Ω(rw.Code).Should(Equal(http.StatusAccepted))
+			Ω(rw.Header().Get("ETag")).Should(BeEmpty())
+			Ω(rw.Header().Get("Cache-Control")).Should(BeEmpty())
+			Ω(rw.Body.String()).Should(Equal(body))
+		})
+	})
+
+	Context("with a PUT request", func() {
+		BeforeEach(func() {
+			method = "PUT"
+			etag = "v42"
+		})
+
+		It("does not buffer the response", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(rw.Code).Should(Equal(http.StatusOK))
+			Ω(rw.Header().Get("Cache-Control")).Should(BeEmpty())
+		})
+
+		Context("and a matching If-Match header", func() {
+			BeforeEach(func() {
+				reqHeader = map[string]string{"If-Match": `"v42"`}
+			})
+
+			It("processes the request", func() {
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(rw.Code).Should(Equal(http.StatusOK))
+			})
+		})
+
+		Context("and a weak If-Match header", func() {
+			BeforeEach(func() {
+				reqHeader = map[string]string{"If-Match": `W/"v42"`}
+			})
+
+			It("uses the strong comparison", func() {
+				Ω(err).Should(HaveOccurred())
+				Ω(err.(goa.ServiceError).ResponseStatus()).Should(Equal(http.StatusPreconditionFailed))
+			})
+		})
+
+		Context("and a failing If-Unmodified-Since header", func() {
+			BeforeEach(func() {
+				etag = ""
+				lastModified = modified
+				reqHeader = map[string]string{"If-Unmodified-Since": modified.Add(-time.Hour).Format(http.TimeFormat)}
+			})
+
+			It("returns a 412 error", func() {
+				Ω(err).Should(HaveOccurred())
+				Ω(err.(goa.ServiceError).ResponseStatus()).Should(Equal(http.StatusPreconditionFailed))
+			})
+		})
+
+		Context("and a matching If-None-Match header", func() {
+			BeforeEach(func() {
+				reqHeader = map[string]string{"If-None-Match": "*"}
+			})
+
+			It("returns a 412 error", func() {
+				Ω(err).Should(HaveOccurred())
+				Ω(err.(goa.ServiceError).ResponseStatus()).Should(Equal(http.StatusPreconditionFailed))
+			})
+		})
+	})
+
+	It("rejects invalid ETag strategies", func() {
+		Ω(func() { httpcache.New(httpcache.ETags("md5")) }).Should(Panic())
+	})
+})
@@ -0,0 +1,119 @@
+package httpcache
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// SetETag sets the ETag header to the given entity tag. The tag is quoted if necessary and
+// prefixed with W/ if weak is true.
+func SetETag(header http.Header, etag string, weak bool) {
+	if !strings.HasPrefix(etag, `"`) {
+		etag = `"` + etag + `"`
+	}
+	if weak {
+		etag = "W/" + etag
+	}
+	header.Set(headerETag, etag)
+}
+
+// SetLastModified sets the Last-Modified header to the given time.
+func SetLastModified(header http.Header, t time.Time) {
+	header.Set(headerLastModified, t.UTC().Format(http.TimeFormat))
+}
+
+// CheckPreconditions evaluates the conditional headers of the request against the validators
+// set in the response header with SetETag and SetLastModified. It returns ErrPreconditionFailed
+// if a precondition fails, nil otherwise. The conditions that result in a 304 Not Modified
+// response to GET and HEAD requests are handled by the middleware and do not cause an error.
+func CheckPreconditions(req *http.Request, header http.Header) error {
+	if evaluate(req, header) == http.StatusPreconditionFailed {
+		return ErrPreconditionFailed("precondition failed", "method", req.Method)
+	}
+	return nil
+}
+
+// evaluate evaluates the request preconditions in the order defined by RFC 9110 section 13.2.2.
+// It returns http.StatusNotModified, http.StatusPreconditionFailed or 0 if the request should
+// be processed normally.
+func evaluate(req *http.Request, header http.Header) int {
+	safe := req.Method == "GET" || req.Method == "HEAD"
+	etag := header.Get(headerETag)
+	lastModified, lmErr := http.ParseTime(header.Get(headerLastModified))
+	if im := req.Header.Get(headerIfMatch); im != "" {
+		if !matchETag(im, etag, false) {
+			return http.StatusPreconditionFailed
+		}
+	} else if ius := req.Header.Get(headerIfUnmodifiedSince); ius != "" && lmErr == nil {
+		if t, err := http.ParseTime(ius); err == nil && lastModified.After(t) {
+			return http.StatusPreconditionFailed
+		}
+	}
+	if inm := req.Header.Get(headerIfNoneMatch); inm != "" {
+		if matchETag(inm, etag, true) {
+			if safe {
+				return http.StatusNotModified
+			}
+			return http.StatusPreconditionFailed
+		}
+	} else if ims := req.Header.Get(headerIfModifiedSince); ims != "" && safe && lmErr == nil {
+		if t, err := http.ParseTime(ims); err == nil && !lastModified.After(t) {
+			return http.StatusNotModified
+		}
+	}
+	return 0
+}
+
+// matchETag returns true if the entity tag list (value of If-Match or If-None-Match) matches
+// the given entity tag. Weak comparison ignores the weakness indicators, strong comparison
+// requires both tags to be strong.
+func matchETag(list, etag string, weak bool) bool {
+	if etag == "" {
+		return false
+	}
+	if strings.TrimSpace(list) == "*" {
+		return true
+	}
+	for {
+		var candidate string
+		candidate, list = scanETag(list)
+		if candidate == "" {
+			return false
+		}
+		if weak {
+			if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		} else if !strings.HasPrefix(candidate, "W/") && !strings.HasPrefix(etag, "W/") && candidate == etag {
+			return true
+		}
+	}
+}
+
+// scanETag returns the first entity tag in the given comma separated list and the remainder of
+// the list. It returns an empty tag if the list does not start with a valid entity tag.
+func scanETag(list string) (string, string) {
+	list = strings.TrimLeft(list, " \t,")
+	start := 0
+	if strings.HasPrefix(list, "W/") {
+		start = 2
+	}
+	if len(list) < start+2 || list[start] != '"' {
+		return "", ""
+	}
+	end := strings.IndexByte(list[start+1:], '"')
+	if end < 0 {
+		return "", ""
+	}
+	end += start + 2
+	return list[:end], list[end:]
+}
+
+// computeETag returns an entity tag identifying the given response body.
+func computeETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return base64.RawURLEncoding.EncodeToString(sum[:16])
+}
@@ -0,0 +1,269 @@
+/*
+Package idempotency implements a middleware that makes it safe for clients to retry requests made
+to actions that are not idempotent, such as POST actions that create payments.
+
+Clients send a unique key with each logical request in the Idempotency-Key header. The middleware
+stores the response of the first request made with a given key (status, headers and body) and
+replays it for later requests made with the same key. Requests that reuse a key with a different
+payload are rejected with ErrKeyReused (409) and requests made while the first request is still
+being processed are rejected with ErrInProgress (409). Replayed responses include the
+Idempotent-Replayed header. Requests that fail with an error, a 5xx response or a panic are not
+stored so that clients may retry them.
+
+Keys are scoped to the controller action and to the client: the ID of the principal set by the
+auth middlewares (see goa.ContextPrincipal) is part of the key so that clients cannot replay the
+responses of other clients. The Scope option overrides how the client is identified.
+Usage:
+
+    service.Use(idempotency.New(idempotency.NewMemoryStore(), idempotency.TTL(24*time.Hour)))
+
+The middleware may also be applied to individual actions by declaring them in the design with
+the Idempotent DSL, the generated Mount functions then wire it automatically using DefaultStore:
+
+    Action("create", func() {
+        Idempotent(24*time.Hour, true)
+    })
+*/
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/goadesign/goa"
+)
+
+const (
+	// KeyHeader is the name of the request header that contains the idempotency key.
+	KeyHeader = "Idempotency-Key"
+
+	// ReplayedHeader is the name of the response header set on replayed responses.
+	ReplayedHeader = "Idempotent-Replayed"
+
+	// DefaultTTL is the default duration responses are stored for.
+	DefaultTTL = 24 * time.Hour
+
+	// DefaultLease is the default duration a key is reserved for while the first request made
+	// with it is being processed.
+	DefaultLease = time.Minute
+)
+
+var (
+	// ErrMissingKey is the error returned when the idempotency key is required and missing.
+	ErrMissingKey = goa.NewErrorClass("idempotency_key_missing", 400)
+
+	// ErrKeyReused is the error returned when a key is reused with a different payload.
+	ErrKeyReused = goa.NewErrorClass("idempotency_key_reused", 409)
+
+	// ErrInProgress is the error returned when a request is made while the first request made
+	// with the same key is still being processed.
+	ErrInProgress = goa.NewErrorClass("idempotency_request_in_progress", 409)
+)
+
+// DefaultStore is the store used by the middlewares mounted by the generated code. It may be
+// replaced before the controllers are mounted, e.g. with a store shared by all the service
+// instances.
+var DefaultStore Store = NewMemoryStore()
+
+type (
+	// Option allows to override default parameters.
+	Option func(*options) error
+
+	// ScopeFunc returns the scope of the idempotency keys sent with the given request, keys
+	// sent with requests that have different scopes never collide.
+	ScopeFunc func(ctx context.Context, req *http.Request) string
+
+	// options contains final options
+	options struct {
+		ttl      time.Duration
+		lease    time.Duration
+		required bool
+		scope    ScopeFunc
+	}
+)
+
+// TTL sets the duration responses are stored for, the default is DefaultTTL.
+func TTL(d time.Duration) Option {
+	return func(o *options) error {
+		if d <= 0 {
+			return fmt.Errorf("idempotency: invalid TTL %s", d)
+		}
+		o.ttl = d
+		return nil
+	}
+}
+
+// Lease sets the duration a key is reserved for while the first request made with it is being
+// processed, the default is DefaultLease. The reservation is released when the request fails so
+// the lease only matters if the process dies while handling the request: the key may be used
+// again once the lease expires.
+func Lease(d time.Duration) Option {
+	return func(o *options) error {
+		if d <= 0 {
+			return fmt.Errorf("idempotency: invalid lease %s", d)
+		}
+		o.lease = d
+		return nil
+	}
+}
+
+// Scope sets the function that computes the scope of the keys, the default uses the ID of the
+// principal returned by goa.ContextPrincipal if any.
+func Scope(f ScopeFunc) Option {
+	return func(o *options) error {
+		if f == nil {
+			return fmt.Errorf("idempotency: scope function must not be nil")
+		}
+		o.scope = f
+		return nil
+	}
+}
+
+// Required causes requests that do not include the Idempotency-Key header to be rejected with
+// ErrMissingKey.
+func Required() Option {
+	return func(o *options) error {
+		o.required = true
+		return nil
+	}
+}
+
+// New returns a middleware that stores the responses of requests made with an idempotency key
+// in store and replays them when the same key is used again. Keys are scoped to the controller
+// action and to the scope computed by the Scope option. Requests made with the GET, HEAD or
+// OPTIONS methods are passed through.
+func New(store Store, o ...Option) goa.Middleware {
+	opts := options{ttl: DefaultTTL, lease: DefaultLease, scope: principalScope}
+	for _, opt := range o {
+		if err := opt(&opts); err != nil {
+			panic(err)
+		}
+	}
+	return func(h goa.Handler) goa.Handler {
+		return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			if req.Method == "GET" || req.Method == "HEAD" || req.Method == "OPTIONS" {
+				return h(ctx, rw, req)
+			}
+			key := req.Header.Get(KeyHeader)
+			if key == "" {
+				if opts.required {
+					return ErrMissingKey("missing required header", "header", KeyHeader)
+				}
+				return h(ctx, rw, req)
+			}
+			// The scope is quoted so that the keys sent by a client cannot collide with
+			// the keys of another scope.
+			key = fmt.Sprintf("%s#%s#%q#%s", goa.ContextController(ctx), goa.ContextAction(ctx),
+				opts.scope(ctx, req), key)
+			hash, err := payloadHash(ctx, req)
+			if err != nil {
+				return err
+			}
+
+			rec, err := store.Reserve(ctx, key, hash, opts.lease)
+			if err != nil {
+				return err
+			}
+			if rec != nil {
+				switch {
+				case rec.Hash != hash:
+					return ErrKeyReused("idempotency key reused with a different payload")
+				case rec.Status == 0:
+					return ErrInProgress("a request with the same idempotency key is in progress")
+				}
+				return replay(ctx, rec)
+			}
+
+			// Record the response while it is written, release the key if the request
+			// fails, including if the handler panics.
+			resp := goa.ContextResponse(ctx)
+			w := resp.SwitchWriter(nil)
+			rec = &Record{Hash: hash}
+			resp.SwitchWriter(&recorder{ResponseWriter: w, rec: rec})
+			saved := false
+			defer func() {
+				resp.SwitchWriter(w)
+				if !saved {
+					if rerr := store.Release(ctx, key); rerr != nil {
+						goa.LogError(ctx, "failed to release idempotency key", "err", rerr)
+					}
+				}
+			}()
+			err = h(ctx, rw, req)
+			if err != nil || rec.Status == 0 || rec.Status >= 500 {
+				return err
+			}
+			if serr := store.Save(ctx, key, rec, opts.ttl); serr != nil {
+				goa.LogError(ctx, "failed to save idempotent response", "err", serr)
+			}
+			saved = true
+			return nil
+		}
+	}
+}
+
+// principalScope is the default ScopeFunc, it returns the ID of the authenticated principal.
+func principalScope(ctx context.Context, req *http.Request) string {
+	if p := goa.ContextPrincipal(ctx); p != nil {
+		return p.ID
+	}
+	return ""
+}
+
+// replay writes the stored response.
+func replay(ctx context.Context, rec *Record) error {
+	resp := goa.ContextResponse(ctx)
+	header := resp.Header()
+	for k, v := range rec.Header {
+		header[k] = append([]string(nil), v...)
+	}
+	header.Set(ReplayedHeader, "true")
+	resp.WriteHeader(rec.Status)
+	_, err := resp.Write(rec.Body)
+	return err
+}
+
+// payloadHash computes the hash identifying the request method, URI and payload. The payload is
+// decoded before the middleware runs so the hash is computed from its JSON representation.
+func payloadHash(ctx context.Context, req *http.Request) (string, error) {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", req.Method, req.URL.RequestURI())
+	if r := goa.ContextRequest(ctx); r != nil && r.Payload != nil {
+		if err := json.NewEncoder(h).Encode(r.Payload); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// recorder records the response written to the underlying writer.
+type recorder struct {
+	http.ResponseWriter
+	rec *Record
+}
+
+// WriteHeader records the status code and headers.
+func (r *recorder) WriteHeader(status int) {
+	if r.rec.Status == 0 {
+		r.rec.Status = status
+		r.rec.Header = make(http.Header, len(r.Header()))
+		for k, v := range r.Header() {
+			r.rec.Header[k] = append([]string(nil), v...)
+		}
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Write records the body.
+func (r *recorder) Write(b []byte) (int, error) {
+	if r.rec.Status == 0 {
+		r.WriteHeader(http.StatusOK)
+	}
+	r.rec.Body = append(r.rec.Body, b...)
+	return r.ResponseWriter.Write(b)
+}
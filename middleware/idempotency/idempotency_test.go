@@ -0,0 +1,255 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/goadesign/goa"
+)
+
+type payment struct {
+	Amount   int    `json:"amount"`
+	Currency string `json:"currency"`
+}
+
+// do runs a request through the middleware and returns the recorded response and the error
+// returned by the middleware.
+func do(mw goa.Middleware, h goa.Handler, key string, p *payment) (*httptest.ResponseRecorder, error) {
+	return doAs(mw, h, nil, key, p)
+}
+
+// doAs runs a request made by the given principal through the middleware.
+func doAs(mw goa.Middleware, h goa.Handler, principal *goa.Principal, key string, p *payment) (*httptest.ResponseRecorder, error) {
+	req := httptest.NewRequest("POST", "/payments", nil)
+	if key != "" {
+		req.Header.Set(KeyHeader, key)
+	}
+	rw := httptest.NewRecorder()
+	ctx := goa.NewContext(context.Background(), rw, req, nil)
+	ctx = goa.WithAction(ctx, "create")
+	if principal != nil {
+		ctx = goa.WithPrincipal(ctx, principal)
+	}
+	goa.ContextRequest(ctx).Payload = p
+	return rw, mw(h)(ctx, goa.ContextResponse(ctx), req)
+}
+
+// leaseStore records the duration of the reservations.
+type leaseStore struct {
+	*MemoryStore
+	lease time.Duration
+}
+
+func (s *leaseStore) Reserve(ctx context.Context, key, hash string, ttl time.Duration) (*Record, error) {
+	s.lease = ttl
+	return s.MemoryStore.Reserve(ctx, key, hash, ttl)
+}
+
+func TestMiddleware(t *testing.T) {
+	var (
+		calls  int
+		status = http.StatusCreated
+		err    error
+	)
+	h := func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		calls++
+		if err != nil {
+			return err
+		}
+		rw.Header().Set("Location", "/payments/1")
+		rw.WriteHeader(status)
+		rw.Write([]byte(`{"id":1}`))
+		return nil
+	}
+	store := NewMemoryStore()
+	mw := New(store)
+	p := &payment{Amount: 100, Currency: "EUR"}
+
+	rw, e := do(mw, h, "k1", p)
+	if e != nil || rw.Code != http.StatusCreated || calls != 1 {
+		t.Fatalf("first request: got %v %d, %d calls", e, rw.Code, calls)
+	}
+	if rw.Header().Get(ReplayedHeader) != "" {
+		t.Errorf("first response should not be replayed")
+	}
+
+	rw, e = do(mw, h, "k1", &payment{Amount: 100, Currency: "EUR"})
+	if e != nil || calls != 1 {
+		t.Fatalf("replay: got %v, %d calls", e, calls)
+	}
+	if rw.Code != http.StatusCreated || rw.Body.String() != `{"id":1}` || rw.Header().Get("Location") != "/payments/1" {
+		t.Errorf("replay: invalid response %d %q %v", rw.Code, rw.Body.String(), rw.Header())
+	}
+	if rw.Header().Get(ReplayedHeader) != "true" {
+		t.Errorf("replay: missing %s header", ReplayedHeader)
+	}
+
+	_, e = do(mw, h, "k1", &payment{Amount: 200, Currency: "EUR"})
+	if se, ok := e.(*goa.ErrorResponse); !ok || se.Status != http.StatusConflict || se.Code != "idempotency_key_reused" {
+		t.Errorf("different payload: expected conflict, got %v", e)
+	}
+
+	rw, e = do(mw, h, "", p)
+	if e != nil || rw.Code != http.StatusCreated || calls != 2 {
+		t.Errorf("no key: got %v %d, %d calls", e, rw.Code, calls)
+	}
+
+	status = http.StatusServiceUnavailable
+	do(mw, h, "k2", p)
+	status = http.StatusCreated
+	rw, _ = do(mw, h, "k2", p)
+	if rw.Code != http.StatusCreated || calls != 4 {
+		t.Errorf("5xx responses should not be stored, got %d, %d calls", rw.Code, calls)
+	}
+
+	err = errors.New("boom")
+	do(mw, h, "k3", p)
+	err = nil
+	rw, _ = do(mw, h, "k3", p)
+	if rw.Code != http.StatusCreated || calls != 6 {
+		t.Errorf("failed requests should not be stored, got %d, %d calls", rw.Code, calls)
+	}
+}
+
+func TestMiddlewareInProgress(t *testing.T) {
+	store := NewMemoryStore()
+	mw := New(store)
+	var inner error
+	h := func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		// Simulate a concurrent request made with the same key.
+		_, inner = do(mw, func(context.Context, http.ResponseWriter, *http.Request) error {
+			t.Error("concurrent request should not be processed")
+			return nil
+		}, "k", nil)
+		rw.WriteHeader(http.StatusOK)
+		return nil
+	}
+
+	do(mw, h, "k", nil)
+
+	if se, ok := inner.(*goa.ErrorResponse); !ok || se.Status != http.StatusConflict || se.Code != "idempotency_request_in_progress" {
+		t.Errorf("expected in progress conflict, got %v", inner)
+	}
+}
+
+func TestMiddlewarePanic(t *testing.T) {
+	store := NewMemoryStore()
+	mw := New(store)
+	var resp *goa.ResponseData
+	h := func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		resp = goa.ContextResponse(ctx)
+		rw.WriteHeader(http.StatusCreated)
+		panic("boom")
+	}
+	func() {
+		defer func() { recover() }()
+		do(mw, h, "k", nil)
+	}()
+	if _, ok := resp.ResponseWriter.(*recorder); ok {
+		t.Errorf("expected the response writer to be restored")
+	}
+	if store.Len() != 0 {
+		t.Errorf("expected the key to be released, got %d records", store.Len())
+	}
+
+	rw, err := do(mw, func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		rw.WriteHeader(http.StatusCreated)
+		return nil
+	}, "k", nil)
+	if err != nil || rw.Code != http.StatusCreated || rw.Header().Get(ReplayedHeader) != "" {
+		t.Errorf("retry: got %v %d %v", err, rw.Code, rw.Header())
+	}
+}
+
+func TestMiddlewareScope(t *testing.T) {
+	var calls int
+	h := func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		calls++
+		rw.WriteHeader(http.StatusCreated)
+		return nil
+	}
+	alice, bob := &goa.Principal{ID: "alice"}, &goa.Principal{ID: "bob"}
+
+	mw := New(NewMemoryStore())
+	doAs(mw, h, alice, "k", nil)
+	rw, _ := doAs(mw, h, bob, "k", nil)
+	if calls != 2 || rw.Header().Get(ReplayedHeader) != "" {
+		t.Errorf("keys of different principals should not collide, got %d calls", calls)
+	}
+	rw, _ = doAs(mw, h, alice, "k", nil)
+	if calls != 2 || rw.Header().Get(ReplayedHeader) != "true" {
+		t.Errorf("keys of the same principal should be replayed, got %d calls", calls)
+	}
+
+	calls = 0
+	mw = New(NewMemoryStore(), Scope(func(ctx context.Context, req *http.Request) string {
+		return "tenant"
+	}))
+	doAs(mw, h, alice, "k", nil)
+	doAs(mw, h, bob, "k", nil)
+	if calls != 1 {
+		t.Errorf("keys with the same custom scope should be replayed, got %d calls", calls)
+	}
+}
+
+func TestMiddlewareLease(t *testing.T) {
+	h := func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		rw.WriteHeader(http.StatusCreated)
+		return nil
+	}
+	store := &leaseStore{MemoryStore: NewMemoryStore()}
+	do(New(store), h, "k1", nil)
+	if store.lease != DefaultLease {
+		t.Errorf("expected default lease %s, got %s", DefaultLease, store.lease)
+	}
+	do(New(store, Lease(5*time.Second)), h, "k2", nil)
+	if store.lease != 5*time.Second {
+		t.Errorf("expected lease 5s, got %s", store.lease)
+	}
+}
+
+func TestMiddlewareRequired(t *testing.T) {
+	h := func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		return nil
+	}
+	_, err := do(New(NewMemoryStore(), Required()), h, "", nil)
+	if se, ok := err.(goa.ServiceError); !ok || se.ResponseStatus() != http.StatusBadRequest {
+		t.Errorf("expected missing key error, got %v", err)
+	}
+}
+
+func TestMemoryStoreTTL(t *testing.T) {
+	now := time.Now()
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if rec, _ := s.Reserve(ctx, "k", "h", time.Minute); rec != nil {
+		t.Fatalf("unexpected record %+v", rec)
+	}
+	s.Save(ctx, "k", &Record{Hash: "h", Status: 200}, time.Minute)
+	if rec, _ := s.Reserve(ctx, "k", "h", time.Minute); rec == nil || rec.Status != 200 {
+		t.Fatalf("expected stored record, got %+v", rec)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if s.Len() != 0 {
+		t.Errorf("expected expired record to be purged, got %d records", s.Len())
+	}
+	if rec, _ := s.Reserve(ctx, "k", "h", time.Minute); rec != nil {
+		t.Errorf("expected expired record to be replaced, got %+v", rec)
+	}
+}
+
+func TestInvalidTTL(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic")
+		}
+	}()
+	New(NewMemoryStore(), TTL(0))
+}
@@ -0,0 +1,104 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type (
+	// Store persists the responses of the requests made with an idempotency key. Stores shared
+	// by multiple service instances must implement Reserve atomically.
+	Store interface {
+		// Reserve records that the request identified by key and whose payload has the
+		// given hash is being processed. It returns the existing record instead if one
+		// already exists for key, nil otherwise. The reservation expires after ttl.
+		Reserve(ctx context.Context, key, hash string, ttl time.Duration) (*Record, error)
+		// Save stores the response of the request that reserved key for the duration ttl.
+		Save(ctx context.Context, key string, rec *Record, ttl time.Duration) error
+		// Release deletes the reservation of key so that the request may be retried.
+		Release(ctx context.Context, key string) error
+	}
+
+	// Record describes a stored request.
+	Record struct {
+		// Hash identifies the request method, URI and payload.
+		Hash string
+		// Status is the response status code, 0 while the request is being processed.
+		Status int
+		// Header contains the response headers.
+		Header http.Header
+		// Body is the response body.
+		Body []byte
+	}
+
+	// MemoryStore is a Store that keeps the records in memory. Records are not shared between
+	// processes.
+	MemoryStore struct {
+		lock      sync.Mutex
+		records   map[string]*memoryRecord
+		now       func() time.Time
+		lastPurge time.Time
+	}
+
+	// memoryRecord is a record with its expiry time.
+	memoryRecord struct {
+		*Record
+		expiry time.Time
+	}
+)
+
+// NewMemoryStore returns a store that keeps the records in memory.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]*memoryRecord), now: time.Now}
+}
+
+// Reserve implements Store.
+func (s *MemoryStore) Reserve(ctx context.Context, key, hash string, ttl time.Duration) (*Record, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	now := s.now()
+	if r, ok := s.records[key]; ok && now.Before(r.expiry) {
+		return r.Record, nil
+	}
+	if now.Sub(s.lastPurge) > time.Minute {
+		s.purge(now)
+	}
+	s.records[key] = &memoryRecord{Record: &Record{Hash: hash}, expiry: now.Add(ttl)}
+	return nil, nil
+}
+
+// Save implements Store.
+func (s *MemoryStore) Save(ctx context.Context, key string, rec *Record, ttl time.Duration) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.records[key] = &memoryRecord{Record: rec, expiry: s.now().Add(ttl)}
+	return nil
+}
+
+// Release implements Store.
+func (s *MemoryStore) Release(ctx context.Context, key string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+// Len returns the number of records that have not expired.
+func (s *MemoryStore) Len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.purge(s.now())
+	return len(s.records)
+}
+
+// purge deletes the expired records.
+func (s *MemoryStore) purge(now time.Time) {
+	for k, r := range s.records {
+		if !now.Before(r.expiry) {
+			delete(s.records, k)
+		}
+	}
+	s.lastPurge = now
+}
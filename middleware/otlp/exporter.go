@@ -0,0 +1,245 @@
+/*
+Package otlp implements a middleware.SpanExporter that sends the spans recorded by the tracer
+middleware and by the doers returned by middleware.SpanDoer to an OpenTelemetry collector using
+the OTLP/HTTP protocol with JSON encoding.
+Usage:
+
+    exporter, err := otlp.New("http://localhost:4318", "cellar")
+    if err != nil {
+        return err
+    }
+    defer exporter.Shutdown(context.Background())
+    service.Use(middleware.NewTracer(
+        middleware.Propagators(middleware.NewW3CPropagator()),
+        middleware.ExportSpans(exporter),
+    ))
+
+The exporter buffers the spans and sends them in batches, see BatchSize and FlushInterval.
+Span and trace IDs must be hexadecimal strings of 16 and 32 characters respectively which is
+the default when the tracer middleware is given an exporter.
+*/
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goadesign/goa/middleware"
+)
+
+// TracesPath is the path of the OTLP/HTTP traces endpoint relative to the collector URL.
+const TracesPath = "/v1/traces"
+
+type (
+	// Exporter sends spans to an OpenTelemetry collector.
+	Exporter struct {
+		url     string
+		service string
+		options *options
+
+		lock    sync.Mutex
+		spans   []*middleware.Span
+		closed  bool
+		flushc  chan struct{}
+		done    chan struct{}
+		stopped chan struct{}
+	}
+
+	// Option allows to override default parameters.
+	Option func(*options) error
+
+	// options contains the exporter settings.
+	options struct {
+		client        *http.Client
+		headers       http.Header
+		batchSize     int
+		flushInterval time.Duration
+		onError       func(error)
+	}
+)
+
+// HTTPClient sets the client used to send the spans, http.DefaultClient by default.
+func HTTPClient(c *http.Client) Option {
+	return func(o *options) error {
+		if c == nil {
+			return errors.New("otlp: HTTP client cannot be nil")
+		}
+		o.client = c
+		return nil
+	}
+}
+
+// Header adds a header to the export requests, for example to authenticate with the collector.
+func Header(name, value string) Option {
+	return func(o *options) error {
+		o.headers.Add(name, value)
+		return nil
+	}
+}
+
+// BatchSize sets the number of buffered spans that triggers an export. Defaults to 512.
+func BatchSize(n int) Option {
+	return func(o *options) error {
+		if n <= 0 {
+			return errors.New("otlp: batch size must be greater than 0")
+		}
+		o.batchSize = n
+		return nil
+	}
+}
+
+// FlushInterval sets the maximum duration spans are buffered before being exported. Defaults
+// to 5 seconds.
+func FlushInterval(d time.Duration) Option {
+	return func(o *options) error {
+		if d <= 0 {
+			return errors.New("otlp: flush interval must be greater than 0")
+		}
+		o.flushInterval = d
+		return nil
+	}
+}
+
+// OnError sets the function called with the errors that occur when spans are exported in the
+// background. Errors are discarded by default.
+func OnError(f func(error)) Option {
+	return func(o *options) error {
+		o.onError = f
+		return nil
+	}
+}
+
+// New returns an exporter that sends spans to the collector at the given URL, for example
+// "http://localhost:4318". service is the value of the "service.name" resource attribute.
+func New(collector, service string, opts ...Option) (*Exporter, error) {
+	o := &options{
+		client:        http.DefaultClient,
+		headers:       make(http.Header),
+		batchSize:     512,
+		flushInterval: 5 * time.Second,
+		onError:       func(error) {},
+	}
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return nil, err
+		}
+	}
+	e := &Exporter{
+		url:     strings.TrimSuffix(collector, "/") + TracesPath,
+		service: service,
+		options: o,
+		flushc:  make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go e.run()
+	return e, nil
+}
+
+// ExportSpan buffers the span, it is sent to the collector with the next batch. Spans exported
+// after Shutdown was called are discarded.
+func (e *Exporter) ExportSpan(s *middleware.Span) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.closed {
+		return
+	}
+	e.spans = append(e.spans, s)
+	if len(e.spans) >= e.options.batchSize {
+		select {
+		case e.flushc <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Flush sends the buffered spans to the collector.
+func (e *Exporter) Flush(ctx context.Context) error {
+	e.lock.Lock()
+	spans := e.spans
+	e.spans = nil
+	e.lock.Unlock()
+	if len(spans) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(e.request(spans))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for n, vs := range e.options.headers {
+		req.Header[n] = vs
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := e.options.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("otlp: failed to export %d spans - %s", len(spans), err)
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("otlp: failed to export %d spans - collector returned %s", len(spans), resp.Status)
+	}
+	return nil
+}
+
+// Shutdown stops the background export and sends the buffered spans to the collector.
+func (e *Exporter) Shutdown(ctx context.Context) error {
+	e.lock.Lock()
+	if e.closed {
+		e.lock.Unlock()
+		return nil
+	}
+	e.closed = true
+	e.lock.Unlock()
+	close(e.done)
+	<-e.stopped
+	return e.Flush(ctx)
+}
+
+// run exports the buffered spans periodically or when a batch is full until Shutdown is called.
+func (e *Exporter) run() {
+	defer close(e.stopped)
+	ticker := time.NewTicker(e.options.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-e.flushc:
+		case <-e.done:
+			return
+		}
+		if err := e.Flush(context.Background()); err != nil {
+			e.options.onError(err)
+		}
+	}
+}
+
+// request builds the OTLP export request body for the given spans.
+func (e *Exporter) request(spans []*middleware.Span) *exportRequest {
+	ss := make([]*span, len(spans))
+	for i, s := range spans {
+		ss[i] = newSpan(s)
+	}
+	return &exportRequest{
+		ResourceSpans: []*resourceSpans{{
+			Resource: &resource{Attributes: []*keyValue{attribute("service.name", e.service)}},
+			ScopeSpans: []*scopeSpans{{
+				Scope: &scope{Name: "github.com/goadesign/goa/middleware"},
+				Spans: ss,
+			}},
+		}},
+	}
+}
@@ -0,0 +1,246 @@
+package otlp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/goadesign/goa/middleware"
+)
+
+const (
+	traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	spanID  = "00f067aa0ba902b7"
+)
+
+// collector is a stand-in for an OpenTelemetry collector that records the export requests.
+type collector struct {
+	*httptest.Server
+	status int
+
+	lock     sync.Mutex
+	requests []*exportRequest
+	headers  []http.Header
+	received chan struct{}
+}
+
+func newCollector(status int) *collector {
+	c := &collector{status: status, received: make(chan struct{}, 10)}
+	c.Server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Method != "POST" || req.URL.Path != TracesPath || req.Header.Get("Content-Type") != "application/json" {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var body exportRequest
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		c.lock.Lock()
+		c.requests = append(c.requests, &body)
+		c.headers = append(c.headers, req.Header)
+		c.lock.Unlock()
+		rw.WriteHeader(c.status)
+		c.received <- struct{}{}
+	}))
+	return c
+}
+
+func (c *collector) spans() []*span {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	var spans []*span
+	for _, r := range c.requests {
+		for _, rs := range r.ResourceSpans {
+			for _, ss := range rs.ScopeSpans {
+				spans = append(spans, ss.Spans...)
+			}
+		}
+	}
+	return spans
+}
+
+func testSpan(name string) *middleware.Span {
+	start := time.Unix(1500000000, 0)
+	return &middleware.Span{
+		TraceID: traceID,
+		SpanID:  spanID,
+		Name:    name,
+		Kind:    middleware.SpanKindServer,
+		Start:   start,
+		End:     start.Add(time.Second),
+		Status:  middleware.SpanStatusError,
+		Attributes: map[string]interface{}{
+			middleware.AttributeController:     "bottle",
+			middleware.AttributeHTTPStatusCode: 500,
+			"ratio":                            0.5,
+			"cached":                           false,
+		},
+		StatusMessage: "boom",
+	}
+}
+
+func TestExporterShutdown(t *testing.T) {
+	c := newCollector(http.StatusOK)
+	defer c.Close()
+	e, err := New(c.URL+"/", "cellar", Header("Authorization", "Bearer token"))
+	if err != nil {
+		t.Fatalf("failed to create exporter: %s", err)
+	}
+
+	e.ExportSpan(testSpan("GET /bottles/:id"))
+	if err := e.Shutdown(context.Background()); err != nil {
+		t.Fatalf("failed to shutdown: %s", err)
+	}
+	e.ExportSpan(testSpan("discarded"))
+
+	if len(c.requests) != 1 {
+		t.Fatalf("expected 1 export request, got %d", len(c.requests))
+	}
+	if auth := c.headers[0].Get("Authorization"); auth != "Bearer token" {
+		t.Errorf("invalid Authorization header %q", auth)
+	}
+	rs := c.requests[0].ResourceSpans[0]
+	if attr := rs.Resource.Attributes[0]; attr.Key != "service.name" || *attr.Value.StringValue != "cellar" {
+		t.Errorf("invalid resource attribute %s", attr.Key)
+	}
+	spans := c.spans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	s := spans[0]
+	if s.TraceID != traceID || s.SpanID != spanID || s.ParentSpanID != "" {
+		t.Errorf("invalid IDs %q %q %q", s.TraceID, s.SpanID, s.ParentSpanID)
+	}
+	if s.Name != "GET /bottles/:id" || s.Kind != spanKindServer {
+		t.Errorf("invalid name or kind %q %d", s.Name, s.Kind)
+	}
+	if s.StartTimeUnixNano != "1500000000000000000" || s.EndTimeUnixNano != "1500000001000000000" {
+		t.Errorf("invalid timing %s - %s", s.StartTimeUnixNano, s.EndTimeUnixNano)
+	}
+	if s.Status.Code != 2 || s.Status.Message != "boom" {
+		t.Errorf("invalid status %+v", s.Status)
+	}
+	if len(s.Attributes) != 4 {
+		t.Fatalf("expected 4 attributes, got %d", len(s.Attributes))
+	}
+	// attributes are sorted by key
+	if a := s.Attributes[0]; a.Key != "cached" || a.Value.BoolValue == nil || *a.Value.BoolValue {
+		t.Errorf("invalid boolean attribute %+v", a.Value)
+	}
+	if a := s.Attributes[1]; a.Key != middleware.AttributeController || *a.Value.StringValue != "bottle" {
+		t.Errorf("invalid string attribute %+v", a.Value)
+	}
+	if a := s.Attributes[2]; a.Key != middleware.AttributeHTTPStatusCode || *a.Value.IntValue != "500" {
+		t.Errorf("invalid integer attribute %+v", a.Value)
+	}
+	if a := s.Attributes[3]; a.Key != "ratio" || *a.Value.DoubleValue != 0.5 {
+		t.Errorf("invalid double attribute %+v", a.Value)
+	}
+}
+
+func TestExporterBatchSize(t *testing.T) {
+	c := newCollector(http.StatusOK)
+	defer c.Close()
+	e, err := New(c.URL, "cellar", BatchSize(2), FlushInterval(time.Hour))
+	if err != nil {
+		t.Fatalf("failed to create exporter: %s", err)
+	}
+	defer e.Shutdown(context.Background())
+
+	e.ExportSpan(testSpan("a"))
+	e.ExportSpan(testSpan("b"))
+
+	select {
+	case <-c.received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("batch was not exported")
+	}
+	if spans := c.spans(); len(spans) != 2 {
+		t.Errorf("expected 2 spans, got %d", len(spans))
+	}
+}
+
+func TestExporterFlushInterval(t *testing.T) {
+	c := newCollector(http.StatusOK)
+	defer c.Close()
+	e, err := New(c.URL, "cellar", FlushInterval(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("failed to create exporter: %s", err)
+	}
+	defer e.Shutdown(context.Background())
+
+	e.ExportSpan(testSpan("a"))
+
+	select {
+	case <-c.received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("span was not exported")
+	}
+}
+
+func TestExporterError(t *testing.T) {
+	c := newCollector(http.StatusServiceUnavailable)
+	defer c.Close()
+	errs := make(chan error, 1)
+	e, err := New(c.URL, "cellar", BatchSize(1), OnError(func(err error) { errs <- err }))
+	if err != nil {
+		t.Fatalf("failed to create exporter: %s", err)
+	}
+	defer e.Shutdown(context.Background())
+
+	e.ExportSpan(testSpan("a"))
+
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Error("expected an error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("error was not reported")
+	}
+}
+
+func TestInvalidOptions(t *testing.T) {
+	cases := map[string]Option{
+		"client":   HTTPClient(nil),
+		"batch":    BatchSize(0),
+		"interval": FlushInterval(-time.Second),
+	}
+	for k, opt := range cases {
+		if _, err := New("http://localhost:4318", "cellar", opt); err == nil {
+			t.Errorf("%s: expected an error", k)
+		}
+	}
+}
+
+func TestTracerExport(t *testing.T) {
+	c := newCollector(http.StatusOK)
+	defer c.Close()
+	e, err := New(c.URL, "cellar")
+	if err != nil {
+		t.Fatalf("failed to create exporter: %s", err)
+	}
+	h := func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		return nil
+	}
+	req := httptest.NewRequest("GET", "/bottles/1", nil)
+	req.Header.Set(middleware.TraceparentHeader, "00-"+traceID+"-"+spanID+"-01")
+
+	middleware.NewTracer(middleware.Propagators(middleware.NewW3CPropagator()), middleware.ExportSpans(e))(h)(context.Background(), httptest.NewRecorder(), req)
+	if err := e.Shutdown(context.Background()); err != nil {
+		t.Fatalf("failed to shutdown: %s", err)
+	}
+
+	spans := c.spans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	if s := spans[0]; s.TraceID != traceID || s.ParentSpanID != spanID || len(s.SpanID) != 16 {
+		t.Errorf("invalid IDs %q %q %q", s.TraceID, s.SpanID, s.ParentSpanID)
+	}
+}
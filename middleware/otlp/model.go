@@ -0,0 +1,142 @@
+package otlp
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/goadesign/goa/middleware"
+)
+
+// The types below implement the JSON encoding of the OTLP ExportTraceServiceRequest message, see
+// https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding. IDs are hexadecimal strings
+// and 64 bit integers are encoded as strings.
+type (
+	exportRequest struct {
+		ResourceSpans []*resourceSpans `json:"resourceSpans"`
+	}
+
+	resourceSpans struct {
+		Resource   *resource     `json:"resource"`
+		ScopeSpans []*scopeSpans `json:"scopeSpans"`
+	}
+
+	resource struct {
+		Attributes []*keyValue `json:"attributes"`
+	}
+
+	scopeSpans struct {
+		Scope *scope  `json:"scope"`
+		Spans []*span `json:"spans"`
+	}
+
+	scope struct {
+		Name string `json:"name"`
+	}
+
+	span struct {
+		TraceID           string      `json:"traceId"`
+		SpanID            string      `json:"spanId"`
+		ParentSpanID      string      `json:"parentSpanId,omitempty"`
+		Name              string      `json:"name"`
+		Kind              int         `json:"kind"`
+		StartTimeUnixNano string      `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string      `json:"endTimeUnixNano"`
+		Attributes        []*keyValue `json:"attributes,omitempty"`
+		Status            *status     `json:"status"`
+	}
+
+	status struct {
+		Code    int    `json:"code"`
+		Message string `json:"message,omitempty"`
+	}
+
+	keyValue struct {
+		Key   string    `json:"key"`
+		Value *anyValue `json:"value"`
+	}
+
+	anyValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		BoolValue   *bool    `json:"boolValue,omitempty"`
+		IntValue    *string  `json:"intValue,omitempty"`
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+	}
+)
+
+// OTLP span kinds.
+const (
+	spanKindServer = 2
+	spanKindClient = 3
+)
+
+// newSpan converts a middleware span to its OTLP representation.
+func newSpan(s *middleware.Span) *span {
+	kind := spanKindServer
+	if s.Kind == middleware.SpanKindClient {
+		kind = spanKindClient
+	}
+	keys := make([]string, 0, len(s.Attributes))
+	for k := range s.Attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	attrs := make([]*keyValue, len(keys))
+	for i, k := range keys {
+		attrs[i] = attribute(k, s.Attributes[k])
+	}
+	return &span{
+		TraceID:           s.TraceID,
+		SpanID:            s.SpanID,
+		ParentSpanID:      s.ParentSpanID,
+		Name:              s.Name,
+		Kind:              kind,
+		StartTimeUnixNano: unixNano(s.Start),
+		EndTimeUnixNano:   unixNano(s.End),
+		Attributes:        attrs,
+		Status:            &status{Code: int(s.Status), Message: s.StatusMessage},
+	}
+}
+
+// attribute converts a span attribute to its OTLP representation.
+func attribute(key string, value interface{}) *keyValue {
+	var v anyValue
+	switch actual := value.(type) {
+	case string:
+		v.StringValue = &actual
+	case bool:
+		v.BoolValue = &actual
+	case int:
+		v.IntValue = intValue(int64(actual))
+	case int32:
+		v.IntValue = intValue(int64(actual))
+	case int64:
+		v.IntValue = intValue(actual)
+	case uint:
+		v.IntValue = intValue(int64(actual))
+	case float32:
+		f := float64(actual)
+		v.DoubleValue = &f
+	case float64:
+		v.DoubleValue = &actual
+	default:
+		s := fmt.Sprint(actual)
+		v.StringValue = &s
+	}
+	return &keyValue{Key: key, Value: &v}
+}
+
+// intValue returns the OTLP JSON representation of an integer.
+func intValue(i int64) *string {
+	s := strconv.FormatInt(i, 10)
+	return &s
+}
+
+// unixNano returns the OTLP JSON representation of a timestamp.
+func unixNano(t time.Time) string {
+	if t.IsZero() {
+		return "0"
+	}
+	return strconv.FormatInt(t.UnixNano(), 10)
+}
@@ -0,0 +1,338 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const (
+	// TraceparentHeader is the name of the W3C Trace Context header containing the trace ID,
+	// the parent span ID and the sampling flag.
+	TraceparentHeader = "traceparent"
+
+	// TracestateHeader is the name of the W3C Trace Context header containing vendor specific
+	// trace information.
+	TracestateHeader = "tracestate"
+
+	// B3Header is the name of the Zipkin B3 single header.
+	B3Header = "b3"
+
+	// B3TraceIDHeader is the name of the Zipkin B3 header containing the trace ID.
+	B3TraceIDHeader = "X-B3-TraceId"
+
+	// B3SpanIDHeader is the name of the Zipkin B3 header containing the span ID.
+	B3SpanIDHeader = "X-B3-SpanId"
+
+	// B3ParentSpanIDHeader is the name of the Zipkin B3 header containing the parent span ID.
+	B3ParentSpanIDHeader = "X-B3-ParentSpanId"
+
+	// B3SampledHeader is the name of the Zipkin B3 header containing the sampling decision.
+	B3SampledHeader = "X-B3-Sampled"
+
+	// B3FlagsHeader is the name of the Zipkin B3 header containing the debug flag.
+	B3FlagsHeader = "X-B3-Flags"
+)
+
+// List of sampling decisions.
+const (
+	// SamplingDeferred indicates that the caller did not make a sampling decision, the local
+	// sampler decides.
+	SamplingDeferred SamplingDecision = iota
+	// SamplingAccepted indicates that the trace is recorded.
+	SamplingAccepted
+	// SamplingRejected indicates that the trace is not recorded.
+	SamplingRejected
+)
+
+type (
+	// SamplingDecision indicates whether a trace is recorded.
+	SamplingDecision int
+
+	// Trace contains the trace information propagated across services.
+	Trace struct {
+		// TraceID is the ID of the trace.
+		TraceID string
+		// SpanID is the ID of the current span. The trace returned by Propagator.Extract
+		// contains the ID of the caller span.
+		SpanID string
+		// ParentSpanID is the ID of the parent span if any.
+		ParentSpanID string
+		// Sampling is the sampling decision.
+		Sampling SamplingDecision
+		// State is the W3C tracestate header value propagated unchanged to the callees.
+		State string
+	}
+
+	// Propagator reads and writes the trace information of requests. The tracer middleware
+	// uses propagators to extract the trace of incoming requests and the doer returned by
+	// TraceDoer uses them to inject the trace in outgoing requests.
+	Propagator interface {
+		// Extract returns the trace propagated by the caller, nil if the headers do not
+		// contain any.
+		Extract(http.Header) *Trace
+		// Inject sets the headers that propagate the trace to the callee.
+		Inject(*Trace, http.Header)
+	}
+
+	// goaPropagator is the Propagator returned by NewGoaPropagator.
+	goaPropagator struct{}
+
+	// w3cPropagator is the Propagator returned by NewW3CPropagator.
+	w3cPropagator struct{}
+
+	// b3Propagator is the Propagator returned by NewB3Propagator.
+	b3Propagator struct {
+		single bool
+	}
+
+	// compositePropagator is the Propagator returned by NewCompositePropagator.
+	compositePropagator []Propagator
+)
+
+// NewGoaPropagator returns the propagator that uses the TraceIDHeader and ParentSpanIDHeader
+// headers. These headers do not carry sampling decisions: the propagator does not inject traces
+// that are not sampled and the presence of a trace ID forces sampling.
+func NewGoaPropagator() Propagator {
+	return goaPropagator{}
+}
+
+// NewW3CPropagator returns the propagator that implements the W3C Trace Context specification
+// (https://www.w3.org/TR/trace-context/) using the traceparent and tracestate headers. The
+// propagator only injects traces whose IDs are lowercase hexadecimal strings of 32 (trace ID)
+// and 16 (span ID) characters, see HexTraceID and HexSpanID.
+func NewW3CPropagator() Propagator {
+	return w3cPropagator{}
+}
+
+// NewB3Propagator returns the propagator that implements the Zipkin B3 specification
+// (https://github.com/openzipkin/b3-propagation). The propagator extracts both the single and
+// the multiple headers formats, it injects the single b3 header if single is true and the
+// X-B3-* headers otherwise.
+func NewB3Propagator(single bool) Propagator {
+	return b3Propagator{single: single}
+}
+
+// NewCompositePropagator returns a propagator that extracts the trace with the first of the
+// given propagators that finds one and injects the trace with all of them.
+func NewCompositePropagator(propagators ...Propagator) Propagator {
+	return compositePropagator(propagators)
+}
+
+// HexTraceID returns a random trace ID compatible with the W3C Trace Context and B3
+// specifications.
+func HexTraceID() string {
+	return randomHex(16)
+}
+
+// HexSpanID returns a random span ID compatible with the W3C Trace Context and B3
+// specifications.
+func HexSpanID() string {
+	return randomHex(8)
+}
+
+// Extract implements Propagator.
+func (goaPropagator) Extract(h http.Header) *Trace {
+	traceID := h.Get(TraceIDHeader)
+	if traceID == "" {
+		return nil
+	}
+	return &Trace{TraceID: traceID, SpanID: h.Get(ParentSpanIDHeader), Sampling: SamplingAccepted}
+}
+
+// Inject implements Propagator.
+func (goaPropagator) Inject(t *Trace, h http.Header) {
+	if t.TraceID == "" || t.Sampling == SamplingRejected {
+		return
+	}
+	h.Set(TraceIDHeader, t.TraceID)
+	h.Set(ParentSpanIDHeader, t.SpanID)
+}
+
+// Extract implements Propagator.
+func (w3cPropagator) Extract(h http.Header) *Trace {
+	elems := strings.Split(strings.TrimSpace(h.Get(TraceparentHeader)), "-")
+	if len(elems) < 4 || len(elems[0]) != 2 || elems[0] == "ff" || (elems[0] == "00" && len(elems) != 4) {
+		return nil
+	}
+	traceID, spanID, flags := elems[1], elems[2], elems[3]
+	if !isID(traceID, 32) || !isID(spanID, 16) || !isHex(flags, 2) {
+		return nil
+	}
+	t := &Trace{TraceID: traceID, SpanID: spanID, Sampling: SamplingRejected, State: h.Get(TracestateHeader)}
+	if b, _ := hex.DecodeString(flags); b[0]&1 == 1 {
+		t.Sampling = SamplingAccepted
+	}
+	return t
+}
+
+// Inject implements Propagator.
+func (w3cPropagator) Inject(t *Trace, h http.Header) {
+	if !isID(t.TraceID, 32) || !isID(t.SpanID, 16) {
+		return
+	}
+	flags := "00"
+	if t.Sampling != SamplingRejected {
+		flags = "01"
+	}
+	h.Set(TraceparentHeader, fmt.Sprintf("00-%s-%s-%s", t.TraceID, t.SpanID, flags))
+	if t.State != "" {
+		h.Set(TracestateHeader, t.State)
+	}
+}
+
+// Extract implements Propagator.
+func (b3Propagator) Extract(h http.Header) *Trace {
+	if single := strings.TrimSpace(h.Get(B3Header)); single != "" {
+		return extractB3Single(single)
+	}
+	t := &Trace{
+		TraceID:      h.Get(B3TraceIDHeader),
+		SpanID:       h.Get(B3SpanIDHeader),
+		ParentSpanID: h.Get(B3ParentSpanIDHeader),
+		Sampling:     b3Sampling(h.Get(B3SampledHeader)),
+	}
+	if h.Get(B3FlagsHeader) == "1" {
+		t.Sampling = SamplingAccepted
+	}
+	if t.TraceID == "" {
+		if t.Sampling == SamplingDeferred {
+			return nil
+		}
+		// Sampling decision only.
+		return &Trace{Sampling: t.Sampling}
+	}
+	if !isB3TraceID(t.TraceID) || !isID(t.SpanID, 16) {
+		return nil
+	}
+	return t
+}
+
+// Inject implements Propagator.
+func (p b3Propagator) Inject(t *Trace, h http.Header) {
+	if !isB3TraceID(t.TraceID) || !isID(t.SpanID, 16) {
+		if t.Sampling == SamplingRejected {
+			if p.single {
+				h.Set(B3Header, "0")
+			} else {
+				h.Set(B3SampledHeader, "0")
+			}
+		}
+		return
+	}
+	sampled := ""
+	switch t.Sampling {
+	case SamplingAccepted:
+		sampled = "1"
+	case SamplingRejected:
+		sampled = "0"
+	}
+	if p.single {
+		elems := []string{t.TraceID, t.SpanID}
+		if sampled != "" {
+			elems = append(elems, sampled)
+		}
+		if sampled != "" && t.ParentSpanID != "" {
+			elems = append(elems, t.ParentSpanID)
+		}
+		h.Set(B3Header, strings.Join(elems, "-"))
+		return
+	}
+	h.Set(B3TraceIDHeader, t.TraceID)
+	h.Set(B3SpanIDHeader, t.SpanID)
+	if t.ParentSpanID != "" {
+		h.Set(B3ParentSpanIDHeader, t.ParentSpanID)
+	}
+	if sampled != "" {
+		h.Set(B3SampledHeader, sampled)
+	}
+}
+
+// Extract implements Propagator.
+func (c compositePropagator) Extract(h http.Header) *Trace {
+	for _, p := range c {
+		if t := p.Extract(h); t != nil {
+			return t
+		}
+	}
+	return nil
+}
+
+// Inject implements Propagator.
+func (c compositePropagator) Inject(t *Trace, h http.Header) {
+	for _, p := range c {
+		p.Inject(t, h)
+	}
+}
+
+// extractB3Single parses the value of the b3 header:
+// {TraceId}-{SpanId}-{SamplingState}-{ParentSpanId} where the last two fields are optional, or
+// {SamplingState} alone.
+func extractB3Single(v string) *Trace {
+	elems := strings.Split(v, "-")
+	if len(elems) == 1 {
+		s := b3Sampling(elems[0])
+		if s == SamplingDeferred {
+			return nil
+		}
+		return &Trace{Sampling: s}
+	}
+	if len(elems) > 4 || !isB3TraceID(elems[0]) || !isID(elems[1], 16) {
+		return nil
+	}
+	t := &Trace{TraceID: elems[0], SpanID: elems[1]}
+	if len(elems) > 2 {
+		t.Sampling = b3Sampling(elems[2])
+	}
+	if len(elems) > 3 {
+		if !isID(elems[3], 16) {
+			return nil
+		}
+		t.ParentSpanID = elems[3]
+	}
+	return t
+}
+
+// b3Sampling converts a B3 sampling state to a sampling decision.
+func b3Sampling(v string) SamplingDecision {
+	switch v {
+	case "1", "d", "true":
+		return SamplingAccepted
+	case "0", "false":
+		return SamplingRejected
+	}
+	return SamplingDeferred
+}
+
+// isB3TraceID returns true if id is a valid B3 trace ID.
+func isB3TraceID(id string) bool {
+	return isID(id, 16) || isID(id, 32)
+}
+
+// isID returns true if s is a lowercase hexadecimal string of length n that is not all zeros.
+func isID(s string, n int) bool {
+	return isHex(s, n) && strings.Trim(s, "0") != ""
+}
+
+// isHex returns true if s is a lowercase hexadecimal string of length n.
+func isHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// randomHex returns the hexadecimal representation of n random bytes.
+func randomHex(n int) string {
+	b := make([]byte, n)
+	io.ReadFull(rand.Reader, b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,261 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+const (
+	testTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	testSpanID  = "00f067aa0ba902b7"
+	testChildID = "b7ad6b7169203331"
+)
+
+func TestW3CPropagatorExtract(t *testing.T) {
+	cases := map[string]struct {
+		Traceparent, Tracestate string
+		// output
+		Trace *Trace
+	}{
+		"none":     {"", "", nil},
+		"sampled":  {"00-" + testTraceID + "-" + testSpanID + "-01", "", &Trace{TraceID: testTraceID, SpanID: testSpanID, Sampling: SamplingAccepted}},
+		"rejected": {"00-" + testTraceID + "-" + testSpanID + "-00", "", &Trace{TraceID: testTraceID, SpanID: testSpanID, Sampling: SamplingRejected}},
+		"state":    {"00-" + testTraceID + "-" + testSpanID + "-01", "congo=t61rcWkgMzE", &Trace{TraceID: testTraceID, SpanID: testSpanID, Sampling: SamplingAccepted, State: "congo=t61rcWkgMzE"}},
+		"future":   {"cc-" + testTraceID + "-" + testSpanID + "-01-what", "", &Trace{TraceID: testTraceID, SpanID: testSpanID, Sampling: SamplingAccepted}},
+
+		"invalid-version":  {"ff-" + testTraceID + "-" + testSpanID + "-01", "", nil},
+		"invalid-trace-id": {"00-00000000000000000000000000000000-" + testSpanID + "-01", "", nil},
+		"invalid-span-id":  {"00-" + testTraceID + "-00F067AA0BA902B7-01", "", nil},
+		"extra-field":      {"00-" + testTraceID + "-" + testSpanID + "-01-what", "", nil},
+	}
+
+	for k, c := range cases {
+		h := make(http.Header)
+		if c.Traceparent != "" {
+			h.Set(TraceparentHeader, c.Traceparent)
+		}
+		if c.Tracestate != "" {
+			h.Set(TracestateHeader, c.Tracestate)
+		}
+		trace := NewW3CPropagator().Extract(h)
+		if !reflect.DeepEqual(trace, c.Trace) {
+			t.Errorf("%s: invalid trace, expected %+v - got %+v", k, c.Trace, trace)
+		}
+	}
+}
+
+func TestW3CPropagatorInject(t *testing.T) {
+	cases := map[string]struct {
+		Trace *Trace
+		// output
+		Traceparent, Tracestate string
+	}{
+		"sampled":  {&Trace{TraceID: testTraceID, SpanID: testSpanID, Sampling: SamplingAccepted}, "00-" + testTraceID + "-" + testSpanID + "-01", ""},
+		"rejected": {&Trace{TraceID: testTraceID, SpanID: testSpanID, Sampling: SamplingRejected}, "00-" + testTraceID + "-" + testSpanID + "-00", ""},
+		"state":    {&Trace{TraceID: testTraceID, SpanID: testSpanID, State: "congo=t61rcWkgMzE"}, "00-" + testTraceID + "-" + testSpanID + "-01", "congo=t61rcWkgMzE"},
+		"goa-ids":  {&Trace{TraceID: "trace", SpanID: "span"}, "", ""},
+	}
+
+	for k, c := range cases {
+		h := make(http.Header)
+		NewW3CPropagator().Inject(c.Trace, h)
+		if h.Get(TraceparentHeader) != c.Traceparent {
+			t.Errorf("%s: invalid traceparent, expected %v - got %v", k, c.Traceparent, h.Get(TraceparentHeader))
+		}
+		if h.Get(TracestateHeader) != c.Tracestate {
+			t.Errorf("%s: invalid tracestate, expected %v - got %v", k, c.Tracestate, h.Get(TracestateHeader))
+		}
+	}
+}
+
+func TestB3PropagatorExtract(t *testing.T) {
+	cases := map[string]struct {
+		Headers map[string]string
+		// output
+		Trace *Trace
+	}{
+		"none": {nil, nil},
+
+		"single":          {map[string]string{B3Header: testTraceID + "-" + testSpanID}, &Trace{TraceID: testTraceID, SpanID: testSpanID}},
+		"single-sampled":  {map[string]string{B3Header: testTraceID + "-" + testSpanID + "-1-" + testChildID}, &Trace{TraceID: testTraceID, SpanID: testSpanID, ParentSpanID: testChildID, Sampling: SamplingAccepted}},
+		"single-debug":    {map[string]string{B3Header: testTraceID + "-" + testSpanID + "-d"}, &Trace{TraceID: testTraceID, SpanID: testSpanID, Sampling: SamplingAccepted}},
+		"single-short-id": {map[string]string{B3Header: testChildID + "-" + testSpanID + "-0"}, &Trace{TraceID: testChildID, SpanID: testSpanID, Sampling: SamplingRejected}},
+		"single-deny":     {map[string]string{B3Header: "0"}, &Trace{Sampling: SamplingRejected}},
+		"single-invalid":  {map[string]string{B3Header: "trace-span"}, nil},
+
+		"multi":         {map[string]string{B3TraceIDHeader: testTraceID, B3SpanIDHeader: testSpanID}, &Trace{TraceID: testTraceID, SpanID: testSpanID}},
+		"multi-sampled": {map[string]string{B3TraceIDHeader: testTraceID, B3SpanIDHeader: testSpanID, B3ParentSpanIDHeader: testChildID, B3SampledHeader: "1"}, &Trace{TraceID: testTraceID, SpanID: testSpanID, ParentSpanID: testChildID, Sampling: SamplingAccepted}},
+		"multi-debug":   {map[string]string{B3TraceIDHeader: testTraceID, B3SpanIDHeader: testSpanID, B3FlagsHeader: "1"}, &Trace{TraceID: testTraceID, SpanID: testSpanID, Sampling: SamplingAccepted}},
+		"multi-deny":    {map[string]string{B3SampledHeader: "0"}, &Trace{Sampling: SamplingRejected}},
+		"multi-invalid": {map[string]string{B3TraceIDHeader: testTraceID}, nil},
+	}
+
+	for k, c := range cases {
+		h := make(http.Header)
+		for n, v := range c.Headers {
+			h.Set(n, v)
+		}
+		trace := NewB3Propagator(true).Extract(h)
+		if !reflect.DeepEqual(trace, c.Trace) {
+			t.Errorf("%s: invalid trace, expected %+v - got %+v", k, c.Trace, trace)
+		}
+	}
+}
+
+func TestB3PropagatorInject(t *testing.T) {
+	cases := map[string]struct {
+		Single bool
+		Trace  *Trace
+		// output
+		Headers map[string]string
+	}{
+		"single":           {true, &Trace{TraceID: testTraceID, SpanID: testSpanID}, map[string]string{B3Header: testTraceID + "-" + testSpanID}},
+		"single-sampled":   {true, &Trace{TraceID: testTraceID, SpanID: testSpanID, ParentSpanID: testChildID, Sampling: SamplingAccepted}, map[string]string{B3Header: testTraceID + "-" + testSpanID + "-1-" + testChildID}},
+		"single-deny":      {true, &Trace{Sampling: SamplingRejected}, map[string]string{B3Header: "0"}},
+		"multi":            {false, &Trace{TraceID: testTraceID, SpanID: testSpanID}, map[string]string{B3TraceIDHeader: testTraceID, B3SpanIDHeader: testSpanID}},
+		"multi-sampled":    {false, &Trace{TraceID: testTraceID, SpanID: testSpanID, ParentSpanID: testChildID, Sampling: SamplingAccepted}, map[string]string{B3TraceIDHeader: testTraceID, B3SpanIDHeader: testSpanID, B3ParentSpanIDHeader: testChildID, B3SampledHeader: "1"}},
+		"multi-deny":       {false, &Trace{TraceID: testTraceID, SpanID: testSpanID, Sampling: SamplingRejected}, map[string]string{B3TraceIDHeader: testTraceID, B3SpanIDHeader: testSpanID, B3SampledHeader: "0"}},
+		"invalid-ids":      {true, &Trace{TraceID: "trace", SpanID: "span"}, map[string]string{}},
+		"invalid-ids-deny": {false, &Trace{TraceID: "trace", SpanID: "span", Sampling: SamplingRejected}, map[string]string{B3SampledHeader: "0"}},
+	}
+
+	for k, c := range cases {
+		h := make(http.Header)
+		NewB3Propagator(c.Single).Inject(c.Trace, h)
+		if len(h) != len(c.Headers) {
+			t.Errorf("%s: invalid headers, expected %v - got %v", k, c.Headers, h)
+		}
+		for n, v := range c.Headers {
+			if h.Get(n) != v {
+				t.Errorf("%s: invalid %s header, expected %v - got %v", k, n, v, h.Get(n))
+			}
+		}
+	}
+}
+
+func TestTracerPropagators(t *testing.T) {
+	var newSpanID = func() string { return testChildID }
+
+	cases := map[string]struct {
+		Rate    int
+		Headers map[string]string
+		// output
+		CtxTraceID, CtxSpanID, CtxParentID string
+		Sampling                           SamplingDecision
+	}{
+		"w3c-sampled":       {0, map[string]string{TraceparentHeader: "00-" + testTraceID + "-" + testSpanID + "-01"}, testTraceID, testChildID, testSpanID, SamplingAccepted},
+		"w3c-rejected":      {100, map[string]string{TraceparentHeader: "00-" + testTraceID + "-" + testSpanID + "-00"}, "", "", "", SamplingRejected},
+		"b3-deferred":       {100, map[string]string{B3Header: testTraceID + "-" + testSpanID}, testTraceID, testChildID, testSpanID, SamplingAccepted},
+		"b3-deferred-zero":  {0, map[string]string{B3Header: testTraceID + "-" + testSpanID}, "", "", "", SamplingRejected},
+		"b3-sampled-no-ids": {0, map[string]string{B3SampledHeader: "1"}, "new", testChildID, "", SamplingAccepted},
+		"b3-deny":           {100, map[string]string{B3Header: "0"}, "", "", "", SamplingRejected},
+		"w3c-first":         {100, map[string]string{TraceparentHeader: "00-" + testTraceID + "-" + testSpanID + "-01", B3Header: "0"}, testTraceID, testChildID, testSpanID, SamplingAccepted},
+	}
+
+	for k, c := range cases {
+		var (
+			ctxTraceID, ctxSpanID, ctxParentID string
+			ctxTrace                           *Trace
+
+			m = NewTracer(
+				SamplingPercent(c.Rate),
+				SpanIDFunc(newSpanID),
+				TraceIDFunc(func() string { return "new" }),
+				Propagators(NewW3CPropagator(), NewB3Propagator(true)),
+			)
+			h = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+				ctxTraceID = ContextTraceID(ctx)
+				ctxSpanID = ContextSpanID(ctx)
+				ctxParentID = ContextParentSpanID(ctx)
+				ctxTrace = ContextTrace(ctx)
+				return nil
+			}
+		)
+		req, _ := http.NewRequest("GET", "/", nil)
+		for n, v := range c.Headers {
+			req.Header.Set(n, v)
+		}
+
+		m(h)(context.Background(), httptest.NewRecorder(), req)
+
+		if ctxTraceID != c.CtxTraceID {
+			t.Errorf("%s: invalid TraceID, expected %v - got %v", k, c.CtxTraceID, ctxTraceID)
+		}
+		if ctxSpanID != c.CtxSpanID {
+			t.Errorf("%s: invalid SpanID, expected %v - got %v", k, c.CtxSpanID, ctxSpanID)
+		}
+		if ctxParentID != c.CtxParentID {
+			t.Errorf("%s: invalid ParentSpanID, expected %v - got %v", k, c.CtxParentID, ctxParentID)
+		}
+		if ctxTrace == nil {
+			t.Errorf("%s: missing trace", k)
+		} else if ctxTrace.Sampling != c.Sampling {
+			t.Errorf("%s: invalid sampling, expected %v - got %v", k, c.Sampling, ctxTrace.Sampling)
+		}
+	}
+}
+
+func TestTracerDefaultIDs(t *testing.T) {
+	var trace *Trace
+	h := func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		trace = ContextTrace(ctx)
+		return nil
+	}
+	req, _ := http.NewRequest("GET", "/", nil)
+
+	NewTracer(Propagators(NewW3CPropagator()))(h)(context.Background(), httptest.NewRecorder(), req)
+
+	if !isID(trace.TraceID, 32) {
+		t.Errorf("invalid default trace ID %q", trace.TraceID)
+	}
+	if !isID(trace.SpanID, 16) {
+		t.Errorf("invalid default span ID %q", trace.SpanID)
+	}
+}
+
+type headerDoer struct {
+	header http.Header
+}
+
+func (d *headerDoer) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	d.header = req.Header
+	return nil, nil
+}
+
+func TestTraceDoer(t *testing.T) {
+	sampled := &Trace{TraceID: testTraceID, SpanID: testSpanID, Sampling: SamplingAccepted, State: "congo=t61rcWkgMzE"}
+	rejected := &Trace{TraceID: testTraceID, SpanID: testSpanID, Sampling: SamplingRejected}
+
+	cases := map[string]struct {
+		Ctx         context.Context
+		Propagators []Propagator
+		// output
+		Headers map[string]string
+	}{
+		"none":        {context.Background(), nil, map[string]string{}},
+		"goa":         {WithTrace(context.Background(), "trace", "span", ""), nil, map[string]string{TraceIDHeader: "trace", ParentSpanIDHeader: "span"}},
+		"goa-reject":  {WithTraceContext(context.Background(), rejected), nil, map[string]string{}},
+		"w3c":         {WithTraceContext(context.Background(), sampled), []Propagator{NewW3CPropagator()}, map[string]string{TraceparentHeader: "00-" + testTraceID + "-" + testSpanID + "-01", TracestateHeader: "congo=t61rcWkgMzE"}},
+		"w3c-reject":  {WithTraceContext(context.Background(), rejected), []Propagator{NewW3CPropagator()}, map[string]string{TraceparentHeader: "00-" + testTraceID + "-" + testSpanID + "-00"}},
+		"w3c-and-b3":  {WithTrace(context.Background(), testTraceID, testSpanID, ""), []Propagator{NewW3CPropagator(), NewB3Propagator(true)}, map[string]string{TraceparentHeader: "00-" + testTraceID + "-" + testSpanID + "-01", B3Header: testTraceID + "-" + testSpanID + "-1"}},
+		"keeps-state": {WithTrace(WithTraceContext(context.Background(), sampled), testTraceID, testChildID, testSpanID), []Propagator{NewW3CPropagator()}, map[string]string{TraceparentHeader: "00-" + testTraceID + "-" + testChildID + "-01", TracestateHeader: "congo=t61rcWkgMzE"}},
+	}
+
+	for k, c := range cases {
+		doer := &headerDoer{}
+		req, _ := http.NewRequest("GET", "/", nil)
+		TraceDoer(doer, c.Propagators...).Do(c.Ctx, req)
+		headers := doer.header
+		if len(headers) != len(c.Headers) {
+			t.Errorf("%s: invalid headers, expected %v - got %v", k, c.Headers, headers)
+		}
+		for n, v := range c.Headers {
+			if headers.Get(n) != v {
+				t.Errorf("%s: invalid %s header, expected %v - got %v", k, n, v, headers.Get(n))
+			}
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+
+	jwtgo "github.com/dgrijalva/jwt-go"
+	"github.com/goadesign/goa"
+	"github.com/goadesign/goa/middleware/security/jwt"
+)
+
+// KeyFunc returns the key that identifies the client making the request. Requests for which the
+// key is empty are not limited.
+type KeyFunc func(ctx context.Context, req *http.Request) (string, error)
+
+var (
+	// keys contains the key functions registered with RegisterKey indexed by name.
+	keys = make(map[string]KeyFunc)
+	// keysLock protects keys.
+	keysLock sync.RWMutex
+)
+
+// ClientIP identifies clients by the IP address of the request remote address. Services deployed
+// behind proxies should use a key function that reads the client IP from the header set by the
+// proxy instead.
+func ClientIP(ctx context.Context, req *http.Request) (string, error) {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr, nil
+	}
+	return host, nil
+}
+
+// Header returns a key function that identifies clients by the value of the given request header.
+func Header(name string) KeyFunc {
+	return func(ctx context.Context, req *http.Request) (string, error) {
+		return req.Header.Get(name), nil
+	}
+}
+
+// APIKey returns a key function that identifies clients by the API key defined by the given
+// security scheme.
+func APIKey(scheme *goa.APIKeySecurity) KeyFunc {
+	if scheme.In == goa.LocQuery {
+		return func(ctx context.Context, req *http.Request) (string, error) {
+			return req.URL.Query().Get(scheme.Name), nil
+		}
+	}
+	return Header(scheme.Name)
+}
+
+// JWTSubject identifies clients by the "sub" claim of the JWT validated by the jwt middleware. The
+// rate limit middleware must thus run after the jwt middleware.
+func JWTSubject(ctx context.Context, req *http.Request) (string, error) {
+	token := jwt.ContextJWT(ctx)
+	if token == nil {
+		return "", nil
+	}
+	switch claims := token.Claims.(type) {
+	case jwtgo.MapClaims:
+		sub, _ := claims["sub"].(string)
+		return sub, nil
+	case *jwtgo.StandardClaims:
+		return claims.Subject, nil
+	}
+	return "", nil
+}
+
+// RegisterKey registers a key function under the given name. The generated code uses the
+// registered functions for the actions whose "ratelimit:key" metadata is "custom:<name>".
+func RegisterKey(name string, key KeyFunc) {
+	keysLock.Lock()
+	defer keysLock.Unlock()
+	keys[name] = key
+}
+
+// Custom returns a key function that invokes the function registered under the given name with
+// RegisterKey. The lookup happens when requests are handled so that functions may be registered
+// after the controllers are mounted.
+func Custom(name string) KeyFunc {
+	return func(ctx context.Context, req *http.Request) (string, error) {
+		keysLock.RLock()
+		key, ok := keys[name]
+		keysLock.RUnlock()
+		if !ok {
+			return "", goa.ErrInternal(fmt.Sprintf("no rate limit key function registered for %q", name))
+		}
+		return key(ctx, req)
+	}
+}
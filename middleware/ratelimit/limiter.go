@@ -0,0 +1,208 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+type (
+	// Limiter is the interface implemented by the rate limiting algorithms. Implementations
+	// must be safe for concurrent use.
+	Limiter interface {
+		// Take records a request made by the client identified by key and reports whether
+		// the request is allowed.
+		Take(key string) Decision
+	}
+
+	// Decision is the outcome of a call to Limiter.Take.
+	Decision struct {
+		// Allowed is true if the request may proceed.
+		Allowed bool
+		// Limit is the maximum number of requests the client may make at once.
+		Limit int
+		// Remaining is the number of requests the client may still make.
+		Remaining int
+		// Reset is the duration until the client allowance is fully restored.
+		Reset time.Duration
+		// RetryAfter is the duration the client must wait before making another request
+		// when the request is not allowed.
+		RetryAfter time.Duration
+	}
+
+	// tokenBucket is the Limiter returned by NewTokenBucket.
+	tokenBucket struct {
+		rate      float64 // tokens per nanosecond
+		burst     int
+		now       func() time.Time
+		lock      sync.Mutex
+		buckets   map[string]*bucket
+		lastSweep time.Time
+	}
+
+	// bucket is the state of a single client token bucket.
+	bucket struct {
+		tokens float64
+		last   time.Time
+	}
+
+	// slidingWindow is the Limiter returned by NewSlidingWindow.
+	slidingWindow struct {
+		limit     int
+		window    time.Duration
+		now       func() time.Time
+		lock      sync.Mutex
+		counters  map[string]*counter
+		lastSweep time.Time
+	}
+
+	// counter is the state of a single client sliding window. The window is approximated by
+	// weighting the count of the previous fixed window by the part of it that overlaps the
+	// sliding window.
+	counter struct {
+		start time.Time
+		prev  int
+		curr  int
+	}
+)
+
+// NewTokenBucket returns a limiter that allows requests requests per period with bursts of up to
+// burst requests. The allowance of each client is replenished continuously. burst defaults to
+// requests if it is not positive.
+func NewTokenBucket(requests int, period time.Duration, burst int) Limiter {
+	if burst <= 0 {
+		burst = requests
+	}
+	return &tokenBucket{
+		rate:    float64(requests) / float64(period),
+		burst:   burst,
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// NewSlidingWindow returns a limiter that allows limit requests during any period of duration
+// window.
+func NewSlidingWindow(limit int, window time.Duration) Limiter {
+	return &slidingWindow{
+		limit:    limit,
+		window:   window,
+		now:      time.Now,
+		counters: make(map[string]*counter),
+	}
+}
+
+// Take implements Limiter.
+func (l *tokenBucket) Take(key string) Decision {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	now := l.now()
+	l.sweep(now)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.burst), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(float64(l.burst), b.tokens+float64(now.Sub(b.last))*l.rate)
+	b.last = now
+	d := Decision{Limit: l.burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		d.Allowed = true
+	} else {
+		d.RetryAfter = duration((1 - b.tokens) / l.rate)
+	}
+	d.Remaining = int(b.tokens)
+	d.Reset = duration((float64(l.burst) - b.tokens) / l.rate)
+	return d
+}
+
+// sweep deletes the buckets that are full at most once per refill period so that the memory
+// used by the limiter does not grow with the number of clients.
+func (l *tokenBucket) sweep(now time.Time) {
+	fill := duration(float64(l.burst) / l.rate)
+	if now.Sub(l.lastSweep) < fill {
+		return
+	}
+	l.lastSweep = now
+	for k, b := range l.buckets {
+		if now.Sub(b.last) >= fill {
+			delete(l.buckets, k)
+		}
+	}
+}
+
+// Take implements Limiter.
+func (l *slidingWindow) Take(key string) Decision {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	now := l.now()
+	l.sweep(now)
+	c, ok := l.counters[key]
+	if !ok {
+		c = &counter{start: now}
+		l.counters[key] = c
+	}
+	if elapsed := now.Sub(c.start); elapsed >= l.window {
+		c.prev = c.curr
+		if elapsed >= 2*l.window {
+			c.prev = 0
+		}
+		c.curr = 0
+		c.start = c.start.Add(elapsed / l.window * l.window)
+	}
+	elapsed := now.Sub(c.start)
+	weight := 1 - float64(elapsed)/float64(l.window)
+	count := float64(c.prev)*weight + float64(c.curr)
+	d := Decision{Limit: l.limit}
+	if count+1 <= float64(l.limit) {
+		c.curr++
+		count++
+		d.Allowed = true
+	} else {
+		d.RetryAfter = l.retryAfter(c, elapsed)
+	}
+	d.Remaining = int(float64(l.limit) - count)
+	if d.Remaining < 0 {
+		d.Remaining = 0
+	}
+	switch {
+	case c.curr > 0:
+		d.Reset = 2*l.window - elapsed
+	case c.prev > 0:
+		d.Reset = l.window - elapsed
+	}
+	return d
+}
+
+// retryAfter returns the duration until the estimated count of requests of c drops below the
+// limit.
+func (l *slidingWindow) retryAfter(c *counter, elapsed time.Duration) time.Duration {
+	target := float64(l.limit - 1)
+	if c.curr <= l.limit-1 {
+		// The count drops below the limit during the current window.
+		return duration(float64(l.window)*(1-(target-float64(c.curr))/float64(c.prev))) - elapsed
+	}
+	// The current count becomes the previous count of the next window.
+	return l.window - elapsed + duration(float64(l.window)*(1-target/float64(c.curr)))
+}
+
+// sweep deletes the counters that have not been used for two windows at most once per window so
+// that the memory used by the limiter does not grow with the number of clients.
+func (l *slidingWindow) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	l.lastSweep = now
+	for k, c := range l.counters {
+		if now.Sub(c.start) >= 2*l.window {
+			delete(l.counters, k)
+		}
+	}
+}
+
+// duration converts a number of nanoseconds computed with floating point arithmetic to a duration
+// rounded to the microsecond so that rounding errors do not show in the headers.
+func duration(ns float64) time.Duration {
+	return time.Duration(math.Round(ns/1e3)) * time.Microsecond
+}
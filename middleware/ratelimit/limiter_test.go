@@ -0,0 +1,106 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+// clock is a fake clock used to control the time seen by the limiters.
+type clock struct {
+	t time.Time
+}
+
+func newClock() *clock                   { return &clock{t: time.Unix(1000, 0)} }
+func (c *clock) now() time.Time          { return c.t }
+func (c *clock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+// take makes n requests with the same key and returns the number of allowed requests.
+func take(l Limiter, n int) int {
+	return takeKey(l, "key", n)
+}
+
+// takeKey makes n requests with the given key and returns the number of allowed requests.
+func takeKey(l Limiter, k string, n int) int {
+	allowed := 0
+	for i := 0; i < n; i++ {
+		if l.Take(k).Allowed {
+			allowed++
+		}
+	}
+	return allowed
+}
+
+func TestTokenBucket(t *testing.T) {
+	c := newClock()
+	l := NewTokenBucket(10, time.Second, 5).(*tokenBucket)
+	l.now = c.now
+
+	if n := take(l, 10); n != 5 {
+		t.Errorf("burst: got %d allowed requests, expected 5", n)
+	}
+	d := l.Take("key")
+	if d.Allowed {
+		t.Errorf("exhausted bucket allowed request")
+	}
+	if d.Limit != 5 || d.Remaining != 0 {
+		t.Errorf("got limit %d and remaining %d, expected 5 and 0", d.Limit, d.Remaining)
+	}
+	if d.RetryAfter != 100*time.Millisecond {
+		t.Errorf("got retry after %s, expected 100ms", d.RetryAfter)
+	}
+	if d.Reset != 500*time.Millisecond {
+		t.Errorf("got reset %s, expected 500ms", d.Reset)
+	}
+
+	c.advance(200 * time.Millisecond)
+	if n := take(l, 5); n != 2 {
+		t.Errorf("refill: got %d allowed requests, expected 2", n)
+	}
+	if n := takeKey(l, "other", 5); n != 5 {
+		t.Errorf("other key: got %d allowed requests, expected 5", n)
+	}
+
+	c.advance(time.Hour)
+	d = l.Take("key")
+	if !d.Allowed || d.Remaining != 4 {
+		t.Errorf("full bucket: got allowed %v and remaining %d, expected true and 4", d.Allowed, d.Remaining)
+	}
+	if len(l.buckets) != 1 {
+		t.Errorf("got %d buckets after sweep, expected 1", len(l.buckets))
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	c := newClock()
+	l := NewSlidingWindow(10, time.Second).(*slidingWindow)
+	l.now = c.now
+
+	if n := take(l, 15); n != 10 {
+		t.Errorf("window: got %d allowed requests, expected 10", n)
+	}
+	d := l.Take("key")
+	if d.Allowed || d.Remaining != 0 || d.Limit != 10 {
+		t.Errorf("got allowed %v, remaining %d and limit %d, expected false, 0 and 10", d.Allowed, d.Remaining, d.Limit)
+	}
+	if d.RetryAfter != 1100*time.Millisecond {
+		t.Errorf("got retry after %s, expected 1.1s", d.RetryAfter)
+	}
+
+	// Half of the previous window overlaps the sliding window: 5 requests remain.
+	c.advance(1500 * time.Millisecond)
+	if n := take(l, 10); n != 5 {
+		t.Errorf("sliding: got %d allowed requests, expected 5", n)
+	}
+	d = l.Take("key")
+	if d.RetryAfter != 100*time.Millisecond {
+		t.Errorf("got retry after %s, expected 100ms", d.RetryAfter)
+	}
+
+	c.advance(time.Hour)
+	if n := take(l, 10); n != 10 {
+		t.Errorf("expired: got %d allowed requests, expected 10", n)
+	}
+	if len(l.counters) != 1 {
+		t.Errorf("got %d counters after sweep, expected 1", len(l.counters))
+	}
+}
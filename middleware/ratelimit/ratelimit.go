@@ -0,0 +1,85 @@
+/*
+Package ratelimit implements a middleware that throttles the requests made to goa actions.
+
+The middleware identifies clients with a key function (client IP, API key, JWT subject or any
+custom function) and asks a limiter whether the client may make another request. Requests that
+exceed the limit are rejected with ErrTooManyRequests which results in a 429 response. All the
+responses include the X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset headers,
+rejected requests also include the Retry-After header.
+Usage:
+
+    limiter := ratelimit.NewTokenBucket(100, time.Minute, 20)
+    service.Use(ratelimit.New(limiter, ratelimit.ClientIP))
+
+The middleware may also be applied to individual actions by declaring the limit in the design
+with the "ratelimit" metadata, the generated Mount functions then wire it automatically:
+
+    Action("show", func() {
+        Metadata("ratelimit", "100/1m")
+        Metadata("ratelimit:key", "jwt")
+    })
+*/
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/goadesign/goa"
+)
+
+const (
+	// LimitHeader is the name of the header that contains the maximum number of requests a
+	// client may make at once.
+	LimitHeader = "X-RateLimit-Limit"
+
+	// RemainingHeader is the name of the header that contains the number of requests the
+	// client may still make.
+	RemainingHeader = "X-RateLimit-Remaining"
+
+	// ResetHeader is the name of the header that contains the number of seconds until the
+	// client allowance is fully restored.
+	ResetHeader = "X-RateLimit-Reset"
+
+	// RetryAfterHeader is the name of the header that contains the number of seconds the
+	// client must wait before making another request.
+	RetryAfterHeader = "Retry-After"
+)
+
+// ErrTooManyRequests is the error returned by the middleware when a client exceeds its limit.
+var ErrTooManyRequests = goa.NewErrorClass("rate_limit_exceeded", 429)
+
+// New returns a middleware that limits the requests made by the clients identified by key using
+// limiter. Requests for which key returns an empty string are not limited.
+func New(limiter Limiter, key KeyFunc) goa.Middleware {
+	return func(h goa.Handler) goa.Handler {
+		return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			k, err := key(ctx, req)
+			if err != nil {
+				return err
+			}
+			if k == "" {
+				return h(ctx, rw, req)
+			}
+			d := limiter.Take(k)
+			header := rw.Header()
+			header.Set(LimitHeader, strconv.Itoa(d.Limit))
+			header.Set(RemainingHeader, strconv.Itoa(d.Remaining))
+			header.Set(ResetHeader, seconds(d.Reset))
+			if !d.Allowed {
+				retry := seconds(d.RetryAfter)
+				header.Set(RetryAfterHeader, retry)
+				return ErrTooManyRequests("rate limit exceeded", "limit", d.Limit, "retry_after", retry)
+			}
+			return h(ctx, rw, req)
+		}
+	}
+}
+
+// seconds formats d as a number of seconds rounded up.
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
@@ -0,0 +1,143 @@
+package ratelimit_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	jwtgo "github.com/dgrijalva/jwt-go"
+	"github.com/goadesign/goa"
+	"github.com/goadesign/goa/middleware/ratelimit"
+	"github.com/goadesign/goa/middleware/security/jwt"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("New", func() {
+	var (
+		limiter ratelimit.Limiter
+		key     ratelimit.KeyFunc
+		ctx     context.Context
+		req     *http.Request
+		rw      *httptest.ResponseRecorder
+		called  int
+		errs    []error
+	)
+
+	BeforeEach(func() {
+		limiter = ratelimit.NewTokenBucket(2, time.Minute, 0)
+		key = ratelimit.ClientIP
+		ctx = context.Background()
+		req = httptest.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "10.0.0.1:4242"
+		called = 0
+		errs = nil
+	})
+
+	JustBeforeEach(func() {
+		h := func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			called++
+			return nil
+		}
+		mw := ratelimit.New(limiter, key)(h)
+		for i := 0; i < 3; i++ {
+			rw = httptest.NewRecorder()
+			errs = append(errs, mw(ctx, rw, req))
+		}
+	})
+
+	It("rejects the requests that exceed the limit", func() {
+		Ω(called).Should(Equal(2))
+		Ω(errs[0]).ShouldNot(HaveOccurred())
+		Ω(errs[1]).ShouldNot(HaveOccurred())
+		Ω(errs[2]).Should(HaveOccurred())
+		Ω(errs[2].(goa.ServiceError).ResponseStatus()).Should(Equal(http.StatusTooManyRequests))
+	})
+
+	It("sets the rate limit headers", func() {
+		Ω(rw.Header().Get(ratelimit.LimitHeader)).Should(Equal("2"))
+		Ω(rw.Header().Get(ratelimit.RemainingHeader)).Should(Equal("0"))
+		Ω(rw.Header().Get(ratelimit.ResetHeader)).Should(Equal("60"))
+		Ω(rw.Header().Get(ratelimit.RetryAfterHeader)).Should(Equal("30"))
+	})
+
+	Context("with requests from different clients", func() {
+		BeforeEach(func() {
+			i := 0
+			key = func(ctx context.Context, req *http.Request) (string, error) {
+				i++
+				return string(rune('a' + i)), nil
+			}
+		})
+
+		It("limits each client separately", func() {
+			Ω(called).Should(Equal(3))
+		})
+	})
+
+	Context("with an empty key", func() {
+		BeforeEach(func() {
+			key = ratelimit.JWTSubject
+		})
+
+		It("does not limit the requests", func() {
+			Ω(called).Should(Equal(3))
+			Ω(rw.Header().Get(ratelimit.LimitHeader)).Should(BeEmpty())
+		})
+	})
+
+	Context("with a JWT subject key", func() {
+		BeforeEach(func() {
+			key = ratelimit.JWTSubject
+			token := &jwtgo.Token{Claims: jwtgo.MapClaims{"sub": "alice"}}
+			ctx = jwt.WithJWT(ctx, token)
+		})
+
+		It("limits the requests of the subject", func() {
+			Ω(called).Should(Equal(2))
+		})
+	})
+
+	Context("with a custom key that is not registered", func() {
+		BeforeEach(func() {
+			key = ratelimit.Custom("unknown")
+		})
+
+		It("returns an internal error", func() {
+			Ω(called).Should(Equal(0))
+			Ω(errs[0].(goa.ServiceError).ResponseStatus()).Should(Equal(http.StatusInternalServerError))
+		})
+	})
+})
+
+var _ = Describe("Key functions", func() {
+	var req *http.Request
+
+	BeforeEach(func() {
+		req = httptest.NewRequest("GET", "/?key=query", nil)
+		req.RemoteAddr = "10.0.0.1:4242"
+		req.Header.Set("X-API-Key", "header")
+	})
+
+	It("extracts the client IP", func() {
+		Ω(ratelimit.ClientIP(context.Background(), req)).Should(Equal("10.0.0.1"))
+	})
+
+	It("extracts the API key from the header", func() {
+		key := ratelimit.APIKey(&goa.APIKeySecurity{In: goa.LocHeader, Name: "X-API-Key"})
+		Ω(key(context.Background(), req)).Should(Equal("header"))
+	})
+
+	It("extracts the API key from the query string", func() {
+		key := ratelimit.APIKey(&goa.APIKeySecurity{In: goa.LocQuery, Name: "key"})
+		Ω(key(context.Background(), req)).Should(Equal("query"))
+	})
+
+	It("invokes the registered custom key functions", func() {
+		ratelimit.RegisterKey("tenant", func(ctx context.Context, req *http.Request) (string, error) {
+			return "acme", nil
+		})
+		Ω(ratelimit.Custom("tenant")(context.Background(), req)).Should(Equal("acme"))
+	})
+})
@@ -0,0 +1,144 @@
+/*
+Package apikey implements a middleware that authenticates requests using API keys. The key is read
+from the request header or query string parameter named by the goa.APIKeySecurity scheme, looked
+up in a KeyStore and the principal it identifies is made available to the handlers via
+goa.ContextPrincipal. The middleware also checks that the principal is allowed the scopes required by
+the action.
+
+MemoryStore is a KeyStore that keeps the keys in memory, it only stores the hashes of the keys so
+that the keys cannot be recovered from the store or from the configuration used to load it.
+
+The generated UseXXXAPIKeyMiddleware functions mount the middleware for the API key security
+schemes defined in the design, e.g.:
+
+    store := apikey.NewMemoryStore()
+    store.AddHash(os.Getenv("ADMIN_KEY_HASH"), &goa.Principal{ID: "admin", Scopes: []string{"api:admin"}})
+    app.UseAPIKeyAPIKeyMiddleware(service, store)
+*/
+package apikey
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/goadesign/goa"
+)
+
+var (
+	// ErrUnauthorized is the error returned when the API key is missing or unknown.
+	ErrUnauthorized = goa.NewErrorClass("api_key_unauthorized", 401)
+
+	// ErrForbidden is the error returned when the principal is not allowed the scopes required
+	// by the action.
+	ErrForbidden = goa.NewErrorClass("api_key_forbidden", 403)
+
+	// ErrLookupUnavailable is the error returned when the key store fails to look up the key.
+	ErrLookupUnavailable = goa.NewErrorClass("api_key_lookup_unavailable", 503)
+)
+
+type (
+	// KeyStore looks up the principals identified by API keys.
+	KeyStore interface {
+		// Lookup returns the principal identified by key or nil if the key is unknown. It
+		// returns an error only if the lookup failed.
+		Lookup(ctx context.Context, key string) (*goa.Principal, error)
+	}
+
+	// MemoryStore is a KeyStore that keeps the hashes of the keys in memory.
+	MemoryStore struct {
+		lock       sync.RWMutex
+		principals map[string]*goa.Principal
+	}
+)
+
+// New returns a middleware that authenticates the requests with the API key read from the
+// location defined by scheme and looked up in store. The middleware checks that the principal
+// is allowed the scopes returned by goa.ContextRequiredScopes.
+func New(store KeyStore, scheme *goa.APIKeySecurity) goa.Middleware {
+	return func(h goa.Handler) goa.Handler {
+		return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			var key string
+			switch scheme.In {
+			case goa.LocHeader:
+				key = req.Header.Get(scheme.Name)
+			case goa.LocQuery:
+				key = req.URL.Query().Get(scheme.Name)
+			default:
+				return fmt.Errorf("security scheme with location (in) %q not supported", scheme.In)
+			}
+			if key == "" {
+				return ErrUnauthorized(fmt.Sprintf("missing API key in %s %q", scheme.In, scheme.Name))
+			}
+			p, err := store.Lookup(ctx, key)
+			if err != nil {
+				goa.LogError(ctx, "failed to look up API key", "err", err)
+				return ErrLookupUnavailable("failed to look up API key")
+			}
+			if p == nil {
+				return ErrUnauthorized("invalid API key")
+			}
+			required := goa.ContextRequiredScopes(ctx)
+			if missing := goa.MissingScopes(required, p.Scopes); missing != nil {
+				return ErrForbidden("API key does not allow the required scopes",
+					"required", required, "scopes", p.Scopes)
+			}
+			return h(goa.WithPrincipal(ctx, p), rw, req)
+		}
+	}
+}
+
+// HashKey returns the hash of key stored by MemoryStore: the hex encoded SHA-256 of the key.
+// Use it to compute the hashes given to AddHash.
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewMemoryStore returns an empty store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{principals: make(map[string]*goa.Principal)}
+}
+
+// Add adds the key identifying p to the store. Only the hash of the key is kept.
+func (s *MemoryStore) Add(key string, p *goa.Principal) error {
+	if key == "" {
+		return errors.New("apikey: key must not be empty")
+	}
+	return s.AddHash(HashKey(key), p)
+}
+
+// AddHash adds the key whose hash (as computed by HashKey) is given to the store.
+func (s *MemoryStore) AddHash(hash string, p *goa.Principal) error {
+	hash = strings.ToLower(hash)
+	if b, err := hex.DecodeString(hash); err != nil || len(b) != sha256.Size {
+		return fmt.Errorf("apikey: invalid key hash %q", hash)
+	}
+	if p == nil {
+		return errors.New("apikey: principal must not be nil")
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.principals[hash] = p
+	return nil
+}
+
+// Remove removes key from the store.
+func (s *MemoryStore) Remove(key string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.principals, HashKey(key))
+}
+
+// Lookup implements KeyStore.
+func (s *MemoryStore) Lookup(ctx context.Context, key string) (*goa.Principal, error) {
+	hash := HashKey(key)
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.principals[hash], nil
+}
@@ -0,0 +1,155 @@
+package apikey_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/goadesign/goa"
+	"github.com/goadesign/goa/middleware/security/apikey"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Middleware", func() {
+	var (
+		store    apikey.KeyStore
+		scheme   *goa.APIKeySecurity
+		scopes   []string
+		header   string
+		query    string
+		err      error
+		received *goa.Principal
+		admin    *goa.Principal
+	)
+
+	BeforeEach(func() {
+		admin = &goa.Principal{ID: "admin", Scopes: []string{"read", "write"}}
+		s := apikey.NewMemoryStore()
+		Ω(s.Add("s3cr3t", admin)).ShouldNot(HaveOccurred())
+		store = s
+		scheme = &goa.APIKeySecurity{In: goa.LocHeader, Name: "X-API-Key"}
+		scopes = nil
+		header = "s3cr3t"
+		query = ""
+		received = nil
+	})
+
+	JustBeforeEach(func() {
+		req, _ := http.NewRequest("GET", "http://example.com/?"+query, nil)
+		if header != "" {
+			req.Header.Set("X-API-Key", header)
+		}
+		h := func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			received = goa.ContextPrincipal(ctx)
+			return nil
+		}
+		ctx := goa.WithRequiredScopes(context.Background(), scopes)
+		err = apikey.New(store, scheme)(h)(ctx, httptest.NewRecorder(), req)
+	})
+
+	It("makes the principal available to the handler", func() {
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(received).Should(Equal(admin))
+	})
+
+	Context("with a key in the query string", func() {
+		BeforeEach(func() {
+			scheme = &goa.APIKeySecurity{In: goa.LocQuery, Name: "api_key"}
+			header = ""
+			query = "api_key=s3cr3t"
+		})
+
+		It("authenticates the request", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(received).Should(Equal(admin))
+		})
+	})
+
+	Context("without key", func() {
+		BeforeEach(func() {
+			header = ""
+		})
+
+		It("rejects the request", func() {
+			Ω(err).Should(HaveOccurred())
+			Ω(err.(*goa.ErrorResponse).Status).Should(Equal(401))
+			Ω(err.(*goa.ErrorResponse).Detail).Should(Equal(`missing API key in header "X-API-Key"`))
+		})
+	})
+
+	Context("with an unknown key", func() {
+		BeforeEach(func() {
+			header = "guess"
+		})
+
+		It("rejects the request", func() {
+			Ω(err).Should(HaveOccurred())
+			Ω(err.(*goa.ErrorResponse).Status).Should(Equal(401))
+		})
+	})
+
+	Context("with allowed scopes", func() {
+		BeforeEach(func() {
+			scopes = []string{"write"}
+		})
+
+		It("authenticates the request", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+		})
+	})
+
+	Context("with scopes that are not allowed", func() {
+		BeforeEach(func() {
+			scopes = []string{"admin"}
+		})
+
+		It("rejects the request", func() {
+			Ω(err).Should(HaveOccurred())
+			Ω(err.(*goa.ErrorResponse).Status).Should(Equal(403))
+		})
+	})
+
+	Context("when the lookup fails", func() {
+		BeforeEach(func() {
+			store = failingStore{}
+		})
+
+		It("returns a 503", func() {
+			Ω(err).Should(HaveOccurred())
+			Ω(err.(*goa.ErrorResponse).Status).Should(Equal(503))
+		})
+	})
+})
+
+var _ = Describe("MemoryStore", func() {
+	var store *apikey.MemoryStore
+
+	BeforeEach(func() {
+		store = apikey.NewMemoryStore()
+	})
+
+	It("looks up keys added by hash", func() {
+		p := &goa.Principal{ID: "svc"}
+		Ω(store.AddHash(apikey.HashKey("key"), p)).ShouldNot(HaveOccurred())
+		Ω(store.Lookup(context.Background(), "key")).Should(Equal(p))
+		store.Remove("key")
+		Ω(store.Lookup(context.Background(), "key")).Should(BeNil())
+	})
+
+	It("rejects invalid hashes", func() {
+		Ω(store.AddHash("key", &goa.Principal{})).Should(HaveOccurred())
+	})
+
+	It("does not store the keys", func() {
+		Ω(apikey.HashKey("key")).ShouldNot(ContainSubstring("key"))
+		Ω(apikey.HashKey("key")).Should(HaveLen(64))
+	})
+})
+
+type failingStore struct{}
+
+func (failingStore) Lookup(context.Context, string) (*goa.Principal, error) {
+	return nil, errors.New("boom")
+}
@@ -0,0 +1,456 @@
+/*
+Package httpsig implements a middleware that authenticates requests signed with a secret shared
+between the client and the service as described in draft-cavage-http-signatures. The clients sign
+the requests with HMAC-SHA256 and send the signature in the "Signature" header together with the
+ID of the key used to sign and the list of covered headers, see client.HTTPSignatureSigner.
+
+The middleware checks that the signature covers the headers required by the security scheme, that
+the "Date" header is within the allowed clock skew so that captured requests cannot be replayed
+later on, that the "Digest" header matches the request body and that the key is allowed the
+scopes required by the action. The principal of the key used to sign is made available to the
+handlers via goa.ContextPrincipal.
+
+goa reads the request body before running the middleware, the generated code wraps the payload
+unmarshaler of the actions secured with a HTTP signature scheme with CaptureBody so that the
+digest can be checked against the raw request body. The generated UseXXXHTTPSignatureMiddleware
+functions mount the middleware for the HTTP signature security schemes defined in the design,
+e.g.:
+
+    store := httpsig.NewMemoryStore()
+    store.Add(&httpsig.Key{
+        Principal: goa.Principal{ID: "billing", Scopes: []string{"api:write"}},
+        Secret:    secret,
+    })
+    app.UseSignedHTTPSignatureMiddleware(service, store, httpsig.RejectReplays(100000))
+*/
+package httpsig
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goadesign/goa"
+)
+
+var (
+	// ErrInvalidSignature is the error returned when the signature is missing, does not
+	// match the request or the request is outside the allowed time window.
+	ErrInvalidSignature = goa.NewErrorClass("invalid_signature", 401)
+
+	// ErrForbidden is the error returned when the key is not allowed the scopes required by
+	// the action.
+	ErrForbidden = goa.NewErrorClass("signature_forbidden", 403)
+
+	// ErrLookupUnavailable is the error returned when the key store fails to look up the key.
+	ErrLookupUnavailable = goa.NewErrorClass("signature_key_lookup_unavailable", 503)
+)
+
+type (
+	// KeyStore looks up the keys used to sign the requests.
+	KeyStore interface {
+		// Lookup returns the key with the given ID or nil if the key is unknown. It returns
+		// an error only if the lookup failed.
+		Lookup(ctx context.Context, keyID string) (*Key, error)
+	}
+
+	// Key describes a secret shared with a client. The principal ID identifies the key, it is
+	// sent by the clients in the keyId signature parameter.
+	Key struct {
+		goa.Principal
+		// Secret is the HMAC secret.
+		Secret []byte
+	}
+
+	// MemoryStore is a KeyStore that keeps the keys in memory.
+	MemoryStore struct {
+		lock sync.RWMutex
+		keys map[string]*Key
+	}
+
+	// Option allows to override default parameters.
+	Option func(*options) error
+
+	// options contains final options
+	options struct {
+		maxSkew time.Duration
+		replays *replayCache
+		now     func() time.Time
+	}
+
+	// signature contains the parameters of the Signature header.
+	signature struct {
+		keyID     string
+		algorithm string
+		headers   []string
+		signature []byte
+	}
+
+	// replayCache remembers the signatures of the requests received within the time window.
+	replayCache struct {
+		lock sync.Mutex
+		max  int
+		seen map[string]time.Time
+	}
+
+	// capturedBody is the request body set by CaptureBody.
+	capturedBody struct {
+		io.Reader
+		raw []byte
+	}
+)
+
+// defaultHeaders is the list of headers covered by the signature if the scheme does not define
+// any.
+var defaultHeaders = []string{"(request-target)", "host", "date", "digest"}
+
+// MaxSkew sets the maximum difference between the request "Date" header and the time the
+// request is received, the default is 5m. Requests outside this window are rejected.
+func MaxSkew(d time.Duration) Option {
+	return func(o *options) error {
+		if d <= 0 {
+			return fmt.Errorf("httpsig: invalid max skew %s", d)
+		}
+		o.maxSkew = d
+		return nil
+	}
+}
+
+// RejectReplays makes the middleware remember the signatures of the requests received within
+// the time window and reject the requests whose signature was already seen. maxEntries is the
+// maximum number of signatures remembered. Note that this also rejects the retries of requests
+// that are not signed again by the client.
+func RejectReplays(maxEntries int) Option {
+	return func(o *options) error {
+		if maxEntries < 1 {
+			return fmt.Errorf("httpsig: invalid max entries %d", maxEntries)
+		}
+		o.replays = &replayCache{max: maxEntries, seen: make(map[string]time.Time)}
+		return nil
+	}
+}
+
+// CaptureBody returns an unmarshaler that keeps a copy of the raw request body before calling
+// unm so that the middleware may check the "Digest" header against it. The generated code wraps
+// the unmarshalers of the actions secured with a HTTP signature scheme. The request body length
+// is limited by the controller MaxRequestBodyLength field. The digest covers the body as sent by
+// the client, that is before its content coding is decoded, see goa.ContextEncodedBody.
+func CaptureBody(unm goa.Unmarshaler) goa.Unmarshaler {
+	return func(ctx context.Context, service *goa.Service, req *http.Request) error {
+		body, err := ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return err
+		}
+		raw := body
+		if encoded := goa.ContextEncodedBody(ctx); encoded != nil {
+			raw = encoded
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+		err = unm(ctx, service, req)
+		req.Body = &capturedBody{Reader: bytes.NewReader(body), raw: raw}
+		return err
+	}
+}
+
+// New returns a middleware that authenticates the requests signed with the keys looked up in
+// store. The signature must cover the headers listed in scheme, "date" and "digest" if the
+// request has a body. The middleware checks that the key is allowed the scopes returned by
+// goa.ContextRequiredScopes.
+func New(store KeyStore, scheme *goa.HTTPSignatureSecurity, o ...Option) goa.Middleware {
+	opts := options{maxSkew: 5 * time.Minute, now: time.Now}
+	for _, opt := range o {
+		if err := opt(&opts); err != nil {
+			panic(err)
+		}
+	}
+	headers := scheme.Headers
+	if len(headers) == 0 {
+		headers = defaultHeaders
+	}
+	challenge := fmt.Sprintf(`Signature headers="%s"`, strings.ToLower(strings.Join(headers, " ")))
+	return func(h goa.Handler) goa.Handler {
+		return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			// The digest cannot be checked if the request body failed to load, the request
+			// is rejected with the loading error.
+			if err := goa.ContextError(ctx); err != nil {
+				return err
+			}
+			key, err := opts.verify(ctx, store, headers, req)
+			if err != nil {
+				if e, ok := err.(*goa.ErrorResponse); ok && e.Status == 401 {
+					rw.Header().Set("WWW-Authenticate", challenge)
+				}
+				return err
+			}
+			required := goa.ContextRequiredScopes(ctx)
+			if missing := goa.MissingScopes(required, key.Scopes); missing != nil {
+				return ErrForbidden("signing key does not allow the required scopes",
+					"required", required, "scopes", key.Scopes)
+			}
+			return h(goa.WithPrincipal(ctx, &key.Principal), rw, req)
+		}
+	}
+}
+
+// verify checks the request signature and returns the key used to sign it.
+func (o *options) verify(ctx context.Context, store KeyStore, required []string, req *http.Request) (*Key, error) {
+	header := req.Header.Get("Signature")
+	if header == "" {
+		return nil, ErrInvalidSignature("missing Signature header")
+	}
+	sig, err := parseSignature(header)
+	if err != nil {
+		return nil, ErrInvalidSignature(err.Error())
+	}
+	switch sig.algorithm {
+	case "", "hmac-sha256", "hs2019":
+	default:
+		return nil, ErrInvalidSignature(fmt.Sprintf("unsupported signature algorithm %q", sig.algorithm))
+	}
+	covered := make(map[string]bool, len(sig.headers))
+	for _, h := range sig.headers {
+		covered[h] = true
+	}
+	for _, h := range append([]string{"date"}, required...) {
+		if !covered[strings.ToLower(h)] {
+			return nil, ErrInvalidSignature(fmt.Sprintf("signature does not cover %q", strings.ToLower(h)))
+		}
+	}
+	if req.ContentLength != 0 && !covered["digest"] {
+		return nil, ErrInvalidSignature(`signature does not cover "digest"`)
+	}
+	date, err := http.ParseTime(req.Header.Get("Date"))
+	if err != nil {
+		return nil, ErrInvalidSignature("invalid Date header")
+	}
+	now := o.now()
+	if date.Before(now.Add(-o.maxSkew)) || date.After(now.Add(o.maxSkew)) {
+		return nil, ErrInvalidSignature("request date is outside of the allowed time window")
+	}
+	key, err := store.Lookup(ctx, sig.keyID)
+	if err != nil {
+		goa.LogError(ctx, "failed to look up signing key", "err", err)
+		return nil, ErrLookupUnavailable("failed to look up signing key")
+	}
+	if key == nil {
+		goa.LogInfo(ctx, "unknown signing key", "keyID", sig.keyID)
+		return nil, ErrInvalidSignature("signature does not match request")
+	}
+	str, err := signingString(req, sig.headers)
+	if err != nil {
+		return nil, ErrInvalidSignature(err.Error())
+	}
+	mac := hmac.New(sha256.New, key.Secret)
+	mac.Write([]byte(str))
+	if !hmac.Equal(mac.Sum(nil), sig.signature) {
+		return nil, ErrInvalidSignature("signature does not match request")
+	}
+	if covered["digest"] {
+		if err := verifyDigest(req); err != nil {
+			return nil, ErrInvalidSignature(err.Error())
+		}
+	}
+	if o.replays != nil && !o.replays.add(string(sig.signature), date.Add(o.maxSkew), now) {
+		return nil, ErrInvalidSignature("request was already received")
+	}
+	return key, nil
+}
+
+// parseSignature parses the value of the Signature header.
+func parseSignature(header string) (*signature, error) {
+	var sig signature
+	params := make(map[string]string)
+	for s := strings.TrimSpace(header); s != ""; {
+		eq := strings.IndexByte(s, '=')
+		if eq < 1 {
+			return nil, errors.New("malformed Signature header")
+		}
+		name := strings.TrimSpace(s[:eq])
+		s = strings.TrimSpace(s[eq+1:])
+		var val string
+		if strings.HasPrefix(s, `"`) {
+			end := strings.IndexByte(s[1:], '"')
+			if end < 0 {
+				return nil, errors.New("malformed Signature header")
+			}
+			val, s = s[1:end+1], s[end+2:]
+		} else {
+			end := strings.IndexByte(s, ',')
+			if end < 0 {
+				end = len(s)
+			}
+			val, s = s[:end], s[end:]
+		}
+		s = strings.TrimSpace(s)
+		if s != "" {
+			if s[0] != ',' {
+				return nil, errors.New("malformed Signature header")
+			}
+			s = strings.TrimSpace(s[1:])
+		}
+		params[name] = val
+	}
+	sig.keyID = params["keyId"]
+	if sig.keyID == "" {
+		return nil, errors.New("missing keyId signature parameter")
+	}
+	sig.algorithm = strings.ToLower(params["algorithm"])
+	headers := params["headers"]
+	if headers == "" {
+		headers = "date"
+	}
+	sig.headers = strings.Fields(strings.ToLower(headers))
+	b, err := base64.StdEncoding.DecodeString(params["signature"])
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("invalid signature parameter")
+	}
+	sig.signature = b
+	return &sig, nil
+}
+
+// signingString builds the string signed by the client from the given headers.
+func signingString(req *http.Request, headers []string) (string, error) {
+	lines := make([]string, len(headers))
+	for i, name := range headers {
+		var val string
+		switch name {
+		case "(request-target)":
+			val = strings.ToLower(req.Method) + " " + req.URL.RequestURI()
+		case "host":
+			val = req.Host
+		default:
+			vals := req.Header[http.CanonicalHeaderKey(name)]
+			if len(vals) == 0 {
+				return "", fmt.Errorf("missing signed header %q", name)
+			}
+			val = strings.Join(vals, ", ")
+		}
+		lines[i] = name + ": " + strings.TrimSpace(val)
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// verifyDigest checks the Digest header against the request body. The body is the one captured
+// by CaptureBody if any, it is read from the request otherwise.
+func verifyDigest(req *http.Request) error {
+	var (
+		alg  string
+		want []byte
+		h    hash.Hash
+	)
+	for _, d := range strings.Split(req.Header.Get("Digest"), ",") {
+		eq := strings.IndexByte(d, '=')
+		if eq < 0 {
+			continue
+		}
+		alg = strings.ToUpper(strings.TrimSpace(d[:eq]))
+		switch alg {
+		case "SHA-256":
+			h = sha256.New()
+		case "SHA-512":
+			h = sha512.New()
+		default:
+			continue
+		}
+		var err error
+		if want, err = base64.StdEncoding.DecodeString(strings.TrimSpace(d[eq+1:])); err != nil {
+			return errors.New("invalid Digest header")
+		}
+		break
+	}
+	if h == nil {
+		return errors.New("missing SHA-256 or SHA-512 digest")
+	}
+	var body []byte
+	if b, ok := req.Body.(*capturedBody); ok {
+		body = b.raw
+	} else if req.Body != nil && req.ContentLength != 0 {
+		var err error
+		if body, err = ioutil.ReadAll(req.Body); err != nil {
+			return errors.New("failed to read request body")
+		}
+		req.Body.Close()
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	h.Write(body)
+	if !bytes.Equal(h.Sum(nil), want) {
+		return fmt.Errorf("%s digest does not match request body", alg)
+	}
+	return nil
+}
+
+// Close implements io.Closer.
+func (b *capturedBody) Close() error {
+	return nil
+}
+
+// add records sig and returns false if it was already recorded. Expired signatures are purged
+// when the cache is full and arbitrary signatures are evicted if it is still full.
+func (c *replayCache) add(sig string, expiry, now time.Time) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if exp, ok := c.seen[sig]; ok && now.Before(exp) {
+		return false
+	}
+	if len(c.seen) >= c.max {
+		for k, exp := range c.seen {
+			if !now.Before(exp) {
+				delete(c.seen, k)
+			}
+		}
+		for k := range c.seen {
+			if len(c.seen) < c.max {
+				break
+			}
+			delete(c.seen, k)
+		}
+	}
+	c.seen[sig] = expiry
+	return true
+}
+
+// NewMemoryStore returns an empty store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{keys: make(map[string]*Key)}
+}
+
+// Add adds key to the store, replacing any key with the same ID.
+func (s *MemoryStore) Add(key *Key) error {
+	if key == nil || key.ID == "" {
+		return errors.New("httpsig: key ID must not be empty")
+	}
+	if len(key.Secret) == 0 {
+		return fmt.Errorf("httpsig: secret of key %q must not be empty", key.ID)
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.keys[key.ID] = key
+	return nil
+}
+
+// Remove removes the key with the given ID from the store.
+func (s *MemoryStore) Remove(keyID string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.keys, keyID)
+}
+
+// Lookup implements KeyStore.
+func (s *MemoryStore) Lookup(ctx context.Context, keyID string) (*Key, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.keys[keyID], nil
+}
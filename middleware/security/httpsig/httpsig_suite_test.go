@@ -0,0 +1,13 @@
+package httpsig_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestHTTPSignatureSecurityMiddleware(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "HTTP Signature Security Middleware")
+}
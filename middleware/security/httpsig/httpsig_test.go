@@ -0,0 +1,419 @@
+package httpsig_test
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/goadesign/goa"
+	"github.com/goadesign/goa/client"
+	"github.com/goadesign/goa/middleware"
+	gzm "github.com/goadesign/goa/middleware/gzip"
+	"github.com/goadesign/goa/middleware/security/httpsig"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Middleware", func() {
+	var (
+		store   httpsig.KeyStore
+		key     *httpsig.Key
+		signer  *client.HTTPSignatureSigner
+		scheme  *goa.HTTPSignatureSecurity
+		options []httpsig.Option
+		scopes  []string
+		body    string
+		capture bool
+		tamper  func(*http.Request)
+
+		rw       *httptest.ResponseRecorder
+		err      error
+		received *goa.Principal
+		payload  string
+	)
+
+	BeforeEach(func() {
+		key = &httpsig.Key{
+			Principal: goa.Principal{ID: "billing", Scopes: []string{"read", "write"}},
+			Secret:    []byte("s3cr3t"),
+		}
+		s := httpsig.NewMemoryStore()
+		Ω(s.Add(key)).ShouldNot(HaveOccurred())
+		store = s
+		signer = &client.HTTPSignatureSigner{KeyID: "billing", Secret: []byte("s3cr3t")}
+		scheme = &goa.HTTPSignatureSecurity{Headers: []string{"(request-target)", "host", "date", "digest"}}
+		options = nil
+		scopes = nil
+		body = ""
+		capture = true
+		tamper = nil
+		err = nil
+		received = nil
+		payload = ""
+	})
+
+	JustBeforeEach(func() {
+		method := "GET"
+		if body != "" {
+			method = "POST"
+		}
+		req, _ := http.NewRequest(method, "http://example.com/invoices?page=2", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		Ω(signer.Sign(req)).ShouldNot(HaveOccurred())
+		if tamper != nil {
+			tamper(req)
+		}
+
+		// Simulate the server side: the request is received over the wire, goa loads the
+		// body before running the middleware.
+		var buf bytes.Buffer
+		Ω(req.Write(&buf)).ShouldNot(HaveOccurred())
+		srvReq, rerr := http.ReadRequest(bufio.NewReader(&buf))
+		Ω(rerr).ShouldNot(HaveOccurred())
+
+		ctx := goa.WithRequiredScopes(context.Background(), scopes)
+		if capture {
+			unm := httpsig.CaptureBody(func(ctx context.Context, service *goa.Service, req *http.Request) error {
+				b, err := ioutil.ReadAll(req.Body)
+				payload = string(b)
+				return err
+			})
+			Ω(unm(ctx, nil, srvReq)).ShouldNot(HaveOccurred())
+		}
+		mw := httpsig.New(store, scheme, options...)
+		h := func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			received = goa.ContextPrincipal(ctx)
+			return nil
+		}
+		rw = httptest.NewRecorder()
+		err = mw(h)(ctx, rw, srvReq)
+	})
+
+	It("authenticates signed requests", func() {
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(received).Should(Equal(&key.Principal))
+	})
+
+	Context("with a body", func() {
+		BeforeEach(func() {
+			body = `{"amount":42}`
+		})
+
+		It("checks the digest against the captured body", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(received).Should(Equal(&key.Principal))
+			Ω(payload).Should(Equal(body))
+		})
+
+		Context("that was tampered with", func() {
+			BeforeEach(func() {
+				tamper = func(req *http.Request) {
+					req.Body = ioutil.NopCloser(strings.NewReader(`{"amount":43}`))
+				}
+			})
+
+			It("rejects the request", func() {
+				Ω(err).Should(HaveOccurred())
+				Ω(err.(*goa.ErrorResponse).Status).Should(Equal(401))
+				Ω(err.(*goa.ErrorResponse).Detail).Should(Equal("SHA-256 digest does not match request body"))
+			})
+		})
+
+		Context("that was not captured", func() {
+			BeforeEach(func() {
+				capture = false
+			})
+
+			It("checks the digest against the request body", func() {
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(received).Should(Equal(&key.Principal))
+			})
+		})
+	})
+
+	Context("without signature", func() {
+		BeforeEach(func() {
+			tamper = func(req *http.Request) {
+				req.Header.Del("Signature")
+			}
+		})
+
+		It("rejects the request", func() {
+			Ω(err).Should(HaveOccurred())
+			Ω(err.(*goa.ErrorResponse).Status).Should(Equal(401))
+			Ω(rw.Header().Get("WWW-Authenticate")).Should(Equal(`Signature headers="(request-target) host date digest"`))
+		})
+	})
+
+	Context("with a modified request", func() {
+		BeforeEach(func() {
+			tamper = func(req *http.Request) {
+				req.URL.RawQuery = "page=3"
+			}
+		})
+
+		It("rejects the request", func() {
+			Ω(err).Should(HaveOccurred())
+			Ω(err.(*goa.ErrorResponse).Detail).Should(Equal("signature does not match request"))
+		})
+	})
+
+	Context("with the wrong secret", func() {
+		BeforeEach(func() {
+			signer.Secret = []byte("guess")
+		})
+
+		It("rejects the request", func() {
+			Ω(err).Should(HaveOccurred())
+			Ω(err.(*goa.ErrorResponse).Status).Should(Equal(401))
+		})
+	})
+
+	Context("with an unknown key", func() {
+		BeforeEach(func() {
+			signer.KeyID = "unknown"
+		})
+
+		It("rejects the request", func() {
+			Ω(err).Should(HaveOccurred())
+			Ω(err.(*goa.ErrorResponse).Status).Should(Equal(401))
+			Ω(err.(*goa.ErrorResponse).Detail).Should(Equal("signature does not match request"))
+		})
+	})
+
+	Context("with a signature that does not cover the required headers", func() {
+		BeforeEach(func() {
+			signer.Headers = []string{"date", "digest"}
+		})
+
+		It("rejects the request", func() {
+			Ω(err).Should(HaveOccurred())
+			Ω(err.(*goa.ErrorResponse).Detail).Should(Equal(`signature does not cover "(request-target)"`))
+		})
+	})
+
+	Context("with a date outside of the time window", func() {
+		BeforeEach(func() {
+			options = []httpsig.Option{httpsig.MaxSkew(time.Minute)}
+			tamper = func(req *http.Request) {
+				req.Header.Set("Date", time.Now().Add(-2*time.Minute).UTC().Format(http.TimeFormat))
+				signer.Sign(req)
+			}
+		})
+
+		It("rejects the request", func() {
+			Ω(err).Should(HaveOccurred())
+			Ω(err.(*goa.ErrorResponse).Detail).Should(Equal("request date is outside of the allowed time window"))
+		})
+	})
+
+	Context("with an unsupported algorithm", func() {
+		BeforeEach(func() {
+			tamper = func(req *http.Request) {
+				sig := req.Header.Get("Signature")
+				req.Header.Set("Signature", strings.Replace(sig, "hmac-sha256", "rsa-sha256", 1))
+			}
+		})
+
+		It("rejects the request", func() {
+			Ω(err).Should(HaveOccurred())
+			Ω(err.(*goa.ErrorResponse).Detail).Should(Equal(`unsupported signature algorithm "rsa-sha256"`))
+		})
+	})
+
+	Context("with allowed scopes", func() {
+		BeforeEach(func() {
+			scopes = []string{"write"}
+		})
+
+		It("authenticates the request", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+		})
+	})
+
+	Context("with scopes that are not allowed", func() {
+		BeforeEach(func() {
+			scopes = []string{"admin"}
+		})
+
+		It("rejects the request", func() {
+			Ω(err).Should(HaveOccurred())
+			Ω(err.(*goa.ErrorResponse).Status).Should(Equal(403))
+		})
+	})
+
+	Context("when the lookup fails", func() {
+		BeforeEach(func() {
+			store = failingStore{}
+		})
+
+		It("returns a 503", func() {
+			Ω(err).Should(HaveOccurred())
+			Ω(err.(*goa.ErrorResponse).Status).Should(Equal(503))
+		})
+	})
+})
+
+var _ = Describe("Mounted middleware", func() {
+	var (
+		ctrl    *goa.Controller
+		server  *httptest.Server
+		signer  *client.HTTPSignatureSigner
+		body    string
+		gzipped bool
+		tamper  func(*http.Request)
+		resp    *http.Response
+		payload interface{}
+	)
+
+	BeforeEach(func() {
+		service := goa.New("billing")
+		service.Decoder.Register(goa.NewJSONDecoder, "application/json")
+		service.Encoder.Register(goa.NewJSONEncoder, "*/*")
+		service.Use(middleware.ErrorHandler(service, false))
+		gzm.Decompress(service)
+		store := httpsig.NewMemoryStore()
+		Ω(store.Add(&httpsig.Key{Principal: goa.Principal{ID: "billing"}, Secret: []byte("s3cr3t")})).ShouldNot(HaveOccurred())
+
+		// Mount the action the way the generated code does.
+		ctrl = service.NewController("Invoices")
+		h := func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			if err := goa.ContextError(ctx); err != nil {
+				return err
+			}
+			payload = goa.ContextRequest(ctx).Payload
+			rw.WriteHeader(http.StatusNoContent)
+			return nil
+		}
+		h = httpsig.New(store, &goa.HTTPSignatureSecurity{})(h)
+		unmarshal := func(ctx context.Context, service *goa.Service, req *http.Request) error {
+			var p map[string]interface{}
+			if err := service.DecodeRequest(req, &p); err != nil {
+				return err
+			}
+			goa.ContextRequest(ctx).Payload = p
+			return nil
+		}
+		service.Mux.Handle("POST", "/invoices", ctrl.MuxHandler("create", h, httpsig.CaptureBody(unmarshal)))
+		server = httptest.NewServer(service.Server.Handler)
+
+		signer = &client.HTTPSignatureSigner{KeyID: "billing", Secret: []byte("s3cr3t")}
+		body = `{"amount":42}`
+		gzipped = false
+		tamper = nil
+		resp = nil
+		payload = nil
+	})
+
+	JustBeforeEach(func() {
+		req, _ := http.NewRequest("POST", server.URL+"/invoices", strings.NewReader(body))
+		if gzipped {
+			var buf bytes.Buffer
+			w := gzip.NewWriter(&buf)
+			w.Write([]byte(body))
+			w.Close()
+			req, _ = http.NewRequest("POST", server.URL+"/invoices", &buf)
+			req.Header.Set("Content-Encoding", "gzip")
+		}
+		req.Header.Set("Content-Type", "application/json")
+		Ω(signer.Sign(req)).ShouldNot(HaveOccurred())
+		if tamper != nil {
+			tamper(req)
+		}
+		var err error
+		resp, err = http.DefaultClient.Do(req)
+		Ω(err).ShouldNot(HaveOccurred())
+		resp.Body.Close()
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("authenticates signed requests with a body", func() {
+		Ω(resp.StatusCode).Should(Equal(http.StatusNoContent))
+		Ω(payload).Should(Equal(map[string]interface{}{"amount": 42.0}))
+	})
+
+	Context("with a body that was tampered with", func() {
+		BeforeEach(func() {
+			tamper = func(req *http.Request) {
+				req.Body = ioutil.NopCloser(strings.NewReader(`{"amount":43}`))
+			}
+		})
+
+		It("rejects the request", func() {
+			Ω(resp.StatusCode).Should(Equal(http.StatusUnauthorized))
+			Ω(payload).Should(BeNil())
+		})
+	})
+
+	Context("with a gzip encoded body", func() {
+		BeforeEach(func() {
+			gzipped = true
+		})
+
+		It("checks the digest against the encoded body", func() {
+			Ω(resp.StatusCode).Should(Equal(http.StatusNoContent))
+			Ω(payload).Should(Equal(map[string]interface{}{"amount": 42.0}))
+		})
+	})
+
+	Context("with a body that is too large", func() {
+		BeforeEach(func() {
+			ctrl.MaxRequestBodyLength = 8
+		})
+
+		It("rejects the request", func() {
+			Ω(resp.StatusCode).Should(Equal(http.StatusRequestEntityTooLarge))
+			Ω(payload).Should(BeNil())
+		})
+	})
+})
+
+var _ = Describe("RejectReplays", func() {
+	It("rejects requests that were already received", func() {
+		store := httpsig.NewMemoryStore()
+		Ω(store.Add(&httpsig.Key{Principal: goa.Principal{ID: "k"}, Secret: []byte("s")})).ShouldNot(HaveOccurred())
+		signer := &client.HTTPSignatureSigner{KeyID: "k", Secret: []byte("s")}
+		req, _ := http.NewRequest("GET", "http://example.com/", nil)
+		Ω(signer.Sign(req)).ShouldNot(HaveOccurred())
+		req.Host = "example.com"
+
+		mw := httpsig.New(store, &goa.HTTPSignatureSecurity{}, httpsig.RejectReplays(10))
+		h := func(context.Context, http.ResponseWriter, *http.Request) error { return nil }
+		Ω(mw(h)(context.Background(), httptest.NewRecorder(), req)).ShouldNot(HaveOccurred())
+
+		err := mw(h)(context.Background(), httptest.NewRecorder(), req)
+		Ω(err).Should(HaveOccurred())
+		Ω(err.(*goa.ErrorResponse).Detail).Should(Equal("request was already received"))
+	})
+})
+
+var _ = Describe("MemoryStore", func() {
+	It("rejects invalid keys", func() {
+		store := httpsig.NewMemoryStore()
+		Ω(store.Add(&httpsig.Key{Secret: []byte("s")})).Should(HaveOccurred())
+		Ω(store.Add(&httpsig.Key{Principal: goa.Principal{ID: "k"}})).Should(HaveOccurred())
+	})
+
+	It("removes keys", func() {
+		store := httpsig.NewMemoryStore()
+		Ω(store.Add(&httpsig.Key{Principal: goa.Principal{ID: "k"}, Secret: []byte("s")})).ShouldNot(HaveOccurred())
+		store.Remove("k")
+		Ω(store.Lookup(context.Background(), "k")).Should(BeNil())
+	})
+})
+
+type failingStore struct{}
+
+func (failingStore) Lookup(context.Context, string) (*httpsig.Key, error) {
+	return nil, errors.New("boom")
+}
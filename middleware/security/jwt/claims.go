@@ -0,0 +1,256 @@
+package jwt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+type (
+	// Option allows to override the default claim validations performed by the middleware.
+	Option func(*options) error
+
+	// options contains the final options.
+	options struct {
+		issuers   map[string]bool
+		audiences []string
+		leeway    time.Duration
+		required  []requiredClaim
+		claims    reflect.Type
+		now       func() time.Time
+	}
+
+	// requiredClaim describes a claim that must be present in the token.
+	requiredClaim struct {
+		name   string
+		values []interface{}
+	}
+)
+
+// Issuer causes tokens whose "iss" claim is not one of the given issuers to be rejected.
+func Issuer(issuers ...string) Option {
+	return func(o *options) error {
+		if len(issuers) == 0 {
+			return fmt.Errorf("jwt: Issuer requires at least one issuer")
+		}
+		o.issuers = make(map[string]bool, len(issuers))
+		for _, iss := range issuers {
+			o.issuers[iss] = true
+		}
+		return nil
+	}
+}
+
+// Audience causes tokens whose "aud" claim does not contain one of the given audiences to be
+// rejected.
+func Audience(audiences ...string) Option {
+	return func(o *options) error {
+		if len(audiences) == 0 {
+			return fmt.Errorf("jwt: Audience requires at least one audience")
+		}
+		o.audiences = audiences
+		return nil
+	}
+}
+
+// Leeway sets the clock skew tolerated when validating the "exp", "nbf" and "iat" claims, the
+// default is 0.
+func Leeway(d time.Duration) Option {
+	return func(o *options) error {
+		if d < 0 {
+			return fmt.Errorf("jwt: invalid leeway %s", d)
+		}
+		o.leeway = d
+		return nil
+	}
+}
+
+// RequireClaim causes tokens that do not have the claim with the given name to be rejected. If
+// values is not empty the claim value must also be equal to one of the values. Use
+// RequireClaim("exp") to reject tokens that do not expire.
+func RequireClaim(name string, values ...interface{}) Option {
+	return func(o *options) error {
+		if name == "" {
+			return fmt.Errorf("jwt: claim name must not be empty")
+		}
+		o.required = append(o.required, requiredClaim{name: name, values: values})
+		return nil
+	}
+}
+
+// BindClaims causes the claims of the validated tokens to be decoded into a new value of the
+// type of claims which must be a pointer to a struct. The decoded value is available to the
+// handlers via ContextClaims, e.g.:
+//
+//	type Claims struct {
+//		Subject string `json:"sub"`
+//		TenantID string `json:"tenant_id"`
+//	}
+//
+//	app.UseJWT(jwt.New(resolver, nil, app.NewJWTSecurity(), jwt.BindClaims(&Claims{})))
+//
+//	func (c *BottleController) Show(ctx *app.ShowBottleContext) error {
+//		claims := jwt.ContextClaims(ctx).(*Claims)
+//		...
+//	}
+func BindClaims(claims interface{}) Option {
+	return func(o *options) error {
+		t := reflect.TypeOf(claims)
+		if t == nil || t.Kind() != reflect.Ptr || t.Elem().Kind() != reflect.Struct {
+			return fmt.Errorf("jwt: BindClaims requires a pointer to a struct, got %T", claims)
+		}
+		o.claims = t.Elem()
+		return nil
+	}
+}
+
+// WithClaims creates a child context containing the given bound claims.
+func WithClaims(ctx context.Context, claims interface{}) context.Context {
+	return context.WithValue(ctx, claimsKey, claims)
+}
+
+// ContextClaims retrieves the claims bound by the security middleware configured with
+// BindClaims, the value has the type given to BindClaims.
+func ContextClaims(ctx context.Context) interface{} {
+	return ctx.Value(claimsKey)
+}
+
+// validateClaims validates the registered claims of token and the required claims.
+func (o *options) validateClaims(claims jwt.MapClaims) error {
+	now := o.now()
+	if exp, ok, err := timeClaim(claims, "exp"); err != nil {
+		return err
+	} else if ok && now.After(exp.Add(o.leeway)) {
+		return fmt.Errorf("token is expired since %s", exp.UTC().Format(time.RFC3339))
+	}
+	if nbf, ok, err := timeClaim(claims, "nbf"); err != nil {
+		return err
+	} else if ok && now.Add(o.leeway).Before(nbf) {
+		return fmt.Errorf("token is not valid before %s", nbf.UTC().Format(time.RFC3339))
+	}
+	if iat, ok, err := timeClaim(claims, "iat"); err != nil {
+		return err
+	} else if ok && now.Add(o.leeway).Before(iat) {
+		return fmt.Errorf("token is issued in the future at %s", iat.UTC().Format(time.RFC3339))
+	}
+	if o.issuers != nil {
+		iss, ok := claims["iss"]
+		if !ok {
+			return fmt.Errorf("missing 'iss' claim")
+		}
+		if s, ok := iss.(string); !ok || !o.issuers[s] {
+			return fmt.Errorf("invalid 'iss' claim %v", iss)
+		}
+	}
+	if o.audiences != nil {
+		aud, ok := claims["aud"]
+		if !ok {
+			return fmt.Errorf("missing 'aud' claim")
+		}
+		if !o.matchAudience(aud) {
+			return fmt.Errorf("invalid 'aud' claim %v", aud)
+		}
+	}
+	for _, r := range o.required {
+		val, ok := claims[r.name]
+		if !ok {
+			return fmt.Errorf("missing required '%s' claim", r.name)
+		}
+		if len(r.values) > 0 && !matchValue(val, r.values) {
+			return fmt.Errorf("invalid '%s' claim %v", r.name, val)
+		}
+	}
+	return nil
+}
+
+// matchAudience returns true if aud is one of the accepted audiences or a list containing one
+// of the accepted audiences.
+func (o *options) matchAudience(aud interface{}) bool {
+	var auds []interface{}
+	switch a := aud.(type) {
+	case string:
+		auds = []interface{}{a}
+	case []interface{}:
+		auds = a
+	default:
+		return false
+	}
+	for _, a := range auds {
+		for _, accepted := range o.audiences {
+			if a == accepted {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// bindClaims decodes claims into a new value of the type given to BindClaims.
+func (o *options) bindClaims(claims jwt.MapClaims) (interface{}, error) {
+	b, err := json.Marshal(claims)
+	if err != nil {
+		return nil, err
+	}
+	v := reflect.New(o.claims).Interface()
+	if err := json.Unmarshal(b, v); err != nil {
+		return nil, fmt.Errorf("invalid claims: %s", err)
+	}
+	return v, nil
+}
+
+// timeClaim returns the value of the NumericDate claim with the given name, whether it is set
+// and an error if it is not a number.
+func timeClaim(claims jwt.MapClaims, name string) (time.Time, bool, error) {
+	val, ok := claims[name]
+	if !ok || val == nil {
+		return time.Time{}, false, nil
+	}
+	var secs float64
+	switch v := val.(type) {
+	case float64:
+		secs = v
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid '%s' claim %v, must be a number", name, val)
+		}
+		secs = f
+	default:
+		return time.Time{}, false, fmt.Errorf("invalid '%s' claim %v, must be a number", name, val)
+	}
+	whole, frac := math.Modf(secs)
+	return time.Unix(int64(whole), int64(frac*1e9)), true, nil
+}
+
+// matchValue returns true if val is equal to one of values. Numbers are compared by value.
+func matchValue(val interface{}, values []interface{}) bool {
+	for _, v := range values {
+		if reflect.DeepEqual(val, v) {
+			return true
+		}
+		if f, ok := val.(float64); ok {
+			if n, ok := toFloat(v); ok && n == f {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// toFloat converts numeric values to float64.
+func toFloat(v interface{}) (float64, bool) {
+	switch n := reflect.ValueOf(v); n.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(n.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(n.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return n.Float(), true
+	}
+	return 0, false
+}
@@ -0,0 +1,238 @@
+package jwt_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	jwtpkg "github.com/dgrijalva/jwt-go"
+	"github.com/goadesign/goa"
+	"github.com/goadesign/goa/middleware/security/jwt"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type tenantClaims struct {
+	Subject  string `json:"sub"`
+	TenantID string `json:"tenant_id"`
+}
+
+var _ = Describe("Claim validation", func() {
+	var (
+		scheme  *goa.JWTSecurity
+		claims  jwtpkg.MapClaims
+		options []jwt.Option
+		request *http.Request
+		omit    bool
+
+		dispatchResult error
+		bound          interface{}
+	)
+
+	sign := func(claims jwtpkg.MapClaims) string {
+		s, err := jwtpkg.NewWithClaims(jwtpkg.SigningMethodHS256, claims).SignedString([]byte("keys"))
+		Ω(err).ShouldNot(HaveOccurred())
+		return s
+	}
+
+	errorMessage := func() string {
+		Ω(dispatchResult).Should(HaveOccurred())
+		se, ok := dispatchResult.(*goa.ErrorResponse)
+		Ω(ok).Should(BeTrue())
+		Ω(se.Status).Should(Equal(401))
+		return se.Detail
+	}
+
+	BeforeEach(func() {
+		scheme = &goa.JWTSecurity{In: goa.LocHeader, Name: "Authorization"}
+		claims = jwtpkg.MapClaims{"sub": "me", "tenant_id": "acme"}
+		options = nil
+		request, _ = http.NewRequest("GET", "http://example.com/", nil)
+		bound = nil
+		omit = false
+	})
+
+	JustBeforeEach(func() {
+		token := sign(claims)
+		if omit {
+			token = ""
+		}
+		switch scheme.In {
+		case goa.LocHeader:
+			request.Header.Set(scheme.Name, "Bearer "+token)
+		case goa.LocQuery:
+			request.URL.RawQuery = scheme.Name + "=" + token
+		case goa.LocCookie:
+			request.AddCookie(&http.Cookie{Name: scheme.Name, Value: token})
+		}
+		h := func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			bound = jwt.ContextClaims(ctx)
+			return nil
+		}
+		resolver := jwt.NewSimpleResolver([]jwt.Key{"keys"})
+		mw := jwt.New(resolver, nil, scheme, options...)
+		dispatchResult = mw(h)(context.Background(), httptest.NewRecorder(), request)
+	})
+
+	It("accepts tokens without registered claims", func() {
+		Ω(dispatchResult).ShouldNot(HaveOccurred())
+	})
+
+	Context("with a token in the query string", func() {
+		BeforeEach(func() {
+			scheme = &goa.JWTSecurity{In: goa.LocQuery, Name: "access_token"}
+		})
+
+		It("accepts the token", func() {
+			Ω(dispatchResult).ShouldNot(HaveOccurred())
+		})
+	})
+
+	Context("with a token in a cookie", func() {
+		BeforeEach(func() {
+			scheme = &goa.JWTSecurity{In: goa.LocCookie, Name: "session"}
+		})
+
+		It("accepts the token", func() {
+			Ω(dispatchResult).ShouldNot(HaveOccurred())
+		})
+
+		Context("missing", func() {
+			BeforeEach(func() {
+				omit = true
+			})
+
+			It("reports the missing cookie", func() {
+				Ω(errorMessage()).Should(Equal(`missing cookie "session"`))
+			})
+		})
+	})
+
+	Context("with an expired token", func() {
+		BeforeEach(func() {
+			claims["exp"] = time.Now().Add(-time.Minute).Unix()
+		})
+
+		It("rejects the token", func() {
+			Ω(errorMessage()).Should(ContainSubstring("token is expired"))
+		})
+
+		Context("within the leeway", func() {
+			BeforeEach(func() {
+				options = []jwt.Option{jwt.Leeway(2 * time.Minute)}
+			})
+
+			It("accepts the token", func() {
+				Ω(dispatchResult).ShouldNot(HaveOccurred())
+			})
+		})
+	})
+
+	Context("with a token not valid yet", func() {
+		BeforeEach(func() {
+			claims["nbf"] = time.Now().Add(time.Minute).Unix()
+		})
+
+		It("rejects the token", func() {
+			Ω(errorMessage()).Should(ContainSubstring("token is not valid before"))
+		})
+	})
+
+	Context("with an invalid exp claim", func() {
+		BeforeEach(func() {
+			claims["exp"] = "tomorrow"
+		})
+
+		It("rejects the token", func() {
+			Ω(errorMessage()).Should(ContainSubstring("invalid 'exp' claim"))
+		})
+	})
+
+	Context("with an issuer", func() {
+		BeforeEach(func() {
+			options = []jwt.Option{jwt.Issuer("https://auth.example.com")}
+		})
+
+		It("rejects tokens without iss claim", func() {
+			Ω(errorMessage()).Should(Equal("missing 'iss' claim"))
+		})
+
+		Context("and a token from another issuer", func() {
+			BeforeEach(func() {
+				claims["iss"] = "https://evil.example.com"
+			})
+
+			It("rejects the token", func() {
+				Ω(errorMessage()).Should(ContainSubstring("invalid 'iss' claim"))
+			})
+		})
+
+		Context("and a token from the issuer", func() {
+			BeforeEach(func() {
+				claims["iss"] = "https://auth.example.com"
+			})
+
+			It("accepts the token", func() {
+				Ω(dispatchResult).ShouldNot(HaveOccurred())
+			})
+		})
+	})
+
+	Context("with an audience", func() {
+		BeforeEach(func() {
+			options = []jwt.Option{jwt.Audience("api")}
+			claims["aud"] = []string{"web", "api"}
+		})
+
+		It("accepts tokens whose audience contains it", func() {
+			Ω(dispatchResult).ShouldNot(HaveOccurred())
+		})
+
+		Context("and a token for another audience", func() {
+			BeforeEach(func() {
+				claims["aud"] = "web"
+			})
+
+			It("rejects the token", func() {
+				Ω(errorMessage()).Should(ContainSubstring("invalid 'aud' claim"))
+			})
+		})
+	})
+
+	Context("with required claims", func() {
+		BeforeEach(func() {
+			options = []jwt.Option{jwt.RequireClaim("tenant_id", "acme", "globex"), jwt.RequireClaim("exp")}
+		})
+
+		It("rejects tokens missing a claim", func() {
+			Ω(errorMessage()).Should(Equal("missing required 'exp' claim"))
+		})
+
+		Context("and a claim with an invalid value", func() {
+			BeforeEach(func() {
+				claims["tenant_id"] = "initech"
+			})
+
+			It("rejects the token", func() {
+				Ω(errorMessage()).Should(ContainSubstring("invalid 'tenant_id' claim"))
+			})
+		})
+	})
+
+	Context("binding claims", func() {
+		BeforeEach(func() {
+			options = []jwt.Option{jwt.BindClaims(&tenantClaims{})}
+		})
+
+		It("makes the typed claims available in the context", func() {
+			Ω(dispatchResult).ShouldNot(HaveOccurred())
+			Ω(bound).Should(Equal(&tenantClaims{Subject: "me", TenantID: "acme"}))
+		})
+	})
+
+	It("panics with invalid options", func() {
+		Ω(func() { jwt.New(nil, nil, scheme, jwt.BindClaims(tenantClaims{})) }).Should(Panic())
+		Ω(func() { jwt.New(nil, nil, scheme, jwt.Leeway(-time.Second)) }).Should(Panic())
+	})
+})
@@ -10,6 +10,11 @@ type contextKey int
 
 const (
 	jwtKey contextKey = iota + 1
+	// rawTokenKey is the key used to store the incoming token in the context of the request
+	// given to KeyResolver.SelectKeys.
+	rawTokenKey
+	// claimsKey is the key used to store the claims bound with BindClaims.
+	claimsKey
 )
 
 // WithJWT creates a child context containing the given JWT.
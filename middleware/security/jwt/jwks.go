@@ -0,0 +1,317 @@
+package jwt
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// JWKSResolver is a key resolver that fetches the keys from a JSON Web Key Set (RFC 7517)
+	// published at a URL, such as the jwks_uri of an OpenID Connect provider. The key set is
+	// cached and refreshed periodically. The keys are selected using the "kid" header of the
+	// incoming token, a token whose "kid" is unknown causes the key set to be fetched again
+	// (at most once per minimum refetch interval) so that rotated keys are picked up. RSA, EC
+	// (P-256, P-384 and P-521) and OKP (Ed25519) keys are supported, keys whose "use" is not
+	// "sig" are ignored.
+	JWKSResolver struct {
+		url        string
+		client     *http.Client
+		refresh    time.Duration
+		minRefetch time.Duration
+		now        func() time.Time
+
+		lock      sync.RWMutex
+		keys      map[string]Key
+		all       []Key
+		fetchedAt time.Time
+
+		// fetchLock serializes fetches so that concurrent requests share the same fetch.
+		fetchLock sync.Mutex
+		// lastFetch is the time of the last fetch attempt, successful or not.
+		lastFetch time.Time
+	}
+
+	// JWKSOption allows to override the default JWKSResolver parameters.
+	JWKSOption func(*JWKSResolver) error
+
+	// jwks is the JSON representation of a JSON Web Key Set.
+	jwks struct {
+		Keys []*jwk `json:"keys"`
+	}
+
+	// jwk is the JSON representation of a JSON Web Key.
+	jwk struct {
+		Kty string `json:"kty"`
+		Use string `json:"use"`
+		Kid string `json:"kid"`
+		Crv string `json:"crv"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+		X   string `json:"x"`
+		Y   string `json:"y"`
+	}
+)
+
+// JWKSRefreshInterval sets the maximum duration the key set is cached for, the default is 1h.
+func JWKSRefreshInterval(d time.Duration) JWKSOption {
+	return func(r *JWKSResolver) error {
+		if d <= 0 {
+			return fmt.Errorf("invalid refresh interval %s", d)
+		}
+		r.refresh = d
+		return nil
+	}
+}
+
+// JWKSMinRefetchInterval sets the minimum duration between two fetches of the key set, it limits
+// the rate of the fetches caused by tokens with an unknown "kid". The default is 1m.
+func JWKSMinRefetchInterval(d time.Duration) JWKSOption {
+	return func(r *JWKSResolver) error {
+		if d < 0 {
+			return fmt.Errorf("invalid minimum refetch interval %s", d)
+		}
+		r.minRefetch = d
+		return nil
+	}
+}
+
+// JWKSClient sets the HTTP client used to fetch the key set, the default client has a 10s
+// timeout.
+func JWKSClient(c *http.Client) JWKSOption {
+	return func(r *JWKSResolver) error {
+		if c == nil {
+			return errors.New("HTTP client must not be nil")
+		}
+		r.client = c
+		return nil
+	}
+}
+
+// NewJWKSResolver returns a resolver that fetches the keys from the JSON Web Key Set published at
+// url. The key set is fetched lazily when the first request is authorized, use Refresh to fetch
+// it eagerly, e.g.:
+//
+//	resolver, err := jwt.NewJWKSResolver("https://auth.example.com/.well-known/jwks.json")
+//	if err != nil {
+//		return err
+//	}
+//	if err := resolver.Refresh(ctx); err != nil {
+//		return err
+//	}
+//	app.UseJWT(jwt.New(resolver, nil, app.NewJWTSecurity()))
+func NewJWKSResolver(url string, o ...JWKSOption) (*JWKSResolver, error) {
+	if url == "" {
+		return nil, errors.New("JWKS URL must not be empty")
+	}
+	r := &JWKSResolver{
+		url:        url,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		refresh:    time.Hour,
+		minRefetch: time.Minute,
+		now:        time.Now,
+	}
+	for _, opt := range o {
+		if err := opt(r); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// SelectKeys returns the key whose ID matches the "kid" header of the request token or all the
+// keys if the token does not have a "kid" header. It fetches the key set if it is stale or if
+// the "kid" is unknown. SelectKeys returns the cached keys if fetching the key set fails.
+func (r *JWKSResolver) SelectKeys(req *http.Request) []Key {
+	kid := tokenKeyID(req)
+	r.lock.RLock()
+	key, ok := r.keys[kid]
+	all, fetchedAt := r.all, r.fetchedAt
+	r.lock.RUnlock()
+
+	stale := r.now().Sub(fetchedAt) >= r.refresh
+	if stale || kid != "" && !ok {
+		r.fetch(req.Context(), fetchedAt)
+		r.lock.RLock()
+		key, ok = r.keys[kid]
+		all = r.all
+		r.lock.RUnlock()
+	}
+	if kid == "" {
+		return all
+	}
+	if !ok {
+		return nil
+	}
+	return []Key{key}
+}
+
+// Refresh fetches the key set.
+func (r *JWKSResolver) Refresh(ctx context.Context) error {
+	r.fetchLock.Lock()
+	defer r.fetchLock.Unlock()
+	return r.load(ctx)
+}
+
+// fetch fetches the key set unless it has been fetched since fetchedAt by a concurrent request or
+// the last fetch was made less than the minimum refetch interval ago.
+func (r *JWKSResolver) fetch(ctx context.Context, fetchedAt time.Time) {
+	r.fetchLock.Lock()
+	defer r.fetchLock.Unlock()
+	r.lock.RLock()
+	refreshed := r.fetchedAt.After(fetchedAt)
+	r.lock.RUnlock()
+	if refreshed || !r.lastFetch.IsZero() && r.now().Sub(r.lastFetch) < r.minRefetch {
+		return
+	}
+	r.load(ctx)
+}
+
+// load fetches and parses the key set and replaces the cached keys. fetchLock must be held.
+func (r *JWKSResolver) load(ctx context.Context) error {
+	r.lastFetch = r.now()
+	req, err := http.NewRequest("GET", r.url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := r.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch JWKS from %s: %s", r.url, resp.Status)
+	}
+	var set jwks
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("invalid JWKS at %s: %s", r.url, err)
+	}
+	keys := make(map[string]Key, len(set.Keys))
+	all := make([]Key, 0, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil || key == nil {
+			continue
+		}
+		all = append(all, key)
+		if k.Kid != "" {
+			keys[k.Kid] = key
+		}
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.keys, r.all, r.fetchedAt = keys, all, r.now()
+	return nil
+}
+
+// publicKey returns the public key described by k or nil if the key type is not supported.
+func (k *jwk) publicKey() (Key, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, nil
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("invalid EC point")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, nil
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid Ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, nil
+}
+
+// decodeBigInt decodes a base64url encoded big-endian integer.
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("empty integer")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// tokenKeyID returns the "kid" header of the request token. The token is read from the request
+// context when the request is authorized by the middleware returned by New and from the
+// Authorization header otherwise.
+func tokenKeyID(req *http.Request) string {
+	token, ok := req.Context().Value(rawTokenKey).(string)
+	if !ok {
+		auth := req.Header.Get("Authorization")
+		if len(auth) < 7 || !strings.EqualFold(auth[:7], "bearer ") {
+			return ""
+		}
+		token = auth[7:]
+	}
+	i := strings.IndexByte(token, '.')
+	if i < 0 {
+		return ""
+	}
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(token[:i], "="))
+	if err != nil {
+		return ""
+	}
+	var header struct {
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(b, &header); err != nil {
+		return ""
+	}
+	return header.Kid
+}
@@ -0,0 +1,172 @@
+package jwt_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	jwtpkg "github.com/dgrijalva/jwt-go"
+	"github.com/goadesign/goa"
+	"github.com/goadesign/goa/middleware/security/jwt"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("JWKSResolver", func() {
+	var (
+		rsaKey *rsa.PrivateKey
+		ecKey  *ecdsa.PrivateKey
+		edPub  ed25519.PublicKey
+		edKey  ed25519.PrivateKey
+
+		lock    sync.Mutex
+		jwks    []map[string]string
+		fetches int
+		server  *httptest.Server
+
+		options  []jwt.JWKSOption
+		resolver *jwt.JWKSResolver
+	)
+
+	enc := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+	rsaJWK := func(kid string, k *rsa.PublicKey) map[string]string {
+		return map[string]string{"kty": "RSA", "kid": kid, "use": "sig",
+			"n": enc(k.N.Bytes()), "e": enc(big.NewInt(int64(k.E)).Bytes())}
+	}
+
+	sign := func(method jwtpkg.SigningMethod, kid string, key interface{}) string {
+		token := jwtpkg.NewWithClaims(method, jwtpkg.MapClaims{"sub": "me"})
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		s, err := token.SignedString(key)
+		Ω(err).ShouldNot(HaveOccurred())
+		return s
+	}
+
+	authorize := func(token string) error {
+		scheme := &goa.JWTSecurity{In: goa.LocHeader, Name: "Authorization"}
+		req, _ := http.NewRequest("GET", "http://example.com/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		h := func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error { return nil }
+		return jwt.New(resolver, nil, scheme)(h)(context.Background(), httptest.NewRecorder(), req)
+	}
+
+	BeforeEach(func() {
+		var err error
+		rsaKey, err = rsa.GenerateKey(rand.Reader, 2048)
+		Ω(err).ShouldNot(HaveOccurred())
+		ecKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Ω(err).ShouldNot(HaveOccurred())
+		edPub, edKey, err = ed25519.GenerateKey(rand.Reader)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		fetches = 0
+		jwks = []map[string]string{
+			rsaJWK("rsa", &rsaKey.PublicKey),
+			{"kty": "EC", "kid": "ec", "crv": "P-256",
+				"x": enc(ecKey.X.Bytes()), "y": enc(ecKey.Y.Bytes())},
+			{"kty": "OKP", "kid": "ed", "crv": "Ed25519", "x": enc(edPub)},
+			{"kty": "RSA", "kid": "enc", "use": "enc", "n": "AQAB", "e": "AQAB"},
+		}
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lock.Lock()
+			defer lock.Unlock()
+			fetches++
+			json.NewEncoder(w).Encode(map[string]interface{}{"keys": jwks})
+		}))
+		options = nil
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		resolver, err = jwt.NewJWKSResolver(server.URL, options...)
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("validates tokens signed with RSA, ECDSA and EdDSA keys", func() {
+		Ω(authorize(sign(jwtpkg.SigningMethodRS256, "rsa", rsaKey))).ShouldNot(HaveOccurred())
+		Ω(authorize(sign(jwtpkg.SigningMethodES256, "ec", ecKey))).ShouldNot(HaveOccurred())
+		Ω(authorize(sign(jwt.SigningMethodEdDSA, "ed", edKey))).ShouldNot(HaveOccurred())
+		Ω(fetches).Should(Equal(1))
+	})
+
+	It("selects the key using the kid header", func() {
+		Ω(authorize(sign(jwtpkg.SigningMethodRS256, "ec", rsaKey))).Should(HaveOccurred())
+	})
+
+	It("ignores keys that are not signature keys", func() {
+		req, _ := http.NewRequest("GET", "http://example.com/", nil)
+		Ω(resolver.Refresh(context.Background())).ShouldNot(HaveOccurred())
+		Ω(resolver.SelectKeys(req)).Should(HaveLen(3))
+	})
+
+	It("returns an error when the key set cannot be fetched", func() {
+		server.Close()
+		Ω(resolver.Refresh(context.Background())).Should(HaveOccurred())
+	})
+
+	Context("with rotated keys", func() {
+		var rotated *rsa.PrivateKey
+
+		BeforeEach(func() {
+			var err error
+			rotated, err = rsa.GenerateKey(rand.Reader, 2048)
+			Ω(err).ShouldNot(HaveOccurred())
+			options = []jwt.JWKSOption{jwt.JWKSMinRefetchInterval(0)}
+		})
+
+		It("fetches the key set again when the kid is unknown", func() {
+			Ω(authorize(sign(jwtpkg.SigningMethodRS256, "rsa", rsaKey))).ShouldNot(HaveOccurred())
+			lock.Lock()
+			jwks = append(jwks, rsaJWK("rotated", &rotated.PublicKey))
+			lock.Unlock()
+			Ω(authorize(sign(jwtpkg.SigningMethodRS256, "rotated", rotated))).ShouldNot(HaveOccurred())
+			Ω(fetches).Should(Equal(2))
+		})
+	})
+
+	Context("with a minimum refetch interval", func() {
+		BeforeEach(func() {
+			options = []jwt.JWKSOption{jwt.JWKSMinRefetchInterval(time.Hour)}
+		})
+
+		It("rate limits the fetches caused by unknown kids", func() {
+			for i := 0; i < 3; i++ {
+				Ω(authorize(sign(jwtpkg.SigningMethodRS256, "unknown", rsaKey))).Should(HaveOccurred())
+			}
+			Ω(fetches).Should(Equal(1))
+		})
+	})
+
+	Context("with a refresh interval", func() {
+		BeforeEach(func() {
+			options = []jwt.JWKSOption{jwt.JWKSRefreshInterval(10 * time.Millisecond), jwt.JWKSMinRefetchInterval(0)}
+		})
+
+		It("refreshes the stale key set", func() {
+			token := sign(jwtpkg.SigningMethodRS256, "rsa", rsaKey)
+			Ω(authorize(token)).ShouldNot(HaveOccurred())
+			Ω(authorize(token)).ShouldNot(HaveOccurred())
+			Ω(fetches).Should(Equal(1))
+			time.Sleep(15 * time.Millisecond)
+			Ω(authorize(token)).ShouldNot(HaveOccurred())
+			Ω(fetches).Should(Equal(2))
+		})
+	})
+})
@@ -2,11 +2,13 @@ package jwt
 
 import (
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"fmt"
 	"net/http"
 	"sort"
 	"strings"
+	"time"
 
 	"context"
 
@@ -19,13 +21,19 @@ import (
 //
 // The steps taken by the middleware are:
 //
-//     1. Validate the "Bearer" token present in the "Authorization" header against the key(s)
-//        given to New
-//     2. If scopes are defined in the design for the action, validate them
+//     1. Read the token from the header (as a "Bearer" token), query string parameter or cookie
+//        named by the scheme depending on its In field (goa.LocHeader, goa.LocQuery or
+//        goa.LocCookie)
+//     2. Validate the token signature against the key(s) given to New
+//     3. Validate the `exp` (expiration), `nbf` (not before) and `iat` (issued at) claims
+//        when present as well as the claims required by the options, see Issuer, Audience,
+//        Leeway and RequireClaim
+//     4. If scopes are defined in the design for the action, validate them
 //        against the scopes presented by the JWT in the claim "scope", or if
 //        that's not defined, "scopes".
+//     5. Decode the claims into the value given to BindClaims if any
 //
-// The `exp` (expiration) and `nbf` (not before) date checks are validated by the JWT library.
+// Failures are reported with ErrJWTError and a message describing the failure.
 //
 // validationKeys can be one of these:
 //
@@ -33,6 +41,7 @@ import (
 //     * string
 //     * an *rsa.PublicKey
 //     * an *ecdsa.PublicKey
+//     * an ed25519.PublicKey
 //     * a slice of any of the above
 //
 // Keys of type string or []byte are interpreted according to the signing method defined in the JWT
@@ -57,31 +66,28 @@ import (
 // defined in the design, e.g.:
 //
 //    jwtResolver, _ := jwt.NewSimpleResolver("secret")
-//    app.UseJWT(jwt.New(jwtResolver, validationHandler, app.NewJWTSecurity()))
+//    app.UseJWT(jwt.New(jwtResolver, validationHandler, app.NewJWTSecurity(),
+//        jwt.Issuer("https://auth.example.com"), jwt.Audience("api"), jwt.Leeway(30*time.Second)))
 //
-func New(resolver KeyResolver, validationFunc goa.Middleware, scheme *goa.JWTSecurity) goa.Middleware {
+func New(resolver KeyResolver, validationFunc goa.Middleware, scheme *goa.JWTSecurity, o ...Option) goa.Middleware {
+	opts := &options{now: time.Now}
+	for _, opt := range o {
+		if err := opt(opts); err != nil {
+			panic(err)
+		}
+	}
 	return func(nextHandler goa.Handler) goa.Handler {
 		return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
-			// TODO: implement the QUERY string handler too
-			if scheme.In != goa.LocHeader {
-				return fmt.Errorf("whoops, security scheme with location (in) %q not supported", scheme.In)
-			}
-			val := req.Header.Get(scheme.Name)
-			if val == "" {
-				return ErrJWTError(fmt.Sprintf("missing header %q", scheme.Name))
-			}
-
-			if !strings.HasPrefix(strings.ToLower(val), "bearer ") {
-				return ErrJWTError(fmt.Sprintf("invalid or malformed %q header, expected 'Bearer JWT-token...'", val))
+			incomingToken, err := extractToken(req, scheme)
+			if err != nil {
+				return err
 			}
 
-			incomingToken := strings.Split(val, " ")[1]
-
-			rsaKeys, ecdsaKeys, hmacKeys := partitionKeys(resolver.SelectKeys(req))
+			keyReq := req.WithContext(context.WithValue(req.Context(), rawTokenKey, incomingToken))
+			rsaKeys, ecdsaKeys, edKeys, hmacKeys := partitionKeys(resolver.SelectKeys(keyReq))
 
 			var (
 				token     *jwt.Token
-				err       error
 				validated = false
 			)
 
@@ -99,6 +105,13 @@ func New(resolver KeyResolver, validationFunc goa.Middleware, scheme *goa.JWTSec
 				}
 			}
 
+			if !validated && len(edKeys) > 0 {
+				token, err = validateEdDSAKeys(edKeys, "EdDSA", incomingToken)
+				if err == nil {
+					validated = true
+				}
+			}
+
 			if !validated && len(hmacKeys) > 0 {
 				token, err = validateHMACKeys(hmacKeys, "HS", incomingToken)
 				if err == nil {
@@ -107,7 +120,18 @@ func New(resolver KeyResolver, validationFunc goa.Middleware, scheme *goa.JWTSec
 			}
 
 			if !validated {
-				return ErrJWTError("JWT validation failed")
+				if err != nil {
+					return ErrJWTError(fmt.Sprintf("JWT validation failed: %s", err))
+				}
+				return ErrJWTError("JWT validation failed: no key matching the token")
+			}
+
+			claims, ok := token.Claims.(jwt.MapClaims)
+			if !ok {
+				return ErrJWTError("unsupported claims shape")
+			}
+			if err := opts.validateClaims(claims); err != nil {
+				return ErrJWTError(err.Error())
 			}
 
 			scopesInClaim, scopesInClaimList, err := parseClaimScopes(token)
@@ -126,19 +150,57 @@ func New(resolver KeyResolver, validationFunc goa.Middleware, scheme *goa.JWTSec
 			}
 
 			ctx = WithJWT(ctx, token)
+			if opts.claims != nil {
+				bound, err := opts.bindClaims(claims)
+				if err != nil {
+					return ErrJWTError(err.Error())
+				}
+				ctx = WithClaims(ctx, bound)
+			}
+			h := nextHandler
 			if validationFunc != nil {
-				nextHandler = validationFunc(nextHandler)
+				h = validationFunc(h)
 			}
-			return nextHandler(ctx, rw, req)
+			return h(ctx, rw, req)
 		}
 	}
 }
 
+// extractToken returns the token contained in the request header, query string parameter or
+// cookie named by the scheme.
+func extractToken(req *http.Request, scheme *goa.JWTSecurity) (string, error) {
+	switch scheme.In {
+	case goa.LocHeader:
+		val := req.Header.Get(scheme.Name)
+		if val == "" {
+			return "", ErrJWTError(fmt.Sprintf("missing header %q", scheme.Name))
+		}
+		if !strings.HasPrefix(strings.ToLower(val), "bearer ") {
+			return "", ErrJWTError(fmt.Sprintf("invalid or malformed %q header, expected 'Bearer JWT-token...'", val))
+		}
+		return strings.TrimSpace(val[len("bearer "):]), nil
+	case goa.LocQuery:
+		val := req.URL.Query().Get(scheme.Name)
+		if val == "" {
+			return "", ErrJWTError(fmt.Sprintf("missing query string parameter %q", scheme.Name))
+		}
+		return val, nil
+	case goa.LocCookie:
+		c, err := req.Cookie(scheme.Name)
+		if err != nil || c.Value == "" {
+			return "", ErrJWTError(fmt.Sprintf("missing cookie %q", scheme.Name))
+		}
+		return c.Value, nil
+	}
+	return "", fmt.Errorf("whoops, security scheme with location (in) %q not supported", scheme.In)
+}
+
 // partitionKeys sorts keys by their type.
-func partitionKeys(keys []Key) ([]*rsa.PublicKey, []*ecdsa.PublicKey, [][]byte) {
+func partitionKeys(keys []Key) ([]*rsa.PublicKey, []*ecdsa.PublicKey, []ed25519.PublicKey, [][]byte) {
 	var (
 		rsaKeys   []*rsa.PublicKey
 		ecdsaKeys []*ecdsa.PublicKey
+		edKeys    []ed25519.PublicKey
 		hmacKeys  [][]byte
 	)
 
@@ -148,6 +210,8 @@ func partitionKeys(keys []Key) ([]*rsa.PublicKey, []*ecdsa.PublicKey, [][]byte)
 			rsaKeys = append(rsaKeys, k)
 		case *ecdsa.PublicKey:
 			ecdsaKeys = append(ecdsaKeys, k)
+		case ed25519.PublicKey:
+			edKeys = append(edKeys, k)
 		case []byte:
 			hmacKeys = append(hmacKeys, k)
 		case string:
@@ -155,7 +219,7 @@ func partitionKeys(keys []Key) ([]*rsa.PublicKey, []*ecdsa.PublicKey, [][]byte)
 		}
 	}
 
-	return rsaKeys, ecdsaKeys, hmacKeys
+	return rsaKeys, ecdsaKeys, edKeys, hmacKeys
 }
 
 // validScopeClaimKeys are the claims under which scopes may be found in a token
@@ -201,9 +265,13 @@ func parseClaimScopes(token *jwt.Token) (map[string]bool, []string, error) {
 	return scopesInClaim, scopesInClaimList, nil
 }
 
+// parser parses the incoming tokens, the claims are validated by the middleware so that the
+// configured leeway applies.
+var parser = &jwt.Parser{SkipClaimsValidation: true}
+
 func validateRSAKeys(rsaKeys []*rsa.PublicKey, algo, incomingToken string) (token *jwt.Token, err error) {
 	for _, pubkey := range rsaKeys {
-		token, err = jwt.Parse(incomingToken, func(token *jwt.Token) (interface{}, error) {
+		token, err = parser.Parse(incomingToken, func(token *jwt.Token) (interface{}, error) {
 			if !strings.HasPrefix(token.Method.Alg(), algo) {
 				return nil, ErrJWTError(fmt.Sprintf("Unexpected signing method: %v", token.Header["alg"]))
 			}
@@ -218,7 +286,7 @@ func validateRSAKeys(rsaKeys []*rsa.PublicKey, algo, incomingToken string) (toke
 
 func validateECDSAKeys(ecdsaKeys []*ecdsa.PublicKey, algo, incomingToken string) (token *jwt.Token, err error) {
 	for _, pubkey := range ecdsaKeys {
-		token, err = jwt.Parse(incomingToken, func(token *jwt.Token) (interface{}, error) {
+		token, err = parser.Parse(incomingToken, func(token *jwt.Token) (interface{}, error) {
 			if !strings.HasPrefix(token.Method.Alg(), algo) {
 				return nil, ErrJWTError(fmt.Sprintf("Unexpected signing method: %v", token.Header["alg"]))
 			}
@@ -231,9 +299,24 @@ func validateECDSAKeys(ecdsaKeys []*ecdsa.PublicKey, algo, incomingToken string)
 	return
 }
 
+func validateEdDSAKeys(edKeys []ed25519.PublicKey, algo, incomingToken string) (token *jwt.Token, err error) {
+	for _, pubkey := range edKeys {
+		token, err = parser.Parse(incomingToken, func(token *jwt.Token) (interface{}, error) {
+			if token.Method.Alg() != algo {
+				return nil, ErrJWTError(fmt.Sprintf("Unexpected signing method: %v", token.Header["alg"]))
+			}
+			return pubkey, nil
+		})
+		if err == nil {
+			return
+		}
+	}
+	return
+}
+
 func validateHMACKeys(hmacKeys [][]byte, algo, incomingToken string) (token *jwt.Token, err error) {
 	for _, key := range hmacKeys {
-		token, err = jwt.Parse(incomingToken, func(token *jwt.Token) (interface{}, error) {
+		token, err = parser.Parse(incomingToken, func(token *jwt.Token) (interface{}, error) {
 			if !strings.HasPrefix(token.Method.Alg(), algo) {
 				return nil, ErrJWTError(fmt.Sprintf("Unexpected signing method: %v", token.Header["alg"]))
 			}
@@ -2,6 +2,7 @@ package jwt
 
 import (
 	"crypto/ecdsa"
+	"crypto/ed25519"
 	"crypto/rsa"
 	"net/http"
 	"sync"
@@ -9,7 +10,8 @@ import (
 
 type (
 	// Key represents a public key used to validate the incoming token signatures.
-	// The value must be of type *rsa.PublicKey, *ecdsa.PublicKey, []byte or string.
+	// The value must be of type *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey, []byte or
+	// string.
 	// Keys of type []byte or string are interpreted depending on the incoming request JWT token
 	// method (HMAC, RSA, etc.).
 	Key interface{}
@@ -46,7 +48,7 @@ func NewResolver(keys map[string][]Key, header string) (*GroupResolver, error) {
 	for name := range keys {
 		for _, keys := range keys[name] {
 			switch keys := keys.(type) {
-			case *rsa.PublicKey, *ecdsa.PublicKey, string, []byte:
+			case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey, string, []byte:
 				keyMap[name] = append(keyMap[name], keys)
 			case []*rsa.PublicKey:
 				for _, key := range keys {
@@ -89,7 +91,7 @@ func (kr *GroupResolver) AddKeys(name string, keys Key) error {
 	kr.Lock()
 	defer kr.Unlock()
 	switch keys := keys.(type) {
-	case *rsa.PublicKey, *ecdsa.PublicKey, []byte, string:
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey, []byte, string:
 		kr.keyMap[name] = append(kr.keyMap[name], keys)
 	case []*rsa.PublicKey:
 		for _, key := range keys {
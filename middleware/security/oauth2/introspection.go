@@ -0,0 +1,195 @@
+package oauth2
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// Introspector is a Validator that validates tokens by querying a token introspection
+	// endpoint as described in RFC 7662. The introspection results are cached for the cache
+	// TTL, the results of active tokens are never cached past the token expiry.
+	Introspector struct {
+		endpoint   string
+		client     *http.Client
+		clientID   string
+		secret     string
+		ttl        time.Duration
+		maxEntries int
+		now        func() time.Time
+
+		lock  sync.Mutex
+		cache map[[sha256.Size]byte]*cacheEntry
+	}
+
+	// IntrospectorOption allows to override the default introspector parameters.
+	IntrospectorOption func(*Introspector) error
+
+	// cacheEntry is a cached introspection result.
+	cacheEntry struct {
+		info   *TokenInfo
+		expiry time.Time
+	}
+)
+
+// ClientCredentials sets the credentials used to authenticate with the introspection endpoint
+// using HTTP basic authentication.
+func ClientCredentials(clientID, secret string) IntrospectorOption {
+	return func(i *Introspector) error {
+		if clientID == "" {
+			return errors.New("oauth2: client ID must not be empty")
+		}
+		i.clientID, i.secret = clientID, secret
+		return nil
+	}
+}
+
+// IntrospectionClient sets the HTTP client used to query the introspection endpoint, the default
+// client has a 10s timeout.
+func IntrospectionClient(c *http.Client) IntrospectorOption {
+	return func(i *Introspector) error {
+		if c == nil {
+			return errors.New("oauth2: HTTP client must not be nil")
+		}
+		i.client = c
+		return nil
+	}
+}
+
+// CacheTTL sets the maximum duration the introspection results are cached for, the default is
+// 1m. A zero value disables caching.
+func CacheTTL(d time.Duration) IntrospectorOption {
+	return func(i *Introspector) error {
+		if d < 0 {
+			return fmt.Errorf("oauth2: invalid cache TTL %s", d)
+		}
+		i.ttl = d
+		return nil
+	}
+}
+
+// MaxCacheEntries sets the maximum number of cached introspection results, the default is 10000.
+func MaxCacheEntries(n int) IntrospectorOption {
+	return func(i *Introspector) error {
+		if n < 1 {
+			return fmt.Errorf("oauth2: invalid max cache entries %d", n)
+		}
+		i.maxEntries = n
+		return nil
+	}
+}
+
+// NewIntrospector returns a validator that queries the token introspection endpoint at the given
+// URL.
+func NewIntrospector(endpoint string, o ...IntrospectorOption) (*Introspector, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("oauth2: invalid introspection endpoint %q", endpoint)
+	}
+	i := &Introspector{
+		endpoint:   endpoint,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		ttl:        time.Minute,
+		maxEntries: 10000,
+		now:        time.Now,
+		cache:      make(map[[sha256.Size]byte]*cacheEntry),
+	}
+	for _, opt := range o {
+		if err := opt(i); err != nil {
+			return nil, err
+		}
+	}
+	return i, nil
+}
+
+// Validate implements Validator.
+func (i *Introspector) Validate(ctx context.Context, token string) (*TokenInfo, error) {
+	key := sha256.Sum256([]byte(token))
+	now := i.now()
+	if i.ttl > 0 {
+		i.lock.Lock()
+		e, ok := i.cache[key]
+		i.lock.Unlock()
+		if ok && now.Before(e.expiry) {
+			return e.info, nil
+		}
+	}
+	info, err := i.introspect(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if i.ttl > 0 {
+		expiry := now.Add(i.ttl)
+		if info.Active && info.Expiry != 0 {
+			if exp := time.Unix(info.Expiry, 0); exp.Before(expiry) {
+				expiry = exp
+			}
+		}
+		i.store(key, &cacheEntry{info: info, expiry: expiry}, now)
+	}
+	return info, nil
+}
+
+// introspect queries the introspection endpoint.
+func (i *Introspector) introspect(ctx context.Context, token string) (*TokenInfo, error) {
+	form := url.Values{"token": {token}, "token_type_hint": {"access_token"}}
+	req, err := http.NewRequest("POST", i.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if i.clientID != "" {
+		req.SetBasicAuth(url.QueryEscape(i.clientID), url.QueryEscape(i.secret))
+	}
+	resp, err := i.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("introspection endpoint returned %s", resp.Status)
+	}
+	var extra map[string]interface{}
+	dec := json.NewDecoder(resp.Body)
+	dec.UseNumber()
+	if err := dec.Decode(&extra); err != nil {
+		return nil, fmt.Errorf("invalid introspection response: %s", err)
+	}
+	b, _ := json.Marshal(extra)
+	var info TokenInfo
+	if err := json.Unmarshal(b, &info); err != nil {
+		return nil, fmt.Errorf("invalid introspection response: %s", err)
+	}
+	info.Extra = extra
+	return &info, nil
+}
+
+// store caches e, expired entries are purged when the cache is full and arbitrary entries are
+// evicted if it is still full.
+func (i *Introspector) store(key [sha256.Size]byte, e *cacheEntry, now time.Time) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	if len(i.cache) >= i.maxEntries {
+		for k, c := range i.cache {
+			if !now.Before(c.expiry) {
+				delete(i.cache, k)
+			}
+		}
+		for k := range i.cache {
+			if len(i.cache) < i.maxEntries {
+				break
+			}
+			delete(i.cache, k)
+		}
+	}
+	i.cache[key] = e
+}
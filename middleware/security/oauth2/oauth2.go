@@ -0,0 +1,229 @@
+/*
+Package oauth2 implements a middleware for services acting as OAuth2 resource servers. The
+middleware validates the bearer access tokens (RFC 6750) sent by the clients in the Authorization
+header, checks that the tokens grant the scopes required by the action and makes the token
+information available to the handlers via ContextTokenInfo. The token subject, or client ID if the
+token has no subject, and scopes are also available via goa.ContextPrincipal.
+
+Tokens are validated by a Validator. Introspector validates tokens by querying the token
+introspection endpoint (RFC 7662) of the authorization server and caches the results, custom
+validators may be provided for other token formats.
+
+The generated UseXXXOAuth2Middleware functions mount the middleware for the OAuth2 security
+schemes defined in the design, e.g.:
+
+    introspector, err := oauth2.NewIntrospector("https://auth.example.com/oauth2/introspect",
+        oauth2.ClientCredentials("resource-server", secret))
+    if err != nil {
+        return err
+    }
+    app.UseGoogAuthOAuth2Middleware(service, introspector)
+*/
+package oauth2
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/goadesign/goa"
+)
+
+var (
+	// ErrInvalidToken is the error returned when the access token is missing, expired, revoked
+	// or otherwise invalid.
+	ErrInvalidToken = goa.NewErrorClass("invalid_token", 401)
+
+	// ErrInsufficientScope is the error returned when the access token does not grant the
+	// scopes required by the action.
+	ErrInsufficientScope = goa.NewErrorClass("insufficient_scope", 403)
+
+	// ErrValidationUnavailable is the error returned when the validator fails to validate the
+	// token, e.g. because the introspection endpoint cannot be reached.
+	ErrValidationUnavailable = goa.NewErrorClass("token_validation_unavailable", 503)
+)
+
+type (
+	// Validator validates access tokens.
+	Validator interface {
+		// Validate returns the information of the given access token. It returns a
+		// TokenInfo whose Active field is false if the token is not valid and an error
+		// only if the token could not be validated.
+		Validate(ctx context.Context, token string) (*TokenInfo, error)
+	}
+
+	// ValidatorFunc is an adapter that makes it possible to use a function as a Validator.
+	ValidatorFunc func(ctx context.Context, token string) (*TokenInfo, error)
+
+	// TokenInfo describes an access token, the fields are the ones defined by RFC 7662.
+	TokenInfo struct {
+		// Active is true if the token is valid.
+		Active bool `json:"active"`
+		// Scope is the space separated list of scopes granted by the token.
+		Scope string `json:"scope,omitempty"`
+		// ClientID is the identifier of the client the token was issued to.
+		ClientID string `json:"client_id,omitempty"`
+		// Username is the name of the resource owner who authorized the token.
+		Username string `json:"username,omitempty"`
+		// TokenType is the type of the token, e.g. "Bearer".
+		TokenType string `json:"token_type,omitempty"`
+		// Expiry is the time the token expires as a number of seconds since the epoch.
+		Expiry int64 `json:"exp,omitempty"`
+		// IssuedAt is the time the token was issued as a number of seconds since the epoch.
+		IssuedAt int64 `json:"iat,omitempty"`
+		// NotBefore is the time before which the token is not valid as a number of seconds
+		// since the epoch.
+		NotBefore int64 `json:"nbf,omitempty"`
+		// Subject is the identifier of the resource owner.
+		Subject string `json:"sub,omitempty"`
+		// Issuer is the identifier of the authorization server that issued the token.
+		Issuer string `json:"iss,omitempty"`
+		// JWTID is the identifier of the token.
+		JWTID string `json:"jti,omitempty"`
+		// Extra contains all the fields of the introspection response including the ones
+		// above and the "aud" field which may be a string or a list of strings.
+		Extra map[string]interface{} `json:"-"`
+	}
+
+	// Option allows to override default parameters.
+	Option func(*options) error
+
+	// options contains final options
+	options struct {
+		realm  string
+		leeway time.Duration
+		now    func() time.Time
+	}
+
+	// contextKey is the private type used to store values in the context.
+	contextKey int
+)
+
+const tokenInfoKey contextKey = iota + 1
+
+// Validate calls f.
+func (f ValidatorFunc) Validate(ctx context.Context, token string) (*TokenInfo, error) {
+	return f(ctx, token)
+}
+
+// Scopes returns the scopes granted by the token.
+func (t *TokenInfo) Scopes() []string {
+	return strings.Fields(t.Scope)
+}
+
+// Realm sets the realm reported in the WWW-Authenticate header of the error responses.
+func Realm(realm string) Option {
+	return func(o *options) error {
+		if strings.ContainsAny(realm, "\"\\") {
+			return fmt.Errorf("oauth2: invalid realm %q", realm)
+		}
+		o.realm = realm
+		return nil
+	}
+}
+
+// Leeway sets the clock skew tolerated when checking the "exp" and "nbf" fields of the token
+// information, the default is 0.
+func Leeway(d time.Duration) Option {
+	return func(o *options) error {
+		if d < 0 {
+			return fmt.Errorf("oauth2: invalid leeway %s", d)
+		}
+		o.leeway = d
+		return nil
+	}
+}
+
+// WithTokenInfo creates a child context containing the given token information.
+func WithTokenInfo(ctx context.Context, info *TokenInfo) context.Context {
+	return context.WithValue(ctx, tokenInfoKey, info)
+}
+
+// ContextTokenInfo retrieves the information of the access token validated by the middleware.
+func ContextTokenInfo(ctx context.Context) *TokenInfo {
+	info, _ := ctx.Value(tokenInfoKey).(*TokenInfo)
+	return info
+}
+
+// New returns a middleware that validates the bearer access token of the requests with
+// validator. The middleware checks that the token is active, has not expired and grants the
+// scopes returned by goa.ContextRequiredScopes. Failures set the WWW-Authenticate header as
+// described in RFC 6750.
+func New(validator Validator, o ...Option) goa.Middleware {
+	opts := options{now: time.Now}
+	for _, opt := range o {
+		if err := opt(&opts); err != nil {
+			panic(err)
+		}
+	}
+	return func(h goa.Handler) goa.Handler {
+		return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			token, ok := bearerToken(req)
+			if !ok {
+				opts.challenge(rw, "", "")
+				return ErrInvalidToken("missing bearer access token")
+			}
+			info, err := validator.Validate(ctx, token)
+			if err != nil {
+				goa.LogError(ctx, "failed to validate access token", "err", err)
+				return ErrValidationUnavailable("failed to validate access token")
+			}
+			if info == nil || !info.Active {
+				opts.challenge(rw, "invalid_token", "")
+				return ErrInvalidToken("access token is not active")
+			}
+			now := opts.now()
+			if info.Expiry != 0 && now.After(time.Unix(info.Expiry, 0).Add(opts.leeway)) {
+				opts.challenge(rw, "invalid_token", "")
+				return ErrInvalidToken("access token is expired")
+			}
+			if info.NotBefore != 0 && now.Add(opts.leeway).Before(time.Unix(info.NotBefore, 0)) {
+				opts.challenge(rw, "invalid_token", "")
+				return ErrInvalidToken("access token is not valid yet")
+			}
+			required := goa.ContextRequiredScopes(ctx)
+			if missing := goa.MissingScopes(required, info.Scopes()); missing != nil {
+				opts.challenge(rw, "insufficient_scope", strings.Join(required, " "))
+				return ErrInsufficientScope("access token does not grant the required scopes",
+					"required", required, "scopes", info.Scopes())
+			}
+			id := info.Subject
+			if id == "" {
+				id = info.ClientID
+			}
+			ctx = goa.WithPrincipal(ctx, &goa.Principal{ID: id, Scopes: info.Scopes()})
+			return h(WithTokenInfo(ctx, info), rw, req)
+		}
+	}
+}
+
+// challenge sets the WWW-Authenticate response header.
+func (o *options) challenge(rw http.ResponseWriter, code, scope string) {
+	var params []string
+	if o.realm != "" {
+		params = append(params, fmt.Sprintf("realm=%q", o.realm))
+	}
+	if code != "" {
+		params = append(params, fmt.Sprintf("error=%q", code))
+	}
+	if scope != "" {
+		params = append(params, fmt.Sprintf("scope=%q", scope))
+	}
+	val := "Bearer"
+	if len(params) > 0 {
+		val += " " + strings.Join(params, ", ")
+	}
+	rw.Header().Set("WWW-Authenticate", val)
+}
+
+// bearerToken returns the bearer token contained in the request Authorization header.
+func bearerToken(req *http.Request) (string, bool) {
+	auth := req.Header.Get("Authorization")
+	if len(auth) < 7 || !strings.EqualFold(auth[:7], "bearer ") {
+		return "", false
+	}
+	token := strings.TrimSpace(auth[7:])
+	return token, token != ""
+}
@@ -0,0 +1,229 @@
+package oauth2_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/goadesign/goa"
+	"github.com/goadesign/goa/middleware/security/oauth2"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Middleware", func() {
+	var (
+		info      *oauth2.TokenInfo
+		verr      error
+		scopes    []string
+		authz     string
+		validator oauth2.Validator
+
+		rw        *httptest.ResponseRecorder
+		err       error
+		received  *oauth2.TokenInfo
+		principal *goa.Principal
+	)
+
+	BeforeEach(func() {
+		info = &oauth2.TokenInfo{Active: true, Scope: "read write", Subject: "me"}
+		verr = nil
+		scopes = nil
+		authz = "Bearer token"
+		validator = oauth2.ValidatorFunc(func(ctx context.Context, token string) (*oauth2.TokenInfo, error) {
+			Ω(token).Should(Equal("token"))
+			return info, verr
+		})
+		received = nil
+	})
+
+	JustBeforeEach(func() {
+		req, _ := http.NewRequest("GET", "http://example.com/", nil)
+		if authz != "" {
+			req.Header.Set("Authorization", authz)
+		}
+		rw = httptest.NewRecorder()
+		h := func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			received = oauth2.ContextTokenInfo(ctx)
+			principal = goa.ContextPrincipal(ctx)
+			return nil
+		}
+		ctx := goa.WithRequiredScopes(context.Background(), scopes)
+		err = oauth2.New(validator, oauth2.Realm("api"))(h)(ctx, rw, req)
+	})
+
+	It("makes the token info available to the handler", func() {
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(received).Should(Equal(info))
+		Ω(principal).Should(Equal(&goa.Principal{ID: "me", Scopes: []string{"read", "write"}}))
+	})
+
+	Context("with the required scopes", func() {
+		BeforeEach(func() {
+			scopes = []string{"write"}
+		})
+
+		It("accepts the request", func() {
+			Ω(err).ShouldNot(HaveOccurred())
+		})
+	})
+
+	Context("with missing scopes", func() {
+		BeforeEach(func() {
+			scopes = []string{"read", "admin"}
+		})
+
+		It("rejects the request", func() {
+			Ω(err).Should(HaveOccurred())
+			Ω(err.(*goa.ErrorResponse).Status).Should(Equal(403))
+			Ω(rw.Header().Get("WWW-Authenticate")).Should(Equal(`Bearer realm="api", error="insufficient_scope", scope="read admin"`))
+		})
+	})
+
+	Context("without token", func() {
+		BeforeEach(func() {
+			authz = ""
+		})
+
+		It("rejects the request", func() {
+			Ω(err).Should(HaveOccurred())
+			Ω(err.(*goa.ErrorResponse).Status).Should(Equal(401))
+			Ω(rw.Header().Get("WWW-Authenticate")).Should(Equal(`Bearer realm="api"`))
+		})
+	})
+
+	Context("with an inactive token", func() {
+		BeforeEach(func() {
+			info = &oauth2.TokenInfo{Active: false}
+		})
+
+		It("rejects the request", func() {
+			Ω(err).Should(HaveOccurred())
+			Ω(err.(*goa.ErrorResponse).Code).Should(Equal("invalid_token"))
+			Ω(rw.Header().Get("WWW-Authenticate")).Should(ContainSubstring(`error="invalid_token"`))
+		})
+	})
+
+	Context("with an expired token", func() {
+		BeforeEach(func() {
+			info.Expiry = time.Now().Add(-time.Minute).Unix()
+		})
+
+		It("rejects the request", func() {
+			Ω(err).Should(HaveOccurred())
+			Ω(err.(*goa.ErrorResponse).Detail).Should(Equal("access token is expired"))
+		})
+	})
+
+	Context("when the validation fails", func() {
+		BeforeEach(func() {
+			verr = errors.New("boom")
+		})
+
+		It("returns a 503", func() {
+			Ω(err).Should(HaveOccurred())
+			Ω(err.(*goa.ErrorResponse).Status).Should(Equal(503))
+		})
+	})
+})
+
+var _ = Describe("Introspector", func() {
+	var (
+		lock      sync.Mutex
+		responses map[string]map[string]interface{}
+		calls     int
+		server    *httptest.Server
+
+		options      []oauth2.IntrospectorOption
+		introspector *oauth2.Introspector
+	)
+
+	BeforeEach(func() {
+		calls = 0
+		responses = map[string]map[string]interface{}{
+			"good": {"active": true, "scope": "read", "client_id": "app", "sub": "me",
+				"exp": time.Now().Add(time.Hour).Unix(), "aud": []string{"api"}},
+		}
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lock.Lock()
+			defer lock.Unlock()
+			calls++
+			if id, secret, ok := r.BasicAuth(); !ok || id != "rs" || secret != "s3cr3t" {
+				w.WriteHeader(401)
+				return
+			}
+			resp, ok := responses[r.PostFormValue("token")]
+			if !ok {
+				resp = map[string]interface{}{"active": false}
+			}
+			json.NewEncoder(w).Encode(resp)
+		}))
+		options = []oauth2.IntrospectorOption{oauth2.ClientCredentials("rs", "s3cr3t")}
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		introspector, err = oauth2.NewIntrospector(server.URL, options...)
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("introspects active tokens", func() {
+		info, err := introspector.Validate(context.Background(), "good")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(info.Active).Should(BeTrue())
+		Ω(info.Scopes()).Should(Equal([]string{"read"}))
+		Ω(info.ClientID).Should(Equal("app"))
+		Ω(info.Subject).Should(Equal("me"))
+		Ω(info.Extra).Should(HaveKey("aud"))
+	})
+
+	It("introspects inactive tokens", func() {
+		info, err := introspector.Validate(context.Background(), "bad")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(info.Active).Should(BeFalse())
+	})
+
+	It("caches the results", func() {
+		for i := 0; i < 3; i++ {
+			_, err := introspector.Validate(context.Background(), "good")
+			Ω(err).ShouldNot(HaveOccurred())
+		}
+		Ω(calls).Should(Equal(1))
+	})
+
+	Context("with caching disabled", func() {
+		BeforeEach(func() {
+			options = append(options, oauth2.CacheTTL(0))
+		})
+
+		It("introspects each time", func() {
+			introspector.Validate(context.Background(), "good")
+			introspector.Validate(context.Background(), "good")
+			Ω(calls).Should(Equal(2))
+		})
+	})
+
+	Context("with invalid credentials", func() {
+		BeforeEach(func() {
+			options = []oauth2.IntrospectorOption{oauth2.ClientCredentials("rs", "wrong")}
+		})
+
+		It("returns an error", func() {
+			_, err := introspector.Validate(context.Background(), "good")
+			Ω(err).Should(HaveOccurred())
+		})
+	})
+
+	It("rejects invalid endpoints", func() {
+		_, err := oauth2.NewIntrospector("not a url")
+		Ω(err).Should(HaveOccurred())
+	})
+})
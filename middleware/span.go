@@ -0,0 +1,289 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/goadesign/goa"
+	"github.com/goadesign/goa/client"
+)
+
+// Span attribute keys. The HTTP and URL keys follow the OpenTelemetry semantic conventions.
+const (
+	// AttributeController is the name of the controller handling the request.
+	AttributeController = "goa.controller"
+	// AttributeAction is the name of the action handling the request.
+	AttributeAction = "goa.action"
+	// AttributeErrorCode is the code of the goa.ErrorResponse returned by the action if any.
+	AttributeErrorCode = "goa.error.code"
+	// AttributeErrorID is the ID of the goa error returned by the action if any.
+	AttributeErrorID = "goa.error.id"
+	// AttributeHTTPMethod is the request HTTP method.
+	AttributeHTTPMethod = "http.request.method"
+	// AttributeHTTPRoute is the path of the route that matched the request, see goa.ContextRoute.
+	AttributeHTTPRoute = "http.route"
+	// AttributeHTTPStatusCode is the response HTTP status code.
+	AttributeHTTPStatusCode = "http.response.status_code"
+	// AttributeURLPath is the request path.
+	AttributeURLPath = "url.path"
+	// AttributeServerAddress is the host targeted by client requests.
+	AttributeServerAddress = "server.address"
+)
+
+// List of span kinds.
+const (
+	// SpanKindServer is the kind of the spans created by the tracer middleware.
+	SpanKindServer SpanKind = iota + 1
+	// SpanKindClient is the kind of the spans created by the doer returned by SpanDoer.
+	SpanKindClient
+)
+
+// List of span status codes.
+const (
+	// SpanStatusUnset is the default status.
+	SpanStatusUnset SpanStatusCode = iota
+	// SpanStatusOK indicates that the operation was explicitly marked as successful.
+	SpanStatusOK
+	// SpanStatusError indicates that the operation failed.
+	SpanStatusError
+)
+
+type (
+	// SpanKind describes the relationship between the span and its parent.
+	SpanKind int
+
+	// SpanStatusCode is the status of the operation described by a span.
+	SpanStatusCode int
+
+	// Span describes a single operation of a trace, for example the handling of a request by
+	// the service or a request made to another service.
+	Span struct {
+		// TraceID is the ID of the trace the span belongs to.
+		TraceID string
+		// SpanID is the ID of the span.
+		SpanID string
+		// ParentSpanID is the ID of the parent span if any.
+		ParentSpanID string
+		// Name is the span name, for example "GET /bottles/:id".
+		Name string
+		// Kind is the span kind.
+		Kind SpanKind
+		// Start is the time the operation started.
+		Start time.Time
+		// End is the time the operation ended.
+		End time.Time
+		// Attributes contains the span attributes, values are strings, booleans, integers
+		// or floats.
+		Attributes map[string]interface{}
+		// Status is the status of the operation.
+		Status SpanStatusCode
+		// StatusMessage describes the error if Status is SpanStatusError.
+		StatusMessage string
+	}
+
+	// SpanExporter is the interface implemented by the span exporters. The tracer
+	// middleware and the doer returned by SpanDoer call ExportSpan with each sampled span
+	// once it ends. Implementations must be safe for concurrent use and should not block.
+	SpanExporter interface {
+		ExportSpan(*Span)
+	}
+
+	// InMemoryExporter is a SpanExporter that keeps the spans in memory. It is intended for
+	// tests.
+	InMemoryExporter struct {
+		lock  sync.Mutex
+		spans []*Span
+	}
+
+	// spanDoer is a goa client Doer that records a span for each request it makes.
+	spanDoer struct {
+		client.Doer
+		exporter SpanExporter
+	}
+)
+
+// ExportSpans is a constructor option that makes the tracer middleware record a span for each
+// sampled request and export it with the given exporter. The span is available to the
+// handlers via ContextSpan. Setting an exporter also changes the default trace and span ID
+// functions to HexTraceID and HexSpanID.
+func ExportSpans(exporter SpanExporter) TracerOption {
+	return func(o *tracerOptions) *tracerOptions {
+		o.exporter = exporter
+		return o
+	}
+}
+
+// SpanDoer wraps a goa client Doer and records a client span for each request made with a
+// sampled trace in the context. The client span is the parent of the span of the downstream
+// service: wrap a doer created with TraceDoer to propagate the trace, for example:
+//
+//	doer := goaclient.HTTPClientDoer(http.DefaultClient)
+//	doer = middleware.SpanDoer(middleware.TraceDoer(doer, middleware.NewW3CPropagator()), exporter)
+//	c := client.New(doer)
+func SpanDoer(doer client.Doer, exporter SpanExporter) client.Doer {
+	return &spanDoer{Doer: doer, exporter: exporter}
+}
+
+// WithSpan returns a context containing the given span.
+func WithSpan(ctx context.Context, s *Span) context.Context {
+	return context.WithValue(ctx, currentSpanKey, s)
+}
+
+// ContextSpan returns the span stored in the given context if any, nil otherwise.
+func ContextSpan(ctx context.Context) *Span {
+	if s := ctx.Value(currentSpanKey); s != nil {
+		return s.(*Span)
+	}
+	return nil
+}
+
+// NewInMemoryExporter returns an exporter that keeps the spans in memory.
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+// ExportSpan records the span.
+func (e *InMemoryExporter) ExportSpan(s *Span) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.spans = append(e.spans, s)
+}
+
+// Spans returns the spans exported so far.
+func (e *InMemoryExporter) Spans() []*Span {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	spans := make([]*Span, len(e.spans))
+	copy(spans, e.spans)
+	return spans
+}
+
+// Reset removes all the spans.
+func (e *InMemoryExporter) Reset() {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.spans = nil
+}
+
+// SetAttribute sets the value of the span attribute with the given key.
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s.Attributes == nil {
+		s.Attributes = make(map[string]interface{})
+	}
+	s.Attributes[key] = value
+}
+
+// RecordError records the goa error code and ID of err if any and marks the span as failed.
+func (s *Span) RecordError(err error) {
+	if se, ok := cause(err).(goa.ServiceError); ok {
+		s.SetAttribute(AttributeErrorID, se.Token())
+		if er, ok := se.(*goa.ErrorResponse); ok {
+			s.SetAttribute(AttributeErrorCode, er.Code)
+		}
+		if se.ResponseStatus() < 500 && s.Kind == SpanKindServer {
+			// Client errors are not server failures.
+			return
+		}
+	}
+	s.Status = SpanStatusError
+	s.StatusMessage = err.Error()
+}
+
+// Do records a client span around the request.
+func (d *spanDoer) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	parent := ContextTrace(ctx)
+	if parent == nil || parent.Sampling == SamplingRejected {
+		return d.Doer.Do(ctx, req)
+	}
+	t := &Trace{
+		TraceID:      parent.TraceID,
+		SpanID:       HexSpanID(),
+		ParentSpanID: parent.SpanID,
+		Sampling:     parent.Sampling,
+		State:        parent.State,
+	}
+	s := &Span{
+		TraceID:      t.TraceID,
+		SpanID:       t.SpanID,
+		ParentSpanID: t.ParentSpanID,
+		Name:         req.Method,
+		Kind:         SpanKindClient,
+		Start:        time.Now(),
+		Attributes: map[string]interface{}{
+			AttributeHTTPMethod:    req.Method,
+			AttributeURLPath:       req.URL.Path,
+			AttributeServerAddress: req.URL.Host,
+		},
+	}
+	resp, err := d.Doer.Do(WithSpan(WithTraceContext(ctx, t), s), req)
+	s.End = time.Now()
+	if err != nil {
+		s.RecordError(err)
+	} else {
+		s.SetAttribute(AttributeHTTPStatusCode, resp.StatusCode)
+		if resp.StatusCode >= 400 {
+			s.Status = SpanStatusError
+			s.StatusMessage = http.StatusText(resp.StatusCode)
+		}
+	}
+	d.exporter.ExportSpan(s)
+	return resp, err
+}
+
+// newServerSpan creates the span recording the handling of a request by the service.
+func newServerSpan(ctx context.Context, t *Trace, req *http.Request) *Span {
+	name := req.Method
+	route := goa.ContextRoute(ctx)
+	if route != "" {
+		name += " " + route
+	}
+	s := &Span{
+		TraceID:      t.TraceID,
+		SpanID:       t.SpanID,
+		ParentSpanID: t.ParentSpanID,
+		Name:         name,
+		Kind:         SpanKindServer,
+		Start:        time.Now(),
+		Attributes: map[string]interface{}{
+			AttributeHTTPMethod: req.Method,
+			AttributeURLPath:    req.URL.Path,
+		},
+	}
+	if route != "" {
+		s.SetAttribute(AttributeHTTPRoute, route)
+	}
+	return s
+}
+
+// endServerSpan records the request outcome in the span.
+func endServerSpan(ctx context.Context, s *Span, err error) {
+	s.End = time.Now()
+	if ctrl := goa.ContextController(ctx); ctrl != "<unknown>" {
+		s.SetAttribute(AttributeController, ctrl)
+	}
+	if action := goa.ContextAction(ctx); action != "<unknown>" {
+		s.SetAttribute(AttributeAction, action)
+	}
+	status := 0
+	if resp := goa.ContextResponse(ctx); resp != nil {
+		status = resp.Status
+	}
+	if err != nil {
+		s.RecordError(err)
+		if status == 0 {
+			status = http.StatusInternalServerError
+			if se, ok := cause(err).(goa.ServiceError); ok {
+				status = se.ResponseStatus()
+			}
+		}
+	}
+	if status != 0 {
+		s.SetAttribute(AttributeHTTPStatusCode, status)
+	}
+	if status >= 500 && s.Status != SpanStatusError {
+		s.Status = SpanStatusError
+		s.StatusMessage = http.StatusText(status)
+	}
+}
@@ -0,0 +1,205 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/goadesign/goa"
+)
+
+func TestTracerExportSpans(t *testing.T) {
+	cases := map[string]struct {
+		Path        string
+		Traceparent string
+		// output
+		Exported        bool
+		Name, Action    string
+		Status          int
+		ErrorCode       string
+		SpanStatus      SpanStatusCode
+		TraceID, Parent string
+		ValidIDs        bool
+	}{
+		"ok":       {"/bottles/1", "", true, "GET /bottles/:id", "show", 200, "", SpanStatusUnset, "", "", true},
+		"parent":   {"/bottles/1", "00-" + testTraceID + "-" + testSpanID + "-01", true, "GET /bottles/:id", "show", 200, "", SpanStatusUnset, testTraceID, testSpanID, true},
+		"rejected": {"/bottles/1", "00-" + testTraceID + "-" + testSpanID + "-00", false, "", "", 0, "", SpanStatusUnset, "", "", false},
+		"bad":      {"/bottles/bad", "", true, "GET /bottles/:id", "show", 404, "not_found", SpanStatusUnset, "", "", true},
+		"error":    {"/boom", "", true, "POST /boom", "boom", 500, "", SpanStatusError, "", "", true},
+	}
+
+	for k, c := range cases {
+		exporter := NewInMemoryExporter()
+		service := goa.New("test")
+		service.WithLogger(goa.NewLogger(log.New(ioutil.Discard, "", 0)))
+		service.Encoder.Register(goa.NewJSONEncoder, "*/*")
+		service.Use(NewTracer(Propagators(NewW3CPropagator()), ExportSpans(exporter)))
+		service.Use(ErrorHandler(service, false))
+		ctrl := service.NewController("bottle")
+		service.Mux.Handle("GET", "/bottles/:id", ctrl.MuxHandler("show", func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			if goa.ContextRequest(ctx).Params.Get("id") == "bad" {
+				return goa.ErrNotFound("no bottle")
+			}
+			if ContextSpan(ctx) == nil {
+				return errors.New("missing span")
+			}
+			rw.WriteHeader(200)
+			return nil
+		}, nil))
+		service.Mux.Handle("POST", "/boom", ctrl.MuxHandler("boom", func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			return errors.New("boom")
+		}, nil))
+		method := "GET"
+		if c.Path == "/boom" {
+			method = "POST"
+		}
+		req := httptest.NewRequest(method, c.Path, nil)
+		if c.Traceparent != "" {
+			req.Header.Set(TraceparentHeader, c.Traceparent)
+		}
+
+		service.Mux.ServeHTTP(httptest.NewRecorder(), req)
+
+		spans := exporter.Spans()
+		if !c.Exported {
+			if len(spans) != 0 {
+				t.Errorf("%s: expected no span, got %d", k, len(spans))
+			}
+			continue
+		}
+		if len(spans) != 1 {
+			t.Errorf("%s: expected 1 span, got %d", k, len(spans))
+			continue
+		}
+		s := spans[0]
+		if s.Name != c.Name {
+			t.Errorf("%s: invalid name, expected %v - got %v", k, c.Name, s.Name)
+		}
+		if s.Kind != SpanKindServer {
+			t.Errorf("%s: invalid kind %v", k, s.Kind)
+		}
+		if s.Attributes[AttributeController] != "bottle" {
+			t.Errorf("%s: invalid controller %v", k, s.Attributes[AttributeController])
+		}
+		if s.Attributes[AttributeAction] != c.Action {
+			t.Errorf("%s: invalid action, expected %v - got %v", k, c.Action, s.Attributes[AttributeAction])
+		}
+		if s.Attributes[AttributeHTTPStatusCode] != c.Status {
+			t.Errorf("%s: invalid status, expected %v - got %v", k, c.Status, s.Attributes[AttributeHTTPStatusCode])
+		}
+		if code, _ := s.Attributes[AttributeErrorCode].(string); code != c.ErrorCode {
+			t.Errorf("%s: invalid error code, expected %v - got %v", k, c.ErrorCode, code)
+		}
+		if s.Status != c.SpanStatus {
+			t.Errorf("%s: invalid span status, expected %v - got %v", k, c.SpanStatus, s.Status)
+		}
+		if c.TraceID != "" && s.TraceID != c.TraceID {
+			t.Errorf("%s: invalid trace ID, expected %v - got %v", k, c.TraceID, s.TraceID)
+		}
+		if s.ParentSpanID != c.Parent {
+			t.Errorf("%s: invalid parent span ID, expected %v - got %v", k, c.Parent, s.ParentSpanID)
+		}
+		if c.ValidIDs && (!isID(s.TraceID, 32) || !isID(s.SpanID, 16)) {
+			t.Errorf("%s: invalid IDs %q %q", k, s.TraceID, s.SpanID)
+		}
+		if s.Start.IsZero() || s.End.Before(s.Start) {
+			t.Errorf("%s: invalid timing %v - %v", k, s.Start, s.End)
+		}
+	}
+}
+
+type statusDoer struct {
+	status int
+	err    error
+	header http.Header
+	span   *Span
+}
+
+func (d *statusDoer) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	d.header = req.Header
+	d.span = ContextSpan(ctx)
+	if d.err != nil {
+		return nil, d.err
+	}
+	return &http.Response{StatusCode: d.status}, nil
+}
+
+func TestSpanDoer(t *testing.T) {
+	sampled := &Trace{TraceID: testTraceID, SpanID: testSpanID, Sampling: SamplingAccepted}
+	rejected := &Trace{TraceID: testTraceID, SpanID: testSpanID, Sampling: SamplingRejected}
+
+	cases := map[string]struct {
+		Trace  *Trace
+		Status int
+		Err    error
+		// output
+		Exported   bool
+		SpanStatus SpanStatusCode
+	}{
+		"no-trace": {nil, 200, nil, false, SpanStatusUnset},
+		"rejected": {rejected, 200, nil, false, SpanStatusUnset},
+		"ok":       {sampled, 200, nil, true, SpanStatusUnset},
+		"failed":   {sampled, 503, nil, true, SpanStatusError},
+		"error":    {sampled, 0, errors.New("refused"), true, SpanStatusError},
+	}
+
+	for k, c := range cases {
+		var (
+			exporter = NewInMemoryExporter()
+			doer     = &statusDoer{status: c.Status, err: c.Err}
+			ctx      = context.Background()
+		)
+		if c.Trace != nil {
+			ctx = WithTraceContext(ctx, c.Trace)
+		}
+		req, _ := http.NewRequest("GET", "http://bottles.example/bottles/1?key=secret", nil)
+
+		SpanDoer(TraceDoer(doer, NewW3CPropagator()), exporter).Do(ctx, req)
+
+		spans := exporter.Spans()
+		if !c.Exported {
+			if len(spans) != 0 {
+				t.Errorf("%s: expected no span, got %d", k, len(spans))
+			}
+			continue
+		}
+		if len(spans) != 1 {
+			t.Errorf("%s: expected 1 span, got %d", k, len(spans))
+			continue
+		}
+		s := spans[0]
+		if s.Kind != SpanKindClient || s.TraceID != testTraceID || s.ParentSpanID != testSpanID {
+			t.Errorf("%s: invalid span %+v", k, s)
+		}
+		if s.Status != c.SpanStatus {
+			t.Errorf("%s: invalid span status, expected %v - got %v", k, c.SpanStatus, s.Status)
+		}
+		if s.Attributes[AttributeURLPath] != "/bottles/1" || s.Attributes[AttributeServerAddress] != "bottles.example" {
+			t.Errorf("%s: invalid attributes %v", k, s.Attributes)
+		}
+		if doer.span != s {
+			t.Errorf("%s: span not set in the doer context", k)
+		}
+		expected := "00-" + testTraceID + "-" + s.SpanID + "-01"
+		if doer.header.Get(TraceparentHeader) != expected {
+			t.Errorf("%s: invalid traceparent, expected %v - got %v", k, expected, doer.header.Get(TraceparentHeader))
+		}
+	}
+}
+
+func TestInMemoryExporter(t *testing.T) {
+	e := NewInMemoryExporter()
+	e.ExportSpan(&Span{Name: "a"})
+	e.ExportSpan(&Span{Name: "b"})
+	if spans := e.Spans(); len(spans) != 2 || spans[0].Name != "a" || spans[1].Name != "b" {
+		t.Errorf("invalid spans %v", spans)
+	}
+	e.Reset()
+	if spans := e.Spans(); len(spans) != 0 {
+		t.Errorf("expected no span after reset, got %v", spans)
+	}
+}
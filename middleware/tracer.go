@@ -34,12 +34,15 @@ type (
 		samplingPercent int
 		maxSamplingRate int
 		sampleSize      int
+		propagators     []Propagator
+		exporter        SpanExporter
 	}
 
 	// tracedDoer is a goa client Doer that inserts the tracing headers for
 	// each request it makes.
 	tracedDoer struct {
 		client.Doer
+		propagator Propagator
 	}
 )
 
@@ -106,13 +109,28 @@ func SampleSize(s int) TracerOption {
 	}
 }
 
+// Propagators sets the propagators used to extract the trace information from
+// the incoming requests, the first propagator that finds a trace wins. The
+// default propagator uses the TraceIDHeader and ParentSpanIDHeader headers, see
+// NewGoaPropagator. Setting propagators also changes the default trace and span
+// ID functions to HexTraceID and HexSpanID.
+func Propagators(props ...Propagator) TracerOption {
+	return func(o *tracerOptions) *tracerOptions {
+		o.propagators = append(o.propagators, props...)
+		return o
+	}
+}
+
 // NewTracer returns a trace middleware that initializes the trace information
 // in the request context. The information can be retrieved using any of the
 // ContextXXX functions.
 //
 // samplingPercent must be a value between 0 and 100. It represents the percentage
-// of requests that should be traced. If the incoming request has a Trace ID
-// header then the sampling rate is disregarded and the tracing is enabled.
+// of requests that should be traced. If the incoming request carries a trace
+// with a sampling decision (see Propagators) then the sampling rate is
+// disregarded and the decision of the caller is honored. Traces that are not
+// sampled are still stored in the context (see ContextTrace) so that the
+// decision is propagated downstream but ContextTraceID returns the empty string.
 //
 // spanIDFunc and traceIDFunc are the functions used to create Span and Trace
 // IDs respectively. This is configurable so that the created IDs are compatible
@@ -120,14 +138,26 @@ func SampleSize(s int) TracerOption {
 // implementations that produce AWS X-Ray compatible IDs.
 func NewTracer(opts ...TracerOption) goa.Middleware {
 	o := &tracerOptions{
-		traceIDFunc:     shortID,
-		spanIDFunc:      shortID,
 		samplingPercent: 100,
 		sampleSize:      1000, // only applies if maxSamplingRate is set
 	}
 	for _, opt := range opts {
 		o = opt(o)
 	}
+	newTraceID, newSpanID := IDFunc(shortID), IDFunc(shortID)
+	if len(o.propagators) > 0 || o.exporter != nil {
+		newTraceID, newSpanID = HexTraceID, HexSpanID
+	}
+	if o.traceIDFunc == nil {
+		o.traceIDFunc = newTraceID
+	}
+	if o.spanIDFunc == nil {
+		o.spanIDFunc = newSpanID
+	}
+	propagator := NewGoaPropagator()
+	if len(o.propagators) > 0 {
+		propagator = NewCompositePropagator(o.propagators...)
+	}
 	var sampler Sampler
 	if o.maxSamplingRate > 0 {
 		sampler = NewAdaptiveSampler(o.maxSamplingRate, o.sampleSize)
@@ -136,22 +166,42 @@ func NewTracer(opts ...TracerOption) goa.Middleware {
 	}
 	return func(h goa.Handler) goa.Handler {
 		return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
-			// insert a new trace ID only if not already being traced.
-			traceID := req.Header.Get(TraceIDHeader)
-			if traceID == "" {
+			upstream := propagator.Extract(req.Header)
+			if upstream == nil {
 				// insert tracing only within sample.
-				if sampler.Sample() {
-					traceID = o.traceIDFunc()
-				} else {
+				if !sampler.Sample() {
 					return h(ctx, rw, req)
 				}
+				upstream = &Trace{Sampling: SamplingAccepted}
+			}
+			t := &Trace{
+				TraceID:      upstream.TraceID,
+				SpanID:       o.spanIDFunc(),
+				ParentSpanID: upstream.SpanID,
+				Sampling:     upstream.Sampling,
+				State:        upstream.State,
+			}
+			if t.Sampling == SamplingDeferred {
+				t.Sampling = SamplingRejected
+				if sampler.Sample() {
+					t.Sampling = SamplingAccepted
+				}
+			}
+			// insert a new trace ID only if not already being traced.
+			if t.TraceID == "" {
+				t.TraceID = o.traceIDFunc()
+			}
+			ctx = WithTraceContext(ctx, t)
+			if o.exporter == nil || t.Sampling == SamplingRejected {
+				return h(ctx, rw, req)
 			}
 
-			// insert IDs into context to enable tracing.
-			spanID := o.spanIDFunc()
-			parentID := req.Header.Get(ParentSpanIDHeader)
-			ctx = WithTrace(ctx, traceID, spanID, parentID)
-			return h(ctx, rw, req)
+			// record the span of sampled requests.
+			s := newServerSpan(ctx, t, req)
+			err := h(WithSpan(ctx, s), rw, req)
+			endServerSpan(ctx, s, err)
+			o.exporter.ExportSpan(s)
+			return err
 		}
 	}
 }
@@ -163,8 +213,22 @@ func Tracer(sampleRate int, spanIDFunc, traceIDFunc IDFunc) goa.Middleware {
 
 // TraceDoer wraps a goa client Doer and sets the trace headers so that the
 // downstream service may properly retrieve the parent span ID and trace ID.
-func TraceDoer(doer client.Doer) client.Doer {
-	return &tracedDoer{doer}
+// The headers are set using the given propagators, NewGoaPropagator if none.
+func TraceDoer(doer client.Doer, props ...Propagator) client.Doer {
+	propagator := NewGoaPropagator()
+	if len(props) > 0 {
+		propagator = NewCompositePropagator(props...)
+	}
+	return &tracedDoer{Doer: doer, propagator: propagator}
+}
+
+// ContextTrace returns the trace stored in the given context if any, nil
+// otherwise. The trace span ID is the ID of the current span.
+func ContextTrace(ctx context.Context) *Trace {
+	if t := ctx.Value(traceContextKey); t != nil {
+		return t.(*Trace)
+	}
+	return nil
 }
 
 // ContextTraceID returns the trace ID extracted from the given context if any,
@@ -195,25 +259,36 @@ func ContextParentSpanID(ctx context.Context) string {
 }
 
 // WithTrace returns a context containing the given trace, span and parent span
-// IDs.
+// IDs. The trace is sampled and keeps the W3C trace state of the trace already
+// stored in the context if they share the same ID.
 func WithTrace(ctx context.Context, traceID, spanID, parentID string) context.Context {
+	t := &Trace{TraceID: traceID, SpanID: spanID, ParentSpanID: parentID, Sampling: SamplingAccepted}
+	if prev := ContextTrace(ctx); prev != nil && prev.TraceID == traceID {
+		t.State = prev.State
+	}
+	return WithTraceContext(ctx, t)
+}
+
+// WithTraceContext returns a context containing the given trace. The trace,
+// span and parent span IDs are only made available to ContextTraceID,
+// ContextSpanID and ContextParentSpanID if the trace is sampled.
+func WithTraceContext(ctx context.Context, t *Trace) context.Context {
+	traceID, spanID, parentID := t.TraceID, t.SpanID, t.ParentSpanID
+	if t.Sampling == SamplingRejected {
+		traceID, spanID, parentID = "", "", ""
+	}
 	if parentID != "" {
 		ctx = context.WithValue(ctx, parentSpanKey, parentID)
 	}
 	ctx = context.WithValue(ctx, traceKey, traceID)
 	ctx = context.WithValue(ctx, spanKey, spanID)
-	return ctx
+	return context.WithValue(ctx, traceContextKey, t)
 }
 
 // Do adds the tracing headers to the requests before making it.
 func (d *tracedDoer) Do(ctx context.Context, req *http.Request) (*http.Response, error) {
-	var (
-		traceID = ContextTraceID(ctx)
-		spanID  = ContextSpanID(ctx)
-	)
-	if traceID != "" {
-		req.Header.Set(TraceIDHeader, traceID)
-		req.Header.Set(ParentSpanIDHeader, spanID)
+	if t := ContextTrace(ctx); t != nil {
+		d.propagator.Inject(t, req.Header)
 	}
 
 	return d.Doer.Do(ctx, req)
@@ -1,6 +1,7 @@
 package goa
 
 import (
+	"context"
 	"net/http"
 	"net/url"
 
@@ -14,13 +15,16 @@ type (
 
 	// MethodNotAllowedHandler provides the implementation for an MethodNotAllowed
 	// handler. The values argument includes both the querystring and path parameter
-	// values. The methods argument includes both the allowed method identifier
-	// and the registered handler.
-	MethodNotAllowedHandler func(http.ResponseWriter, *http.Request, url.Values, map[string]httptreemux.HandlerFunc)
+	// values. The methods argument maps the HTTP methods allowed for the request path
+	// to the registered handlers.
+	MethodNotAllowedHandler func(http.ResponseWriter, *http.Request, url.Values, map[string]MuxHandler)
 
 	// ServeMux is the interface implemented by the service request muxes.
 	// It implements http.Handler and makes it possible to register request handlers for
 	// specific HTTP methods and request path via the Handle method.
+	// Paths use the goa wildcard syntax: ":name" matches a single path segment and "*name"
+	// matches the remainder of the path. The package github.com/goadesign/goa/mux contains
+	// adapters that make it possible to use other routers than the default httptreemux.
 	ServeMux interface {
 		http.Handler
 		// Handle sets the MuxHandler for a given HTTP method and path.
@@ -46,27 +50,54 @@ type (
 	mux struct {
 		router  *httptreemux.TreeMux
 		handles map[string]MuxHandler
+		// routes maps the registered paths to the handlers indexed by HTTP method.
+		routes map[string]map[string]MuxHandler
 	}
 )
 
+// lookupMethod is the pseudo HTTP method used to register a lookup handler for each path with
+// httptreemux. httptreemux does not expose the path matched by a request, the method not allowed
+// handler calls the lookup handler to retrieve the handlers registered for the path instead. The
+// method contains a space so that it never matches the method of an actual request.
+const lookupMethod = "goa lookup"
+
 // NewMux returns a Mux.
 func NewMux() ServeMux {
 	r := httptreemux.New()
 	r.EscapeAddedRoutes = true
-	return &mux{
+	m := &mux{
 		router:  r,
 		handles: make(map[string]MuxHandler),
+		routes:  make(map[string]map[string]MuxHandler),
 	}
+	m.HandleMethodNotAllowed(func(rw http.ResponseWriter, req *http.Request, _ url.Values, methods map[string]MuxHandler) {
+		for method := range methods {
+			rw.Header().Add("Allow", method)
+		}
+		rw.WriteHeader(http.StatusMethodNotAllowed)
+	})
+	return m
 }
 
 // Handle sets the handler for the given verb and path.
 func (m *mux) Handle(method, path string, handle MuxHandler) {
+	handles, ok := m.routes[path]
+	if !ok {
+		handles = make(map[string]MuxHandler)
+		m.routes[path] = handles
+		m.router.Handle(lookupMethod, path, func(_ http.ResponseWriter, req *http.Request, _ map[string]string) {
+			if route, ok := req.Context().Value(muxHandlesKey).(*map[string]MuxHandler); ok {
+				*route = handles
+			}
+		})
+	}
+	handles[method] = handle
 	hthandle := func(rw http.ResponseWriter, req *http.Request, htparams map[string]string) {
 		params := req.URL.Query()
 		for n, p := range htparams {
 			params.Set(n, p)
 		}
-		handle(rw, req, params)
+		handle(rw, req.WithContext(WithRoute(req.Context(), path)), params)
 	}
 	m.handles[method+path] = handle
 	m.router.Handle(method, path, hthandle)
@@ -85,7 +116,23 @@ func (m *mux) HandleNotFound(handle MuxHandler) {
 // the path of a handler but not its HTTP method.
 func (m *mux) HandleMethodNotAllowed(handle MethodNotAllowedHandler) {
 	mna := func(rw http.ResponseWriter, req *http.Request, methods map[string]httptreemux.HandlerFunc) {
-		handle(rw, req, nil, methods)
+		var route map[string]MuxHandler
+		if lookup, ok := methods[lookupMethod]; ok {
+			lookup(rw, req.WithContext(context.WithValue(req.Context(), muxHandlesKey, &route)), nil)
+		}
+		allowed := make(map[string]MuxHandler, len(methods))
+		for method := range methods {
+			if method == lookupMethod {
+				continue
+			}
+			h, ok := route[method]
+			if !ok && method == "HEAD" {
+				// httptreemux serves HEAD requests with the GET handler.
+				h = route["GET"]
+			}
+			allowed[method] = h
+		}
+		handle(rw, req, nil, allowed)
 	}
 	m.router.MethodNotAllowedHandler = mna
 }
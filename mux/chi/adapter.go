@@ -0,0 +1,71 @@
+/*
+Package goachi contains an adapter that makes it possible to configure goa so it uses the chi
+router as service mux.
+Usage:
+
+    // Initialize the chi router, other handlers may be mounted on it as well
+    r := chi.NewRouter()
+    // Initialize the goa service mux using the adapter
+    service.Mux = goachi.New(r)
+    // ... Proceed with mounting the controllers and starting the service
+*/
+package goachi
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/go-chi/chi"
+	"github.com/goadesign/goa"
+	"github.com/goadesign/goa/mux"
+)
+
+// router adapts a chi router to the mux.Router interface.
+type router struct {
+	chi.Router
+	// catchAll records the names of the catch-all wildcards.
+	catchAll map[string]bool
+}
+
+// New returns a goa.ServeMux that uses the given chi router to match request paths.
+func New(r chi.Router) goa.ServeMux {
+	return mux.Adapt(&router{Router: r, catchAll: make(map[string]bool)})
+}
+
+// Handle registers the handler using the chi pattern equivalent to the goa path. chi does not
+// name catch-all wildcards so a path may contain at most one.
+func (r *router) Handle(path string, handler http.Handler) {
+	r.Router.Handle(mux.Rewrite(path, func(name string, catchAll bool) string {
+		if catchAll {
+			r.catchAll[name] = true
+			return "*"
+		}
+		return "{" + name + "}"
+	}), handler)
+}
+
+// HandleNotFound registers the handler invoked when no pattern matches the request path.
+func (r *router) HandleNotFound(handler http.Handler) {
+	r.Router.NotFound(handler.ServeHTTP)
+}
+
+// Params returns the wildcard values of the request. chi matches the raw path of requests whose
+// path contains escaped characters so the values are unescaped in this case.
+func (r *router) Params(req *http.Request, names []string) map[string]string {
+	params := make(map[string]string, len(names))
+	ctx := chi.RouteContext(req.Context())
+	for _, n := range names {
+		key := n
+		if r.catchAll[n] {
+			key = "*"
+		}
+		v := ctx.URLParam(key)
+		if req.URL.RawPath != "" {
+			if u, err := url.PathUnescape(v); err == nil {
+				v = u
+			}
+		}
+		params[n] = v
+	}
+	return params
+}
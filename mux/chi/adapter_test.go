@@ -0,0 +1,12 @@
+package goachi_test
+
+import (
+	"github.com/go-chi/chi"
+	"github.com/goadesign/goa"
+	"github.com/goadesign/goa/mux/chi"
+	"github.com/goadesign/goa/mux/muxtest"
+)
+
+var _ = muxtest.Conformance("chi", func() goa.ServeMux {
+	return goachi.New(chi.NewRouter())
+})
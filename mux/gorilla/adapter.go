@@ -0,0 +1,63 @@
+/*
+Package goagorilla contains an adapter that makes it possible to configure goa so it uses the
+gorilla router as service mux.
+Usage:
+
+    // Initialize the gorilla router, other handlers may be mounted on it as well
+    r := mux.NewRouter()
+    // Initialize the goa service mux using the adapter
+    service.Mux = goagorilla.New(r)
+    // ... Proceed with mounting the controllers and starting the service
+*/
+package goagorilla
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/goadesign/goa"
+	goamux "github.com/goadesign/goa/mux"
+	"github.com/gorilla/mux"
+)
+
+// router adapts a gorilla router to the mux.Router interface.
+type router struct {
+	*mux.Router
+}
+
+// New returns a goa.ServeMux that uses the given gorilla router to match request paths. New
+// configures the router to match the escaped request paths so that wildcard values may contain
+// escaped slashes.
+func New(r *mux.Router) goa.ServeMux {
+	r.UseEncodedPath()
+	return goamux.Adapt(&router{Router: r})
+}
+
+// Handle registers the handler using the gorilla template equivalent to the goa path.
+func (r *router) Handle(path string, handler http.Handler) {
+	r.Router.Handle(goamux.Rewrite(path, func(name string, catchAll bool) string {
+		if catchAll {
+			return "{" + name + ":.*}"
+		}
+		return "{" + name + "}"
+	}), handler)
+}
+
+// HandleNotFound registers the handler invoked when no template matches the request path.
+func (r *router) HandleNotFound(handler http.Handler) {
+	r.Router.NotFoundHandler = handler
+}
+
+// Params returns the unescaped wildcard values of the request.
+func (r *router) Params(req *http.Request, names []string) map[string]string {
+	vars := mux.Vars(req)
+	params := make(map[string]string, len(names))
+	for _, n := range names {
+		v := vars[n]
+		if u, err := url.PathUnescape(v); err == nil {
+			v = u
+		}
+		params[n] = v
+	}
+	return params
+}
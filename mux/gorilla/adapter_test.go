@@ -0,0 +1,12 @@
+package goagorilla_test
+
+import (
+	"github.com/goadesign/goa"
+	"github.com/goadesign/goa/mux/gorilla"
+	"github.com/goadesign/goa/mux/muxtest"
+	"github.com/gorilla/mux"
+)
+
+var _ = muxtest.Conformance("gorilla", func() goa.ServeMux {
+	return goagorilla.New(mux.NewRouter())
+})
@@ -0,0 +1,13 @@
+package goagorilla_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestGorilla(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Goagorilla Suite")
+}
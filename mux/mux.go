@@ -0,0 +1,152 @@
+/*
+Package mux contains the building blocks for using other HTTP routers than the default httptreemux
+as goa service muxes.
+
+Adapt turns any Router into a goa.ServeMux: routers only need to match request paths, Adapt
+takes care of dispatching on the HTTP method and of invoking the not found and method not allowed
+handlers. The package also provides an adapter for the net/http ServeMux (Go 1.22 or later) while
+the chi and gorilla subpackages provide adapters for the corresponding routers.
+Usage:
+
+    service := goa.New("my api")
+    service.Mux = mux.NewServeMux()
+    // ... Proceed with mounting the controllers and starting the service
+
+The muxtest subpackage implements the conformance test suite all the adapters must pass.
+*/
+package mux
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/goadesign/goa"
+)
+
+type (
+	// Router is the interface implemented by the HTTP routers adapted with Adapt.
+	Router interface {
+		http.Handler
+		// Handle registers the handler invoked for all the requests whose path matches the
+		// given goa path regardless of their HTTP method. The path uses the goa wildcard
+		// syntax, see Rewrite.
+		Handle(path string, handler http.Handler)
+		// HandleNotFound registers the handler invoked for requests whose path does not
+		// match any of the registered paths.
+		HandleNotFound(handler http.Handler)
+		// Params returns the unescaped values of the wildcards with the given names for a
+		// request matched by one of the registered paths.
+		Params(req *http.Request, names []string) map[string]string
+	}
+
+	// serveMux is the goa.ServeMux implementation returned by Adapt.
+	serveMux struct {
+		router           Router
+		routes           map[string]*route
+		handles          map[string]goa.MuxHandler
+		notFound         goa.MuxHandler
+		methodNotAllowed goa.MethodNotAllowedHandler
+	}
+
+	// route holds the handlers registered for a given path.
+	route struct {
+		names   []string
+		handles map[string]goa.MuxHandler
+	}
+)
+
+// WildcardRegex matches the wildcards of goa paths.
+var WildcardRegex = regexp.MustCompile(`/(?::|\*)([a-zA-Z0-9_]+)`)
+
+// Adapt returns a goa.ServeMux that uses the given router to match request paths.
+func Adapt(r Router) goa.ServeMux {
+	m := &serveMux{
+		router:  r,
+		routes:  make(map[string]*route),
+		handles: make(map[string]goa.MuxHandler),
+	}
+	r.HandleNotFound(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if m.notFound == nil {
+			http.NotFound(rw, req)
+			return
+		}
+		m.notFound(rw, req, nil)
+	}))
+	return m
+}
+
+// Rewrite converts the wildcards of the given goa path using the given function. The function is
+// called with the wildcard name and whether it is a catch-all wildcard ("*name") or a segment
+// wildcard (":name"), the value it returns replaces the wildcard in the path.
+func Rewrite(path string, wildcard func(name string, catchAll bool) string) string {
+	return WildcardRegex.ReplaceAllStringFunc(path, func(w string) string {
+		return "/" + wildcard(w[2:], w[1] == '*')
+	})
+}
+
+// Handle sets the handler for the given verb and path.
+func (m *serveMux) Handle(method, path string, handle goa.MuxHandler) {
+	r, ok := m.routes[path]
+	if !ok {
+		r = &route{handles: make(map[string]goa.MuxHandler)}
+		for _, match := range WildcardRegex.FindAllStringSubmatch(path, -1) {
+			r.names = append(r.names, match[1])
+		}
+		m.routes[path] = r
+		m.router.Handle(path, http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			m.serve(r, path, rw, req)
+		}))
+	}
+	r.handles[method] = handle
+	m.handles[method+path] = handle
+}
+
+// HandleNotFound sets the MuxHandler invoked for requests that don't match any
+// handler registered with Handle.
+func (m *serveMux) HandleNotFound(handle goa.MuxHandler) {
+	m.notFound = handle
+}
+
+// HandleMethodNotAllowed sets the MuxHandler invoked for requests that match
+// the path of a handler but not its HTTP method.
+func (m *serveMux) HandleMethodNotAllowed(handle goa.MethodNotAllowedHandler) {
+	m.methodNotAllowed = handle
+}
+
+// Lookup returns the MuxHandler associated with the given method and path.
+func (m *serveMux) Lookup(method, path string) goa.MuxHandler {
+	return m.handles[method+path]
+}
+
+// ServeHTTP is the function called back by the underlying HTTP server to handle incoming requests.
+func (m *serveMux) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	m.router.ServeHTTP(rw, req)
+}
+
+// serve dispatches the request matched by the given route to the handler registered for its
+// method. The route path is available to the handler via goa.ContextRoute.
+func (m *serveMux) serve(r *route, path string, rw http.ResponseWriter, req *http.Request) {
+	if handle, ok := r.handles[req.Method]; ok {
+		params := req.URL.Query()
+		for n, v := range m.router.Params(req, r.names) {
+			params.Set(n, v)
+		}
+		handle(rw, req.WithContext(goa.WithRoute(req.Context(), path)), params)
+		return
+	}
+	methods := make(map[string]goa.MuxHandler, len(r.handles))
+	allowed := make([]string, 0, len(r.handles))
+	for method, handle := range r.handles {
+		methods[method] = handle
+		allowed = append(allowed, method)
+	}
+	sort.Strings(allowed)
+	if m.methodNotAllowed != nil {
+		m.methodNotAllowed(rw, req, nil, methods)
+		return
+	}
+	rw.Header().Set("Allow", strings.Join(allowed, ", "))
+	http.Error(rw, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+}
@@ -0,0 +1,41 @@
+package mux_test
+
+import (
+	"github.com/goadesign/goa/mux"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Rewrite", func() {
+	var path string
+	var rewritten string
+
+	JustBeforeEach(func() {
+		rewritten = mux.Rewrite(path, func(name string, catchAll bool) string {
+			if catchAll {
+				return "{" + name + "...}"
+			}
+			return "<" + name + ">"
+		})
+	})
+
+	Context("with a static path", func() {
+		BeforeEach(func() {
+			path = "/foo/bar"
+		})
+
+		It("leaves the path unchanged", func() {
+			Ω(rewritten).Should(Equal("/foo/bar"))
+		})
+	})
+
+	Context("with wildcards", func() {
+		BeforeEach(func() {
+			path = "/foo/:id/bar/:bar_id/*rest"
+		})
+
+		It("rewrites all the wildcards", func() {
+			Ω(rewritten).Should(Equal("/foo/<id>/bar/<bar_id>/{rest...}"))
+		})
+	})
+})
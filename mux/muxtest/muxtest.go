@@ -0,0 +1,234 @@
+/*
+Package muxtest implements the conformance test suite that goa.ServeMux implementations must pass.
+The suite is written with ginkgo, adapters register it from their own test suite:
+
+    var _ = muxtest.Conformance("chi", func() goa.ServeMux {
+        return goachi.New(chi.NewRouter())
+    })
+*/
+package muxtest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+
+	"github.com/goadesign/goa"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// Conformance registers the specs of the conformance suite for the muxes created with newMux.
+// Each spec uses a new mux.
+func Conformance(name string, newMux func() goa.ServeMux) bool {
+	return Describe(name+" conformance", func() {
+		var (
+			mux         goa.ServeMux
+			method      string
+			target      string
+			rw          *httptest.ResponseRecorder
+			handled     string
+			values      url.Values
+			route       string
+			notFound    bool
+			notAllowed  map[string]goa.MuxHandler
+			handlerFor  func(string) goa.MuxHandler
+			withHandler bool
+		)
+
+		BeforeEach(func() {
+			mux = newMux()
+			method = "GET"
+			handled = ""
+			values = nil
+			route = ""
+			notFound = false
+			notAllowed = nil
+			withHandler = true
+			handlerFor = func(id string) goa.MuxHandler {
+				return func(rw http.ResponseWriter, req *http.Request, v url.Values) {
+					handled = id
+					values = v
+					route = goa.ContextRoute(req.Context())
+					rw.WriteHeader(http.StatusOK)
+				}
+			}
+			mux.Handle("GET", "/", handlerFor("root"))
+			mux.Handle("GET", "/foo", handlerFor("list"))
+			mux.Handle("GET", "/foo/:id", handlerFor("show"))
+			mux.Handle("PUT", "/foo/:id", handlerFor("update"))
+			mux.Handle("GET", "/foo/:id/bar/:bar", handlerFor("nested"))
+			mux.Handle("GET", "/files/*path", handlerFor("files"))
+		})
+
+		JustBeforeEach(func() {
+			if withHandler {
+				mux.HandleNotFound(func(rw http.ResponseWriter, req *http.Request, v url.Values) {
+					notFound = true
+					rw.WriteHeader(http.StatusNotFound)
+				})
+				mux.HandleMethodNotAllowed(func(rw http.ResponseWriter, req *http.Request, v url.Values, methods map[string]goa.MuxHandler) {
+					notAllowed = methods
+					rw.WriteHeader(http.StatusMethodNotAllowed)
+				})
+			}
+			rw = httptest.NewRecorder()
+			mux.ServeHTTP(rw, httptest.NewRequest(method, target, nil))
+		})
+
+		Context("with a static path", func() {
+			BeforeEach(func() {
+				target = "/foo"
+			})
+
+			It("invokes the handler", func() {
+				Ω(rw.Code).Should(Equal(http.StatusOK))
+				Ω(handled).Should(Equal("list"))
+				Ω(values).Should(BeEmpty())
+			})
+		})
+
+		Context("with the root path", func() {
+			BeforeEach(func() {
+				target = "/"
+			})
+
+			It("invokes the handler", func() {
+				Ω(handled).Should(Equal("root"))
+			})
+		})
+
+		Context("with wildcards", func() {
+			BeforeEach(func() {
+				target = "/foo/42/bar/baz?q=1&q=2"
+			})
+
+			It("sets the path and querystring values", func() {
+				Ω(handled).Should(Equal("nested"))
+				Ω(values).Should(Equal(url.Values{"id": {"42"}, "bar": {"baz"}, "q": {"1", "2"}}))
+			})
+
+			It("sets the route path in the request context", func() {
+				Ω(route).Should(Equal("/foo/:id/bar/:bar"))
+			})
+		})
+
+		Context("with the same path and different methods", func() {
+			BeforeEach(func() {
+				method = "PUT"
+				target = "/foo/42"
+			})
+
+			It("invokes the handler registered for the method", func() {
+				Ω(handled).Should(Equal("update"))
+				Ω(values.Get("id")).Should(Equal("42"))
+			})
+		})
+
+		Context("with a catch-all wildcard", func() {
+			BeforeEach(func() {
+				target = "/files/a/b/c.txt"
+			})
+
+			It("sets the remainder of the path", func() {
+				Ω(handled).Should(Equal("files"))
+				Ω(values.Get("path")).Should(Equal("a/b/c.txt"))
+			})
+		})
+
+		Context("with escaped characters", func() {
+			BeforeEach(func() {
+				target = "/foo/a%20b"
+			})
+
+			It("unescapes the wildcard value", func() {
+				Ω(handled).Should(Equal("show"))
+				Ω(values.Get("id")).Should(Equal("a b"))
+			})
+		})
+
+		Context("with an escaped slash", func() {
+			BeforeEach(func() {
+				target = "/foo/a%2Fb"
+			})
+
+			It("matches a single segment", func() {
+				Ω(handled).Should(Equal("show"))
+				Ω(values.Get("id")).Should(Equal("a/b"))
+			})
+		})
+
+		Context("with an unknown path", func() {
+			BeforeEach(func() {
+				target = "/unknown"
+			})
+
+			It("invokes the not found handler", func() {
+				Ω(handled).Should(BeEmpty())
+				Ω(notFound).Should(BeTrue())
+				Ω(rw.Code).Should(Equal(http.StatusNotFound))
+			})
+
+			Context("and no not found handler", func() {
+				BeforeEach(func() {
+					withHandler = false
+				})
+
+				It("responds with 404", func() {
+					Ω(rw.Code).Should(Equal(http.StatusNotFound))
+				})
+			})
+		})
+
+		Context("with a method not allowed", func() {
+			BeforeEach(func() {
+				method = "DELETE"
+				target = "/foo/42"
+			})
+
+			It("invokes the method not allowed handler with the allowed methods", func() {
+				Ω(handled).Should(BeEmpty())
+				Ω(notFound).Should(BeFalse())
+				Ω(rw.Code).Should(Equal(http.StatusMethodNotAllowed))
+				Ω(notAllowed).Should(HaveKey("GET"))
+				Ω(notAllowed).Should(HaveKey("PUT"))
+				Ω(notAllowed["GET"]).ShouldNot(BeNil())
+				Ω(notAllowed["PUT"]).ShouldNot(BeNil())
+				Ω(notAllowed).ShouldNot(HaveKey("DELETE"))
+			})
+
+			It("gives the registered handlers to the method not allowed handler", func() {
+				notAllowed["PUT"](httptest.NewRecorder(), httptest.NewRequest("PUT", target, nil), nil)
+				Ω(handled).Should(Equal("update"))
+			})
+
+			Context("and no method not allowed handler", func() {
+				BeforeEach(func() {
+					withHandler = false
+				})
+
+				It("responds with 405", func() {
+					Ω(rw.Code).Should(Equal(http.StatusMethodNotAllowed))
+					allow := strings.Join(rw.Header()["Allow"], ", ")
+					Ω(allow).Should(ContainSubstring("GET"))
+					Ω(allow).Should(ContainSubstring("PUT"))
+					Ω(allow).ShouldNot(ContainSubstring("DELETE"))
+				})
+			})
+		})
+
+		Context("looking up handlers", func() {
+			BeforeEach(func() {
+				target = "/"
+			})
+
+			It("returns the registered handlers", func() {
+				Ω(mux.Lookup("GET", "/foo/:id")).ShouldNot(BeNil())
+				Ω(mux.Lookup("PUT", "/foo/:id")).ShouldNot(BeNil())
+				Ω(mux.Lookup("DELETE", "/foo/:id")).Should(BeNil())
+				Ω(mux.Lookup("GET", "/unknown")).Should(BeNil())
+			})
+		})
+	})
+}
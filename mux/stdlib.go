@@ -0,0 +1,81 @@
+//go:build go1.22
+// +build go1.22
+
+package mux
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/goadesign/goa"
+)
+
+// stdRouter adapts the net/http ServeMux to the Router interface.
+type stdRouter struct {
+	mux      *http.ServeMux
+	root     http.Handler
+	notFound http.Handler
+}
+
+// NewServeMux returns a goa.ServeMux that uses a net/http ServeMux to match request paths.
+// It relies on the wildcard patterns introduced in Go 1.22 which are disabled when the
+// GODEBUG setting httpmuxgo121 is set to 1 (the default for modules declaring an older Go
+// version).
+func NewServeMux() goa.ServeMux {
+	r := &stdRouter{mux: http.NewServeMux(), notFound: http.NotFoundHandler()}
+	r.mux.Handle("/", http.HandlerFunc(r.serveUnmatched))
+	return Adapt(r)
+}
+
+// Handle registers the handler using the net/http pattern equivalent to the goa path.
+func (r *stdRouter) Handle(path string, handler http.Handler) {
+	if m := WildcardRegex.FindStringSubmatch(path); m != nil && m[0] == path && path[1] == '*' {
+		// The "/{name...}" pattern would conflict with the "/" pattern, serve the path
+		// with the requests that do not match any other pattern instead.
+		r.root = http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			req.SetPathValue(m[1], strings.TrimPrefix(req.URL.Path, "/"))
+			handler.ServeHTTP(rw, req)
+		})
+		return
+	}
+	pattern := Rewrite(path, func(name string, catchAll bool) string {
+		if catchAll {
+			return "{" + name + "...}"
+		}
+		return "{" + name + "}"
+	})
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "{$}"
+	}
+	r.mux.Handle(pattern, handler)
+}
+
+// HandleNotFound registers the handler invoked when no pattern matches the request path.
+func (r *stdRouter) HandleNotFound(handler http.Handler) {
+	r.notFound = handler
+}
+
+// Params returns the wildcard values of the request.
+func (r *stdRouter) Params(req *http.Request, names []string) map[string]string {
+	params := make(map[string]string, len(names))
+	for _, n := range names {
+		params[n] = req.PathValue(n)
+	}
+	return params
+}
+
+// ServeHTTP serves the request with the handler of the matching pattern.
+func (r *stdRouter) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	r.mux.ServeHTTP(rw, req)
+}
+
+// serveUnmatched is registered with the "/" pattern and serves the requests that do not match
+// any other pattern with the handler of the "/*name" path if any, the not found handler
+// otherwise.
+func (r *stdRouter) serveUnmatched(rw http.ResponseWriter, req *http.Request) {
+	if r.root != nil {
+		r.root.ServeHTTP(rw, req)
+		return
+	}
+	r.notFound.ServeHTTP(rw, req)
+}
@@ -0,0 +1,42 @@
+//go:build go1.22
+// +build go1.22
+
+package mux_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+
+	"github.com/goadesign/goa/mux"
+	"github.com/goadesign/goa/mux/muxtest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = muxtest.Conformance("net/http", mux.NewServeMux)
+
+var _ = Describe("NewServeMux", func() {
+	Context("with a catch-all path at the root", func() {
+		var values url.Values
+		var rw *httptest.ResponseRecorder
+
+		BeforeEach(func() {
+			values = nil
+			m := mux.NewServeMux()
+			m.Handle("GET", "/", func(rw http.ResponseWriter, req *http.Request, v url.Values) {
+				rw.WriteHeader(http.StatusNoContent)
+			})
+			m.Handle("GET", "/*path", func(rw http.ResponseWriter, req *http.Request, v url.Values) {
+				values = v
+			})
+			rw = httptest.NewRecorder()
+			m.ServeHTTP(rw, httptest.NewRequest("GET", "/foo/bar", nil))
+		})
+
+		It("serves the requests that match no other path", func() {
+			Ω(rw.Code).Should(Equal(http.StatusOK))
+			Ω(values.Get("path")).Should(Equal("foo/bar"))
+		})
+	})
+})
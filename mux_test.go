@@ -7,10 +7,13 @@ import (
 	"net/url"
 
 	"github.com/goadesign/goa"
+	"github.com/goadesign/goa/mux/muxtest"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
 
+var _ = muxtest.Conformance("httptreemux", goa.NewMux)
+
 var _ = Describe("Mux", func() {
 	var mux goa.ServeMux
 
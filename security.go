@@ -12,6 +12,9 @@ const LocHeader Location = "header"
 // LocQuery indicates the secret value should be loaded from the request URL querystring.
 const LocQuery Location = "query"
 
+// LocCookie indicates the secret value should be loaded from the request cookies.
+const LocCookie Location = "cookie"
+
 // ContextRequiredScopes extracts the security scopes from the given context.
 // This should be used in auth handlers to validate that the required scopes are present in the
 // JWT or OAuth2 token.
@@ -27,6 +30,48 @@ func WithRequiredScopes(ctx context.Context, scopes []string) context.Context {
 	return context.WithValue(ctx, securityScopesKey, scopes)
 }
 
+// MissingScopes returns the scopes listed in required that are not listed in granted, nil if
+// all the required scopes are granted. Auth middlewares use it to check the scopes returned by
+// ContextRequiredScopes.
+func MissingScopes(required, granted []string) []string {
+	if len(required) == 0 {
+		return nil
+	}
+	allowed := make(map[string]bool, len(granted))
+	for _, s := range granted {
+		allowed[s] = true
+	}
+	var missing []string
+	for _, s := range required {
+		if !allowed[s] {
+			missing = append(missing, s)
+		}
+	}
+	return missing
+}
+
+// Principal describes the client authenticated by an auth middleware.
+type Principal struct {
+	// ID identifies the client.
+	ID string
+	// Scopes lists the scopes the client is allowed.
+	Scopes []string
+	// Metadata contains arbitrary information about the client.
+	Metadata map[string]string
+}
+
+// WithPrincipal builds a context containing the given principal.
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey, p)
+}
+
+// ContextPrincipal extracts the principal authenticated by the auth middleware from the given
+// context, nil if none.
+func ContextPrincipal(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey).(*Principal)
+	return p
+}
+
 // OAuth2Security represents the `oauth2` security scheme. It is instantiated by the generated code
 // accordingly to the use of the different `*Security()` DSL functions and `Security()` in the
 // design.
@@ -66,12 +111,23 @@ type APIKeySecurity struct {
 type JWTSecurity struct {
 	// Description of the security scheme
 	Description string
-	// In represents where to check for the JWT, `query` or `header`
+	// In represents where to check for the JWT, `query`, `header` or `cookie`
 	In Location
-	// Name is the name of the `header` or `query` parameter to check for data.
+	// Name is the name of the `header`, `query` parameter or `cookie` to check for data.
 	Name string
 	// TokenURL defines the URL where you'd get the JWT tokens.
 	TokenURL string
 	// Scopes defines a list of scopes for the security scheme, along with their description.
 	Scopes map[string]string
 }
+
+// HTTPSignatureSecurity represents a scheme where the requests are signed with a secret shared
+// between the client and the service. The signature is sent in the "Signature" header and covers
+// the headers listed in Headers, the "(request-target)" pseudo-header stands for the request
+// method and path.
+type HTTPSignatureSecurity struct {
+	// Description of the security scheme
+	Description string
+	// Headers lists the lowercase names of the headers covered by the signature.
+	Headers []string
+}
@@ -0,0 +1,29 @@
+package goa_test
+
+import (
+	"context"
+
+	"github.com/goadesign/goa"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MissingScopes", func() {
+	It("returns the scopes that are not granted", func() {
+		Ω(goa.MissingScopes([]string{"read", "write", "admin"}, []string{"write", "read"})).Should(Equal([]string{"admin"}))
+	})
+
+	It("returns nil when all the scopes are granted", func() {
+		Ω(goa.MissingScopes([]string{"read"}, []string{"read", "write"})).Should(BeNil())
+		Ω(goa.MissingScopes(nil, nil)).Should(BeNil())
+	})
+})
+
+var _ = Describe("Principal", func() {
+	It("is stored in the context", func() {
+		p := &goa.Principal{ID: "svc", Scopes: []string{"read"}}
+		ctx := goa.WithPrincipal(context.Background(), p)
+		Ω(goa.ContextPrincipal(ctx)).Should(Equal(p))
+		Ω(goa.ContextPrincipal(context.Background())).Should(BeNil())
+	})
+})
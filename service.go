@@ -1,6 +1,7 @@
 package goa
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -13,8 +14,8 @@ import (
 	"sort"
 	"strings"
 	"sync"
-
-	"github.com/dimfeld/httptreemux"
+	"sync/atomic"
+	"time"
 )
 
 type (
@@ -39,13 +40,36 @@ type (
 		Decoder *HTTPDecoder
 		// Response body encoder
 		Encoder *HTTPEncoder
+		// ContentDecoders maps the content codings (e.g. "gzip") of request bodies to the
+		// functions that decode them. Request bodies are decoded before being unmarshaled
+		// so that MaxRequestBodyLength applies to the decoded size. Requests that use
+		// other codings are rejected with ErrUnsupportedMediaType. Content codings are
+		// not decoded if ContentDecoders is empty, see the gzip middleware package.
+		ContentDecoders map[string]ContentDecoder
+		// DrainDelay is the duration Shutdown waits for after reporting that the service is
+		// shutting down and before it stops accepting connections, so that load balancers
+		// polling the readiness endpoint stop routing requests to the service first. See
+		// ShuttingDown and the health package.
+		DrainDelay time.Duration
 
 		middleware []Middleware       // Middleware chain
 		cancel     context.CancelFunc // Service context cancel signal trigger
+
+		lock         sync.Mutex                    // Protects the shutdown state below
+		controllers  []*Controller                 // Controllers created with NewController
+		conns        map[net.Conn]struct{}         // Hijacked connections still open
+		hooks        []func(context.Context) error // Shutdown hooks
+		shuttingDown bool                          // Whether Shutdown was called
+		done         chan struct{}                 // Closed once Shutdown completes
+		shutdownErr  error                         // Error returned by Shutdown
 	}
 
 	// Controller defines the common fields and behavior of generated controllers.
 	Controller struct {
+		// inFlight is the number of requests being handled, accessed atomically. It is the
+		// first field so that it is 64-bit aligned on 32-bit platforms.
+		inFlight int64
+
 		// Controller resource name
 		Name string
 		// Service that exposes the controller
@@ -86,6 +110,10 @@ type (
 
 	// DecodeFunc is the function that initialize the unmarshaled payload from the request body.
 	DecodeFunc func(context.Context, io.ReadCloser, interface{}) error
+
+	// ContentDecoder is the function that decodes request bodies encoded with a given content
+	// coding. Closing the returned reader must close body.
+	ContentDecoder func(body io.ReadCloser) (io.ReadCloser, error)
 )
 
 // New instantiates a service with the given name.
@@ -106,6 +134,8 @@ func New(name string) *Service {
 			Encoder: NewHTTPEncoder(),
 
 			cancel: cancel,
+			conns:  make(map[net.Conn]struct{}),
+			done:   make(chan struct{}),
 		}
 		notFoundHandler         Handler
 		methodNotAllowedHandler Handler
@@ -136,7 +166,7 @@ func New(name string) *Service {
 	})
 
 	// Setup default MethodNotAllowed handler
-	mux.HandleMethodNotAllowed(func(rw http.ResponseWriter, req *http.Request, params url.Values, methods map[string]httptreemux.HandlerFunc) {
+	mux.HandleMethodNotAllowed(func(rw http.ResponseWriter, req *http.Request, params url.Values, methods map[string]MuxHandler) {
 		if resp := ContextResponse(ctx); resp != nil && resp.Written() {
 			return
 		}
@@ -201,25 +231,25 @@ func (service *Service) LogError(msg string, keyvals ...interface{}) {
 func (service *Service) ListenAndServe(addr string) error {
 	service.LogInfo("listen", "transport", "http", "addr", addr)
 	service.Server.Addr = addr
-	return service.Server.ListenAndServe()
+	return service.serveErr(service.Server.ListenAndServe())
 }
 
 // ListenAndServeTLS starts a HTTPS server and sets up a listener on the given host/port.
 func (service *Service) ListenAndServeTLS(addr, certFile, keyFile string) error {
 	service.LogInfo("listen", "transport", "https", "addr", addr)
 	service.Server.Addr = addr
-	return service.Server.ListenAndServeTLS(certFile, keyFile)
+	return service.serveErr(service.Server.ListenAndServeTLS(certFile, keyFile))
 }
 
 // Serve accepts incoming HTTP connections on the listener l, invoking the service mux handler for each.
 func (service *Service) Serve(l net.Listener) error {
-	return service.Server.Serve(l)
+	return service.serveErr(service.Server.Serve(l))
 }
 
 // NewController returns a controller for the given resource. This method is mainly intended for
 // use by the generated code. User code shouldn't have to call it directly.
 func (service *Service) NewController(name string) *Controller {
-	return &Controller{
+	ctrl := &Controller{
 		Name:                 name,
 		Service:              service,
 		Context:              context.WithValue(service.Context, ctrlKey, name),
@@ -228,6 +258,10 @@ func (service *Service) NewController(name string) *Controller {
 			return http.Dir(dir)
 		},
 	}
+	service.lock.Lock()
+	service.controllers = append(service.controllers, ctrl)
+	service.lock.Unlock()
+	return ctrl
 }
 
 // Send serializes the given body matching the request Accept header against the service
@@ -260,6 +294,53 @@ func (service *Service) DecodeRequest(req *http.Request, v interface{}) error {
 	return nil
 }
 
+// decodeContent replaces the body of requests that use content codings with a reader that
+// decodes it using the service content decoders. It returns the buffer that records the bytes
+// read from the original body if the body was replaced, nil otherwise.
+func (service *Service) decodeContent(rw http.ResponseWriter, req *http.Request) (*bytes.Buffer, error) {
+	var codings []string
+	for _, c := range strings.Split(req.Header.Get("Content-Encoding"), ",") {
+		if c = strings.ToLower(strings.TrimSpace(c)); c != "" && c != "identity" {
+			codings = append(codings, c)
+		}
+	}
+	if len(codings) == 0 {
+		return nil, nil
+	}
+	for _, c := range codings {
+		if _, ok := service.ContentDecoders[c]; !ok {
+			supported := make([]string, 0, len(service.ContentDecoders))
+			for s := range service.ContentDecoders {
+				supported = append(supported, s)
+			}
+			sort.Strings(supported)
+			rw.Header().Set("Accept-Encoding", strings.Join(supported, ", "))
+			return nil, ErrUnsupportedMediaType("unsupported content encoding", "encoding", c)
+		}
+	}
+	// Record the encoded body, the Digest header of signed requests covers it.
+	encoded := new(bytes.Buffer)
+	req.Body = &teeBody{Reader: io.TeeReader(req.Body, encoded), Closer: req.Body}
+	// Codings are listed in the order in which they were applied.
+	for i := len(codings) - 1; i >= 0; i-- {
+		body, err := service.ContentDecoders[codings[i]](req.Body)
+		if err != nil {
+			return nil, ErrInvalidEncoding(err, "encoding", codings[i])
+		}
+		req.Body = body
+	}
+	req.Header.Del("Content-Encoding")
+	req.Header.Del("Content-Length")
+	req.ContentLength = -1
+	return encoded, nil
+}
+
+// teeBody is the request body that records the bytes read by the content decoders.
+type teeBody struct {
+	io.Reader
+	io.Closer
+}
+
 // EncodeResponse uses the HTTP encoder to marshal and write the response body based on the request
 // Accept header.
 func (service *Service) EncodeResponse(ctx context.Context, v interface{}) error {
@@ -300,8 +381,14 @@ func (ctrl *Controller) MuxHandler(name string, hdlr Handler, unm Unmarshaler) M
 	// registered.
 	var handler Handler
 	var initHandler sync.Once
+	metrics := newActionMetrics(ctrl.Name, name)
 
 	return func(rw http.ResponseWriter, req *http.Request, params url.Values) {
+		// Track request so Shutdown can wait for it
+		atomic.AddInt64(&ctrl.inFlight, 1)
+		defer atomic.AddInt64(&ctrl.inFlight, -1)
+		rw = newTrackingWriter(rw, ctrl.Service)
+
 		// Build handler middleware chains on first invocation
 		initHandler.Do(func() {
 			handler = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
@@ -320,6 +407,26 @@ func (ctrl *Controller) MuxHandler(name string, hdlr Handler, unm Unmarshaler) M
 
 		// Build context
 		ctx := NewContext(WithAction(ctrl.Context, name), rw, req, params)
+		if route := ContextRoute(req.Context()); route != "" {
+			ctx = WithRoute(ctx, route)
+		}
+
+		// Record request metrics
+		done := metrics.start(req)
+		defer func() { done(ContextResponse(ctx)) }()
+
+		// Decode request body content coding if the action has a payload
+		decoded, load := false, unm != nil
+		if load && len(ctrl.Service.ContentDecoders) > 0 && req.ContentLength != 0 {
+			encoded, err := ctrl.Service.decodeContent(rw, req)
+			if err != nil {
+				ctx = WithError(ctx, err)
+				load = false
+			} else if encoded != nil {
+				ctx = context.WithValue(ctx, encodedBodyKey, encoded)
+				decoded = true
+			}
+		}
 
 		// Protect against request bodies with unreasonable length
 		if ctrl.MaxRequestBodyLength > 0 {
@@ -327,9 +434,9 @@ func (ctrl *Controller) MuxHandler(name string, hdlr Handler, unm Unmarshaler) M
 		}
 
 		// Load body if any
-		if req.ContentLength > 0 && unm != nil {
+		if (req.ContentLength > 0 || decoded) && load {
 			if err := unm(ctx, ctrl.Service, req); err != nil {
-				if err.Error() == "http: request body too large" {
+				if strings.HasSuffix(err.Error(), "http: request body too large") {
 					msg := fmt.Sprintf("request body length exceeds %d bytes", ctrl.MaxRequestBodyLength)
 					err = ErrRequestBodyTooLarge(msg)
 				} else {
@@ -354,7 +461,7 @@ func (ctrl *Controller) MuxHandler(name string, hdlr Handler, unm Unmarshaler) M
 // of the URL (e.g. *filepath). If it does the matching path is appended to filename to form the
 // full file path, so:
 //
-// 	c.FileHandler("/index.html", "/www/data/index.html")
+//	c.FileHandler("/index.html", "/www/data/index.html")
 //
 // Returns the content of the file "/www/data/index.html" when requests are sent to "/index.html"
 // and:
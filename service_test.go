@@ -256,6 +256,16 @@ var _ = Describe("Service", func() {
 				Ω(tw.Body).Should(Equal(respContent))
 			})
 
+			Context("matched by a mux route", func() {
+				BeforeEach(func() {
+					r = r.WithContext(goa.WithRoute(r.Context(), "/foo"))
+				})
+
+				It("sets the route in the handler context", func() {
+					Ω(goa.ContextRoute(ctx)).Should(Equal("/foo"))
+				})
+			})
+
 			Context("with an invalid payload", func() {
 				BeforeEach(func() {
 					r.Body = ioutil.NopCloser(bytes.NewBuffer([]byte("not json")))
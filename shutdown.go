@@ -0,0 +1,319 @@
+package goa
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"time"
+)
+
+type (
+	// trackingWriter wraps the response writers given to the controller handlers so that the
+	// connections they hijack (e.g. websocket connections) can be closed on shutdown.
+	trackingWriter struct {
+		http.ResponseWriter
+		service *Service
+	}
+
+	// trackingHijacker records the connections hijacked through a trackingWriter.
+	trackingHijacker struct {
+		w *trackingWriter
+	}
+
+	// trackedConn is a hijacked connection that removes itself from the service on close.
+	trackedConn struct {
+		net.Conn
+		service *Service
+	}
+)
+
+// shutdownPollInterval is the interval at which Shutdown checks whether all the in-flight
+// requests have completed.
+var shutdownPollInterval = 10 * time.Millisecond
+
+// OnShutdown registers a hook invoked by Shutdown once the in-flight requests have completed.
+// Hooks are invoked in the order they were registered with the context given to Shutdown.
+// Typical uses include flushing metrics or closing database connections.
+func (service *Service) OnShutdown(hook func(context.Context) error) {
+	service.lock.Lock()
+	defer service.lock.Unlock()
+	service.hooks = append(service.hooks, hook)
+}
+
+// Shutdown gracefully shuts down the service: it reports that the service is shutting down (see
+// ShuttingDown), waits for DrainDelay, stops accepting new connections, closes the hijacked
+// connections (e.g. websocket connections), waits for the requests being handled by the service
+// controllers to complete, cancels the service root context and runs the shutdown hooks.
+// Shutdown returns the context error if ctx is done before the requests complete.
+// ListenAndServe, ListenAndServeTLS and Serve return nil once Shutdown completes.
+func (service *Service) Shutdown(ctx context.Context) error {
+	service.lock.Lock()
+	if service.shuttingDown {
+		service.lock.Unlock()
+		select {
+		case <-service.done:
+			return service.shutdownErr
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	service.shuttingDown = true
+	hooks := service.hooks
+	service.lock.Unlock()
+
+	service.LogInfo("shutdown", "drain", service.DrainDelay)
+	if service.DrainDelay > 0 {
+		t := time.NewTimer(service.DrainDelay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+		}
+	}
+	err := service.Server.Shutdown(ctx)
+	service.closeConns()
+	if werr := service.waitInFlight(ctx); err == nil {
+		err = werr
+	}
+	service.cancel()
+	for _, hook := range hooks {
+		if herr := hook(ctx); herr != nil && err == nil {
+			err = herr
+		}
+	}
+
+	service.shutdownErr = err
+	close(service.done)
+	return err
+}
+
+// ShutdownOnSignal makes the service shut down gracefully when the process receives one of the
+// given signals or os.Interrupt if no signal is given. The shutdown may take up to timeout to
+// complete, including DrainDelay. A typical usage for services deployed in Kubernetes is:
+//
+//	service.DrainDelay = 5 * time.Second
+//	service.ShutdownOnSignal(30*time.Second, os.Interrupt, syscall.SIGTERM)
+func (service *Service) ShutdownOnSignal(timeout time.Duration, signals ...os.Signal) {
+	if len(signals) == 0 {
+		signals = []os.Signal{os.Interrupt}
+	}
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, signals...)
+	go func() {
+		sig := <-c
+		signal.Stop(c)
+		service.LogInfo("signal", "signal", sig.String())
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		if err := service.Shutdown(ctx); err != nil {
+			service.LogError("shutdown", "err", err)
+		}
+	}()
+}
+
+// ShuttingDown returns true once Shutdown has been called, including during the drain delay while
+// the service still accepts connections.
+func (service *Service) ShuttingDown() bool {
+	service.lock.Lock()
+	defer service.lock.Unlock()
+	return service.shuttingDown
+}
+
+// InFlight returns the number of requests being handled by the controller.
+func (ctrl *Controller) InFlight() int64 {
+	return atomic.LoadInt64(&ctrl.inFlight)
+}
+
+// serveErr returns the error returned by ListenAndServe, ListenAndServeTLS or Serve. It waits for
+// Shutdown to complete and returns nil if the server was closed by Shutdown.
+func (service *Service) serveErr(err error) error {
+	if err != http.ErrServerClosed {
+		return err
+	}
+	if !service.ShuttingDown() {
+		return err
+	}
+	<-service.done
+	return nil
+}
+
+// waitInFlight waits until the controllers have no request in flight or ctx is done.
+func (service *Service) waitInFlight(ctx context.Context) error {
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+	for {
+		service.lock.Lock()
+		var pending []interface{}
+		for _, ctrl := range service.controllers {
+			if n := ctrl.InFlight(); n > 0 {
+				pending = append(pending, ctrl.Name, n)
+			}
+		}
+		service.lock.Unlock()
+		if len(pending) == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			service.LogError("shutdown", append([]interface{}{"err", ctx.Err()}, pending...)...)
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// closeConns closes the hijacked connections that are still open.
+func (service *Service) closeConns() {
+	service.lock.Lock()
+	conns := make([]net.Conn, 0, len(service.conns))
+	for c := range service.conns {
+		conns = append(conns, c)
+	}
+	service.lock.Unlock()
+	for _, c := range conns {
+		c.Close()
+	}
+}
+
+// newTrackingWriter wraps rw in a trackingWriter. The returned writer implements the optional
+// http.Flusher, http.Hijacker, http.Pusher and http.CloseNotifier interfaces only if rw does so
+// that handlers that test for them get the same answer as they would with rw.
+func newTrackingWriter(rw http.ResponseWriter, service *Service) http.ResponseWriter {
+	w := &trackingWriter{ResponseWriter: rw, service: service}
+	f, isF := rw.(http.Flusher)
+	_, isH := rw.(http.Hijacker)
+	h := &trackingHijacker{w}
+	p, isP := rw.(http.Pusher)
+	c, isC := rw.(http.CloseNotifier)
+	switch {
+	case isF && isH && isP && isC:
+		return struct {
+			*trackingWriter
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+			http.CloseNotifier
+		}{w, f, h, p, c}
+	case isF && isH && isP:
+		return struct {
+			*trackingWriter
+			http.Flusher
+			http.Hijacker
+			http.Pusher
+		}{w, f, h, p}
+	case isF && isH && isC:
+		return struct {
+			*trackingWriter
+			http.Flusher
+			http.Hijacker
+			http.CloseNotifier
+		}{w, f, h, c}
+	case isF && isP && isC:
+		return struct {
+			*trackingWriter
+			http.Flusher
+			http.Pusher
+			http.CloseNotifier
+		}{w, f, p, c}
+	case isH && isP && isC:
+		return struct {
+			*trackingWriter
+			http.Hijacker
+			http.Pusher
+			http.CloseNotifier
+		}{w, h, p, c}
+	case isF && isH:
+		return struct {
+			*trackingWriter
+			http.Flusher
+			http.Hijacker
+		}{w, f, h}
+	case isF && isP:
+		return struct {
+			*trackingWriter
+			http.Flusher
+			http.Pusher
+		}{w, f, p}
+	case isF && isC:
+		return struct {
+			*trackingWriter
+			http.Flusher
+			http.CloseNotifier
+		}{w, f, c}
+	case isH && isP:
+		return struct {
+			*trackingWriter
+			http.Hijacker
+			http.Pusher
+		}{w, h, p}
+	case isH && isC:
+		return struct {
+			*trackingWriter
+			http.Hijacker
+			http.CloseNotifier
+		}{w, h, c}
+	case isP && isC:
+		return struct {
+			*trackingWriter
+			http.Pusher
+			http.CloseNotifier
+		}{w, p, c}
+	case isF:
+		return struct {
+			*trackingWriter
+			http.Flusher
+		}{w, f}
+	case isH:
+		return struct {
+			*trackingWriter
+			http.Hijacker
+		}{w, h}
+	case isP:
+		return struct {
+			*trackingWriter
+			http.Pusher
+		}{w, p}
+	case isC:
+		return struct {
+			*trackingWriter
+			http.CloseNotifier
+		}{w, c}
+	}
+	return w
+}
+
+// Unwrap returns the underlying writer so that http.ResponseController can access its methods.
+func (w *trackingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Hijack hijacks the underlying connection and records it so it can be closed on shutdown.
+func (h *trackingHijacker) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w := h.w
+	conn, buf, err := w.ResponseWriter.(http.Hijacker).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	w.service.lock.Lock()
+	defer w.service.lock.Unlock()
+	if w.service.shuttingDown {
+		conn.Close()
+		return nil, nil, fmt.Errorf("service is shutting down")
+	}
+	tc := &trackedConn{Conn: conn, service: w.service}
+	w.service.conns[tc] = struct{}{}
+	return tc, buf, nil
+}
+
+// Close closes the connection and stops tracking it.
+func (c *trackedConn) Close() error {
+	c.service.lock.Lock()
+	delete(c.service.conns, c)
+	c.service.lock.Unlock()
+	return c.Conn.Close()
+}
@@ -0,0 +1,252 @@
+package goa_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/goadesign/goa"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Shutdown", func() {
+	var (
+		s       *goa.Service
+		ctrl    *goa.Controller
+		l       net.Listener
+		served  chan error
+		started chan struct{}
+		release chan struct{}
+		handler goa.Handler
+		timeout time.Duration
+		hooks   []string
+		hookErr error
+		done    chan error
+		stopped chan struct{}
+	)
+
+	BeforeEach(func() {
+		s = goa.New("test")
+		s.WithLogger(goa.NewLogger(log.New(ioutil.Discard, "", 0)))
+		ctrl = s.NewController("test")
+		started = make(chan struct{})
+		release = make(chan struct{})
+		handler = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			close(started)
+			<-release
+			rw.WriteHeader(200)
+			return nil
+		}
+		timeout = time.Second
+		hooks = nil
+		hookErr = nil
+		s.OnShutdown(func(context.Context) error {
+			hooks = append(hooks, "first")
+			return hookErr
+		})
+		s.OnShutdown(func(context.Context) error {
+			hooks = append(hooks, "second")
+			return nil
+		})
+	})
+
+	JustBeforeEach(func() {
+		s.Mux.Handle("GET", "/", ctrl.MuxHandler("test", handler, nil))
+		s.Mux.Handle("GET", "/ping", ctrl.MuxHandler("ping", func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			rw.WriteHeader(http.StatusNoContent)
+			return nil
+		}, nil))
+		var err error
+		l, err = net.Listen("tcp", "127.0.0.1:0")
+		Ω(err).ShouldNot(HaveOccurred())
+		served = make(chan error, 1)
+		go func(s *goa.Service, l net.Listener, served chan error) { served <- s.Serve(l) }(s, l, served)
+		go http.Get("http://" + l.Addr().String() + "/")
+		Eventually(started).Should(BeClosed())
+		done = make(chan error, 1)
+		stopped = make(chan struct{})
+		go func(s *goa.Service, timeout time.Duration, done chan error, stopped chan struct{}) {
+			defer close(stopped)
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			done <- s.Shutdown(ctx)
+		}(s, timeout, done, stopped)
+	})
+
+	AfterEach(func() {
+		select {
+		case <-release:
+		default:
+			close(release)
+		}
+		// Wait for Shutdown so its hooks don't run during the next test.
+		Eventually(stopped).Should(BeClosed())
+	})
+
+	It("waits for in-flight requests", func() {
+		Consistently(done, 50*time.Millisecond).ShouldNot(Receive())
+		Ω(ctrl.InFlight()).Should(Equal(int64(1)))
+		close(release)
+		Eventually(done).Should(Receive(BeNil()))
+		Ω(ctrl.InFlight()).Should(Equal(int64(0)))
+		Eventually(served).Should(Receive(BeNil()))
+	})
+
+	It("runs the hooks in order and cancels the root context", func() {
+		close(release)
+		Eventually(done).Should(Receive(BeNil()))
+		Ω(hooks).Should(Equal([]string{"first", "second"}))
+		Ω(s.Context.Err()).Should(HaveOccurred())
+	})
+
+	It("stops accepting connections", func() {
+		close(release)
+		Eventually(done).Should(Receive(BeNil()))
+		_, err := net.Dial("tcp", l.Addr().String())
+		Ω(err).Should(HaveOccurred())
+	})
+
+	Context("with a drain delay", func() {
+		BeforeEach(func() {
+			s.DrainDelay = 100 * time.Millisecond
+		})
+
+		It("keeps accepting connections during the drain", func() {
+			Eventually(s.ShuttingDown).Should(BeTrue())
+			c := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+			resp, err := c.Get("http://" + l.Addr().String() + "/ping")
+			Ω(err).ShouldNot(HaveOccurred())
+			resp.Body.Close()
+			Ω(resp.StatusCode).Should(Equal(http.StatusNoContent))
+
+			close(release)
+			Eventually(done).Should(Receive(BeNil()))
+			_, err = net.Dial("tcp", l.Addr().String())
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("stops accepting connections once the drain delay elapsed", func() {
+			Eventually(func() error {
+				_, err := net.Dial("tcp", l.Addr().String())
+				return err
+			}).Should(HaveOccurred())
+			Ω(done).ShouldNot(Receive())
+		})
+	})
+
+	Context("with a failing hook", func() {
+		BeforeEach(func() {
+			hookErr = errors.New("hook failed")
+		})
+
+		It("returns the hook error and runs the other hooks", func() {
+			close(release)
+			Eventually(done).Should(Receive(Equal(hookErr)))
+			Ω(hooks).Should(Equal([]string{"first", "second"}))
+		})
+	})
+
+	Context("with requests that outlive the deadline", func() {
+		BeforeEach(func() {
+			timeout = 20 * time.Millisecond
+			handler = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+				close(started)
+				<-ctx.Done()
+				return nil
+			}
+		})
+
+		It("returns the context error and cancels the requests", func() {
+			Eventually(done).Should(Receive(Equal(context.DeadlineExceeded)))
+			Ω(hooks).Should(Equal([]string{"first", "second"}))
+			Eventually(ctrl.InFlight).Should(Equal(int64(0)))
+		})
+	})
+
+	Context("with a hijacked connection", func() {
+		var readErr chan error
+
+		BeforeEach(func() {
+			readErr = make(chan error, 1)
+			handler = func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+				conn, _, err := goa.ContextResponse(ctx).ResponseWriter.(http.Hijacker).Hijack()
+				if err != nil {
+					readErr <- err
+					return nil
+				}
+				close(started)
+				_, err = conn.Read(make([]byte, 1))
+				readErr <- err
+				return nil
+			}
+		})
+
+		It("closes the connection", func() {
+			Eventually(done).Should(Receive(BeNil()))
+			Ω(readErr).Should(Receive(HaveOccurred()))
+		})
+	})
+})
+
+var _ = Describe("MuxHandler response writer", func() {
+	var writer http.ResponseWriter
+	var rw http.ResponseWriter
+
+	BeforeEach(func() {
+		writer = httptest.NewRecorder()
+	})
+
+	JustBeforeEach(func() {
+		s := goa.New("test")
+		ctrl := s.NewController("test")
+		h := ctrl.MuxHandler("test", func(ctx context.Context, _ http.ResponseWriter, req *http.Request) error {
+			rw = goa.ContextResponse(ctx).ResponseWriter
+			return nil
+		}, nil)
+		h(writer, httptest.NewRequest("GET", "/", nil), nil)
+	})
+
+	It("gives access to the underlying writer", func() {
+		u, ok := rw.(interface{ Unwrap() http.ResponseWriter })
+		Ω(ok).Should(BeTrue())
+		Ω(u.Unwrap()).Should(BeIdenticalTo(writer))
+	})
+
+	It("implements the optional interfaces of the underlying writer", func() {
+		_, ok := rw.(http.Flusher)
+		Ω(ok).Should(BeTrue())
+		rw.(http.Flusher).Flush()
+		Ω(writer.(*httptest.ResponseRecorder).Flushed).Should(BeTrue())
+	})
+
+	It("does not implement the optional interfaces the underlying writer lacks", func() {
+		_, ok := rw.(http.Hijacker)
+		Ω(ok).Should(BeFalse())
+		_, ok = rw.(http.Pusher)
+		Ω(ok).Should(BeFalse())
+		_, ok = rw.(http.CloseNotifier)
+		Ω(ok).Should(BeFalse())
+	})
+
+	Context("with a writer that cannot flush", func() {
+		BeforeEach(func() {
+			writer = struct{ http.ResponseWriter }{httptest.NewRecorder()}
+		})
+
+		It("does not implement http.Flusher", func() {
+			_, ok := rw.(http.Flusher)
+			Ω(ok).Should(BeFalse())
+		})
+
+		It("reports the lack of support through http.ResponseController", func() {
+			err := http.NewResponseController(rw).Flush()
+			Ω(errors.Is(err, http.ErrNotSupported)).Should(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,173 @@
+package goa
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EventStreamContentType is the content type of server-sent events streams.
+const EventStreamContentType = "text/event-stream"
+
+// EventStreamKeepAlive is the default interval at which event streams send keep-alive comments
+// so that intermediaries do not close idle connections. Set to 0 to disable keep-alive comments.
+var EventStreamKeepAlive = 15 * time.Second
+
+// ErrEventStreamClosed is the error returned when sending an event on a closed stream.
+var ErrEventStreamClosed = errors.New("event stream closed")
+
+type (
+	// Event is a server-sent event as described in
+	// https://html.spec.whatwg.org/multipage/server-sent-events.html.
+	Event struct {
+		// ID is the event ID, clients send back the ID of the last event they received in
+		// the Last-Event-ID header when reconnecting.
+		ID string
+		// Event is the event type.
+		Event string
+		// Data is the event payload.
+		Data []byte
+		// Retry is the reconnection time clients should use if the connection is lost.
+		Retry time.Duration
+	}
+
+	// EventStream writes server-sent events to the response of a request. EventStream methods
+	// may be called concurrently.
+	EventStream struct {
+		ctx    context.Context
+		resp   *ResponseData
+		mu     sync.Mutex
+		closed bool
+		done   chan struct{}
+		exited chan struct{}
+	}
+)
+
+// NewEventStream writes the headers of a server-sent events response to the response stored in
+// ctx and returns a stream that can be used to send events. keepAlive is the interval at which
+// keep-alive comments are sent, 0 disables keep-alive comments. The stream is closed when the
+// context of the HTTP request is canceled, for example because the client disconnected. The
+// stream must be closed before the handler returns as the response cannot be written to past
+// that point.
+func NewEventStream(ctx context.Context, keepAlive time.Duration) (*EventStream, error) {
+	resp := ContextResponse(ctx)
+	if resp == nil {
+		return nil, errors.New("missing response data in context")
+	}
+	if resp.Written() {
+		return nil, errors.New("response already written")
+	}
+	resp.Header().Set("Content-Type", EventStreamContentType)
+	resp.Header().Set("Cache-Control", "no-cache")
+	resp.WriteHeader(http.StatusOK)
+	resp.Flush()
+
+	if req := ContextRequest(ctx); req != nil && req.Request != nil {
+		ctx = req.Request.Context()
+	}
+	s := &EventStream{ctx: ctx, resp: resp, done: make(chan struct{}), exited: make(chan struct{})}
+	go s.run(keepAlive)
+	return s, nil
+}
+
+// Send writes the event to the response and flushes it. Send returns the context error if the
+// request context is done and ErrEventStreamClosed if the stream was closed.
+func (s *EventStream) Send(e *Event) error {
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+	return s.write(e.encode())
+}
+
+// Close closes the stream and waits for the keep-alive comments to stop, further calls to Send
+// return ErrEventStreamClosed.
+func (s *EventStream) Close() {
+	s.close()
+	<-s.exited
+}
+
+// close marks the stream as closed.
+func (s *EventStream) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.done)
+	}
+}
+
+// Done returns a channel that is closed when the stream is closed or the request context is
+// done.
+func (s *EventStream) Done() <-chan struct{} {
+	return s.done
+}
+
+// run sends the keep-alive comments and closes the stream when the request context is done.
+func (s *EventStream) run(keepAlive time.Duration) {
+	defer close(s.exited)
+	var tick <-chan time.Time
+	if keepAlive > 0 {
+		ticker := time.NewTicker(keepAlive)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	for {
+		select {
+		case <-s.ctx.Done():
+			s.close()
+			return
+		case <-s.done:
+			return
+		case <-tick:
+			if err := s.write([]byte(": keep-alive\n\n")); err != nil {
+				s.close()
+				return
+			}
+		}
+	}
+}
+
+// write writes and flushes the given bytes.
+func (s *EventStream) write(b []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrEventStreamClosed
+	}
+	if _, err := s.resp.Write(b); err != nil {
+		return err
+	}
+	s.resp.Flush()
+	return nil
+}
+
+// encode returns the wire representation of the event.
+func (e *Event) encode() []byte {
+	var b bytes.Buffer
+	if e.ID != "" {
+		fmt.Fprintf(&b, "id: %s\n", singleLine(e.ID))
+	}
+	if e.Event != "" {
+		fmt.Fprintf(&b, "event: %s\n", singleLine(e.Event))
+	}
+	if e.Retry > 0 {
+		fmt.Fprintf(&b, "retry: %s\n", strconv.FormatInt(int64(e.Retry/time.Millisecond), 10))
+	}
+	data := strings.Replace(string(e.Data), "\r\n", "\n", -1)
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteByte('\n')
+	return b.Bytes()
+}
+
+// singleLine removes the line breaks from s.
+func singleLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
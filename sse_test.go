@@ -0,0 +1,91 @@
+package goa_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/goadesign/goa"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("EventStream", func() {
+	var rw *httptest.ResponseRecorder
+	var ctx context.Context
+	var cancel context.CancelFunc
+	var keepAlive time.Duration
+	var stream *goa.EventStream
+
+	BeforeEach(func() {
+		rw = httptest.NewRecorder()
+		req, err := http.NewRequest("GET", "/events", nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		var reqCtx context.Context
+		reqCtx, cancel = context.WithCancel(context.Background())
+		ctx = goa.NewContext(context.Background(), rw, req.WithContext(reqCtx), nil)
+		keepAlive = 0
+	})
+
+	JustBeforeEach(func() {
+		var err error
+		stream, err = goa.NewEventStream(ctx, keepAlive)
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	It("writes the event stream headers", func() {
+		stream.Close()
+		Ω(rw.Code).Should(Equal(http.StatusOK))
+		Ω(rw.Header().Get("Content-Type")).Should(Equal("text/event-stream"))
+		Ω(rw.Header().Get("Cache-Control")).Should(Equal("no-cache"))
+		Ω(rw.Flushed).Should(BeTrue())
+	})
+
+	It("encodes events", func() {
+		err := stream.Send(&goa.Event{ID: "1", Event: "update", Data: []byte("foo\nbar"), Retry: time.Second})
+		Ω(err).ShouldNot(HaveOccurred())
+		err = stream.Send(&goa.Event{Data: []byte(`{"id":2}`)})
+		Ω(err).ShouldNot(HaveOccurred())
+		stream.Close()
+		Ω(rw.Body.String()).Should(Equal("id: 1\nevent: update\nretry: 1000\ndata: foo\ndata: bar\n\n" +
+			"data: {\"id\":2}\n\n"))
+	})
+
+	It("fails to send events once closed", func() {
+		stream.Close()
+		err := stream.Send(&goa.Event{Data: []byte("foo")})
+		Ω(err).Should(Equal(goa.ErrEventStreamClosed))
+	})
+
+	It("closes when the client disconnects", func() {
+		cancel()
+		Eventually(stream.Done()).Should(BeClosed())
+		err := stream.Send(&goa.Event{Data: []byte("foo")})
+		Ω(err).Should(Equal(context.Canceled))
+	})
+
+	Context("with keep-alive", func() {
+		BeforeEach(func() {
+			keepAlive = 5 * time.Millisecond
+		})
+
+		It("sends keep-alive comments", func() {
+			time.Sleep(20 * time.Millisecond)
+			cancel()
+			Eventually(stream.Done()).Should(BeClosed())
+			Ω(rw.Body.String()).Should(HavePrefix(": keep-alive\n\n"))
+		})
+
+		It("stops sending keep-alive comments once closed", func() {
+			stream.Close()
+			written := rw.Body.Len()
+			time.Sleep(20 * time.Millisecond)
+			Ω(rw.Body.Len()).Should(Equal(written))
+		})
+	})
+})